@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+const benchUsage = `Usage:
+  goclode bench [--provider id] [--model m] [--suite prompts.json] [--out report.md] [--json]
+                                          Run a prompt suite against every available provider and model
+                                          and report first-token latency, tokens/sec, cost, and correctness
+
+A suite is a JSON array of {"name", "system", "prompt", "expect"}; a reply is
+correct when it contains "expect". Costs come from "price_in" and "price_out"
+(USD per million tokens) in the provider's config.
+`
+
+// defaultBenchSuite is the standard suite: short coding prompts with
+// answers easy to check
+var defaultBenchSuite = []providers.BenchPrompt{
+	{Name: "arithmetic", Prompt: "What is 17 * 23? Answer with the number only.", Expect: "391"},
+	{Name: "go-syntax", Prompt: "Write a Go function Reverse(s string) string that reverses a string rune by rune. Reply with the code only.", Expect: "func Reverse"},
+	{Name: "explain", Prompt: "In two sentences, explain what a goroutine leak is."},
+	{Name: "sql", Prompt: "Write a SQLite query counting the rows of a table named sessions. Reply with the query only.", Expect: "count("},
+	{Name: "refactor", System: "You are a concise Go reviewer.", Prompt: "Suggest one improvement to: for i := 0; i < len(xs); i++ { total = total + xs[i] }"},
+}
+
+// benchTarget is a model to benchmark
+type benchTarget struct {
+	provider providers.Provider
+	model    string
+	price    providers.Pricing
+}
+
+// runBench runs "goclode bench" and returns the exit code
+func runBench(engine *core.Engine, args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, benchUsage) }
+	providerID := fs.String("provider", "", "Only benchmark this provider")
+	model := fs.String("model", "", "Only benchmark this model")
+	suitePath := fs.String("suite", "", "JSON prompt suite (default: the built-in one)")
+	out := fs.String("out", "", "Write the report to this file (default: stdout)")
+	asJSON := fs.Bool("json", false, "Write the results as JSON instead of markdown")
+	timeout := fs.Duration("timeout", 2*time.Minute, "Time limit per prompt")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if err := bench(engine, *providerID, *model, *suitePath, *out, *asJSON, *timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// bench runs the suite and writes the report
+func bench(engine *core.Engine, providerID, model, suitePath, out string, asJSON bool, timeout time.Duration) error {
+	suite := defaultBenchSuite
+	if suitePath != "" {
+		data, err := os.ReadFile(suitePath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &suite); err != nil {
+			return fmt.Errorf("read %s: %w", suitePath, err)
+		}
+		if len(suite) == 0 {
+			return fmt.Errorf("%s has no prompts", suitePath)
+		}
+	}
+
+	registry := providers.NewRegistry(engine.DB())
+	targets := make([]benchTarget, 0)
+	for _, p := range registry.Available() {
+		if providerID != "" && p.ID() != providerID {
+			continue
+		}
+		price, err := registry.Pricing(p.ID())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		for _, m := range p.Models() {
+			if model == "" || m == model {
+				targets = append(targets, benchTarget{provider: p, model: m, price: price})
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no available provider or model to benchmark (set an API key, check --provider and --model)")
+	}
+
+	results := make([]providers.BenchResult, 0, len(targets)*len(suite))
+	for _, t := range targets {
+		for _, prompt := range suite {
+			fmt.Fprintf(os.Stderr, "%s/%s  %s ... ", t.provider.ID(), t.model, prompt.Name)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			r := providers.Bench(ctx, t.provider, t.model, prompt, t.price)
+			cancel()
+			if r.Err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", r.Err)
+			} else {
+				fmt.Fprintf(os.Stderr, "%dms\n", r.Total.Milliseconds())
+			}
+			results = append(results, r)
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if asJSON {
+		return writeBenchJSON(w, results)
+	}
+	writeBenchReport(w, results)
+	if out != "" {
+		fmt.Fprintf(os.Stderr, "Report written to %s\n", out)
+	}
+	return nil
+}
+
+// benchSummary is one model's results over the suite
+type benchSummary struct {
+	key                string
+	runs, errors       int
+	firstToken         time.Duration
+	tokensPerSec, cost float64
+	checked, correct   int
+	estimated          bool
+}
+
+// summarizeBench groups results by provider/model, in run order
+func summarizeBench(results []providers.BenchResult) []*benchSummary {
+	byKey := make(map[string]*benchSummary)
+	order := make([]*benchSummary, 0)
+	for _, r := range results {
+		key := r.Provider + "/" + r.Model
+		s := byKey[key]
+		if s == nil {
+			s = &benchSummary{key: key}
+			byKey[key] = s
+			order = append(order, s)
+		}
+		if r.Err != nil {
+			s.errors++
+			continue
+		}
+		s.runs++
+		s.firstToken += r.FirstToken
+		s.tokensPerSec += r.TokensPerSec
+		s.cost += r.Cost
+		s.estimated = s.estimated || r.Estimated
+		if r.Correct != nil {
+			s.checked++
+			if *r.Correct {
+				s.correct++
+			}
+		}
+	}
+	return order
+}
+
+// writeBenchReport writes the comparison as markdown: a summary per model,
+// then every run
+func writeBenchReport(w io.Writer, results []providers.BenchResult) {
+	fmt.Fprintf(w, "# GoClode benchmark\n\n%s\n\n", time.Now().Format("2006-01-02 15:04"))
+	fmt.Fprintln(w, "| Model | First token (avg) | Tokens/sec (avg) | Cost | Correct | Errors |")
+	fmt.Fprintln(w, "|---|---:|---:|---:|---:|---:|")
+	estimated := false
+	for _, s := range summarizeBench(results) {
+		firstToken, tps, correct := "-", "-", "-"
+		if s.runs > 0 {
+			firstToken = fmt.Sprintf("%dms", (s.firstToken / time.Duration(s.runs)).Milliseconds())
+			tps = fmt.Sprintf("%.1f", s.tokensPerSec/float64(s.runs))
+			if s.estimated {
+				tps += "*"
+				estimated = true
+			}
+		}
+		if s.checked > 0 {
+			correct = fmt.Sprintf("%d/%d", s.correct, s.checked)
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | $%.5f | %s | %d |\n", s.key, firstToken, tps, s.cost, correct, s.errors)
+	}
+	if estimated {
+		fmt.Fprintln(w, "\n\\* token counts estimated from the text: the provider reported none")
+	}
+
+	fmt.Fprint(w, "\n## Runs\n\n")
+	fmt.Fprintln(w, "| Model | Prompt | First token | Total | Tokens in/out | Tokens/sec | Cost | Result |")
+	fmt.Fprintln(w, "|---|---|---:|---:|---:|---:|---:|---|")
+	for _, r := range results {
+		result := "ok"
+		switch {
+		case r.Err != nil:
+			result = "error: " + strings.ReplaceAll(r.Err.Error(), "|", "/")
+		case r.Correct != nil && *r.Correct:
+			result = "correct"
+		case r.Correct != nil:
+			result = "wrong"
+		}
+		fmt.Fprintf(w, "| %s/%s | %s | %dms | %dms | %d/%d | %.1f | $%.5f | %s |\n",
+			r.Provider, r.Model, r.Prompt, r.FirstToken.Milliseconds(), r.Total.Milliseconds(),
+			r.TokensIn, r.TokensOut, r.TokensPerSec, r.Cost, result)
+	}
+}
+
+// writeBenchJSON writes the runs as a JSON array
+func writeBenchJSON(w io.Writer, results []providers.BenchResult) error {
+	type run struct {
+		Provider     string  `json:"provider"`
+		Model        string  `json:"model"`
+		Prompt       string  `json:"prompt"`
+		FirstTokenMs int64   `json:"first_token_ms"`
+		TotalMs      int64   `json:"total_ms"`
+		TokensIn     int     `json:"tokens_in"`
+		TokensOut    int     `json:"tokens_out"`
+		Estimated    bool    `json:"tokens_estimated,omitempty"`
+		TokensPerSec float64 `json:"tokens_per_sec"`
+		Cost         float64 `json:"cost_usd"`
+		Correct      *bool   `json:"correct,omitempty"`
+		Error        string  `json:"error,omitempty"`
+	}
+	runs := make([]run, 0, len(results))
+	for _, r := range results {
+		item := run{
+			Provider: r.Provider, Model: r.Model, Prompt: r.Prompt,
+			FirstTokenMs: r.FirstToken.Milliseconds(), TotalMs: r.Total.Milliseconds(),
+			TokensIn: r.TokensIn, TokensOut: r.TokensOut, Estimated: r.Estimated,
+			TokensPerSec: r.TokensPerSec, Cost: r.Cost, Correct: r.Correct,
+		}
+		if r.Err != nil {
+			item.Error = r.Err.Error()
+		}
+		runs = append(runs, item)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(runs)
+}
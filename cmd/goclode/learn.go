@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/modules"
+)
+
+const learnUsage = `Usage:
+  goclode learn export [file]             Write learned patterns as JSON (default: stdout)
+  goclode learn import [--replace] <file> Merge learned patterns; the more confident row wins
+                                          (--replace: discard local learning first)
+`
+
+// runLearn runs "goclode learn" and returns the exit code
+func runLearn(engine *core.Engine, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, learnUsage)
+		return 2
+	}
+	lm := modules.NewLearningModule(engine, core.NewModuleManager(engine))
+	if err := lm.OpenGlobal(""); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer lm.Close()
+
+	var err error
+	switch args[0] {
+	case "export":
+		err = learnExport(lm, args[1:])
+	case "import":
+		err = learnImport(lm, args[1:])
+	default:
+		fmt.Fprint(os.Stderr, learnUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// learnExport writes the learning export to a file or stdout
+func learnExport(lm *modules.LearningModule, args []string) error {
+	data, err := lm.Export()
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+
+	if len(args) == 0 || args[0] == "-" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(args[0], out, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d intents, %d code patterns, %d preferences to %s\n",
+		len(data.LearnedIntents), len(data.CodePatterns), len(data.UserPreferences), args[0])
+	return nil
+}
+
+// learnImport merges (or with --replace, restores) a learning export
+func learnImport(lm *modules.LearningModule, args []string) error {
+	fs := flag.NewFlagSet("learn import", flag.ContinueOnError)
+	replace := fs.Bool("replace", false, "Discard local learning before importing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: goclode learn import [--replace] <file>")
+	}
+
+	var raw []byte
+	var err error
+	if fs.Arg(0) == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+	var data modules.LearningExport
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("parse %s: %w", fs.Arg(0), err)
+	}
+
+	stats, err := lm.Import(&data, *replace)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported: %d added, %d updated, %d kept (local was as confident)\n",
+		stats.Added, stats.Updated, stats.Kept)
+	return nil
+}
@@ -11,20 +11,36 @@ import (
 	"github.com/hazyhaar/GoClode/internal/ui"
 )
 
-const version = "0.1.0"
+// version is set at build time by the Makefile (-X main.version=...)
+var version = "0.1.0"
 
 func main() {
 	// Flags
 	var (
 		showVersion = flag.Bool("version", false, "Show version")
-		dbPath      = flag.String("db", "", "Database path (default: auto-generated in .goclode/)")
+		dbPath      = flag.String("db", "", "Database path (default: the project database, .goclode/goclode.db)")
 		debug       = flag.Bool("debug", false, "Enable debug mode")
+		debugAddr   = flag.String("debug-addr", "", "Serve pprof, session state, hooks, live debug events, and a history query API on this localhost address (e.g. :6060)")
+		stdio       = flag.Bool("stdio", false, "Speak JSON-RPC on stdin/stdout for editor extensions instead of the terminal UI")
+		listen      = flag.String("listen", "", "Speak the --stdio protocol to editors connecting to this localhost address (e.g. :7777); they first send initialize with the token in .goclode/listen-<port>.token")
+		detach      = flag.Bool("detach", false, "With --listen, keep serving after Ctrl+C or the terminal closing; goclode attach starts servers this way")
 	)
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `GoClode v%s - AI Coding Assistant
 
 Usage: goclode [options]
+       goclode [options] learn export|import ...
+       goclode [options] test run|add|list ...
+       goclode [options] bench ...
+       goclode [options] review --pr <n> ...
+       goclode [options] usage [--since 30d] [--by day,provider,model,session] [--json]
+       goclode [options] slack
+       goclode [options] discord
+       goclode [options] webhook [addr]
+       goclode [options] attach [addr]
+       goclode [options] setup
+       goclode self-update [--check] [--force]
 
 Options:
 `, version)
@@ -32,12 +48,47 @@ Options:
 		fmt.Fprintf(os.Stderr, `
 Examples:
   goclode                    Start interactive session
-  goclode --debug            Start with debug logging
+  goclode --debug            Start in debug mode, logging everything
+  goclode --debug --debug-addr :6060  Also serve pprof and live debug events on localhost
   goclode --db ./my.db       Use specific database
+  goclode --stdio            Serve an editor extension (chat/send, changes/preview, changes/apply)
+  goclode --listen :7777     Wait for an editor to connect, e.g. Neovim via vim.lsp.rpc.connect, or a browser on ws://localhost:7777/ws
+  goclode attach             Follow the background session on :7777, starting it if needed; leaving keeps it running
+  goclode setup              Pick a provider, check its key, and save the defaults (runs on first launch)
+  goclode self-update        Install the latest release after checking its checksum (--check only looks)
+  goclode learn export a.json    Save learned patterns to move them to another machine
+  goclode learn import team.json Merge shared patterns (the more confident row wins)
+  goclode test run --mock r.json Check stored test cases against canned replies
+  goclode bench --out bench.md   Compare latency, speed, cost, and correctness of every model
+  goclode review --pr 42         Post review comments on a GitHub pull request, e.g. from CI
+  goclode usage --since 7d --by provider,model  Tokens and cost of the last week's sessions
+  goclode slack              Share this workspace in Slack: mention the bot, one session per thread
+                             (only for slack_allowed_users / slack_allowed_channels)
+  goclode discord            Same on Discord, with /status, /diff, /undo... as slash commands
+                             (only for discord_allowed_users / discord_allowed_guilds)
+  goclode webhook :8080      Start a session for each GitHub issue or Sentry alert delivered to /github or /sentry
 
 Environment Variables:
   CEREBRAS_API_KEY           Cerebras API key
   OPENROUTER_API_KEY         OpenRouter API key (optional)
+  SLACK_APP_TOKEN            Slack app-level token with connections:write, for goclode slack
+  SLACK_BOT_TOKEN            Slack bot token (app_mentions:read, chat:write, channels:history, im:history)
+  GITHUB_TOKEN               GitHub token for goclode review (GITHUB_REPOSITORY picks the repository) and self-update
+  DISCORD_TOKEN              Discord bot token; enable the Message Content intent, for goclode discord
+  GITHUB_WEBHOOK_SECRET      Secret of the GitHub webhook, for goclode webhook
+  SENTRY_WEBHOOK_SECRET      Client secret of the Sentry integration, for goclode webhook
+
+  Variables also load from the repository's .env, then ~/.goclode/env; the shell wins over both.
+  A repository's .env only sets *_API_KEY and the tokens and secrets above
+
+Config Files (key = value lines, each over the one before; /config <key> shows the source):
+  ~/.config/goclode/config   Every project (or $XDG_CONFIG_HOME/goclode/config)
+  .goclode/config            The repository, at its root
+  <dir>/.goclode/config      Each subdirectory down to where goclode starts
+  Values set with /config win over the files until /config unset <key>
+  Repository files only set workflow keys (temperature, auto_commit, commit_trailers,
+  max_context_messages, timeouts...); the others, such as command_allowlist or the budgets,
+  are read from the global file only
 
 For more info: https://github.com/hazyhaar/GoClode
 `)
@@ -50,12 +101,103 @@ For more info: https://github.com/hazyhaar/GoClode
 		return
 	}
 
+	// Self-update replaces the binary: no session database to open
+	if flag.Arg(0) == "self-update" {
+		os.Exit(runSelfUpdate(flag.Args()[1:]))
+	}
+
 	// Create engine
 	engine, err := core.NewEngine(*dbPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	if err := engine.LoadEnvFiles("."); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if err := engine.LoadConfigFiles("."); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if err := engine.ConfigureLogging(*debug); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	if flag.Arg(0) == "learn" {
+		code := runLearn(engine, flag.Args()[1:])
+		engine.Close()
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "test" {
+		code := runTest(engine, flag.Args()[1:])
+		engine.Close()
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "bench" {
+		code := runBench(engine, flag.Args()[1:])
+		engine.Close()
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "usage" {
+		code := runUsage(engine, *dbPath, flag.Args()[1:])
+		engine.Close()
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "review" {
+		code := runReview(engine, flag.Args()[1:])
+		engine.Close()
+		os.Exit(code)
+	}
+	if flag.Arg(0) == "setup" {
+		err := ui.RunSetup(engine)
+		engine.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "attach" {
+		// The session lives in the server: this process is only a client
+		engine.Close()
+		addr := ui.DefaultAttachAddr
+		if flag.Arg(1) != "" {
+			addr = flag.Arg(1)
+		}
+		var serverArgs []string
+		if *dbPath != "" {
+			serverArgs = append(serverArgs, "--db", *dbPath)
+		}
+		if err := ui.Attach(addr, serverArgs); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// In stdio mode the protocol owns stdin and stdout: terminal output
+	// goes to stderr as a log. Editors and chat threads approve changes
+	// themselves, so prompts read nothing.
+	in, out := os.Stdin, os.Stdout
+	if *stdio {
+		os.Stdout = os.Stderr
+	}
+	headless := *stdio || *listen != "" || flag.Arg(0) == "slack" || flag.Arg(0) == "discord" || flag.Arg(0) == "webhook"
+	if headless {
+		if null, err := os.Open(os.DevNull); err == nil {
+			os.Stdin = null
+		}
+	}
+
+	// First launch: ask for a provider before there is nothing to talk to
+	if !headless && ui.NeedsSetup(engine) {
+		if err := ui.RunSetup(engine); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if !headless && engine.GetConfigBool("update_check") {
+		notifyUpdate()
+	}
 
 	// Create chat interface
 	chat, err := ui.NewChat(engine)
@@ -67,8 +209,52 @@ For more info: https://github.com/hazyhaar/GoClode
 
 	// Enable debug if requested
 	if *debug {
-		// This would be set through the chat interface
-		engine.SetConfig("debug_mode", "true")
+		chat.SetDebug(true)
+	}
+	if *debugAddr != "" {
+		if err := chat.ServeDebug(*debugAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if *stdio {
+		if err := chat.ServeStdio(in, out); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "slack" {
+		if err := chat.ServeSlack(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "discord" {
+		if err := chat.ServeDiscord(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "webhook" {
+		addr := flag.Arg(1)
+		if addr == "" {
+			addr = ":8080"
+		}
+		if err := chat.ServeWebhooks(addr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *listen != "" {
+		if err := chat.ServeTCP(*listen, *detach); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
 	// Run
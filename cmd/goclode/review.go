@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/git"
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/review"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+const reviewUsage = `Usage:
+  goclode review --pr <n> [--repo owner/name] [--provider id] [--model m] [--dry-run] [--fail-on level]
+                                          Review each file a GitHub pull request changes with the
+                                          code_review prompt and post the findings as review comments
+
+The repository defaults to GITHUB_REPOSITORY, then to the forge_remote (origin)
+remote of the current directory; GITHUB_SERVER_URL selects GitHub Enterprise.
+The token comes from github_token or GITHUB_TOKEN. Files are read from the
+working tree for context, so check out the pull request's head first.
+
+--fail-on warning|error exits with 1 when a finding is that serious, to fail a
+CI job; the default, none, only comments.
+
+GitHub Actions:
+  - uses: actions/checkout@v4
+  - run: goclode review --pr ${{ github.event.pull_request.number }} --fail-on error
+    env:
+      GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }}     # needs pull-requests: write
+      CEREBRAS_API_KEY: ${{ secrets.CEREBRAS_API_KEY }}
+`
+
+// runReview runs "goclode review" and returns the exit code
+func runReview(engine *core.Engine, args []string) int {
+	fs := flag.NewFlagSet("review", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, reviewUsage) }
+	number := fs.Int("pr", 0, "Pull request number")
+	repo := fs.String("repo", "", "GitHub repository as owner/name (default: GITHUB_REPOSITORY or the git remote)")
+	providerID := fs.String("provider", "", "Provider to review with (default: the current one)")
+	model := fs.String("model", "", "Model to review with (default: the provider's)")
+	dryRun := fs.Bool("dry-run", false, "Print the review instead of posting it")
+	failOn := fs.String("fail-on", "none", "Exit with 1 on findings this serious: none, warning, or error")
+	timeout := fs.Duration("timeout", 2*time.Minute, "Time limit per file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *number <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --pr is required")
+		fs.Usage()
+		return 2
+	}
+	if *failOn != "none" && *failOn != review.SeverityWarning && *failOn != review.SeverityError {
+		fmt.Fprintf(os.Stderr, "Error: --fail-on must be none, warning, or error, not %q\n", *failOn)
+		return 2
+	}
+
+	findings, err := reviewPR(engine, *number, *repo, *providerID, *model, *dryRun, *timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *failOn != "none" {
+		for _, f := range findings {
+			if review.AtLeast(f.Severity, *failOn) {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// reviewRemote finds the GitHub repository to review
+func reviewRemote(engine *core.Engine, repo string) (*git.Remote, error) {
+	if repo == "" {
+		repo = os.Getenv("GITHUB_REPOSITORY")
+	}
+	if repo != "" {
+		host := "github.com"
+		if server := os.Getenv("GITHUB_SERVER_URL"); server != "" {
+			if u, err := url.Parse(server); err == nil && u.Hostname() != "" {
+				host = u.Hostname()
+			}
+		}
+		return &git.Remote{Host: host, Path: repo}, nil
+	}
+
+	remoteName, _ := engine.GetConfig("forge_remote")
+	if remoteName == "" {
+		remoteName = "origin"
+	}
+	rawURL, err := git.NewManager("").RemoteURL(remoteName)
+	if err != nil {
+		return nil, fmt.Errorf("remote %s: %w (or pass --repo)", remoteName, err)
+	}
+	return git.ParseRemoteURL(rawURL)
+}
+
+// reviewTemplate returns the code_review prompt template
+func reviewTemplate(engine *core.Engine) (string, error) {
+	var template string
+	err := engine.DB().QueryRow(
+		"SELECT template FROM prompts WHERE prompt_id = 'code_review' AND enabled = 1").Scan(&template)
+	if errors.Is(err, sql.ErrNoRows) {
+		return review.DefaultTemplate, nil
+	}
+	return template, err
+}
+
+// reviewPR reviews every changed file and posts (or prints) the review
+func reviewPR(engine *core.Engine, number int, repo, providerID, model string, dryRun bool, timeout time.Duration) ([]review.Finding, error) {
+	remote, err := reviewRemote(engine, repo)
+	if err != nil {
+		return nil, err
+	}
+	token, _ := engine.GetConfig("github_token")
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	forge, err := git.NewForge(remote, git.ForgeGitHub, token)
+	if err != nil {
+		return nil, err
+	}
+	reviewer, ok := forge.(git.Reviewer)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support reviews", forge.Name())
+	}
+
+	registry := providers.NewRegistry(engine.DB())
+	provider := registry.Current()
+	if providerID != "" {
+		if provider, err = registry.Get(providerID); err != nil {
+			return nil, err
+		}
+	}
+	if provider == nil {
+		return nil, fmt.Errorf("no provider available (set an API key)")
+	}
+
+	template, err := reviewTemplate(engine)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	head, files, err := reviewer.PullRequestFiles(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir, _ := os.Getwd()
+	protected, _ := engine.GetConfig("protected_paths")
+	guard := workspace.NewGuard(workDir, workspace.ParsePatternList(protected))
+
+	reviewed := make([]git.PullRequestFile, 0, len(files))
+	for _, file := range files {
+		if file.Status == "removed" || file.Patch == "" {
+			continue
+		}
+		if err := guard.Check(file.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", file.Path, err)
+			continue
+		}
+		reviewed = append(reviewed, file)
+	}
+
+	findings := make([]review.Finding, 0)
+	patches := make(map[string]string, len(reviewed))
+	for _, file := range reviewed {
+		patches[file.Path] = file.Patch
+		others := make([]string, 0, len(reviewed)-1)
+		for _, other := range reviewed {
+			if other.Path != file.Path {
+				others = append(others, other.Path)
+			}
+		}
+		content, _ := os.ReadFile(filepath.Join(workDir, filepath.FromSlash(file.Path)))
+
+		fmt.Fprintf(os.Stderr, "Reviewing %s ... ", file.Path)
+		fileCtx, cancel := context.WithTimeout(ctx, timeout)
+		found, err := review.File(fileCtx, provider, model, file.Path, review.Prompt(template, file, string(content), others))
+		cancel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed: %v\n", err)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%d finding(s)\n", len(found))
+		findings = append(findings, found...)
+	}
+
+	comments, outside := review.Comments(findings, patches)
+	body := review.Body(findings, outside, len(reviewed))
+	if dryRun {
+		fmt.Println(body)
+		for _, c := range comments {
+			fmt.Printf("\n%s:%d\n  %s\n", c.Path, c.Line, c.Body)
+		}
+		return findings, nil
+	}
+	if err := reviewer.PostReview(ctx, number, head, body, comments); err != nil {
+		return findings, err
+	}
+	fmt.Fprintf(os.Stderr, "Posted a review of #%d with %d comment(s)\n", number, len(comments))
+	return findings, nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/modules"
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/ui"
+)
+
+const testUsage = `Usage:
+  goclode test run [--tag t] [--provider id | --mock replies.json] [name...]
+                                          Run test cases through the intent parser and the model
+                                          (--mock: answer from a JSON object of input -> reply)
+  goclode test add --name n --input text [--intent i] [--output text] [--tags a,b]
+                                          Store a test case
+  goclode test list                       List test cases and their last result
+`
+
+// runTest runs "goclode test" and returns the exit code
+func runTest(engine *core.Engine, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, testUsage)
+		return 2
+	}
+	dm := modules.NewDebugModule(engine, core.NewModuleManager(engine))
+
+	var err error
+	switch args[0] {
+	case "run":
+		var failed int
+		failed, err = testRun(engine, dm, args[1:])
+		if err == nil && failed > 0 {
+			return 1
+		}
+	case "add":
+		err = testAdd(dm, args[1:])
+	case "list":
+		err = testList(dm)
+	default:
+		fmt.Fprint(os.Stderr, testUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// testRun runs the selected test cases and returns how many failed
+func testRun(engine *core.Engine, dm *modules.DebugModule, args []string) (int, error) {
+	fs := flag.NewFlagSet("test run", flag.ContinueOnError)
+	tag := fs.String("tag", "", "Only run test cases with this tag")
+	providerID := fs.String("provider", "", "Provider answering the inputs (default: the current one)")
+	mock := fs.String("mock", "", "JSON file of canned replies, instead of a provider")
+	if err := fs.Parse(args); err != nil {
+		return 0, err
+	}
+
+	cases, err := dm.TestCases(*tag, fs.Args())
+	if err != nil {
+		return 0, err
+	}
+	if len(cases) == 0 {
+		return 0, fmt.Errorf("no test cases to run (goclode test add)")
+	}
+
+	provider, err := testProvider(engine, *providerID, *mock)
+	if err != nil {
+		return 0, err
+	}
+	parser := ui.NewIntentParser(engine.DB())
+	pipeline := modules.TestPipeline{
+		Intent: func(input string) string {
+			if intent := parser.Parse(input); intent != nil {
+				return string(intent.Type)
+			}
+			return ""
+		},
+	}
+	if provider != nil {
+		systemPrompt, _ := engine.GetConfig("system_prompt")
+		pipeline.Respond = func(ctx context.Context, input string) (string, error) {
+			resp, err := provider.Generate(ctx, &providers.Request{
+				Messages: []providers.Message{
+					{Role: "system", Content: systemPrompt},
+					{Role: "user", Content: input},
+				},
+				Temperature: 0.2,
+			})
+			if err != nil {
+				return "", err
+			}
+			return resp.Content, nil
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, "No provider available: output checks are skipped (--mock to use canned replies)")
+	}
+
+	failed := 0
+	for _, tc := range cases {
+		r := dm.RunTestCase(context.Background(), tc, pipeline)
+		status := "PASS"
+		if !r.Passed {
+			status = "FAIL"
+			failed++
+		}
+		skipped := ""
+		if len(r.Skipped) > 0 {
+			skipped = " (skipped: " + strings.Join(r.Skipped, ", ") + ")"
+		}
+		fmt.Printf("%s  %s  %dms  trace %s%s\n", status, tc.Name, r.Duration.Milliseconds(), r.TraceID[:8], skipped)
+		for _, f := range r.Failures {
+			fmt.Println("      " + strings.ReplaceAll(strings.TrimRight(f, "\n"), "\n", "\n      "))
+		}
+	}
+	fmt.Printf("\n%d passed, %d failed\n", len(cases)-failed, failed)
+	return failed, nil
+}
+
+// testProvider picks the provider answering test inputs: canned replies
+// with --mock, otherwise the requested or current one if it is configured
+func testProvider(engine *core.Engine, id, mock string) (providers.Provider, error) {
+	if mock != "" {
+		data, err := os.ReadFile(mock)
+		if err != nil {
+			return nil, err
+		}
+		replies := make(map[string]string)
+		if err := json.Unmarshal(data, &replies); err != nil {
+			return nil, fmt.Errorf("read %s: %w", mock, err)
+		}
+		return providers.NewReplayProvider(replies), nil
+	}
+
+	registry := providers.NewRegistry(engine.DB())
+	if id != "" {
+		p, err := registry.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if !p.IsAvailable() {
+			return nil, fmt.Errorf("provider %s is not configured", id)
+		}
+		return p, nil
+	}
+	if p := registry.Current(); p != nil && p.IsAvailable() {
+		return p, nil
+	}
+	return nil, nil
+}
+
+// testAdd stores a test case from flags
+func testAdd(dm *modules.DebugModule, args []string) error {
+	fs := flag.NewFlagSet("test add", flag.ContinueOnError)
+	var tc modules.TestCase
+	fs.StringVar(&tc.Name, "name", "", "Test case name")
+	fs.StringVar(&tc.Input, "input", "", "What the user types")
+	fs.StringVar(&tc.ExpectedIntent, "intent", "", "Intent the input should be parsed as (e.g. code, undo)")
+	fs.StringVar(&tc.ExpectedOutput, "output", "", "Text the reply must contain")
+	fs.StringVar(&tc.Description, "description", "", "What the test case checks")
+	tags := fs.String("tags", "", "Comma-separated tags")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	for _, t := range strings.Split(*tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tc.Tags = append(tc.Tags, t)
+		}
+	}
+
+	if _, err := dm.AddTestCase(tc); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Added test case %s\n", tc.Name)
+	return nil
+}
+
+// testList prints the test cases with their last result
+func testList(dm *modules.DebugModule) error {
+	cases, err := dm.TestCases("", nil)
+	if err != nil {
+		return err
+	}
+	for _, tc := range cases {
+		expects := make([]string, 0, 2)
+		if tc.ExpectedIntent != "" {
+			expects = append(expects, "intent "+tc.ExpectedIntent)
+		}
+		if tc.ExpectedOutput != "" {
+			expects = append(expects, "output")
+		}
+		last := tc.LastResult
+		if last == "" {
+			last = "never run"
+		}
+		fmt.Printf("%-24s %-24s %-30q %s\n", tc.Name, strings.Join(expects, ", "), tc.Input, last)
+	}
+	return nil
+}
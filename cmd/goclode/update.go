@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/update"
+)
+
+const updateUsage = `Usage:
+  goclode self-update [--check] [--force]
+                                          Download the latest GitHub release for this system, check
+                                          it against the release's checksums.txt, and replace this binary
+
+--check only says whether a newer version is out. --force installs the latest
+release even when it is not newer, e.g. over a dev build. GITHUB_TOKEN, when
+set, lifts GitHub's rate limit.
+
+With update_check on (/config update_check true), interactive sessions say when
+a newer version is out, checking at most once a day.
+`
+
+// updateCheckInterval is how long a startup check's answer is reused
+const updateCheckInterval = 24 * time.Hour
+
+// runSelfUpdate runs "goclode self-update" and returns the exit code
+func runSelfUpdate(args []string) int {
+	fs := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, updateUsage) }
+	check := fs.Bool("check", false, "Only say whether a newer version is out")
+	force := fs.Bool("force", false, "Install the latest release even if it is not newer")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	release, err := update.Latest(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !*force && !update.Newer(version, release.Version()) {
+		fmt.Printf("GoClode v%s is up to date (latest: %s)\n", version, release.Tag)
+		return 0
+	}
+	if *check {
+		fmt.Printf("GoClode %s is available (you have v%s): %s\n", release.Tag, version, release.URL)
+		return 0
+	}
+
+	target, err := os.Executable()
+	if err == nil {
+		target, err = filepath.EvalSymlinks(target)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot find this binary: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Downloading GoClode %s...\n", release.Tag)
+	binary, err := update.Download(ctx, release)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := update.Install(binary, target); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Updated %s from v%s to %s\n", target, version, release.Tag)
+	return 0
+}
+
+// notifyUpdate says when a newer release is out, checking GitHub at most
+// once a day; a slow or failed check says nothing
+func notifyUpdate() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	release, err := update.LatestCached(ctx, filepath.Join(home, ".goclode", "update.json"), updateCheckInterval)
+	if err != nil {
+		core.Logger("update").Debug("Update check failed", "error", err)
+		return
+	}
+	if update.Newer(version, release.Version()) {
+		fmt.Printf("\033[33m⬆️  GoClode %s is available (you have v%s): goclode self-update\033[0m\n", release.Tag, version)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/session"
+	"github.com/hazyhaar/GoClode/internal/ui"
+)
+
+const usageUsage = `Usage:
+  goclode usage ` + ui.UsageHelp + `
+                                          Tokens and cost of the replies, by day, provider, model, or session
+                                          (default: the last 30 days, by day)
+
+Without --db the project database and the session databases older launches
+left in .goclode/ are added up. Costs come from
+"price_in" and "price_out" (USD per million tokens) in the provider's config.
+`
+
+// runUsage runs "goclode usage" and returns the exit code
+func runUsage(engine *core.Engine, dbPath string, args []string) int {
+	sources := []*session.Manager{session.NewManager(engine)}
+	if dbPath == "" {
+		// Launches before the project database each had their own
+		paths, _ := filepath.Glob(filepath.Join(".goclode", "session_*.db"))
+		for _, path := range paths {
+			e, err := core.NewEngine(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", path, err)
+				continue
+			}
+			defer e.Close()
+			sources = append(sources, session.NewManager(e))
+		}
+	}
+
+	if err := ui.ReportUsage(os.Stdout, args, sources); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(os.Stderr, usageUsage)
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,176 @@
+// Package core - Declarative assertions: invariants a test_assert hook
+// declares in its config and checks against every event it receives
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AssertionSpec is one invariant of a test_assert hook's config, either a
+// JSONPath comparison or an SQL expression over the payload:
+//
+//	{"assertions": [
+//	  {"name": "fast reply", "expr": "$.latency_ms < 5000"},
+//	  {"name": "answered", "sql": "json_extract(:payload, '$.tokens_out') > 0"}
+//	]}
+type AssertionSpec struct {
+	Name string `json:"name,omitempty"`
+	Expr string `json:"expr,omitempty"` // <JSONPath> <op> <JSON literal>
+	SQL  string `json:"sql,omitempty"`  // SQLite boolean expression; :payload is the event payload as JSON
+}
+
+// String is the expression the spec checks
+func (s AssertionSpec) String() string {
+	if s.SQL != "" {
+		return s.SQL
+	}
+	return s.Expr
+}
+
+var assertionExpr = regexp.MustCompile(`^\s*(\$(?:\.[A-Za-z_][A-Za-z0-9_]*|\[\d+\])*)\s*(==|!=|<=|>=|=|<|>|contains\b|exists\b)\s*(.*?)\s*$`)
+
+// assertionOps turn an expr operator into SQL comparing the value of the
+// payload ?1 at path ?2 with the literal ?3
+var assertionOps = map[string]string{
+	"==":       "json_extract(?1, ?2) = ?3",
+	"=":        "json_extract(?1, ?2) = ?3",
+	"!=":       "json_extract(?1, ?2) != ?3",
+	"<":        "json_extract(?1, ?2) < ?3",
+	"<=":       "json_extract(?1, ?2) <= ?3",
+	">":        "json_extract(?1, ?2) > ?3",
+	">=":       "json_extract(?1, ?2) >= ?3",
+	"contains": "instr(json_extract(?1, ?2), ?3) > 0",
+	"exists":   "json_type(?1, ?2) IS NOT NULL",
+}
+
+// hookAssertions reads the assertions declared in a hook's config
+func hookAssertions(h *Hook) ([]AssertionSpec, error) {
+	if h == nil || h.Config["assertions"] == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(h.Config["assertions"])
+	if err != nil {
+		return nil, err
+	}
+	var specs []AssertionSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("hook %s: assertions: %w", h.ID, err)
+	}
+	for i := range specs {
+		if specs[i].Name == "" {
+			specs[i].Name = specs[i].String()
+		}
+	}
+	return specs, nil
+}
+
+// assertionQuery builds the query checking a spec: it selects whether the
+// assertion holds and, for expr, the value found at the path
+func assertionQuery(spec AssertionSpec, payload string) (string, []interface{}, error) {
+	switch {
+	case spec.SQL != "" && spec.Expr != "":
+		return "", nil, fmt.Errorf("give expr or sql, not both")
+	case spec.SQL != "":
+		if strings.Contains(spec.SQL, ";") {
+			return "", nil, fmt.Errorf("sql must be a single expression")
+		}
+		return `SELECT (` + spec.SQL + `), ''`, []interface{}{sql.Named("payload", payload)}, nil
+	case spec.Expr != "":
+		m := assertionExpr.FindStringSubmatch(spec.Expr)
+		if m == nil {
+			return "", nil, fmt.Errorf("cannot parse %q (e.g. $.latency_ms < 5000)", spec.Expr)
+		}
+		path, op, literal := m[1], m[2], m[3]
+		args := []interface{}{payload, path}
+		switch {
+		case op == "exists":
+			if literal != "" {
+				return "", nil, fmt.Errorf("exists takes no value")
+			}
+		case literal == "":
+			return "", nil, fmt.Errorf("%s needs a value", op)
+		default:
+			var value interface{}
+			if err := json.Unmarshal([]byte(literal), &value); err != nil {
+				value = literal // A bare word is a string
+			}
+			if b, ok := value.(bool); ok {
+				value = 0
+				if b {
+					value = 1 // SQLite's JSON booleans
+				}
+			}
+			args = append(args, value)
+		}
+		return `SELECT ` + assertionOps[op] + `, COALESCE(CAST(json_extract(?1, ?2) AS TEXT), 'null')`, args, nil
+	}
+	return "", nil, fmt.Errorf("an assertion needs expr or sql")
+}
+
+// evalAssertion checks a spec against a JSON payload
+func (mm *ModuleManager) evalAssertion(spec AssertionSpec, payload string) (passed bool, actual string, err error) {
+	query, args, err := assertionQuery(spec, payload)
+	if err != nil {
+		return false, "", err
+	}
+	var holds sql.NullInt64
+	if err := mm.engine.QueryRow(query, args...).Scan(&holds, &actual); err != nil {
+		return false, "", err
+	}
+	return holds.Valid && holds.Int64 != 0, actual, nil
+}
+
+// handleTestAssertHook checks the invariants declared in the hook's config
+// against the event payload, returning an error when one does not hold; in
+// debug mode each check is recorded on the trace. A hook without declared
+// assertions checks the one the payload describes.
+func (mm *ModuleManager) handleTestAssertHook(ctx *HookContext) error {
+	specs, err := hookAssertions(ctx.Hook)
+	if err != nil {
+		return err
+	}
+	if len(specs) == 0 {
+		return handleTestAssert(ctx)
+	}
+
+	payload, err := json.Marshal(ctx.Payload)
+	if err != nil {
+		return fmt.Errorf("encode payload: %w", err)
+	}
+	failures := make([]string, 0)
+	for _, spec := range specs {
+		passed, actual, err := mm.evalAssertion(spec, string(payload))
+		assertion := DebugAssertion{
+			ID:        uuid.New().String(),
+			Timestamp: time.Now(),
+			Name:      spec.Name,
+			Expected:  spec.String(),
+			Actual:    actual,
+			Passed:    passed,
+		}
+		switch {
+		case err != nil:
+			assertion.Message = fmt.Sprintf("Invalid assertion: %v", err)
+		case !passed:
+			assertion.Message = fmt.Sprintf("Assertion failed: %s (got %s)", spec, actual)
+		}
+		if !assertion.Passed {
+			failures = append(failures, spec.Name+": "+assertion.Message)
+		}
+		if ctx.Debug != nil {
+			assertion.TraceID = ctx.Debug.TraceID
+			ctx.Debug.Assertions = append(ctx.Debug.Assertions, assertion)
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d assertions failed: %s", len(failures), len(specs), strings.Join(failures, "; "))
+	}
+	return nil
+}
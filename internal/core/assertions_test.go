@@ -0,0 +1,71 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeclarativeAssertions(t *testing.T) {
+	engine, err := NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	defer engine.Close()
+	mm := NewModuleManager(engine)
+
+	payload := `{"latency_ms": 7200, "tokens_out": 12, "model": "zai-glm-4.6", "cached": false}`
+	tests := []struct {
+		spec AssertionSpec
+		want bool
+	}{
+		{AssertionSpec{Expr: "$.latency_ms < 5000"}, false},
+		{AssertionSpec{Expr: "$.latency_ms >= 7200"}, true},
+		{AssertionSpec{Expr: `$.model == "zai-glm-4.6"`}, true},
+		{AssertionSpec{Expr: "$.model contains glm"}, true},
+		{AssertionSpec{Expr: "$.cached == false"}, true},
+		{AssertionSpec{Expr: "$.error exists"}, false},
+		{AssertionSpec{SQL: "json_extract(:payload, '$.tokens_out') > 0"}, true},
+	}
+	for _, tt := range tests {
+		got, _, err := mm.evalAssertion(tt.spec, payload)
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+		} else if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+	for _, bad := range []AssertionSpec{{Expr: "latency < 5"}, {Expr: "$.a <"}, {SQL: "1; DROP TABLE config"}, {}} {
+		if _, _, err := mm.evalAssertion(bad, payload); err == nil {
+			t.Errorf("%+v: expected an error", bad)
+		}
+	}
+
+	// A test_assert hook checks its declared invariants on every event
+	if err := mm.RegisterModule(&Module{ID: "checks", Name: "Checks", Version: "1.0.0", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	mm.RegisterHook(&Hook{
+		ID: "slow_replies", ModuleID: "checks", Event: "chat_complete", Handler: "test_assert", Priority: 10, Enabled: true,
+		Config: map[string]interface{}{"assertions": []interface{}{
+			map[string]interface{}{"name": "fast reply", "expr": "$.latency_ms < 5000"},
+		}},
+	})
+	handler, _ := mm.handler("test_assert")
+	hooks := mm.hooks["chat_complete"]
+	if len(hooks) != 1 {
+		t.Fatalf("hooks = %+v", hooks)
+	}
+	ctx := &HookContext{
+		Event:   "chat_complete",
+		Payload: map[string]interface{}{"latency_ms": 7200},
+		Debug:   &DebugContext{TraceID: "t1"},
+		Hook:    hooks[0],
+	}
+	if err := handler(ctx); err == nil || !strings.Contains(err.Error(), "fast reply") {
+		t.Errorf("handler() = %v, want the fast reply assertion to fail", err)
+	}
+	if len(ctx.Debug.Assertions) != 1 || ctx.Debug.Assertions[0].Passed || ctx.Debug.Assertions[0].Actual != "7200" {
+		t.Errorf("assertions = %+v, want one failed with the latency", ctx.Debug.Assertions)
+	}
+}
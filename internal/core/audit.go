@@ -0,0 +1,61 @@
+// Package core - Audit log: security events, such as secrets redacted
+// from requests, kept in the audit_log table
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditEntry is an event of the audit log
+type AuditEntry struct {
+	ID        int64
+	SessionID string
+	Event     string
+	Detail    map[string]interface{}
+	CreatedAt time.Time
+}
+
+// Audit records an event in the audit log. Details must never hold the
+// secrets themselves.
+func (e *Engine) Audit(sessionID, event string, detail map[string]interface{}) error {
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return fmt.Errorf("audit %s: %w", event, err)
+	}
+	_, err = e.db.Exec(`INSERT INTO audit_log (session_id, event, detail) VALUES (?, ?, ?)`, sessionID, event, string(data))
+	if err != nil {
+		return fmt.Errorf("audit %s: %w", event, err)
+	}
+	Logger("audit").Info("Audit event", "event", event, "session", sessionID)
+	return nil
+}
+
+// AuditLog returns the latest audit events, newest first
+func (e *Engine) AuditLog(limit int) ([]AuditEntry, error) {
+	rows, err := e.db.Query(`
+		SELECT id, COALESCE(session_id, ''), event, COALESCE(detail, '{}'), created_at
+		FROM audit_log ORDER BY id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("read audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var a AuditEntry
+		var detail string
+		var created int64
+		if err := rows.Scan(&a.ID, &a.SessionID, &a.Event, &detail, &created); err != nil {
+			return nil, err
+		}
+		a.CreatedAt = time.Unix(created, 0)
+		if err := json.Unmarshal([]byte(detail), &a.Detail); err != nil {
+			a.Detail = map[string]interface{}{"raw": detail}
+		}
+		entries = append(entries, a)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	engine, err := NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	defer engine.Close()
+
+	if err := engine.Audit("s1", "secret_redacted", map[string]interface{}{"provider": "cerebras"}); err != nil {
+		t.Fatalf("Audit: %v", err)
+	}
+	if err := engine.Audit("", "other", nil); err != nil {
+		t.Fatalf("Audit: %v", err)
+	}
+
+	entries, err := engine.AuditLog(10)
+	if err != nil {
+		t.Fatalf("AuditLog: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Event != "other" || entries[1].Event != "secret_redacted" {
+		t.Errorf("Expected newest first, got %s then %s", entries[0].Event, entries[1].Event)
+	}
+	if entries[1].SessionID != "s1" || entries[1].Detail["provider"] != "cerebras" {
+		t.Errorf("Unexpected entry %+v", entries[1])
+	}
+
+	entries, _ = engine.AuditLog(1)
+	if len(entries) != 1 {
+		t.Errorf("Expected the limit to apply, got %d entries", len(entries))
+	}
+}
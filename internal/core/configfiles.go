@@ -0,0 +1,420 @@
+// Package core - Config files layered over the database: global
+// (~/.config/goclode/config), the repository's .goclode/config, and the
+// .goclode/config of each subdirectory down to the working directory, then
+// the file of the --listen client being served
+package core
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config sources besides the layers
+const (
+	ConfigSourceDefault  = "default"  // The database's default
+	ConfigSourceDatabase = "database" // Set with /config, which wins over the files
+)
+
+// globalLayer is the user's own config file, trusted like /config
+const globalLayer = "global"
+
+// repoConfigKeys are the keys a repository's config files may set: they
+// shape how GoClode works in the project without running programs, sending
+// data elsewhere, spending more, or turning a safeguard off. Every other
+// key, including ones added later, is only read from the global file and
+// /config, since a cloned repository could set it.
+var repoConfigKeys = map[string]bool{
+	"temperature": true, "auto_commit": true, "amend_commits": true, "commit_trailers": true, "auto_stash": true,
+	"stage_changes": true, "stream_output": true, "type_ahead": true, "max_context_messages": true,
+	"repo_map_bytes": true, "rag_top_k": true, "command_timeout": true, "test_timeout": true, "lsp_timeout": true,
+	"learned_routing": true, "learning_scope": true, "learning_precedence": true, "style_preferences": true,
+	"intent_suggestions": true, "log_level": true, "log_format": true, "forge_type": true,
+}
+
+// TrustedConfigKey reports whether only the global config file and /config
+// may set key
+func TrustedConfigKey(key string) bool {
+	return !repoConfigKeys[key]
+}
+
+// ConfigLayer is a config file. Files are key = value lines; # starts a
+// comment, and a value may be a quoted Go string.
+type ConfigLayer struct {
+	Name    string // global, repo, the subdirectory, or user:<client>
+	Path    string
+	Values  map[string]string // nil if the file does not exist or load
+	Err     error             // Why the file did not load
+	Ignored []string          // Trusted keys the file set, which only the global file may
+	modTime time.Time         // 0 if the file does not exist
+}
+
+// ConfigEntry is a setting's effective value and where it comes from
+type ConfigEntry struct {
+	Key         string
+	Value       string
+	Source      string // ConfigSourceDefault, ConfigSourceDatabase, or a layer name
+	Description string
+}
+
+// GlobalConfigPath is the user's config file: $XDG_CONFIG_HOME/goclode/config,
+// by default ~/.config/goclode/config
+func GlobalConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "goclode", "config")
+}
+
+// ConfigLayers lists the config files that apply in dir, lowest precedence
+// first: global, then the repository root (the nearest parent with .git),
+// then each subdirectory between the root and dir. Files that do not exist
+// are listed too, so creating one takes effect.
+func ConfigLayers(dir string) ([]ConfigLayer, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	root := RepoRoot(dir)
+
+	layers := make([]ConfigLayer, 0)
+	if path := GlobalConfigPath(); path != "" {
+		layers = append(layers, ConfigLayer{Name: globalLayer, Path: path})
+	}
+	layers = append(layers, ConfigLayer{Name: "repo", Path: filepath.Join(root, ".goclode", "config")})
+	if rel, err := filepath.Rel(root, dir); err == nil && rel != "." {
+		sub := root
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			sub = filepath.Join(sub, part)
+			name, _ := filepath.Rel(root, sub)
+			layers = append(layers, ConfigLayer{Name: filepath.ToSlash(name), Path: filepath.Join(sub, ".goclode", "config")})
+		}
+	}
+	return layers, nil
+}
+
+// RepoRoot returns the nearest of dir and its parents with a .git, or dir
+// outside a repository
+func RepoRoot(dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if filepath.Dir(d) == d {
+			return dir
+		}
+	}
+}
+
+// ReadConfigFile reads a config file's settings
+func ReadConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%s:%d: want key = value", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			value = unquoted
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+// LoadConfigFiles layers the config files that apply in dir over the
+// database, and reloads them when they change. A file that does not parse
+// is skipped and reported.
+func (e *Engine) LoadConfigFiles(dir string) error {
+	layers, err := ConfigLayers(dir)
+	if err != nil {
+		return err
+	}
+	errs := e.readLayers(layers)
+	e.layersMu.Lock()
+	e.layers = layers
+	e.layersMu.Unlock()
+	if len(errs) > 0 {
+		return fmt.Errorf("config files: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// readLayers reads each layer's file, reporting the ones that fail and
+// the trusted keys the repository's files set
+func (e *Engine) readLayers(layers []ConfigLayer) []string {
+	errs := make([]string, 0)
+	for i := range layers {
+		l := &layers[i]
+		l.Values, l.Err, l.Ignored, l.modTime = nil, nil, nil, time.Time{}
+		info, err := os.Stat(l.Path)
+		if err != nil {
+			continue
+		}
+		l.modTime = info.ModTime()
+		if l.Values, l.Err = ReadConfigFile(l.Path); l.Err != nil {
+			errs = append(errs, l.Err.Error())
+			continue
+		}
+		if l.Name == globalLayer {
+			continue
+		}
+		for key := range l.Values {
+			if TrustedConfigKey(key) {
+				delete(l.Values, key)
+				l.Ignored = append(l.Ignored, key)
+			}
+		}
+		if len(l.Ignored) > 0 {
+			sort.Strings(l.Ignored)
+			errs = append(errs, fmt.Sprintf("%s: %s ignored (only %s or /config may set them)",
+				l.Path, strings.Join(l.Ignored, ", "), GlobalConfigPath()))
+		}
+	}
+	return errs
+}
+
+// reloadConfigFiles rereads the layers if a file was created, changed, or
+// removed, and reports whether it did
+func (e *Engine) reloadConfigFiles() bool {
+	e.layersMu.RLock()
+	layers := append([]ConfigLayer(nil), e.layers...)
+	e.layersMu.RUnlock()
+
+	changed := false
+	for _, l := range layers {
+		var modTime time.Time
+		if info, err := os.Stat(l.Path); err == nil {
+			modTime = info.ModTime()
+		}
+		if !modTime.Equal(l.modTime) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return false
+	}
+	for _, err := range e.readLayers(layers) {
+		Logger("core").Warn("Config file not loaded", "error", err)
+	}
+	e.layersMu.Lock()
+	e.layers = layers
+	e.layersMu.Unlock()
+	return true
+}
+
+// userLayer prefixes the name of a --listen client's layer
+const userLayer = "user:"
+
+// SetUserConfig layers the config file of the --listen client name over the
+// others while GoClode serves it; an empty name removes it. Like the
+// repository's files, it may not set trusted keys.
+func (e *Engine) SetUserConfig(name, path string) error {
+	var layer []ConfigLayer
+	var errs []string
+	if name != "" {
+		layer = []ConfigLayer{{Name: userLayer + name, Path: path}}
+		errs = e.readLayers(layer)
+	}
+
+	e.layersMu.Lock()
+	layers := make([]ConfigLayer, 0, len(e.layers)+1)
+	for _, l := range e.layers {
+		if !strings.HasPrefix(l.Name, userLayer) {
+			layers = append(layers, l)
+		}
+	}
+	e.layers = append(layers, layer...)
+	e.layersMu.Unlock()
+	if len(errs) > 0 {
+		return fmt.Errorf("config files: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ConfigLayers returns the loaded config files, lowest precedence first
+func (e *Engine) ConfigLayers() []ConfigLayer {
+	e.layersMu.RLock()
+	defer e.layersMu.RUnlock()
+	return append([]ConfigLayer(nil), e.layers...)
+}
+
+// layerValue returns a key's value in the highest layer that sets it
+func (e *Engine) layerValue(key string) (string, string, bool) {
+	e.layersMu.RLock()
+	defer e.layersMu.RUnlock()
+	for i := len(e.layers) - 1; i >= 0; i-- {
+		if value, ok := e.layers[i].Values[key]; ok {
+			return value, e.layers[i].Name, true
+		}
+	}
+	return "", "", false
+}
+
+// GetConfigSource returns a setting's effective value and its source:
+// a value set with /config, else the nearest file, else the default
+func (e *Engine) GetConfigSource(key string) (string, string, error) {
+	var value, source string
+	err := e.db.QueryRow("SELECT value, COALESCE(source, '') FROM config WHERE key = ?", key).Scan(&value, &source)
+	if err != nil && err != sql.ErrNoRows {
+		return "", "", err
+	}
+	if source == ConfigSourceDatabase {
+		return value, source, nil
+	}
+	if v, layer, ok := e.layerValue(key); ok {
+		return v, layer, nil
+	}
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	return value, ConfigSourceDefault, nil
+}
+
+// ConfigEntries returns every setting, from the database or a file, with
+// its effective value and source, by key
+func (e *Engine) ConfigEntries() ([]ConfigEntry, error) {
+	rows, err := e.db.Query("SELECT key, COALESCE(description, '') FROM config ORDER BY key")
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	descriptions := make(map[string]string)
+	for rows.Next() {
+		var key, description string
+		if err := rows.Scan(&key, &description); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keys = append(keys, key)
+		descriptions[key] = description
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, l := range e.ConfigLayers() {
+		for key := range l.Values {
+			if _, ok := descriptions[key]; !ok {
+				descriptions[key] = ""
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	entries := make([]ConfigEntry, 0, len(keys))
+	for _, key := range keys {
+		value, source, err := e.GetConfigSource(key)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, ConfigEntry{Key: key, Value: value, Source: source, Description: descriptions[key]})
+	}
+	return entries, nil
+}
+
+// UnsetConfig drops a value set with /config, so the files or the default
+// apply again; keys without a default are removed
+func (e *Engine) UnsetConfig(key string) error {
+	_, err := e.db.Exec(`
+		UPDATE config SET value = COALESCE(default_value, value), source = ? WHERE key = ?
+	`, ConfigSourceDefault, key)
+	if err != nil {
+		return err
+	}
+	_, err = e.db.Exec("DELETE FROM config WHERE key = ? AND default_value IS NULL", key)
+	return err
+}
+
+// WriteConfigFile sets keys in a config file, keeping its other lines and
+// comments; keys it does not have yet are appended
+func WriteConfigFile(path string, values map[string]string) error {
+	lines := make(map[string]string, len(values))
+	for key, value := range values {
+		lines[key] = key + " = " + quoteValue(value)
+	}
+	return writeKeyLines(path, 0o644, lines)
+}
+
+// quoteValue quotes a value the file would otherwise read back differently
+func quoteValue(value string) string {
+	if value == "" || value != strings.TrimSpace(value) || strings.ContainsAny(value, "#\"'\n\r") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// writeKeyLines replaces the lines that set each key, in a config or
+// environment file, with the given line, and appends the keys the file
+// does not set, creating it if needed
+func writeKeyLines(path string, perm os.FileMode, lines map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	out := make([]string, 0)
+	written := make(map[string]bool)
+	if len(data) > 0 {
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			trimmed := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "export "))
+			key, _, ok := strings.Cut(trimmed, "=")
+			key = strings.TrimSpace(key)
+			if replacement, set := lines[key]; ok && set && !strings.HasPrefix(trimmed, "#") {
+				if written[key] {
+					continue
+				}
+				line = replacement
+				written[key] = true
+			}
+			out = append(out, line)
+		}
+	}
+	keys := make([]string, 0)
+	for key := range lines {
+		if !written[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		out = append(out, lines[key])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(out, "\n")+"\n"), perm)
+}
@@ -0,0 +1,200 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte("# Team defaults\nauto_commit = false\ntemperature=0.2 # cooler\nsystem_prompt = \"Be brief.\\nUse Go.\"\n\n"), 0o644)
+
+	values, err := ReadConfigFile(path)
+	if err != nil {
+		t.Fatalf("ReadConfigFile: %v", err)
+	}
+	want := map[string]string{"auto_commit": "false", "temperature": "0.2", "system_prompt": "Be brief.\nUse Go."}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("ReadConfigFile = %v, want %v", values, want)
+	}
+
+	os.WriteFile(path, []byte("auto commit\n"), 0o644)
+	if _, err := ReadConfigFile(path); err == nil {
+		t.Error("Expected a line without = to fail")
+	}
+}
+
+func TestConfigLayers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0o755)
+	sub := filepath.Join(repo, "services", "api")
+	os.MkdirAll(sub, 0o755)
+
+	write := func(dir, content string) {
+		os.MkdirAll(dir, 0o755)
+		os.WriteFile(filepath.Join(dir, "config"), []byte(content), 0o644)
+	}
+	write(filepath.Join(home, "goclode"), "temperature = 0.1\nauto_commit = false\n")
+	write(filepath.Join(repo, ".goclode"), "temperature = 0.3\n")
+	write(filepath.Join(sub, ".goclode"), "test_timeout = 900\n")
+
+	layers, err := ConfigLayers(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, l := range layers {
+		names = append(names, l.Name)
+	}
+	if want := []string{"global", "repo", "services", "services/api"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Layers %v, want %v", names, want)
+	}
+
+	engine, err := NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	if err := engine.LoadConfigFiles(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(key, wantValue, wantSource string) {
+		t.Helper()
+		value, source, err := engine.GetConfigSource(key)
+		if err != nil || value != wantValue || source != wantSource {
+			t.Errorf("%s = %q from %q (%v), want %q from %q", key, value, source, err, wantValue, wantSource)
+		}
+	}
+	check("temperature", "0.3", "repo")
+	check("auto_commit", "false", "global")
+	check("test_timeout", "900", "services/api")
+	check("max_context_messages", "20", ConfigSourceDefault)
+	check("no_such_key", "", "")
+
+	engine.SetConfig("temperature", "0.9")
+	check("temperature", "0.9", ConfigSourceDatabase)
+	if err := engine.UnsetConfig("temperature"); err != nil {
+		t.Fatal(err)
+	}
+	check("temperature", "0.3", "repo")
+	os.Remove(filepath.Join(repo, ".goclode", "config"))
+	engine.reloadConfigFiles()
+	check("temperature", "0.1", "global")
+
+	engine.SetConfig("custom_key", "x")
+	engine.UnsetConfig("custom_key")
+	check("custom_key", "", "")
+
+	write(filepath.Join(repo, ".goclode"), "temperature = 0.5\n")
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(repo, ".goclode", "config"), future, future)
+	if !engine.reloadConfigFiles() {
+		t.Error("Expected a created file to reload the layers")
+	}
+	check("temperature", "0.5", "repo")
+
+	entries, err := engine.ConfigEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Key == "test_timeout" && (e.Value != "900" || e.Source != "services/api" || e.Description == "") {
+			t.Errorf("Unexpected entry %+v", e)
+		}
+	}
+}
+
+func TestConfigLayersTrustedKeys(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0o755)
+	os.MkdirAll(filepath.Join(home, "goclode"), 0o755)
+	os.WriteFile(filepath.Join(home, "goclode", "config"), []byte("test_command = make check\n"), 0o644)
+	os.MkdirAll(filepath.Join(repo, ".goclode"), 0o755)
+	os.WriteFile(filepath.Join(repo, ".goclode", "config"), []byte("command_allowlist = [\"*\"]\ntest_command = ./pwn.sh\nbudget_daily = 0\ntemperature = 0.3\n"), 0o644)
+
+	engine, err := NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	err = engine.LoadConfigFiles(repo)
+	if err == nil || !strings.Contains(err.Error(), "budget_daily, command_allowlist, test_command ignored") {
+		t.Errorf("LoadConfigFiles = %v, want the ignored keys reported", err)
+	}
+
+	for key, want := range map[string]string{"test_command": "global", "command_allowlist": ConfigSourceDefault, "budget_daily": ConfigSourceDefault, "temperature": "repo"} {
+		if _, source, _ := engine.GetConfigSource(key); source != want {
+			t.Errorf("%s from %q, want %q", key, source, want)
+		}
+	}
+}
+
+func TestWriteConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goclode", "config")
+	if err := WriteConfigFile(path, map[string]string{"auto_commit": "false"}); err != nil {
+		t.Fatalf("WriteConfigFile: %v", err)
+	}
+	os.WriteFile(path, []byte("# Mine\nauto_commit = false\ntemperature = 0.2\n"), 0o644)
+
+	err := WriteConfigFile(path, map[string]string{"auto_commit": "true", "default_model": "", "system_prompt": "Be brief. # not a comment"})
+	if err != nil {
+		t.Fatalf("WriteConfigFile: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "# Mine\nauto_commit = true\ntemperature = 0.2\n") {
+		t.Errorf("Expected the comment and other keys kept in place, got:\n%s", data)
+	}
+	values, err := ReadConfigFile(path)
+	if err != nil {
+		t.Fatalf("ReadConfigFile: %v", err)
+	}
+	want := map[string]string{"auto_commit": "true", "temperature": "0.2", "default_model": "", "system_prompt": "Be brief. # not a comment"}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Read back %v, want %v", values, want)
+	}
+}
+
+func TestSetUserConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0o755)
+	os.MkdirAll(filepath.Join(repo, ".goclode"), 0o755)
+	os.WriteFile(filepath.Join(repo, ".goclode", "config"), []byte("temperature = 0.3\n"), 0o644)
+	alice := filepath.Join(t.TempDir(), "alice.config")
+	os.WriteFile(alice, []byte("temperature = 0.9\nbudget_daily = 0\n"), 0o644)
+
+	engine, err := NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	engine.LoadConfigFiles(repo)
+
+	if err := engine.SetUserConfig("alice", alice); err == nil || !strings.Contains(err.Error(), "budget_daily ignored") {
+		t.Errorf("SetUserConfig = %v, want budget_daily ignored", err)
+	}
+	if value, source, _ := engine.GetConfigSource("temperature"); value != "0.9" || source != "user:alice" {
+		t.Errorf("temperature = %q from %q, want alice's", value, source)
+	}
+	if _, source, _ := engine.GetConfigSource("budget_daily"); source != ConfigSourceDefault {
+		t.Errorf("budget_daily from %q, want the default", source)
+	}
+
+	engine.SetUserConfig("bob", filepath.Join(t.TempDir(), "missing.config"))
+	if value, source, _ := engine.GetConfigSource("temperature"); value != "0.3" || source != "repo" {
+		t.Errorf("temperature for bob = %q from %q, want the repository's", value, source)
+	}
+	engine.SetUserConfig("", "")
+	if layers := engine.ConfigLayers(); layers[len(layers)-1].Name != "repo" {
+		t.Errorf("Expected no user layer left, got %v", layers[len(layers)-1].Name)
+	}
+}
@@ -0,0 +1,88 @@
+// Package core - Cron hooks: module handlers run on a schedule instead of
+// on an event
+package core
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CronEvent is the event of scheduled hooks. Their config "every" holds the
+// interval as a duration ("24h"); without one they run daily.
+const CronEvent = "cron"
+
+const defaultCronInterval = 24 * time.Hour
+
+// cronInterval reads a cron hook's interval
+func cronInterval(h *Hook) time.Duration {
+	if every, ok := h.Config["every"].(string); ok {
+		if d, err := time.ParseDuration(every); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultCronInterval
+}
+
+// RunDueCron runs the cron hooks whose interval has passed since their last
+// run and returns how many ran
+func (mm *ModuleManager) RunDueCron(now time.Time) (int, error) {
+	mm.mu.RLock()
+	hooks := mm.hooks[CronEvent]
+	mm.mu.RUnlock()
+
+	ran := 0
+	for _, hook := range hooks {
+		var lastRun int64
+		err := mm.engine.QueryRow(`SELECT last_run_at FROM cron_runs WHERE hook_id = ?`, hook.ID).Scan(&lastRun)
+		if err != nil && err != sql.ErrNoRows {
+			return ran, fmt.Errorf("read cron runs: %w", err)
+		}
+		if err == nil && now.Sub(time.Unix(lastRun, 0)) < cronInterval(hook) {
+			continue
+		}
+
+		handler, ok := mm.handler(hook.Handler)
+		if !ok {
+			continue
+		}
+		if err := handler(&HookContext{Event: CronEvent, Payload: hook.Config, Timestamp: now}); err != nil {
+			return ran, fmt.Errorf("cron %s: %w", hook.Handler, err)
+		}
+		ran++
+
+		if _, err := mm.engine.Exec(`
+			INSERT INTO cron_runs (hook_id, last_run_at) VALUES (?, ?)
+			ON CONFLICT(hook_id) DO UPDATE SET last_run_at = excluded.last_run_at
+		`, hook.ID, now.Unix()); err != nil {
+			return ran, fmt.Errorf("record cron run: %w", err)
+		}
+	}
+	return ran, nil
+}
+
+// RunCron runs due cron hooks now and then every tick until ctx is done
+func (mm *ModuleManager) RunCron(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		if _, err := mm.RunDueCron(time.Now()); err != nil {
+			mm.logDebug(DebugEvent{
+				ID:        uuid.New().String(),
+				Timestamp: time.Now(),
+				Level:     "error",
+				Event:     CronEvent,
+				Message:   err.Error(),
+			})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunDueCron(t *testing.T) {
+	engine, err := NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	defer engine.Close()
+
+	mm := NewModuleManager(engine)
+	if err := mm.RegisterModule(&Module{ID: "test", Name: "Test", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	mm.RegisterHandler("count", func(ctx *HookContext) error {
+		calls++
+		return nil
+	})
+	if err := mm.RegisterHook(&Hook{
+		ID:       "count_hourly",
+		ModuleID: "test",
+		Event:    CronEvent,
+		Handler:  "count",
+		Enabled:  true,
+		Config:   map[string]interface{}{"every": "1h"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	steps := []struct {
+		at      time.Duration
+		wantRan int
+	}{
+		{0, 1},                // never ran
+		{30 * time.Minute, 0}, // within the interval
+		{61 * time.Minute, 1},
+	}
+	for _, s := range steps {
+		ran, err := mm.RunDueCron(start.Add(s.at))
+		if err != nil {
+			t.Fatalf("RunDueCron at +%v failed: %v", s.at, err)
+		}
+		if ran != s.wantRan {
+			t.Errorf("RunDueCron at +%v ran %d hooks, want %d", s.at, ran, s.wantRan)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
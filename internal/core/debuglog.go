@@ -0,0 +1,103 @@
+// Package core - Where debug events go: the in-memory ring, the
+// debug_events table, and an optional JSONL file rotated by size
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// defaultDebugLogSize is the ring size when debug_log_size is unset
+const defaultDebugLogSize = 1000
+
+// debugLogConfig is how debug events are kept, from the debug_log_* settings
+type debugLogConfig struct {
+	size     int    // Events kept in memory
+	db       bool   // Store events in debug_events
+	file     string // JSONL file, "" for none
+	maxBytes int64  // Rotate the file past this size
+	keep     int    // Rotated files kept
+}
+
+// loadDebugLogConfig reads the debug_log_* settings
+func loadDebugLogConfig(e *Engine) debugLogConfig {
+	cfg := debugLogConfig{
+		size:     e.GetConfigInt("debug_log_size"),
+		db:       e.GetConfigBool("debug_log_db"),
+		maxBytes: int64(e.GetConfigInt("debug_log_max_mb")) << 20,
+		keep:     e.GetConfigInt("debug_log_keep"),
+	}
+	cfg.file, _ = e.GetConfig("debug_log_file")
+	if cfg.size <= 0 {
+		cfg.size = defaultDebugLogSize
+	}
+	return cfg
+}
+
+// jsonlSink appends debug events to a file, one JSON object per line,
+// rotating it to <file>.1 ... <file>.<keep> once it reaches maxBytes
+type jsonlSink struct {
+	path     string
+	maxBytes int64
+	keep     int
+	f        *os.File
+	size     int64
+}
+
+// write appends an event, opening or rotating the file as needed
+func (s *jsonlSink) write(event DebugEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if s.f != nil && s.maxBytes > 0 && s.size+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if s.f == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+			return fmt.Errorf("debug log: %w", err)
+		}
+		f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("debug log: %w", err)
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("debug log: %w", err)
+		}
+		s.f, s.size = f, info.Size()
+	}
+
+	n, err := s.f.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// rotate shifts the rotated files up by one and moves the current file to
+// <file>.1, dropping the oldest; with keep 0 the file is just truncated
+func (s *jsonlSink) rotate() error {
+	s.close()
+	if s.keep <= 0 {
+		return os.Remove(s.path)
+	}
+	os.Remove(fmt.Sprintf("%s.%d", s.path, s.keep))
+	for i := s.keep - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	return os.Rename(s.path, s.path+".1")
+}
+
+// close closes the file; the next write reopens it
+func (s *jsonlSink) close() {
+	if s.f != nil {
+		s.f.Close()
+		s.f = nil
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugLogSinks(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := NewEngine(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	defer engine.Close()
+
+	logFile := filepath.Join(dir, "logs", "debug.jsonl")
+	engine.SetConfig("debug_log_size", "3")
+	engine.SetConfig("debug_log_file", logFile)
+	engine.SetConfig("debug_log_keep", "2")
+	mm := NewModuleManager(engine)
+	defer mm.Close()
+	mm.configureDebugLog()
+	mm.debugFile.maxBytes = 600 // A few events per file
+	mm.EnableDebug()
+
+	for i := 0; i < 10; i++ {
+		mm.logDebug(DebugEvent{
+			ID:        fmt.Sprintf("e%d", i),
+			TraceID:   "t1",
+			Timestamp: time.Now(),
+			Level:     "debug",
+			Event:     "chat_complete",
+			Message:   strings.Repeat("x", 100),
+		})
+	}
+
+	log := mm.GetDebugLog()
+	if len(log) != 3 || log[0].ID != "e7" || log[2].ID != "e9" {
+		t.Errorf("ring = %d events starting at %v, want e7..e9", len(log), log)
+	}
+
+	var n int
+	engine.QueryRow(`SELECT COUNT(*) FROM debug_events WHERE trace_id = 't1'`).Scan(&n)
+	if n != 10 {
+		t.Errorf("debug_events has %d rows, want 10", n)
+	}
+
+	current, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(current), `"id":"e9"`) {
+		t.Errorf("current log file misses the last event:\n%s", current)
+	}
+	if _, err := os.Stat(logFile + ".1"); err != nil {
+		t.Errorf("no rotated file: %v", err)
+	}
+	if _, err := os.Stat(logFile + ".3"); !os.IsNotExist(err) {
+		t.Errorf("kept more than debug_log_keep rotated files")
+	}
+}
@@ -0,0 +1,87 @@
+// Package core - Live views of the module manager for the debug server:
+// subscribers to debug events and a snapshot of the hook registry
+package core
+
+import "sort"
+
+// debugSubscriberBuffer is how many events a slow subscriber may fall
+// behind before events are dropped for it
+const debugSubscriberBuffer = 256
+
+// SubscribeDebug streams the debug events logged from now on; cancel stops
+// the stream and closes the channel. Events are dropped, not waited for,
+// when the subscriber falls behind.
+func (mm *ModuleManager) SubscribeDebug() (events <-chan DebugEvent, cancel func()) {
+	ch := make(chan DebugEvent, debugSubscriberBuffer)
+	mm.debugMu.Lock()
+	if mm.debugSubs == nil {
+		mm.debugSubs = make(map[chan DebugEvent]struct{})
+	}
+	mm.debugSubs[ch] = struct{}{}
+	mm.debugMu.Unlock()
+
+	return ch, func() {
+		mm.debugMu.Lock()
+		defer mm.debugMu.Unlock()
+		if _, ok := mm.debugSubs[ch]; ok {
+			delete(mm.debugSubs, ch)
+			close(ch)
+		}
+	}
+}
+
+// publishDebug sends an event to the subscribers; mm.debugMu must be held
+func (mm *ModuleManager) publishDebug(event DebugEvent) {
+	for ch := range mm.debugSubs {
+		select {
+		case ch <- event:
+		default: // Subscriber behind
+		}
+	}
+}
+
+// HookInfo is a hook of the registry and whether its handler exists
+type HookInfo struct {
+	Hook
+	HandlerFound bool `json:"handler_found"`
+}
+
+// Modules returns the enabled modules, by priority
+func (mm *ModuleManager) Modules() []Module {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	modules := make([]Module, 0, len(mm.modules))
+	for _, m := range mm.modules {
+		modules = append(modules, *m)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		if modules[i].Priority != modules[j].Priority {
+			return modules[i].Priority < modules[j].Priority
+		}
+		return modules[i].ID < modules[j].ID
+	})
+	return modules
+}
+
+// Hooks returns the enabled hooks by event, then in the order they run
+func (mm *ModuleManager) Hooks() []HookInfo {
+	mm.mu.RLock()
+	hooks := make([]HookInfo, 0)
+	for _, list := range mm.hooks {
+		for _, h := range list {
+			hooks = append(hooks, HookInfo{Hook: *h})
+		}
+	}
+	mm.mu.RUnlock()
+
+	for i := range hooks {
+		_, hooks[i].HandlerFound = mm.handler(hooks[i].Handler)
+	}
+	sort.SliceStable(hooks, func(i, j int) bool {
+		if hooks[i].Event != hooks[j].Event {
+			return hooks[i].Event < hooks[j].Event
+		}
+		return hooks[i].Priority < hooks[j].Priority
+	})
+	return hooks
+}
@@ -0,0 +1,181 @@
+// Package core - Environment files: API keys and settings read from the
+// project's .env and ~/.goclode/env at startup
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// envKeyPattern matches a variable name
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// repoEnvNames are the variables a repository's .env may set besides
+// *_API_KEY: the ones GoClode reads. Git and the tools it
+// runs inherit the environment, so a cloned repository's GIT_SSH_COMMAND
+// or LD_PRELOAD would run code.
+var repoEnvNames = map[string]bool{
+	"GITHUB_TOKEN": true, "GITLAB_TOKEN": true, "BITBUCKET_TOKEN": true,
+	"SLACK_APP_TOKEN": true, "SLACK_BOT_TOKEN": true, "DISCORD_TOKEN": true,
+	"GITHUB_WEBHOOK_SECRET": true, "SENTRY_WEBHOOK_SECRET": true,
+	"SMTP_USERNAME": true, "SMTP_PASSWORD": true, "REPORT_SLACK_WEBHOOK": true,
+}
+
+// repoEnvAllowed reports whether a repository's .env may set key
+func repoEnvAllowed(key string) bool {
+	return repoEnvNames[key] || strings.HasSuffix(key, "_API_KEY")
+}
+
+// EnvFile is an environment file that was loaded
+type EnvFile struct {
+	Path    string
+	Set     []string // Variables it set
+	Skipped []string // Variables already set by the shell or a nearer file
+	Ignored []string // Variables the repository's .env may not set
+}
+
+// EnvFilePaths lists the environment files for dir, nearest first: the
+// repository's .env, then ~/.goclode/env
+func EnvFilePaths(dir string) []string {
+	paths := make([]string, 0, 2)
+	if abs, err := filepath.Abs(dir); err == nil {
+		paths = append(paths, filepath.Join(RepoRoot(abs), ".env"))
+	}
+	if path, err := UserEnvPath(); err == nil {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// ReadEnvFile reads KEY=value lines: export prefixes, # comments, and
+// single or double quotes are understood. Errors name the line, never the
+// value.
+func ReadEnvFile(path string) ([]string, map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	keys := make([]string, 0)
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envKeyPattern.MatchString(key) {
+			return nil, nil, fmt.Errorf("%s:%d: want KEY=value", path, n)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: %s has an unterminated or bad double-quoted value", path, n, key)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			if len(value) < 2 || !strings.HasSuffix(value, "'") {
+				return nil, nil, fmt.Errorf("%s:%d: %s has an unterminated single-quoted value", path, n, key)
+			}
+			value = value[1 : len(value)-1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		if _, seen := values[key]; !seen {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	return keys, values, scanner.Err()
+}
+
+// LoadEnvFiles sets the variables of the environment files for dir that
+// are not set yet: the shell wins over the project's .env, which wins over
+// ~/.goclode/env. The project's .env only sets the variables GoClode reads;
+// the others are reported. Values are never logged. A file that does not
+// parse is skipped and reported.
+func (e *Engine) LoadEnvFiles(dir string) error {
+	errs := make([]string, 0)
+	loaded := make([]EnvFile, 0)
+	userPath, _ := UserEnvPath()
+	for _, path := range EnvFilePaths(dir) {
+		keys, values, err := ReadEnvFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		file := EnvFile{Path: path, Set: make([]string, 0), Skipped: make([]string, 0)}
+		for _, key := range keys {
+			if path != userPath && !repoEnvAllowed(key) {
+				file.Ignored = append(file.Ignored, key)
+				continue
+			}
+			if _, set := os.LookupEnv(key); set {
+				file.Skipped = append(file.Skipped, key)
+				continue
+			}
+			os.Setenv(key, values[key])
+			file.Set = append(file.Set, key)
+		}
+		Logger("core").Debug("Loaded environment file", "path", path, "set", file.Set, "skipped", file.Skipped)
+		if len(file.Ignored) > 0 {
+			errs = append(errs, fmt.Sprintf("%s: %s not loaded (a repository's .env only sets API keys and GoClode's tokens; put others in %s)",
+				path, strings.Join(file.Ignored, ", "), userPath))
+		}
+		loaded = append(loaded, file)
+	}
+
+	e.mu.Lock()
+	e.envFiles = loaded
+	e.mu.Unlock()
+	if len(errs) > 0 {
+		return fmt.Errorf("environment files: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// EnvFiles returns the environment files LoadEnvFiles loaded
+func (e *Engine) EnvFiles() []EnvFile {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]EnvFile(nil), e.envFiles...)
+}
+
+// UserEnvPath is ~/.goclode/env, the environment file of every project
+func UserEnvPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".goclode", "env"), nil
+}
+
+// SetEnvFileValue sets a variable in an environment file, keeping its
+// other lines. The file is made readable by its owner only, since it holds
+// keys.
+func SetEnvFileValue(path, key, value string) error {
+	if !envKeyPattern.MatchString(key) {
+		return fmt.Errorf("bad variable name %q", key)
+	}
+	if err := writeKeyLines(path, 0o600, map[string]string{key: key + "=" + quoteValue(value)}); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0o600)
+}
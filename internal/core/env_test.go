@@ -0,0 +1,104 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("# Keys\nexport CEREBRAS_API_KEY=csk-123 # main\nOPENROUTER_API_KEY='sk #1'\nGREETING=\"a\\nb\"\nEMPTY=\n"), 0o644)
+
+	keys, values, err := ReadEnvFile(path)
+	if err != nil {
+		t.Fatalf("ReadEnvFile: %v", err)
+	}
+	if want := []string{"CEREBRAS_API_KEY", "OPENROUTER_API_KEY", "GREETING", "EMPTY"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Keys %v, want %v", keys, want)
+	}
+	want := map[string]string{"CEREBRAS_API_KEY": "csk-123", "OPENROUTER_API_KEY": "sk #1", "GREETING": "a\nb", "EMPTY": ""}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Values %v, want %v", values, want)
+	}
+
+	os.WriteFile(path, []byte("TOKEN=\"secret-value\n"), 0o644)
+	_, _, err = ReadEnvFile(path)
+	if err == nil || strings.Contains(err.Error(), "secret-value") {
+		t.Errorf("Expected an error that does not echo the value, got %v", err)
+	}
+}
+
+func TestLoadEnvFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0o755)
+	os.WriteFile(filepath.Join(repo, ".env"), []byte("TEST_A_API_KEY=project\nTEST_B_API_KEY=project\nGIT_SSH_COMMAND=./pwn.sh\nGOCLODE_TEST=project\n"), 0o644)
+	os.MkdirAll(filepath.Join(home, ".goclode"), 0o755)
+	os.WriteFile(filepath.Join(home, ".goclode", "env"), []byte("TEST_A_API_KEY=home\nTEST_C_API_KEY=home\n"), 0o644)
+	t.Setenv("TEST_B_API_KEY", "shell")
+	for _, key := range []string{"TEST_A_API_KEY", "TEST_C_API_KEY"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	engine, err := NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+	for _, key := range []string{"GIT_SSH_COMMAND", "GOCLODE_TEST"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	if err := engine.LoadEnvFiles(repo); err == nil || !strings.Contains(err.Error(), "GIT_SSH_COMMAND, GOCLODE_TEST not loaded") {
+		t.Errorf("LoadEnvFiles = %v, want GIT_SSH_COMMAND and GOCLODE_TEST reported", err)
+	}
+	for _, key := range []string{"GIT_SSH_COMMAND", "GOCLODE_TEST"} {
+		if _, set := os.LookupEnv(key); set {
+			t.Errorf("Expected %s from the repository's .env not to be set", key)
+		}
+	}
+
+	for key, want := range map[string]string{"TEST_A_API_KEY": "project", "TEST_B_API_KEY": "shell", "TEST_C_API_KEY": "home"} {
+		if got := os.Getenv(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	files := engine.EnvFiles()
+	if len(files) != 2 || !reflect.DeepEqual(files[0].Set, []string{"TEST_A_API_KEY"}) || !reflect.DeepEqual(files[0].Skipped, []string{"TEST_B_API_KEY"}) {
+		t.Errorf("Unexpected loaded files %+v", files)
+	}
+}
+
+func TestSetEnvFileValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".goclode", "env")
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	os.WriteFile(path, []byte("# Keys\nexport CEREBRAS_API_KEY=old\nGITHUB_TOKEN=gh\n"), 0o644)
+
+	if err := SetEnvFileValue(path, "CEREBRAS_API_KEY", "csk-new"); err != nil {
+		t.Fatalf("SetEnvFileValue: %v", err)
+	}
+	if err := SetEnvFileValue(path, "OPENROUTER_API_KEY", "sk 'or' #1"); err != nil {
+		t.Fatalf("SetEnvFileValue: %v", err)
+	}
+	keys, values, err := ReadEnvFile(path)
+	if err != nil {
+		t.Fatalf("ReadEnvFile: %v", err)
+	}
+	if want := []string{"CEREBRAS_API_KEY", "GITHUB_TOKEN", "OPENROUTER_API_KEY"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Keys %v, want %v", keys, want)
+	}
+	if values["CEREBRAS_API_KEY"] != "csk-new" || values["OPENROUTER_API_KEY"] != "sk 'or' #1" {
+		t.Errorf("Unexpected values %v", values)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if err := SetEnvFileValue(path, "BAD KEY", "x"); err == nil {
+		t.Error("Expected a bad variable name to fail")
+	}
+}
@@ -0,0 +1,137 @@
+// Package core - Structured logging: the slog handler configured from the
+// log_* settings, per-module loggers, and debug mode raising the level
+package core
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Logger returns the logger of a module: the default logger, with the
+// module attribute on every record
+func Logger(module string) *slog.Logger {
+	return slog.Default().With("module", module)
+}
+
+// ParseLogLevel reads a log_level value: debug, info, warn, or error
+func ParseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("log_level: %q is not debug, info, warn, or error", s)
+	}
+	return level, nil
+}
+
+// ConfigureLogging makes the default logger follow the log_level,
+// log_format, and log_file settings, now and whenever they change; debug
+// starts it at the debug level
+func (e *Engine) ConfigureLogging(debug bool) error {
+	e.mu.Lock()
+	e.logDebug = debug
+	watch := !e.logWatching
+	e.logWatching = true
+	e.mu.Unlock()
+
+	if watch {
+		e.OnChange(func(event string) {
+			if event == "config_changed" {
+				if err := e.applyLogConfig(); err != nil {
+					Logger("core").Warn("Logging not reconfigured", "error", err)
+				}
+			}
+		})
+	}
+	return e.applyLogConfig()
+}
+
+// SetDebugLogging logs everything while debug mode is on, and back at
+// log_level once it is off
+func (e *Engine) SetDebugLogging(on bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.logDebug = on
+	e.setLogLevel()
+}
+
+// setLogLevel applies the level; e.mu must be held
+func (e *Engine) setLogLevel() {
+	if e.logDebug && e.logBase > slog.LevelDebug {
+		e.logLevel.Set(slog.LevelDebug)
+	} else {
+		e.logLevel.Set(e.logBase)
+	}
+}
+
+// applyLogConfig builds the handler from the log_* settings and makes it
+// the default
+func (e *Engine) applyLogConfig() error {
+	levelName, _ := e.GetConfig("log_level")
+	format, _ := e.GetConfig("log_format")
+	path, _ := e.GetConfig("log_file")
+
+	base, err := ParseLogLevel(levelName)
+	if levelName == "" {
+		base, err = slog.LevelInfo, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.logBase = base
+	e.setLogLevel()
+
+	var w io.Writer = os.Stderr
+	if e.logFile == nil || e.logFile.Name() != path {
+		e.closeLogFileLocked()
+		if path != "" {
+			if mkErr := os.MkdirAll(filepath.Dir(path), 0755); mkErr != nil {
+				return fmt.Errorf("log_file: %w", mkErr)
+			}
+			f, openErr := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if openErr != nil {
+				return fmt.Errorf("log_file: %w", openErr)
+			}
+			e.logFile = f
+		}
+	}
+	if e.logFile != nil {
+		w = e.logFile
+	}
+
+	opts := &slog.HandlerOptions{Level: &e.logLevel}
+	var handler slog.Handler
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "", "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		handler = slog.NewTextHandler(w, opts)
+		if err == nil {
+			err = fmt.Errorf("log_format: %q is not text or json", format)
+		}
+	}
+	slog.SetDefault(slog.New(handler))
+	return err
+}
+
+// closeLogFile closes the log file, if any
+func (e *Engine) closeLogFile() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closeLogFileLocked()
+}
+
+// closeLogFileLocked closes the log file; e.mu must be held. Logging goes
+// back to stderr, since the default logger may still point at the file.
+func (e *Engine) closeLogFileLocked() {
+	if e.logFile == nil {
+		return
+	}
+	e.logFile.Close()
+	e.logFile = nil
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &e.logLevel})))
+}
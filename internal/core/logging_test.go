@@ -0,0 +1,62 @@
+package core
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogging(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	dir := t.TempDir()
+	engine, err := NewEngine(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	defer engine.Close()
+
+	logFile := filepath.Join(dir, "logs", "goclode.log")
+	engine.SetConfig("log_file", logFile)
+	engine.SetConfig("log_format", "json")
+	engine.SetConfig("log_level", "warn")
+	if err := engine.ConfigureLogging(false); err != nil {
+		t.Fatalf("ConfigureLogging failed: %v", err)
+	}
+
+	Logger("rag").Info("Hidden at warn")
+	Logger("rag").Warn("Retrieval skipped", "error", "no index")
+	engine.SetDebugLogging(true)
+	Logger("lsp").Debug("Shown in debug mode")
+	engine.SetDebugLogging(false)
+	Logger("lsp").Debug("Hidden again")
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("Log file not written: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 records, got %d:\n%s", len(lines), data)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Expected JSON records: %v", err)
+	}
+	if record["level"] != "WARN" || record["module"] != "rag" || record["msg"] != "Retrieval skipped" || record["error"] != "no index" {
+		t.Errorf("Unexpected record: %v", record)
+	}
+	if !strings.Contains(lines[1], `"msg":"Shown in debug mode"`) {
+		t.Errorf("Expected the debug record, got %s", lines[1])
+	}
+
+	engine.SetConfig("log_level", "loud")
+	if err := engine.applyLogConfig(); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if _, err := ParseLogLevel("ERROR"); err != nil {
+		t.Errorf("ParseLogLevel: %v", err)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,10 +21,24 @@ type ModuleManager struct {
 	hooks   map[string][]*Hook
 	mu      sync.RWMutex
 
+	// Handlers modules provide in code, alongside the built-in ones
+	handlers   map[string]HookHandler
+	handlersMu sync.RWMutex
+
 	// Debug hooks for autonomous LLM testing
 	debugEnabled bool
 	debugLog     []DebugEvent
 	debugMu      sync.Mutex
+	debugConfig  debugLogConfig
+	debugFile    *jsonlSink
+	debugSubs    map[chan DebugEvent]struct{} // Live tails of the debug server
+	tracer       Tracer
+}
+
+// Tracer stores what happened during an event emitted in debug mode, so
+// traces can be inspected after the fact; the debug module implements it
+type Tracer interface {
+	RecordTrace(event, module string, debug *DebugContext, err error)
 }
 
 // Module represents a loadable module
@@ -55,6 +71,7 @@ type HookContext struct {
 	Session   string
 	Timestamp time.Time
 	Debug     *DebugContext
+	Hook      *Hook // The hook being run, for handlers configured per hook
 }
 
 // DebugContext for LLM autonomous debugging
@@ -110,17 +127,23 @@ func NewModuleManager(engine *Engine) *ModuleManager {
 		engine:   engine,
 		modules:  make(map[string]*Module),
 		hooks:    make(map[string][]*Hook),
-		debugLog: make([]DebugEvent, 0, 1000),
+		handlers: make(map[string]HookHandler),
+		debugLog: make([]DebugEvent, 0, defaultDebugLogSize),
 	}
+	mm.handlers["test_assert"] = mm.handleTestAssertHook
 
 	// Load modules from DB
 	mm.reload()
+	mm.configureDebugLog()
 
 	// Watch for changes
 	engine.OnChange(func(event string) {
 		if event == "config_changed" || event == "module_changed" {
 			mm.reload()
 		}
+		if event == "config_changed" {
+			mm.configureDebugLog()
+		}
 	})
 
 	return mm
@@ -248,10 +271,44 @@ func (mm *ModuleManager) RegisterHook(h *Hook) error {
 	return nil
 }
 
+// RegisterHandler makes a handler available to hooks under a name, for
+// modules whose handlers need their own state
+func (mm *ModuleManager) RegisterHandler(name string, handler HookHandler) {
+	mm.handlersMu.Lock()
+	defer mm.handlersMu.Unlock()
+	mm.handlers[name] = handler
+}
+
+// handler looks up a hook handler, registered ones first
+func (mm *ModuleManager) handler(name string) (HookHandler, bool) {
+	mm.handlersMu.RLock()
+	handler, ok := mm.handlers[name]
+	mm.handlersMu.RUnlock()
+	if ok {
+		return handler, true
+	}
+	handler, ok = builtinHandlers[name]
+	return handler, ok
+}
+
+// ModuleConfig returns a setting of an enabled module, nil if unset
+func (mm *ModuleManager) ModuleConfig(moduleID, key string) interface{} {
+	mm.mu.RLock()
+	defer mm.mu.RUnlock()
+	if m, ok := mm.modules[moduleID]; ok {
+		return m.Config[key]
+	}
+	return nil
+}
+
 // Emit triggers all hooks for an event
 func (mm *ModuleManager) Emit(event string, payload map[string]interface{}) error {
 	mm.mu.RLock()
 	hooks := mm.hooks[event]
+	if wildcard := mm.hooks["*"]; len(wildcard) > 0 && event != "*" {
+		hooks = append(append([]*Hook{}, hooks...), wildcard...)
+		sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].Priority < hooks[j].Priority })
+	}
 	mm.mu.RUnlock()
 
 	if len(hooks) == 0 {
@@ -260,11 +317,13 @@ func (mm *ModuleManager) Emit(event string, payload map[string]interface{}) erro
 
 	// Create debug context if debugging enabled
 	var debugCtx *DebugContext
+	var traceID string
 	if mm.debugEnabled {
 		debugCtx = &DebugContext{
 			TraceID:   uuid.New().String(),
 			StartTime: time.Now(),
 		}
+		traceID = debugCtx.TraceID
 	}
 
 	ctx := &HookContext{
@@ -275,47 +334,96 @@ func (mm *ModuleManager) Emit(event string, payload map[string]interface{}) erro
 	}
 
 	// Execute hooks in priority order
+	var firstErr error
+	modules := make([]string, 0, len(hooks))
 	for _, hook := range hooks {
-		if handler, ok := builtinHandlers[hook.Handler]; ok {
+		if handler, ok := mm.handler(hook.Handler); ok {
 			start := time.Now()
+			ctx.Hook = hook
+			if !containsString(modules, hook.ModuleID) {
+				modules = append(modules, hook.ModuleID)
+			}
 
+			var debugEvent DebugEvent
 			if err := handler(ctx); err != nil {
-				mm.logDebug(DebugEvent{
+				if firstErr == nil {
+					firstErr = fmt.Errorf("hook %s: %w", hook.Handler, err)
+				}
+				Logger(hook.ModuleID).Warn("Hook failed", "event", event, "hook", hook.ID, "handler", hook.Handler, "error", err)
+				debugEvent = DebugEvent{
 					ID:        uuid.New().String(),
-					TraceID:   debugCtx.TraceID,
+					TraceID:   traceID,
 					Timestamp: time.Now(),
 					Level:     "error",
 					Event:     event,
 					Module:    hook.ModuleID,
 					Message:   fmt.Sprintf("Hook %s failed: %v", hook.Handler, err),
 					Duration:  time.Since(start),
-				})
+				}
 			} else {
-				mm.logDebug(DebugEvent{
+				Logger(hook.ModuleID).Debug("Hook executed", "event", event, "hook", hook.ID, "handler", hook.Handler, "duration", time.Since(start))
+				debugEvent = DebugEvent{
 					ID:        uuid.New().String(),
-					TraceID:   debugCtx.TraceID,
+					TraceID:   traceID,
 					Timestamp: time.Now(),
 					Level:     "debug",
 					Event:     event,
 					Module:    hook.ModuleID,
 					Message:   fmt.Sprintf("Hook %s executed", hook.Handler),
 					Duration:  time.Since(start),
-				})
+				}
+			}
+			mm.logDebug(debugEvent)
+			if debugCtx != nil {
+				debugCtx.Events = append(debugCtx.Events, debugEvent)
 			}
 		}
 	}
 
+	if debugCtx != nil {
+		mm.mu.RLock()
+		tracer := mm.tracer
+		mm.mu.RUnlock()
+		if tracer != nil {
+			tracer.RecordTrace(event, strings.Join(modules, ","), debugCtx, firstErr)
+		}
+	}
+
 	return nil
 }
 
-// EnableDebug enables debug mode
+// SetTracer makes the tracer record each event emitted in debug mode
+func (mm *ModuleManager) SetTracer(t Tracer) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.tracer = t
+}
+
+// DebugEnabled reports whether debug mode is on
+func (mm *ModuleManager) DebugEnabled() bool {
+	return mm.debugEnabled
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableDebug enables debug mode: events are recorded and traced, and
+// everything is logged
 func (mm *ModuleManager) EnableDebug() {
 	mm.debugEnabled = true
+	mm.engine.SetDebugLogging(true)
 }
 
-// DisableDebug disables debug mode
+// DisableDebug disables debug mode, logging back at log_level
 func (mm *ModuleManager) DisableDebug() {
 	mm.debugEnabled = false
+	mm.engine.SetDebugLogging(false)
 }
 
 // GetDebugLog returns the debug log for LLM analysis
@@ -341,6 +449,43 @@ func (mm *ModuleManager) GetDebugLogJSON() string {
 	return string(data)
 }
 
+// configureDebugLog applies the debug_log_* settings: the ring size, the
+// debug_events table, and the JSONL file
+func (mm *ModuleManager) configureDebugLog() {
+	cfg := loadDebugLogConfig(mm.engine)
+
+	mm.debugMu.Lock()
+	defer mm.debugMu.Unlock()
+	mm.debugConfig = cfg
+	if len(mm.debugLog) > cfg.size {
+		mm.debugLog = append(mm.debugLog[:0:0], mm.debugLog[len(mm.debugLog)-cfg.size:]...)
+	}
+	if mm.debugFile != nil && mm.debugFile.path != cfg.file {
+		mm.debugFile.close()
+		mm.debugFile = nil
+	}
+	if cfg.file == "" {
+		return
+	}
+	if mm.debugFile == nil {
+		mm.debugFile = &jsonlSink{path: cfg.file}
+	}
+	mm.debugFile.maxBytes, mm.debugFile.keep = cfg.maxBytes, cfg.keep
+}
+
+// Close closes the debug log file and ends the live tails
+func (mm *ModuleManager) Close() {
+	mm.debugMu.Lock()
+	defer mm.debugMu.Unlock()
+	if mm.debugFile != nil {
+		mm.debugFile.close()
+	}
+	for ch := range mm.debugSubs {
+		delete(mm.debugSubs, ch)
+		close(ch)
+	}
+}
+
 func (mm *ModuleManager) logDebug(event DebugEvent) {
 	if !mm.debugEnabled {
 		return
@@ -349,17 +494,30 @@ func (mm *ModuleManager) logDebug(event DebugEvent) {
 	mm.debugMu.Lock()
 	defer mm.debugMu.Unlock()
 
-	// Keep last 1000 events
-	if len(mm.debugLog) >= 1000 {
-		mm.debugLog = mm.debugLog[1:]
+	// Keep the last debug_log_size events
+	if len(mm.debugLog) >= mm.debugConfig.size {
+		mm.debugLog = mm.debugLog[len(mm.debugLog)-mm.debugConfig.size+1:]
 	}
 	mm.debugLog = append(mm.debugLog, event)
+	mm.publishDebug(event)
 
-	// Also persist to DB for later analysis
-	mm.engine.Exec(`
-		INSERT INTO learning_patterns (pattern_id, pattern_type, input_pattern, metadata, created_at)
-		VALUES (?, 'debug_event', ?, ?, strftime('%s', 'now'))
-	`, event.ID, event.Event, event.Message)
+	// Also persist for later analysis
+	if mm.debugConfig.db {
+		data, err := json.Marshal(event.Data)
+		if err != nil || event.Data == nil {
+			data = []byte("{}")
+		}
+		mm.engine.Exec(`
+			INSERT OR IGNORE INTO debug_events (id, trace_id, event, level, module, message, data, duration_ms, timestamp)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, event.ID, event.TraceID, event.Event, event.Level, event.Module, event.Message, string(data),
+			event.Duration.Milliseconds(), event.Timestamp.UnixMilli())
+	}
+	if mm.debugFile != nil {
+		if err := mm.debugFile.write(event); err != nil {
+			Logger("debug").Error("Debug log file not written", "file", mm.debugFile.path, "error", err)
+		}
+	}
 }
 
 // ============================================================
@@ -367,8 +525,11 @@ func (mm *ModuleManager) logDebug(event DebugEvent) {
 // ============================================================
 
 func handleLog(ctx *HookContext) error {
-	data, _ := json.Marshal(ctx.Payload)
-	fmt.Printf("[%s] %s: %s\n", ctx.Timestamp.Format("15:04:05"), ctx.Event, string(data))
+	module := "log"
+	if ctx.Hook != nil {
+		module = ctx.Hook.ModuleID
+	}
+	Logger(module).Info(ctx.Event, "payload", ctx.Payload)
 	return nil
 }
 
@@ -383,6 +544,8 @@ func handleDebug(ctx *HookContext) error {
 		Timestamp: time.Now(),
 		Level:     "debug",
 		Event:     ctx.Event,
+		Module:    "debug",
+		Message:   "Event payload",
 		Data:      ctx.Payload,
 	}
 
@@ -0,0 +1,295 @@
+// Package debugserver - Read-only query API over the stored history, for
+// dashboards and analytics
+package debugserver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Page sizes of the list endpoints
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+const apiDocs = `GoClode query API (read-only, JSON)
+
+GET /api/sessions        Sessions            filters: provider, branch
+GET /api/sessions/{id}   One session, with message, token, file, and commit totals
+GET /api/messages        Messages            filters: session, role, provider, model, q (text in the content)
+GET /api/files           Files modified      filters: session, path (prefix), operation, batch
+GET /api/commits         Auto-commits        filters: session, hash (prefix)
+
+Every list also takes:
+  since, until    created_at bounds: unix seconds or RFC 3339 (2024-05-01T00:00:00Z)
+  limit, offset   Page size (default 50, at most 500) and rows to skip
+  order           desc (newest first, the default) or asc
+
+Lists return {"items": [...], "total": n, "limit": n, "offset": n}; times are
+unix seconds, and metadata is the stored JSON.
+`
+
+// apiFilter is a query parameter compared with a column
+type apiFilter struct {
+	column string
+	prefix bool // Match values starting with the parameter
+}
+
+// apiList describes a list endpoint over a table
+type apiList struct {
+	table   string
+	columns []string
+	filters map[string]apiFilter
+	search  string // Column the q parameter searches, if any
+}
+
+var (
+	sessionsList = apiList{
+		table:   "sessions",
+		columns: []string{"session_id", "created_at", "last_active_at", "git_branch", "git_commit_start", "provider_id", "metadata"},
+		filters: map[string]apiFilter{"provider": {column: "provider_id"}, "branch": {column: "git_branch"}},
+	}
+	messagesList = apiList{
+		table:   "messages",
+		columns: []string{"message_id", "session_id", "role", "content", "provider_id", "model", "tokens_in", "tokens_out", "latency_ms", "created_at", "metadata"},
+		filters: map[string]apiFilter{
+			"session":  {column: "session_id"},
+			"role":     {column: "role"},
+			"provider": {column: "provider_id"},
+			"model":    {column: "model"},
+		},
+		search: "content",
+	}
+	filesList = apiList{
+		table:   "files_modified",
+		columns: []string{"file_id", "session_id", "message_id", "file_path", "operation", "diff", "batch_id", "undone_at", "created_at"},
+		filters: map[string]apiFilter{
+			"session":   {column: "session_id"},
+			"path":      {column: "file_path", prefix: true},
+			"operation": {column: "operation"},
+			"batch":     {column: "batch_id"},
+		},
+	}
+	commitsList = apiList{
+		table:   "git_commits",
+		columns: []string{"commit_id", "session_id", "message_id", "git_hash", "commit_message", "files_changed", "insertions", "deletions", "revert_hash", "reverted_at", "created_at"},
+		filters: map[string]apiFilter{"session": {column: "session_id"}, "hash": {column: "git_hash", prefix: true}},
+	}
+)
+
+// apiRoutes adds the query API to mux
+func (s *Server) apiRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, apiDocs)
+	})
+	mux.HandleFunc("GET /api/sessions", s.listHandler(sessionsList))
+	mux.HandleFunc("GET /api/sessions/{id}", s.handleAPISession)
+	mux.HandleFunc("GET /api/messages", s.listHandler(messagesList))
+	mux.HandleFunc("GET /api/files", s.listHandler(filesList))
+	mux.HandleFunc("GET /api/commits", s.listHandler(commitsList))
+}
+
+// listHandler serves a filtered, paginated list
+func (s *Server) listHandler(list apiList) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		where, args, err := list.where(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit, offset, err := page(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		order := "DESC"
+		switch query.Get("order") {
+		case "", "desc":
+		case "asc":
+			order = "ASC"
+		default:
+			http.Error(w, "order must be asc or desc", http.StatusBadRequest)
+			return
+		}
+
+		var total int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM "+list.table+where, args...).Scan(&total); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rows, err := s.db.Query(fmt.Sprintf("SELECT %s FROM %s%s ORDER BY created_at %s, rowid %s LIMIT ? OFFSET ?",
+			strings.Join(list.columns, ", "), list.table, where, order, order), append(args, limit, offset)...)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		items, err := scanRows(rows, list.columns)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"items": items, "total": total, "limit": limit, "offset": offset})
+	}
+}
+
+// handleAPISession serves one session with its totals
+func (s *Server) handleAPISession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rows, err := s.db.Query("SELECT "+strings.Join(sessionsList.columns, ", ")+" FROM sessions WHERE session_id = ?", id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	items, err := scanRows(rows, sessionsList.columns)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(items) == 0 {
+		http.Error(w, "no session "+id, http.StatusNotFound)
+		return
+	}
+	session := items[0]
+
+	var messages, tokensIn, tokensOut, files, commits int64
+	err = s.db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM messages WHERE session_id = ?1),
+			(SELECT COALESCE(SUM(tokens_in), 0) FROM messages WHERE session_id = ?1),
+			(SELECT COALESCE(SUM(tokens_out), 0) FROM messages WHERE session_id = ?1),
+			(SELECT COUNT(*) FROM files_modified WHERE session_id = ?1),
+			(SELECT COUNT(*) FROM git_commits WHERE session_id = ?1)`, id).
+		Scan(&messages, &tokensIn, &tokensOut, &files, &commits)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	session["messages"] = messages
+	session["tokens_in"] = tokensIn
+	session["tokens_out"] = tokensOut
+	session["files_modified"] = files
+	session["commits"] = commits
+	writeJSON(w, session)
+}
+
+// where builds the WHERE clause of a list from its query parameters
+func (l apiList) where(query map[string][]string) (string, []interface{}, error) {
+	conds := make([]string, 0)
+	args := make([]interface{}, 0)
+	get := func(key string) string {
+		if values := query[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	for param, f := range l.filters {
+		value := get(param)
+		if value == "" {
+			continue
+		}
+		if f.prefix {
+			conds = append(conds, f.column+" LIKE ? ESCAPE '\\'")
+			args = append(args, escapeLike(value)+"%")
+		} else {
+			conds = append(conds, f.column+" = ?")
+			args = append(args, value)
+		}
+	}
+	if q := get("q"); q != "" && l.search != "" {
+		conds = append(conds, l.search+" LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(q)+"%")
+	}
+	for param, op := range map[string]string{"since": ">=", "until": "<"} {
+		value := get(param)
+		if value == "" {
+			continue
+		}
+		t, err := parseTime(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", param, err)
+		}
+		conds = append(conds, "created_at "+op+" ?")
+		args = append(args, t)
+	}
+
+	if len(conds) == 0 {
+		return "", args, nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args, nil
+}
+
+// escapeLike escapes the LIKE wildcards of a value
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// parseTime reads unix seconds or an RFC 3339 time
+func parseTime(value string) (int64, error) {
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("want unix seconds or RFC 3339, got %q", value)
+	}
+	return t.Unix(), nil
+}
+
+// page reads limit and offset
+func page(query map[string][]string) (int, int, error) {
+	limit, offset := defaultPageSize, 0
+	if values := query["limit"]; len(values) > 0 {
+		n, err := strconv.Atoi(values[0])
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("limit must be a positive number")
+		}
+		limit = min(n, maxPageSize)
+	}
+	if values := query["offset"]; len(values) > 0 {
+		n, err := strconv.Atoi(values[0])
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("offset must be zero or more")
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// scanRows reads rows into maps keyed by column, with JSON metadata kept
+// as JSON
+func scanRows(rows *sql.Rows, columns []string) ([]map[string]interface{}, error) {
+	defer rows.Close()
+	items := make([]map[string]interface{}, 0)
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		item := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			v := values[i]
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			if s, ok := v.(string); ok && col == "metadata" && json.Valid([]byte(s)) {
+				v = json.RawMessage(s)
+			}
+			item[col] = v
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,90 @@
+package debugserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func TestQueryAPI(t *testing.T) {
+	engine, err := core.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	defer engine.Close()
+	mm := core.NewModuleManager(engine)
+	defer mm.Close()
+	ts := httptest.NewServer(New(engine.DB(), mm, nil).Handler())
+	defer ts.Close()
+
+	db := engine.DB()
+	for _, stmt := range []string{
+		`INSERT INTO sessions (session_id, created_at, git_branch, metadata) VALUES ('s1', 100, 'main', '{"thread": "slack:C1"}'), ('s2', 200, 'dev', '{}')`,
+		`INSERT INTO messages (message_id, session_id, role, content, tokens_in, tokens_out, created_at) VALUES
+			('m1', 's1', 'user', 'fix the 100% bug', 10, 0, 101),
+			('m2', 's1', 'assistant', 'done', 0, 20, 102),
+			('m3', 's2', 'user', 'hello', 5, 0, 201)`,
+		`INSERT INTO files_modified (file_id, session_id, file_path, operation, created_at) VALUES ('f1', 's1', 'internal/ui/chat.go', 'modify', 103)`,
+		`INSERT INTO git_commits (commit_id, session_id, git_hash, commit_message, created_at) VALUES ('c1', 's1', 'abcdef1234', 'Fix bug', 104)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	type list struct {
+		Items []map[string]interface{} `json:"items"`
+		Total int                      `json:"total"`
+	}
+	var l list
+	getJSON(t, ts.URL+"/api/messages?session=s1&order=asc&limit=1", &l)
+	if l.Total != 2 || len(l.Items) != 1 || l.Items[0]["message_id"] != "m1" {
+		t.Errorf("Unexpected page %+v", l)
+	}
+	l = list{}
+	getJSON(t, ts.URL+"/api/messages?q=100%25", &l)
+	if l.Total != 1 || l.Items[0]["message_id"] != "m1" {
+		t.Errorf("Expected the search to match m1 only, got %+v", l)
+	}
+	l = list{}
+	getJSON(t, ts.URL+"/api/sessions?since=150", &l)
+	if l.Total != 1 || l.Items[0]["session_id"] != "s2" {
+		t.Errorf("Expected s2 only, got %+v", l)
+	}
+	l = list{}
+	getJSON(t, ts.URL+"/api/files?path=internal/", &l)
+	if l.Total != 1 {
+		t.Errorf("Expected one file under internal/, got %+v", l)
+	}
+	l = list{}
+	getJSON(t, ts.URL+"/api/commits?hash=abcdef", &l)
+	if l.Total != 1 || l.Items[0]["commit_message"] != "Fix bug" {
+		t.Errorf("Expected the commit by hash prefix, got %+v", l)
+	}
+
+	var session map[string]interface{}
+	getJSON(t, ts.URL+"/api/sessions/s1", &session)
+	metadata, _ := session["metadata"].(map[string]interface{})
+	if session["messages"] != float64(2) || session["tokens_out"] != float64(20) || session["commits"] != float64(1) || metadata["thread"] != "slack:C1" {
+		t.Errorf("Unexpected session %v", session)
+	}
+
+	for url, want := range map[string]int{
+		"/api/sessions/nope":       http.StatusNotFound,
+		"/api/messages?limit=0":    http.StatusBadRequest,
+		"/api/messages?since=soon": http.StatusBadRequest,
+		"/api":                     http.StatusOK,
+	} {
+		resp, err := http.Get(ts.URL + url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: got %d, want %d", url, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package debugserver is an optional HTTP server on localhost for
+// diagnosing hangs: pprof, the session state, the hook registry, a live
+// tail of debug events as server-sent events, and a read-only query API
+// over the stored history.
+package debugserver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+// StateFunc describes the current session for /session
+type StateFunc func() map[string]interface{}
+
+// keepAlive is how often an idle event stream gets a comment, so proxies
+// and clients notice a dead connection
+const keepAlive = 15 * time.Second
+
+const index = `GoClode debug server
+
+/debug/pprof/   Profiles: goroutine, heap, profile?seconds=30, trace?seconds=5
+/session        Current session state
+/hooks          Modules and hooks, in the order they run
+/events         Live debug events (server-sent events; ?backlog=n replays the last n)
+/api            Query API over sessions, messages, files modified, and commits
+`
+
+// Server serves the debug endpoints
+type Server struct {
+	db      *sql.DB
+	modules *core.ModuleManager
+	state   StateFunc
+	srv     *http.Server
+	ln      net.Listener
+}
+
+// New creates a debug server over the database db; Start listens
+func New(db *sql.DB, modules *core.ModuleManager, state StateFunc) *Server {
+	s := &Server{db: db, modules: modules, state: state}
+	s.srv = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// Handler routes the debug endpoints, for requests addressed to a
+// localhost name only
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, index)
+	})
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/session", s.handleSession)
+	mux.HandleFunc("/hooks", s.handleHooks)
+	mux.HandleFunc("/events", s.handleEvents)
+	s.apiRoutes(mux)
+	return localOnly(mux)
+}
+
+// localOnly refuses requests whose Host header is not a loopback name or
+// address. A web page can point its own domain at 127.0.0.1 (DNS
+// rebinding), but its requests still carry that domain as the Host.
+func localOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if !isLoopback(strings.Trim(host, "[]")) {
+			http.Error(w, "forbidden host", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Start listens on addr, which must be a loopback address (":6060" means
+// 127.0.0.1:6060), and serves in the background; it returns the address
+// listened on
+func (s *Server) Start(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("debug server: %w", err)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if !isLoopback(host) {
+		return "", fmt.Errorf("debug server: %s is not a localhost address", host)
+	}
+
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return "", fmt.Errorf("debug server: %w", err)
+	}
+	s.ln = ln
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			core.Logger("debugserver").Error("Debug server stopped", "error", err)
+		}
+	}()
+	return ln.Addr().String(), nil
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// isLoopback reports whether a host only accepts local connections
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleSession serves the session state with runtime figures
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	state := map[string]interface{}{}
+	if s.state != nil {
+		state = s.state()
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	state["goroutines"] = runtime.NumGoroutine()
+	state["heap_bytes"] = mem.HeapAlloc
+	state["debug_mode"] = s.modules.DebugEnabled()
+	writeJSON(w, state)
+}
+
+// handleHooks serves the modules and hooks
+func (s *Server) handleHooks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"modules": s.modules.Modules(),
+		"hooks":   s.modules.Hooks(),
+	})
+}
+
+// handleEvents streams debug events as server-sent events, after the
+// last ?backlog=n logged ones
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	events, cancel := s.modules.SubscribeDebug()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	if !s.modules.DebugEnabled() {
+		fmt.Fprint(w, ": debug mode is off, no events are logged until /debug turns it on\n\n")
+	}
+	if n, err := strconv.Atoi(r.URL.Query().Get("backlog")); err == nil && n > 0 {
+		backlog := s.modules.GetDebugLog()
+		if len(backlog) > n {
+			backlog = backlog[len(backlog)-n:]
+		}
+		for _, event := range backlog {
+			if writeEvent(w, event) != nil {
+				return
+			}
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return // Shutting down
+			}
+			if writeEvent(w, event) != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a debug event as an SSE message named after its event
+func writeEvent(w http.ResponseWriter, event core.DebugEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Event, data)
+	return err
+}
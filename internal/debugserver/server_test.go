@@ -0,0 +1,146 @@
+package debugserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func setupServer(t *testing.T) (*core.ModuleManager, *httptest.Server) {
+	t.Helper()
+	engine, err := core.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	mm := core.NewModuleManager(engine)
+	t.Cleanup(mm.Close)
+
+	s := New(engine.DB(), mm, func() map[string]interface{} {
+		return map[string]interface{}{"session_id": "s1"}
+	})
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return mm, ts
+}
+
+func getJSON(t *testing.T, url string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("%s: %v", url, err)
+	}
+}
+
+func TestSessionAndHooks(t *testing.T) {
+	mm, ts := setupServer(t)
+	mm.RegisterModule(&core.Module{ID: "probe", Name: "Probe", Version: "1", Enabled: true})
+	mm.RegisterHook(&core.Hook{ID: "probe_missing", ModuleID: "probe", Event: "chat_complete", Handler: "no_such_handler", Enabled: true})
+
+	var state map[string]interface{}
+	getJSON(t, ts.URL+"/session", &state)
+	if state["session_id"] != "s1" || state["goroutines"] == nil {
+		t.Errorf("Unexpected session state: %v", state)
+	}
+
+	var registry struct {
+		Hooks []core.HookInfo `json:"hooks"`
+	}
+	getJSON(t, ts.URL+"/hooks", &registry)
+	found := false
+	for _, h := range registry.Hooks {
+		if h.ID == "probe_missing" {
+			found = true
+			if h.HandlerFound {
+				t.Error("Expected the missing handler to be reported")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected probe_missing among %d hooks", len(registry.Hooks))
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	mm, ts := setupServer(t)
+	mm.RegisterModule(&core.Module{ID: "probe", Name: "Probe", Version: "1", Enabled: true})
+	mm.RegisterHook(&core.Hook{ID: "probe_debug", ModuleID: "probe", Event: "chat_complete", Handler: "debug", Enabled: true})
+	mm.EnableDebug()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %s", ct)
+	}
+
+	mm.Emit("chat_complete", map[string]interface{}{"tokens_out": 12})
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+			var event core.DebugEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatalf("Bad event %q: %v", line, err)
+			}
+			if event.Event != "chat_complete" {
+				t.Errorf("Expected chat_complete, got %s", event.Event)
+			}
+			return
+		}
+	}
+	t.Fatalf("No event streamed: %v", scanner.Err())
+}
+
+func TestForeignHostRefused(t *testing.T) {
+	_, ts := setupServer(t)
+	for host, want := range map[string]int{
+		"attacker.example:80": http.StatusForbidden,
+		"attacker.example":    http.StatusForbidden,
+		"localhost:6060":      http.StatusOK,
+		"[::1]:6060":          http.StatusOK,
+	} {
+		req, _ := http.NewRequest("GET", ts.URL+"/api/messages", nil)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("Host %s: status %d, want %d", host, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestStartLocalhostOnly(t *testing.T) {
+	mm, _ := setupServer(t)
+	s := New(nil, mm, nil)
+	if _, err := s.Start("0.0.0.0:0"); err == nil {
+		t.Error("Expected a non-loopback address to be refused")
+	}
+	addr, err := s.Start(":0")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer s.Close()
+	if !strings.HasPrefix(addr, "127.0.0.1:") {
+		t.Errorf("Expected a loopback address, got %s", addr)
+	}
+}
@@ -0,0 +1,314 @@
+// Package diff computes line diffs and unified hunks for file changes
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// OpKind is the kind of a diff operation
+type OpKind int
+
+const (
+	Equal  OpKind = iota // Line present in both versions
+	Delete               // Line only in the old version
+	Insert               // Line only in the new version
+)
+
+// Op is a single line operation. Lines keep their trailing newline.
+type Op struct {
+	Kind OpKind
+	Line string
+}
+
+// Hunk is a contiguous group of changes with surrounding context
+type Hunk struct {
+	OldStart int // 0-based index of the first old line
+	OldLines int
+	NewStart int // 0-based index of the first new line
+	NewLines int
+	Ops      []Op
+}
+
+// maxEditDistance bounds the Myers search; beyond it the diff degrades
+// to a full replacement rather than using quadratic memory.
+const maxEditDistance = 4000
+
+// SplitLines splits text into lines, each keeping its trailing newline
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines computes the shortest edit script between a and b (Myers' algorithm)
+func Lines(a, b []string) []Op {
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD > maxEditDistance {
+		maxD = maxEditDistance
+	}
+
+	off := maxD + 1
+	v := make([]int, 2*maxD+3)
+	trace := make([][]int, 0)
+	final := -1
+
+search:
+	for d := 0; d <= maxD; d++ {
+		// Snapshot diagonals -d..d as they were before step d
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				final = d
+				break search
+			}
+		}
+	}
+
+	if final < 0 {
+		return replaceAll(a, b)
+	}
+
+	ops := make([]Op, 0, n+m)
+	x, y := n, m
+	for d := final; d > 0; d-- {
+		vd := trace[d]
+		at := func(k int) int { return vd[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, Op{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, Op{Insert, b[y-1]})
+			y--
+		} else {
+			ops = append(ops, Op{Delete, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, Op{Equal, a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceAll is the fallback script: delete everything, insert everything
+func replaceAll(a, b []string) []Op {
+	ops := make([]Op, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, Op{Delete, line})
+	}
+	for _, line := range b {
+		ops = append(ops, Op{Insert, line})
+	}
+	return ops
+}
+
+// Compute returns the hunks turning old into new, with context lines around each
+func Compute(old, new string, context int) []Hunk {
+	ops := Lines(SplitLines(old), SplitLines(new))
+
+	hunks := make([]Hunk, 0)
+	var current *Hunk
+	oldIdx, newIdx := 0, 0
+	lastChange := -1
+
+	for i, op := range ops {
+		if op.Kind != Equal {
+			if current == nil || i-lastChange-1 > 2*context {
+				if current != nil {
+					hunks = append(hunks, closeHunk(*current, ops, lastChange, context))
+				}
+				start := max(0, i-context)
+				current = &Hunk{
+					OldStart: oldIdx - countKind(ops[start:i], Delete, Equal),
+					NewStart: newIdx - countKind(ops[start:i], Insert, Equal),
+				}
+				current.Ops = append(current.Ops, ops[start:i]...)
+			} else {
+				current.Ops = append(current.Ops, ops[lastChange+1:i]...)
+			}
+			current.Ops = append(current.Ops, op)
+			lastChange = i
+		}
+
+		switch op.Kind {
+		case Equal:
+			oldIdx++
+			newIdx++
+		case Delete:
+			oldIdx++
+		case Insert:
+			newIdx++
+		}
+	}
+
+	if current != nil {
+		hunks = append(hunks, closeHunk(*current, ops, lastChange, context))
+	}
+	return hunks
+}
+
+// closeHunk appends trailing context and computes line counts
+func closeHunk(h Hunk, ops []Op, lastChange, context int) Hunk {
+	end := min(len(ops), lastChange+1+context)
+	h.Ops = append(h.Ops, ops[lastChange+1:end]...)
+	h.OldLines = countKind(h.Ops, Delete, Equal)
+	h.NewLines = countKind(h.Ops, Insert, Equal)
+	return h
+}
+
+func countKind(ops []Op, kinds ...OpKind) int {
+	n := 0
+	for _, op := range ops {
+		for _, k := range kinds {
+			if op.Kind == k {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// Header returns the @@ line for a hunk
+func (h Hunk) Header() string {
+	oldStart, newStart := h.OldStart+1, h.NewStart+1
+	if h.OldLines == 0 {
+		oldStart--
+	}
+	if h.NewLines == 0 {
+		newStart--
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, h.OldLines, newStart, h.NewLines)
+}
+
+// String renders the hunk in unified diff format
+func (h Hunk) String() string {
+	var sb strings.Builder
+	sb.WriteString(h.Header())
+	sb.WriteString("\n")
+	for _, op := range h.Ops {
+		switch op.Kind {
+		case Equal:
+			sb.WriteString(" ")
+		case Delete:
+			sb.WriteString("-")
+		case Insert:
+			sb.WriteString("+")
+		}
+		sb.WriteString(op.Line)
+		if !strings.HasSuffix(op.Line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	return sb.String()
+}
+
+// Unified renders a unified diff for one file; empty if nothing changed
+func Unified(path, old, new string) string {
+	hunks := Compute(old, new, 3)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", path, path)
+	for _, h := range hunks {
+		sb.WriteString(h.String())
+	}
+	return sb.String()
+}
+
+// ApplyHunks rebuilds new content from old, applying only accepted hunks.
+// Hunks must come from Compute on the same old content.
+func ApplyHunks(old string, hunks []Hunk, accepted []bool) string {
+	lines := SplitLines(old)
+
+	var sb strings.Builder
+	pos := 0
+	for i, h := range hunks {
+		for ; pos < h.OldStart && pos < len(lines); pos++ {
+			sb.WriteString(lines[pos])
+		}
+
+		for _, op := range h.Ops {
+			switch {
+			case op.Kind == Equal:
+				sb.WriteString(op.Line)
+			case op.Kind == Delete && !accepted[i]:
+				sb.WriteString(op.Line)
+			case op.Kind == Insert && accepted[i]:
+				sb.WriteString(op.Line)
+			}
+		}
+		pos = h.OldStart + h.OldLines
+	}
+	for ; pos < len(lines); pos++ {
+		sb.WriteString(lines[pos])
+	}
+	return sb.String()
+}
+
+// hunkHeaderPattern reads the new-side start of a hunk header
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// NewLines returns the 1-based lines of the new version a unified diff
+// shows, added or context: the lines a review can comment on
+func NewLines(patch string) map[int]bool {
+	lines := make(map[int]bool)
+	line := 0
+	for _, text := range strings.Split(patch, "\n") {
+		if m := hunkHeaderPattern.FindStringSubmatch(text); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if line == 0 {
+			continue // File headers
+		}
+		if strings.HasPrefix(text, "+") || strings.HasPrefix(text, " ") {
+			lines[line] = true
+			line++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,122 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func numbered(n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	return sb.String()
+}
+
+func TestLines(t *testing.T) {
+	a := SplitLines("a\nb\nc\n")
+	b := SplitLines("a\nx\nc\nd\n")
+
+	ops := Lines(a, b)
+
+	var rebuiltOld, rebuiltNew strings.Builder
+	changes := 0
+	for _, op := range ops {
+		if op.Kind != Insert {
+			rebuiltOld.WriteString(op.Line)
+		}
+		if op.Kind != Delete {
+			rebuiltNew.WriteString(op.Line)
+		}
+		if op.Kind != Equal {
+			changes++
+		}
+	}
+
+	if rebuiltOld.String() != "a\nb\nc\n" || rebuiltNew.String() != "a\nx\nc\nd\n" {
+		t.Errorf("Ops do not rebuild inputs: %+v", ops)
+	}
+	if changes != 3 {
+		t.Errorf("Changes = %d, want 3 (delete b, insert x, insert d)", changes)
+	}
+}
+
+func TestCompute_SeparateHunks(t *testing.T) {
+	old := numbered(30)
+	new := strings.Replace(old, "line 3\n", "line three\n", 1)
+	new = strings.Replace(new, "line 25\n", "line twenty-five\n", 1)
+
+	hunks := Compute(old, new, 3)
+	if len(hunks) != 2 {
+		t.Fatalf("Hunks = %d, want 2", len(hunks))
+	}
+	if got := hunks[0].Header(); got != "@@ -1,6 +1,6 @@" {
+		t.Errorf("First header = %q", got)
+	}
+	if got := hunks[1].Header(); got != "@@ -22,7 +22,7 @@" {
+		t.Errorf("Second header = %q", got)
+	}
+}
+
+func TestCompute_MergesNearbyChanges(t *testing.T) {
+	old := numbered(20)
+	new := strings.Replace(old, "line 5\n", "five\n", 1)
+	new = strings.Replace(new, "line 11\n", "eleven\n", 1)
+
+	if hunks := Compute(old, new, 3); len(hunks) != 1 {
+		t.Errorf("Hunks = %d, want 1", len(hunks))
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	old := numbered(30)
+	new := strings.Replace(old, "line 3\n", "line three\n", 1)
+	new = strings.Replace(new, "line 25\n", "", 1)
+
+	hunks := Compute(old, new, 3)
+	if len(hunks) != 2 {
+		t.Fatalf("Hunks = %d, want 2", len(hunks))
+	}
+
+	if got := ApplyHunks(old, hunks, []bool{true, true}); got != new {
+		t.Errorf("Accept all mismatch:\n%s", got)
+	}
+	if got := ApplyHunks(old, hunks, []bool{false, false}); got != old {
+		t.Errorf("Reject all mismatch:\n%s", got)
+	}
+
+	want := strings.Replace(old, "line 3\n", "line three\n", 1)
+	if got := ApplyHunks(old, hunks, []bool{true, false}); got != want {
+		t.Errorf("Partial mismatch:\n%s", got)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	if got := Unified("a.txt", "same\n", "same\n"); got != "" {
+		t.Errorf("Expected empty diff, got %q", got)
+	}
+
+	got := Unified("a.txt", "one\n", "two")
+	want := "--- a/a.txt\n+++ b/a.txt\n@@ -1,1 +1,1 @@\n-one\n+two\n\\ No newline at end of file\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := Unified("new.txt", "", "x\n"); !strings.Contains(got, "@@ -0,0 +1,1 @@") {
+		t.Errorf("Creation header wrong: %q", got)
+	}
+}
+
+func TestNewLines(t *testing.T) {
+	patch := "@@ -1,3 +1,4 @@\n package main\n-import \"fmt\"\n+import (\n+\t\"fmt\"\n+++counter\n@@ -10,2 +11,2 @@ func main() {\n \tx := 1\n-\ty := 2\n+\ty := 3\n\\ No newline at end of file"
+	got := NewLines(patch)
+	for _, line := range []int{1, 2, 3, 4, 11, 12} {
+		if !got[line] {
+			t.Errorf("Expected line %d commentable", line)
+		}
+	}
+	if len(got) != 6 {
+		t.Errorf("Expected 6 lines, got %v", got)
+	}
+}
@@ -0,0 +1,467 @@
+// Package discord is the Discord client of the bot mode: messages and
+// slash commands from the gateway, and the REST calls that post and edit
+// replies.
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/websocket"
+)
+
+// DefaultAPIURL is the Discord REST API
+const DefaultAPIURL = "https://discord.com/api/v10"
+
+// MaxMessageLength is the longest message content Discord accepts
+const MaxMessageLength = 2000
+
+// Gateway intents: guild and direct messages, with their content
+const intents = 1<<9 | 1<<12 | 1<<15
+
+// Gateway opcodes
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatACK   = 11
+)
+
+// reconnectDelay is the pause before reopening a dropped connection
+const reconnectDelay = 5 * time.Second
+
+// Client talks to Discord as one bot
+type Client struct {
+	APIURL string
+	token  string
+	http   *http.Client
+
+	// Set once the gateway is ready
+	UserID string // The bot's user, to recognize mentions
+	AppID  string // Its application, for slash commands
+
+	mu      sync.Mutex
+	threads map[string]bool // Thread channels seen
+}
+
+// New creates a client from a bot token
+func New(token string) *Client {
+	return &Client{
+		APIURL:  DefaultAPIURL,
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+		threads: make(map[string]bool),
+	}
+}
+
+// User is a Discord account
+type User struct {
+	ID  string `json:"id"`
+	Bot bool   `json:"bot"`
+}
+
+// Message is a message posted in a channel the bot can read
+type Message struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"` // Empty in direct messages
+	Content   string `json:"content"`
+	Author    User   `json:"author"`
+	Mentions  []User `json:"mentions"`
+	InThread  bool   `json:"-"`
+}
+
+// MentionsUser reports whether the message mentions a user
+func (m Message) MentionsUser(id string) bool {
+	for _, u := range m.Mentions {
+		if u.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Interaction is a slash command a user ran
+type Interaction struct {
+	ID        string
+	Token     string
+	ChannelID string
+	GuildID   string // Empty in direct messages
+	UserID    string
+	Name      string
+	Options   map[string]string
+}
+
+// Command is a slash command to register
+type Command struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options,omitempty"`
+}
+
+// CommandOption is a string argument of a slash command
+type CommandOption struct {
+	Type        int    `json:"type"` // 3 for a string
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// request calls the REST API and decodes the response into out
+func (c *Client) request(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.APIURL+path, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bot "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DiscordBot (https://github.com/hazyhaar/GoClode, 0.1)")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: HTTP %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateMessage posts in a channel, as a reply to a message unless replyTo
+// is empty, and returns the new message's ID
+func (c *Client) CreateMessage(ctx context.Context, channel, replyTo, content string) (string, error) {
+	in := map[string]interface{}{"content": truncate(content), "allowed_mentions": map[string][]string{"parse": {}}}
+	if replyTo != "" {
+		in["message_reference"] = map[string]string{"message_id": replyTo}
+	}
+	var out struct {
+		ID string `json:"id"`
+	}
+	err := c.request(ctx, "POST", "/channels/"+channel+"/messages", in, &out)
+	return out.ID, err
+}
+
+// EditMessage replaces the content of a message the bot posted
+func (c *Client) EditMessage(ctx context.Context, channel, id, content string) error {
+	return c.request(ctx, "PATCH", "/channels/"+channel+"/messages/"+id, map[string]string{"content": truncate(content)}, nil)
+}
+
+// EditResponse replaces the response to an interaction
+func (c *Client) EditResponse(ctx context.Context, token, content string) error {
+	return c.request(ctx, "PATCH", "/webhooks/"+c.AppID+"/"+token+"/messages/@original", map[string]string{"content": truncate(content)}, nil)
+}
+
+// FollowUp adds a message to an interaction's response and returns its ID
+func (c *Client) FollowUp(ctx context.Context, token, content string) (string, error) {
+	var out struct {
+		ID string `json:"id"`
+	}
+	err := c.request(ctx, "POST", "/webhooks/"+c.AppID+"/"+token, map[string]string{"content": truncate(content)}, &out)
+	return out.ID, err
+}
+
+// EditFollowUp replaces a follow-up message of an interaction
+func (c *Client) EditFollowUp(ctx context.Context, token, id, content string) error {
+	return c.request(ctx, "PATCH", "/webhooks/"+c.AppID+"/"+token+"/messages/"+id, map[string]string{"content": truncate(content)}, nil)
+}
+
+// RegisterCommands replaces the bot's global slash commands
+func (c *Client) RegisterCommands(ctx context.Context, commands []Command) error {
+	if c.AppID == "" {
+		return fmt.Errorf("register commands: gateway not ready")
+	}
+	return c.request(ctx, "PUT", "/applications/"+c.AppID+"/commands", commands, nil)
+}
+
+// deferResponse acknowledges an interaction within Discord's three
+// seconds; the response follows later
+func (c *Client) deferResponse(ctx context.Context, id, token string) error {
+	return c.request(ctx, "POST", "/interactions/"+id+"/"+token+"/callback", map[string]int{"type": 5}, nil)
+}
+
+// truncate cuts content Discord would refuse
+func truncate(content string) string {
+	if len(content) <= MaxMessageLength {
+		return content
+	}
+	return content[:MaxMessageLength-16] + "\n… (truncated)"
+}
+
+// payload is a gateway message
+type payload struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d"`
+	Seq  *int64          `json:"s,omitempty"`
+	Type string          `json:"t,omitempty"`
+}
+
+// Handler receives what the bot is sent
+type Handler struct {
+	Message func(Message)     // Called for each message from a person
+	Command func(Interaction) // Called for a slash command, already acknowledged
+	Ready   func()            // Called on each (re)connection, once UserID and AppID are set
+}
+
+// Listen receives events until ctx is done, reconnecting when the
+// connection drops or Discord asks to. Events are handled one at a time,
+// in order.
+func (c *Client) Listen(ctx context.Context, h Handler) error {
+	events := make(chan func(), 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for handle := range events {
+			handle()
+		}
+	}()
+	defer func() {
+		close(events)
+		<-done
+	}()
+
+	var gateway struct {
+		URL string `json:"url"`
+	}
+	if err := c.request(ctx, "GET", "/gateway/bot", nil, &gateway); err != nil {
+		return err
+	}
+
+	log := core.Logger("discord")
+	for ctx.Err() == nil {
+		err := c.listenOnce(ctx, gateway.URL+"/?v=10&encoding=json", h, events)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			log.Warn("Gateway connection lost, reconnecting", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}
+	return nil
+}
+
+// listenOnce serves one gateway connection
+func (c *Client) listenOnce(ctx context.Context, url string, h Handler, events chan<- func()) error {
+	conn, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	closed := make(chan struct{})
+	defer close(closed)
+
+	var seq atomic.Int64
+	seq.Store(-1)
+	var acked atomic.Bool
+	send := func(op int, d interface{}) error {
+		data, err := json.Marshal(map[string]interface{}{"op": op, "d": d})
+		if err != nil {
+			return err
+		}
+		return conn.WriteMessage(data)
+	}
+	heartbeat := func() error {
+		if s := seq.Load(); s >= 0 {
+			return send(opHeartbeat, s)
+		}
+		return send(opHeartbeat, nil)
+	}
+
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var p payload
+		if err := json.Unmarshal(data, &p); err != nil {
+			return fmt.Errorf("decode payload: %w", err)
+		}
+		if p.Seq != nil {
+			seq.Store(*p.Seq)
+		}
+
+		switch p.Op {
+		case opHello:
+			var hello struct {
+				Interval int64 `json:"heartbeat_interval"`
+			}
+			json.Unmarshal(p.Data, &hello)
+			if hello.Interval <= 0 {
+				return fmt.Errorf("hello without heartbeat interval")
+			}
+			acked.Store(true)
+			go func() {
+				ticker := time.NewTicker(time.Duration(hello.Interval) * time.Millisecond)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-closed:
+						return
+					case <-ticker.C:
+					}
+					// No ACK since the last heartbeat: the connection is dead
+					if !acked.Swap(false) || heartbeat() != nil {
+						conn.Close()
+						return
+					}
+				}
+			}()
+			identify := map[string]interface{}{
+				"token":   c.token,
+				"intents": intents,
+				"properties": map[string]string{
+					"os": "linux", "browser": "goclode", "device": "goclode",
+				},
+			}
+			if err := send(opIdentify, identify); err != nil {
+				return err
+			}
+
+		case opHeartbeat:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+
+		case opHeartbeatACK:
+			acked.Store(true)
+
+		case opReconnect:
+			return nil
+
+		case opInvalidSession:
+			return fmt.Errorf("invalid session")
+
+		case opDispatch:
+			c.dispatch(ctx, p, h, events)
+		}
+	}
+}
+
+// dispatch routes a gateway event
+func (c *Client) dispatch(ctx context.Context, p payload, h Handler, events chan<- func()) {
+	switch p.Type {
+	case "READY":
+		var ready struct {
+			User        User `json:"user"`
+			Application struct {
+				ID string `json:"id"`
+			} `json:"application"`
+		}
+		if json.Unmarshal(p.Data, &ready) == nil {
+			c.UserID, c.AppID = ready.User.ID, ready.Application.ID
+			if h.Ready != nil {
+				events <- h.Ready
+			}
+		}
+
+	case "GUILD_CREATE":
+		var guild struct {
+			Threads []struct {
+				ID string `json:"id"`
+			} `json:"threads"`
+		}
+		if json.Unmarshal(p.Data, &guild) == nil {
+			for _, t := range guild.Threads {
+				c.markThread(t.ID)
+			}
+		}
+
+	case "THREAD_CREATE":
+		var thread struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(p.Data, &thread) == nil {
+			c.markThread(thread.ID)
+		}
+
+	case "MESSAGE_CREATE":
+		var m Message
+		if json.Unmarshal(p.Data, &m) != nil || m.Author.Bot || strings.TrimSpace(m.Content) == "" || h.Message == nil {
+			return
+		}
+		m.InThread = c.isThread(m.ChannelID)
+		events <- func() { h.Message(m) }
+
+	case "INTERACTION_CREATE":
+		var raw struct {
+			ID        string `json:"id"`
+			Token     string `json:"token"`
+			Type      int    `json:"type"`
+			ChannelID string `json:"channel_id"`
+			GuildID   string `json:"guild_id"`
+			Member    struct {
+				User User `json:"user"`
+			} `json:"member"` // In a guild
+			User User `json:"user"` // In direct messages
+			Data struct {
+				Name    string `json:"name"`
+				Options []struct {
+					Name  string      `json:"name"`
+					Value interface{} `json:"value"`
+				} `json:"options"`
+			} `json:"data"`
+		}
+		if json.Unmarshal(p.Data, &raw) != nil || raw.Type != 2 || h.Command == nil {
+			return // Not an application command
+		}
+		if err := c.deferResponse(ctx, raw.ID, raw.Token); err != nil {
+			core.Logger("discord").Warn("Could not acknowledge a command", "command", raw.Data.Name, "error", err)
+			return
+		}
+		in := Interaction{ID: raw.ID, Token: raw.Token, ChannelID: raw.ChannelID, GuildID: raw.GuildID, UserID: raw.User.ID, Name: raw.Data.Name, Options: make(map[string]string)}
+		if in.UserID == "" {
+			in.UserID = raw.Member.User.ID
+		}
+		for _, o := range raw.Data.Options {
+			in.Options[o.Name] = fmt.Sprint(o.Value)
+		}
+		events <- func() { h.Command(in) }
+	}
+}
+
+func (c *Client) markThread(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.threads[id] = true
+}
+
+func (c *Client) isThread(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.threads[id]
+}
@@ -0,0 +1,174 @@
+package discord
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDiscord serves the gateway URL, an interaction callback, and a
+// gateway sending the given dispatches after checking the identify
+type fakeDiscord struct {
+	t          *testing.T
+	dispatches []string
+	mu         sync.Mutex
+	callbacks  []string
+}
+
+func (f *fakeDiscord) handler(wsURL *string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/gateway/bot", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bot test-token" {
+			http.Error(w, `{"message": "401: Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"url": *wsURL})
+	})
+	mux.HandleFunc("/api/interactions/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.callbacks = append(f.callbacks, r.URL.Path)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/gateway/", func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			f.t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + accept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		writeText(rw, `{"op":10,"d":{"heartbeat_interval":60000}}`)
+		rw.Flush()
+
+		identify, err := readText(rw.Reader)
+		if err != nil || !strings.Contains(identify, `"op":2`) || !strings.Contains(identify, `"token":"test-token"`) {
+			f.t.Errorf("Expected an identify, got %s (%v)", identify, err)
+			return
+		}
+		for i, d := range f.dispatches {
+			writeText(rw, `{"op":0,"s":`+string(rune('1'+i))+`,"t":`+d+`}`)
+		}
+		rw.Flush()
+		for {
+			if _, err := readText(rw.Reader); err != nil {
+				return
+			}
+		}
+	})
+	return mux
+}
+
+func TestListen(t *testing.T) {
+	f := &fakeDiscord{t: t, dispatches: []string{
+		`"READY","d":{"user":{"id":"B1","bot":true},"application":{"id":"A1"}}`,
+		`"GUILD_CREATE","d":{"id":"G1","threads":[{"id":"T1"}]}`,
+		`"MESSAGE_CREATE","d":{"id":"M1","channel_id":"T1","guild_id":"G1","content":"hi","author":{"id":"U1"}}`,
+		`"MESSAGE_CREATE","d":{"id":"M2","channel_id":"T1","guild_id":"G1","content":"my reply","author":{"id":"B1","bot":true}}`,
+		`"INTERACTION_CREATE","d":{"id":"I1","token":"tok","type":2,"channel_id":"C1","guild_id":"G1","member":{"user":{"id":"U1"}},"data":{"name":"pr","options":[{"name":"title","value":"Add retries"}]}}`,
+	}}
+	var wsURL string
+	srv := httptest.NewServer(f.handler(&wsURL))
+	defer srv.Close()
+	wsURL = "ws" + strings.TrimPrefix(srv.URL, "http") + "/gateway"
+
+	c := New("test-token")
+	c.APIURL = srv.URL + "/api"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ready := false
+	var messages []Message
+	var commands []Interaction
+	c.Listen(ctx, Handler{
+		Ready:   func() { ready = true },
+		Message: func(m Message) { messages = append(messages, m) },
+		Command: func(in Interaction) {
+			commands = append(commands, in)
+			cancel()
+		},
+	})
+
+	if !ready || c.UserID != "B1" || c.AppID != "A1" {
+		t.Errorf("Expected the bot identified, got ready=%v user=%q app=%q", ready, c.UserID, c.AppID)
+	}
+	if len(messages) != 1 || messages[0].Content != "hi" || !messages[0].InThread {
+		t.Errorf("Expected the person's message in a thread, got %+v", messages)
+	}
+	if len(commands) != 1 || commands[0].Name != "pr" || commands[0].Options["title"] != "Add retries" ||
+		commands[0].UserID != "U1" || commands[0].GuildID != "G1" {
+		t.Errorf("Unexpected commands %+v", commands)
+	}
+	if len(f.callbacks) != 1 || f.callbacks[0] != "/api/interactions/I1/tok/callback" {
+		t.Errorf("Expected the command acknowledged, got %v", f.callbacks)
+	}
+}
+
+func TestListenUnauthorized(t *testing.T) {
+	f := &fakeDiscord{t: t}
+	var wsURL string
+	srv := httptest.NewServer(f.handler(&wsURL))
+	defer srv.Close()
+
+	c := New("bad")
+	c.APIURL = srv.URL + "/api"
+	if err := c.Listen(context.Background(), Handler{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected the authorization error, got %v", err)
+	}
+}
+
+// accept computes Sec-WebSocket-Accept, as a server does
+func accept(key string) string {
+	sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeText writes an unmasked text frame, as a server does
+func writeText(w io.Writer, text string) {
+	head := []byte{0x81}
+	if n := len(text); n < 126 {
+		head = append(head, byte(n))
+	} else {
+		head = append(head, 126, byte(n>>8), byte(n))
+	}
+	w.Write(head)
+	io.WriteString(w, text)
+}
+
+// readText reads a masked client frame
+func readText(r *bufio.Reader) (string, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return "", err
+	}
+	n := int(head[1] & 0x7F)
+	if n == 126 {
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return "", err
+		}
+		n = int(ext[0])<<8 | int(ext[1])
+	}
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(r, mask); err != nil {
+		return "", err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return string(payload), nil
+}
@@ -3,12 +3,15 @@ package git
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/hazyhaar/GoClode/internal/diff"
 )
 
 // Manager handles git operations
@@ -16,8 +19,13 @@ type Manager struct {
 	workDir  string
 	provider string
 	version  string
+	trailers string
 }
 
+// DefaultTrailers is the metadata block appended to GoClode commit messages.
+// Templates may use {version}, {provider}, and {timestamp}; "\n" separates lines.
+const DefaultTrailers = `Generated-by: GoClode v{version}\nProvider: {provider}\nTimestamp: {timestamp}`
+
 // FileChange represents a file change
 type FileChange struct {
 	Path      string
@@ -33,21 +41,33 @@ func NewManager(workDir string) *Manager {
 		workDir, _ = os.Getwd()
 	}
 	return &Manager{
-		workDir: workDir,
-		version: "0.1.0",
+		workDir:  workDir,
+		version:  "0.1.0",
+		trailers: DefaultTrailers,
 	}
 }
 
+// WorkDir returns the working directory git commands run in
+func (m *Manager) WorkDir() string {
+	return m.workDir
+}
+
 // SetProvider sets the current provider name for commit messages
 func (m *Manager) SetProvider(provider string) {
 	m.provider = provider
 }
 
-// IsRepo checks if the current directory is a git repository
+// SetTrailers sets the commit trailer template ("" for none)
+func (m *Manager) SetTrailers(template string) {
+	m.trailers = template
+}
+
+// IsRepo checks if the working directory is inside a git work tree. Asking
+// git (rather than looking for a .git directory) also covers linked
+// worktrees and submodules, where .git is a file pointing at the gitdir.
 func (m *Manager) IsRepo() bool {
-	gitDir := filepath.Join(m.workDir, ".git")
-	info, err := os.Stat(gitDir)
-	return err == nil && info.IsDir()
+	out, err := m.exec("git", "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
 }
 
 // CurrentBranch returns the current git branch
@@ -59,6 +79,23 @@ func (m *Manager) CurrentBranch() (string, error) {
 	return strings.TrimSpace(out), nil
 }
 
+// CreateBranch creates a branch at HEAD and switches to it, keeping
+// uncommitted and staged changes
+func (m *Manager) CreateBranch(name string) error {
+	if _, err := m.exec("git", "switch", "-c", name); err != nil {
+		return fmt.Errorf("create branch %s: %w", name, err)
+	}
+	return nil
+}
+
+// Switch checks out an existing branch
+func (m *Manager) Switch(name string) error {
+	if _, err := m.exec("git", "switch", name); err != nil {
+		return fmt.Errorf("switch to %s: %w", name, err)
+	}
+	return nil
+}
+
 // CurrentCommit returns the current commit hash
 func (m *Manager) CurrentCommit() (string, error) {
 	out, err := m.exec("git", "rev-parse", "HEAD")
@@ -70,16 +107,29 @@ func (m *Manager) CurrentCommit() (string, error) {
 
 // AutoCommit commits changes with GoClode metadata
 func (m *Manager) AutoCommit(files []string, message string) (string, error) {
+	return m.commit(files, message, false)
+}
+
+// AmendCommit folds changes into the last commit, replacing its message
+func (m *Manager) AmendCommit(files []string, message string) (string, error) {
+	return m.commit(files, message, true)
+}
+
+// commit stages files and commits (or amends) with GoClode metadata
+func (m *Manager) commit(files []string, message string, amend bool) (string, error) {
 	if !m.IsRepo() {
 		return "", fmt.Errorf("not a git repository")
 	}
 
-	if len(files) == 0 {
-		return "", fmt.Errorf("no files to commit")
-	}
-
-	// Stage files
+	// Stage files (removals for files no longer on disk); callers may
+	// already have staged hunks with StageEdit
 	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(m.workDir, file)); os.IsNotExist(err) {
+			if _, err := m.exec("git", "rm", "--cached", "--ignore-unmatch", "-q", "--", file); err != nil {
+				return "", fmt.Errorf("stage removal %s: %w", file, err)
+			}
+			continue
+		}
 		if _, err := m.exec("git", "add", file); err != nil {
 			return "", fmt.Errorf("stage %s: %w", file, err)
 		}
@@ -96,21 +146,36 @@ func (m *Manager) AutoCommit(files []string, message string) (string, error) {
 	}
 
 	// Build commit message with metadata
-	provider := m.provider
-	if provider == "" {
-		provider = "unknown"
+	fullMessage := message
+	if trailers := m.renderTrailers(time.Now()); trailers != "" {
+		fullMessage += "\n\n" + trailers
 	}
 
-	fullMessage := fmt.Sprintf(`%s
-
-Generated-by: GoClode v%s
-Provider: %s
-Timestamp: %s`, message, m.version, provider, time.Now().Format(time.RFC3339))
-
-	// Commit
-	if _, err := m.exec("git", "commit", "-m", fullMessage); err != nil {
+	// Commit, keeping hook output (which goes to stdout and stderr) for
+	// feedback, and git's trace of the hooks it ran to tell a rejection by
+	// one of them apart from other failures (no identity, a lock file...)
+	trace, err := os.CreateTemp("", "goclode-commit-trace-*")
+	if err != nil {
 		return "", fmt.Errorf("commit: %w", err)
 	}
+	trace.Close()
+	defer os.Remove(trace.Name())
+
+	args := []string{"commit", "-m", fullMessage}
+	if amend {
+		args = append(args, "--amend")
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = m.workDir
+	cmd.Env = append(os.Environ(), "GIT_TRACE2_EVENT="+trace.Name())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		output := strings.TrimSpace(string(out))
+		events, _ := os.ReadFile(trace.Name())
+		if hook := failedHook(events); hook != "" {
+			return "", &HookError{Hooks: []string{hook}, Output: output}
+		}
+		return "", fmt.Errorf("commit: %s", output)
+	}
 
 	// Get commit hash
 	hash, err := m.CurrentCommit()
@@ -121,24 +186,207 @@ Timestamp: %s`, message, m.version, provider, time.Now().Format(time.RFC3339))
 	return hash, nil
 }
 
-// Undo reverts the last GoClode commit
-func (m *Manager) Undo() (string, error) {
+// HookError reports a commit rejected by the repository's git hooks
+type HookError struct {
+	Hooks  []string // The hook that rejected it
+	Output string   // Everything the commit printed, the hooks' output included
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("rejected by %s hook", strings.Join(e.Hooks, "/"))
+}
+
+// failedHook returns the hook that exited with an error in the trace2
+// events of a git command (GIT_TRACE2_EVENT), or "". Git commands the hooks
+// run add their own events, told apart by their session id.
+func failedHook(events []byte) string {
+	started := make(map[string]string) // Hook by session and child id
+	for _, line := range bytes.Split(events, []byte("\n")) {
+		var ev struct {
+			Event   string   `json:"event"`
+			SID     string   `json:"sid"`
+			ChildID int      `json:"child_id"`
+			Class   string   `json:"child_class"`
+			Hook    string   `json:"hook_name"`
+			Argv    []string `json:"argv"`
+			Code    int      `json:"code"`
+		}
+		if json.Unmarshal(line, &ev) != nil {
+			continue
+		}
+		child := fmt.Sprintf("%s/%d", ev.SID, ev.ChildID)
+		switch ev.Event {
+		case "child_start":
+			if ev.Class != "hook" {
+				continue
+			}
+			name := ev.Hook
+			if name == "" && len(ev.Argv) > 0 {
+				name = filepath.Base(ev.Argv[0]) // Git before 2.36
+			}
+			started[child] = name
+		case "child_exit":
+			if name, ok := started[child]; ok && ev.Code != 0 {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// renderTrailers expands the trailer template
+func (m *Manager) renderTrailers(now time.Time) string {
+	provider := m.provider
+	if provider == "" {
+		provider = "unknown"
+	}
+
+	r := strings.NewReplacer(
+		`\n`, "\n",
+		"{version}", m.version,
+		"{provider}", provider,
+		"{timestamp}", now.Format(time.RFC3339),
+	)
+	return strings.TrimSpace(r.Replace(m.trailers))
+}
+
+// StageEdit stages only GoClode's edit of a file the user had also
+// modified: the diff from before (the working copy GoClode started from) to
+// the file's current content is applied to the index, leaving the user's own
+// edits unstaged. It reports false when the whole file can simply be added
+// (no user edits, or the file is not in the index).
+func (m *Manager) StageEdit(file, before string) (bool, error) {
+	indexed, err := m.exec("git", "show", ":./"+file)
+	if err != nil || indexed == before {
+		return false, nil
+	}
+
+	// git apply reads patch paths relative to the repository root
+	prefix, err := m.exec("git", "rev-parse", "--show-prefix")
+	if err != nil {
+		return false, err
+	}
+
+	after, err := m.GetFileContent(file)
+	if err != nil {
+		return false, err
+	}
+	patch := diff.Unified(strings.TrimSpace(prefix)+filepath.ToSlash(file), before, after)
+	if patch == "" {
+		return true, nil // Nothing of GoClode's left to stage
+	}
+
+	if _, err := m.execInput(patch, "git", "apply", "--cached", "--recount", "-"); err != nil {
+		return false, fmt.Errorf("stage hunks of %s: %w", file, err)
+	}
+	return true, nil
+}
+
+// Add stages files as they are on disk
+func (m *Manager) Add(files ...string) error {
+	args := append([]string{"add", "--"}, files...)
+	if _, err := m.exec("git", args...); err != nil {
+		return fmt.Errorf("stage: %w", err)
+	}
+	return nil
+}
+
+// ConflictedFiles lists files with unresolved merge conflicts
+func (m *Manager) ConflictedFiles() ([]string, error) {
+	out, err := m.exec("git", "diff", "--name-only", "--relative", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Move renames a tracked file with git mv so history follows it
+func (m *Manager) Move(oldPath, newPath string) error {
+	if !m.IsRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	if _, err := m.exec("git", "mv", "--", oldPath, newPath); err != nil {
+		return fmt.Errorf("move %s: %w", oldPath, err)
+	}
+	return nil
+}
+
+// Revert reverts a commit (non-destructive) and returns the new commit's hash
+func (m *Manager) Revert(hash string) (string, error) {
 	if !m.IsRepo() {
 		return "", fmt.Errorf("not a git repository")
 	}
 
-	// Find last GoClode commit
-	lastCommit, err := m.LastGoClodeCommit()
+	if _, err := m.exec("git", "revert", "--no-edit", hash); err != nil {
+		// Leave the tree as it was rather than mid-revert
+		m.exec("git", "revert", "--abort")
+		return "", fmt.Errorf("revert %s: %w", hash, err)
+	}
+
+	return m.CurrentCommit()
+}
+
+// DiffStat summarizes the size of a change
+type DiffStat struct {
+	Files      int
+	Insertions int
+	Deletions  int
+}
+
+// String formats the stat as "+X/−Y across N files"
+func (s DiffStat) String() string {
+	files := "files"
+	if s.Files == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("+%d/−%d across %d %s", s.Insertions, s.Deletions, s.Files, files)
+}
+
+// CommitStat returns a commit's shortstat against its parent
+func (m *Manager) CommitStat(hash string) (DiffStat, error) {
+	out, err := m.exec("git", "show", "--shortstat", "--format=", hash)
 	if err != nil {
-		return "", err
+		return DiffStat{}, err
 	}
+	return parseShortStat(out), nil
+}
 
-	// Revert (non-destructive)
-	if _, err := m.exec("git", "revert", "--no-edit", lastCommit); err != nil {
-		return "", fmt.Errorf("revert %s: %w", lastCommit, err)
+// parseShortStat reads " 3 files changed, 10 insertions(+), 2 deletions(-)"
+func parseShortStat(out string) DiffStat {
+	var stat DiffStat
+	for _, part := range strings.Split(strings.TrimSpace(out), ",") {
+		var n int
+		var kind string
+		if _, err := fmt.Sscanf(strings.TrimSpace(part), "%d %s", &n, &kind); err != nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(kind, "file"):
+			stat.Files = n
+		case strings.HasPrefix(kind, "insertion"):
+			stat.Insertions = n
+		case strings.HasPrefix(kind, "deletion"):
+			stat.Deletions = n
+		}
 	}
+	return stat
+}
+
+// IsPushed reports whether a commit is on any remote-tracking branch
+func (m *Manager) IsPushed(hash string) bool {
+	out, err := m.exec("git", "branch", "-r", "--contains", hash)
+	return err == nil && strings.TrimSpace(out) != ""
+}
 
-	return lastCommit, nil
+// IsAncestor reports whether a commit is part of the current branch's history
+func (m *Manager) IsAncestor(hash string) bool {
+	_, err := m.exec("git", "merge-base", "--is-ancestor", hash, "HEAD")
+	return err == nil
 }
 
 // LastGoClodeCommit returns the hash of the last GoClode commit
@@ -171,6 +419,11 @@ func (m *Manager) GetDiff(file string) (string, error) {
 	return out, nil
 }
 
+// Show returns a commit's message, file stats, and patch
+func (m *Manager) Show(hash string) (string, error) {
+	return m.exec("git", "show", "--stat", "--patch", hash)
+}
+
 // GetLastDiff returns the diff of the last commit
 func (m *Manager) GetLastDiff() (string, error) {
 	out, err := m.exec("git", "diff", "HEAD~1", "HEAD")
@@ -185,6 +438,24 @@ func (m *Manager) Status() (string, error) {
 	return m.exec("git", "status", "--porcelain")
 }
 
+// ShortStatus returns the status in git status --short form
+func (m *Manager) ShortStatus() (string, error) {
+	return m.exec("git", "status", "--short")
+}
+
+// WorkingDiff returns the unstaged changes, or the staged ones; stat gives
+// the per-file summary instead of the patch
+func (m *Manager) WorkingDiff(staged, stat bool) (string, error) {
+	args := []string{"diff"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	if stat {
+		args = append(args, "--stat")
+	}
+	return m.exec("git", args...)
+}
+
 // HasChanges checks if there are uncommitted changes
 func (m *Manager) HasChanges() bool {
 	status, err := m.Status()
@@ -194,6 +465,46 @@ func (m *Manager) HasChanges() bool {
 	return strings.TrimSpace(status) != ""
 }
 
+// HasTrackedChanges checks for uncommitted changes to tracked files
+func (m *Manager) HasTrackedChanges() bool {
+	status, err := m.exec("git", "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(status) != ""
+}
+
+// ChangedFiles lists tracked files with uncommitted changes, staged or not
+func (m *Manager) ChangedFiles() ([]string, error) {
+	out, err := m.exec("git", "diff", "--name-only", "--relative", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Stash saves uncommitted changes to tracked files and cleans the tree.
+// It reports whether anything was stashed.
+func (m *Manager) Stash(message string) (bool, error) {
+	out, err := m.exec("git", "stash", "push", "-m", message)
+	if err != nil {
+		return false, fmt.Errorf("stash: %w", err)
+	}
+	return !strings.Contains(out, "No local changes to save"), nil
+}
+
+// StashPop re-applies the latest stash. On conflict the stash is kept.
+func (m *Manager) StashPop() error {
+	if _, err := m.exec("git", "stash", "pop"); err != nil {
+		return fmt.Errorf("stash pop: %w", err)
+	}
+	return nil
+}
+
 // Init initializes a new git repository
 func (m *Manager) Init() error {
 	if m.IsRepo() {
@@ -204,9 +515,13 @@ func (m *Manager) Init() error {
 	return err
 }
 
-// GetFileContent reads file content (before changes)
+// GetFileContent reads file content (before changes). Relative paths are
+// in the workspace; absolute ones (path_policy allow) are read as they are.
 func (m *Manager) GetFileContent(path string) (string, error) {
-	content, err := os.ReadFile(filepath.Join(m.workDir, path))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.workDir, path)
+	}
+	content, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return "", nil
@@ -216,6 +531,43 @@ func (m *Manager) GetFileContent(path string) (string, error) {
 	return string(content), nil
 }
 
+// RemoteURL returns the URL of a remote
+func (m *Manager) RemoteURL(remote string) (string, error) {
+	out, err := m.exec("git", "remote", "get-url", remote)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// DefaultBranch returns the remote's default branch, falling back to main
+func (m *Manager) DefaultBranch(remote string) string {
+	out, err := m.exec("git", "symbolic-ref", "--short", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		return "main"
+	}
+	return strings.TrimPrefix(strings.TrimSpace(out), remote+"/")
+}
+
+// Push pushes a branch, setting its upstream
+func (m *Manager) Push(remote, branch string) error {
+	_, err := m.exec("git", "push", "--set-upstream", remote, branch)
+	return err
+}
+
+// Subjects returns commit subjects in a revision range (e.g. "main..HEAD"), oldest first
+func (m *Manager) Subjects(revRange string) ([]string, error) {
+	out, err := m.exec("git", "log", "--reverse", "--format=%s", revRange)
+	if err != nil {
+		return nil, err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
 // Log returns recent commits
 func (m *Manager) Log(count int) ([]CommitInfo, error) {
 	if count <= 0 {
@@ -253,6 +605,72 @@ func (m *Manager) Log(count int) ([]CommitInfo, error) {
 	return commits, nil
 }
 
+// BlameLine attributes one line of a file to the commit that last changed it
+type BlameLine struct {
+	Line    int
+	Hash    string
+	Author  string
+	Time    time.Time
+	Summary string
+	Content string
+}
+
+// Blame attributes lines start..end (1-based, inclusive) of a file
+func (m *Manager) Blame(file string, start, end int) ([]BlameLine, error) {
+	out, err := m.exec("git", "blame", "--porcelain", fmt.Sprintf("-L%d,%d", start, end), "--", file)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlame(out), nil
+}
+
+// parseBlame reads git blame --porcelain output. Commit details are only
+// printed the first time a commit appears, so they are remembered by hash.
+func parseBlame(out string) []BlameLine {
+	lines := make([]BlameLine, 0)
+	commits := make(map[string]*BlameLine)
+
+	var cur *BlameLine
+	for _, raw := range strings.Split(out, "\n") {
+		if content, ok := strings.CutPrefix(raw, "\t"); ok {
+			if cur != nil {
+				cur.Content = content
+				lines = append(lines, *cur)
+				cur = nil
+			}
+			continue
+		}
+
+		fields := strings.Fields(raw)
+		if cur == nil {
+			if len(fields) < 3 || len(fields[0]) != 40 {
+				continue
+			}
+			cur = &BlameLine{Hash: fields[0]}
+			fmt.Sscanf(fields[2], "%d", &cur.Line)
+			if known, ok := commits[cur.Hash]; ok {
+				cur.Author, cur.Time, cur.Summary = known.Author, known.Time, known.Summary
+			} else {
+				commits[cur.Hash] = cur
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(raw, " ")
+		switch key {
+		case "author":
+			cur.Author = value
+		case "author-time":
+			var ts int64
+			fmt.Sscanf(value, "%d", &ts)
+			cur.Time = time.Unix(ts, 0)
+		case "summary":
+			cur.Summary = value
+		}
+	}
+	return lines
+}
+
 // CommitInfo represents a git commit
 type CommitInfo struct {
 	Hash      string
@@ -264,8 +682,24 @@ type CommitInfo struct {
 
 // exec runs a git command and returns output
 func (m *Manager) exec(name string, args ...string) (string, error) {
+	return m.execInput("", name, args...)
+}
+
+// execInput runs a git command with input on stdin and returns output
+func (m *Manager) execInput(input, name string, args ...string) (string, error) {
+	return m.execEnv(nil, input, name, args...)
+}
+
+// execEnv runs a git command with extra environment variables
+func (m *Manager) execEnv(env []string, input, name string, args ...string) (string, error) {
 	cmd := exec.Command(name, args...)
 	cmd.Dir = m.workDir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -0,0 +1,324 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitRun runs a git command in dir, failing the test on error
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestIsRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	plain := t.TempDir()
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q")
+	if err := os.WriteFile(filepath.Join(repo, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, repo, "add", "a.txt")
+	gitRun(t, repo, "commit", "-q", "-m", "init")
+
+	worktree := filepath.Join(t.TempDir(), "wt")
+	gitRun(t, repo, "worktree", "add", "-q", worktree)
+
+	sub := filepath.Join(repo, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want bool
+	}{
+		{"plain directory", plain, false},
+		{"repository root", repo, true},
+		{"subdirectory", sub, true},
+		{"linked worktree", worktree, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewManager(tt.dir).IsRepo(); got != tt.want {
+				t.Errorf("IsRepo(%s) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFileContent(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(dir, "in.txt"), []byte("in\n"), 0644)
+	os.WriteFile(filepath.Join(outside, "out.txt"), []byte("out\n"), 0644)
+	m := NewManager(dir)
+
+	if got, err := m.GetFileContent("in.txt"); err != nil || got != "in\n" {
+		t.Errorf("relative path = %q, %v", got, err)
+	}
+	if got, err := m.GetFileContent(filepath.Join(outside, "out.txt")); err != nil || got != "out\n" {
+		t.Errorf("absolute path = %q, %v", got, err)
+	}
+	if got, err := m.GetFileContent("missing.txt"); err != nil || got != "" {
+		t.Errorf("missing file = %q, %v; want empty", got, err)
+	}
+}
+
+func TestRenderTrailers(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		template string
+		provider string
+		want     string
+	}{
+		{"default", DefaultTrailers, "cerebras",
+			"Generated-by: GoClode v0.1.0\nProvider: cerebras\nTimestamp: 2024-05-01T12:00:00Z"},
+		{"unknown provider", "Provider: {provider}", "", "Provider: unknown"},
+		{"co-author", `Co-authored-by: GoClode <bot@example.com>\nRefs: JIRA-1`, "x",
+			"Co-authored-by: GoClode <bot@example.com>\nRefs: JIRA-1"},
+		{"none", "", "x", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(t.TempDir())
+			m.SetProvider(tt.provider)
+			m.SetTrailers(tt.template)
+			if got := m.renderTrailers(now); got != tt.want {
+				t.Errorf("renderTrailers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStageEdit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q")
+	sub := filepath.Join(repo, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	original := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	path := filepath.Join(sub, "f.txt")
+	os.WriteFile(path, []byte(original), 0644)
+	gitRun(t, repo, "add", ".")
+	gitRun(t, repo, "commit", "-q", "-m", "init")
+
+	// The user edits the first line, then GoClode edits the last one
+	before := "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	after := "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"
+	os.WriteFile(path, []byte(after), 0644)
+
+	m := NewManager(sub) // Run from a subdirectory to exercise path prefixes
+	staged, err := m.StageEdit("f.txt", before)
+	if err != nil || !staged {
+		t.Fatalf("StageEdit = %v, %v; want true, nil", staged, err)
+	}
+
+	indexed, err := m.exec("git", "show", ":./f.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"; indexed != want {
+		t.Errorf("index = %q, want only GoClode's hunk %q", indexed, want)
+	}
+
+	// Without user edits the whole file is staged by the caller
+	if staged, err := m.StageEdit("f.txt", indexed); err != nil || staged {
+		t.Errorf("StageEdit on an unmodified file = %v, %v; want false, nil", staged, err)
+	}
+}
+
+func TestAutoCommit_HookError(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q")
+	gitRun(t, repo, "config", "user.name", "test")
+	gitRun(t, repo, "config", "user.email", "test@example.com")
+
+	hook := filepath.Join(repo, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\necho 'lint: main.go:3 unused variable'\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n"), 0644)
+
+	m := NewManager(repo)
+	_, err := m.AutoCommit([]string{"main.go"}, "add main")
+
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("AutoCommit error = %v, want *HookError", err)
+	}
+	if !strings.Contains(hookErr.Output, "unused variable") {
+		t.Errorf("hook output = %q, want the hook's message", hookErr.Output)
+	}
+
+	// The failing hook is named from the commit's own run, not run again
+	os.WriteFile(hook, []byte("#!/bin/sh\necho run >> runs.log\n"), 0755)
+	msgHook := filepath.Join(repo, ".git", "hooks", "commit-msg")
+	os.WriteFile(msgHook, []byte("#!/bin/sh\necho 'subject too short' >&2\nexit 1\n"), 0755)
+	_, err = m.AutoCommit([]string{"main.go"}, "add main")
+	if !errors.As(err, &hookErr) || hookErr.Hooks[0] != "commit-msg" || !strings.Contains(hookErr.Output, "subject too short") {
+		t.Errorf("AutoCommit error = %v, want the commit-msg hook's", err)
+	}
+	if runs, _ := os.ReadFile(filepath.Join(repo, "runs.log")); string(runs) != "run\n" {
+		t.Errorf("pre-commit ran %q, want once", runs)
+	}
+
+	// Without the hooks the same commit succeeds
+	os.Remove(msgHook)
+	os.Remove(hook)
+	if _, err := m.AutoCommit([]string{"main.go"}, "add main"); err != nil {
+		t.Errorf("AutoCommit without hook: %v", err)
+	}
+}
+
+func TestAutoCommit_OtherFailure(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	// No identity anywhere: the commit fails, but not because of the hook
+	empty := filepath.Join(t.TempDir(), "gitconfig")
+	os.WriteFile(empty, nil, 0644)
+	t.Setenv("GIT_CONFIG_GLOBAL", empty)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL", "EMAIL"} {
+		t.Setenv(v, "")
+		os.Unsetenv(v)
+	}
+
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q")
+	gitRun(t, repo, "config", "user.useConfigOnly", "true")
+	hook := filepath.Join(repo, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n"), 0644)
+
+	m := NewManager(repo)
+	_, err := m.AutoCommit([]string{"main.go"}, "add main")
+	if err == nil {
+		t.Fatal("Expected the commit to fail without an identity")
+	}
+	var hookErr *HookError
+	if errors.As(err, &hookErr) {
+		t.Errorf("AutoCommit error = %v, want a plain error", err)
+	}
+}
+
+func TestParseShortStat(t *testing.T) {
+	tests := []struct {
+		out  string
+		want DiffStat
+	}{
+		{" 3 files changed, 10 insertions(+), 2 deletions(-)\n", DiffStat{3, 10, 2}},
+		{" 1 file changed, 1 insertion(+)\n", DiffStat{1, 1, 0}},
+		{" 2 files changed, 7 deletions(-)\n", DiffStat{2, 0, 7}},
+		{"", DiffStat{}},
+	}
+
+	for _, tt := range tests {
+		if got := parseShortStat(tt.out); got != tt.want {
+			t.Errorf("parseShortStat(%q) = %+v, want %+v", tt.out, got, tt.want)
+		}
+	}
+}
+
+func TestBlame(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q")
+	path := filepath.Join(repo, "f.txt")
+
+	os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0644)
+	gitRun(t, repo, "add", ".")
+	gitRun(t, repo, "commit", "-q", "-m", "initial")
+	os.WriteFile(path, []byte("one\nTWO\nthree\n"), 0644)
+	gitRun(t, repo, "commit", "-q", "-am", "shout two")
+
+	lines, err := NewManager(repo).Blame("f.txt", 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+
+	want := []struct {
+		content, summary string
+	}{{"one", "initial"}, {"TWO", "shout two"}, {"three", "initial"}}
+	for i, w := range want {
+		l := lines[i]
+		if l.Line != i+1 || l.Content != w.content || l.Summary != w.summary || l.Author != "test" {
+			t.Errorf("line %d = %+v, want %q from %q by test", i+1, l, w.content, w.summary)
+		}
+	}
+	if lines[0].Hash != lines[2].Hash || lines[0].Hash == lines[1].Hash {
+		t.Errorf("hashes = %s %s %s, want lines 1 and 3 from the same commit", lines[0].Hash, lines[1].Hash, lines[2].Hash)
+	}
+}
+
+func TestWorkingDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q")
+	os.WriteFile(filepath.Join(repo, "a.txt"), []byte("a\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "b.txt"), []byte("b\n"), 0644)
+	gitRun(t, repo, "add", ".")
+	gitRun(t, repo, "commit", "-q", "-m", "init")
+
+	os.WriteFile(filepath.Join(repo, "a.txt"), []byte("staged\n"), 0644)
+	gitRun(t, repo, "add", "a.txt")
+	os.WriteFile(filepath.Join(repo, "b.txt"), []byte("unstaged\n"), 0644)
+
+	m := NewManager(repo)
+	status, err := m.ShortStatus()
+	if err != nil || !strings.Contains(status, "M  a.txt") || !strings.Contains(status, " M b.txt") {
+		t.Errorf("ShortStatus = %q, %v", status, err)
+	}
+	if patch, _ := m.WorkingDiff(true, false); !strings.Contains(patch, "+staged") || strings.Contains(patch, "b.txt") {
+		t.Errorf("staged patch = %q, want only a.txt", patch)
+	}
+	if stat, _ := m.WorkingDiff(false, true); !strings.Contains(stat, "b.txt") || strings.Contains(stat, "+unstaged") {
+		t.Errorf("unstaged stat = %q, want a summary of b.txt", stat)
+	}
+	if files, err := m.ChangedFiles(); err != nil || strings.Join(files, ",") != "a.txt,b.txt" {
+		t.Errorf("ChangedFiles = %v, %v; want a.txt and b.txt", files, err)
+	}
+}
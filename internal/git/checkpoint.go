@@ -0,0 +1,235 @@
+// Package git - Checkpoints: the whole working tree, untracked files
+// included, saved as a commit under refs/goclode/checkpoints without
+// touching the branch, the index, or the files
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkpointRefs is where checkpoint commits are kept
+const checkpointRefs = "refs/goclode/checkpoints/"
+
+// stateDir is GoClode's directory in the workspace, left out of checkpoints
+const stateDir = ".goclode"
+
+// Checkpoint is a saved working tree
+type Checkpoint struct {
+	Name    string
+	Hash    string // Commit of the saved tree
+	Head    string // HEAD when it was taken, "" on an unborn branch
+	Created time.Time
+}
+
+// CreateCheckpoint saves the working tree, ignored files excepted, as name
+func (m *Manager) CreateCheckpoint(name string) (*Checkpoint, error) {
+	if _, err := m.Checkpoint(name); err == nil {
+		return nil, fmt.Errorf("checkpoint %s already exists", name)
+	}
+	top, err := m.toplevel()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := top.snapshotTree()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"commit-tree", tree, "-m", "GoClode checkpoint " + name}
+	head, _ := top.exec("git", "rev-parse", "--verify", "-q", "HEAD")
+	if head = strings.TrimSpace(head); head != "" {
+		args = append(args, "-p", head)
+	}
+	hash, err := top.exec("git", args...)
+	if err != nil {
+		return nil, err
+	}
+	hash = strings.TrimSpace(hash)
+	if _, err := top.exec("git", "update-ref", checkpointRefs+name, hash); err != nil {
+		return nil, err
+	}
+	return &Checkpoint{Name: name, Hash: hash, Head: head, Created: time.Now()}, nil
+}
+
+// Checkpoints lists the checkpoints, newest first
+func (m *Manager) Checkpoints() ([]Checkpoint, error) {
+	out, err := m.exec("git", "for-each-ref", "--sort=-creatordate",
+		"--format=%(refname:strip=3)%09%(objectname)%09%(parent)%09%(creatordate:unix)", checkpointRefs)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := make([]Checkpoint, 0)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		unix, _ := strconv.ParseInt(fields[3], 10, 64)
+		checkpoints = append(checkpoints, Checkpoint{
+			Name:    fields[0],
+			Hash:    fields[1],
+			Head:    fields[2],
+			Created: time.Unix(unix, 0),
+		})
+	}
+	return checkpoints, nil
+}
+
+// Checkpoint returns the checkpoint called name
+func (m *Manager) Checkpoint(name string) (*Checkpoint, error) {
+	checkpoints, err := m.Checkpoints()
+	if err != nil {
+		return nil, err
+	}
+	for _, cp := range checkpoints {
+		if cp.Name == name {
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("no checkpoint %s", name)
+}
+
+// DeleteCheckpoint removes a checkpoint
+func (m *Manager) DeleteCheckpoint(name string) error {
+	cp, err := m.Checkpoint(name)
+	if err != nil {
+		return err
+	}
+	_, err = m.exec("git", "update-ref", "-d", checkpointRefs+cp.Name)
+	return err
+}
+
+// RestoreCheckpoint puts the working tree back as the checkpoint saved
+// it: its files written, files added since removed, and the branch moved
+// back over commits made since. The index is reset to the branch.
+func (m *Manager) RestoreCheckpoint(cp *Checkpoint) error {
+	top, err := m.toplevel()
+	if err != nil {
+		return err
+	}
+	head, _ := top.exec("git", "rev-parse", "--verify", "-q", "HEAD")
+	head = strings.TrimSpace(head)
+	if cp.Head != head && (cp.Head == "" || !top.IsAncestor(cp.Head)) {
+		return fmt.Errorf("checkpoint %s was taken on another history: switch back to its branch first", cp.Name)
+	}
+
+	current, err := top.snapshotFiles()
+	if err != nil {
+		return err
+	}
+	saved, err := top.exec("git", "ls-tree", "-r", "-z", "--name-only", cp.Hash)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool)
+	for _, path := range strings.Split(saved, "\x00") {
+		keep[path] = true
+	}
+
+	index, cleanup, err := top.scratchIndex(false)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	env := []string{"GIT_INDEX_FILE=" + index}
+	if _, err := top.execEnv(env, "", "git", "read-tree", cp.Hash); err != nil {
+		return err
+	}
+	if _, err := top.execEnv(env, "", "git", "checkout-index", "-a", "-f"); err != nil {
+		return err
+	}
+	for _, path := range current {
+		if path != "" && !keep[path] {
+			if err := os.Remove(filepath.Join(top.workDir, filepath.FromSlash(path))); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	if cp.Head == "" {
+		return nil // Unborn branch: no commit to reset to
+	}
+	_, err = top.exec("git", "reset", "-q", cp.Head)
+	return err
+}
+
+// snapshotTree writes the working tree, ignored files and .goclode
+// excepted, as a tree object and returns its hash
+func (m *Manager) snapshotTree() (string, error) {
+	index, cleanup, err := m.scratchIndex(true)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+	env := []string{"GIT_INDEX_FILE=" + index}
+	if _, err := m.execEnv(env, "", "git", "add", "-A", "--", ".", ":(exclude)"+stateDir); err != nil {
+		return "", err
+	}
+	// GoClode's own state, session databases included, is never rolled back
+	if _, err := m.execEnv(env, "", "git", "rm", "-r", "-q", "--cached", "--ignore-unmatch", stateDir); err != nil {
+		return "", err
+	}
+	tree, err := m.execEnv(env, "", "git", "write-tree")
+	return strings.TrimSpace(tree), err
+}
+
+// snapshotFiles lists the files of the working tree a checkpoint would
+// save
+func (m *Manager) snapshotFiles() ([]string, error) {
+	tree, err := m.snapshotTree()
+	if err != nil {
+		return nil, err
+	}
+	out, err := m.exec("git", "ls-tree", "-r", "-z", "--name-only", tree)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(out, "\x00"), nil
+}
+
+// scratchIndex returns a temporary index file, a copy of the repository's
+// when seeded (its cached file stats spare rehashing unchanged files)
+func (m *Manager) scratchIndex(seeded bool) (string, func(), error) {
+	f, err := os.CreateTemp("", "goclode-index-*")
+	if err != nil {
+		return "", nil, err
+	}
+	path := f.Name()
+	cleanup := func() { os.Remove(path) }
+
+	copied := false
+	if seeded {
+		real, err := m.exec("git", "rev-parse", "--git-path", "index")
+		if real = strings.TrimSpace(real); err == nil && !filepath.IsAbs(real) {
+			real = filepath.Join(m.workDir, real)
+		}
+		if data, err := os.ReadFile(real); err == nil && len(data) > 0 {
+			if _, err := f.Write(data); err != nil {
+				f.Close()
+				cleanup()
+				return "", nil, err
+			}
+			copied = true
+		}
+	}
+	f.Close()
+	if !copied {
+		os.Remove(path) // Git wants a missing index, not an empty file
+	}
+	return path, cleanup, nil
+}
+
+// toplevel returns a manager at the repository's top directory, where
+// index paths are rooted
+func (m *Manager) toplevel() (*Manager, error) {
+	dir, err := m.exec("git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	return NewManager(strings.TrimSpace(dir)), nil
+}
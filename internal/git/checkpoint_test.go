@@ -0,0 +1,86 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	repo := t.TempDir()
+	gitRun(t, repo, "init", "-q")
+	gitRun(t, repo, "config", "user.name", "test")
+	gitRun(t, repo, "config", "user.email", "test@example.com")
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(repo, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitignore", "*.log\n")
+	write("a.txt", "a\n")
+	gitRun(t, repo, "add", ".")
+	gitRun(t, repo, "commit", "-q", "-m", "init")
+	write("a.txt", "edited, not committed\n")
+	write("notes.txt", "untracked\n")
+	write("run.log", "ignored\n")
+	write(".goclode/session.db", "state\n")
+
+	m := NewManager(repo)
+	cp, err := m.CreateCheckpoint("before")
+	if err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+	if status, _ := m.ShortStatus(); !strings.Contains(status, " M a.txt") || !strings.Contains(status, "?? notes.txt") {
+		t.Errorf("Expected the working tree untouched, got %q", status)
+	}
+	if _, err := m.CreateCheckpoint("before"); err == nil {
+		t.Error("Expected a second checkpoint of the same name refused")
+	}
+
+	// An agent run gone wrong: edits, a commit, a new file, a removal
+	write("a.txt", "broken\n")
+	gitRun(t, repo, "commit", "-q", "-am", "agent step")
+	write("b.txt", "new\n")
+	os.Remove(filepath.Join(repo, "notes.txt"))
+	write(".goclode/session.db", "state, later\n")
+
+	if err := m.RestoreCheckpoint(cp); err != nil {
+		t.Fatalf("RestoreCheckpoint: %v", err)
+	}
+	for name, want := range map[string]string{
+		"a.txt":               "edited, not committed\n",
+		"notes.txt":           "untracked\n",
+		"run.log":             "ignored\n",
+		".goclode/session.db": "state, later\n",
+	} {
+		if got, _ := os.ReadFile(filepath.Join(repo, name)); string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repo, "b.txt")); !os.IsNotExist(err) {
+		t.Error("Expected the file added since removed")
+	}
+	if head, _ := m.CurrentCommit(); head != cp.Head {
+		t.Errorf("Expected the branch back at %s, got %s", cp.Head, head)
+	}
+
+	list, err := m.Checkpoints()
+	if err != nil || len(list) != 1 || list[0].Name != "before" || list[0].Hash != cp.Hash {
+		t.Errorf("Checkpoints = %+v, %v", list, err)
+	}
+	if err := m.DeleteCheckpoint("before"); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := m.Checkpoints(); len(list) != 0 {
+		t.Errorf("Expected no checkpoints left, got %+v", list)
+	}
+}
@@ -0,0 +1,235 @@
+// Package git - Pull/merge requests on GitHub, GitLab, and Bitbucket
+package git
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Forge names
+const (
+	ForgeGitHub    = "github"
+	ForgeGitLab    = "gitlab"
+	ForgeBitbucket = "bitbucket"
+)
+
+// Forge opens pull requests (merge requests on GitLab) on a hosting service
+type Forge interface {
+	// Name returns the forge identifier
+	Name() string
+
+	// CreatePullRequest opens a request and returns its web URL
+	CreatePullRequest(ctx context.Context, pr PullRequest) (string, error)
+}
+
+// PullRequest describes a request to merge Head into Base
+type PullRequest struct {
+	Title string
+	Body  string
+	Head  string // Source branch
+	Base  string // Target branch
+}
+
+// Remote is a parsed git remote URL
+type Remote struct {
+	Host string
+	Path string // owner/repo, or group/subgroup/repo on GitLab
+}
+
+var scpRemotePattern = regexp.MustCompile(`^(?:[\w.-]+@)?([\w.-]+):(.+)$`)
+
+// ParseRemoteURL understands https://, ssh://, and scp-like (git@host:path) remotes
+func ParseRemoteURL(raw string) (*Remote, error) {
+	raw = strings.TrimSpace(raw)
+
+	var host, path string
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse remote %q: %w", raw, err)
+		}
+		host, path = u.Hostname(), u.Path
+	} else if m := scpRemotePattern.FindStringSubmatch(raw); m != nil {
+		host, path = m[1], m[2]
+	} else {
+		return nil, fmt.Errorf("unrecognized remote URL %q", raw)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return nil, fmt.Errorf("remote %q has no owner/repository path", raw)
+	}
+	return &Remote{Host: host, Path: path}, nil
+}
+
+// DetectForgeType guesses the forge from the remote host; "" if unknown
+func DetectForgeType(remote *Remote) string {
+	host := strings.ToLower(remote.Host)
+	switch {
+	case strings.Contains(host, "github"):
+		return ForgeGitHub
+	case strings.Contains(host, "gitlab"):
+		return ForgeGitLab
+	case strings.Contains(host, "bitbucket"):
+		return ForgeBitbucket
+	}
+	return ""
+}
+
+// NewForge creates a forge client. forgeType may be "" to detect it from
+// the remote host (set it explicitly for self-hosted instances).
+func NewForge(remote *Remote, forgeType, token string) (Forge, error) {
+	if forgeType == "" {
+		forgeType = DetectForgeType(remote)
+	}
+	if forgeType == "" {
+		return nil, fmt.Errorf("cannot detect the forge for %s (set forge_type)", remote.Host)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("no %s token configured", forgeType)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	switch forgeType {
+	case ForgeGitHub:
+		apiURL := "https://api.github.com"
+		if remote.Host != "github.com" {
+			apiURL = "https://" + remote.Host + "/api/v3" // GitHub Enterprise
+		}
+		return &githubForge{apiURL: apiURL, remote: remote, token: token, client: client}, nil
+	case ForgeGitLab:
+		return &gitlabForge{apiURL: "https://" + remote.Host + "/api/v4", remote: remote, token: token, client: client}, nil
+	case ForgeBitbucket:
+		return &bitbucketForge{apiURL: "https://api.bitbucket.org/2.0", remote: remote, token: token, client: client}, nil
+	}
+	return nil, fmt.Errorf("unsupported forge %q", forgeType)
+}
+
+// postJSON sends a JSON request and decodes the JSON response into out
+func postJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, in, out interface{}) error {
+	return sendJSON(ctx, client, "POST", endpoint, headers, in, out)
+}
+
+// sendJSON sends a request, with a JSON body unless in is nil, and decodes
+// the JSON response into out
+func sendJSON(ctx context.Context, client *http.Client, method, endpoint string, headers map[string]string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("API error %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// githubForge uses the GitHub REST API
+type githubForge struct {
+	apiURL string
+	remote *Remote
+	token  string
+	client *http.Client
+}
+
+func (f *githubForge) Name() string { return ForgeGitHub }
+
+func (f *githubForge) CreatePullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	var out struct {
+		HTMLURL string `json:"html_url"`
+	}
+	err := postJSON(ctx, f.client, fmt.Sprintf("%s/repos/%s/pulls", f.apiURL, f.remote.Path),
+		map[string]string{"Authorization": "Bearer " + f.token},
+		map[string]string{"title": pr.Title, "body": pr.Body, "head": pr.Head, "base": pr.Base},
+		&out)
+	return out.HTMLURL, err
+}
+
+// gitlabForge uses the GitLab REST API (merge requests)
+type gitlabForge struct {
+	apiURL string
+	remote *Remote
+	token  string
+	client *http.Client
+}
+
+func (f *gitlabForge) Name() string { return ForgeGitLab }
+
+func (f *gitlabForge) CreatePullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	var out struct {
+		WebURL string `json:"web_url"`
+	}
+	err := postJSON(ctx, f.client, fmt.Sprintf("%s/projects/%s/merge_requests", f.apiURL, url.PathEscape(f.remote.Path)),
+		map[string]string{"PRIVATE-TOKEN": f.token},
+		map[string]string{"title": pr.Title, "description": pr.Body, "source_branch": pr.Head, "target_branch": pr.Base},
+		&out)
+	return out.WebURL, err
+}
+
+// bitbucketForge uses the Bitbucket Cloud REST API
+type bitbucketForge struct {
+	apiURL string
+	remote *Remote
+	token  string
+	client *http.Client
+}
+
+func (f *bitbucketForge) Name() string { return ForgeBitbucket }
+
+func (f *bitbucketForge) CreatePullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	type branch struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+	}
+	var source, destination branch
+	source.Branch.Name = pr.Head
+	destination.Branch.Name = pr.Base
+
+	var out struct {
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	}
+	err := postJSON(ctx, f.client, fmt.Sprintf("%s/repositories/%s/pullrequests", f.apiURL, f.remote.Path),
+		map[string]string{"Authorization": "Bearer " + f.token},
+		map[string]interface{}{"title": pr.Title, "description": pr.Body, "source": source, "destination": destination},
+		&out)
+	return out.Links.HTML.Href, err
+}
@@ -0,0 +1,183 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		host  string
+		path  string
+		forge string
+	}{
+		{"git@github.com:hazyhaar/GoClode.git", "github.com", "hazyhaar/GoClode", ForgeGitHub},
+		{"https://github.com/hazyhaar/GoClode", "github.com", "hazyhaar/GoClode", ForgeGitHub},
+		{"ssh://git@gitlab.example.com:2222/group/sub/repo.git", "gitlab.example.com", "group/sub/repo", ForgeGitLab},
+		{"https://bitbucket.org/team/repo.git", "bitbucket.org", "team/repo", ForgeBitbucket},
+		{"git@git.internal:team/repo.git", "git.internal", "team/repo", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			r, err := ParseRemoteURL(tt.url)
+			if err != nil {
+				t.Fatalf("ParseRemoteURL failed: %v", err)
+			}
+			if r.Host != tt.host || r.Path != tt.path {
+				t.Errorf("got %s %s, want %s %s", r.Host, r.Path, tt.host, tt.path)
+			}
+			if got := DetectForgeType(r); got != tt.forge {
+				t.Errorf("DetectForgeType() = %q, want %q", got, tt.forge)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "not a url", "https://github.com/only-owner"} {
+		if _, err := ParseRemoteURL(bad); err == nil {
+			t.Errorf("ParseRemoteURL(%q) should fail", bad)
+		}
+	}
+}
+
+func TestForges_CreatePullRequest(t *testing.T) {
+	remote := &Remote{Host: "example.com", Path: "group/repo"}
+	pr := PullRequest{Title: "Add feature", Body: "- change", Head: "feature", Base: "main"}
+
+	tests := []struct {
+		forge    string
+		path     string
+		auth     string
+		response string
+		check    func(t *testing.T, body map[string]interface{})
+	}{
+		{
+			forge:    ForgeGitHub,
+			path:     "/repos/group/repo/pulls",
+			auth:     "Authorization",
+			response: `{"html_url": "https://example.com/pr/1"}`,
+			check: func(t *testing.T, body map[string]interface{}) {
+				if body["head"] != "feature" || body["base"] != "main" {
+					t.Errorf("body = %v", body)
+				}
+			},
+		},
+		{
+			forge:    ForgeGitLab,
+			path:     "/projects/group%2Frepo/merge_requests",
+			auth:     "Private-Token",
+			response: `{"web_url": "https://example.com/pr/1"}`,
+			check: func(t *testing.T, body map[string]interface{}) {
+				if body["source_branch"] != "feature" || body["target_branch"] != "main" {
+					t.Errorf("body = %v", body)
+				}
+			},
+		},
+		{
+			forge:    ForgeBitbucket,
+			path:     "/repositories/group/repo/pullrequests",
+			auth:     "Authorization",
+			response: `{"links": {"html": {"href": "https://example.com/pr/1"}}}`,
+			check: func(t *testing.T, body map[string]interface{}) {
+				src, _ := body["source"].(map[string]interface{})
+				if src == nil || src["branch"].(map[string]interface{})["name"] != "feature" {
+					t.Errorf("body = %v", body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.forge, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.EscapedPath() != tt.path {
+					t.Errorf("path = %s, want %s", r.URL.EscapedPath(), tt.path)
+				}
+				if r.Header.Get(tt.auth) == "" {
+					t.Errorf("missing %s header", tt.auth)
+				}
+				var body map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&body)
+				tt.check(t, body)
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(tt.response))
+			}))
+			defer srv.Close()
+
+			forge, err := NewForge(remote, tt.forge, "token")
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch f := forge.(type) {
+			case *githubForge:
+				f.apiURL = srv.URL
+			case *gitlabForge:
+				f.apiURL = srv.URL
+			case *bitbucketForge:
+				f.apiURL = srv.URL
+			}
+
+			url, err := forge.CreatePullRequest(context.Background(), pr)
+			if err != nil {
+				t.Fatalf("CreatePullRequest failed: %v", err)
+			}
+			if url != "https://example.com/pr/1" {
+				t.Errorf("url = %q", url)
+			}
+		})
+	}
+
+	if _, err := NewForge(remote, "", "token"); err == nil {
+		t.Error("undetectable forge should fail")
+	}
+	if _, err := NewForge(remote, ForgeGitHub, ""); err == nil {
+		t.Error("missing token should fail")
+	}
+}
+
+func TestGitHubForge_Review(t *testing.T) {
+	var posted map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/owner/repo/pulls/7":
+			w.Write([]byte(`{"head": {"sha": "abc123"}}`))
+		case r.Method == "GET" && r.URL.Path == "/repos/owner/repo/pulls/7/files":
+			w.Write([]byte(`[{"filename": "main.go", "status": "modified", "patch": "@@ -1 +1 @@\n-a\n+b"}]`))
+		case r.Method == "POST" && r.URL.Path == "/repos/owner/repo/pulls/7/reviews":
+			json.NewDecoder(r.Body).Decode(&posted)
+			w.Write([]byte(`{"id": 1}`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	forge, err := NewForge(&Remote{Host: "github.com", Path: "owner/repo"}, ForgeGitHub, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forge.(*githubForge).apiURL = srv.URL
+	reviewer := forge.(Reviewer)
+
+	head, files, err := reviewer.PullRequestFiles(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("PullRequestFiles failed: %v", err)
+	}
+	if head != "abc123" || len(files) != 1 || files[0].Path != "main.go" || files[0].Patch == "" {
+		t.Errorf("head = %q, files = %+v", head, files)
+	}
+
+	err = reviewer.PostReview(context.Background(), 7, head, "summary", []ReviewComment{{Path: "main.go", Line: 1, Body: "bug"}})
+	if err != nil {
+		t.Fatalf("PostReview failed: %v", err)
+	}
+	comments, _ := posted["comments"].([]interface{})
+	if posted["commit_id"] != "abc123" || posted["event"] != "COMMENT" || len(comments) != 1 {
+		t.Errorf("posted = %v", posted)
+	}
+}
@@ -0,0 +1,95 @@
+// Package git - Pull request reviews: the changed files, and line comments
+package git
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxReviewFilePages bounds the pages of changed files read (100 each)
+const maxReviewFilePages = 30
+
+// PullRequestFile is a file a pull request changes
+type PullRequestFile struct {
+	Path   string
+	Status string // added, modified, removed, renamed...
+	Patch  string // Unified diff hunks; empty for binary or huge files
+}
+
+// ReviewComment is a comment on a line of a pull request's new version
+type ReviewComment struct {
+	Path string
+	Line int
+	Body string
+}
+
+// Reviewer reads pull requests and posts reviews. Only the GitHub forge
+// implements it.
+type Reviewer interface {
+	// PullRequestFiles returns the head commit and the changed files
+	PullRequestFiles(ctx context.Context, number int) (string, []PullRequestFile, error)
+
+	// PostReview posts a comment review of commit: a body and line comments
+	PostReview(ctx context.Context, number int, commit, body string, comments []ReviewComment) error
+}
+
+func (f *githubForge) PullRequestFiles(ctx context.Context, number int) (string, []PullRequestFile, error) {
+	headers := map[string]string{"Authorization": "Bearer " + f.token}
+	base := fmt.Sprintf("%s/repos/%s/pulls/%d", f.apiURL, f.remote.Path, number)
+
+	var pr struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := sendJSON(ctx, f.client, "GET", base, headers, nil, &pr); err != nil {
+		return "", nil, fmt.Errorf("pull request #%d: %w", number, err)
+	}
+
+	files := make([]PullRequestFile, 0)
+	for page := 1; page <= maxReviewFilePages; page++ {
+		var batch []struct {
+			Filename string `json:"filename"`
+			Status   string `json:"status"`
+			Patch    string `json:"patch"`
+		}
+		if err := sendJSON(ctx, f.client, "GET", fmt.Sprintf("%s/files?per_page=100&page=%d", base, page), headers, nil, &batch); err != nil {
+			return "", nil, fmt.Errorf("pull request #%d files: %w", number, err)
+		}
+		for _, file := range batch {
+			files = append(files, PullRequestFile{Path: file.Filename, Status: file.Status, Patch: file.Patch})
+		}
+		if len(batch) < 100 {
+			break
+		}
+	}
+	return pr.Head.SHA, files, nil
+}
+
+func (f *githubForge) PostReview(ctx context.Context, number int, commit, body string, comments []ReviewComment) error {
+	type comment struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Side string `json:"side"`
+		Body string `json:"body"`
+	}
+	in := struct {
+		CommitID string    `json:"commit_id"`
+		Body     string    `json:"body"`
+		Event    string    `json:"event"`
+		Comments []comment `json:"comments"`
+	}{CommitID: commit, Body: body, Event: "COMMENT", Comments: make([]comment, 0, len(comments))}
+	for _, c := range comments {
+		in.Comments = append(in.Comments, comment{Path: c.Path, Line: c.Line, Side: "RIGHT", Body: c.Body})
+	}
+
+	var out struct {
+		ID int64 `json:"id"`
+	}
+	err := postJSON(ctx, f.client, fmt.Sprintf("%s/repos/%s/pulls/%d/reviews", f.apiURL, f.remote.Path, number),
+		map[string]string{"Authorization": "Bearer " + f.token}, in, &out)
+	if err != nil {
+		return fmt.Errorf("post review: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+// Package index - Embedded code chunks for retrieval (RAG)
+package index
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+const (
+	chunkLines        = 60         // Lines per chunk
+	chunkOverlap      = 10         // Lines shared by consecutive chunks
+	maxChunkFileBytes = 256 * 1024 // Larger files are not embedded
+	embedBatchSize    = 32         // Chunks per embeddings request
+)
+
+// Chunk is a line range of a file
+type Chunk struct {
+	Path      string
+	StartLine int // 1-based, inclusive
+	EndLine   int
+	Content   string
+	Score     float64 // Similarity to the query, set by Retrieve
+}
+
+// SplitChunks cuts content into overlapping windows of lines, skipping
+// windows that are only whitespace
+func SplitChunks(path, content string) []Chunk {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	chunks := make([]Chunk, 0)
+	for start := 0; start < len(lines); start += chunkLines - chunkOverlap {
+		end := min(start+chunkLines, len(lines))
+		text := strings.Join(lines[start:end], "")
+		if strings.TrimSpace(text) != "" {
+			chunks = append(chunks, Chunk{Path: path, StartLine: start + 1, EndLine: end, Content: text})
+		}
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks
+}
+
+// ChunkStore keeps embedded chunks of the workspace's files in the chunks
+// table. Embeddings from different models are never compared.
+type ChunkStore struct {
+	engine   *core.Engine
+	root     string
+	embedder providers.Embedder
+	model    string
+}
+
+// NewChunkStore creates a chunk store embedding with the given model
+func NewChunkStore(engine *core.Engine, root string, embedder providers.Embedder, model string) *ChunkStore {
+	return &ChunkStore{engine: engine, root: root, embedder: embedder, model: model}
+}
+
+// Count returns how many chunks are embedded with the store's model
+func (s *ChunkStore) Count() int {
+	var n int
+	s.engine.QueryRow(`SELECT COUNT(*) FROM chunks WHERE model = ?`, s.model).Scan(&n)
+	return n
+}
+
+// Build embeds the files that changed since they were last embedded and
+// drops the files no longer listed. read returns a file's text, or false to
+// leave the file out (binary, generated, protected). progress is called
+// after each file. It returns how many files were embedded.
+func (s *ChunkStore) Build(ctx context.Context, files []string, read func(path string) (string, bool), progress func(done, total int)) (int, error) {
+	indexed := make(map[string]int64)
+	rows, err := s.engine.Query(`SELECT path, mtime FROM chunk_files WHERE model = ?`, s.model)
+	if err != nil {
+		return 0, fmt.Errorf("list embedded files: %w", err)
+	}
+	for rows.Next() {
+		var file string
+		var mtime int64
+		if rows.Scan(&file, &mtime) == nil {
+			indexed[file] = mtime
+		}
+	}
+	rows.Close()
+
+	listed := make(map[string]bool, len(files))
+	stale := make([]string, 0)
+	mtimes := make(map[string]int64)
+	for _, file := range files {
+		file = filepath.ToSlash(file)
+		info, err := os.Stat(filepath.Join(s.root, filepath.FromSlash(file)))
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxChunkFileBytes {
+			continue
+		}
+		listed[file] = true
+		mtimes[file] = info.ModTime().UnixNano()
+		if mtime, ok := indexed[file]; !ok || mtime != mtimes[file] {
+			stale = append(stale, file)
+		}
+	}
+
+	for file := range indexed {
+		if !listed[file] {
+			if err := s.forget(file); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	for i, file := range stale {
+		if err := ctx.Err(); err != nil {
+			return i, err
+		}
+		content, ok := read(file)
+		if !ok {
+			content = "" // Recorded without chunks so it is not retried until it changes
+		}
+		if err := s.embedFile(ctx, file, content, mtimes[file]); err != nil {
+			return i, err
+		}
+		if progress != nil {
+			progress(i+1, len(stale))
+		}
+	}
+	return len(stale), nil
+}
+
+// embedFile replaces a file's chunks with freshly embedded ones
+func (s *ChunkStore) embedFile(ctx context.Context, file, content string, mtime int64) error {
+	chunks := SplitChunks(file, content)
+	vectors := make([][]float32, 0, len(chunks))
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		batch := chunks[start:min(start+embedBatchSize, len(chunks))]
+		texts := make([]string, len(batch))
+		for i, c := range batch {
+			texts[i] = c.Path + "\n" + c.Content
+		}
+		v, err := s.embedder.Embed(ctx, s.model, texts)
+		if err != nil {
+			return fmt.Errorf("embed %s: %w", file, err)
+		}
+		vectors = append(vectors, v...)
+	}
+
+	tx, err := s.engine.DB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM chunks WHERE path = ? AND model = ?`, file, s.model); err != nil {
+		return err
+	}
+	for i, c := range chunks {
+		if _, err := tx.Exec(`INSERT INTO chunks (path, start_line, end_line, content, model, embedding) VALUES (?, ?, ?, ?, ?, ?)`,
+			c.Path, c.StartLine, c.EndLine, c.Content, s.model, encodeVector(vectors[i])); err != nil {
+			return fmt.Errorf("store %s: %w", file, err)
+		}
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO chunk_files (path, model, mtime) VALUES (?, ?, ?)`, file, s.model, mtime); err != nil {
+		return fmt.Errorf("store %s: %w", file, err)
+	}
+	return tx.Commit()
+}
+
+func (s *ChunkStore) forget(file string) error {
+	if _, err := s.engine.Exec(`DELETE FROM chunks WHERE path = ? AND model = ?`, file, s.model); err != nil {
+		return fmt.Errorf("forget %s: %w", file, err)
+	}
+	if _, err := s.engine.Exec(`DELETE FROM chunk_files WHERE path = ? AND model = ?`, file, s.model); err != nil {
+		return fmt.Errorf("forget %s: %w", file, err)
+	}
+	return nil
+}
+
+// Retrieve returns the k chunks most similar to query, best first
+func (s *ChunkStore) Retrieve(ctx context.Context, query string, k int) ([]Chunk, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	vectors, err := s.embedder.Embed(ctx, s.model, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	q := vectors[0]
+
+	rows, err := s.engine.Query(`SELECT path, start_line, end_line, content, embedding FROM chunks WHERE model = ?`, s.model)
+	if err != nil {
+		return nil, fmt.Errorf("load chunks: %w", err)
+	}
+	return topChunks(rows, q, k)
+}
+
+// topChunks scores every row against q and keeps the k best
+func topChunks(rows *sql.Rows, q []float32, k int) ([]Chunk, error) {
+	defer rows.Close()
+
+	best := make([]Chunk, 0, k+1)
+	for rows.Next() {
+		var c Chunk
+		var blob []byte
+		if err := rows.Scan(&c.Path, &c.StartLine, &c.EndLine, &c.Content, &blob); err != nil {
+			return nil, err
+		}
+		c.Score = cosine(q, decodeVector(blob))
+		if len(best) == k && c.Score <= best[k-1].Score {
+			continue
+		}
+		i := sort.Search(len(best), func(i int) bool { return best[i].Score < c.Score })
+		best = append(best, Chunk{})
+		copy(best[i+1:], best[i:])
+		best[i] = c
+		if len(best) > k {
+			best = best[:k]
+		}
+	}
+	return best, rows.Err()
+}
+
+// cosine returns the cosine similarity of two vectors (0 if their sizes differ)
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// encodeVector stores a vector as little-endian float32s
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
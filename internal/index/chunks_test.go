@@ -0,0 +1,127 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// wordEmbedder embeds text as counts of a fixed vocabulary and records calls
+type wordEmbedder struct {
+	vocab []string
+	calls int
+}
+
+func (e *wordEmbedder) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	e.calls++
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(e.vocab))
+		for j, word := range e.vocab {
+			v[j] = float32(strings.Count(text, word))
+		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func TestSplitChunks(t *testing.T) {
+	var sb strings.Builder
+	for i := 1; i <= 120; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+
+	chunks := SplitChunks("a.go", sb.String())
+	want := [][2]int{{1, 60}, {51, 110}, {101, 120}}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, c := range chunks {
+		if c.StartLine != want[i][0] || c.EndLine != want[i][1] {
+			t.Errorf("chunk %d = lines %d-%d, want %d-%d", i, c.StartLine, c.EndLine, want[i][0], want[i][1])
+		}
+	}
+	if !strings.HasPrefix(chunks[1].Content, "line 51\n") {
+		t.Errorf("chunk 1 starts with %q", chunks[1].Content[:10])
+	}
+
+	if got := SplitChunks("empty.go", "\n\n  \n"); len(got) != 0 {
+		t.Errorf("blank file gave %d chunks", len(got))
+	}
+}
+
+func TestChunkStore_BuildAndRetrieve(t *testing.T) {
+	s, root := setupStore(t)
+	embedder := &wordEmbedder{vocab: []string{"database", "http", "parse"}}
+	chunks := NewChunkStore(s.engine, root, embedder, "words")
+
+	writeFile(t, root, "db.go", "package db\n// database database connection\n")
+	writeFile(t, root, "server.go", "package server\n// http handler\n")
+	writeFile(t, root, "parse.go", "package parse\n// parse input for http\n")
+	writeFile(t, root, "secret.env", "TOKEN=x\n")
+	files := []string{"db.go", "server.go", "parse.go", "secret.env"}
+	read := func(path string) (string, bool) {
+		if path == "secret.env" {
+			return "", false
+		}
+		return readFile(t, root, path), true
+	}
+
+	n, err := chunks.Build(context.Background(), files, read, nil)
+	if err != nil || n != 4 {
+		t.Fatalf("Build() = %d, %v; want 4 files", n, err)
+	}
+	if got := chunks.Count(); got != 3 {
+		t.Errorf("Count() = %d, want 3 (secret.env has no chunks)", got)
+	}
+
+	// Unchanged files are not embedded again
+	calls := embedder.calls
+	if n, _ := chunks.Build(context.Background(), files, read, nil); n != 0 || embedder.calls != calls {
+		t.Errorf("second Build() embedded %d files with %d calls", n, embedder.calls-calls)
+	}
+
+	got, err := chunks.Retrieve(context.Background(), "http", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Path != "server.go" || got[1].Path != "parse.go" {
+		t.Errorf("Retrieve(http) = %v; want server.go then parse.go", chunkPaths(got))
+	}
+
+	// Files no longer listed are dropped; other models are untouched
+	other := NewChunkStore(s.engine, root, embedder, "other")
+	other.Build(context.Background(), []string{"db.go"}, read, nil)
+	chunks.Build(context.Background(), []string{"db.go"}, read, nil)
+	if got := chunks.Count(); got != 1 {
+		t.Errorf("Count() after removal = %d, want 1", got)
+	}
+	if got := other.Count(); got != 1 {
+		t.Errorf("other model Count() = %d, want 1", got)
+	}
+}
+
+func TestVectorRoundTrip(t *testing.T) {
+	v := []float32{0, 1.5, -2.25, 1e-7}
+	got := decodeVector(encodeVector(v))
+	for i := range v {
+		if got[i] != v[i] {
+			t.Fatalf("decodeVector(encodeVector(%v)) = %v", v, got)
+		}
+	}
+	if s := cosine([]float32{1, 0}, []float32{2, 0}); s < 0.999 {
+		t.Errorf("cosine of parallel vectors = %f", s)
+	}
+	if s := cosine([]float32{1, 0}, []float32{1, 0, 0}); s != 0 {
+		t.Errorf("cosine of mismatched sizes = %f", s)
+	}
+}
+
+func chunkPaths(chunks []Chunk) []string {
+	paths := make([]string, len(chunks))
+	for i, c := range chunks {
+		paths[i] = c.Path
+	}
+	return paths
+}
@@ -0,0 +1,234 @@
+// Package index - Symbol extraction for code navigation
+package index
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Symbol kinds
+const (
+	KindFunc   = "func"
+	KindMethod = "method"
+	KindType   = "type"
+	KindClass  = "class"
+	KindConst  = "const"
+	KindVar    = "var"
+	KindImport = "import"
+)
+
+// maxSignatureLen cuts long declaration lines
+const maxSignatureLen = 160
+
+// Symbol is a declaration or import found in a source file
+type Symbol struct {
+	Path      string // Workspace-relative, slash-separated
+	Name      string // Methods are Receiver.Name; imports are the imported path
+	Kind      string
+	Line      int
+	Signature string // The declaration's first line
+}
+
+// Extractor pulls the symbols out of one language's source. Extractors are
+// picked by file extension, so a parser-backed extractor (tree-sitter or a
+// language server) can replace a pattern-based one with Register.
+type Extractor interface {
+	Extract(src []byte) []Symbol
+}
+
+var extractors = map[string]Extractor{
+	".go":   goExtractor{},
+	".py":   pythonExtractor,
+	".js":   jsExtractor,
+	".jsx":  jsExtractor,
+	".mjs":  jsExtractor,
+	".ts":   jsExtractor,
+	".tsx":  jsExtractor,
+	".rs":   rustExtractor,
+	".java": javaExtractor,
+	".rb":   rubyExtractor,
+}
+
+// Register sets the extractor used for files with the given extension
+func Register(ext string, e Extractor) {
+	extractors[strings.ToLower(ext)] = e
+}
+
+// Supported reports whether symbols can be extracted from a file
+func Supported(path string) bool {
+	_, ok := extractors[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// Extract returns the symbols declared and imported by a file
+func Extract(path string, src []byte) []Symbol {
+	e, ok := extractors[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil
+	}
+	symbols := e.Extract(src)
+	for i := range symbols {
+		symbols[i].Path = filepath.ToSlash(path)
+	}
+	sort.SliceStable(symbols, func(i, j int) bool { return symbols[i].Line < symbols[j].Line })
+	return symbols
+}
+
+// goExtractor reads Go files with go/parser, so methods, grouped
+// declarations, and multi-line signatures come out right
+type goExtractor struct{}
+
+func (goExtractor) Extract(src []byte) []Symbol {
+	fset := token.NewFileSet()
+	// A file with syntax errors still yields the declarations before them
+	file, _ := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+
+	lines := strings.Split(string(src), "\n")
+	symbol := func(name, kind string, pos token.Pos) Symbol {
+		line := fset.Position(pos).Line
+		return Symbol{Name: name, Kind: kind, Line: line, Signature: signatureAt(lines, line)}
+	}
+
+	symbols := make([]Symbol, 0)
+	for _, imp := range file.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+			symbols = append(symbols, symbol(path, KindImport, imp.Pos()))
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name := receiverName(d.Recv.List[0].Type) + "." + d.Name.Name
+				symbols = append(symbols, symbol(name, KindMethod, d.Pos()))
+			} else {
+				symbols = append(symbols, symbol(d.Name.Name, KindFunc, d.Pos()))
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					symbols = append(symbols, symbol(s.Name.Name, KindType, s.Pos()))
+				case *ast.ValueSpec:
+					kind := KindVar
+					if d.Tok == token.CONST {
+						kind = KindConst
+					}
+					for _, name := range s.Names {
+						if name.Name != "_" {
+							symbols = append(symbols, symbol(name.Name, kind, name.Pos()))
+						}
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+// receiverName returns the type name of a method receiver (*T, T[K], ...)
+func receiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return "?"
+		}
+	}
+}
+
+// signatureAt returns the trimmed text of a 1-based line
+func signatureAt(lines []string, line int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	sig := strings.TrimSpace(strings.TrimSuffix(lines[line-1], "\r"))
+	sig = strings.TrimSpace(strings.TrimSuffix(sig, "{"))
+	if len(sig) > maxSignatureLen {
+		sig = sig[:maxSignatureLen] + "..."
+	}
+	return sig
+}
+
+// patternRule finds one kind of symbol; the first submatch is its name
+type patternRule struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// patternExtractor approximates a language's declarations with line patterns
+type patternExtractor []patternRule
+
+func (p patternExtractor) Extract(src []byte) []Symbol {
+	code := string(src)
+	lines := strings.Split(code, "\n")
+
+	symbols := make([]Symbol, 0)
+	for _, rule := range p {
+		for _, m := range rule.pattern.FindAllStringSubmatchIndex(code, -1) {
+			name := code[m[2]:m[3]]
+			line := strings.Count(code[:m[2]], "\n") + 1
+			symbols = append(symbols, Symbol{Name: name, Kind: rule.kind, Line: line, Signature: signatureAt(lines, line)})
+		}
+	}
+	return symbols
+}
+
+func rule(kind, pattern string) patternRule {
+	return patternRule{kind: kind, pattern: regexp.MustCompile(`(?m)` + pattern)}
+}
+
+var (
+	pythonExtractor = patternExtractor{
+		rule(KindFunc, `^[ \t]*(?:async[ \t]+)?def[ \t]+([A-Za-z_]\w*)`),
+		rule(KindClass, `^[ \t]*class[ \t]+([A-Za-z_]\w*)`),
+		rule(KindImport, `^[ \t]*import[ \t]+([\w.]+)`),
+		rule(KindImport, `^[ \t]*from[ \t]+([\w.]+)[ \t]+import\b`),
+	}
+
+	jsExtractor = patternExtractor{
+		rule(KindFunc, `^[ \t]*(?:export[ \t]+)?(?:default[ \t]+)?(?:async[ \t]+)?function\*?[ \t]+([A-Za-z_$][\w$]*)`),
+		rule(KindFunc, `^[ \t]*(?:export[ \t]+)?(?:const|let|var)[ \t]+([A-Za-z_$][\w$]*)[ \t]*=[ \t]*(?:async[ \t]*)?(?:\([^)\n]*\)|[A-Za-z_$][\w$]*)[ \t]*=>`),
+		rule(KindClass, `^[ \t]*(?:export[ \t]+)?(?:default[ \t]+)?(?:abstract[ \t]+)?class[ \t]+([A-Za-z_$][\w$]*)`),
+		rule(KindType, `^[ \t]*(?:export[ \t]+)?(?:declare[ \t]+)?(?:interface|type|enum)[ \t]+([A-Za-z_$][\w$]*)`),
+		rule(KindImport, `^[ \t]*import[ \t][^'"\n]*?from[ \t]*['"]([^'"\n]+)['"]`),
+		rule(KindImport, `^[ \t]*import[ \t]*['"]([^'"\n]+)['"]`),
+	}
+
+	rustExtractor = patternExtractor{
+		rule(KindFunc, `^[ \t]*(?:pub(?:\([^)]*\))?[ \t]+)?(?:const[ \t]+)?(?:async[ \t]+)?(?:unsafe[ \t]+)?fn[ \t]+([A-Za-z_]\w*)`),
+		rule(KindType, `^[ \t]*(?:pub(?:\([^)]*\))?[ \t]+)?(?:struct|enum|trait|type|union)[ \t]+([A-Za-z_]\w*)`),
+		rule(KindImport, `^[ \t]*(?:pub[ \t]+)?use[ \t]+([\w:]+)`),
+	}
+
+	javaExtractor = patternExtractor{
+		rule(KindClass, `^[ \t]*(?:(?:public|protected|private|abstract|final|static|sealed)[ \t]+)*(?:class|interface|enum|record)[ \t]+([A-Za-z_]\w*)`),
+		rule(KindFunc, `^[ \t]*(?:(?:public|protected|private|abstract|final|static|synchronized)[ \t]+)+[\w<>\[\], ]+?[ \t]+([A-Za-z_]\w*)[ \t]*\(`),
+		rule(KindImport, `^[ \t]*import[ \t]+(?:static[ \t]+)?([\w.]+)`),
+	}
+
+	rubyExtractor = patternExtractor{
+		rule(KindFunc, `^[ \t]*def[ \t]+(?:self\.)?([A-Za-z_]\w*[?!=]?)`),
+		rule(KindClass, `^[ \t]*(?:class|module)[ \t]+([A-Z]\w*(?:::[A-Z]\w*)*)`),
+		rule(KindImport, `^[ \t]*require(?:_relative)?[ \t(]+['"]([^'"\n]+)['"]`),
+	}
+)
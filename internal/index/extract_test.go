@@ -0,0 +1,122 @@
+package index
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// names formats symbols as kind:name@line for compact comparison
+func names(symbols []Symbol) []string {
+	out := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		out = append(out, fmt.Sprintf("%s:%s@%d", s.Kind, s.Name, s.Line))
+	}
+	return out
+}
+
+func TestExtract_Go(t *testing.T) {
+	src := `package store
+
+import (
+	"fmt"
+	sq "database/sql"
+)
+
+const Version = "1"
+
+var (
+	ErrClosed = fmt.Errorf("closed")
+	_         = sq.ErrNoRows
+)
+
+type Store struct{ db *sq.DB }
+
+func New() *Store { return nil }
+
+func (s *Store) Save(key string,
+	value []byte) error {
+	return nil
+}
+
+func (c Cache[K, V]) Get(key K) V { var v V; return v }
+`
+	symbols := Extract("store/store.go", []byte(src))
+	want := []string{
+		"import:fmt@4", "import:database/sql@5",
+		"const:Version@8",
+		"var:ErrClosed@11",
+		"type:Store@15",
+		"func:New@17",
+		"method:Store.Save@19",
+		"method:Cache.Get@24",
+	}
+	if got := names(symbols); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %v, want %v", got, want)
+	}
+	if symbols[0].Path != "store/store.go" {
+		t.Errorf("Path = %q", symbols[0].Path)
+	}
+	if got := symbols[6].Signature; got != "func (s *Store) Save(key string," {
+		t.Errorf("Signature = %q", got)
+	}
+}
+
+func TestExtract_GoSyntaxError(t *testing.T) {
+	src := "package x\n\nfunc Good() {}\n\nfunc Broken( {\n"
+	got := names(Extract("x.go", []byte(src)))
+	if len(got) == 0 || got[0] != "func:Good@3" {
+		t.Errorf("Extract() = %v, want Good first", got)
+	}
+}
+
+func TestExtract_Patterns(t *testing.T) {
+	tests := []struct {
+		path string
+		src  string
+		want []string
+	}{
+		{
+			"app.py",
+			"import os\nfrom pkg.mod import thing\n\nclass Handler:\n    async def handle(self):\n        pass\n",
+			[]string{"import:os@1", "import:pkg.mod@2", "class:Handler@4", "func:handle@5"},
+		},
+		{
+			"web/app.ts",
+			"import { x } from './x'\nexport interface Props {}\nexport default class App {}\nexport const render = (p: Props) => x\nasync function load() {}\n",
+			[]string{"import:./x@1", "type:Props@2", "class:App@3", "func:render@4", "func:load@5"},
+		},
+		{
+			"src/lib.rs",
+			"use std::io;\npub struct Config;\npub(crate) async fn run() {}\n",
+			[]string{"import:std::io@1", "type:Config@2", "func:run@3"},
+		},
+		{
+			"Main.java",
+			"import java.util.List;\npublic final class Main {\n    public static void main(String[] args) {}\n}\n",
+			[]string{"import:java.util.List@1", "class:Main@2", "func:main@3"},
+		},
+		{
+			"lib/a.rb",
+			"require 'json'\nmodule Api::V1\n  def self.call!; end\nend\n",
+			[]string{"import:json@1", "class:Api::V1@2", "func:call!@3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := names(Extract(tt.path, []byte(tt.src))); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract_Unsupported(t *testing.T) {
+	if Supported("README.md") {
+		t.Error("Supported(README.md) = true")
+	}
+	if got := Extract("README.md", []byte("# func main")); got != nil {
+		t.Errorf("Extract() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,315 @@
+// Package index - Symbol table persisted in the session database
+package index
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+const (
+	maxIndexFileBytes = 1024 * 1024 // Larger files are not parsed
+	syncBatchSize     = 200         // Files indexed per transaction
+)
+
+// Store keeps the symbols of the workspace's files in the symbols table,
+// re-parsing a file only when its modification time changes
+type Store struct {
+	engine *core.Engine
+	root   string
+}
+
+// NewStore creates a symbol store for the workspace at root
+func NewStore(engine *core.Engine, root string) *Store {
+	return &Store{engine: engine, root: root}
+}
+
+// Sync brings the store in line with the given workspace-relative files:
+// changed files are re-parsed and files no longer listed are forgotten.
+// It returns how many files were (re)indexed.
+func (s *Store) Sync(files []string) (int, error) {
+	indexed, err := s.indexedTimes()
+	if err != nil {
+		return 0, err
+	}
+
+	listed := make(map[string]bool, len(files))
+	stale := make([]string, 0)
+	for _, file := range files {
+		file = filepath.ToSlash(file)
+		if !Supported(file) {
+			continue
+		}
+		listed[file] = true
+		info, err := os.Stat(s.abs(file))
+		if err != nil {
+			continue
+		}
+		if mtime, ok := indexed[file]; !ok || mtime != info.ModTime().UnixNano() {
+			stale = append(stale, file)
+		}
+	}
+
+	for start := 0; start < len(stale); start += syncBatchSize {
+		batch := stale[start:min(start+syncBatchSize, len(stale))]
+		if err := s.inTx(func(tx *sql.Tx) error {
+			for _, file := range batch {
+				if err := s.index(tx, file); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return start, err
+		}
+	}
+
+	gone := make([]string, 0)
+	for file := range indexed {
+		if !listed[file] {
+			gone = append(gone, file)
+		}
+	}
+	if len(gone) > 0 {
+		if err := s.inTx(func(tx *sql.Tx) error {
+			for _, file := range gone {
+				if err := forget(tx, file); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return len(stale), err
+		}
+	}
+	return len(stale), nil
+}
+
+// Update re-parses one file, or forgets it if it no longer exists
+func (s *Store) Update(file string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		return s.index(tx, filepath.ToSlash(file))
+	})
+}
+
+// Remove forgets a file's symbols
+func (s *Store) Remove(file string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		return forget(tx, filepath.ToSlash(file))
+	})
+}
+
+// index replaces a file's rows with freshly extracted symbols
+func (s *Store) index(tx *sql.Tx, file string) error {
+	if err := forget(tx, file); err != nil {
+		return err
+	}
+	if !Supported(file) {
+		return nil
+	}
+
+	info, err := os.Stat(s.abs(file))
+	if err != nil || !info.Mode().IsRegular() {
+		return nil // Deleted or not a file: nothing to record
+	}
+	var symbols []Symbol
+	if info.Size() <= maxIndexFileBytes {
+		src, err := os.ReadFile(s.abs(file))
+		if err != nil {
+			return nil
+		}
+		symbols = Extract(file, src)
+	}
+
+	for _, sym := range symbols {
+		if _, err := tx.Exec(`INSERT INTO symbols (path, name, kind, line, signature) VALUES (?, ?, ?, ?, ?)`,
+			sym.Path, sym.Name, sym.Kind, sym.Line, sym.Signature); err != nil {
+			return fmt.Errorf("index %s: %w", file, err)
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO symbol_files (path, mtime) VALUES (?, ?)`, file, info.ModTime().UnixNano()); err != nil {
+		return fmt.Errorf("index %s: %w", file, err)
+	}
+	return nil
+}
+
+func forget(tx *sql.Tx, file string) error {
+	if _, err := tx.Exec(`DELETE FROM symbols WHERE path = ?`, file); err != nil {
+		return fmt.Errorf("forget %s: %w", file, err)
+	}
+	if _, err := tx.Exec(`DELETE FROM symbol_files WHERE path = ?`, file); err != nil {
+		return fmt.Errorf("forget %s: %w", file, err)
+	}
+	return nil
+}
+
+func (s *Store) indexedTimes() (map[string]int64, error) {
+	rows, err := s.engine.Query(`SELECT path, mtime FROM symbol_files`)
+	if err != nil {
+		return nil, fmt.Errorf("list indexed files: %w", err)
+	}
+	defer rows.Close()
+
+	times := make(map[string]int64)
+	for rows.Next() {
+		var file string
+		var mtime int64
+		if err := rows.Scan(&file, &mtime); err != nil {
+			return nil, err
+		}
+		times[file] = mtime
+	}
+	return times, rows.Err()
+}
+
+func (s *Store) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.engine.DB().Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) abs(file string) string {
+	return filepath.Join(s.root, filepath.FromSlash(file))
+}
+
+// Search finds declarations whose name contains query (case-insensitive),
+// exact and prefix matches first. Imports are not returned.
+func (s *Store) Search(query string, limit int) ([]Symbol, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	q := strings.ToLower(query)
+	rows, err := s.engine.Query(`
+		SELECT path, name, kind, line, COALESCE(signature, '') FROM symbols
+		WHERE kind != ? AND instr(lower(name), ?) > 0
+		ORDER BY
+			CASE
+				WHEN lower(name) = ? OR lower(name) LIKE ? ESCAPE '\' THEN 0
+				WHEN instr(lower(name), ?) = 1 THEN 1
+				ELSE 2
+			END,
+			length(name), path, line
+		LIMIT ?
+	`, KindImport, q, q, "%."+escapeLike(q), q, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search symbols: %w", err)
+	}
+	return scanSymbols(rows)
+}
+
+// FileSymbols returns a file's symbols in line order
+func (s *Store) FileSymbols(file string) ([]Symbol, error) {
+	rows, err := s.engine.Query(`
+		SELECT path, name, kind, line, COALESCE(signature, '') FROM symbols
+		WHERE path = ? ORDER BY line
+	`, filepath.ToSlash(file))
+	if err != nil {
+		return nil, err
+	}
+	return scanSymbols(rows)
+}
+
+// GuessPath returns the file with extension ext that already declares the
+// most of names, provided it declares at least half of them. Methods match
+// by their bare name, so "Save" finds "Store.Save".
+func (s *Store) GuessPath(names []string, ext string) (string, error) {
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	counts := make(map[string]int)
+	for _, name := range names {
+		rows, err := s.engine.Query(`
+			SELECT DISTINCT path FROM symbols
+			WHERE kind != ? AND (name = ? OR name LIKE ? ESCAPE '\')
+		`, KindImport, name, "%."+escapeLike(name))
+		if err != nil {
+			return "", err
+		}
+		for rows.Next() {
+			var file string
+			if err := rows.Scan(&file); err == nil && strings.EqualFold(path.Ext(file), ext) {
+				counts[file]++
+			}
+		}
+		rows.Close()
+	}
+
+	best := ""
+	for file, n := range counts {
+		if best == "" || n > counts[best] || (n == counts[best] && (len(file) < len(best) || (len(file) == len(best) && file < best))) {
+			best = file
+		}
+	}
+	if best == "" || counts[best]*2 < len(names) {
+		return "", nil
+	}
+	return best, nil
+}
+
+// Map outlines the workspace as one line per file listing its top-level
+// declarations, cut at maxBytes
+func (s *Store) Map(maxBytes int) (string, error) {
+	rows, err := s.engine.Query(`
+		SELECT path, name, kind, line, COALESCE(signature, '') FROM symbols
+		WHERE kind != ? ORDER BY path, line
+	`, KindImport)
+	if err != nil {
+		return "", err
+	}
+	symbols, err := scanSymbols(rows)
+	if err != nil {
+		return "", err
+	}
+
+	byFile := make(map[string][]string)
+	files := make([]string, 0)
+	for _, sym := range symbols {
+		if _, ok := byFile[sym.Path]; !ok {
+			files = append(files, sym.Path)
+		}
+		byFile[sym.Path] = append(byFile[sym.Path], sym.Name)
+	}
+	sort.Strings(files)
+
+	var sb strings.Builder
+	for i, file := range files {
+		line := file + ": " + strings.Join(byFile[file], ", ") + "\n"
+		if maxBytes > 0 && sb.Len()+len(line) > maxBytes {
+			fmt.Fprintf(&sb, "... (%d more files)\n", len(files)-i)
+			break
+		}
+		sb.WriteString(line)
+	}
+	return sb.String(), nil
+}
+
+func scanSymbols(rows *sql.Rows) ([]Symbol, error) {
+	defer rows.Close()
+	symbols := make([]Symbol, 0)
+	for rows.Next() {
+		var sym Symbol
+		if err := rows.Scan(&sym.Path, &sym.Name, &sym.Kind, &sym.Line, &sym.Signature); err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, sym)
+	}
+	return symbols, rows.Err()
+}
+
+// escapeLike escapes LIKE wildcards for use with ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,143 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func setupStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	engine, err := core.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+
+	root := t.TempDir()
+	return NewStore(engine, root), root
+}
+
+func writeFile(t *testing.T, root, path, content string) {
+	t.Helper()
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, root, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestStore_SyncAndSearch(t *testing.T) {
+	s, root := setupStore(t)
+	writeFile(t, root, "a/store.go", "package a\n\ntype Store struct{}\n\nfunc (s *Store) Save() {}\n")
+	writeFile(t, root, "b/save.go", "package b\n\nfunc SaveAll() {}\n")
+	writeFile(t, root, "README.md", "# Save\n")
+	files := []string{"a/store.go", "b/save.go", "README.md"}
+
+	n, err := s.Sync(files)
+	if err != nil || n != 2 {
+		t.Fatalf("Sync() = %d, %v; want 2 files", n, err)
+	}
+	if n, _ := s.Sync(files); n != 0 {
+		t.Errorf("second Sync() reindexed %d unchanged files", n)
+	}
+
+	got, err := s.Search("save", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != "Store.Save" || got[1].Name != "SaveAll" {
+		t.Errorf("Search(save) = %+v; want Store.Save then SaveAll", got)
+	}
+
+	// Files missing from the list are forgotten
+	if _, err := s.Sync([]string{"a/store.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Search("SaveAll", 10); len(got) != 0 {
+		t.Errorf("Search after removal = %+v", got)
+	}
+}
+
+func TestStore_Update(t *testing.T) {
+	s, root := setupStore(t)
+	writeFile(t, root, "x.go", "package x\n\nfunc Old() {}\n")
+	if err := s.Update("x.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, root, "x.go", "package x\n\nfunc New() {}\n")
+	if err := s.Update("x.go"); err != nil {
+		t.Fatal(err)
+	}
+	symbols, _ := s.FileSymbols("x.go")
+	if len(symbols) != 1 || symbols[0].Name != "New" {
+		t.Errorf("FileSymbols() = %+v; want only New", symbols)
+	}
+
+	os.Remove(filepath.Join(root, "x.go"))
+	if err := s.Update("x.go"); err != nil {
+		t.Fatal(err)
+	}
+	if symbols, _ := s.FileSymbols("x.go"); len(symbols) != 0 {
+		t.Errorf("FileSymbols() after delete = %+v", symbols)
+	}
+}
+
+func TestStore_GuessPath(t *testing.T) {
+	s, root := setupStore(t)
+	writeFile(t, root, "a/store.go", "package a\n\ntype Store struct{}\n\nfunc (s *Store) Save() {}\nfunc (s *Store) Load() {}\n")
+	writeFile(t, root, "b/load.go", "package b\n\nfunc Load() {}\n")
+	writeFile(t, root, "b/load.py", "def Save():\n    pass\n")
+	s.Sync([]string{"a/store.go", "b/load.go", "b/load.py"})
+
+	tests := []struct {
+		names []string
+		ext   string
+		want  string
+	}{
+		{[]string{"Save", "Load"}, ".go", "a/store.go"},
+		{[]string{"Load"}, ".go", "b/load.go"}, // Tie: shorter path
+		{[]string{"Save"}, ".py", "b/load.py"},
+		{[]string{"Save", "Other", "Another"}, ".go", ""}, // Under half
+		{nil, ".go", ""},
+	}
+	for _, tt := range tests {
+		if got, err := s.GuessPath(tt.names, tt.ext); err != nil || got != tt.want {
+			t.Errorf("GuessPath(%v, %s) = %q, %v; want %q", tt.names, tt.ext, got, err, tt.want)
+		}
+	}
+}
+
+func TestStore_Map(t *testing.T) {
+	s, root := setupStore(t)
+	writeFile(t, root, "a.go", "package a\n\nimport \"fmt\"\n\nfunc A() { fmt.Println() }\n")
+	writeFile(t, root, "b.go", "package b\n\ntype B int\n\nfunc (B) M() {}\n")
+	s.Sync([]string{"a.go", "b.go"})
+
+	got, err := s.Map(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.go: A\nb.go: B, B.M\n"; got != want {
+		t.Errorf("Map() = %q, want %q", got, want)
+	}
+
+	if got, _ := s.Map(12); !strings.HasPrefix(got, "a.go: A\n... (1 more files)") {
+		t.Errorf("Map(12) = %q", got)
+	}
+}
@@ -0,0 +1,74 @@
+// Package injection finds instructions embedded in content the model
+// reads but the user did not write, such as files and web pages: requests
+// to ignore previous instructions, chat template tokens, tool-call bait,
+// and hidden characters. Neutralize rewrites them so they read as data.
+package injection
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Pattern is a kind of embedded instruction
+type Pattern struct {
+	Kind string
+	Re   *regexp.Regexp
+}
+
+// Patterns match embedded instructions
+var Patterns = []Pattern{
+	{"override", regexp.MustCompile(`(?i)\b(?:ignore|disregard|forget|override|bypass)\s+(?:all\s+|any\s+)?(?:of\s+)?(?:the\s+|your\s+)?(?:previous|prior|above|earlier|preceding|original|system)\s+(?:instructions?|prompts?|rules|directions|guidelines|context)\b`)},
+	{"role change", regexp.MustCompile(`(?i)\b(?:you are now (?:a|an|in)\b|from now on,? you (?:are|will|must)\b|new (?:system )?instructions\s*:|(?:enter|enable) (?:developer|god|jailbreak|DAN) mode\b)`)},
+	{"chat token", regexp.MustCompile(`<\|(?:im_start|im_end|system|user|assistant|endoftext|eot_id|start_header_id|end_header_id)\|>|\[/?INST\]|<</?SYS>>`)},
+	{"tool call bait", regexp.MustCompile(`(?i)</?(?:tool_call|function_calls?|invoke|tool_use)\b[^>]*>|\b(?:call|run|use|invoke|execute) the (?:run_command|run_snippet|write_file|edit_file|delete_file|web_search|delegate) tool\b`)},
+	{"exfiltration", regexp.MustCompile(`(?i)\b(?:reveal|print|show|repeat|output|send|leak)\s+(?:me\s+)?(?:your|the)\s+(?:system prompt|hidden instructions|api keys?|secrets|credentials|environment variables)\b`)},
+	{"hidden text", regexp.MustCompile(`[\x{200B}-\x{200F}\x{202A}-\x{202E}\x{2060}-\x{2064}\x{FEFF}\x{E0000}-\x{E007F}]{3,}`)},
+}
+
+// Finding is an embedded instruction: its kind and the text, cut short
+type Finding struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+// maxFindingText bounds the text a finding quotes
+const maxFindingText = 60
+
+// Scan returns the embedded instructions in text
+func Scan(text string) []Finding {
+	findings := make([]Finding, 0)
+	for _, p := range Patterns {
+		for _, match := range p.Re.FindAllString(text, -1) {
+			findings = append(findings, Finding{Kind: p.Kind, Text: quote(p.Kind, match)})
+		}
+	}
+	return findings
+}
+
+// Neutralize replaces the embedded instructions in text with a marker
+// naming their kind, and returns what it replaced. Hidden text is dropped.
+func Neutralize(text string) (string, []Finding) {
+	findings := make([]Finding, 0)
+	for _, p := range Patterns {
+		text = p.Re.ReplaceAllStringFunc(text, func(match string) string {
+			findings = append(findings, Finding{Kind: p.Kind, Text: quote(p.Kind, match)})
+			if p.Kind == "hidden text" {
+				return ""
+			}
+			return "[neutralized " + p.Kind + "]"
+		})
+	}
+	return text, findings
+}
+
+// quote shortens a match for display; hidden text is shown as its length
+func quote(kind, match string) string {
+	if kind == "hidden text" {
+		return strings.Repeat("·", min(len([]rune(match)), 10))
+	}
+	match = strings.Join(strings.Fields(match), " ")
+	if r := []rune(match); len(r) > maxFindingText {
+		match = string(r[:maxFindingText]) + "…"
+	}
+	return match
+}
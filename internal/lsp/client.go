@@ -0,0 +1,396 @@
+// Package lsp is a minimal Language Server Protocol client: it opens files
+// in a server such as gopls and collects the diagnostics it publishes, so
+// edits get compiler-grade feedback without a full build.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = 1
+	SeverityWarning = 2
+)
+
+// settleTime is how long diagnostics must stay quiet before they are read
+const settleTime = 300 * time.Millisecond
+
+// Position is a zero-based line and character offset
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a span of a document
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Diagnostic is a problem the server reports in a file
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+}
+
+// String formats the diagnostic as path:line:col: message, 1-based
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s", d.Range.Start.Line+1, d.Range.Start.Character+1, d.Message)
+}
+
+// message is a JSON-RPC 2.0 request, response, or notification
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Client talks to one language server over stdio
+type Client struct {
+	root string
+	cmd  *exec.Cmd
+
+	w   io.WriteCloser
+	wmu sync.Mutex
+
+	mu       sync.Mutex
+	nextID   int
+	pending  map[int]chan message
+	diags    map[string][]Diagnostic // By file URI
+	received map[string]int          // publishDiagnostics count per URI
+	versions map[string]int          // Open documents and their version
+	lastDiag time.Time
+	done     chan struct{}
+}
+
+// Start launches a language server for the workspace at root and
+// completes the initialize handshake
+func Start(ctx context.Context, command []string, root string) (*Client, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no language server command")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", command[0], err)
+	}
+
+	c := newClient(root, stdout, stdin)
+	c.cmd = cmd
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newClient wires a client to a server's output and input
+func newClient(root string, r io.Reader, w io.WriteCloser) *Client {
+	c := &Client{
+		root:     root,
+		w:        w,
+		pending:  make(map[int]chan message),
+		diags:    make(map[string][]Diagnostic),
+		received: make(map[string]int),
+		versions: make(map[string]int),
+		done:     make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(r))
+	return c
+}
+
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   fileURI(c.root),
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"publishDiagnostics": map[string]interface{}{"versionSupport": true},
+			},
+		},
+		"workspaceFolders": []map[string]string{{"uri": fileURI(c.root), "name": filepath.Base(c.root)}},
+	}
+	if _, err := c.call(ctx, "initialize", params); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	return c.notify("initialized", map[string]interface{}{})
+}
+
+// Diagnostics syncs files (workspace-relative path to current content; ""
+// for deleted files) with the server and returns the diagnostics it
+// publishes for them, waiting until they settle or wait runs out
+func (c *Client) Diagnostics(ctx context.Context, files map[string]string, languageID func(path string) string, wait time.Duration) (map[string][]Diagnostic, error) {
+	baseline := make(map[string]int)
+	for path, content := range files {
+		uri := fileURI(filepath.Join(c.root, path))
+		c.mu.Lock()
+		version, open := c.versions[uri]
+		baseline[uri] = c.received[uri]
+		c.mu.Unlock()
+
+		var err error
+		switch {
+		case content == "" && open:
+			err = c.notify("textDocument/didClose", map[string]interface{}{"textDocument": map[string]string{"uri": uri}})
+			c.mu.Lock()
+			delete(c.versions, uri)
+			delete(c.diags, uri)
+			c.mu.Unlock()
+			delete(baseline, uri)
+		case content == "":
+			delete(baseline, uri)
+		case open:
+			err = c.notify("textDocument/didChange", map[string]interface{}{
+				"textDocument":   map[string]interface{}{"uri": uri, "version": version + 1},
+				"contentChanges": []map[string]string{{"text": content}},
+			})
+			c.setVersion(uri, version+1)
+		default:
+			err = c.notify("textDocument/didOpen", map[string]interface{}{
+				"textDocument": map[string]interface{}{"uri": uri, "languageId": languageID(path), "version": 1, "text": content},
+			})
+			c.setVersion(uri, 1)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Wait for a fresh publish for every file, then for the stream to go quiet
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		c.mu.Lock()
+		fresh := true
+		for uri, n := range baseline {
+			if c.received[uri] <= n {
+				fresh = false
+				break
+			}
+		}
+		quiet := time.Since(c.lastDiag) >= settleTime
+		c.mu.Unlock()
+		if fresh && quiet {
+			break
+		}
+		select {
+		case <-c.done:
+			return nil, fmt.Errorf("language server exited")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	result := make(map[string][]Diagnostic)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for path := range files {
+		if diags := c.diags[fileURI(filepath.Join(c.root, path))]; len(diags) > 0 {
+			result[path] = diags
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) setVersion(uri string, version int) {
+	c.mu.Lock()
+	c.versions[uri] = version
+	c.mu.Unlock()
+}
+
+// Close shuts the server down
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	c.call(ctx, "shutdown", nil)
+	c.notify("exit", nil)
+	c.w.Close()
+
+	if c.cmd != nil {
+		exited := make(chan struct{})
+		go func() {
+			c.cmd.Wait()
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-time.After(2 * time.Second):
+			c.cmd.Process.Kill()
+		}
+	}
+	return nil
+}
+
+// call sends a request and waits for its response
+func (c *Client) call(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(message{ID: &id, Method: method, Params: marshal(params)}); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s: %s", method, resp.Error.Message)
+		}
+		return resp.Result, nil
+	case <-c.done:
+		return nil, fmt.Errorf("language server exited")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) notify(method string, params interface{}) error {
+	return c.send(message{Method: method, Params: marshal(params)})
+}
+
+func marshal(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+// send writes one message with its Content-Length header
+func (c *Client) send(msg message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		return fmt.Errorf("send %s: %w", msg.Method, err)
+	}
+	return nil
+}
+
+// readLoop dispatches responses and stores published diagnostics until
+// the server's output closes
+func (c *Client) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			return
+		}
+
+		switch {
+		case msg.ID != nil && msg.Method == "":
+			c.mu.Lock()
+			ch := c.pending[*msg.ID]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		case msg.ID != nil:
+			// Server-to-client request (configuration, progress): answer empty
+			c.send(message{ID: msg.ID, Result: json.RawMessage("null")})
+		case msg.Method == "textDocument/publishDiagnostics":
+			var params struct {
+				URI         string       `json:"uri"`
+				Diagnostics []Diagnostic `json:"diagnostics"`
+			}
+			if json.Unmarshal(msg.Params, &params) == nil {
+				c.mu.Lock()
+				c.diags[params.URI] = params.Diagnostics
+				c.received[params.URI]++
+				c.lastDiag = time.Now()
+				c.mu.Unlock()
+			}
+		}
+	}
+}
+
+// readMessage reads one Content-Length framed message
+func readMessage(r *bufio.Reader) (message, error) {
+	var msg message
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return msg, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return msg, fmt.Errorf("bad Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return msg, err
+	}
+	return msg, json.Unmarshal(body, &msg)
+}
+
+// fileURI converts an absolute path to a file:// URI
+func fileURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// Format renders diagnostics at or above a severity as path:line:col: message
+// lines, sorted by path and position
+func Format(diags map[string][]Diagnostic, maxSeverity int) string {
+	paths := make([]string, 0, len(diags))
+	for path := range diags {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		for _, d := range diags[path] {
+			// Severity 0 is unspecified; treat it as an error
+			if d.Severity > maxSeverity {
+				continue
+			}
+			sb.WriteString(path + ":" + d.String() + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,124 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer answers requests and publishes one diagnostic per line
+// containing "BAD" whenever a document is opened or changed
+func fakeServer(t *testing.T, in io.Reader, out io.Writer) {
+	r := bufio.NewReader(in)
+	write := func(msg map[string]interface{}) {
+		msg["jsonrpc"] = "2.0"
+		body, _ := json.Marshal(msg)
+		fmt.Fprintf(out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			return
+		}
+		if msg.ID != nil {
+			write(map[string]interface{}{"id": *msg.ID, "result": map[string]interface{}{}})
+			continue
+		}
+
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		text := params.TextDocument.Text
+		if len(params.ContentChanges) > 0 {
+			text = params.ContentChanges[0].Text
+		}
+
+		switch msg.Method {
+		case "textDocument/didOpen", "textDocument/didChange":
+			diags := make([]Diagnostic, 0)
+			for i, line := range strings.Split(text, "\n") {
+				if col := strings.Index(line, "BAD"); col >= 0 {
+					diags = append(diags, Diagnostic{
+						Range:    Range{Start: Position{Line: i, Character: col}},
+						Severity: SeverityError,
+						Message:  "undefined: BAD",
+					})
+				}
+			}
+			write(map[string]interface{}{
+				"method": "textDocument/publishDiagnostics",
+				"params": map[string]interface{}{"uri": params.TextDocument.URI, "diagnostics": diags},
+			})
+		case "exit":
+			return
+		}
+	}
+}
+
+func TestClient_Diagnostics(t *testing.T) {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	go func() {
+		fakeServer(t, serverIn, serverOut)
+		serverOut.Close()
+	}()
+
+	root := t.TempDir()
+	c := newClient(root, clientIn, clientOut)
+	ctx := context.Background()
+	if err := c.initialize(ctx); err != nil {
+		t.Fatalf("initialize: %v", err)
+	}
+	defer c.Close()
+
+	lang := func(string) string { return "go" }
+	diags, err := c.Diagnostics(ctx, map[string]string{
+		"a.go":    "package a\n\nvar x = BAD\n",
+		"ok/b.go": "package ok\n",
+	}, lang, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Format(diags, SeverityError), "a.go:3:9: undefined: BAD"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+
+	// A change is sent as a new version and replaces the diagnostics
+	diags, err = c.Diagnostics(ctx, map[string]string{"a.go": "package a\n"}, lang, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 0 {
+		t.Errorf("after fix: %v", diags)
+	}
+	if v := c.versions[fileURI(filepath.Join(root, "a.go"))]; v != 2 {
+		t.Errorf("version = %d, want 2", v)
+	}
+}
+
+func TestFormat_Severity(t *testing.T) {
+	diags := map[string][]Diagnostic{
+		"b.go": {{Severity: SeverityWarning, Message: "unused"}},
+		"a.go": {{Severity: SeverityError, Message: "broken"}, {Message: "unspecified"}},
+	}
+	if got, want := Format(diags, SeverityError), "a.go:1:1: broken\na.go:1:1: unspecified"; got != want {
+		t.Errorf("errors = %q, want %q", got, want)
+	}
+	if got := Format(diags, SeverityWarning); !strings.HasSuffix(got, "b.go:1:1: unused") {
+		t.Errorf("warnings = %q", got)
+	}
+}
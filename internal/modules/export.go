@@ -0,0 +1,276 @@
+// Package modules - Moving what the learning module learned between
+// machines, or merging a team's shared patterns
+package modules
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+// LearningExportVersion is the format version of LearningExport
+const LearningExportVersion = 1
+
+// LearningExport is the portable form of everything the learning module
+// has learned
+type LearningExport struct {
+	Version         int              `json:"version"`
+	ExportedAt      time.Time        `json:"exported_at"`
+	LearnedIntents  []LearnedIntent  `json:"learned_intents"`
+	CodePatterns    []CodePattern    `json:"code_patterns"`
+	UserPreferences []UserPreference `json:"user_preferences"`
+}
+
+// LearnedIntent is a row of learned_intents
+type LearnedIntent struct {
+	InputPattern   string  `json:"input_pattern"`
+	DetectedIntent string  `json:"detected_intent"`
+	Confidence     float64 `json:"confidence"`
+	SuccessCount   int     `json:"success_count"`
+	FailureCount   int     `json:"failure_count"`
+	LastUsedAt     int64   `json:"last_used_at,omitempty"`
+	Scope          string  `json:"scope,omitempty"` // Empty: global
+}
+
+// CodePattern is a row of code_patterns
+type CodePattern struct {
+	Language    string `json:"language"`
+	PatternType string `json:"pattern_type"`
+	TriggerText string `json:"trigger_text"`
+	Suggestion  string `json:"suggestion"`
+	UsageCount  int    `json:"usage_count"`
+}
+
+// UserPreference is a row of user_preferences
+type UserPreference struct {
+	Key        string  `json:"key"`
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+	UpdatedAt  int64   `json:"updated_at,omitempty"`
+}
+
+// ImportStats counts what an import did
+type ImportStats struct {
+	Added   int // New rows
+	Updated int // Local rows replaced by a more confident import
+	Kept    int // Local rows at least as confident as the import
+}
+
+// Export returns all learned intents, code patterns, and preferences
+func (lm *LearningModule) Export() (*LearningExport, error) {
+	data := &LearningExport{
+		Version:         LearningExportVersion,
+		ExportedAt:      time.Now().UTC(),
+		LearnedIntents:  make([]LearnedIntent, 0),
+		CodePatterns:    make([]CodePattern, 0),
+		UserPreferences: make([]UserPreference, 0),
+	}
+
+	for _, db := range lm.stores() {
+		rows, err := db.Query(`
+			SELECT input_pattern, detected_intent, confidence, success_count, failure_count, COALESCE(last_used_at, 0), COALESCE(scope, 'global')
+			FROM learned_intents
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("export learned intents: %w", err)
+		}
+		for rows.Next() {
+			var li LearnedIntent
+			if err := rows.Scan(&li.InputPattern, &li.DetectedIntent, &li.Confidence, &li.SuccessCount, &li.FailureCount, &li.LastUsedAt, &li.Scope); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			data.LearnedIntents = append(data.LearnedIntents, li)
+		}
+		rows.Close()
+
+		rows, err = db.Query(`
+			SELECT key, value, confidence, COALESCE(updated_at, 0) FROM user_preferences
+		`)
+		if err != nil {
+			return nil, fmt.Errorf("export preferences: %w", err)
+		}
+		for rows.Next() {
+			var up UserPreference
+			if err := rows.Scan(&up.Key, &up.Value, &up.Confidence, &up.UpdatedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			data.UserPreferences = append(data.UserPreferences, up)
+		}
+		rows.Close()
+	}
+	sort.Slice(data.LearnedIntents, func(i, j int) bool {
+		a, b := data.LearnedIntents[i], data.LearnedIntents[j]
+		if a.Scope != b.Scope {
+			return a.Scope < b.Scope
+		}
+		if a.InputPattern != b.InputPattern {
+			return a.InputPattern < b.InputPattern
+		}
+		return a.DetectedIntent < b.DetectedIntent
+	})
+	sort.Slice(data.UserPreferences, func(i, j int) bool {
+		return data.UserPreferences[i].Key < data.UserPreferences[j].Key
+	})
+
+	rows, err := lm.engine.Query(`
+		SELECT language, pattern_type, trigger_text, suggestion, usage_count
+		FROM code_patterns ORDER BY language, pattern_type, trigger_text
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("export code patterns: %w", err)
+	}
+	for rows.Next() {
+		var cp CodePattern
+		if err := rows.Scan(&cp.Language, &cp.PatternType, &cp.TriggerText, &cp.Suggestion, &cp.UsageCount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		data.CodePatterns = append(data.CodePatterns, cp)
+	}
+	rows.Close()
+
+	return data, nil
+}
+
+// Import merges exported learning into the databases of its scopes. A row
+// that already exists locally is replaced only when the imported one is
+// more confident (code patterns: more used). With replace, local learning
+// is cleared first instead.
+func (lm *LearningModule) Import(data *LearningExport, replace bool) (ImportStats, error) {
+	var stats ImportStats
+	if data.Version != LearningExportVersion {
+		return stats, fmt.Errorf("unsupported export version %d (want %d)", data.Version, LearningExportVersion)
+	}
+
+	// Global learning goes to the user's database, the rest to the project's
+	parts := make(map[*core.Engine]*LearningExport)
+	for _, db := range lm.stores() {
+		parts[db] = &LearningExport{}
+	}
+	for _, li := range data.LearnedIntents {
+		if li.Scope == "" {
+			li.Scope = GlobalScope
+		}
+		part := parts[lm.store(li.Scope)]
+		part.LearnedIntents = append(part.LearnedIntents, li)
+	}
+	parts[lm.engine].CodePatterns = data.CodePatterns
+	for _, up := range data.UserPreferences {
+		scope, _ := SplitScopedKey(up.Key)
+		part := parts[lm.store(scope)]
+		part.UserPreferences = append(part.UserPreferences, up)
+	}
+
+	for _, db := range lm.stores() {
+		s, err := importInto(db, parts[db], replace)
+		stats.Added += s.Added
+		stats.Updated += s.Updated
+		stats.Kept += s.Kept
+		if err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// importInto merges exported learning into one database, in a transaction
+func importInto(db *core.Engine, data *LearningExport, replace bool) (ImportStats, error) {
+	var stats ImportStats
+	tx, err := db.DB().Begin()
+	if err != nil {
+		return stats, err
+	}
+	defer tx.Rollback()
+
+	if replace {
+		for _, table := range []string{"learned_intents", "code_patterns", "user_preferences"} {
+			if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+				return stats, fmt.Errorf("clear %s: %w", table, err)
+			}
+		}
+	}
+
+	for _, li := range data.LearnedIntents {
+		var id string
+		var confidence float64
+		err := tx.QueryRow(`
+			SELECT id, confidence FROM learned_intents WHERE input_pattern = ? AND detected_intent = ? AND scope = ?
+		`, li.InputPattern, li.DetectedIntent, li.Scope).Scan(&id, &confidence)
+		switch {
+		case err == sql.ErrNoRows:
+			_, err = tx.Exec(`
+				INSERT INTO learned_intents (id, input_pattern, detected_intent, confidence, success_count, failure_count, last_used_at, scope)
+				VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?)
+			`, uuid.New().String(), li.InputPattern, li.DetectedIntent, li.Confidence, li.SuccessCount, li.FailureCount, li.LastUsedAt, li.Scope)
+			stats.Added++
+		case err != nil:
+		case li.Confidence > confidence:
+			_, err = tx.Exec(`
+				UPDATE learned_intents SET confidence = ?, success_count = ?, failure_count = ?, last_used_at = NULLIF(?, 0)
+				WHERE id = ?
+			`, li.Confidence, li.SuccessCount, li.FailureCount, li.LastUsedAt, id)
+			stats.Updated++
+		default:
+			stats.Kept++
+		}
+		if err != nil {
+			return stats, fmt.Errorf("import learned intent %q: %w", li.InputPattern, err)
+		}
+	}
+
+	for _, cp := range data.CodePatterns {
+		var id string
+		var usage int
+		err := tx.QueryRow(`
+			SELECT id, usage_count FROM code_patterns WHERE language = ? AND pattern_type = ? AND trigger_text = ?
+		`, cp.Language, cp.PatternType, cp.TriggerText).Scan(&id, &usage)
+		switch {
+		case err == sql.ErrNoRows:
+			_, err = tx.Exec(`
+				INSERT INTO code_patterns (id, language, pattern_type, trigger_text, suggestion, usage_count)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, uuid.New().String(), cp.Language, cp.PatternType, cp.TriggerText, cp.Suggestion, cp.UsageCount)
+			stats.Added++
+		case err != nil:
+		case cp.UsageCount > usage:
+			_, err = tx.Exec(`UPDATE code_patterns SET suggestion = ?, usage_count = ? WHERE id = ?`, cp.Suggestion, cp.UsageCount, id)
+			stats.Updated++
+		default:
+			stats.Kept++
+		}
+		if err != nil {
+			return stats, fmt.Errorf("import code pattern %q: %w", cp.TriggerText, err)
+		}
+	}
+
+	for _, up := range data.UserPreferences {
+		var confidence float64
+		err := tx.QueryRow(`SELECT confidence FROM user_preferences WHERE key = ?`, up.Key).Scan(&confidence)
+		switch {
+		case err == sql.ErrNoRows:
+			_, err = tx.Exec(`
+				INSERT INTO user_preferences (key, value, confidence) VALUES (?, ?, ?)
+			`, up.Key, up.Value, up.Confidence)
+			stats.Added++
+		case err != nil:
+		case up.Confidence > confidence:
+			_, err = tx.Exec(`
+				UPDATE user_preferences SET value = ?, confidence = ?, updated_at = strftime('%s', 'now') WHERE key = ?
+			`, up.Value, up.Confidence, up.Key)
+			stats.Updated++
+		default:
+			stats.Kept++
+		}
+		if err != nil {
+			return stats, fmt.Errorf("import preference %q: %w", up.Key, err)
+		}
+	}
+
+	return stats, tx.Commit()
+}
@@ -0,0 +1,62 @@
+package modules
+
+import "testing"
+
+func TestExportImport(t *testing.T) {
+	src := setupLearning(t)
+	src.RecordSuccess("what changed", "/diff")
+	src.RecordSuccess("ship it", "/push")
+	src.LearnPreference("/src/app:style.indent", "tabs")
+	if _, err := src.engine.Exec(`
+		INSERT INTO code_patterns (id, language, pattern_type, trigger_text, suggestion, usage_count)
+		VALUES ('p1', 'go', 'snippet', 'iferr', 'if err != nil { return err }', 4)
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := src.Export()
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(data.LearnedIntents) != 2 || len(data.CodePatterns) != 1 || len(data.UserPreferences) != 1 {
+		t.Fatalf("Export() = %+v", data)
+	}
+
+	dst := setupLearning(t)
+	dst.RecordSuccess("what changed", "/diff")
+	dst.RecordFailure("what changed", "/diff") // 0.5, less confident than the import
+	dst.LearnPreference("/src/app:style.indent", "spaces")
+	dst.LearnPreference("/src/app:style.indent", "spaces") // 0.7, more confident
+
+	stats, err := dst.Import(data, false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if stats != (ImportStats{Added: 2, Updated: 1, Kept: 1}) {
+		t.Errorf("Import() stats = %+v", stats)
+	}
+	if v, _, _ := dst.GetPreference("/src/app:style.indent"); v != "spaces" {
+		t.Errorf("preference = %q, want the more confident local spaces", v)
+	}
+	var confidence float64
+	dst.engine.QueryRow(`SELECT confidence FROM learned_intents WHERE input_pattern = 'what changed'`).Scan(&confidence)
+	if confidence != 1.0 {
+		t.Errorf("intent confidence = %v, want the imported 1.0", confidence)
+	}
+
+	stats, err = dst.Import(data, true)
+	if err != nil {
+		t.Fatalf("Import with replace failed: %v", err)
+	}
+	if stats.Added != 4 {
+		t.Errorf("Import(replace) added %d, want 4", stats.Added)
+	}
+	if v, _, _ := dst.GetPreference("/src/app:style.indent"); v != "tabs" {
+		t.Errorf("preference after replace = %q, want tabs", v)
+	}
+
+	data.Version = 99
+	if _, err := dst.Import(data, false); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
@@ -3,7 +3,10 @@
 package modules
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/hazyhaar/GoClode/internal/core"
@@ -13,8 +16,11 @@ import (
 // LearningModule provides pattern learning capabilities
 // It learns from user interactions to improve suggestions
 type LearningModule struct {
-	mm     *core.ModuleManager
-	engine *core.Engine
+	mm      *core.ModuleManager
+	engine  *core.Engine
+	global  *core.Engine // Global learning, when not in the project database (OpenGlobal)
+	project string       // Scope of the current workspace, "" outside one
+	user    string       // Scope of the --listen client served, if any (SetUser)
 }
 
 // NewLearningModule creates a new learning module
@@ -34,12 +40,17 @@ func NewLearningModule(engine *core.Engine, mm *core.ModuleManager) *LearningMod
 		Config: map[string]interface{}{
 			"min_success_count": 3,
 			"decay_days":        30,
+			"decay_rate":        0.9,
+			"min_confidence":    0.3,
 		},
 		SchemaSQL: lm.Schema(),
 	})
+	engine.EnsureColumn("learned_intents", "scope", "TEXT DEFAULT 'global'")
 
-	// Register hooks
+	// Register hooks (fixed IDs so registering on every start doesn't
+	// duplicate them)
 	mm.RegisterHook(&core.Hook{
+		ID:       "learning_pattern_learn",
 		ModuleID: "learning",
 		Event:    "chat_complete",
 		Handler:  "pattern_learn",
@@ -47,6 +58,30 @@ func NewLearningModule(engine *core.Engine, mm *core.ModuleManager) *LearningMod
 		Enabled:  true,
 	})
 
+	mm.RegisterHandler("learning_undo", lm.handleUndo)
+	mm.RegisterHook(&core.Hook{
+		ID:       "learning_undo",
+		ModuleID: "learning",
+		Event:    UndoEvent,
+		Handler:  "learning_undo",
+		Priority: 100,
+		Enabled:  true,
+	})
+
+	mm.RegisterHandler("learning_decay", func(ctx *core.HookContext) error {
+		_, _, err := lm.Decay(ctx.Timestamp)
+		return err
+	})
+	mm.RegisterHook(&core.Hook{
+		ID:       "learning_decay",
+		ModuleID: "learning",
+		Event:    core.CronEvent,
+		Handler:  "learning_decay",
+		Priority: 100,
+		Enabled:  true,
+		Config:   map[string]interface{}{"every": "24h"},
+	})
+
 	return lm
 }
 
@@ -62,6 +97,7 @@ func (lm *LearningModule) Schema() string {
 		success_count INTEGER DEFAULT 0,
 		failure_count INTEGER DEFAULT 0,
 		last_used_at INTEGER,
+		scope TEXT DEFAULT 'global', -- global, or project:<hash>
 		created_at INTEGER DEFAULT (strftime('%s', 'now'))
 	);
 
@@ -85,50 +121,93 @@ func (lm *LearningModule) Schema() string {
 		confidence REAL DEFAULT 0.5,
 		updated_at INTEGER DEFAULT (strftime('%s', 'now'))
 	);
+
+	-- Learned intents offered to the user, and whether they took them
+	CREATE TABLE IF NOT EXISTS intent_routes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		input TEXT NOT NULL,
+		detected_intent TEXT NOT NULL,
+		confidence REAL,
+		accepted INTEGER NOT NULL,
+		created_at INTEGER DEFAULT (strftime('%s', 'now'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_intent_routes_created ON intent_routes(created_at DESC);
 	`
 }
 
-// RecordSuccess records a successful pattern match
+// NormalizeInput is the form inputs are learned under: lower case with
+// single spaces and no trailing punctuation
+func NormalizeInput(input string) string {
+	return strings.TrimRight(strings.Join(strings.Fields(strings.ToLower(input)), " "), ".!?")
+}
+
+// RecordSuccess records a successful pattern match in the write scope
 func (lm *LearningModule) RecordSuccess(inputPattern, intent string) error {
-	id := uuid.New().String()
-
-	_, err := lm.engine.Exec(`
-		INSERT INTO learned_intents (id, input_pattern, detected_intent, success_count, last_used_at)
-		VALUES (?, ?, ?, 1, strftime('%s', 'now'))
-		ON CONFLICT(id) DO UPDATE SET
-			success_count = success_count + 1,
-			confidence = CAST(success_count AS REAL) / (success_count + failure_count),
+	inputPattern = NormalizeInput(inputPattern)
+	scope := lm.writeScope()
+	db := lm.store(scope)
+
+	n, err := db.Exec(`
+		UPDATE learned_intents
+		SET success_count = success_count + 1,
+			confidence = CAST(success_count + 1 AS REAL) / (success_count + failure_count + 1),
 			last_used_at = strftime('%s', 'now')
-	`, id, inputPattern, intent)
+		WHERE input_pattern = ? AND detected_intent = ? AND scope = ?
+	`, inputPattern, intent, scope)
+	if err != nil || n > 0 {
+		return err
+	}
 
+	_, err = db.Exec(`
+		INSERT INTO learned_intents (id, input_pattern, detected_intent, confidence, success_count, last_used_at, scope)
+		VALUES (?, ?, ?, 1.0, 1, strftime('%s', 'now'), ?)
+	`, uuid.New().String(), inputPattern, intent, scope)
 	return err
 }
 
-// RecordFailure records a failed pattern match
+// RecordFailure records a failed pattern match in every scope it was
+// learned in that applies here
 func (lm *LearningModule) RecordFailure(inputPattern, intent string) error {
-	_, err := lm.engine.Exec(`
-		UPDATE learned_intents
-		SET failure_count = failure_count + 1,
-			confidence = CAST(success_count AS REAL) / (success_count + failure_count + 1)
-		WHERE input_pattern = ? AND detected_intent = ?
-	`, inputPattern, intent)
-
-	return err
+	for _, scope := range lm.readScopes() {
+		_, err := lm.store(scope).Exec(`
+			UPDATE learned_intents
+			SET failure_count = failure_count + 1,
+				confidence = CAST(success_count AS REAL) / (success_count + failure_count + 1),
+				last_used_at = strftime('%s', 'now')
+			WHERE input_pattern = ? AND detected_intent = ? AND scope = ?
+		`, NormalizeInput(inputPattern), intent, scope)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// GetSuggestion returns a learned suggestion for input
+// GetSuggestion returns the learned intent for an input once it has been
+// confirmed min_success_count times with a confidence of at least 0.7,
+// preferring the scope learning_precedence puts first; sql.ErrNoRows when
+// there is none
 func (lm *LearningModule) GetSuggestion(input string) (string, float64, error) {
 	var intent string
 	var confidence float64
 
-	err := lm.engine.QueryRow(`
-		SELECT detected_intent, confidence
-		FROM learned_intents
-		WHERE input_pattern LIKE ?
-		AND confidence >= 0.7
-		ORDER BY confidence DESC, success_count DESC
-		LIMIT 1
-	`, "%"+input+"%").Scan(&intent, &confidence)
+	err := sql.ErrNoRows
+	for _, scope := range lm.readScopes() {
+		err = lm.store(scope).QueryRow(`
+			SELECT detected_intent, confidence
+			FROM learned_intents
+			WHERE input_pattern = ?
+			AND confidence >= 0.7
+			AND success_count >= ?
+			AND scope = ?
+			ORDER BY confidence DESC, success_count DESC
+			LIMIT 1
+		`, NormalizeInput(input), int(lm.configFloat("min_success_count", 3)), scope).Scan(&intent, &confidence)
+		if err != sql.ErrNoRows {
+			break
+		}
+	}
 
 	if err != nil {
 		return "", 0, err
@@ -137,14 +216,55 @@ func (lm *LearningModule) GetSuggestion(input string) (string, float64, error) {
 	return intent, confidence, nil
 }
 
-// LearnPreference learns a user preference
+// configFloat reads a numeric setting of the learning module
+func (lm *LearningModule) configFloat(key string, def float64) float64 {
+	if v, ok := lm.mm.ModuleConfig("learning", key).(float64); ok && v > 0 {
+		return v
+	}
+	return def
+}
+
+// Decay lowers the confidence of learned intents unused for decay_days by
+// decay_rate (once per run, so per day with the daily cron hook), then
+// prunes the stale ones that fell below min_confidence
+func (lm *LearningModule) Decay(now time.Time) (decayed, pruned int64, err error) {
+	days := lm.configFloat("decay_days", 30)
+	rate := lm.configFloat("decay_rate", 0.9)
+	floor := lm.configFloat("min_confidence", 0.3)
+	cutoff := now.Add(-time.Duration(days*24) * time.Hour).Unix()
+
+	for _, db := range lm.stores() {
+		n, err := db.Exec(`
+			UPDATE learned_intents SET confidence = confidence * ?
+			WHERE COALESCE(last_used_at, created_at) < ?
+		`, rate, cutoff)
+		if err != nil {
+			return decayed, pruned, fmt.Errorf("decay learned intents: %w", err)
+		}
+		decayed += n
+
+		n, err = db.Exec(`
+			DELETE FROM learned_intents
+			WHERE confidence < ? AND COALESCE(last_used_at, created_at) < ?
+		`, floor, cutoff)
+		if err != nil {
+			return decayed, pruned, fmt.Errorf("prune learned intents: %w", err)
+		}
+		pruned += n
+	}
+	return decayed, pruned, nil
+}
+
+// LearnPreference learns a user preference, in the database of the key's
+// scope
 func (lm *LearningModule) LearnPreference(key, value string) error {
-	_, err := lm.engine.Exec(`
+	scope, _ := SplitScopedKey(key)
+	_, err := lm.store(scope).Exec(`
 		INSERT INTO user_preferences (key, value, confidence)
 		VALUES (?, ?, 0.6)
 		ON CONFLICT(key) DO UPDATE SET
 			value = excluded.value,
-			confidence = MIN(1.0, confidence + 0.1),
+			confidence = CASE WHEN value = excluded.value THEN MIN(1.0, confidence + 0.1) ELSE excluded.confidence END,
 			updated_at = strftime('%s', 'now')
 	`, key, value)
 
@@ -156,7 +276,8 @@ func (lm *LearningModule) GetPreference(key string) (string, float64, error) {
 	var value string
 	var confidence float64
 
-	err := lm.engine.QueryRow(`
+	scope, _ := SplitScopedKey(key)
+	err := lm.store(scope).QueryRow(`
 		SELECT value, confidence FROM user_preferences WHERE key = ?
 	`, key).Scan(&value, &confidence)
 
@@ -197,6 +318,7 @@ func NewDebugModule(engine *core.Engine, mm *core.ModuleManager) *DebugModule {
 
 	// Register debug hooks
 	mm.RegisterHook(&core.Hook{
+		ID:       "debug_all",
 		ModuleID: "debug",
 		Event:    "*", // All events
 		Handler:  "debug",
@@ -308,34 +430,6 @@ func (dm *DebugModule) AddAssertion(traceID, name, expected, actual string) bool
 	return passed
 }
 
-// RunTestCase runs a test case and records results
-func (dm *DebugModule) RunTestCase(id string) (bool, error) {
-	var name, input, expectedOutput, expectedIntent string
-
-	err := dm.engine.QueryRow(`
-		SELECT name, input, COALESCE(expected_output, ''), COALESCE(expected_intent, '')
-		FROM test_cases WHERE id = ? AND enabled = 1
-	`, id).Scan(&name, &input, &expectedOutput, &expectedIntent)
-
-	if err != nil {
-		return false, err
-	}
-
-	traceID := dm.StartTrace("test_case", "debug")
-
-	// Record test was run
-	dm.engine.Exec(`
-		UPDATE test_cases SET last_run_at = strftime('%s', 'now') WHERE id = ?
-	`, id)
-
-	// Test would be executed here by the chat system
-	// For now just record the trace
-
-	dm.EndTrace(traceID, nil)
-
-	return true, nil
-}
-
 // GetFailedAssertions returns recent failed assertions for LLM analysis
 func (dm *DebugModule) GetFailedAssertions(limit int) (string, error) {
 	if limit <= 0 {
@@ -373,10 +467,31 @@ func (dm *DebugModule) GetFailedAssertions(limit int) (string, error) {
 	return string(data), nil
 }
 
-// GenerateLLMDebugPrompt generates a prompt for LLM to analyze debug data
+// analyzeLogEvents caps the debug log events sent for analysis
+const analyzeLogEvents = 200
+
+// GenerateLLMDebugPrompt generates a prompt for LLM to analyze debug data:
+// failed assertions, traces that ended in an error, and the tail of the
+// debug log. Recommendations end with follow-up tasks, one "- " per line.
 func (dm *DebugModule) GenerateLLMDebugPrompt() string {
 	failures, _ := dm.GetFailedAssertions(20)
-	debugLog := dm.mm.GetDebugLogJSON()
+
+	var traces strings.Builder
+	if failed, err := dm.Traces(20, true); err == nil {
+		for _, t := range failed {
+			fmt.Fprintf(&traces, "- %s %s [%s] %dms: %s (%d failed assertions)\n",
+				t.Start.Format(time.DateTime), t.Event, t.Module, t.Duration.Milliseconds(), t.Error, t.Failed)
+		}
+	}
+	if traces.Len() == 0 {
+		traces.WriteString("(none)\n")
+	}
+
+	log := dm.mm.GetDebugLog()
+	if len(log) > analyzeLogEvents {
+		log = log[len(log)-analyzeLogEvents:]
+	}
+	debugLog, _ := json.MarshalIndent(log, "", "  ")
 
 	return `Analyze the following debug information from GoClode and provide:
 1. Root cause analysis for any failures
@@ -386,8 +501,10 @@ func (dm *DebugModule) GenerateLLMDebugPrompt() string {
 ## Failed Assertions
 ` + failures + `
 
+## Failed Traces
+` + traces.String() + `
 ## Debug Log
-` + debugLog + `
+` + string(debugLog) + `
 
-Please provide actionable recommendations.`
+Please provide actionable recommendations. End with a "Follow-up tasks:" section listing concrete tasks, one per line starting with "- " (or "- none").`
 }
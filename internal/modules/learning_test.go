@@ -0,0 +1,105 @@
+package modules
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func setupLearning(t *testing.T) *LearningModule {
+	t.Helper()
+	engine, err := core.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return NewLearningModule(engine, core.NewModuleManager(engine))
+}
+
+func TestDecay(t *testing.T) {
+	lm := setupLearning(t)
+	now := time.Now()
+	day := int64(24 * 60 * 60)
+
+	rows := []struct {
+		id         string
+		confidence float64
+		lastUsed   int64
+	}{
+		{"fresh", 0.8, now.Unix() - day},
+		{"stale", 0.8, now.Unix() - 40*day},
+		{"faded", 0.32, now.Unix() - 40*day},
+	}
+	for _, r := range rows {
+		if _, err := lm.engine.Exec(`
+			INSERT INTO learned_intents (id, input_pattern, detected_intent, confidence, last_used_at)
+			VALUES (?, ?, 'code', ?, ?)
+		`, r.id, r.id, r.confidence, r.lastUsed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	decayed, pruned, err := lm.Decay(now)
+	if err != nil {
+		t.Fatalf("Decay failed: %v", err)
+	}
+	if decayed != 2 || pruned != 1 {
+		t.Errorf("Decay() = %d decayed, %d pruned; want 2, 1", decayed, pruned)
+	}
+
+	want := map[string]float64{"fresh": 0.8, "stale": 0.72}
+	got := make(map[string]float64)
+	dbRows, err := lm.engine.Query(`SELECT id, confidence FROM learned_intents`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbRows.Close()
+	for dbRows.Next() {
+		var id string
+		var c float64
+		dbRows.Scan(&id, &c)
+		got[id] = c
+	}
+	if len(got) != len(want) {
+		t.Fatalf("remaining intents = %v, want %v", got, want)
+	}
+	for id, c := range want {
+		if d := got[id] - c; d > 1e-9 || d < -1e-9 {
+			t.Errorf("confidence of %s = %v, want %v", id, got[id], c)
+		}
+	}
+}
+
+func TestSuggestion(t *testing.T) {
+	lm := setupLearning(t)
+
+	for i := 0; i < 2; i++ {
+		if err := lm.RecordSuccess("What changed?", "/diff"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := lm.GetSuggestion("what changed"); err == nil {
+		t.Error("expected no suggestion below min_success_count")
+	}
+
+	lm.RecordSuccess("what  changed", "/diff")
+	intent, confidence, err := lm.GetSuggestion("WHAT CHANGED")
+	if err != nil || intent != "/diff" || confidence != 1.0 {
+		t.Fatalf("GetSuggestion() = %q, %v, %v; want /diff, 1.0", intent, confidence, err)
+	}
+
+	// Two refusals bring it to 3/5 = 0.6, under the 0.7 bar
+	lm.RecordFailure("what changed", "/diff")
+	lm.RecordFailure("what changed", "/diff")
+	if _, _, err := lm.GetSuggestion("what changed"); err == nil {
+		t.Error("expected no suggestion after refusals")
+	}
+
+	var rows int
+	lm.engine.QueryRow(`SELECT COUNT(*) FROM learned_intents`).Scan(&rows)
+	if rows != 1 {
+		t.Errorf("learned_intents has %d rows, want 1", rows)
+	}
+}
@@ -0,0 +1,401 @@
+// Package modules - Digest reports: activity across sessions over a
+// period, written as markdown and sent to Slack or by email on a schedule
+package modules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+// reportListSize caps the files, commits, and intents listed in a digest
+const reportListSize = 10
+
+// ReportModule counts intents as they are parsed and writes the digest
+// reports of the report_every config
+type ReportModule struct {
+	engine  *core.Engine
+	workDir string // report_dir is relative to it
+	http    *http.Client
+}
+
+// ProviderUsage is the tokens a model used over a period, and their cost
+type ProviderUsage struct {
+	Provider  string
+	Model     string
+	TokensIn  int
+	TokensOut int
+	Cost      float64 // USD, from the provider's price_in and price_out
+}
+
+// FileActivity is how many times a file was changed
+type FileActivity struct {
+	Path    string
+	Changes int
+}
+
+// CommitSummary is an auto-commit of the period
+type CommitSummary struct {
+	Hash       string
+	Message    string
+	Insertions int
+	Deletions  int
+}
+
+// IntentCount is how many inputs were parsed as an intent
+type IntentCount struct {
+	Intent string
+	Count  int
+}
+
+// Digest summarizes the activity between From and To
+type Digest struct {
+	From, To     time.Time
+	Sessions     int
+	Messages     int
+	Usage        []ProviderUsage
+	FileChanges  int
+	Files        []FileActivity // Most changed first
+	FilesTouched int
+	Commits      []CommitSummary // Newest first
+	CommitCount  int
+	Insertions   int
+	Deletions    int
+	Intents      []IntentCount // Most frequent first
+}
+
+// Cost totals the usage cost
+func (d *Digest) Cost() float64 {
+	total := 0.0
+	for _, u := range d.Usage {
+		total += u.Cost
+	}
+	return total
+}
+
+// NewReportModule creates the reporting module. Its cron hook runs at the
+// report_every interval read at startup; changes to the other report
+// settings apply at the next run.
+func NewReportModule(engine *core.Engine, mm *core.ModuleManager, workDir string) *ReportModule {
+	rm := &ReportModule{engine: engine, workDir: workDir, http: &http.Client{Timeout: 30 * time.Second}}
+
+	mm.RegisterModule(&core.Module{
+		ID:        "report",
+		Name:      "Digest Reports",
+		Version:   "1.0.0",
+		Enabled:   true,
+		Priority:  60,
+		SchemaSQL: rm.Schema(),
+	})
+
+	mm.RegisterHandler("report_intent", rm.handleIntent)
+	mm.RegisterHook(&core.Hook{
+		ID:       "report_intent",
+		ModuleID: "report",
+		Event:    "intent_parsed",
+		Handler:  "report_intent",
+		Priority: 100,
+		Enabled:  true,
+	})
+
+	every := "24h"
+	if d := rm.interval(); d > 0 {
+		every = d.String()
+	}
+	mm.RegisterHandler("report_digest", func(ctx *core.HookContext) error {
+		_, err := rm.Run(ctx.Timestamp)
+		return err
+	})
+	mm.RegisterHook(&core.Hook{
+		ID:       "report_digest",
+		ModuleID: "report",
+		Event:    core.CronEvent,
+		Handler:  "report_digest",
+		Priority: 100,
+		Enabled:  true,
+		Config:   map[string]interface{}{"every": every},
+	})
+
+	return rm
+}
+
+// Schema returns the intent counts table
+func (rm *ReportModule) Schema() string {
+	return `
+	-- Inputs parsed as each intent, by UTC day
+	CREATE TABLE IF NOT EXISTS report_intents (
+		day TEXT NOT NULL,
+		intent TEXT NOT NULL,
+		count INTEGER DEFAULT 0,
+		PRIMARY KEY (day, intent)
+	);
+	`
+}
+
+// interval reads report_every; 0 when reports are off
+func (rm *ReportModule) interval() time.Duration {
+	raw, _ := rm.engine.GetConfig("report_every")
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// handleIntent counts a parsed intent
+func (rm *ReportModule) handleIntent(ctx *core.HookContext) error {
+	intent, _ := ctx.Payload["type"].(string)
+	if intent == "" {
+		return nil
+	}
+	_, err := rm.engine.Exec(`
+		INSERT INTO report_intents (day, intent, count) VALUES (?, ?, 1)
+		ON CONFLICT(day, intent) DO UPDATE SET count = count + 1
+	`, ctx.Timestamp.UTC().Format("2006-01-02"), intent)
+	return err
+}
+
+// Run writes and sends the digest of the report_every period ending at
+// now, if reports are on; it returns the report's path, "" if none
+func (rm *ReportModule) Run(now time.Time) (string, error) {
+	every := rm.interval()
+	if every == 0 {
+		return "", nil
+	}
+	d, err := rm.Digest(now.Add(-every), now)
+	if err != nil {
+		return "", err
+	}
+	report := d.Markdown()
+
+	var path string
+	var errs []string
+	if dir, _ := rm.engine.GetConfig("report_dir"); dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(rm.workDir, dir)
+		}
+		path = filepath.Join(dir, "digest-"+now.Format("2006-01-02-1504")+".md")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			errs = append(errs, err.Error())
+		} else if err := os.WriteFile(path, []byte(report), 0o644); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if url := rm.secret("report_slack_webhook"); url != "" {
+		if err := rm.postSlack(url, report); err != nil {
+			errs = append(errs, "slack: "+err.Error())
+		}
+	}
+	if to, _ := rm.engine.GetConfig("report_email_to"); to != "" {
+		if err := rm.sendEmail(to, "GoClode digest "+now.Format("2006-01-02"), report); err != nil {
+			errs = append(errs, "email: "+err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return path, fmt.Errorf("report: %s", strings.Join(errs, "; "))
+	}
+	return path, nil
+}
+
+// secret reads a credential from config, falling back to the environment
+// variable of the same name in upper case
+func (rm *ReportModule) secret(key string) string {
+	if value, _ := rm.engine.GetConfig(key); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToUpper(key))
+}
+
+// Digest gathers the activity between from and to
+func (rm *ReportModule) Digest(from, to time.Time) (*Digest, error) {
+	d := &Digest{
+		From:    from,
+		To:      to,
+		Usage:   make([]ProviderUsage, 0),
+		Files:   make([]FileActivity, 0),
+		Commits: make([]CommitSummary, 0),
+		Intents: make([]IntentCount, 0),
+	}
+	start, end := from.Unix(), to.Unix()
+
+	if err := rm.engine.QueryRow(`
+		SELECT COUNT(DISTINCT session_id), COUNT(*) FROM messages WHERE created_at >= ? AND created_at < ?
+	`, start, end).Scan(&d.Sessions, &d.Messages); err != nil {
+		return nil, fmt.Errorf("count messages: %w", err)
+	}
+
+	rows, err := rm.engine.Query(`
+		SELECT COALESCE(m.provider_id, ''), COALESCE(m.model, ''), SUM(m.tokens_in), SUM(m.tokens_out),
+			CASE WHEN json_valid(p.config) THEN COALESCE(json_extract(p.config, '$.price_in'), 0) ELSE 0 END,
+			CASE WHEN json_valid(p.config) THEN COALESCE(json_extract(p.config, '$.price_out'), 0) ELSE 0 END
+		FROM messages m LEFT JOIN providers p ON p.provider_id = m.provider_id
+		WHERE m.role = 'assistant' AND m.created_at >= ? AND m.created_at < ?
+		GROUP BY m.provider_id, m.model
+		ORDER BY SUM(m.tokens_in + m.tokens_out) DESC
+	`, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("read usage: %w", err)
+	}
+	for rows.Next() {
+		var u ProviderUsage
+		var priceIn, priceOut float64
+		if err := rows.Scan(&u.Provider, &u.Model, &u.TokensIn, &u.TokensOut, &priceIn, &priceOut); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		u.Cost = (float64(u.TokensIn)*priceIn + float64(u.TokensOut)*priceOut) / 1e6
+		d.Usage = append(d.Usage, u)
+	}
+	rows.Close()
+
+	if err := rm.engine.QueryRow(`
+		SELECT COUNT(*), COUNT(DISTINCT file_path) FROM files_modified
+		WHERE undone_at IS NULL AND created_at >= ? AND created_at < ?
+	`, start, end).Scan(&d.FileChanges, &d.FilesTouched); err != nil {
+		return nil, fmt.Errorf("count files: %w", err)
+	}
+	rows, err = rm.engine.Query(`
+		SELECT file_path, COUNT(*) FROM files_modified
+		WHERE undone_at IS NULL AND created_at >= ? AND created_at < ?
+		GROUP BY file_path ORDER BY COUNT(*) DESC, file_path LIMIT ?
+	`, start, end, reportListSize)
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	for rows.Next() {
+		var f FileActivity
+		if err := rows.Scan(&f.Path, &f.Changes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.Files = append(d.Files, f)
+	}
+	rows.Close()
+
+	if err := rm.engine.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(insertions), 0), COALESCE(SUM(deletions), 0) FROM git_commits
+		WHERE revert_hash IS NULL AND created_at >= ? AND created_at < ?
+	`, start, end).Scan(&d.CommitCount, &d.Insertions, &d.Deletions); err != nil {
+		return nil, fmt.Errorf("count commits: %w", err)
+	}
+	rows, err = rm.engine.Query(`
+		SELECT git_hash, commit_message, COALESCE(insertions, 0), COALESCE(deletions, 0) FROM git_commits
+		WHERE revert_hash IS NULL AND created_at >= ? AND created_at < ?
+		ORDER BY created_at DESC LIMIT ?
+	`, start, end, reportListSize)
+	if err != nil {
+		return nil, fmt.Errorf("list commits: %w", err)
+	}
+	for rows.Next() {
+		var c CommitSummary
+		if err := rows.Scan(&c.Hash, &c.Message, &c.Insertions, &c.Deletions); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.Commits = append(d.Commits, c)
+	}
+	rows.Close()
+
+	rows, err = rm.engine.Query(`
+		SELECT intent, SUM(count) FROM report_intents WHERE day >= ? AND day <= ?
+		GROUP BY intent ORDER BY SUM(count) DESC, intent LIMIT ?
+	`, from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02"), reportListSize)
+	if err != nil {
+		return nil, fmt.Errorf("list intents: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i IntentCount
+		if err := rows.Scan(&i.Intent, &i.Count); err != nil {
+			return nil, err
+		}
+		d.Intents = append(d.Intents, i)
+	}
+	return d, rows.Err()
+}
+
+// Markdown renders the digest
+func (d *Digest) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# GoClode digest: %s to %s\n\n", d.From.Format("2006-01-02 15:04"), d.To.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&sb, "- %d session(s), %d message(s)\n", d.Sessions, d.Messages)
+	fmt.Fprintf(&sb, "- %d file change(s) across %d file(s)\n", d.FileChanges, d.FilesTouched)
+	fmt.Fprintf(&sb, "- %d commit(s), +%d -%d\n", d.CommitCount, d.Insertions, d.Deletions)
+	fmt.Fprintf(&sb, "- Cost: $%.4f\n", d.Cost())
+
+	if len(d.Usage) > 0 {
+		sb.WriteString("\n## Usage\n\n| Provider | Model | Tokens in | Tokens out | Cost |\n|---|---|---:|---:|---:|\n")
+		for _, u := range d.Usage {
+			fmt.Fprintf(&sb, "| %s | %s | %d | %d | $%.4f |\n", u.Provider, u.Model, u.TokensIn, u.TokensOut, u.Cost)
+		}
+	}
+	if len(d.Files) > 0 {
+		sb.WriteString("\n## Most changed files\n\n")
+		for _, f := range d.Files {
+			fmt.Fprintf(&sb, "- `%s` (%d)\n", f.Path, f.Changes)
+		}
+	}
+	if len(d.Commits) > 0 {
+		sb.WriteString("\n## Commits\n\n")
+		for _, c := range d.Commits {
+			subject, _, _ := strings.Cut(c.Message, "\n")
+			fmt.Fprintf(&sb, "- `%.7s` %s (+%d -%d)\n", c.Hash, subject, c.Insertions, c.Deletions)
+		}
+		if more := d.CommitCount - len(d.Commits); more > 0 {
+			fmt.Fprintf(&sb, "- … and %d more\n", more)
+		}
+	}
+	if len(d.Intents) > 0 {
+		sb.WriteString("\n## Top intents\n\n")
+		for _, i := range d.Intents {
+			fmt.Fprintf(&sb, "- %s: %d\n", i.Intent, i.Count)
+		}
+	}
+	return sb.String()
+}
+
+// postSlack posts a report to a Slack incoming webhook
+func (rm *ReportModule) postSlack(url, report string) error {
+	body, _ := json.Marshal(map[string]string{"text": report})
+	resp, err := rm.http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sendEmail mails a report through smtp_addr to comma-separated addresses
+func (rm *ReportModule) sendEmail(to, subject, report string) error {
+	addr, _ := rm.engine.GetConfig("smtp_addr")
+	from, _ := rm.engine.GetConfig("report_email_from")
+	if addr == "" || from == "" {
+		return fmt.Errorf("set smtp_addr and report_email_from")
+	}
+	recipients := make([]string, 0)
+	for _, r := range strings.Split(to, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			recipients = append(recipients, r)
+		}
+	}
+
+	var auth smtp.Auth
+	if user := rm.secret("smtp_username"); user != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		auth = smtp.PlainAuth("", user, rm.secret("smtp_password"), host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/markdown; charset=utf-8\r\n\r\n%s",
+		from, strings.Join(recipients, ", "), subject, strings.ReplaceAll(report, "\n", "\r\n"))
+	return smtp.SendMail(addr, auth, from, recipients, []byte(msg))
+}
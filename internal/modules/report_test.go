@@ -0,0 +1,101 @@
+package modules
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func TestDigest(t *testing.T) {
+	engine, err := core.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	workDir := t.TempDir()
+	rm := NewReportModule(engine, core.NewModuleManager(engine), workDir)
+
+	now := time.Now()
+	at := now.Add(-time.Hour).Unix()
+	old := now.Add(-48 * time.Hour).Unix()
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE providers SET config = '{"price_in": 1, "price_out": 2}' WHERE provider_id = 'cerebras'`, nil},
+		{`INSERT INTO sessions (session_id) VALUES ('s1'), ('s2')`, nil},
+		{`INSERT INTO messages (message_id, session_id, role, content, provider_id, model, tokens_in, tokens_out, created_at) VALUES
+			('m1', 's1', 'user', 'hi', 'cerebras', 'm', 0, 0, ?),
+			('m2', 's1', 'assistant', 'ok', 'cerebras', 'm', 1000000, 500000, ?),
+			('m3', 's2', 'assistant', 'old', 'cerebras', 'm', 9, 9, ?)`, []interface{}{at, at, old}},
+		{`INSERT INTO files_modified (file_id, session_id, file_path, operation, created_at) VALUES
+			('f1', 's1', 'main.go', 'modify', ?), ('f2', 's1', 'main.go', 'modify', ?), ('f3', 's1', 'util.go', 'create', ?)`, []interface{}{at, at, at}},
+		{`INSERT INTO git_commits (commit_id, session_id, git_hash, commit_message, insertions, deletions, created_at) VALUES
+			('c1', 's1', 'abcdef123456', 'Fix parser' || char(10) || char(10) || 'Body', 10, 2, ?)`, []interface{}{at}},
+	} {
+		if _, err := engine.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("%s: %v", stmt.query, err)
+		}
+	}
+	for _, intent := range []string{"code", "code", "undo"} {
+		if err := rm.handleIntent(&core.HookContext{Payload: map[string]interface{}{"type": intent}, Timestamp: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := rm.Digest(now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Digest failed: %v", err)
+	}
+	if d.Sessions != 1 || d.Messages != 2 || d.FileChanges != 3 || d.FilesTouched != 2 || d.CommitCount != 1 {
+		t.Errorf("Unexpected counts %+v", d)
+	}
+	if d.Cost() != 2 {
+		t.Errorf("Expected $2 (1M in at $1, 0.5M out at $2), got %v", d.Cost())
+	}
+	if len(d.Files) == 0 || d.Files[0].Path != "main.go" || len(d.Intents) == 0 || d.Intents[0] != (IntentCount{"code", 2}) {
+		t.Errorf("Unexpected lists %+v %+v", d.Files, d.Intents)
+	}
+
+	report := d.Markdown()
+	for _, want := range []string{"1 session(s), 2 message(s)", "`abcdef1` Fix parser (+10 -2)", "`main.go` (2)", "- code: 2", "$2.0000"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected %q in the report:\n%s", want, report)
+		}
+	}
+
+	// Off until report_every is set
+	if path, err := rm.Run(now); err != nil || path != "" {
+		t.Errorf("Expected no report, got %q (%v)", path, err)
+	}
+
+	var posted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		posted = string(body)
+	}))
+	defer srv.Close()
+	engine.SetConfig("report_every", "24h")
+	engine.SetConfig("report_slack_webhook", srv.URL)
+
+	path, err := rm.Run(now)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.HasPrefix(path, filepath.Join(workDir, ".goclode", "reports")) {
+		t.Errorf("Unexpected report path %s", path)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != report {
+		t.Errorf("Report file differs from the digest (%v)", err)
+	}
+	if !strings.Contains(posted, "GoClode digest") {
+		t.Errorf("Expected the digest posted to Slack, got %q", posted)
+	}
+}
@@ -0,0 +1,178 @@
+// Package modules - Learning scopes: what is learned in one project stays
+// there unless it is global
+package modules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+// GlobalScope holds learning shared by every project
+const GlobalScope = "global"
+
+// UserLearningPath is ~/.goclode/learning.db, the database global learning
+// is kept in so that every project sees it
+func UserLearningPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".goclode", "learning.db"), nil
+}
+
+// OpenGlobal keeps global learning in the database at path ("" for
+// UserLearningPath) instead of the project database. Without it, global
+// learning stays in the project database.
+func (lm *LearningModule) OpenGlobal(path string) error {
+	if path == "" {
+		var err error
+		if path, err = UserLearningPath(); err != nil {
+			return fmt.Errorf("open global learning: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("open global learning: %w", err)
+	}
+	global, err := core.NewEngine(path)
+	if err != nil {
+		return fmt.Errorf("open global learning: %w", err)
+	}
+	if _, err := global.Exec(lm.Schema()); err != nil {
+		global.Close()
+		return fmt.Errorf("open global learning: %w", err)
+	}
+	if err := global.EnsureColumn("learned_intents", "scope", "TEXT DEFAULT 'global'"); err != nil {
+		global.Close()
+		return fmt.Errorf("open global learning: %w", err)
+	}
+	lm.Close()
+	lm.global = global
+	return nil
+}
+
+// Close closes the global learning database OpenGlobal opened
+func (lm *LearningModule) Close() error {
+	if lm.global == nil {
+		return nil
+	}
+	err := lm.global.Close()
+	lm.global = nil
+	return err
+}
+
+// store is the database learning in scope is kept in
+func (lm *LearningModule) store(scope string) *core.Engine {
+	if scope == GlobalScope && lm.global != nil {
+		return lm.global
+	}
+	return lm.engine
+}
+
+// stores lists the databases learning is kept in, the project's first
+func (lm *LearningModule) stores() []*core.Engine {
+	if lm.global == nil {
+		return []*core.Engine{lm.engine}
+	}
+	return []*core.Engine{lm.engine, lm.global}
+}
+
+// ProjectScope is the scope of the workspace at root
+func ProjectScope(root string) string {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	sum := sha256.Sum256([]byte(root))
+	return "project:" + hex.EncodeToString(sum[:6])
+}
+
+// UserScope is the scope of a --listen client's own learning
+func UserScope(user string) string {
+	return "user:" + user
+}
+
+// SetUser scopes learning to a --listen client: what it teaches stays its
+// own, and comes first when it asks. An empty name goes back to the
+// project and global scopes.
+func (lm *LearningModule) SetUser(user string) {
+	lm.user = ""
+	if user != "" {
+		lm.user = UserScope(user)
+	}
+}
+
+// SetProject scopes learning to the workspace at root
+func (lm *LearningModule) SetProject(root string) {
+	lm.project = ProjectScope(root)
+}
+
+// writeScope is where new learning goes (learning_scope)
+func (lm *LearningModule) writeScope() string {
+	if lm.user != "" {
+		return lm.user
+	}
+	if lm.project == "" {
+		return GlobalScope
+	}
+	if scope, _ := lm.engine.GetConfig("learning_scope"); scope == "global" {
+		return GlobalScope
+	}
+	return lm.project
+}
+
+// readScopes lists the scopes learning is read from, the one that wins
+// first: the client's, then as learning_precedence says
+func (lm *LearningModule) readScopes() []string {
+	scopes := make([]string, 0, 3)
+	if lm.user != "" {
+		scopes = append(scopes, lm.user)
+	}
+	if lm.project == "" {
+		return append(scopes, GlobalScope)
+	}
+	switch precedence, _ := lm.engine.GetConfig("learning_precedence"); precedence {
+	case "global":
+		return append(scopes, GlobalScope, lm.project)
+	case "project_only":
+		return append(scopes, lm.project)
+	}
+	return append(scopes, lm.project, GlobalScope)
+}
+
+// ScopeLabel describes a scope relative to the current project
+func (lm *LearningModule) ScopeLabel(scope string) string {
+	switch {
+	case scope == GlobalScope:
+		return "global"
+	case scope == lm.project:
+		return "project"
+	case strings.HasPrefix(scope, "user:"):
+		return strings.TrimPrefix(scope, "user:")
+	}
+	return "other project"
+}
+
+// scopedKey prefixes a preference key with its scope
+func scopedKey(scope, name string) string {
+	return scope + ":" + name
+}
+
+// SplitScopedKey separates a preference key into scope and name
+func SplitScopedKey(key string) (scope, name string) {
+	if rest, ok := strings.CutPrefix(key, GlobalScope+":"); ok {
+		return GlobalScope, rest
+	}
+	for _, prefix := range []string{"project:", "user:"} {
+		if rest, ok := strings.CutPrefix(key, prefix); ok {
+			if id, name, ok := strings.Cut(rest, ":"); ok {
+				return prefix + id, name
+			}
+		}
+	}
+	return GlobalScope, key
+}
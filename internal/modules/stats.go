@@ -0,0 +1,155 @@
+// Package modules - What the learning module knows and how it has been
+// used, so users can audit it
+package modules
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Route is one learned intent offered for an input
+type Route struct {
+	Input      string
+	Intent     string
+	Confidence float64
+	Accepted   bool
+	At         time.Time
+}
+
+// LearningStats summarizes the learning module's tables
+type LearningStats struct {
+	Intents      int
+	Patterns     int
+	Preferences  int
+	Offered      int // Suggestions shown
+	Accepted     int // Suggestions taken
+	TopIntents   []LearnedIntent
+	TopPrefs     []UserPreference
+	RecentRoutes []Route
+}
+
+// HitRate is the share of an intent's matches the user confirmed
+func (li LearnedIntent) HitRate() float64 {
+	if total := li.SuccessCount + li.FailureCount; total > 0 {
+		return float64(li.SuccessCount) / float64(total)
+	}
+	return 0
+}
+
+// RecordRoute logs a learned intent offered for an input and the answer
+func (lm *LearningModule) RecordRoute(input, intent string, confidence float64, accepted bool) error {
+	_, err := lm.engine.Exec(`
+		INSERT INTO intent_routes (input, detected_intent, confidence, accepted) VALUES (?, ?, ?, ?)
+	`, input, intent, confidence, accepted)
+	if err != nil {
+		return fmt.Errorf("record route: %w", err)
+	}
+	return nil
+}
+
+// Stats returns counts, the limit most used intents and most confident
+// preferences, and the suggestions offered in the last days
+func (lm *LearningModule) Stats(limit, days int) (*LearningStats, error) {
+	stats := &LearningStats{
+		TopIntents:   make([]LearnedIntent, 0),
+		TopPrefs:     make([]UserPreference, 0),
+		RecentRoutes: make([]Route, 0),
+	}
+	since := time.Now().AddDate(0, 0, -days).Unix()
+
+	if err := lm.engine.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM code_patterns),
+			(SELECT COUNT(*) FROM intent_routes WHERE created_at >= ?),
+			(SELECT COUNT(*) FROM intent_routes WHERE created_at >= ? AND accepted = 1)
+	`, since, since).Scan(&stats.Patterns, &stats.Offered, &stats.Accepted); err != nil {
+		return nil, fmt.Errorf("count learning: %w", err)
+	}
+
+	// Intents and preferences are in the project database and, for global
+	// learning, the user's
+	for _, db := range lm.stores() {
+		var intents, prefs int
+		if err := db.QueryRow(`
+			SELECT (SELECT COUNT(*) FROM learned_intents), (SELECT COUNT(*) FROM user_preferences)
+		`).Scan(&intents, &prefs); err != nil {
+			return nil, fmt.Errorf("count learning: %w", err)
+		}
+		stats.Intents += intents
+		stats.Preferences += prefs
+
+		rows, err := db.Query(`
+			SELECT input_pattern, detected_intent, confidence, success_count, failure_count, COALESCE(last_used_at, 0), COALESCE(scope, 'global')
+			FROM learned_intents ORDER BY success_count DESC, confidence DESC LIMIT ?
+		`, limit)
+		if err != nil {
+			return nil, fmt.Errorf("list learned intents: %w", err)
+		}
+		for rows.Next() {
+			var li LearnedIntent
+			if err := rows.Scan(&li.InputPattern, &li.DetectedIntent, &li.Confidence, &li.SuccessCount, &li.FailureCount, &li.LastUsedAt, &li.Scope); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			stats.TopIntents = append(stats.TopIntents, li)
+		}
+		rows.Close()
+
+		rows, err = db.Query(`
+			SELECT key, value, confidence, COALESCE(updated_at, 0) FROM user_preferences
+			ORDER BY confidence DESC, updated_at DESC LIMIT ?
+		`, limit)
+		if err != nil {
+			return nil, fmt.Errorf("list preferences: %w", err)
+		}
+		for rows.Next() {
+			var up UserPreference
+			if err := rows.Scan(&up.Key, &up.Value, &up.Confidence, &up.UpdatedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			stats.TopPrefs = append(stats.TopPrefs, up)
+		}
+		rows.Close()
+	}
+
+	sort.SliceStable(stats.TopIntents, func(i, j int) bool {
+		a, b := stats.TopIntents[i], stats.TopIntents[j]
+		if a.SuccessCount != b.SuccessCount {
+			return a.SuccessCount > b.SuccessCount
+		}
+		return a.Confidence > b.Confidence
+	})
+	if len(stats.TopIntents) > limit {
+		stats.TopIntents = stats.TopIntents[:limit]
+	}
+	sort.SliceStable(stats.TopPrefs, func(i, j int) bool {
+		a, b := stats.TopPrefs[i], stats.TopPrefs[j]
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		return a.UpdatedAt > b.UpdatedAt
+	})
+	if len(stats.TopPrefs) > limit {
+		stats.TopPrefs = stats.TopPrefs[:limit]
+	}
+
+	rows, err := lm.engine.Query(`
+		SELECT input, detected_intent, COALESCE(confidence, 0), accepted, created_at FROM intent_routes
+		WHERE created_at >= ? ORDER BY created_at DESC, id DESC LIMIT ?
+	`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list routes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r Route
+		var at int64
+		if err := rows.Scan(&r.Input, &r.Intent, &r.Confidence, &r.Accepted, &at); err != nil {
+			return nil, err
+		}
+		r.At = time.Unix(at, 0)
+		stats.RecentRoutes = append(stats.RecentRoutes, r)
+	}
+	return stats, rows.Err()
+}
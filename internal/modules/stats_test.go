@@ -0,0 +1,29 @@
+package modules
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	lm := setupLearning(t)
+	for i := 0; i < 3; i++ {
+		lm.RecordSuccess("what changed", "/diff")
+	}
+	lm.RecordFailure("what changed", "/diff")
+	lm.RecordSuccess("ship it", "/push")
+	lm.LearnPreference("/src/app:style.indent", "tabs")
+	lm.RecordRoute("what changed", "/diff", 1.0, true)
+	lm.RecordRoute("what changed", "/diff", 1.0, false)
+
+	stats, err := lm.Stats(10, 7)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Intents != 2 || stats.Preferences != 1 || stats.Offered != 2 || stats.Accepted != 1 {
+		t.Errorf("Stats() counts = %+v", stats)
+	}
+	if len(stats.TopIntents) != 2 || stats.TopIntents[0].DetectedIntent != "/diff" || stats.TopIntents[0].HitRate() != 0.75 {
+		t.Errorf("TopIntents = %+v", stats.TopIntents)
+	}
+	if len(stats.RecentRoutes) != 2 || stats.RecentRoutes[0].Accepted {
+		t.Errorf("RecentRoutes = %+v, want the refusal first", stats.RecentRoutes)
+	}
+}
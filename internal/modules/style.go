@@ -0,0 +1,216 @@
+// Package modules - Style preferences learned from the changes a user
+// undoes and the corrections they send with /feedback
+package modules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// styleMinConfidence is the confidence a preference needs before it goes
+// into the system prompt: a detected style has to recur, a /feedback
+// correction counts right away
+const (
+	styleMinConfidence = 0.7
+	noteConfidence     = 0.8
+)
+
+// Preference is one learned user preference
+type Preference struct {
+	Key        string
+	Value      string
+	Confidence float64
+}
+
+// styleRules describe learned style values for the system prompt
+var styleRules = map[string]string{
+	"indent=tabs":   "Indent with tabs, not spaces.",
+	"indent=spaces": "Indent with spaces, not tabs.",
+	"quotes=double": "Use double quotes for strings where the language allows either.",
+	"quotes=single": "Use single quotes for strings where the language allows either.",
+}
+
+// ChangedLines returns the lines a change removed and added, ignoring
+// lines it left alone
+func ChangedLines(before, after string) (removed, added []string) {
+	count := make(map[string]int)
+	for _, line := range strings.Split(before, "\n") {
+		count[line]++
+	}
+	for _, line := range strings.Split(after, "\n") {
+		if count[line] > 0 {
+			count[line]--
+			continue
+		}
+		added = append(added, line)
+	}
+	kept := make(map[string]int)
+	for _, line := range strings.Split(after, "\n") {
+		kept[line]++
+	}
+	for _, line := range strings.Split(before, "\n") {
+		if kept[line] > 0 {
+			kept[line]--
+			continue
+		}
+		removed = append(removed, line)
+	}
+	return removed, added
+}
+
+// PatchLines returns the removed and added lines of a unified diff
+func PatchLines(patch string) (removed, added []string) {
+	for _, line := range strings.Split(patch, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "-"):
+			removed = append(removed, line[1:])
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line[1:])
+		}
+	}
+	return removed, added
+}
+
+// DetectStyle looks at a change the user undid for style it imposed on
+// lines whose content it otherwise left alone, and returns the styles the
+// user kept instead (e.g. indent=tabs when it re-indented with spaces)
+func DetectStyle(removed, added []string) map[string]string {
+	byContent := make(map[string][]string)
+	for _, line := range removed {
+		key := strings.TrimSpace(line)
+		byContent[key] = append(byContent[key], line)
+	}
+
+	votes := make(map[string]int)
+	for _, line := range added {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		for _, old := range byContent[trimmed] {
+			switch {
+			case strings.HasPrefix(old, "\t") && strings.HasPrefix(line, " "):
+				votes["indent=tabs"]++
+			case strings.HasPrefix(old, " ") && strings.HasPrefix(line, "\t"):
+				votes["indent=spaces"]++
+			}
+		}
+		for _, old := range removed {
+			if old == line || strings.ReplaceAll(old, "'", `"`) != strings.ReplaceAll(line, "'", `"`) {
+				continue
+			}
+			if strings.Count(old, `"`) > strings.Count(line, `"`) {
+				votes["quotes=double"]++
+			} else {
+				votes["quotes=single"]++
+			}
+		}
+	}
+
+	prefs := make(map[string]string)
+	for _, pair := range [][2]string{{"indent=tabs", "indent=spaces"}, {"quotes=double", "quotes=single"}} {
+		a, b := votes[pair[0]], votes[pair[1]]
+		if a == b {
+			continue
+		}
+		winner := pair[0]
+		if b > a {
+			winner = pair[1]
+		}
+		name, value, _ := strings.Cut(winner, "=")
+		prefs[name] = value
+	}
+	return prefs
+}
+
+// LearnStyle records styles detected in an undone change in the write
+// scope; each recurrence raises their confidence
+func (lm *LearningModule) LearnStyle(prefs map[string]string) error {
+	for name, value := range prefs {
+		if err := lm.LearnPreference(scopedKey(lm.writeScope(), "style."+name), value); err != nil {
+			return fmt.Errorf("learn style: %w", err)
+		}
+	}
+	return nil
+}
+
+// NoteStyle records a correction the user wrote with /feedback
+func (lm *LearningModule) NoteStyle(note string) error {
+	sum := sha256.Sum256([]byte(NormalizeInput(note)))
+	scope := lm.writeScope()
+	_, err := lm.store(scope).Exec(`
+		INSERT INTO user_preferences (key, value, confidence)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			confidence = MIN(1.0, confidence + 0.1),
+			updated_at = strftime('%s', 'now')
+	`, scopedKey(scope, "style.note."+hex.EncodeToString(sum[:6])), note, noteConfidence)
+	if err != nil {
+		return fmt.Errorf("record style note: %w", err)
+	}
+	return nil
+}
+
+// StylePreferences returns the confident style preferences that apply here,
+// the most confident first. When a style was learned in several scopes,
+// the one learning_precedence puts first wins.
+func (lm *LearningModule) StylePreferences(limit int) ([]Preference, error) {
+	prefs := make([]Preference, 0)
+	seen := make(map[string]bool)
+	for _, scope := range lm.readScopes() {
+		prefix := scopedKey(scope, "style.")
+		rows, err := lm.store(scope).Query(`
+			SELECT key, value, confidence FROM user_preferences
+			WHERE key LIKE ? ESCAPE '\' AND confidence >= ?
+			ORDER BY confidence DESC, updated_at DESC
+		`, escapeLike(prefix)+"%", styleMinConfidence)
+		if err != nil {
+			return nil, fmt.Errorf("list style preferences: %w", err)
+		}
+		for rows.Next() {
+			var p Preference
+			if err := rows.Scan(&p.Key, &p.Value, &p.Confidence); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			p.Key = strings.TrimPrefix(p.Key, prefix)
+			if !seen[p.Key] {
+				seen[p.Key] = true
+				prefs = append(prefs, p)
+			}
+		}
+		rows.Close()
+	}
+
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].Confidence > prefs[j].Confidence })
+	if len(prefs) > limit {
+		prefs = prefs[:limit]
+	}
+	return prefs, nil
+}
+
+// StylePrompt turns style preferences into instructions for the model
+func StylePrompt(prefs []Preference) string {
+	if len(prefs) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\nThe user's style preferences for this project:\n")
+	for _, p := range prefs {
+		if rule, ok := styleRules[p.Key+"="+p.Value]; ok {
+			sb.WriteString("- " + rule + "\n")
+		} else {
+			sb.WriteString("- " + p.Value + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// escapeLike escapes LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
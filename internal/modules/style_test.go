@@ -0,0 +1,224 @@
+package modules
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectStyle(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   map[string]string
+	}{
+		{
+			"tabs to spaces",
+			"func f() {\n\treturn 1\n}\n",
+			"func f() {\n    return 1\n}\n",
+			map[string]string{"indent": "tabs"},
+		},
+		{
+			"double to single quotes",
+			"const a = \"x\";\nconst b = 1;\n",
+			"const a = 'x';\nconst b = 1;\n",
+			map[string]string{"quotes": "double"},
+		},
+		{
+			"content change only",
+			"func f() {\n\treturn 1\n}\n",
+			"func f() {\n\treturn 2\n}\n",
+			map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectStyle(ChangedLines(tt.before, tt.after))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectStyle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchLines(t *testing.T) {
+	patch := "--- a/f.go\n+++ b/f.go\n@@ -1,3 +1,3 @@\n func f() {\n-\treturn 1\n+    return 1\n }\n"
+	removed, added := PatchLines(patch)
+	if !reflect.DeepEqual(removed, []string{"\treturn 1"}) || !reflect.DeepEqual(added, []string{"    return 1"}) {
+		t.Errorf("PatchLines() = %q, %q", removed, added)
+	}
+}
+
+func TestStylePreferences(t *testing.T) {
+	lm := setupLearning(t)
+	tabs := map[string]string{"indent": "tabs"}
+
+	lm.SetProject("/src/app")
+	lm.LearnStyle(tabs)
+	prefs, err := lm.StylePreferences(5)
+	if err != nil || len(prefs) != 0 {
+		t.Fatalf("StylePreferences() after one undo = %v, %v; want none", prefs, err)
+	}
+
+	lm.LearnStyle(tabs)
+	lm.NoteStyle("Prefer table-driven tests")
+	lm.SetProject("/src/other")
+	lm.NoteStyle("Use testify")
+	lm.SetProject("/src/app")
+
+	prefs, err = lm.StylePreferences(5)
+	if err != nil || len(prefs) != 2 {
+		t.Fatalf("StylePreferences() = %v, %v; want 2", prefs, err)
+	}
+	prompt := StylePrompt(prefs)
+	for _, want := range []string{"Indent with tabs", "Prefer table-driven tests"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("StylePrompt() = %q, missing %q", prompt, want)
+		}
+	}
+	if strings.Contains(prompt, "testify") {
+		t.Errorf("StylePrompt() = %q includes another project's preference", prompt)
+	}
+
+	// Undoing the opposite change starts over
+	lm.LearnStyle(map[string]string{"indent": "spaces"})
+	prefs, _ = lm.StylePreferences(5)
+	if len(prefs) != 1 {
+		t.Errorf("StylePreferences() after a switch = %v, want only the note", prefs)
+	}
+}
+
+func TestScopePrecedence(t *testing.T) {
+	lm := setupLearning(t)
+	lm.SetProject("/src/app")
+
+	lm.engine.SetConfig("learning_scope", "global")
+	lm.LearnStyle(map[string]string{"indent": "spaces"})
+	lm.LearnStyle(map[string]string{"indent": "spaces"})
+	lm.engine.SetConfig("learning_scope", "project")
+	lm.LearnStyle(map[string]string{"indent": "tabs"})
+	lm.LearnStyle(map[string]string{"indent": "tabs"})
+
+	tests := []struct {
+		precedence string
+		want       string
+	}{
+		{"project", "tabs"},
+		{"global", "spaces"},
+		{"project_only", "tabs"},
+	}
+	for _, tt := range tests {
+		lm.engine.SetConfig("learning_precedence", tt.precedence)
+		prefs, err := lm.StylePreferences(5)
+		if err != nil || len(prefs) != 1 || prefs[0].Value != tt.want {
+			t.Errorf("precedence %s: StylePreferences() = %v, %v; want indent %s", tt.precedence, prefs, err, tt.want)
+		}
+	}
+
+	// A project that never learned anything only sees global learning
+	lm.SetProject("/src/python")
+	lm.engine.SetConfig("learning_precedence", "project_only")
+	if prefs, _ := lm.StylePreferences(5); len(prefs) != 0 {
+		t.Errorf("project_only in a new project = %v, want none", prefs)
+	}
+	lm.engine.SetConfig("learning_precedence", "project")
+	if prefs, _ := lm.StylePreferences(5); len(prefs) != 1 || prefs[0].Value != "spaces" {
+		t.Errorf("new project = %v, want the global spaces", prefs)
+	}
+}
+
+func TestScopedSuggestion(t *testing.T) {
+	lm := setupLearning(t)
+	lm.SetProject("/src/app")
+	for i := 0; i < 3; i++ {
+		lm.RecordSuccess("run the checks", "/test")
+	}
+	if intent, _, err := lm.GetSuggestion("run the checks"); err != nil || intent != "/test" {
+		t.Fatalf("GetSuggestion() in the project = %q, %v", intent, err)
+	}
+
+	lm.SetProject("/src/python")
+	if _, _, err := lm.GetSuggestion("run the checks"); err == nil {
+		t.Error("a project-scoped intent leaked into another project")
+	}
+}
+
+func TestGlobalLearningDB(t *testing.T) {
+	global := filepath.Join(t.TempDir(), "learning.db")
+	project := func(root string) *LearningModule {
+		lm := setupLearning(t)
+		if err := lm.OpenGlobal(global); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { lm.Close() })
+		lm.SetProject(root)
+		return lm
+	}
+
+	app := project("/src/app")
+	app.engine.SetConfig("learning_scope", "global")
+	for i := 0; i < 3; i++ {
+		app.RecordSuccess("run the checks", "/test")
+	}
+	app.LearnStyle(map[string]string{"indent": "tabs"})
+	app.LearnStyle(map[string]string{"indent": "tabs"})
+	app.engine.SetConfig("learning_scope", "project")
+	for i := 0; i < 3; i++ {
+		app.RecordSuccess("show me", "/diff")
+	}
+
+	// Another project has its own database but shares the user's
+	other := project("/src/other")
+	if intent, _, err := other.GetSuggestion("run the checks"); err != nil || intent != "/test" {
+		t.Errorf("GetSuggestion() of global learning = %q, %v; want /test", intent, err)
+	}
+	if prefs, _ := other.StylePreferences(5); len(prefs) != 1 || prefs[0].Value != "tabs" {
+		t.Errorf("StylePreferences() of global learning = %v, want indent tabs", prefs)
+	}
+	if _, _, err := other.GetSuggestion("show me"); err == nil {
+		t.Error("project learning leaked into another project's database")
+	}
+
+	data, err := other.Export()
+	if err != nil || len(data.LearnedIntents) != 1 {
+		t.Errorf("Export() = %v, %v; want the global intent", data, err)
+	}
+}
+
+func TestUserScope(t *testing.T) {
+	lm := setupLearning(t)
+	lm.SetProject("/src/app")
+	lm.LearnStyle(map[string]string{"indent": "spaces"})
+	lm.LearnStyle(map[string]string{"indent": "spaces"})
+
+	lm.SetUser("alice")
+	lm.LearnStyle(map[string]string{"indent": "tabs"})
+	lm.LearnStyle(map[string]string{"indent": "tabs"})
+	if prefs, _ := lm.StylePreferences(5); len(prefs) != 1 || prefs[0].Value != "tabs" {
+		t.Errorf("alice's StylePreferences() = %v, want her tabs first", prefs)
+	}
+	for i := 0; i < 3; i++ {
+		lm.RecordSuccess("ship it", "/commit")
+	}
+
+	lm.SetUser("bob")
+	if prefs, _ := lm.StylePreferences(5); len(prefs) != 1 || prefs[0].Value != "spaces" {
+		t.Errorf("bob's StylePreferences() = %v, want the project's spaces", prefs)
+	}
+	if _, _, err := lm.GetSuggestion("ship it"); err == nil {
+		t.Error("alice's learning leaked to bob")
+	}
+
+	stats, _ := lm.Stats(10, 7)
+	labels := make(map[string]bool)
+	for _, p := range stats.TopPrefs {
+		scope, _ := SplitScopedKey(p.Key)
+		labels[lm.ScopeLabel(scope)] = true
+	}
+	if !labels["alice"] || !labels["project"] {
+		t.Errorf("Expected preferences of alice and the project, got %v", labels)
+	}
+}
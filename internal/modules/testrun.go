@@ -0,0 +1,175 @@
+// Package modules - Running test_cases through the chat pipeline and
+// recording the outcome as debug traces
+package modules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hazyhaar/GoClode/internal/diff"
+)
+
+// TestCase is a stored input with the intent and output it should produce
+type TestCase struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Description    string   `json:"description,omitempty"`
+	Input          string   `json:"input"`
+	ExpectedIntent string   `json:"expected_intent,omitempty"`
+	ExpectedOutput string   `json:"expected_output,omitempty"` // Must appear in the reply
+	Tags           []string `json:"tags,omitempty"`
+	LastResult     string   `json:"last_result,omitempty"`
+}
+
+// TestPipeline is the part of the chat a test case goes through
+type TestPipeline struct {
+	Intent  func(input string) string                               // The intent detected for an input
+	Respond func(ctx context.Context, input string) (string, error) // The model's reply; nil skips expected_output
+}
+
+// TestResult is the outcome of one test case
+type TestResult struct {
+	Case     TestCase
+	TraceID  string
+	Intent   string
+	Output   string
+	Passed   bool
+	Skipped  []string // Checks that could not run
+	Failures []string // What went wrong, with a diff for outputs
+	Duration time.Duration
+}
+
+// AddTestCase stores a test case and returns its ID
+func (dm *DebugModule) AddTestCase(tc TestCase) (string, error) {
+	if tc.Name == "" || tc.Input == "" {
+		return "", fmt.Errorf("a test case needs a name and an input")
+	}
+	if tc.ExpectedIntent == "" && tc.ExpectedOutput == "" {
+		return "", fmt.Errorf("test case %s expects nothing: give an intent or an output", tc.Name)
+	}
+	if tc.ID == "" {
+		tc.ID = uuid.New().String()
+	}
+	tags, _ := json.Marshal(tc.Tags)
+	_, err := dm.engine.Exec(`
+		INSERT INTO test_cases (id, name, description, input, expected_output, expected_intent, tags)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)
+	`, tc.ID, tc.Name, tc.Description, tc.Input, tc.ExpectedOutput, tc.ExpectedIntent, string(tags))
+	if err != nil {
+		return "", fmt.Errorf("add test case: %w", err)
+	}
+	return tc.ID, nil
+}
+
+// TestCases returns the enabled test cases, by name, optionally only those
+// with a tag or a name in names
+func (dm *DebugModule) TestCases(tag string, names []string) ([]TestCase, error) {
+	rows, err := dm.engine.Query(`
+		SELECT id, name, COALESCE(description, ''), input, COALESCE(expected_intent, ''),
+			COALESCE(expected_output, ''), COALESCE(tags, '[]'), COALESCE(last_result, '')
+		FROM test_cases WHERE enabled = 1 ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list test cases: %w", err)
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]bool, len(names))
+	for _, n := range names {
+		wanted[n] = true
+	}
+	cases := make([]TestCase, 0)
+	for rows.Next() {
+		var tc TestCase
+		var tags string
+		if err := rows.Scan(&tc.ID, &tc.Name, &tc.Description, &tc.Input, &tc.ExpectedIntent,
+			&tc.ExpectedOutput, &tags, &tc.LastResult); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(tags), &tc.Tags)
+		if len(wanted) > 0 && !wanted[tc.Name] && !wanted[tc.ID] {
+			continue
+		}
+		if tag != "" && !containsTag(tc.Tags, tag) {
+			continue
+		}
+		cases = append(cases, tc)
+	}
+	return cases, rows.Err()
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// RunTestCase feeds a test case's input through the pipeline, compares
+// the intent and the reply with what it expects, and records the checks as
+// assertions of a test_case trace
+func (dm *DebugModule) RunTestCase(ctx context.Context, tc TestCase, p TestPipeline) *TestResult {
+	start := time.Now()
+	result := &TestResult{Case: tc, TraceID: dm.StartTrace("test_case", "debug")}
+
+	if tc.ExpectedIntent != "" {
+		result.Intent = p.Intent(tc.Input)
+		if !dm.AddAssertion(result.TraceID, "intent", tc.ExpectedIntent, result.Intent) {
+			result.Failures = append(result.Failures,
+				fmt.Sprintf("intent: expected %s, got %s", tc.ExpectedIntent, result.Intent))
+		}
+	}
+
+	var runErr error
+	switch {
+	case tc.ExpectedOutput == "":
+	case p.Respond == nil:
+		result.Skipped = append(result.Skipped, "output (no provider)")
+	default:
+		result.Output, runErr = p.Respond(ctx, tc.Input)
+		if runErr != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("output: %v", runErr))
+			break
+		}
+		passed := strings.Contains(result.Output, tc.ExpectedOutput)
+		message := "OK"
+		if !passed {
+			message = "expected output not found in the reply:\n" +
+				diff.Unified("output", tc.ExpectedOutput+"\n", result.Output+"\n")
+			result.Failures = append(result.Failures, "output: "+message)
+		}
+		dm.recordAssertion(result.TraceID, "output", tc.ExpectedOutput, result.Output, passed, message)
+	}
+
+	result.Passed = len(result.Failures) == 0
+	result.Duration = time.Since(start)
+
+	lastResult := "pass"
+	var traceErr error
+	if !result.Passed {
+		lastResult = "fail: " + strings.SplitN(result.Failures[0], "\n", 2)[0]
+		traceErr = fmt.Errorf("%d checks failed", len(result.Failures))
+	}
+	if runErr != nil {
+		traceErr = runErr
+	}
+	dm.engine.Exec(`
+		UPDATE test_cases SET last_run_at = strftime('%s', 'now'), last_result = ? WHERE id = ?
+	`, lastResult, tc.ID)
+	dm.EndTrace(result.TraceID, traceErr)
+	return result
+}
+
+// recordAssertion stores a check whose outcome the caller decided
+func (dm *DebugModule) recordAssertion(traceID, name, expected, actual string, passed bool, message string) {
+	dm.engine.Exec(`
+		INSERT INTO debug_assertions (id, trace_id, name, expected, actual, passed, message)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), traceID, name, expected, actual, passed, message)
+}
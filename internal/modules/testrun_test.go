@@ -0,0 +1,55 @@
+package modules
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunTestCase(t *testing.T) {
+	lm := setupLearning(t)
+	dm := NewDebugModule(lm.engine, lm.mm)
+
+	if _, err := dm.AddTestCase(TestCase{Name: "empty", Input: "hi"}); err == nil {
+		t.Error("expected an error for a test case that expects nothing")
+	}
+	for _, tc := range []TestCase{
+		{Name: "undo", Input: "annule", ExpectedIntent: "undo", Tags: []string{"intents"}},
+		{Name: "readme", Input: "create a README", ExpectedIntent: "code", ExpectedOutput: "**File: README.md**"},
+	} {
+		if _, err := dm.AddTestCase(tc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cases, err := dm.TestCases("intents", nil); err != nil || len(cases) != 1 || cases[0].Name != "undo" {
+		t.Fatalf("TestCases(intents) = %+v, %v", cases, err)
+	}
+	cases, err := dm.TestCases("", []string{"readme"})
+	if err != nil || len(cases) != 1 {
+		t.Fatalf("TestCases(readme) = %+v, %v", cases, err)
+	}
+
+	pipeline := TestPipeline{
+		Intent: func(string) string { return "code" },
+		Respond: func(context.Context, string) (string, error) {
+			return "Here it is:\n**File: README**", nil
+		},
+	}
+	r := dm.RunTestCase(context.Background(), cases[0], pipeline)
+	if r.Passed || len(r.Failures) != 1 || !strings.Contains(r.Failures[0], "+**File: README**") {
+		t.Fatalf("RunTestCase() = %+v, want an output failure with a diff", r)
+	}
+	detail, err := dm.Trace(r.TraceID)
+	if err != nil || detail.Status != "error" || len(detail.Assertions) != 2 || detail.Failed != 1 {
+		t.Errorf("trace = %+v, %v; want an intent and a failed output assertion", detail, err)
+	}
+
+	// Without a provider only the intent is checked
+	pipeline.Respond = nil
+	if r := dm.RunTestCase(context.Background(), cases[0], pipeline); !r.Passed || len(r.Skipped) != 1 {
+		t.Errorf("RunTestCase() without a provider = %+v, want a pass with the output skipped", r)
+	}
+	if cases, _ := dm.TestCases("", []string{"readme"}); cases[0].LastResult != "pass" {
+		t.Errorf("last_result = %q, want pass", cases[0].LastResult)
+	}
+}
@@ -0,0 +1,219 @@
+// Package modules - Recording and reading the debug traces of emitted events
+package modules
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+// Trace is one event emitted in debug mode
+type Trace struct {
+	ID       string
+	Event    string
+	Module   string // Modules whose hooks ran, comma-separated
+	Status   string // running, success, or error
+	Error    string
+	Start    time.Time
+	Duration time.Duration
+	Events   int
+	Failed   int // Failed assertions
+}
+
+// TraceEvent is something that happened within a trace
+type TraceEvent struct {
+	Time     time.Time
+	Level    string
+	Module   string
+	Message  string
+	Data     string // JSON
+	Duration time.Duration
+}
+
+// TraceDetail is a trace with its events and assertions
+type TraceDetail struct {
+	Trace
+	Events     []TraceEvent
+	Assertions []core.DebugAssertion
+}
+
+// RecordTrace stores an emitted event with its assertions and the events
+// debug_events does not have yet, unless log_to_db is off, and keeps the
+// newest max_log_size traces
+func (dm *DebugModule) RecordTrace(event, module string, debug *core.DebugContext, err error) {
+	if logToDB, ok := dm.mm.ModuleConfig("debug", "log_to_db").(bool); ok && !logToDB {
+		return
+	}
+
+	end := time.Now()
+	status, errMsg := "success", ""
+	if err != nil {
+		status, errMsg = "error", err.Error()
+	}
+
+	tx, txErr := dm.engine.DB().Begin()
+	if txErr != nil {
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO debug_traces (trace_id, parent_id, event, module, start_time, end_time, duration_ms, status, error)
+		VALUES (?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?)
+	`, debug.TraceID, debug.ParentID, event, module, debug.StartTime.UnixMilli(), end.UnixMilli(),
+		end.Sub(debug.StartTime).Milliseconds(), status, errMsg); err != nil {
+		return
+	}
+	for _, e := range debug.Events {
+		data, err := json.Marshal(e.Data)
+		if err != nil || e.Data == nil {
+			data = []byte("{}")
+		}
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO debug_events (id, trace_id, event, level, module, message, data, duration_ms, timestamp)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, e.ID, debug.TraceID, event, e.Level, e.Module, e.Message, string(data), e.Duration.Milliseconds(), e.Timestamp.UnixMilli()); err != nil {
+			return
+		}
+	}
+	for _, a := range debug.Assertions {
+		message := a.Message
+		if message == "" && a.Passed {
+			message = "OK"
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO debug_assertions (id, trace_id, name, expected, actual, passed, message)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, a.ID, debug.TraceID, a.Name, a.Expected, a.Actual, a.Passed, message); err != nil {
+			return
+		}
+	}
+
+	if maxTraces, ok := dm.mm.ModuleConfig("debug", "max_log_size").(float64); ok && maxTraces > 0 {
+		const pruned = `SELECT trace_id FROM debug_traces WHERE trace_id NOT IN (
+			SELECT trace_id FROM debug_traces ORDER BY start_time DESC, rowid DESC LIMIT ?)`
+		tx.Exec(`DELETE FROM debug_events WHERE trace_id IN (`+pruned+`)`, int(maxTraces))
+		tx.Exec(`DELETE FROM debug_traces WHERE trace_id IN (`+pruned+`)`, int(maxTraces))
+	}
+	tx.Commit()
+}
+
+// traceColumns selects a Trace, with t the debug_traces alias
+const traceColumns = `
+	t.trace_id, t.event, COALESCE(t.module, ''), t.status, COALESCE(t.error, ''),
+	COALESCE(t.start_time, 0), COALESCE(t.duration_ms, 0),
+	(SELECT COUNT(*) FROM debug_events e WHERE e.trace_id = t.trace_id),
+	(SELECT COUNT(*) FROM debug_assertions a WHERE a.trace_id = t.trace_id AND a.passed = 0)`
+
+func scanTrace(row interface{ Scan(...interface{}) error }) (Trace, error) {
+	var t Trace
+	var start, durationMs int64
+	err := row.Scan(&t.ID, &t.Event, &t.Module, &t.Status, &t.Error, &start, &durationMs, &t.Events, &t.Failed)
+	t.Start = time.UnixMilli(start)
+	t.Duration = time.Duration(durationMs) * time.Millisecond
+	return t, err
+}
+
+// Traces returns the most recent traces, newest first; with failedOnly,
+// only those that ended in an error or have failed assertions
+func (dm *DebugModule) Traces(limit int, failedOnly bool) ([]Trace, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	where := ""
+	if failedOnly {
+		where = `WHERE t.status = 'error' OR EXISTS (
+			SELECT 1 FROM debug_assertions a WHERE a.trace_id = t.trace_id AND a.passed = 0)`
+	}
+	rows, err := dm.engine.Query(`
+		SELECT `+traceColumns+` FROM debug_traces t `+where+`
+		ORDER BY t.start_time DESC, t.rowid DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list traces: %w", err)
+	}
+	defer rows.Close()
+
+	traces := make([]Trace, 0)
+	for rows.Next() {
+		t, err := scanTrace(rows)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, t)
+	}
+	return traces, rows.Err()
+}
+
+// Trace returns a trace by ID or unambiguous ID prefix, with its events
+// and assertions
+func (dm *DebugModule) Trace(idPrefix string) (*TraceDetail, error) {
+	rows, err := dm.engine.Query(`
+		SELECT `+traceColumns+` FROM debug_traces t WHERE t.trace_id LIKE ? || '%' LIMIT 2
+	`, idPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("find trace: %w", err)
+	}
+	matches := make([]Trace, 0, 2)
+	for rows.Next() {
+		t, err := scanTrace(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		matches = append(matches, t)
+	}
+	rows.Close()
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no trace %q", idPrefix)
+	case 2:
+		return nil, fmt.Errorf("trace prefix %q is ambiguous", idPrefix)
+	}
+
+	detail := &TraceDetail{Trace: matches[0], Events: make([]TraceEvent, 0), Assertions: make([]core.DebugAssertion, 0)}
+	rows, err = dm.engine.Query(`
+		SELECT COALESCE(level, ''), COALESCE(module, ''), COALESCE(message, ''), COALESCE(data, '{}'),
+			COALESCE(duration_ms, 0), COALESCE(timestamp, 0)
+		FROM debug_events WHERE trace_id = ? ORDER BY timestamp, rowid
+	`, detail.ID)
+	if err != nil {
+		return nil, fmt.Errorf("trace events: %w", err)
+	}
+	for rows.Next() {
+		var e TraceEvent
+		var durationMs, ts int64
+		if err := rows.Scan(&e.Level, &e.Module, &e.Message, &e.Data, &durationMs, &ts); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.Duration = time.Duration(durationMs) * time.Millisecond
+		e.Time = time.UnixMilli(ts)
+		detail.Events = append(detail.Events, e)
+	}
+	rows.Close()
+
+	rows, err = dm.engine.Query(`
+		SELECT id, name, COALESCE(expected, ''), COALESCE(actual, ''), COALESCE(passed, 0),
+			COALESCE(message, ''), created_at
+		FROM debug_assertions WHERE trace_id = ? ORDER BY created_at, rowid
+	`, detail.ID)
+	if err != nil {
+		return nil, fmt.Errorf("trace assertions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a core.DebugAssertion
+		var createdAt sql.NullInt64
+		if err := rows.Scan(&a.ID, &a.Name, &a.Expected, &a.Actual, &a.Passed, &a.Message, &createdAt); err != nil {
+			return nil, err
+		}
+		a.TraceID = detail.ID
+		a.Timestamp = time.Unix(createdAt.Int64, 0)
+		detail.Assertions = append(detail.Assertions, a)
+	}
+	return detail, rows.Err()
+}
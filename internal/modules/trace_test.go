@@ -0,0 +1,60 @@
+package modules
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func TestTraces(t *testing.T) {
+	lm := setupLearning(t)
+	mm := lm.mm
+	dm := NewDebugModule(lm.engine, mm)
+	mm.SetTracer(dm)
+
+	mm.RegisterHandler("fail", func(*core.HookContext) error { return fmt.Errorf("boom") })
+	mm.RegisterHook(&core.Hook{ID: "test_fail", ModuleID: "debug", Event: "broken", Handler: "fail", Priority: 50, Enabled: true})
+	mm.RegisterHook(&core.Hook{ID: "test_assert", ModuleID: "debug", Event: "checked", Handler: "test_assert", Priority: 50, Enabled: true})
+
+	// Nothing is traced outside debug mode
+	mm.Emit("quiet", nil)
+	if traces, err := dm.Traces(10, false); err != nil || len(traces) != 0 {
+		t.Fatalf("Traces() outside debug mode = %+v, %v", traces, err)
+	}
+
+	mm.EnableDebug()
+	mm.Emit("quiet", map[string]interface{}{"n": 1})
+	mm.Emit("broken", nil)
+	mm.Emit("checked", map[string]interface{}{"assertion_name": "intent", "expected": "code", "actual": "chat"})
+
+	traces, err := dm.Traces(10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 3 {
+		t.Fatalf("Traces() = %+v, want 3", traces)
+	}
+	failed, err := dm.Traces(10, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 {
+		t.Fatalf("Traces(failed) = %+v, want the error and the failed assertion", failed)
+	}
+
+	if broken := failed[1]; broken.Event != "broken" || broken.Status != "error" || broken.Error == "" || broken.Events != 3 {
+		t.Errorf("Traces(failed)[1] = %+v, want the broken event with the payload and 2 hook events", broken)
+	}
+
+	detail, err := dm.Trace(failed[0].ID[:8])
+	if err != nil {
+		t.Fatalf("Trace() failed: %v", err)
+	}
+	if detail.Event != "checked" || len(detail.Assertions) != 1 || detail.Assertions[0].Passed {
+		t.Errorf("Trace(checked) = %+v, want one failed assertion", detail)
+	}
+	if _, err := dm.Trace("zzz"); err == nil {
+		t.Error("expected an error for an unknown trace")
+	}
+}
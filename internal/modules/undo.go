@@ -0,0 +1,145 @@
+// Package modules - Undone generations: every /undo is a negative signal on
+// the reply that made the change
+package modules
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+// UndoEvent is the event /undo emits, with the reverted exchange
+const UndoEvent = "undo"
+
+// undonePatternType marks undone exchanges in learning_patterns
+const undonePatternType = "undone_generation"
+
+const (
+	undoneSimilarity = 0.5 // Word overlap for a request to count as similar
+	undoneScanLimit  = 200 // Recent undone patterns compared with a request
+	maxUndoneOutput  = 2000
+)
+
+// UndonePattern is a request whose answer the user undid
+type UndonePattern struct {
+	Prompt     string
+	Response   string
+	Failures   int
+	Similarity float64
+}
+
+// handleUndo records the exchange carried by an undo event
+func (lm *LearningModule) handleUndo(ctx *core.HookContext) error {
+	prompt, _ := ctx.Payload["prompt"].(string)
+	response, _ := ctx.Payload["response"].(string)
+	return lm.RecordUndo(prompt, response)
+}
+
+// RecordUndo records that the user undid the answer to prompt; each undo of
+// the same request counts as one more failure
+func (lm *LearningModule) RecordUndo(prompt, response string) error {
+	normalized := NormalizeInput(prompt)
+	if normalized == "" {
+		return nil
+	}
+	if r := []rune(response); len(r) > maxUndoneOutput {
+		response = string(r[:maxUndoneOutput])
+	}
+	scope := lm.writeScope()
+	sum := sha256.Sum256([]byte(scope + "\n" + normalized))
+
+	_, err := lm.store(scope).Exec(`
+		INSERT INTO learning_patterns (pattern_id, pattern_type, input_pattern, output_pattern, failure_count, last_used_at, scope)
+		VALUES (?, ?, ?, ?, 1, strftime('%s', 'now'), ?)
+		ON CONFLICT(pattern_id) DO UPDATE SET
+			output_pattern = excluded.output_pattern,
+			failure_count = failure_count + 1,
+			last_used_at = excluded.last_used_at
+	`, "undo_"+hex.EncodeToString(sum[:8]), undonePatternType, normalized, response, scope)
+	if err != nil {
+		return fmt.Errorf("record undo: %w", err)
+	}
+	return nil
+}
+
+// SimilarUndone returns up to limit undone requests resembling prompt from
+// the scopes that apply here, the closest (then most undone) first
+func (lm *LearningModule) SimilarUndone(prompt string, limit int) ([]UndonePattern, error) {
+	words := wordSet(NormalizeInput(prompt))
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	similar := make([]UndonePattern, 0)
+	for _, scope := range lm.readScopes() {
+		found, err := lm.similarUndoneIn(scope, words)
+		if err != nil {
+			return nil, err
+		}
+		similar = append(similar, found...)
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		if similar[i].Similarity != similar[j].Similarity {
+			return similar[i].Similarity > similar[j].Similarity
+		}
+		return similar[i].Failures > similar[j].Failures
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
+
+// similarUndoneIn returns the recent undone requests of a scope sharing
+// enough of words
+func (lm *LearningModule) similarUndoneIn(scope string, words map[string]bool) ([]UndonePattern, error) {
+	rows, err := lm.store(scope).Query(`
+		SELECT input_pattern, COALESCE(output_pattern, ''), failure_count FROM learning_patterns
+		WHERE pattern_type = ? AND scope = ? ORDER BY last_used_at DESC LIMIT ?
+	`, undonePatternType, scope, undoneScanLimit)
+	if err != nil {
+		return nil, fmt.Errorf("list undone patterns: %w", err)
+	}
+	defer rows.Close()
+
+	similar := make([]UndonePattern, 0)
+	for rows.Next() {
+		var p UndonePattern
+		if err := rows.Scan(&p.Prompt, &p.Response, &p.Failures); err != nil {
+			return nil, err
+		}
+		if p.Similarity = jaccard(words, wordSet(p.Prompt)); p.Similarity >= undoneSimilarity {
+			similar = append(similar, p)
+		}
+	}
+	return similar, rows.Err()
+}
+
+// wordSet returns the distinct words of s
+func wordSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		set[strings.Trim(w, ".,;:!?\"'()")] = true
+	}
+	delete(set, "")
+	return set
+}
+
+// jaccard is the share of words two sets have in common
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
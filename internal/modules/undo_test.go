@@ -0,0 +1,43 @@
+package modules
+
+import "testing"
+
+func TestUndoPatterns(t *testing.T) {
+	lm := setupLearning(t)
+
+	// Undo events reach the learning module through its hook
+	if err := lm.mm.Emit(UndoEvent, map[string]interface{}{
+		"prompt":   "Add retries to the HTTP client",
+		"response": "I rewrote client.go with a retry loop",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	lm.RecordUndo("add retries to the http client", "Another rewrite")
+	lm.RecordUndo("rename the config package", "Renamed to settings")
+
+	similar, err := lm.SimilarUndone("add retries to the client", 5)
+	if err != nil {
+		t.Fatalf("SimilarUndone failed: %v", err)
+	}
+	if len(similar) != 1 {
+		t.Fatalf("SimilarUndone() = %+v, want the retries request only", similar)
+	}
+	if similar[0].Failures != 2 || similar[0].Response != "Another rewrite" {
+		t.Errorf("SimilarUndone()[0] = %+v, want 2 failures and the latest answer", similar[0])
+	}
+
+	if similar, _ := lm.SimilarUndone("write a README", 5); len(similar) != 0 {
+		t.Errorf("SimilarUndone(unrelated) = %+v", similar)
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	a := wordSet("add retries to the client")
+	b := wordSet("add retries to the http client")
+	if got := jaccard(a, b); got != 5.0/6 {
+		t.Errorf("jaccard() = %v, want %v", got, 5.0/6)
+	}
+	if got := jaccard(a, wordSet("")); got != 0 {
+		t.Errorf("jaccard(empty) = %v", got)
+	}
+}
@@ -0,0 +1,147 @@
+// Package prompt renders the templates of the prompts table, a subset of
+// mustache: {{name}} is a variable, {{#name}}...{{/name}} is kept when name
+// is set and {{^name}}...{{/name}} when it is not. Text is not escaped.
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tagPattern matches a tag: {{name}}, {{#name}}, {{^name}}, or {{/name}}
+var tagPattern = regexp.MustCompile(`\{\{\s*([#^/]?)\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// node is text, a variable, or a section with its children
+type node struct {
+	text     string
+	variable string
+	section  string
+	inverted bool
+	children []node
+}
+
+// Template is a parsed template
+type Template struct {
+	nodes []node
+}
+
+// MissingError lists the variables a render needed but was not given
+type MissingError struct {
+	Names []string
+}
+
+func (e *MissingError) Error() string {
+	return "missing variables: " + strings.Join(e.Names, ", ")
+}
+
+// Parse reads a template, checking that its sections are closed in order
+func Parse(text string) (*Template, error) {
+	type frame struct {
+		node  node
+		nodes []node
+	}
+	stack := []frame{{}}
+	last := 0
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(text, -1) {
+		top := &stack[len(stack)-1]
+		if m[0] > last {
+			top.nodes = append(top.nodes, node{text: text[last:m[0]]})
+		}
+		last = m[1]
+		kind, name := text[m[2]:m[3]], text[m[4]:m[5]]
+
+		switch kind {
+		case "":
+			top.nodes = append(top.nodes, node{variable: name})
+		case "#", "^":
+			stack = append(stack, frame{node: node{section: name, inverted: kind == "^"}})
+		case "/":
+			if len(stack) == 1 || top.node.section != name {
+				return nil, fmt.Errorf("template: {{/%s}} closes no open section", name)
+			}
+			closed := top.node
+			closed.children = top.nodes
+			stack = stack[:len(stack)-1]
+			parent := &stack[len(stack)-1]
+			parent.nodes = append(parent.nodes, closed)
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("template: {{#%s}} is not closed", stack[len(stack)-1].node.section)
+	}
+	if last < len(text) {
+		stack[0].nodes = append(stack[0].nodes, node{text: text[last:]})
+	}
+	return &Template{nodes: stack[0].nodes}, nil
+}
+
+// Variables returns the names the template uses, variables and sections,
+// in the order they first appear
+func (t *Template) Variables() []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	var walk func([]node)
+	walk = func(nodes []node) {
+		for _, n := range nodes {
+			name := n.variable
+			if name == "" {
+				name = n.section
+			}
+			if name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+			walk(n.children)
+		}
+	}
+	walk(t.nodes)
+	return names
+}
+
+// Render fills the template. A variable rendered without a value is an
+// error (a *MissingError); an unset section is just left out.
+func (t *Template) Render(vars map[string]string) (string, error) {
+	var sb strings.Builder
+	missing := make(map[string]bool)
+	var render func([]node)
+	render = func(nodes []node) {
+		for _, n := range nodes {
+			switch {
+			case n.variable != "":
+				value, ok := vars[n.variable]
+				if !ok {
+					missing[n.variable] = true
+				}
+				sb.WriteString(value)
+			case n.section != "":
+				if (strings.TrimSpace(vars[n.section]) != "") != n.inverted {
+					render(n.children)
+				}
+			default:
+				sb.WriteString(n.text)
+			}
+		}
+	}
+	render(t.nodes)
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", &MissingError{Names: names}
+	}
+	return sb.String(), nil
+}
+
+// Render parses and fills a template
+func Render(text string, vars map[string]string) (string, error) {
+	t, err := Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(vars)
+}
@@ -0,0 +1,42 @@
+package prompt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	text := "Review {{file}}:\n{{code}}{{#focus}}\nFocus on {{ focus }}.{{/focus}}{{^focus}}\nCheck everything.{{/focus}}"
+
+	got, err := Render(text, map[string]string{"file": "a.go", "code": "x := 1", "focus": "errors"})
+	if err != nil || got != "Review a.go:\nx := 1\nFocus on errors." {
+		t.Errorf("Unexpected render %q (%v)", got, err)
+	}
+	got, err = Render(text, map[string]string{"file": "a.go", "code": "x := 1"})
+	if err != nil || got != "Review a.go:\nx := 1\nCheck everything." {
+		t.Errorf("Unexpected render without the section %q (%v)", got, err)
+	}
+
+	_, err = Render(text, map[string]string{"focus": "errors"})
+	var missing *MissingError
+	if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Names, []string{"code", "file"}) {
+		t.Errorf("Expected code and file to be missing, got %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tpl, err := Parse("{{a}} {{#b}}{{c}} {{a}}{{/b}} {{ not a tag }}")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := tpl.Variables(); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("Variables = %v", got)
+	}
+
+	for _, bad := range []string{"{{#a}}open", "{{/a}}", "{{#a}}{{#b}}{{/a}}{{/b}}"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Expected %q to fail", bad)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package providers - Benchmarking providers: latency, throughput, cost,
+// and correctness on a prompt suite
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BenchPrompt is one prompt of a benchmark suite
+type BenchPrompt struct {
+	Name   string `json:"name"`
+	System string `json:"system,omitempty"`
+	Prompt string `json:"prompt"`
+	Expect string `json:"expect,omitempty"` // Golden output: text the reply must contain
+}
+
+// Pricing is what a provider charges, in USD per million tokens; it is
+// read from the provider's config ({"price_in": 0.6, "price_out": 1.2})
+type Pricing struct {
+	In  float64 `json:"price_in"`
+	Out float64 `json:"price_out"`
+}
+
+// Cost is the price of a request
+func (p Pricing) Cost(tokensIn, tokensOut int) float64 {
+	return (float64(tokensIn)*p.In + float64(tokensOut)*p.Out) / 1e6
+}
+
+// BenchResult is how one model did on one prompt
+type BenchResult struct {
+	Provider     string
+	Model        string
+	Prompt       string
+	FirstToken   time.Duration // Until the first streamed text
+	Total        time.Duration
+	TokensIn     int
+	TokensOut    int
+	Estimated    bool // Token counts estimated from the text: the provider sent none
+	TokensPerSec float64
+	Cost         float64
+	Correct      *bool // nil without a golden output
+	Err          error
+}
+
+// estimateTokens guesses a token count at about four characters a token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// Bench streams one prompt from a model and measures the reply
+func Bench(ctx context.Context, p Provider, model string, prompt BenchPrompt, price Pricing) BenchResult {
+	result := BenchResult{Provider: p.ID(), Model: model, Prompt: prompt.Name}
+	messages := make([]Message, 0, 2)
+	if prompt.System != "" {
+		messages = append(messages, Message{Role: "system", Content: prompt.System})
+	}
+	messages = append(messages, Message{Role: "user", Content: prompt.Prompt})
+
+	start := time.Now()
+	stream, err := p.Stream(ctx, &Request{Model: model, Messages: messages, Temperature: 0.2, Stream: true})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	var content strings.Builder
+	for chunk := range stream {
+		if chunk.Error != nil {
+			result.Err = chunk.Error
+			break
+		}
+		if chunk.Delta != "" && result.FirstToken == 0 {
+			result.FirstToken = time.Since(start)
+		}
+		content.WriteString(chunk.Delta)
+		if chunk.Done {
+			result.TokensIn, result.TokensOut = chunk.TokensIn, chunk.TokensOut
+		}
+	}
+	result.Total = time.Since(start)
+	if result.Err != nil {
+		return result
+	}
+
+	if result.TokensOut == 0 {
+		result.Estimated = true
+		result.TokensOut = estimateTokens(content.String())
+		for _, m := range messages {
+			result.TokensIn += estimateTokens(m.Content)
+		}
+	}
+	if generating := result.Total - result.FirstToken; generating > 0 {
+		result.TokensPerSec = float64(result.TokensOut) / generating.Seconds()
+	}
+	result.Cost = price.Cost(result.TokensIn, result.TokensOut)
+	if prompt.Expect != "" {
+		correct := strings.Contains(strings.ToLower(content.String()), strings.ToLower(prompt.Expect))
+		result.Correct = &correct
+	}
+	if content.Len() == 0 {
+		result.Err = fmt.Errorf("empty reply")
+	}
+	return result
+}
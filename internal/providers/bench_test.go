@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestBench(t *testing.T) {
+	p := NewReplayProvider(map[string]string{"What is 17 * 23?": "It is 391."})
+	price := Pricing{In: 1, Out: 2}
+
+	r := Bench(context.Background(), p, "replay", BenchPrompt{Name: "math", Prompt: "What is 17 * 23?", Expect: "391"}, price)
+	if r.Err != nil {
+		t.Fatalf("Bench: %v", r.Err)
+	}
+	if r.Provider != "replay" || r.Model != "replay" || r.Prompt != "math" {
+		t.Errorf("Unexpected labels: %+v", r)
+	}
+	if !r.Estimated || r.TokensOut != estimateTokens("It is 391.") || r.TokensIn != estimateTokens("What is 17 * 23?") {
+		t.Errorf("Expected estimated tokens, got in=%d out=%d estimated=%v", r.TokensIn, r.TokensOut, r.Estimated)
+	}
+	if want := price.Cost(r.TokensIn, r.TokensOut); r.Cost != want || want == 0 {
+		t.Errorf("Expected cost %f, got %f", want, r.Cost)
+	}
+	if r.Correct == nil || !*r.Correct {
+		t.Errorf("Expected a correct reply, got %v", r.Correct)
+	}
+
+	r = Bench(context.Background(), p, "replay", BenchPrompt{Name: "math", Prompt: "What is 17 * 23?", Expect: "392"}, price)
+	if r.Correct == nil || *r.Correct {
+		t.Errorf("Expected a wrong reply, got %v", r.Correct)
+	}
+
+	r = Bench(context.Background(), p, "replay", BenchPrompt{Name: "open", Prompt: "unknown"}, price)
+	if r.Err == nil {
+		t.Error("Expected an error for an empty reply")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if r = Bench(ctx, p, "replay", BenchPrompt{Prompt: "What is 17 * 23?"}, price); r.Err == nil {
+		t.Error("Expected an error for a cancelled context")
+	}
+}
+
+func TestRegistryPricing(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE providers (provider_id TEXT PRIMARY KEY, name TEXT, base_url TEXT, api_key_env TEXT,
+		default_model TEXT, enabled INTEGER, priority INTEGER, rate_limit_rpm INTEGER, config TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec(`INSERT INTO providers VALUES ('priced', 'Priced', '', '', 'm', 1, 1, NULL, '{"price_in": 0.5, "price_out": 1.5}')`)
+	db.Exec(`INSERT INTO providers VALUES ('free', 'Free', '', '', 'm', 1, 2, NULL, '{}')`)
+	r := NewRegistry(db)
+
+	price, err := r.Pricing("priced")
+	if err != nil || price.In != 0.5 || price.Out != 1.5 {
+		t.Errorf("Expected {0.5 1.5}, got %+v (%v)", price, err)
+	}
+	if price, err = r.Pricing("free"); err != nil || price != (Pricing{}) {
+		t.Errorf("Expected no price, got %+v (%v)", price, err)
+	}
+	if _, err = r.Pricing("missing"); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+}
@@ -71,6 +71,7 @@ type cerebrasRequest struct {
 	Temperature      float64   `json:"temperature,omitempty"`
 	MaxTokens        int       `json:"max_tokens,omitempty"`
 	Stream           bool      `json:"stream"`
+	Tools            []Tool    `json:"tools,omitempty"`
 	DisableReasoning *bool     `json:"disable_reasoning,omitempty"` // zai-glm-4.6: false=reasoning enabled
 }
 
@@ -83,9 +84,10 @@ type cerebrasResponse struct {
 	Choices []struct {
 		Index   int `json:"index"`
 		Message struct {
-			Role      string `json:"role"`
-			Content   string `json:"content"`
-			Reasoning string `json:"reasoning"` // zai-glm-4.6 uses reasoning field
+			Role      string     `json:"role"`
+			Content   string     `json:"content"`
+			Reasoning string     `json:"reasoning"` // zai-glm-4.6 uses reasoning field
+			ToolCalls []ToolCall `json:"tool_calls"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -108,6 +110,12 @@ type cerebrasStreamChunk struct {
 			Role      string `json:"role,omitempty"`
 			Content   string `json:"content,omitempty"`
 			Reasoning string `json:"reasoning,omitempty"` // zai-glm-4.6 uses reasoning
+			ToolCalls []struct {
+				Index    int              `json:"index"`
+				ID       string           `json:"id,omitempty"`
+				Type     string           `json:"type,omitempty"`
+				Function ToolCallFunction `json:"function"`
+			} `json:"tool_calls,omitempty"`
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason,omitempty"`
 	} `json:"choices"`
@@ -135,10 +143,11 @@ func (p *CerebrasProvider) Generate(ctx context.Context, req *Request) (*Respons
 
 	cereq := &cerebrasRequest{
 		Model:       model,
-		Messages:    req.Messages,
+		Messages:    redactMessages(p.config.ID, req.Messages),
 		Temperature: temp,
 		MaxTokens:   req.MaxTokens,
 		Stream:      false,
+		Tools:       req.Tools,
 	}
 
 	start := time.Now()
@@ -154,6 +163,7 @@ func (p *CerebrasProvider) Generate(ctx context.Context, req *Request) (*Respons
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	recordRequest(p.config.ID, httpReq, body)
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -171,23 +181,29 @@ func (p *CerebrasProvider) Generate(ctx context.Context, req *Request) (*Respons
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
-	content := ""
+	content, finishReason := "", ""
+	var toolCalls []ToolCall
 	if len(ceres.Choices) > 0 {
+		finishReason = ceres.Choices[0].FinishReason
 		// zai-glm-4.6 uses reasoning field, others use content
 		content = ceres.Choices[0].Message.Content
 		if content == "" {
 			content = ceres.Choices[0].Message.Reasoning
 		}
+		toolCalls = ceres.Choices[0].Message.ToolCalls
 	}
+	content, toolCalls = restoreReply(content, toolCalls)
 
 	return &Response{
-		ID:        ceres.ID,
-		Model:     ceres.Model,
-		Content:   content,
-		TokensIn:  ceres.Usage.PromptTokens,
-		TokensOut: ceres.Usage.CompletionTokens,
-		Latency:   time.Since(start).Milliseconds(),
-		Raw:       ceres,
+		ID:           ceres.ID,
+		Model:        ceres.Model,
+		Content:      content,
+		TokensIn:     ceres.Usage.PromptTokens,
+		TokensOut:    ceres.Usage.CompletionTokens,
+		Latency:      time.Since(start).Milliseconds(),
+		ToolCalls:    toolCalls,
+		FinishReason: finishReason,
+		Raw:          ceres,
 	}, nil
 }
 
@@ -209,10 +225,11 @@ func (p *CerebrasProvider) Stream(ctx context.Context, req *Request) (<-chan Str
 
 	cereq := &cerebrasRequest{
 		Model:       model,
-		Messages:    req.Messages,
+		Messages:    redactMessages(p.config.ID, req.Messages),
 		Temperature: temp,
 		MaxTokens:   req.MaxTokens,
 		Stream:      true,
+		Tools:       req.Tools,
 	}
 
 	body, err := json.Marshal(cereq)
@@ -228,6 +245,7 @@ func (p *CerebrasProvider) Stream(ctx context.Context, req *Request) (<-chan Str
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
 	httpReq.Header.Set("Accept", "text/event-stream")
+	recordRequest(p.config.ID, httpReq, body)
 
 	resp, err := p.client.Do(httpReq)
 	if err != nil {
@@ -252,6 +270,9 @@ func (p *CerebrasProvider) Stream(ctx context.Context, req *Request) (<-chan Str
 		scanner.Buffer(buf, 1024*1024)
 
 		var tokensIn, tokensOut int
+		var finishReason string
+		var toolCalls []ToolCall // Assembled from indexed deltas
+		restorer := &streamRestorer{r: currentRedactor()}
 
 		for scanner.Scan() {
 			select {
@@ -272,7 +293,11 @@ func (p *CerebrasProvider) Stream(ctx context.Context, req *Request) (<-chan Str
 
 			// End of stream
 			if data == "[DONE]" {
-				ch <- StreamChunk{Done: true, TokensIn: tokensIn, TokensOut: tokensOut}
+				if rest := restorer.flush(); rest != "" {
+					ch <- StreamChunk{Delta: rest}
+				}
+				_, toolCalls = restoreReply("", toolCalls)
+				ch <- StreamChunk{Done: true, TokensIn: tokensIn, TokensOut: tokensOut, ToolCalls: toolCalls, FinishReason: finishReason}
 				return
 			}
 
@@ -287,12 +312,26 @@ func (p *CerebrasProvider) Stream(ctx context.Context, req *Request) (<-chan Str
 				if delta == "" {
 					delta = chunk.Choices[0].Delta.Reasoning
 				}
-				if delta != "" {
+				if delta = restorer.push(delta); delta != "" {
 					ch <- StreamChunk{Delta: delta}
 				}
 
+				// Tool call arguments arrive in fragments keyed by index
+				for _, tc := range chunk.Choices[0].Delta.ToolCalls {
+					for len(toolCalls) <= tc.Index {
+						toolCalls = append(toolCalls, ToolCall{Type: "function"})
+					}
+					call := &toolCalls[tc.Index]
+					if tc.ID != "" {
+						call.ID = tc.ID
+					}
+					call.Function.Name += tc.Function.Name
+					call.Function.Arguments += tc.Function.Arguments
+				}
+
 				// Check for finish
 				if chunk.Choices[0].FinishReason != "" {
+					finishReason = chunk.Choices[0].FinishReason
 					if chunk.Usage != nil {
 						tokensIn = chunk.Usage.PromptTokens
 						tokensOut = chunk.Usage.CompletionTokens
@@ -301,6 +340,9 @@ func (p *CerebrasProvider) Stream(ctx context.Context, req *Request) (<-chan Str
 			}
 		}
 
+		if rest := restorer.flush(); rest != "" {
+			ch <- StreamChunk{Delta: rest}
+		}
 		if err := scanner.Err(); err != nil {
 			ch <- StreamChunk{Error: err, Done: true}
 		}
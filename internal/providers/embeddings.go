@@ -0,0 +1,77 @@
+// Package providers - OpenAI-compatible embeddings endpoint
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// embeddingsRequest is the OpenAI-compatible /embeddings request format
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// embeddingsResponse is the OpenAI-compatible /embeddings response format
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed sends texts to the provider's /embeddings endpoint
+func (p *CerebrasProvider) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if !p.IsAvailable() {
+		return nil, fmt.Errorf("%s API key not configured (set %s)", p.config.Name, p.config.APIKeyEnv)
+	}
+	if model == "" {
+		return nil, fmt.Errorf("no embedding model configured")
+	}
+
+	body, err := json.Marshal(&embeddingsRequest{Model: model, Input: redactTexts(p.config.ID, texts)})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	}
+
+	var embres embeddingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embres); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range embres.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return vectors, nil
+}
@@ -3,6 +3,7 @@ package providers
 
 import (
 	"context"
+	"encoding/json"
 )
 
 // Provider is the interface all LLM providers must implement
@@ -26,6 +27,27 @@ type Provider interface {
 	IsAvailable() bool
 }
 
+// Embedder is implemented by providers that can turn text into vectors
+// for similarity search
+type Embedder interface {
+	// Embed returns one vector per text, in order
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+// ModelLister is implemented by providers that can list the models their
+// key may use
+type ModelLister interface {
+	// ListModels returns the model IDs the provider serves
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// Pinger is implemented by providers that can check their API can be
+// reached without spending tokens
+type Pinger interface {
+	// Ping returns an error if the API cannot be reached
+	Ping(ctx context.Context) error
+}
+
 // Request represents a generation request
 type Request struct {
 	Model       string    `json:"model"`
@@ -33,6 +55,7 @@ type Request struct {
 	Temperature float64   `json:"temperature,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Stream      bool      `json:"stream"`
+	Tools       []Tool    `json:"tools,omitempty"`
 
 	// Provider-specific options
 	Options map[string]interface{} `json:"options,omitempty"`
@@ -40,8 +63,36 @@ type Request struct {
 
 // Message represents a chat message
 type Message struct {
-	Role    string `json:"role"` // system, user, assistant
-	Content string `json:"content"`
+	Role       string     `json:"role"` // system, user, assistant, tool
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant: tools the model invoked
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool: the call this message answers
+}
+
+// Tool describes a function the model may call (OpenAI-compatible format)
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is the name, description, and JSON schema of a tool
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ToolCall is a tool invocation requested by the model
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the called tool and its JSON-encoded arguments
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // Response represents a generation response
@@ -53,10 +104,27 @@ type Response struct {
 	TokensOut int   `json:"tokens_out"`
 	Latency   int64 `json:"latency_ms"`
 
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// FinishReason is why generation stopped: stop, tool_calls, length
+	// (the max_tokens limit), or timeout when the caller stopped it
+	FinishReason string `json:"finish_reason,omitempty"`
+
 	// Raw response for debugging
 	Raw interface{} `json:"raw,omitempty"`
 }
 
+// Reasons a reply was cut short
+const (
+	FinishLength  = "length"
+	FinishTimeout = "timeout"
+)
+
+// Truncated reports whether the reply was cut short
+func (r *Response) Truncated() bool {
+	return r.FinishReason == FinishLength || r.FinishReason == FinishTimeout
+}
+
 // StreamChunk represents a streaming response chunk
 type StreamChunk struct {
 	Delta     string `json:"delta"`
@@ -64,6 +132,12 @@ type StreamChunk struct {
 	TokensOut int    `json:"tokens_out,omitempty"`
 	Done      bool   `json:"done"`
 	Error     error  `json:"error,omitempty"`
+
+	// ToolCalls are assembled from deltas and delivered with the Done chunk
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// FinishReason is delivered with the Done chunk, like Response's
+	FinishReason string `json:"finish_reason,omitempty"`
 }
 
 // ProviderConfig from database
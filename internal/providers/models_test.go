@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestListModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer key-1" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data": [{"id": "model-b"}, {"id": "model-a"}]}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("GOCLODE_TEST_KEY", "key-1")
+	p := NewGenericProvider(&ProviderConfig{ID: "test", BaseURL: srv.URL + "/v1", APIKeyEnv: "GOCLODE_TEST_KEY"})
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if want := []string{"model-a", "model-b"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels = %v, want %v", models, want)
+	}
+
+	t.Setenv("GOCLODE_TEST_KEY", "wrong")
+	p = NewGenericProvider(&ProviderConfig{ID: "test", BaseURL: srv.URL + "/v1", APIKeyEnv: "GOCLODE_TEST_KEY"})
+	if _, err := p.ListModels(context.Background()); err == nil {
+		t.Error("Expected a rejected key to fail")
+	}
+}
+
+func TestRegistryConfigs(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE providers (provider_id TEXT PRIMARY KEY, name TEXT, base_url TEXT, api_key_env TEXT,
+		default_model TEXT, enabled INTEGER, priority INTEGER, rate_limit_rpm INTEGER, config TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec(`INSERT INTO providers VALUES ('second', 'Second', '', 'SECOND_KEY', 'm2', 1, 2, NULL, '{}')`)
+	db.Exec(`INSERT INTO providers VALUES ('first', 'First', '', 'FIRST_KEY', 'm1', 1, 1, NULL, '{}')`)
+	db.Exec(`INSERT INTO providers VALUES ('off', 'Off', '', 'OFF_KEY', 'm', 0, 0, NULL, '{}')`)
+	r := NewRegistry(db)
+
+	configs, err := r.Configs()
+	if err != nil {
+		t.Fatalf("Configs: %v", err)
+	}
+	if len(configs) != 2 || configs[0].ID != "first" || configs[1].APIKeyEnv != "SECOND_KEY" {
+		t.Errorf("Expected first then second, got %+v", configs)
+	}
+
+	if err := r.SetDefaultModel("second", "m3"); err != nil {
+		t.Fatalf("SetDefaultModel: %v", err)
+	}
+	if configs, _ = r.Configs(); configs[1].DefaultModel != "m3" {
+		t.Errorf("Expected m3, got %s", configs[1].DefaultModel)
+	}
+	if err := r.SetDefaultModel("missing", "m"); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+}
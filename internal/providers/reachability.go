@@ -0,0 +1,43 @@
+// Package providers - Reachability: telling a provider that cannot be
+// reached at all from one that returned an error
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// IsUnreachable reports whether err means the API could not be reached:
+// no network, a DNS failure, a refused connection, or a timeout. Errors
+// the API returned, such as a bad key, are not.
+func IsUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	if errors.As(err, &dnsErr) || errors.As(err, &opErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && urlErr.Timeout()
+}
+
+// Ping checks the API answers at all: any HTTP response, even an error
+// status, means it can be reached
+func (p *CerebrasProvider) Ping(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsUnreachable(t *testing.T) {
+	// A port nothing listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	p := NewCerebrasProvider(&ProviderConfig{ID: "test", BaseURL: "http://" + addr})
+	err = p.Ping(context.Background())
+	if !IsUnreachable(err) {
+		t.Errorf("Expected a refused connection to be unreachable, got %v", err)
+	}
+	if !IsUnreachable(fmt.Errorf("stream: %w", err)) {
+		t.Error("Expected wrapped errors to be recognized")
+	}
+	if IsUnreachable(errors.New("API error 401: bad key")) || IsUnreachable(nil) {
+		t.Error("Expected API errors not to be unreachable")
+	}
+}
+
+func TestPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	p := NewCerebrasProvider(&ProviderConfig{ID: "test", BaseURL: server.URL})
+	if err := p.Ping(context.Background()); err != nil {
+		t.Errorf("Expected any HTTP answer to count as reachable, got %v", err)
+	}
+}
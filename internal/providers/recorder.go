@@ -0,0 +1,67 @@
+// Package providers - Recording the request payloads sent to provider APIs,
+// for repro bundles
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxRecordedRequests is how many of the latest requests are kept
+const maxRecordedRequests = 20
+
+// RecordedRequest is a request exactly as sent to a provider's API, with
+// the credentials in its headers redacted
+type RecordedRequest struct {
+	Time     time.Time         `json:"time"`
+	Provider string            `json:"provider"`
+	Method   string            `json:"method"`
+	URL      string            `json:"url"`
+	Headers  map[string]string `json:"headers"`
+	Body     json.RawMessage   `json:"body"`
+}
+
+var recorded struct {
+	mu       sync.Mutex
+	requests []RecordedRequest
+}
+
+// credentialHeaders are the headers whose values are never recorded
+var credentialHeaders = map[string]bool{"Authorization": true, "X-Api-Key": true, "Api-Key": true}
+
+// recordRequest keeps a request among the latest ones
+func recordRequest(provider string, req *http.Request, body []byte) {
+	headers := make(map[string]string, len(req.Header))
+	for name := range req.Header {
+		if credentialHeaders[http.CanonicalHeaderKey(name)] {
+			headers[name] = "[REDACTED]"
+		} else {
+			headers[name] = req.Header.Get(name)
+		}
+	}
+	r := RecordedRequest{
+		Time:     time.Now(),
+		Provider: provider,
+		Method:   req.Method,
+		URL:      req.URL.Redacted(),
+		Headers:  headers,
+		Body:     append(json.RawMessage(nil), body...),
+	}
+
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+	if len(recorded.requests) >= maxRecordedRequests {
+		recorded.requests = recorded.requests[len(recorded.requests)-maxRecordedRequests+1:]
+	}
+	recorded.requests = append(recorded.requests, r)
+}
+
+// RecentRequests returns the latest requests sent to provider APIs, oldest
+// first
+func RecentRequests() []RecordedRequest {
+	recorded.mu.Lock()
+	defer recorded.mu.Unlock()
+	return append([]RecordedRequest(nil), recorded.requests...)
+}
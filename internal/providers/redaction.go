@@ -0,0 +1,117 @@
+// Package providers - Redaction: text rewritten on its way to a provider's
+// API and back, so secrets never leave the machine
+package providers
+
+import (
+	"strings"
+	"sync"
+)
+
+// Redactor hides text from providers: Redact runs on everything sent,
+// Restore on the replies and RestoreJSON on tool call arguments
+type Redactor interface {
+	Redact(provider, text string) string
+	Restore(text string) string
+	RestoreJSON(text string) string
+}
+
+// maxPlaceholderLength bounds the text a stream holds back in case it is
+// the start of a placeholder
+const maxPlaceholderLength = 24
+
+var redaction struct {
+	mu sync.RWMutex
+	r  Redactor
+}
+
+// SetRedactor installs the redactor every provider applies; nil removes it
+func SetRedactor(r Redactor) {
+	redaction.mu.Lock()
+	defer redaction.mu.Unlock()
+	redaction.r = r
+}
+
+// currentRedactor returns the installed redactor, or nil
+func currentRedactor() Redactor {
+	redaction.mu.RLock()
+	defer redaction.mu.RUnlock()
+	return redaction.r
+}
+
+// redactMessages returns a copy of messages with their content and tool
+// call arguments redacted
+func redactMessages(provider string, messages []Message) []Message {
+	r := currentRedactor()
+	if r == nil {
+		return messages
+	}
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		m.Content = r.Redact(provider, m.Content)
+		if len(m.ToolCalls) > 0 {
+			calls := make([]ToolCall, len(m.ToolCalls))
+			for j, call := range m.ToolCalls {
+				call.Function.Arguments = r.Redact(provider, call.Function.Arguments)
+				calls[j] = call
+			}
+			m.ToolCalls = calls
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// redactTexts returns a copy of texts, redacted
+func redactTexts(provider string, texts []string) []string {
+	r := currentRedactor()
+	if r == nil {
+		return texts
+	}
+	out := make([]string, len(texts))
+	for i, text := range texts {
+		out[i] = r.Redact(provider, text)
+	}
+	return out
+}
+
+// restoreReply puts the secrets back in a reply's content and tool calls
+func restoreReply(content string, calls []ToolCall) (string, []ToolCall) {
+	r := currentRedactor()
+	if r == nil {
+		return content, calls
+	}
+	for i := range calls {
+		calls[i].Function.Arguments = r.RestoreJSON(calls[i].Function.Arguments)
+	}
+	return r.Restore(content), calls
+}
+
+// streamRestorer restores placeholders in streamed text, holding back a
+// tail that may be a placeholder cut between two deltas
+type streamRestorer struct {
+	r       Redactor
+	pending string
+}
+
+// push returns the text of a delta that can be shown
+func (s *streamRestorer) push(delta string) string {
+	if s.r == nil {
+		return delta
+	}
+	text := s.pending + delta
+	s.pending = ""
+	if i := strings.LastIndex(text, "["); i >= 0 && !strings.Contains(text[i:], "]") && len(text)-i < maxPlaceholderLength {
+		text, s.pending = text[:i], text[i:]
+	}
+	return s.r.Restore(text)
+}
+
+// flush returns the text held back
+func (s *streamRestorer) flush() string {
+	text := s.pending
+	s.pending = ""
+	if s.r == nil || text == "" {
+		return text
+	}
+	return s.r.Restore(text)
+}
@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeRedactor stands for a secret redactor: "secret" is sent as [S]
+type fakeRedactor struct{}
+
+func (fakeRedactor) Redact(provider, text string) string {
+	return strings.ReplaceAll(text, "secret", "[S]")
+}
+
+func (fakeRedactor) Restore(text string) string {
+	return strings.ReplaceAll(text, "[S]", "secret")
+}
+
+func (fakeRedactor) RestoreJSON(text string) string {
+	return strings.ReplaceAll(text, "[S]", "secret")
+}
+
+func TestRedactMessages(t *testing.T) {
+	SetRedactor(fakeRedactor{})
+	defer SetRedactor(nil)
+
+	messages := []Message{
+		{Role: "user", Content: "my secret"},
+		{Role: "assistant", ToolCalls: []ToolCall{{Function: ToolCallFunction{Name: "bash", Arguments: `{"command":"echo secret"}`}}}},
+	}
+	out := redactMessages("cerebras", messages)
+	if out[0].Content != "my [S]" || out[1].ToolCalls[0].Function.Arguments != `{"command":"echo [S]"}` {
+		t.Errorf("Not redacted: %+v", out)
+	}
+	if messages[0].Content != "my secret" || messages[1].ToolCalls[0].Function.Arguments != `{"command":"echo secret"}` {
+		t.Errorf("Original messages changed: %+v", messages)
+	}
+
+	content, calls := restoreReply("use [S]", []ToolCall{{Function: ToolCallFunction{Arguments: `"[S]"`}}})
+	if content != "use secret" || calls[0].Function.Arguments != `"secret"` {
+		t.Errorf("Not restored: %q %+v", content, calls)
+	}
+}
+
+func TestStreamRestorer(t *testing.T) {
+	s := &streamRestorer{r: fakeRedactor{}}
+	got := s.push("the [") + s.push("S] is [") + s.push("S")
+	if got != "the secret is " {
+		t.Errorf("Pushed %q", got)
+	}
+	if rest := s.flush(); rest != "[S" {
+		t.Errorf("Flushed %q", rest)
+	}
+
+	plain := &streamRestorer{}
+	if got := plain.push("a [") + plain.flush(); got != "a [" {
+		t.Errorf("Without a redactor got %q", got)
+	}
+}
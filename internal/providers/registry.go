@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+
+	"github.com/hazyhaar/GoClode/internal/core"
 )
 
 // Registry manages all available providers with hot-reload support
@@ -48,6 +50,7 @@ func (r *Registry) reload() error {
 		err := rows.Scan(&cfg.ID, &cfg.Name, &cfg.BaseURL, &cfg.APIKeyEnv, &cfg.DefaultModel,
 			&cfg.Enabled, &cfg.Priority, &rateLimit, &configJSON)
 		if err != nil {
+			core.Logger("providers").Warn("Provider skipped", "error", err)
 			continue
 		}
 
@@ -140,6 +143,41 @@ func (r *Registry) Available() []Provider {
 	return list
 }
 
+// Configs returns the settings of the enabled providers, by priority
+func (r *Registry) Configs() ([]ProviderConfig, error) {
+	rows, err := r.db.Query(`
+		SELECT provider_id, name, base_url, api_key_env, default_model, enabled, priority
+		FROM providers WHERE enabled = 1 ORDER BY priority, provider_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	configs := make([]ProviderConfig, 0)
+	for rows.Next() {
+		var cfg ProviderConfig
+		if err := rows.Scan(&cfg.ID, &cfg.Name, &cfg.BaseURL, &cfg.APIKeyEnv, &cfg.DefaultModel, &cfg.Enabled, &cfg.Priority); err != nil {
+			return nil, err
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, rows.Err()
+}
+
+// SetDefaultModel changes the model a provider uses when a request names
+// none
+func (r *Registry) SetDefaultModel(id, model string) error {
+	result, err := r.db.Exec(`UPDATE providers SET default_model = ? WHERE provider_id = ?`, model, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("provider %q not found", id)
+	}
+	return r.reload()
+}
+
 // Reload reloads providers from database
 func (r *Registry) Reload() error {
 	return r.reload()
@@ -193,3 +231,21 @@ func (p *GenericProvider) ID() string {
 func (p *GenericProvider) Name() string {
 	return p.config.Name
 }
+
+// Pricing returns what a provider charges, from the price_in and price_out
+// of its config; zero when no price is set
+func (r *Registry) Pricing(id string) (Pricing, error) {
+	var price Pricing
+	var configJSON sql.NullString
+	err := r.db.QueryRow(`SELECT config FROM providers WHERE provider_id = ?`, id).Scan(&configJSON)
+	if err == sql.ErrNoRows {
+		return price, fmt.Errorf("provider not found: %s", id)
+	}
+	if err != nil || !configJSON.Valid || configJSON.String == "" {
+		return price, err
+	}
+	if err := json.Unmarshal([]byte(configJSON.String), &price); err != nil {
+		return price, fmt.Errorf("provider %s: config: %w", id, err)
+	}
+	return price, nil
+}
@@ -0,0 +1,77 @@
+// Package providers - Replay provider answering from canned replies, for
+// running test cases offline
+package providers
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplayProvider answers each request with the reply recorded for its last
+// user message instead of calling a model
+type ReplayProvider struct {
+	Replies map[string]string // User message -> reply
+	Default string            // Reply to unknown messages
+}
+
+// NewReplayProvider creates a replay provider
+func NewReplayProvider(replies map[string]string) *ReplayProvider {
+	return &ReplayProvider{Replies: replies}
+}
+
+// ID returns the provider identifier
+func (p *ReplayProvider) ID() string {
+	return "replay"
+}
+
+// Name returns the human-readable name
+func (p *ReplayProvider) Name() string {
+	return "Replay"
+}
+
+// Models returns available models
+func (p *ReplayProvider) Models() []string {
+	return []string{"replay"}
+}
+
+// IsAvailable is always true: no API key is needed
+func (p *ReplayProvider) IsAvailable() bool {
+	return true
+}
+
+// reply finds the canned reply for a request
+func (p *ReplayProvider) reply(req *Request) string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			if reply, ok := p.Replies[req.Messages[i].Content]; ok {
+				return reply
+			}
+			break
+		}
+	}
+	return p.Default
+}
+
+// Generate returns the canned reply
+func (p *ReplayProvider) Generate(ctx context.Context, req *Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if req == nil {
+		return nil, fmt.Errorf("nil request")
+	}
+	return &Response{Model: "replay", Content: p.reply(req)}, nil
+}
+
+// Stream sends the canned reply as a single chunk
+func (p *ReplayProvider) Stream(ctx context.Context, req *Request) (<-chan StreamChunk, error) {
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan StreamChunk, 2)
+	ch <- StreamChunk{Delta: resp.Content}
+	ch <- StreamChunk{Done: true}
+	close(ch)
+	return ch, nil
+}
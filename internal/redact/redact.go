@@ -0,0 +1,148 @@
+// Package redact finds credentials in text: API keys, tokens, private
+// keys, and .env-style values. A Redactor swaps them for placeholders and
+// keeps the map to put them back, so secrets never leave the machine.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// MinSecretLength is the shortest known value searched for
+const MinSecretLength = 8
+
+// Pattern is a kind of credential. When the expression has a group, the
+// group is kept and only the rest of the match is the secret.
+type Pattern struct {
+	Kind string
+	Re   *regexp.Regexp
+}
+
+// Patterns match credentials in text. None takes quotes or backslashes,
+// so JSON stays valid.
+var Patterns = []Pattern{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"bearer token", regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]{8,}`)},
+	{"api key", regexp.MustCompile(`\b(?:sk|pk|rk|csk|ghp|gho|ghs|ghu|glpat|xox[abpr])[-_][A-Za-z0-9_-]{10,}`)},
+	{"aws access key", regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+	{"url password", regexp.MustCompile(`([a-z][a-z0-9+.-]*://[^:/\s"'@\\]+:)[^@\s"'\\/]{3,}@`)},
+	{"env value", regexp.MustCompile(`(?m)^(\s*(?:export\s+)?[A-Z0-9_]*(?:KEY|SECRET|TOKEN|PASSWORD|PASSWD|CREDENTIALS?)[A-Z0-9_]*\s*=\s*["']?)[^\s"'#\\]{4,}`)},
+	{"assignment", regexp.MustCompile(`(?i)((?:api[_-]?key|secret|token|password|passwd)\\?["']?\s*[:=]\s*\\?["']?)[^\s"',\\]{4,}`)},
+}
+
+// placeholderPattern matches the placeholders a Redactor writes
+var placeholderPattern = regexp.MustCompile(`\[SECRET_\d+\]`)
+
+// Finding is a secret that was redacted: its kind and placeholder, never
+// its value
+type Finding struct {
+	Kind        string `json:"kind"`
+	Placeholder string `json:"placeholder"`
+}
+
+// Redactor replaces secrets with numbered placeholders, the same one each
+// time a secret is seen, and restores them
+type Redactor struct {
+	mu       sync.Mutex
+	known    []string
+	patterns []Pattern
+	secrets  map[string]string // Placeholder to secret
+	slots    map[string]string // Secret to placeholder
+}
+
+// New returns a Redactor that also hides the known values, such as the
+// API keys in the environment, and the matches of the extra patterns
+func New(known []string, extra []Pattern) *Redactor {
+	r := &Redactor{
+		known:    make([]string, 0, len(known)),
+		patterns: append(append([]Pattern(nil), Patterns...), extra...),
+		secrets:  make(map[string]string),
+		slots:    make(map[string]string),
+	}
+	for _, k := range known {
+		if len(k) >= MinSecretLength {
+			r.known = append(r.known, k)
+		}
+	}
+	return r
+}
+
+// Redact replaces the secrets in text with placeholders, and returns the
+// secrets seen for the first time
+func (r *Redactor) Redact(text string) (string, []Finding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	findings := make([]Finding, 0)
+	for _, secret := range r.known {
+		if strings.Contains(text, secret) {
+			text = strings.ReplaceAll(text, secret, r.placeholder(secret, "known value", &findings))
+		}
+	}
+	for _, p := range r.patterns {
+		text = p.Re.ReplaceAllStringFunc(text, func(match string) string {
+			prefix := ""
+			if m := p.Re.FindStringSubmatch(match); len(m) > 1 {
+				prefix = m[1]
+			}
+			secret := strings.TrimPrefix(match, prefix)
+			if placeholderPattern.MatchString(secret) {
+				return match // Already redacted
+			}
+			return prefix + r.placeholder(secret, p.Kind, &findings)
+		})
+	}
+	return text, findings
+}
+
+// placeholder returns a secret's placeholder, numbering new secrets
+func (r *Redactor) placeholder(secret, kind string, findings *[]Finding) string {
+	if slot, ok := r.slots[secret]; ok {
+		return slot
+	}
+	slot := fmt.Sprintf("[SECRET_%d]", len(r.secrets)+1)
+	r.secrets[slot] = secret
+	r.slots[secret] = slot
+	*findings = append(*findings, Finding{Kind: kind, Placeholder: slot})
+	return slot
+}
+
+// Restore puts the secrets back in place of their placeholders
+func (r *Redactor) Restore(text string) string {
+	return r.restore(text, false)
+}
+
+// RestoreJSON puts the secrets back in JSON text, escaped as string
+// content
+func (r *Redactor) RestoreJSON(text string) string {
+	return r.restore(text, true)
+}
+
+func (r *Redactor) restore(text string, escape bool) string {
+	if !strings.Contains(text, "[SECRET_") {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return placeholderPattern.ReplaceAllStringFunc(text, func(slot string) string {
+		secret, ok := r.secrets[slot]
+		if !ok {
+			return slot
+		}
+		if escape {
+			quoted, _ := json.Marshal(secret)
+			return string(quoted[1 : len(quoted)-1])
+		}
+		return secret
+	})
+}
+
+// Len is how many secrets have placeholders
+func (r *Redactor) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.secrets)
+}
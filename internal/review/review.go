@@ -0,0 +1,185 @@
+// Package review reviews pull requests file by file with a model, and
+// turns its findings into line comments
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/diff"
+	"github.com/hazyhaar/GoClode/internal/git"
+	"github.com/hazyhaar/GoClode/internal/prompt"
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+// maxContent caps the bytes of a file's new version sent with its diff
+const maxContent = 60 * 1024
+
+// Severities, most serious first
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNote    = "note"
+)
+
+// DefaultTemplate is used when the code_review prompt is missing
+const DefaultTemplate = "Review the following code for bugs, security issues, and improvements:\n\n{{code}}"
+
+// instructions ask for findings the tool can place on lines
+const instructions = `
+
+Reply with a JSON array of findings only, [] if the change looks right:
+[{"line": <line number in the new version>, "severity": "error" | "warning" | "note", "comment": "<what is wrong and how to fix it>"}]
+Report real problems, not style preferences. Use "error" for bugs and security issues.`
+
+// Finding is a problem the model reported in a file
+type Finding struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Severity string `json:"severity"`
+	Comment  string `json:"comment"`
+}
+
+// rank orders severities, unknown ones as notes
+func rank(severity string) int {
+	switch severity {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	}
+	return 1
+}
+
+// AtLeast reports whether a severity is as serious as threshold
+func AtLeast(severity, threshold string) bool {
+	return rank(severity) >= rank(threshold)
+}
+
+// Prompt fills a review template ({{code}}) for one file: its diff, its
+// new version for context, and the other files of the pull request
+func Prompt(template string, file git.PullRequestFile, content string, others []string) string {
+	// Templates seeded from SQL keep their \n escapes
+	template = strings.ReplaceAll(template, `\n`, "\n")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "File: %s (%s)\n\nDiff:\n```diff\n%s\n```\n", file.Path, file.Status, file.Patch)
+	if content != "" {
+		if len(content) > maxContent {
+			content = content[:maxContent] + "\n... (truncated)"
+		}
+		fmt.Fprintf(&sb, "\nNew version, for context:\n```\n%s\n```\n", content)
+	}
+	if len(others) > 0 {
+		fmt.Fprintf(&sb, "\nOther files in this pull request: %s\n", strings.Join(others, ", "))
+	}
+
+	code := sb.String()
+	if t, err := prompt.Parse(template); err == nil && slices.Contains(t.Variables(), "code") {
+		if text, err := t.Render(map[string]string{"code": code}); err == nil {
+			return text + instructions
+		}
+	}
+	return template + "\n\n" + code + instructions
+}
+
+// jsonArrayPattern finds the JSON array of a reply, fenced or not
+var jsonArrayPattern = regexp.MustCompile(`(?s)\[.*\]`)
+
+// ParseFindings reads the findings in a reply about path
+func ParseFindings(path, reply string) ([]Finding, error) {
+	raw := jsonArrayPattern.FindString(reply)
+	if raw == "" {
+		return nil, fmt.Errorf("no JSON array of findings in the reply")
+	}
+	var findings []Finding
+	if err := json.Unmarshal([]byte(raw), &findings); err != nil {
+		return nil, fmt.Errorf("read findings: %w", err)
+	}
+	kept := findings[:0]
+	for _, f := range findings {
+		f.Comment = strings.TrimSpace(f.Comment)
+		if f.Comment == "" {
+			continue
+		}
+		f.Path = path
+		f.Severity = strings.ToLower(strings.TrimSpace(f.Severity))
+		if f.Severity != SeverityError && f.Severity != SeverityWarning {
+			f.Severity = SeverityNote
+		}
+		kept = append(kept, f)
+	}
+	return kept, nil
+}
+
+// File asks a model to review one file
+func File(ctx context.Context, p providers.Provider, model, path, prompt string) ([]Finding, error) {
+	resp, err := p.Generate(ctx, &providers.Request{
+		Model:       model,
+		Messages:    []providers.Message{{Role: "user", Content: prompt}},
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ParseFindings(path, resp.Content)
+}
+
+// Comments places findings on the lines of their file's diff; the others,
+// on lines the diff does not show, are returned to go in the review body
+func Comments(findings []Finding, patches map[string]string) ([]git.ReviewComment, []Finding) {
+	lines := make(map[string]map[int]bool)
+	comments := make([]git.ReviewComment, 0, len(findings))
+	outside := make([]Finding, 0)
+	for _, f := range findings {
+		if lines[f.Path] == nil {
+			lines[f.Path] = diff.NewLines(patches[f.Path])
+		}
+		if !lines[f.Path][f.Line] {
+			outside = append(outside, f)
+			continue
+		}
+		comments = append(comments, git.ReviewComment{
+			Path: f.Path,
+			Line: f.Line,
+			Body: fmt.Sprintf("**%s**: %s", f.Severity, f.Comment),
+		})
+	}
+	return comments, outside
+}
+
+// Body summarizes a review: counts by severity, then the findings that
+// could not be placed on a line
+func Body(findings, outside []Finding, files int) string {
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+
+	var sb strings.Builder
+	if len(findings) == 0 {
+		fmt.Fprintf(&sb, "GoClode reviewed %d file(s) and found nothing to report.", files)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "GoClode reviewed %d file(s): %d error(s), %d warning(s), %d note(s).",
+		files, counts[SeverityError], counts[SeverityWarning], counts[SeverityNote])
+
+	if len(outside) > 0 {
+		sorted := append([]Finding(nil), outside...)
+		sort.SliceStable(sorted, func(i, j int) bool { return rank(sorted[i].Severity) > rank(sorted[j].Severity) })
+		sb.WriteString("\n\nOutside the changed lines:\n")
+		for _, f := range sorted {
+			where := f.Path
+			if f.Line > 0 {
+				where = fmt.Sprintf("%s:%d", f.Path, f.Line)
+			}
+			fmt.Fprintf(&sb, "- **%s** `%s`: %s\n", f.Severity, where, f.Comment)
+		}
+	}
+	return sb.String()
+}
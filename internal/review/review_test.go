@@ -0,0 +1,65 @@
+package review
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/git"
+)
+
+func TestPrompt(t *testing.T) {
+	file := git.PullRequestFile{Path: "main.go", Status: "modified", Patch: "@@ -1 +1 @@\n-a\n+b"}
+	prompt := Prompt(`Review this:\n\n{{code}}`, file, "package main\n", []string{"util.go"})
+	for _, want := range []string{"Review this:\n\nFile: main.go (modified)", "+b", "package main", "util.go", `"severity"`} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestParseFindings(t *testing.T) {
+	reply := "Here is my review:\n```json\n" +
+		`[{"line": 3, "severity": "Error", "comment": "nil map write"},` +
+		`{"line": 9, "severity": "nit", "comment": "rename x"},` +
+		`{"line": 4, "severity": "warning", "comment": "  "}]` + "\n```"
+	findings, err := ParseFindings("main.go", reply)
+	if err != nil {
+		t.Fatalf("ParseFindings failed: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %+v", findings)
+	}
+	if findings[0].Path != "main.go" || findings[0].Severity != SeverityError || findings[1].Severity != SeverityNote {
+		t.Errorf("Unexpected findings %+v", findings)
+	}
+
+	if findings, err := ParseFindings("main.go", "Looks good: []"); err != nil || len(findings) != 0 {
+		t.Errorf("Expected no findings, got %v (%v)", findings, err)
+	}
+	if _, err := ParseFindings("main.go", "Looks good to me!"); err == nil {
+		t.Error("Expected an error without a JSON array")
+	}
+}
+
+func TestComments(t *testing.T) {
+	patches := map[string]string{"main.go": "@@ -1,2 +1,3 @@\n a\n+b\n c"}
+	findings := []Finding{
+		{Path: "main.go", Line: 2, Severity: SeverityError, Comment: "bug"},
+		{Path: "main.go", Line: 40, Severity: SeverityWarning, Comment: "elsewhere"},
+	}
+	comments, outside := Comments(findings, patches)
+	if len(comments) != 1 || comments[0].Line != 2 || !strings.Contains(comments[0].Body, "bug") {
+		t.Errorf("Unexpected comments %+v", comments)
+	}
+	if len(outside) != 1 || outside[0].Line != 40 {
+		t.Errorf("Unexpected outside findings %+v", outside)
+	}
+
+	body := Body(findings, outside, 1)
+	if !strings.Contains(body, "1 error(s), 1 warning(s)") || !strings.Contains(body, "`main.go:40`") {
+		t.Errorf("Unexpected body:\n%s", body)
+	}
+	if !AtLeast(SeverityError, SeverityWarning) || AtLeast(SeverityNote, SeverityWarning) {
+		t.Error("Unexpected severity ordering")
+	}
+}
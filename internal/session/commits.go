@@ -0,0 +1,106 @@
+// Package session - Undo/redo stack over GoClode's git commits
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CommitRecord is a GoClode auto-commit and its revert state
+type CommitRecord struct {
+	ID           string
+	Hash         string
+	Message      string
+	RevertHash   string // Empty while the commit is live
+	MessageID    string // Assistant reply the commit came from
+	FilesChanged int
+	CreatedAt    time.Time
+}
+
+// commitColumns is the column list scanCommits expects
+const commitColumns = `commit_id, git_hash, commit_message, COALESCE(revert_hash, ''), COALESCE(message_id, ''), files_changed, created_at`
+
+// UndoableCommits returns live GoClode commits, newest first
+func (m *Manager) UndoableCommits(limit int) ([]CommitRecord, error) {
+	rows, err := m.engine.Query(`
+		SELECT `+commitColumns+` FROM git_commits
+		WHERE revert_hash IS NULL
+		ORDER BY created_at DESC, rowid DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanCommits(rows)
+}
+
+// LastRevertedCommit returns the most recently undone commit that can be redone
+func (m *Manager) LastRevertedCommit() (*CommitRecord, error) {
+	rows, err := m.engine.Query(`
+		SELECT ` + commitColumns + ` FROM git_commits
+		WHERE revert_hash IS NOT NULL AND reverted_at IS NOT NULL
+		ORDER BY reverted_at DESC, rowid DESC LIMIT 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := scanCommits(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &commits[0], nil
+}
+
+// SessionCommits returns the commits made during the current session, newest first
+func (m *Manager) SessionCommits() ([]CommitRecord, error) {
+	rows, err := m.engine.Query(`
+		SELECT `+commitColumns+` FROM git_commits
+		WHERE session_id = ?
+		ORDER BY created_at DESC, rowid DESC
+	`, m.sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return scanCommits(rows)
+}
+
+// MarkCommitReverted records that revertHash undid a commit
+func (m *Manager) MarkCommitReverted(commitID, revertHash string) error {
+	_, err := m.engine.Exec(`
+		UPDATE git_commits SET revert_hash = ?, reverted_at = ? WHERE commit_id = ?
+	`, revertHash, time.Now().UnixNano(), commitID)
+	if err != nil {
+		return fmt.Errorf("mark reverted: %w", err)
+	}
+	return nil
+}
+
+// MarkCommitRestored records that a revert was itself reverted (/redo)
+func (m *Manager) MarkCommitRestored(commitID string) error {
+	_, err := m.engine.Exec(`
+		UPDATE git_commits SET revert_hash = NULL, reverted_at = NULL WHERE commit_id = ?
+	`, commitID)
+	if err != nil {
+		return fmt.Errorf("mark restored: %w", err)
+	}
+	return nil
+}
+
+func scanCommits(rows *sql.Rows) ([]CommitRecord, error) {
+	defer rows.Close()
+
+	commits := make([]CommitRecord, 0)
+	for rows.Next() {
+		var c CommitRecord
+		var createdAt int64
+		if err := rows.Scan(&c.ID, &c.Hash, &c.Message, &c.RevertHash, &c.MessageID, &c.FilesChanged, &createdAt); err != nil {
+			return nil, err
+		}
+		c.CreatedAt = time.Unix(createdAt, 0)
+		commits = append(commits, c)
+	}
+	return commits, rows.Err()
+}
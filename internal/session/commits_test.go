@@ -0,0 +1,105 @@
+package session
+
+import "testing"
+
+func TestCommits_UndoRedoStack(t *testing.T) {
+	m := setupTestManager(t)
+
+	m.RecordGitCommit("aaa", "first", 1)
+	m.RecordGitCommit("bbb", "second", 1)
+
+	commits, err := m.UndoableCommits(10)
+	if err != nil || len(commits) != 2 || commits[0].Hash != "bbb" {
+		t.Fatalf("UndoableCommits = %+v, %v", commits, err)
+	}
+
+	// Undo twice walks back through history
+	m.MarkCommitReverted(commits[0].ID, "rev-b")
+	commits, _ = m.UndoableCommits(10)
+	if len(commits) != 1 || commits[0].Hash != "aaa" {
+		t.Fatalf("after first undo = %+v", commits)
+	}
+	m.MarkCommitReverted(commits[0].ID, "rev-a")
+
+	// Redo restores the most recently undone commit first
+	redo, err := m.LastRevertedCommit()
+	if err != nil || redo.Hash != "aaa" || redo.RevertHash != "rev-a" {
+		t.Fatalf("LastRevertedCommit = %+v, %v", redo, err)
+	}
+	m.MarkCommitRestored(redo.ID)
+
+	redo, err = m.LastRevertedCommit()
+	if err != nil || redo.Hash != "bbb" {
+		t.Fatalf("second LastRevertedCommit = %+v, %v", redo, err)
+	}
+
+	// A new commit clears the redo history
+	m.RecordGitCommit("ccc", "third", 1)
+	if _, err := m.LastRevertedCommit(); err == nil {
+		t.Error("redo should not be possible after a new commit")
+	}
+	commits, _ = m.UndoableCommits(10)
+	if len(commits) != 2 || commits[0].Hash != "ccc" {
+		t.Errorf("UndoableCommits after new commit = %+v", commits)
+	}
+}
+
+func TestCommits_SessionCommits(t *testing.T) {
+	m := setupTestManager(t)
+
+	m.RecordGitCommit("aaa", "first", 2)
+	m.RecordGitCommit("bbb", "second", 1)
+
+	commits, _ := m.UndoableCommits(10)
+	m.MarkCommitReverted(commits[0].ID, "rev-b")
+
+	commits, err := m.SessionCommits()
+	if err != nil || len(commits) != 2 {
+		t.Fatalf("SessionCommits = %+v, %v", commits, err)
+	}
+	if commits[0].Hash != "bbb" || commits[0].RevertHash != "rev-b" {
+		t.Errorf("newest = %+v, want bbb reverted by rev-b", commits[0])
+	}
+	if commits[1].Hash != "aaa" || commits[1].RevertHash != "" || commits[1].FilesChanged != 2 {
+		t.Errorf("oldest = %+v, want live aaa with 2 files", commits[1])
+	}
+}
+
+func TestCommits_AmendGitCommit(t *testing.T) {
+	m := setupTestManager(t)
+
+	m.RecordGitCommit("aaa", "GoClode: update a.go", 1)
+	if err := m.AmendGitCommit("aaa", "bbb", "GoClode: update a.go\n\n- update b.go", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := m.SessionCommits()
+	if err != nil || len(commits) != 1 {
+		t.Fatalf("SessionCommits = %+v, %v", commits, err)
+	}
+	if commits[0].Hash != "bbb" || commits[0].FilesChanged != 2 {
+		t.Errorf("amended commit = %+v, want bbb with 2 files", commits[0])
+	}
+}
+
+func TestCommits_Stats(t *testing.T) {
+	m := setupTestManager(t)
+
+	m.RecordGitCommit("aaa", "first", 1)
+	m.RecordGitCommit("bbb", "second", 1)
+	m.SetCommitStats("aaa", 1, 10, 2)
+	m.SetCommitStats("bbb", 3, 5, 4)
+
+	stats, err := m.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["insertions"] != 15 || stats["deletions"] != 6 {
+		t.Errorf("churn = +%v/-%v, want +15/-6", stats["insertions"], stats["deletions"])
+	}
+
+	commits, _ := m.SessionCommits()
+	if commits[0].FilesChanged != 3 {
+		t.Errorf("files changed = %d, want 3 from the stat", commits[0].FilesChanged)
+	}
+}
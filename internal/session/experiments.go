@@ -0,0 +1,348 @@
+// Package session - System prompt A/B experiments: each session is served
+// one variant, its replies are tagged with it, and feedback and undo rates
+// are compared per variant
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ControlVariant is the variant that keeps the system_prompt setting
+const ControlVariant = "config"
+
+// Experiment statuses
+const (
+	ExperimentRunning = "running"
+	ExperimentStopped = "stopped"
+)
+
+// Experiment is an A/B test between system prompt variants
+type Experiment struct {
+	Name      string
+	Variants  []string // Prompt IDs, or ControlVariant
+	Status    string
+	CreatedAt time.Time
+}
+
+// VariantStats is how the replies of one variant were received
+type VariantStats struct {
+	Variant  string
+	Sessions int
+	Replies  int
+	Liked    int // Replies rated 👍
+	Disliked int // Replies rated 👎
+	Undone   int // Replies whose changes were undone
+}
+
+// rate is n as a share of the variant's replies
+func (v VariantStats) rate(n int) float64 {
+	if v.Replies == 0 {
+		return 0
+	}
+	return float64(n) / float64(v.Replies)
+}
+
+// LikeRate is the share of replies rated 👍
+func (v VariantStats) LikeRate() float64 { return v.rate(v.Liked) }
+
+// DislikeRate is the share of replies rated 👎
+func (v VariantStats) DislikeRate() float64 { return v.rate(v.Disliked) }
+
+// UndoRate is the share of replies whose changes were undone
+func (v VariantStats) UndoRate() float64 { return v.rate(v.Undone) }
+
+// StartExperiment starts an experiment between at least two variants,
+// stopping the one running; variants are enabled system prompts from the
+// prompts table, or ControlVariant
+func (m *Manager) StartExperiment(name string, variants []string) error {
+	if len(variants) < 2 {
+		return fmt.Errorf("an experiment needs at least two variants")
+	}
+	seen := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if seen[v] {
+			return fmt.Errorf("variant %s listed twice", v)
+		}
+		seen[v] = true
+		if v == ControlVariant {
+			continue
+		}
+		if _, err := m.variantPrompt(v); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(variants)
+	if err != nil {
+		return err
+	}
+	if _, err := m.StopExperiment(); err != nil {
+		return err
+	}
+	if _, err := m.engine.Exec(`
+		INSERT INTO experiments (name, variants) VALUES (?, ?)
+	`, name, string(data)); err != nil {
+		return fmt.Errorf("start experiment %s: %w", name, err)
+	}
+	return nil
+}
+
+// StopExperiment stops the running experiment and returns its name, ""
+// when none was running
+func (m *Manager) StopExperiment() (string, error) {
+	running, err := m.RunningExperiment()
+	if err != nil || running == nil {
+		return "", err
+	}
+	if _, err := m.engine.Exec(`
+		UPDATE experiments SET status = ?, stopped_at = ? WHERE name = ?
+	`, ExperimentStopped, time.Now().Unix(), running.Name); err != nil {
+		return "", fmt.Errorf("stop experiment: %w", err)
+	}
+	m.experiment, m.variant = "", ""
+	return running.Name, nil
+}
+
+// RunningExperiment returns the running experiment, nil when there is none
+func (m *Manager) RunningExperiment() (*Experiment, error) {
+	experiments, err := m.queryExperiments(`WHERE status = ? LIMIT 1`, ExperimentRunning)
+	if err != nil || len(experiments) == 0 {
+		return nil, err
+	}
+	return &experiments[0], nil
+}
+
+// Experiments returns all experiments, newest first
+func (m *Manager) Experiments() ([]Experiment, error) {
+	return m.queryExperiments(`ORDER BY created_at DESC, rowid DESC`)
+}
+
+// GetExperiment returns an experiment by name
+func (m *Manager) GetExperiment(name string) (*Experiment, error) {
+	experiments, err := m.queryExperiments(`WHERE name = ?`, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(experiments) == 0 {
+		return nil, fmt.Errorf("no experiment %q", name)
+	}
+	return &experiments[0], nil
+}
+
+// queryExperiments lists experiments, filtered and ordered by a clause
+func (m *Manager) queryExperiments(where string, args ...interface{}) ([]Experiment, error) {
+	rows, err := m.engine.Query(`
+		SELECT name, variants, status, created_at FROM experiments
+	`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list experiments: %w", err)
+	}
+	defer rows.Close()
+
+	experiments := make([]Experiment, 0)
+	for rows.Next() {
+		var e Experiment
+		var variants string
+		var createdAt int64
+		if err := rows.Scan(&e.Name, &variants, &e.Status, &createdAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(variants), &e.Variants); err != nil {
+			return nil, fmt.Errorf("experiment %s: %w", e.Name, err)
+		}
+		e.CreatedAt = time.Unix(createdAt, 0)
+		experiments = append(experiments, e)
+	}
+	return experiments, rows.Err()
+}
+
+// AssignVariant serves the current session a variant of the running
+// experiment, keeping the one it already has, and tags its messages with
+// it from now on. It returns the variant's system prompt: "" for
+// ControlVariant or when no experiment is running.
+func (m *Manager) AssignVariant() (variant, prompt string, err error) {
+	m.experiment, m.variant = "", ""
+	running, err := m.RunningExperiment()
+	if err != nil || running == nil {
+		return "", "", err
+	}
+
+	err = m.engine.QueryRow(`
+		SELECT variant FROM experiment_sessions WHERE session_id = ? AND experiment = ?
+	`, m.sessionID, running.Name).Scan(&variant)
+	if err == sql.ErrNoRows {
+		if variant, err = m.leastServedVariant(running); err != nil {
+			return "", "", err
+		}
+		_, err = m.engine.Exec(`
+			INSERT OR REPLACE INTO experiment_sessions (session_id, experiment, variant) VALUES (?, ?, ?)
+		`, m.sessionID, running.Name, variant)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("assign variant: %w", err)
+	}
+
+	if variant != ControlVariant {
+		if prompt, err = m.variantPrompt(variant); err != nil {
+			return "", "", err
+		}
+	}
+	m.experiment, m.variant = running.Name, variant
+	return variant, prompt, nil
+}
+
+// Variant returns the experiment and variant the current session is served
+func (m *Manager) Variant() (experiment, variant string) {
+	return m.experiment, m.variant
+}
+
+// leastServedVariant picks one of the variants with the fewest sessions,
+// at random among ties, so variants stay balanced
+func (m *Manager) leastServedVariant(e *Experiment) (string, error) {
+	counts := make(map[string]int, len(e.Variants))
+	rows, err := m.engine.Query(`
+		SELECT variant, COUNT(*) FROM experiment_sessions WHERE experiment = ? GROUP BY variant
+	`, e.Name)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v string
+		var n int
+		if err := rows.Scan(&v, &n); err != nil {
+			return "", err
+		}
+		counts[v] = n
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	least := make([]string, 0, len(e.Variants))
+	for _, v := range e.Variants {
+		switch {
+		case len(least) == 0 || counts[v] < counts[least[0]]:
+			least = append(least[:0], v)
+		case counts[v] == counts[least[0]]:
+			least = append(least, v)
+		}
+	}
+	return least[rand.Intn(len(least))], nil
+}
+
+// variantPrompt returns the template of an enabled system prompt
+func (m *Manager) variantPrompt(promptID string) (string, error) {
+	var template string
+	err := m.engine.QueryRow(`
+		SELECT template FROM prompts WHERE prompt_id = ? AND category = 'system' AND enabled = 1
+	`, promptID).Scan(&template)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no enabled system prompt %q", promptID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("find prompt %s: %w", promptID, err)
+	}
+	return template, nil
+}
+
+// SaveSystemPrompt adds or replaces a system prompt in the prompts table,
+// so it can be used as an experiment variant
+func (m *Manager) SaveSystemPrompt(promptID, template string) error {
+	_, err := m.engine.Exec(`
+		INSERT INTO prompts (prompt_id, name, template, category) VALUES (?, ?, ?, 'system')
+		ON CONFLICT(prompt_id) DO UPDATE SET
+			template = excluded.template, category = 'system', enabled = 1,
+			version = version + 1, updated_at = strftime('%s', 'now')
+	`, promptID, promptID, template)
+	if err != nil {
+		return fmt.Errorf("save prompt %s: %w", promptID, err)
+	}
+	return nil
+}
+
+// SystemPrompts returns the IDs of the enabled system prompts
+func (m *Manager) SystemPrompts() ([]string, error) {
+	rows, err := m.engine.Query(`
+		SELECT prompt_id FROM prompts WHERE category = 'system' AND enabled = 1 ORDER BY prompt_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list prompts: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ExperimentStats aggregates, per variant, the sessions served and how
+// their replies were received
+func (m *Manager) ExperimentStats(name string) ([]VariantStats, error) {
+	e, err := m.GetExperiment(name)
+	if err != nil {
+		return nil, err
+	}
+	byVariant := make(map[string]*VariantStats, len(e.Variants))
+	stats := make([]VariantStats, len(e.Variants))
+	for i, v := range e.Variants {
+		stats[i].Variant = v
+		byVariant[v] = &stats[i]
+	}
+
+	rows, err := m.engine.Query(`
+		SELECT variant, COUNT(*) FROM experiment_sessions WHERE experiment = ? GROUP BY variant
+	`, name)
+	if err != nil {
+		return nil, fmt.Errorf("experiment stats: %w", err)
+	}
+	for rows.Next() {
+		var v string
+		var n int
+		if err := rows.Scan(&v, &n); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if s := byVariant[v]; s != nil {
+			s.Sessions = n
+		}
+	}
+	rows.Close()
+
+	rows, err = m.engine.Query(`
+		SELECT json_extract(m.metadata, '$.variant'),
+			COUNT(DISTINCT m.message_id),
+			COUNT(DISTINCT CASE WHEN f.feedback_type != ? AND f.rating > 0 THEN m.message_id END),
+			COUNT(DISTINCT CASE WHEN f.feedback_type != ? AND f.rating < 0 THEN m.message_id END),
+			COUNT(DISTINCT CASE WHEN f.feedback_type = ? THEN m.message_id END)
+		FROM messages m
+		LEFT JOIN feedback f ON f.message_id = m.message_id
+		WHERE m.role = 'assistant' AND json_extract(m.metadata, '$.experiment') = ?
+		GROUP BY 1
+	`, FeedbackUndo, FeedbackUndo, FeedbackUndo, name)
+	if err != nil {
+		return nil, fmt.Errorf("experiment stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v string
+		var replies, liked, disliked, undone int
+		if err := rows.Scan(&v, &replies, &liked, &disliked, &undone); err != nil {
+			return nil, err
+		}
+		if s := byVariant[v]; s != nil {
+			s.Replies, s.Liked, s.Disliked, s.Undone = replies, liked, disliked, undone
+		}
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,132 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func TestExperiments(t *testing.T) {
+	m := setupTestManager(t)
+
+	if variant, prompt, err := m.AssignVariant(); err != nil || variant != "" || prompt != "" {
+		t.Fatalf("AssignVariant() without an experiment = %q, %q, %v", variant, prompt, err)
+	}
+	if err := m.StartExperiment("terse", []string{ControlVariant}); err == nil {
+		t.Error("expected an error for a single variant")
+	}
+	if err := m.StartExperiment("terse", []string{ControlVariant, "missing"}); err == nil {
+		t.Error("expected an error for an unknown prompt")
+	}
+	if err := m.SaveSystemPrompt("terse_v1", "Answer in as few words as possible."); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.StartExperiment("terse", []string{ControlVariant, "terse_v1"}); err != nil {
+		t.Fatalf("StartExperiment failed: %v", err)
+	}
+
+	// Two sessions get one variant each, and keep it
+	first := m.Current()
+	v1, p1, err := m.AssignVariant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddMessage("user", "add a flag", nil)
+	m.AddMessage("assistant", "reply 1", nil)
+	m.FeedbackLastReply(1, FeedbackRating, "👍")
+
+	m.Create("cerebras")
+	v2, p2, err := m.AssignVariant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1 == v2 {
+		t.Fatalf("both sessions got %s, want one each", v1)
+	}
+	for _, a := range []struct{ variant, prompt string }{{v1, p1}, {v2, p2}} {
+		want := ""
+		if a.variant == "terse_v1" {
+			want = "Answer in as few words as possible."
+		}
+		if a.prompt != want {
+			t.Errorf("variant %s served prompt %q, want %q", a.variant, a.prompt, want)
+		}
+	}
+	m.AddMessage("user", "add a flag", nil)
+	m.AddMessage("assistant", "reply 2", nil)
+	m.FeedbackLastReply(-1, FeedbackUndo, "undone")
+	m.AddMessage("assistant", "reply 3", nil)
+
+	m.SetSession(first)
+	if v, _, _ := m.AssignVariant(); v != v1 {
+		t.Errorf("resumed session got %s, want %s", v, v1)
+	}
+
+	stats, err := m.ExperimentStats("terse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]VariantStats)
+	for _, s := range stats {
+		got[s.Variant] = s
+	}
+	if s := got[v1]; s.Sessions != 1 || s.Replies != 1 || s.Liked != 1 || s.Undone != 0 {
+		t.Errorf("stats[%s] = %+v, want 1 session, 1 liked reply", v1, s)
+	}
+	if s := got[v2]; s.Sessions != 1 || s.Replies != 2 || s.Disliked != 0 || s.Undone != 1 || s.UndoRate() != 0.5 {
+		t.Errorf("stats[%s] = %+v, want 1 session, 1 of 2 replies undone", v2, s)
+	}
+
+	// Stopping ends tagging
+	if name, err := m.StopExperiment(); err != nil || name != "terse" {
+		t.Fatalf("StopExperiment() = %q, %v", name, err)
+	}
+	if variant, _, err := m.AssignVariant(); err != nil || variant != "" {
+		t.Errorf("AssignVariant() after stop = %q, %v", variant, err)
+	}
+	if experiments, err := m.Experiments(); err != nil || len(experiments) != 1 || experiments[0].Status != ExperimentStopped {
+		t.Errorf("Experiments() = %+v, %v", experiments, err)
+	}
+}
+
+func TestExperiments_NextLaunch(t *testing.T) {
+	inTempDir(t)
+	launch := func() (*Manager, func()) {
+		engine, err := core.NewEngine("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := NewManager(engine)
+		m.Create("cerebras")
+		return m, func() { engine.Close() }
+	}
+
+	m, done := launch()
+	m.SaveSystemPrompt("terse_v1", "Answer in as few words as possible.")
+	if err := m.StartExperiment("terse", []string{ControlVariant, "terse_v1"}); err != nil {
+		t.Fatal(err)
+	}
+	v1, _, _ := m.AssignVariant()
+	m.AddMessage("assistant", "reply 1", nil)
+	m.FeedbackLastReply(-1, FeedbackUndo, "undone")
+	done()
+
+	// Each launch serves one session; the stats add them all up
+	m, done = launch()
+	defer done()
+	v2, _, err := m.AssignVariant()
+	if err != nil || v2 == "" || v2 == v1 {
+		t.Fatalf("Expected the running experiment to serve the other variant, got %q (%v)", v2, err)
+	}
+	m.AddMessage("assistant", "reply 2", nil)
+
+	stats, err := m.ExperimentStats("terse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range stats {
+		if s.Sessions != 1 || s.Replies != 1 || (s.Variant == v1) != (s.Undone == 1) {
+			t.Errorf("Unexpected stats for %s: %+v", s.Variant, s)
+		}
+	}
+}
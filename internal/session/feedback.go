@@ -0,0 +1,74 @@
+// Package session - Feedback on assistant replies, linked to the message it
+// rates so the feedback table can serve as training data
+package session
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Feedback types
+const (
+	FeedbackRating  = "rating"  // 👍/👎 without a reason
+	FeedbackComment = "comment" // Free text from /feedback
+	FeedbackUndo    = "undo"    // The user undid the change a reply made
+)
+
+// lastReplyQuery selects the latest assistant reply of a session (the
+// session ID is its parameter); changes record it as their origin
+const lastReplyQuery = `
+	SELECT message_id FROM messages
+	WHERE session_id = ? AND role = 'assistant'
+	ORDER BY created_at DESC, rowid DESC
+	LIMIT 1`
+
+// LastAssistantMessage returns the ID of the current session's latest
+// assistant reply, "" when the model has not answered yet
+func (m *Manager) LastAssistantMessage() (string, error) {
+	var messageID string
+	err := m.engine.QueryRow(lastReplyQuery, m.sessionID).Scan(&messageID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("find last reply: %w", err)
+	}
+	return messageID, nil
+}
+
+// Exchange returns an assistant reply and the user message it answered
+func (m *Manager) Exchange(messageID string) (prompt, response string, err error) {
+	var sessionID string
+	var rowID int64
+	err = m.engine.QueryRow(`
+		SELECT session_id, content, rowid FROM messages WHERE message_id = ?
+	`, messageID).Scan(&sessionID, &response, &rowID)
+	if err != nil {
+		return "", "", fmt.Errorf("find reply %s: %w", messageID, err)
+	}
+	err = m.engine.QueryRow(`
+		SELECT content FROM messages
+		WHERE session_id = ? AND role = 'user' AND rowid < ?
+		ORDER BY rowid DESC LIMIT 1
+	`, sessionID, rowID).Scan(&prompt)
+	if err != nil && err != sql.ErrNoRows {
+		return "", "", fmt.Errorf("find request of %s: %w", messageID, err)
+	}
+	return prompt, response, nil
+}
+
+// FeedbackLastReply records feedback on the latest assistant reply and
+// returns its message ID
+func (m *Manager) FeedbackLastReply(rating int, feedbackType, content string) (string, error) {
+	messageID, err := m.LastAssistantMessage()
+	if err != nil {
+		return "", err
+	}
+	if messageID == "" {
+		return "", fmt.Errorf("no reply to give feedback on yet")
+	}
+	if err := m.RecordFeedback(messageID, rating, feedbackType, content); err != nil {
+		return "", fmt.Errorf("record feedback: %w", err)
+	}
+	return messageID, nil
+}
@@ -0,0 +1,56 @@
+package session
+
+import "testing"
+
+func TestFeedbackLastReply(t *testing.T) {
+	m := setupTestManager(t)
+
+	if _, err := m.FeedbackLastReply(1, FeedbackRating, "👍"); err == nil {
+		t.Error("expected an error before any reply")
+	}
+
+	m.AddMessage("user", "add a flag", nil)
+	m.AddMessage("assistant", "first reply", nil)
+	m.AddMessage("user", "now test it", nil)
+	m.AddMessage("assistant", "second reply", nil)
+
+	id, err := m.FeedbackLastReply(-1, FeedbackComment, "the test never runs")
+	if err != nil {
+		t.Fatalf("FeedbackLastReply failed: %v", err)
+	}
+
+	var content string
+	var rating int
+	if err := m.engine.QueryRow(`
+		SELECT m.content, f.rating FROM feedback f JOIN messages m ON m.message_id = f.message_id
+		WHERE f.message_id = ? AND f.feedback_type = ?
+	`, id, FeedbackComment).Scan(&content, &rating); err != nil {
+		t.Fatal(err)
+	}
+	if content != "second reply" || rating != -1 {
+		t.Errorf("feedback linked to %q with rating %d, want \"second reply\" and -1", content, rating)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	m := setupTestManager(t)
+
+	m.AddMessage("user", "add a flag", nil)
+	m.AddMessage("assistant", "added --verbose", nil)
+	m.RecordFileChange("main.go", "modify", "a", "b", "")
+	m.RecordGitCommit("abc123", "GoClode: update main.go", 1)
+
+	changes, err := m.LastBatch(false)
+	if err != nil || len(changes) != 1 || changes[0].MessageID == "" {
+		t.Fatalf("LastBatch() = %+v, %v; want the change linked to the reply", changes, err)
+	}
+	commits, err := m.SessionCommits()
+	if err != nil || len(commits) != 1 || commits[0].MessageID != changes[0].MessageID {
+		t.Fatalf("SessionCommits() = %+v, %v; want the commit linked to the reply", commits, err)
+	}
+
+	prompt, response, err := m.Exchange(changes[0].MessageID)
+	if err != nil || prompt != "add a flag" || response != "added --verbose" {
+		t.Errorf("Exchange() = %q, %q, %v", prompt, response, err)
+	}
+}
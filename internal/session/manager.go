@@ -17,6 +17,10 @@ type Manager struct {
 	engine    *core.Engine
 	sessionID string
 	provider  string
+	batchID   string // Groups file changes applied together
+
+	experiment string // Experiment and variant tagged on new messages
+	variant    string
 }
 
 // Session represents a conversation session
@@ -65,6 +69,7 @@ func (m *Manager) Create(providerID string) (*Session, error) {
 
 	m.sessionID = sessionID
 	m.provider = providerID
+	m.experiment, m.variant = "", ""
 
 	return &Session{
 		ID:           sessionID,
@@ -92,6 +97,7 @@ func (m *Manager) SetSession(sessionID string) error {
 	}
 
 	m.sessionID = sessionID
+	m.experiment, m.variant = "", ""
 	return nil
 }
 
@@ -117,9 +123,9 @@ func (m *Manager) AddMessage(role, content string, resp *providers.Response) err
 	}
 
 	_, err := m.engine.Exec(`
-		INSERT INTO messages (message_id, session_id, role, content, provider_id, model, tokens_in, tokens_out, latency_ms)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, messageID, m.sessionID, role, content, providerID, model, tokensIn, tokensOut, latencyMs)
+		INSERT INTO messages (message_id, session_id, role, content, provider_id, model, tokens_in, tokens_out, latency_ms, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, messageID, m.sessionID, role, content, providerID, model, tokensIn, tokensOut, latencyMs, m.messageMetadata(resp))
 
 	if err != nil {
 		return fmt.Errorf("add message: %w", err)
@@ -133,6 +139,21 @@ func (m *Manager) AddMessage(role, content string, resp *providers.Response) err
 	return nil
 }
 
+// messageMetadata is the metadata stored with a new message: the
+// experiment variant the session is served, if any, and why a reply was
+// cut short
+func (m *Manager) messageMetadata(resp *providers.Response) string {
+	meta := make(map[string]string)
+	if m.experiment != "" {
+		meta["experiment"], meta["variant"] = m.experiment, m.variant
+	}
+	if resp != nil && resp.Truncated() {
+		meta["truncated"] = resp.FinishReason
+	}
+	data, _ := json.Marshal(meta)
+	return string(data)
+}
+
 // GetMessages returns all messages for the current session
 func (m *Manager) GetMessages(limit int) ([]Message, error) {
 	if m.sessionID == "" {
@@ -174,6 +195,46 @@ func (m *Manager) GetMessages(limit int) ([]Message, error) {
 	return messages, nil
 }
 
+// LastMessages returns the latest messages of the current session, oldest
+// first
+func (m *Manager) LastMessages(limit int) ([]Message, error) {
+	if m.sessionID == "" {
+		return nil, fmt.Errorf("no active session")
+	}
+
+	rows, err := m.engine.Query(`
+		SELECT message_id, session_id, role, content, provider_id, model,
+			   tokens_in, tokens_out, latency_ms, created_at
+		FROM (
+			SELECT message_id, session_id, role, content,
+				   COALESCE(provider_id, '') AS provider_id, COALESCE(model, '') AS model,
+				   tokens_in, tokens_out, latency_ms, created_at, rowid AS seq
+			FROM messages
+			WHERE session_id = ?
+			ORDER BY created_at DESC, rowid DESC
+			LIMIT ?
+		)
+		ORDER BY created_at ASC, seq ASC
+	`, m.sessionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]Message, 0)
+	for rows.Next() {
+		var msg Message
+		var createdAt int64
+		if err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Role, &msg.Content,
+			&msg.Provider, &msg.Model, &msg.TokensIn, &msg.TokensOut, &msg.LatencyMs, &createdAt); err != nil {
+			return nil, err
+		}
+		msg.CreatedAt = time.Unix(createdAt, 0)
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
 // GetContextMessages returns recent messages for LLM context
 func (m *Manager) GetContextMessages(maxMessages int) ([]providers.Message, error) {
 	messages, err := m.GetMessages(maxMessages)
@@ -202,10 +263,14 @@ func (m *Manager) RecordFileChange(filePath, operation, contentBefore, contentAf
 
 	fileID := uuid.New().String()
 
+	if m.batchID == "" {
+		m.BeginBatch()
+	}
+
 	_, err := m.engine.Exec(`
-		INSERT INTO files_modified (file_id, session_id, file_path, operation, content_before, content_after, diff)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, fileID, m.sessionID, filePath, operation, contentBefore, contentAfter, diff)
+		INSERT INTO files_modified (file_id, session_id, message_id, file_path, operation, content_before, content_after, diff, batch_id)
+		VALUES (?, ?, (`+lastReplyQuery+`), ?, ?, ?, ?, ?, ?)
+	`, fileID, m.sessionID, m.sessionID, filePath, operation, contentBefore, contentAfter, diff, m.batchID)
 
 	return err
 }
@@ -219,10 +284,33 @@ func (m *Manager) RecordGitCommit(gitHash, message string, filesChanged int) err
 	commitID := uuid.New().String()
 
 	_, err := m.engine.Exec(`
-		INSERT INTO git_commits (commit_id, session_id, git_hash, commit_message, files_changed)
-		VALUES (?, ?, ?, ?, ?)
-	`, commitID, m.sessionID, gitHash, message, filesChanged)
+		INSERT INTO git_commits (commit_id, session_id, message_id, git_hash, commit_message, files_changed)
+		VALUES (?, ?, (`+lastReplyQuery+`), ?, ?, ?)
+	`, commitID, m.sessionID, m.sessionID, gitHash, message, filesChanged)
+	if err != nil {
+		return err
+	}
+
+	// A new commit ends the redo history
+	_, err = m.engine.Exec(`UPDATE git_commits SET reverted_at = NULL WHERE reverted_at IS NOT NULL`)
+	return err
+}
+
+// SetCommitStats stores a commit's churn
+func (m *Manager) SetCommitStats(gitHash string, files, insertions, deletions int) error {
+	_, err := m.engine.Exec(`
+		UPDATE git_commits SET files_changed = ?, insertions = ?, deletions = ?
+		WHERE git_hash = ? AND session_id = ?
+	`, files, insertions, deletions, gitHash, m.sessionID)
+	return err
+}
 
+// AmendGitCommit records that a commit was amended into a new hash
+func (m *Manager) AmendGitCommit(oldHash, newHash, message string, filesChanged int) error {
+	_, err := m.engine.Exec(`
+		UPDATE git_commits SET git_hash = ?, commit_message = ?, files_changed = files_changed + ?
+		WHERE git_hash = ? AND session_id = ?
+	`, newHash, message, filesChanged, oldHash, m.sessionID)
 	return err
 }
 
@@ -268,9 +356,14 @@ func (m *Manager) GetStats() (map[string]interface{}, error) {
 	stats["files_modified"] = fileCount
 
 	// Commits
-	var commitCount int
-	m.engine.QueryRow("SELECT COUNT(*) FROM git_commits WHERE session_id = ?", m.sessionID).Scan(&commitCount)
+	var commitCount, insertions, deletions int
+	m.engine.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(insertions), 0), COALESCE(SUM(deletions), 0)
+		FROM git_commits WHERE session_id = ?
+	`, m.sessionID).Scan(&commitCount, &insertions, &deletions)
 	stats["commits"] = commitCount
+	stats["insertions"] = insertions
+	stats["deletions"] = deletions
 
 	return stats, nil
 }
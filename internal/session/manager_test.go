@@ -0,0 +1,28 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLastMessages(t *testing.T) {
+	m := setupTestManager(t)
+	for i := 0; i < 5; i++ {
+		if err := m.AddMessage("user", fmt.Sprintf("message %d", i), nil); err != nil {
+			t.Fatalf("AddMessage failed: %v", err)
+		}
+	}
+
+	messages, err := m.LastMessages(3)
+	if err != nil {
+		t.Fatalf("LastMessages failed: %v", err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	for i, msg := range messages {
+		if want := fmt.Sprintf("message %d", i+2); msg.Content != want {
+			t.Errorf("Message %d: expected %q, got %q", i, want, msg.Content)
+		}
+	}
+}
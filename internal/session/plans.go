@@ -0,0 +1,151 @@
+// Package session - Agent plans: a goal broken into steps, persisted so an
+// interrupted /agent run can resume after a restart
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Plan statuses
+const (
+	PlanActive  = "active"
+	PlanDone    = "done"
+	PlanStopped = "stopped"
+)
+
+// Step statuses
+const (
+	StepPending = "pending"
+	StepRunning = "running"
+	StepDone    = "done"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// PlanStep is one step of an agent plan
+type PlanStep struct {
+	Index       int // 1-based
+	Description string
+	Status      string
+	Result      string
+}
+
+// Plan is an agent goal and its steps
+type Plan struct {
+	ID        string
+	Goal      string
+	Status    string
+	Steps     []PlanStep
+	CreatedAt time.Time
+}
+
+// Next returns the first step still to run (pending, interrupted, or
+// failed), or nil when the plan is finished
+func (p *Plan) Next() *PlanStep {
+	for i := range p.Steps {
+		switch p.Steps[i].Status {
+		case StepDone, StepSkipped:
+			continue
+		}
+		return &p.Steps[i]
+	}
+	return nil
+}
+
+// CreatePlan records a new active plan for the current session
+func (m *Manager) CreatePlan(goal string, steps []string) (*Plan, error) {
+	if m.sessionID == "" {
+		return nil, fmt.Errorf("no active session")
+	}
+
+	plan := &Plan{ID: uuid.New().String(), Goal: goal, Status: PlanActive, CreatedAt: time.Now()}
+	tx, err := m.engine.DB().Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO agent_plans (plan_id, session_id, goal) VALUES (?, ?, ?)
+	`, plan.ID, m.sessionID, goal); err != nil {
+		return nil, fmt.Errorf("create plan: %w", err)
+	}
+	for i, description := range steps {
+		step := PlanStep{Index: i + 1, Description: description, Status: StepPending}
+		if _, err := tx.Exec(`
+			INSERT INTO agent_steps (plan_id, step, description) VALUES (?, ?, ?)
+		`, plan.ID, step.Index, description); err != nil {
+			return nil, fmt.Errorf("create plan: %w", err)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ActivePlan returns the most recent unfinished plan of any session, or
+// sql.ErrNoRows when there is none
+func (m *Manager) ActivePlan() (*Plan, error) {
+	plan := &Plan{}
+	var createdAt int64
+	err := m.engine.QueryRow(`
+		SELECT plan_id, goal, status, created_at FROM agent_plans
+		WHERE status = ? ORDER BY updated_at DESC, rowid DESC LIMIT 1
+	`, PlanActive).Scan(&plan.ID, &plan.Goal, &plan.Status, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	plan.CreatedAt = time.Unix(createdAt, 0)
+
+	rows, err := m.engine.Query(`
+		SELECT step, description, status, COALESCE(result, '') FROM agent_steps
+		WHERE plan_id = ? ORDER BY step
+	`, plan.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var step PlanStep
+		if err := rows.Scan(&step.Index, &step.Description, &step.Status, &step.Result); err != nil {
+			return nil, err
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan, rows.Err()
+}
+
+// SetStepStatus records a step's progress and result
+func (m *Manager) SetStepStatus(planID string, step int, status, result string) error {
+	_, err := m.engine.Exec(`
+		UPDATE agent_steps SET status = ?, result = ? WHERE plan_id = ? AND step = ?
+	`, status, result, planID, step)
+	if err != nil {
+		return fmt.Errorf("update step: %w", err)
+	}
+	return m.touchPlan(planID, "")
+}
+
+// SetPlanStatus marks a plan done or stopped
+func (m *Manager) SetPlanStatus(planID, status string) error {
+	return m.touchPlan(planID, status)
+}
+
+// touchPlan bumps a plan's updated_at, setting its status when given
+func (m *Manager) touchPlan(planID, status string) error {
+	var err error
+	if status == "" {
+		_, err = m.engine.Exec(`UPDATE agent_plans SET updated_at = ? WHERE plan_id = ?`, time.Now().Unix(), planID)
+	} else {
+		_, err = m.engine.Exec(`UPDATE agent_plans SET status = ?, updated_at = ? WHERE plan_id = ?`, status, time.Now().Unix(), planID)
+	}
+	if err != nil {
+		return fmt.Errorf("update plan: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func TestPlans(t *testing.T) {
+	m := setupTestManager(t)
+
+	if _, err := m.ActivePlan(); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("ActivePlan() with no plans: err = %v, want sql.ErrNoRows", err)
+	}
+
+	created, err := m.CreatePlan("add a flag", []string{"parse it", "use it", "test it"})
+	if err != nil {
+		t.Fatalf("CreatePlan failed: %v", err)
+	}
+	if err := m.SetStepStatus(created.ID, 1, StepDone, "added parsing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetStepStatus(created.ID, 2, StepFailed, "build error"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart opens a new session; the plan is still found
+	if _, err := m.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := m.ActivePlan()
+	if err != nil {
+		t.Fatalf("ActivePlan failed: %v", err)
+	}
+	if plan.ID != created.ID || plan.Goal != "add a flag" || len(plan.Steps) != 3 {
+		t.Fatalf("ActivePlan() = %+v", plan)
+	}
+	if s := plan.Steps[0]; s.Status != StepDone || s.Result != "added parsing" {
+		t.Errorf("step 1 = %+v", s)
+	}
+	if next := plan.Next(); next == nil || next.Index != 2 {
+		t.Errorf("Next() = %+v, want the failed step 2", next)
+	}
+
+	plan.Steps[1].Status = StepDone
+	plan.Steps[2].Status = StepSkipped
+	if next := plan.Next(); next != nil {
+		t.Errorf("Next() on a finished plan = %+v", next)
+	}
+
+	if err := m.SetPlanStatus(plan.ID, PlanDone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ActivePlan(); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("ActivePlan() after done: err = %v", err)
+	}
+}
+
+func TestPlans_NextLaunch(t *testing.T) {
+	inTempDir(t)
+	first, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(first)
+	m.Create("cerebras")
+	plan, err := m.CreatePlan("add a --json flag", []string{"parse it", "print JSON"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	second, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	m = NewManager(second)
+	m.Create("cerebras")
+	resumed, err := m.ActivePlan()
+	if err != nil || resumed.ID != plan.ID || len(resumed.Steps) != 2 {
+		t.Errorf("Expected /agent resume to find the plan after a restart, got %+v (%v)", resumed, err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package session - The prompts table: stored templates /prompt lists,
+// edits, and fills
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hazyhaar/GoClode/internal/prompt"
+)
+
+// Prompt is a stored prompt template
+type Prompt struct {
+	ID        string
+	Name      string
+	Template  string
+	Category  string
+	Variables []string // Read from the template
+	Enabled   bool
+	Version   int
+	UpdatedAt int64
+}
+
+// Prompts returns the stored prompts by category, then ID
+func (m *Manager) Prompts() ([]Prompt, error) {
+	rows, err := m.engine.Query(`
+		SELECT prompt_id, name, template, category, enabled, version, updated_at
+		FROM prompts ORDER BY category, prompt_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list prompts: %w", err)
+	}
+	defer rows.Close()
+
+	prompts := make([]Prompt, 0)
+	for rows.Next() {
+		p, err := scanPrompt(rows)
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, *p)
+	}
+	return prompts, rows.Err()
+}
+
+// GetPrompt finds a prompt by ID or name
+func (m *Manager) GetPrompt(idOrName string) (*Prompt, error) {
+	p, err := scanPrompt(m.engine.QueryRow(`
+		SELECT prompt_id, name, template, category, enabled, version, updated_at
+		FROM prompts WHERE prompt_id = ?1 OR name = ?1 ORDER BY prompt_id = ?1 DESC LIMIT 1
+	`, idOrName))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no prompt %q (/prompt list)", idOrName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find prompt %s: %w", idOrName, err)
+	}
+	return p, nil
+}
+
+// SavePrompt adds a prompt, or replaces the template of an existing one
+// and bumps its version. The template must parse.
+func (m *Manager) SavePrompt(promptID, template string) error {
+	t, err := prompt.Parse(template)
+	if err != nil {
+		return err
+	}
+	variables, _ := json.Marshal(t.Variables())
+	_, err = m.engine.Exec(`
+		INSERT INTO prompts (prompt_id, name, template, variables) VALUES (?1, ?1, ?2, ?3)
+		ON CONFLICT(prompt_id) DO UPDATE SET
+			template = excluded.template, variables = excluded.variables,
+			version = version + 1, updated_at = strftime('%s', 'now')
+	`, promptID, template, string(variables))
+	if err != nil {
+		return fmt.Errorf("save prompt %s: %w", promptID, err)
+	}
+	return nil
+}
+
+// scanPrompt reads a prompt row
+func scanPrompt(row interface{ Scan(...interface{}) error }) (*Prompt, error) {
+	var p Prompt
+	var updated sql.NullInt64
+	if err := row.Scan(&p.ID, &p.Name, &p.Template, &p.Category, &p.Enabled, &p.Version, &updated); err != nil {
+		return nil, err
+	}
+	p.UpdatedAt = updated.Int64
+	p.Variables = make([]string, 0)
+	if t, err := prompt.Parse(p.Template); err == nil {
+		p.Variables = t.Variables()
+	}
+	return &p, nil
+}
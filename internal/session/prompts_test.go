@@ -0,0 +1,44 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrompts(t *testing.T) {
+	m := setupTestManager(t)
+
+	p, err := m.GetPrompt("Code Review")
+	if err != nil || p.ID != "code_review" || !reflect.DeepEqual(p.Variables, []string{"code"}) {
+		t.Fatalf("GetPrompt by name = %+v, %v", p, err)
+	}
+	if _, err := m.GetPrompt("missing"); err == nil {
+		t.Error("Expected an error for an unknown prompt")
+	}
+
+	if err := m.SavePrompt("tests", "Write tests for {{#file}}"); err == nil {
+		t.Error("Expected an unclosed section to be refused")
+	}
+	if err := m.SavePrompt("tests", "Write tests for {{file}}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SavePrompt("tests", "Write table tests for {{file}}{{#focus}}, covering {{focus}}{{/focus}}"); err != nil {
+		t.Fatal(err)
+	}
+	p, err = m.GetPrompt("tests")
+	if err != nil || p.Version != 2 || p.Category != "general" || !reflect.DeepEqual(p.Variables, []string{"file", "focus"}) {
+		t.Errorf("Unexpected saved prompt %+v (%v)", p, err)
+	}
+
+	prompts, err := m.Prompts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, p := range prompts {
+		found = found || p.ID == "tests"
+	}
+	if !found {
+		t.Errorf("Saved prompt not listed in %+v", prompts)
+	}
+}
@@ -0,0 +1,86 @@
+// Package session - The pending_requests table: prompts queued while no
+// provider is reachable, sent in order once one is
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// PendingRequest is a queued prompt
+type PendingRequest struct {
+	ID        int64
+	SessionID string
+	Prompt    string
+	CreatedAt time.Time
+}
+
+// QueueRequest queues a prompt of the current session and returns how
+// many are pending
+func (m *Manager) QueueRequest(prompt string) (int, error) {
+	_, err := m.engine.Exec(`INSERT INTO pending_requests (session_id, prompt) VALUES (?, ?)`, m.sessionID, prompt)
+	if err != nil {
+		return 0, fmt.Errorf("queue request: %w", err)
+	}
+	var n int
+	if err := m.engine.QueryRow(`SELECT COUNT(*) FROM pending_requests`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count pending requests: %w", err)
+	}
+	return n, nil
+}
+
+// PendingRequests returns the queued prompts of every session, oldest first
+func (m *Manager) PendingRequests() ([]PendingRequest, error) {
+	rows, err := m.engine.Query(`
+		SELECT id, COALESCE(session_id, ''), prompt, created_at
+		FROM pending_requests ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list pending requests: %w", err)
+	}
+	defer rows.Close()
+
+	pending := make([]PendingRequest, 0)
+	for rows.Next() {
+		var r PendingRequest
+		var created int64
+		if err := rows.Scan(&r.ID, &r.SessionID, &r.Prompt, &created); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = time.Unix(created, 0)
+		pending = append(pending, r)
+	}
+	return pending, rows.Err()
+}
+
+// RemovePendingRequest takes a prompt off the queue
+func (m *Manager) RemovePendingRequest(id int64) error {
+	if _, err := m.engine.Exec(`DELETE FROM pending_requests WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("remove pending request %d: %w", id, err)
+	}
+	return nil
+}
+
+// ClearPendingRequests empties the queue and returns how many prompts it
+// dropped
+func (m *Manager) ClearPendingRequests() (int64, error) {
+	n, err := m.engine.Exec(`DELETE FROM pending_requests`)
+	if err != nil {
+		return 0, fmt.Errorf("clear pending requests: %w", err)
+	}
+	return n, nil
+}
+
+// DropUnansweredMessage removes the session's last message if it is the
+// user's, for a request that never reached the model
+func (m *Manager) DropUnansweredMessage() error {
+	_, err := m.engine.Exec(`
+		DELETE FROM messages WHERE rowid = (
+			SELECT rowid FROM messages WHERE session_id = ? ORDER BY rowid DESC LIMIT 1
+		) AND role = 'user'
+	`, m.sessionID)
+	if err != nil {
+		return fmt.Errorf("drop unanswered message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package session
+
+import "testing"
+
+func TestPendingRequests(t *testing.T) {
+	m := setupTestManager(t)
+
+	for i, prompt := range []string{"add a test", "fix the bug"} {
+		n, err := m.QueueRequest(prompt)
+		if err != nil || n != i+1 {
+			t.Fatalf("QueueRequest = %d, %v; want %d", n, err, i+1)
+		}
+	}
+	pending, err := m.PendingRequests()
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("PendingRequests = %+v, %v", pending, err)
+	}
+	if pending[0].Prompt != "add a test" || pending[0].SessionID != m.Current() {
+		t.Errorf("Expected the oldest first, got %+v", pending[0])
+	}
+
+	if err := m.RemovePendingRequest(pending[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := m.PendingRequests(); len(pending) != 1 || pending[0].Prompt != "fix the bug" {
+		t.Errorf("Expected one request left, got %+v", pending)
+	}
+	if n, err := m.ClearPendingRequests(); err != nil || n != 1 {
+		t.Errorf("ClearPendingRequests = %d, %v", n, err)
+	}
+}
+
+func TestDropUnansweredMessage(t *testing.T) {
+	m := setupTestManager(t)
+	m.AddMessage("user", "first", nil)
+	m.AddMessage("assistant", "reply", nil)
+
+	if err := m.DropUnansweredMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if msgs, _ := m.GetMessages(10); len(msgs) != 2 {
+		t.Fatalf("Expected an answered message kept, got %d messages", len(msgs))
+	}
+
+	m.AddMessage("user", "unsent", nil)
+	m.DropUnansweredMessage()
+	msgs, _ := m.GetMessages(10)
+	if len(msgs) != 2 || msgs[len(msgs)-1].Content != "reply" {
+		t.Errorf("Expected the unsent message dropped, got %+v", msgs)
+	}
+}
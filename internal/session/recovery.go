@@ -0,0 +1,83 @@
+// Package session - Crash recovery: marking a session that panicked with
+// its stack trace and the request in flight, so the next start can resume it
+package session
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// maxCrashStack caps the stack trace kept in a session's metadata
+const maxCrashStack = 16 << 10
+
+// Crash is what was recorded about a session that crashed
+type Crash struct {
+	SessionID string
+	At        time.Time
+	Error     string
+	Stack     string
+	Input     string // Request being handled when it crashed
+}
+
+// MarkCrashed records in the current session's metadata that it crashed,
+// then flushes the WAL so the record survives the exit
+func (m *Manager) MarkCrashed(reason, stack, input string) error {
+	if m.sessionID == "" {
+		return fmt.Errorf("no session")
+	}
+	if len(stack) > maxCrashStack {
+		stack = stack[:maxCrashStack] + "\n..."
+	}
+	_, err := m.engine.Exec(`
+		UPDATE sessions SET metadata = json_set(COALESCE(metadata, '{}'),
+			'$.crashed_at', ?, '$.crash_error', ?, '$.crash_stack', ?, '$.crash_input', ?)
+		WHERE session_id = ?
+	`, strconv.FormatInt(time.Now().Unix(), 10), reason, stack, input, m.sessionID)
+	if err != nil {
+		return fmt.Errorf("mark crashed: %w", err)
+	}
+	return m.engine.Checkpoint()
+}
+
+// CrashedSessions lists the crashed sessions not recovered yet, newest first
+func (m *Manager) CrashedSessions() ([]Crash, error) {
+	rows, err := m.engine.Query(`
+		SELECT session_id, json_extract(metadata, '$.crashed_at'),
+			COALESCE(json_extract(metadata, '$.crash_error'), ''),
+			COALESCE(json_extract(metadata, '$.crash_stack'), ''),
+			COALESCE(json_extract(metadata, '$.crash_input'), '')
+		FROM sessions
+		WHERE json_extract(metadata, '$.crashed_at') IS NOT NULL
+		  AND json_extract(metadata, '$.recovered_at') IS NULL
+		ORDER BY CAST(json_extract(metadata, '$.crashed_at') AS INTEGER) DESC, rowid DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	crashes := make([]Crash, 0)
+	for rows.Next() {
+		var c Crash
+		var at string
+		if err := rows.Scan(&c.SessionID, &at, &c.Error, &c.Stack, &c.Input); err != nil {
+			return nil, err
+		}
+		if unix, err := strconv.ParseInt(at, 10, 64); err == nil {
+			c.At = time.Unix(unix, 0)
+		}
+		crashes = append(crashes, c)
+	}
+	return crashes, rows.Err()
+}
+
+// MarkRecovered records that a crashed session was resumed, so it is no
+// longer offered
+func (m *Manager) MarkRecovered(sessionID string) error {
+	_, err := m.engine.Exec(`
+		UPDATE sessions SET metadata = json_set(COALESCE(metadata, '{}'), '$.recovered_at', ?)
+		WHERE session_id = ?
+	`, strconv.FormatInt(time.Now().Unix(), 10), sessionID)
+	return err
+}
@@ -0,0 +1,94 @@
+package session
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func TestCrashRecovery(t *testing.T) {
+	m := setupTestManager(t)
+	crashed := m.Current()
+
+	if crashes, err := m.CrashedSessions(); err != nil || len(crashes) != 0 {
+		t.Fatalf("Expected no crashes, got %v (%v)", crashes, err)
+	}
+	if err := m.MarkCrashed("runtime error: nil map", strings.Repeat("goroutine 1\n", 5000), "refactor main.go"); err != nil {
+		t.Fatalf("MarkCrashed failed: %v", err)
+	}
+	if _, err := m.Create("cerebras"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	crashes, err := m.CrashedSessions()
+	if err != nil || len(crashes) != 1 {
+		t.Fatalf("Expected 1 crash, got %v (%v)", crashes, err)
+	}
+	c := crashes[0]
+	if c.SessionID != crashed || c.Error != "runtime error: nil map" || c.Input != "refactor main.go" || c.At.IsZero() {
+		t.Errorf("Unexpected crash: %+v", c)
+	}
+	if len(c.Stack) > maxCrashStack+10 || !strings.HasPrefix(c.Stack, "goroutine 1") {
+		t.Errorf("Expected a capped stack, got %d bytes", len(c.Stack))
+	}
+
+	sessions, err := m.ListSessions(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range sessions {
+		if s.ID == crashed && s.Metadata["crash_error"] != "runtime error: nil map" {
+			t.Errorf("Expected the crash in the session metadata, got %v", s.Metadata)
+		}
+	}
+
+	if err := m.MarkRecovered(crashed); err != nil {
+		t.Fatalf("MarkRecovered failed: %v", err)
+	}
+	if crashes, _ := m.CrashedSessions(); len(crashes) != 0 {
+		t.Errorf("Expected no crash left to recover, got %v", crashes)
+	}
+}
+
+// inTempDir runs the rest of the test in a new directory, where
+// core.NewEngine("") opens the default database as goclode does without --db
+func inTempDir(t *testing.T) {
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestCrashRecovery_NextLaunch(t *testing.T) {
+	inTempDir(t)
+	first, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(first)
+	if _, err := m.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	crashed := m.Current()
+	if err := m.MarkCrashed("runtime error: nil map", "goroutine 1", "refactor main.go"); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	second, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	m = NewManager(second)
+	if _, err := m.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	crashes, err := m.CrashedSessions()
+	if err != nil || len(crashes) != 1 || crashes[0].SessionID != crashed {
+		t.Errorf("Expected the next launch to offer the crashed session, got %v (%v)", crashes, err)
+	}
+}
@@ -0,0 +1,149 @@
+// Package session - File change snapshots for undo/redo without git
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileChange is a recorded file modification with its before/after content
+type FileChange struct {
+	ID            string
+	SessionID     string
+	Path          string
+	Operation     string // create, modify, delete, rename
+	ContentBefore string
+	ContentAfter  string
+	Diff          string
+	BatchID       string
+	MessageID     string // Assistant reply the change came from
+	Undone        bool
+	CreatedAt     time.Time
+}
+
+// BeginBatch starts a new group of file changes; /undo last reverts a whole batch
+func (m *Manager) BeginBatch() {
+	m.batchID = uuid.New().String()
+}
+
+const fileChangeColumns = `file_id, session_id, file_path, operation, COALESCE(content_before, ''),
+	COALESCE(content_after, ''), COALESCE(diff, ''), COALESCE(batch_id, file_id), COALESCE(message_id, ''),
+	undone_at IS NOT NULL, created_at`
+
+func scanFileChanges(rows *sql.Rows) ([]FileChange, error) {
+	defer rows.Close()
+
+	changes := make([]FileChange, 0)
+	for rows.Next() {
+		var fc FileChange
+		var createdAt int64
+		if err := rows.Scan(&fc.ID, &fc.SessionID, &fc.Path, &fc.Operation, &fc.ContentBefore,
+			&fc.ContentAfter, &fc.Diff, &fc.BatchID, &fc.MessageID, &fc.Undone, &createdAt); err != nil {
+			return nil, err
+		}
+		fc.CreatedAt = time.Unix(createdAt, 0)
+		changes = append(changes, fc)
+	}
+	return changes, rows.Err()
+}
+
+// UpdateContentAfter replaces the recorded result of path in the current
+// batch, e.g. after a formatter rewrote the file
+func (m *Manager) UpdateContentAfter(path, content string) error {
+	if m.batchID == "" {
+		return nil
+	}
+	_, err := m.engine.Exec(`
+		UPDATE files_modified SET content_after = ?
+		WHERE batch_id = ? AND file_path = ? AND operation IN ('create', 'modify')
+	`, content, m.batchID, path)
+	return err
+}
+
+// LastBatch returns the changes of the most recent batch that can be undone
+// (undone=false) or redone (undone=true), in the order they were applied
+func (m *Manager) LastBatch(undone bool) ([]FileChange, error) {
+	var batchID string
+	var err error
+	if undone {
+		err = m.engine.QueryRow(`
+			SELECT COALESCE(batch_id, file_id) FROM files_modified
+			WHERE undone_at IS NOT NULL
+			ORDER BY undone_at DESC, rowid DESC LIMIT 1
+		`).Scan(&batchID)
+	} else {
+		err = m.engine.QueryRow(`
+			SELECT COALESCE(batch_id, file_id) FROM files_modified
+			WHERE undone_at IS NULL
+			ORDER BY created_at DESC, rowid DESC LIMIT 1
+		`).Scan(&batchID)
+	}
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("nothing to %s", undoVerb(undone))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.engine.Query(`
+		SELECT `+fileChangeColumns+` FROM files_modified
+		WHERE COALESCE(batch_id, file_id) = ? AND (undone_at IS NOT NULL) = ?
+		ORDER BY rowid
+	`, batchID, undone)
+	if err != nil {
+		return nil, err
+	}
+	return scanFileChanges(rows)
+}
+
+// LastFileChange returns the most recent change to path that can be undone
+// (undone=false) or redone (undone=true)
+func (m *Manager) LastFileChange(path string, undone bool) (*FileChange, error) {
+	order := "created_at DESC, rowid DESC"
+	if undone {
+		order = "undone_at DESC, rowid DESC"
+	}
+
+	rows, err := m.engine.Query(`
+		SELECT `+fileChangeColumns+` FROM files_modified
+		WHERE file_path = ? AND (undone_at IS NOT NULL) = ?
+		ORDER BY `+order+` LIMIT 1
+	`, path, undone)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := scanFileChanges(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("nothing to %s for %s", undoVerb(undone), path)
+	}
+	return &changes[0], nil
+}
+
+// MarkUndone flags changes as undone, or clears the flag after a redo
+func (m *Manager) MarkUndone(ids []string, undone bool) error {
+	for _, id := range ids {
+		var err error
+		if undone {
+			_, err = m.engine.Exec(`UPDATE files_modified SET undone_at = ? WHERE file_id = ?`, time.Now().UnixNano(), id)
+		} else {
+			_, err = m.engine.Exec(`UPDATE files_modified SET undone_at = NULL WHERE file_id = ?`, id)
+		}
+		if err != nil {
+			return fmt.Errorf("mark %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func undoVerb(undone bool) string {
+	if undone {
+		return "redo"
+	}
+	return "undo"
+}
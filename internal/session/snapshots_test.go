@@ -0,0 +1,73 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func setupTestManager(t *testing.T) *Manager {
+	t.Helper()
+	engine, err := core.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	t.Cleanup(func() { engine.Close() })
+
+	m := NewManager(engine)
+	if _, err := m.Create("cerebras"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return m
+}
+
+func TestSnapshots_UndoRedoStack(t *testing.T) {
+	m := setupTestManager(t)
+
+	if _, err := m.LastBatch(false); err == nil {
+		t.Error("LastBatch on empty history should fail")
+	}
+
+	m.BeginBatch()
+	m.RecordFileChange("a.go", "create", "", "a1", "")
+	m.RecordFileChange("b.go", "modify", "b0", "b1", "")
+	m.BeginBatch()
+	m.RecordFileChange("a.go", "modify", "a1", "a2", "")
+	m.UpdateContentAfter("a.go", "a2 formatted")
+
+	// Latest batch first
+	batch, err := m.LastBatch(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 1 || batch[0].ContentAfter != "a2 formatted" {
+		t.Fatalf("LastBatch = %+v", batch)
+	}
+	m.MarkUndone([]string{batch[0].ID}, true)
+
+	// Repeated undo walks back to the previous batch
+	batch, _ = m.LastBatch(false)
+	if len(batch) != 2 || batch[0].Path != "a.go" || batch[1].Path != "b.go" {
+		t.Fatalf("second LastBatch = %+v", batch)
+	}
+	m.MarkUndone([]string{batch[0].ID, batch[1].ID}, true)
+
+	// Redo takes the most recently undone batch
+	redo, err := m.LastBatch(true)
+	if err != nil || len(redo) != 2 {
+		t.Fatalf("redo batch = %+v, %v", redo, err)
+	}
+
+	fc, err := m.LastFileChange("a.go", true)
+	if err != nil || fc.Operation != "create" {
+		t.Errorf("LastFileChange(redo) = %+v, %v", fc, err)
+	}
+
+	m.MarkUndone([]string{redo[0].ID, redo[1].ID}, false)
+	// a.go's creation is redone; its newer modification is still undone
+	fc, err = m.LastFileChange("a.go", false)
+	if err != nil || fc.Operation != "create" {
+		t.Errorf("LastFileChange(undo) = %+v, %v", fc, err)
+	}
+}
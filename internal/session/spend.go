@@ -0,0 +1,56 @@
+// Package session - Spend: the tokens and cost of the replies recorded in
+// the messages table, for budgets
+package session
+
+import (
+	"fmt"
+	"time"
+)
+
+// replyCostSQL totals the USD cost of the messages m, joined with their
+// providers p
+const replyCostSQL = `COALESCE(SUM((m.tokens_in *
+	CASE WHEN json_valid(p.config) THEN COALESCE(json_extract(p.config, '$.price_in'), 0) ELSE 0 END
+	+ m.tokens_out *
+	CASE WHEN json_valid(p.config) THEN COALESCE(json_extract(p.config, '$.price_out'), 0) ELSE 0 END
+) / 1e6), 0)`
+
+// Spend is the tokens replies used and what they cost
+type Spend struct {
+	TokensIn  int
+	TokensOut int
+	Cost      float64 // USD, from each provider's price_in and price_out
+}
+
+// Tokens is the tokens in and out
+func (s Spend) Tokens() int {
+	return s.TokensIn + s.TokensOut
+}
+
+// SessionSpend returns what the current session's replies used
+func (m *Manager) SessionSpend() (Spend, error) {
+	if m.sessionID == "" {
+		return Spend{}, fmt.Errorf("no active session")
+	}
+	return m.spend("m.session_id = ?", m.sessionID)
+}
+
+// SpendSince returns what the replies of every session used since t
+func (m *Manager) SpendSince(t time.Time) (Spend, error) {
+	return m.spend("m.created_at >= ?", t.Unix())
+}
+
+// spend totals the assistant messages matching a condition, priced by
+// their provider
+func (m *Manager) spend(where string, arg interface{}) (Spend, error) {
+	var s Spend
+	err := m.engine.QueryRow(`
+		SELECT COALESCE(SUM(m.tokens_in), 0), COALESCE(SUM(m.tokens_out), 0),
+			`+replyCostSQL+`
+		FROM messages m LEFT JOIN providers p ON p.provider_id = m.provider_id
+		WHERE m.role = 'assistant' AND `+where, arg).Scan(&s.TokensIn, &s.TokensOut, &s.Cost)
+	if err != nil {
+		return s, fmt.Errorf("read spend: %w", err)
+	}
+	return s, nil
+}
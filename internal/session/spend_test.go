@@ -0,0 +1,64 @@
+package session
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+func TestSpend(t *testing.T) {
+	m := setupTestManager(t)
+	m.engine.Exec(`UPDATE providers SET config = '{"price_in": 1, "price_out": 2}' WHERE provider_id = 'cerebras'`)
+
+	m.AddMessage("user", "question", nil)
+	m.AddMessage("assistant", "answer", &providers.Response{TokensIn: 1000000, TokensOut: 500000})
+	m.AddMessage("assistant", "more", &providers.Response{TokensIn: 100, TokensOut: 50})
+
+	s, err := m.SessionSpend()
+	if err != nil {
+		t.Fatalf("SessionSpend: %v", err)
+	}
+	if s.Tokens() != 1500150 || math.Abs(s.Cost-(2+0.0002)) > 1e-9 {
+		t.Errorf("Unexpected session spend %+v", s)
+	}
+
+	if _, err := m.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := m.SessionSpend(); s.Tokens() != 0 {
+		t.Errorf("Expected a new session to have spent nothing, got %+v", s)
+	}
+	if s, _ := m.SpendSince(time.Now().Add(-time.Hour)); s.Tokens() != 1500150 {
+		t.Errorf("Expected the earlier session's spend, got %+v", s)
+	}
+	if s, _ := m.SpendSince(time.Now().Add(time.Hour)); s.Tokens() != 0 {
+		t.Errorf("Expected nothing spent in the future, got %+v", s)
+	}
+}
+
+func TestSpendSince_NextLaunch(t *testing.T) {
+	inTempDir(t)
+	first, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(first)
+	m.Create("cerebras")
+	m.AddMessage("assistant", "answer", &providers.Response{TokensIn: 300, TokensOut: 200})
+	first.Close()
+
+	// A restart must not reset the daily spend
+	second, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	m = NewManager(second)
+	m.Create("cerebras")
+	if s, err := m.SpendSince(time.Now().Add(-time.Hour)); err != nil || s.Tokens() != 500 {
+		t.Errorf("Expected the earlier launch's 500 tokens, got %+v (%v)", s, err)
+	}
+}
@@ -0,0 +1,221 @@
+// Package session - The todo list the agent keeps for multi-step work,
+// stored per session so /resume brings it back after a restart
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Task statuses
+const (
+	TaskPending    = "pending"
+	TaskInProgress = "in_progress"
+	TaskDone       = "done"
+)
+
+// Task is one item of a session's todo list
+type Task struct {
+	ID       int64
+	Position int // 1-based
+	Content  string
+	Status   string
+}
+
+// ValidTaskStatus reports whether status is pending, in_progress, or done
+func ValidTaskStatus(status string) bool {
+	switch status {
+	case TaskPending, TaskInProgress, TaskDone:
+		return true
+	}
+	return false
+}
+
+// Progress counts finished tasks and returns the one in progress (or the
+// first pending one), nil when everything is done
+func Progress(tasks []Task) (done int, current *Task) {
+	for i := range tasks {
+		switch tasks[i].Status {
+		case TaskDone:
+			done++
+		case TaskInProgress:
+			current = &tasks[i]
+		}
+	}
+	if current == nil {
+		for i := range tasks {
+			if tasks[i].Status == TaskPending {
+				return done, &tasks[i]
+			}
+		}
+	}
+	return done, current
+}
+
+// Tasks returns the current session's todo list in order
+func (m *Manager) Tasks() ([]Task, error) {
+	return m.SessionTasks(m.sessionID)
+}
+
+// SessionTasks returns a session's todo list in order
+func (m *Manager) SessionTasks(sessionID string) ([]Task, error) {
+	rows, err := m.engine.Query(`
+		SELECT task_id, position, content, status FROM tasks
+		WHERE session_id = ? ORDER BY position
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("list tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]Task, 0)
+	for rows.Next() {
+		var t Task
+		if err := rows.Scan(&t.ID, &t.Position, &t.Content, &t.Status); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// SetTasks replaces the todo list, as the todo tool writes it whole
+func (m *Manager) SetTasks(tasks []Task) error {
+	if m.sessionID == "" {
+		return fmt.Errorf("no active session")
+	}
+	for _, t := range tasks {
+		if !ValidTaskStatus(t.Status) {
+			return fmt.Errorf("unknown task status %q (pending, in_progress, or done)", t.Status)
+		}
+	}
+
+	tx, err := m.engine.DB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM tasks WHERE session_id = ?`, m.sessionID); err != nil {
+		return fmt.Errorf("save tasks: %w", err)
+	}
+	for i, t := range tasks {
+		if _, err := tx.Exec(`
+			INSERT INTO tasks (session_id, position, content, status) VALUES (?, ?, ?, ?)
+		`, m.sessionID, i+1, t.Content, t.Status); err != nil {
+			return fmt.Errorf("save tasks: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// AddTask appends a pending task to the list
+func (m *Manager) AddTask(content string) error {
+	if m.sessionID == "" {
+		return fmt.Errorf("no active session")
+	}
+	_, err := m.engine.Exec(`
+		INSERT INTO tasks (session_id, position, content)
+		SELECT ?, COALESCE(MAX(position), 0) + 1, ? FROM tasks WHERE session_id = ?
+	`, m.sessionID, content, m.sessionID)
+	if err != nil {
+		return fmt.Errorf("add task: %w", err)
+	}
+	return nil
+}
+
+// SetTaskStatus updates the task at a position
+func (m *Manager) SetTaskStatus(position int, status string) error {
+	if !ValidTaskStatus(status) {
+		return fmt.Errorf("unknown task status %q (pending, in_progress, or done)", status)
+	}
+	n, err := m.engine.Exec(`
+		UPDATE tasks SET status = ?, updated_at = ? WHERE session_id = ? AND position = ?
+	`, status, time.Now().Unix(), m.sessionID, position)
+	if err != nil {
+		return fmt.Errorf("update task: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("no task %d", position)
+	}
+	return nil
+}
+
+// RemoveTask deletes the task at a position; later tasks move up
+func (m *Manager) RemoveTask(position int) error {
+	n, err := m.removeTasks(`position = ?`, position)
+	if err == nil && n == 0 {
+		return fmt.Errorf("no task %d", position)
+	}
+	return err
+}
+
+// ClearDoneTasks deletes finished tasks and returns how many there were
+func (m *Manager) ClearDoneTasks() (int, error) {
+	n, err := m.removeTasks(`status = ?`, TaskDone)
+	return int(n), err
+}
+
+// removeTasks deletes the current session's tasks matching a condition and
+// renumbers the rest
+func (m *Manager) removeTasks(where string, arg interface{}) (int64, error) {
+	tx, err := m.engine.DB().Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM tasks WHERE session_id = ? AND `+where, m.sessionID, arg)
+	if err != nil {
+		return 0, fmt.Errorf("remove tasks: %w", err)
+	}
+	removed, _ := res.RowsAffected()
+	if removed == 0 {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(`SELECT task_id FROM tasks WHERE session_id = ? ORDER BY position`, m.sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("remove tasks: %w", err)
+	}
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for i, id := range ids {
+		if _, err := tx.Exec(`UPDATE tasks SET position = ? WHERE task_id = ?`, i+1, id); err != nil {
+			return 0, fmt.Errorf("remove tasks: %w", err)
+		}
+	}
+	return removed, tx.Commit()
+}
+
+// UnfinishedTasks returns the most recently active other session that has
+// tasks left to do, and how many; "" when there is none
+func (m *Manager) UnfinishedTasks() (string, int, error) {
+	var sessionID string
+	var n int
+	err := m.engine.QueryRow(`
+		SELECT t.session_id, COUNT(*) FROM tasks t
+		JOIN sessions s ON s.session_id = t.session_id
+		WHERE t.status != ? AND t.session_id != ?
+		GROUP BY t.session_id
+		ORDER BY MAX(s.last_active_at) DESC, MAX(t.updated_at) DESC
+		LIMIT 1
+	`, TaskDone, m.sessionID).Scan(&sessionID, &n)
+	if err == sql.ErrNoRows {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("find unfinished tasks: %w", err)
+	}
+	return sessionID, n, nil
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func TestTasks(t *testing.T) {
+	m := setupTestManager(t)
+	first := m.Current()
+
+	if err := m.SetTasks([]Task{
+		{Content: "parse flags", Status: TaskDone},
+		{Content: "wire them up", Status: TaskInProgress},
+		{Content: "write tests", Status: TaskPending},
+	}); err != nil {
+		t.Fatalf("SetTasks failed: %v", err)
+	}
+	if err := m.SetTasks([]Task{{Content: "x", Status: "started"}}); err == nil {
+		t.Error("expected an error for an unknown status")
+	}
+	if err := m.AddTask("update docs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetTaskStatus(2, TaskDone); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetTaskStatus(9, TaskDone); err == nil {
+		t.Error("expected an error for a missing task")
+	}
+
+	n, err := m.ClearDoneTasks()
+	if err != nil || n != 2 {
+		t.Fatalf("ClearDoneTasks() = %d, %v; want 2", n, err)
+	}
+	tasks, err := m.Tasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].Position != 1 || tasks[0].Content != "write tests" || tasks[1].Position != 2 || tasks[1].Content != "update docs" {
+		t.Fatalf("Tasks() after clear = %+v", tasks)
+	}
+	if done, current := Progress(tasks); done != 0 || current == nil || current.Content != "write tests" {
+		t.Errorf("Progress() = %d, %+v", done, current)
+	}
+
+	// A restart opens a new session and points back at the unfinished one
+	if _, err := m.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	if tasks, _ := m.Tasks(); len(tasks) != 0 {
+		t.Errorf("new session has tasks: %+v", tasks)
+	}
+	id, n, err := m.UnfinishedTasks()
+	if err != nil || id != first || n != 2 {
+		t.Errorf("UnfinishedTasks() = %q, %d, %v; want %q, 2", id, n, err, first)
+	}
+
+	if err := m.SetSession(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveTask(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveTask(5); err == nil {
+		t.Error("expected an error removing a missing task")
+	}
+	tasks, _ = m.Tasks()
+	if len(tasks) != 1 || tasks[0].Position != 1 || tasks[0].Content != "update docs" {
+		t.Errorf("Tasks() after remove = %+v", tasks)
+	}
+}
+
+func TestTasks_NextLaunch(t *testing.T) {
+	inTempDir(t)
+	first, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(first)
+	m.Create("cerebras")
+	earlier := m.Current()
+	if err := m.SetTasks([]Task{{Content: "parse flags", Status: TaskDone}, {Content: "write tests", Status: TaskPending}}); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	// The next launch starts a new session; /resume switches back to it
+	second, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	m = NewManager(second)
+	m.Create("cerebras")
+	if id, n, err := m.UnfinishedTasks(); err != nil || id != earlier || n != 1 {
+		t.Errorf("Expected the start to point at the earlier session's task, got %s, %d (%v)", id, n, err)
+	}
+	if err := m.SetSession(earlier); err != nil {
+		t.Fatalf("Expected the earlier session to be resumable, got %v", err)
+	}
+	if tasks, err := m.Tasks(); err != nil || len(tasks) != 2 || tasks[1].Content != "write tests" {
+		t.Errorf("Expected the todo list back, got %v (%v)", tasks, err)
+	}
+}
@@ -0,0 +1,56 @@
+// Package session - Chat threads of the bot modes and clients of --listen,
+// each with its own session
+package session
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ThreadSession returns the session bound to a chat thread, or "" if the
+// thread has none yet. Keys are qualified by platform, like
+// "slack:C123:1700000000.000100".
+func (m *Manager) ThreadSession(thread string) (string, error) {
+	return m.boundSession("$.thread", thread)
+}
+
+// BindThread binds a chat thread to the current session
+func (m *Manager) BindThread(thread string) error {
+	return m.bind("$.thread", thread)
+}
+
+// UserSession returns the latest session a --listen client owns, or "" if
+// it has none yet
+func (m *Manager) UserSession(user string) (string, error) {
+	return m.boundSession("$.user", user)
+}
+
+// BindUser makes a --listen client the owner of the current session
+func (m *Manager) BindUser(user string) error {
+	return m.bind("$.user", user)
+}
+
+// boundSession returns the latest session whose metadata has value at path
+func (m *Manager) boundSession(path, value string) (string, error) {
+	var id string
+	err := m.engine.QueryRow(`
+		SELECT session_id FROM sessions WHERE json_extract(metadata, ?) = ?
+		ORDER BY rowid DESC LIMIT 1
+	`, path, value).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+// bind sets path in the current session's metadata
+func (m *Manager) bind(path, value string) error {
+	if m.sessionID == "" {
+		return fmt.Errorf("no session")
+	}
+	_, err := m.engine.Exec(`
+		UPDATE sessions SET metadata = json_set(COALESCE(metadata, '{}'), ?, ?)
+		WHERE session_id = ?
+	`, path, value, m.sessionID)
+	return err
+}
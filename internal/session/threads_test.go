@@ -0,0 +1,56 @@
+package session
+
+import "testing"
+
+func TestThreadSession(t *testing.T) {
+	m := setupTestManager(t)
+	first := m.Current()
+
+	if id, err := m.ThreadSession("slack:C1:1.1"); err != nil || id != "" {
+		t.Fatalf("Expected no session for a new thread, got %q (%v)", id, err)
+	}
+	if err := m.BindThread("slack:C1:1.1"); err != nil {
+		t.Fatalf("BindThread failed: %v", err)
+	}
+	if _, err := m.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.BindThread("slack:C1:2.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := m.ThreadSession("slack:C1:1.1"); err != nil || id != first {
+		t.Errorf("Expected %s for the first thread, got %q (%v)", first, id, err)
+	}
+	if id, _ := m.ThreadSession("slack:C1:2.2"); id != m.Current() {
+		t.Errorf("Expected the current session for the second thread, got %q", id)
+	}
+}
+
+func TestUserSession(t *testing.T) {
+	m := setupTestManager(t)
+	if err := m.BindThread("slack:C1:1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := m.UserSession("alice"); err != nil || id != "" {
+		t.Fatalf("Expected no session for a new user, got %q (%v)", id, err)
+	}
+	if err := m.BindUser("alice"); err != nil {
+		t.Fatalf("BindUser failed: %v", err)
+	}
+	alice := m.Current()
+	if _, err := m.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	m.BindUser("bob")
+
+	if id, _ := m.UserSession("alice"); id != alice {
+		t.Errorf("Expected alice's session %s, got %q", alice, id)
+	}
+	if id, _ := m.UserSession("bob"); id != m.Current() {
+		t.Errorf("Expected bob's session to be the current one, got %q", id)
+	}
+	if id, _ := m.ThreadSession("slack:C1:1.1"); id != alice {
+		t.Errorf("Binding a user lost the thread, got %q", id)
+	}
+}
@@ -0,0 +1,64 @@
+// Package session - Replies cut short by a response limit, kept so
+// /continue can finish them
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+// TruncatedReply returns the session's last reply if it was cut short, or
+// nil, with why it stopped
+func (m *Manager) TruncatedReply() (*Message, string, error) {
+	if m.sessionID == "" {
+		return nil, "", fmt.Errorf("no active session")
+	}
+	var msg Message
+	var createdAt int64
+	var reason sql.NullString
+	err := m.engine.QueryRow(`
+		SELECT message_id, session_id, role, content,
+			   COALESCE(provider_id, ''), COALESCE(model, ''),
+			   tokens_in, tokens_out, latency_ms, created_at,
+			   CASE WHEN json_valid(metadata) THEN json_extract(metadata, '$.truncated') END
+		FROM messages
+		WHERE session_id = ? AND role = 'assistant'
+		ORDER BY rowid DESC LIMIT 1
+	`, m.sessionID).Scan(&msg.ID, &msg.SessionID, &msg.Role, &msg.Content,
+		&msg.Provider, &msg.Model, &msg.TokensIn, &msg.TokensOut, &msg.LatencyMs, &createdAt, &reason)
+	if err == sql.ErrNoRows || (err == nil && reason.String == "") {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("find truncated reply: %w", err)
+	}
+	msg.CreatedAt = time.Unix(createdAt, 0)
+	return &msg, reason.String, nil
+}
+
+// ExtendReply appends a continuation to a reply, adding up its tokens; the
+// reply stays marked truncated only if the continuation was cut short too
+func (m *Manager) ExtendReply(messageID string, resp *providers.Response) error {
+	truncated := ""
+	if resp.Truncated() {
+		truncated = resp.FinishReason
+	}
+	_, err := m.engine.Exec(`
+		UPDATE messages SET
+			content = content || ?2,
+			tokens_in = tokens_in + ?3, tokens_out = tokens_out + ?4, latency_ms = latency_ms + ?5,
+			metadata = CASE
+				WHEN ?6 != '' THEN json_set(CASE WHEN json_valid(metadata) THEN metadata ELSE '{}' END, '$.truncated', ?6)
+				WHEN json_valid(metadata) THEN json_remove(metadata, '$.truncated')
+				ELSE metadata
+			END
+		WHERE message_id = ?1
+	`, messageID, resp.Content, resp.TokensIn, resp.TokensOut, resp.Latency, truncated)
+	if err != nil {
+		return fmt.Errorf("extend reply: %w", err)
+	}
+	return nil
+}
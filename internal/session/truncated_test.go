@@ -0,0 +1,40 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+func TestTruncatedReply(t *testing.T) {
+	m := setupTestManager(t)
+	m.AddMessage("user", "write a long file", nil)
+	m.AddMessage("assistant", "done", &providers.Response{FinishReason: "stop"})
+
+	if reply, _, err := m.TruncatedReply(); err != nil || reply != nil {
+		t.Fatalf("Expected no truncated reply, got %+v, %v", reply, err)
+	}
+
+	m.AddMessage("assistant", "first half", &providers.Response{TokensOut: 10, FinishReason: providers.FinishLength})
+	reply, reason, err := m.TruncatedReply()
+	if err != nil || reply == nil || reply.Content != "first half" || reason != providers.FinishLength {
+		t.Fatalf("TruncatedReply = %+v, %q, %v", reply, reason, err)
+	}
+
+	if err := m.ExtendReply(reply.ID, &providers.Response{Content: ", second", TokensOut: 5, FinishReason: providers.FinishTimeout}); err != nil {
+		t.Fatal(err)
+	}
+	if _, reason, _ := m.TruncatedReply(); reason != providers.FinishTimeout {
+		t.Errorf("Expected the reply still cut short, got %q", reason)
+	}
+
+	m.ExtendReply(reply.ID, &providers.Response{Content: " and the end", TokensOut: 5, FinishReason: "stop"})
+	if reply, _, _ := m.TruncatedReply(); reply != nil {
+		t.Errorf("Expected a finished reply, got %+v", reply)
+	}
+	msgs, _ := m.GetMessages(10)
+	last := msgs[len(msgs)-1]
+	if last.Content != "first half, second and the end" || last.TokensOut != 20 {
+		t.Errorf("Expected the continuations appended, got %q with %d tokens", last.Content, last.TokensOut)
+	}
+}
@@ -0,0 +1,152 @@
+// Package session - Usage: the tokens and cost of the recorded replies,
+// grouped by day, provider, model, or session
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UsageGroups are what Usage groups replies by
+var UsageGroups = []string{"day", "provider", "model", "session"}
+
+// usageColumns are the SQL expressions of the usage groups
+var usageColumns = map[string]string{
+	"day":      "date(m.created_at, 'unixepoch', 'localtime')",
+	"provider": "COALESCE(NULLIF(m.provider_id, ''), 'unknown')",
+	"model":    "COALESCE(NULLIF(m.model, ''), 'unknown')",
+	"session":  "m.session_id",
+}
+
+// UsageRow is what the replies of a group used. Only the fields of the
+// groups asked for are set.
+type UsageRow struct {
+	Day       string  `json:"day,omitempty"`
+	Provider  string  `json:"provider,omitempty"`
+	Model     string  `json:"model,omitempty"`
+	Session   string  `json:"session,omitempty"`
+	Requests  int     `json:"requests"`
+	TokensIn  int     `json:"tokens_in"`
+	TokensOut int     `json:"tokens_out"`
+	Cost      float64 `json:"cost_usd"` // From each provider's price_in and price_out
+}
+
+// Usage totals the replies from from until to (no limit if zero) by the
+// given groups, as sorted by SortUsage
+func (m *Manager) Usage(from, to time.Time, by []string) ([]UsageRow, error) {
+	columns := make([]string, 0, len(by))
+	for _, group := range by {
+		column, ok := usageColumns[group]
+		if !ok {
+			return nil, fmt.Errorf("cannot group usage by %q (want %s)", group, strings.Join(UsageGroups, ", "))
+		}
+		columns = append(columns, column)
+	}
+	selected := ""
+	grouped := ""
+	if len(columns) > 0 {
+		selected = strings.Join(columns, ", ") + ", "
+		grouped = "GROUP BY " + strings.Join(columns, ", ")
+	}
+	until := int64(1<<62 - 1)
+	if !to.IsZero() {
+		until = to.Unix()
+	}
+
+	rows, err := m.engine.Query(`
+		SELECT `+selected+`COUNT(*), COALESCE(SUM(m.tokens_in), 0), COALESCE(SUM(m.tokens_out), 0),
+			`+replyCostSQL+`
+		FROM messages m LEFT JOIN providers p ON p.provider_id = m.provider_id
+		WHERE m.role = 'assistant' AND m.created_at >= ? AND m.created_at < ?
+		`+grouped, from.Unix(), until)
+	if err != nil {
+		return nil, fmt.Errorf("read usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make([]UsageRow, 0)
+	for rows.Next() {
+		var u UsageRow
+		keys := make([]string, len(by))
+		dest := make([]interface{}, 0, len(by)+4)
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+		dest = append(dest, &u.Requests, &u.TokensIn, &u.TokensOut, &u.Cost)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if u.Requests == 0 {
+			continue // No replies at all: the aggregate of no rows
+		}
+		for i, group := range by {
+			u.set(group, keys[i])
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	SortUsage(usage)
+	return usage, nil
+}
+
+// set sets a group's field
+func (u *UsageRow) set(group, value string) {
+	switch group {
+	case "day":
+		u.Day = value
+	case "provider":
+		u.Provider = value
+	case "model":
+		u.Model = value
+	case "session":
+		u.Session = value
+	}
+}
+
+// Add adds another row's usage to u
+func (u *UsageRow) Add(other UsageRow) {
+	u.Requests += other.Requests
+	u.TokensIn += other.TokensIn
+	u.TokensOut += other.TokensOut
+	u.Cost += other.Cost
+}
+
+// MergeUsage adds up the rows of the same group, e.g. from several
+// databases, sorted by SortUsage
+func MergeUsage(rows []UsageRow) []UsageRow {
+	merged := make([]UsageRow, 0, len(rows))
+	index := make(map[[4]string]int)
+	for _, r := range rows {
+		key := [4]string{r.Day, r.Provider, r.Model, r.Session}
+		if i, ok := index[key]; ok {
+			merged[i].Add(r)
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, r)
+	}
+	SortUsage(merged)
+	return merged
+}
+
+// SortUsage sorts rows newest day first, then by cost and tokens, most
+// first
+func SortUsage(rows []UsageRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Day != b.Day {
+			return a.Day > b.Day
+		}
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		if a.TokensIn+a.TokensOut != b.TokensIn+b.TokensOut {
+			return a.TokensIn+a.TokensOut > b.TokensIn+b.TokensOut
+		}
+		return a.Provider+a.Model+a.Session < b.Provider+b.Model+b.Session
+	})
+}
@@ -0,0 +1,49 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+func TestUsage(t *testing.T) {
+	m := setupTestManager(t)
+	m.engine.Exec(`UPDATE providers SET config = '{"price_in": 1, "price_out": 1}' WHERE provider_id = 'cerebras'`)
+	m.AddMessage("user", "question", nil)
+	m.AddMessage("assistant", "a", &providers.Response{Model: "glm", TokensIn: 1000000})
+	m.AddMessage("assistant", "b", &providers.Response{Model: "qwen", TokensIn: 10, TokensOut: 20})
+	m.AddMessage("assistant", "c", &providers.Response{Model: "glm", TokensOut: 5})
+
+	from := time.Now().Add(-time.Hour)
+	rows, err := m.Usage(from, time.Time{}, []string{"provider", "model"})
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Model != "glm" || rows[0].Provider != "cerebras" || rows[0].Requests != 2 || rows[0].TokensOut != 5 {
+		t.Fatalf("Expected glm first with 2 requests, got %+v", rows)
+	}
+	if rows[0].Day != "" || rows[0].Session != "" {
+		t.Errorf("Expected only the grouped fields set, got %+v", rows[0])
+	}
+
+	rows, _ = m.Usage(from, time.Time{}, nil)
+	if len(rows) != 1 || rows[0].Requests != 3 || rows[0].TokensIn != 1000010 {
+		t.Errorf("Expected one overall row, got %+v", rows)
+	}
+	if rows, _ = m.Usage(from, from.Add(time.Minute), []string{"day"}); len(rows) != 0 {
+		t.Errorf("Expected nothing before the replies, got %+v", rows)
+	}
+	if _, err := m.Usage(from, time.Time{}, []string{"week"}); err == nil {
+		t.Error("Expected an unknown group to fail")
+	}
+
+	merged := MergeUsage([]UsageRow{
+		{Day: "2026-10-01", Requests: 1, TokensIn: 5},
+		{Day: "2026-10-02", Requests: 2, Cost: 1},
+		{Day: "2026-10-01", Requests: 3, TokensIn: 7},
+	})
+	if len(merged) != 2 || merged[0].Day != "2026-10-02" || merged[1].Requests != 4 || merged[1].TokensIn != 12 {
+		t.Errorf("Unexpected merge %+v", merged)
+	}
+}
@@ -0,0 +1,271 @@
+// Package slack is the Slack client of the bot mode: events over Socket
+// Mode, so no public endpoint is needed, and the Web API calls that post
+// and edit replies.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/websocket"
+)
+
+// DefaultAPIURL is the Slack Web API
+const DefaultAPIURL = "https://slack.com/api"
+
+// MaxMessageLength is where message text is cut; Slack truncates at 40000
+const MaxMessageLength = 39000
+
+// reconnectDelay is the pause before reopening a dropped connection
+const reconnectDelay = 5 * time.Second
+
+// Client talks to one Slack app
+type Client struct {
+	APIURL   string
+	appToken string // xapp-: opens Socket Mode connections
+	botToken string // xoxb-: calls the Web API as the bot
+	http     *http.Client
+}
+
+// New creates a client from the app-level and bot tokens
+func New(appToken, botToken string) *Client {
+	return &Client{
+		APIURL:   DefaultAPIURL,
+		appToken: appToken,
+		botToken: botToken,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Event is a message the bot was sent: a mention in a channel, a reply in
+// a thread it is part of, or a direct message
+type Event struct {
+	Type     string `json:"type"` // app_mention or message
+	Subtype  string `json:"subtype"`
+	Channel  string `json:"channel"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+}
+
+// Thread returns the timestamp of the thread the message belongs to,
+// which is its own for a top-level message
+func (e Event) Thread() string {
+	if e.ThreadTS != "" {
+		return e.ThreadTS
+	}
+	return e.TS
+}
+
+// call invokes a Web API method and decodes the response into out
+func (c *Client) call(ctx context.Context, method, token string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.APIURL+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d: %s", method, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("%s: decode response: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("%s: %s", method, status.Error)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%s: decode response: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// BotUserID returns the bot's own user ID, to recognize mentions of it
+func (c *Client) BotUserID(ctx context.Context) (string, error) {
+	var out struct {
+		UserID string `json:"user_id"`
+	}
+	err := c.call(ctx, "auth.test", c.botToken, map[string]string{}, &out)
+	return out.UserID, err
+}
+
+// PostMessage posts text in a channel, in a thread unless threadTS is
+// empty, and returns the new message's timestamp
+func (c *Client) PostMessage(ctx context.Context, channel, threadTS, text string) (string, error) {
+	var out struct {
+		TS string `json:"ts"`
+	}
+	in := map[string]string{"channel": channel, "text": truncate(text)}
+	if threadTS != "" {
+		in["thread_ts"] = threadTS
+	}
+	err := c.call(ctx, "chat.postMessage", c.botToken, in, &out)
+	return out.TS, err
+}
+
+// UpdateMessage replaces the text of a message the bot posted
+func (c *Client) UpdateMessage(ctx context.Context, channel, ts, text string) error {
+	return c.call(ctx, "chat.update", c.botToken, map[string]string{"channel": channel, "ts": ts, "text": truncate(text)}, nil)
+}
+
+// truncate cuts text Slack would refuse
+func truncate(text string) string {
+	if len(text) <= MaxMessageLength {
+		return text
+	}
+	return text[:MaxMessageLength] + "\n… (truncated)"
+}
+
+// envelope is a Socket Mode message
+type envelope struct {
+	EnvelopeID string `json:"envelope_id"`
+	Type       string `json:"type"` // hello, disconnect, events_api...
+	Payload    struct {
+		Event Event `json:"event"`
+	} `json:"payload"`
+}
+
+// Listen receives events until ctx is done, reconnecting when Slack asks
+// to or the connection drops. Events are acknowledged on arrival and
+// handled one at a time, in order, so a slow reply does not make Slack
+// resend them.
+func (c *Client) Listen(ctx context.Context, handle func(Event)) error {
+	events := make(chan Event, 64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range events {
+			handle(e)
+		}
+	}()
+	defer func() {
+		close(events)
+		<-done
+	}()
+
+	log := core.Logger("slack")
+	seen := newRecent(256)
+	for ctx.Err() == nil {
+		err := c.listenOnce(ctx, events, seen)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			log.Warn("Socket Mode connection lost, reconnecting", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}
+	return nil
+}
+
+// listenOnce serves one Socket Mode connection until Slack ends it
+func (c *Client) listenOnce(ctx context.Context, events chan<- Event, seen *recent) error {
+	var open struct {
+		URL string `json:"url"`
+	}
+	if err := c.call(ctx, "apps.connections.open", c.appToken, map[string]string{}, &open); err != nil {
+		return err
+	}
+	conn, err := websocket.Dial(ctx, open.URL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return fmt.Errorf("decode envelope: %w", err)
+		}
+		if env.EnvelopeID != "" {
+			ack, _ := json.Marshal(map[string]string{"envelope_id": env.EnvelopeID})
+			if err := conn.WriteMessage(ack); err != nil {
+				return err
+			}
+		}
+
+		switch env.Type {
+		case "disconnect":
+			return nil // Slack is about to close this one: open another
+		case "events_api":
+			// A mention in a channel the bot reads also arrives as a
+			// message event
+			if e := env.Payload.Event; wanted(e) && seen.add(e.Channel+"/"+e.TS) {
+				events <- e
+			}
+		}
+	}
+}
+
+// wanted reports whether an event is a person's message: not an edit, a
+// join, or the bot's own output
+func wanted(e Event) bool {
+	if e.Type != "app_mention" && e.Type != "message" {
+		return false
+	}
+	return e.Subtype == "" && e.BotID == "" && e.User != "" && strings.TrimSpace(e.Text) != ""
+}
+
+// recent remembers the last keys added, to drop duplicates
+type recent struct {
+	keys  map[string]bool
+	order []string
+	max   int
+}
+
+func newRecent(max int) *recent {
+	return &recent{keys: make(map[string]bool), max: max}
+}
+
+// add records key and reports whether it is new
+func (r *recent) add(key string) bool {
+	if r.keys[key] {
+		return false
+	}
+	r.keys[key] = true
+	r.order = append(r.order, key)
+	if len(r.order) > r.max {
+		delete(r.keys, r.order[0])
+		r.order = r.order[1:]
+	}
+	return true
+}
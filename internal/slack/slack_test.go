@@ -0,0 +1,188 @@
+package slack
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSlack serves the Web API methods the client calls and a Socket Mode
+// endpoint sending the given envelopes, recording the acks
+type fakeSlack struct {
+	t         *testing.T
+	envelopes []string
+	mu        sync.Mutex
+	acks      []string
+	posted    []map[string]string
+}
+
+func (f *fakeSlack) handler(wsURL *string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xapp-test" {
+			w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "url": *wsURL})
+	})
+	mux.HandleFunc("/api/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		f.mu.Lock()
+		f.posted = append(f.posted, in)
+		f.mu.Unlock()
+		w.Write([]byte(`{"ok":true,"ts":"1700000000.000200"}`))
+	})
+	mux.HandleFunc("/socket", func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			f.t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + accept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		for _, env := range f.envelopes {
+			writeText(rw, env)
+		}
+		rw.Flush()
+		for {
+			ack, err := readText(rw.Reader)
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.acks = append(f.acks, ack)
+			f.mu.Unlock()
+		}
+	})
+	return mux
+}
+
+func (f *fakeSlack) ackCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.acks)
+}
+
+func TestListen(t *testing.T) {
+	f := &fakeSlack{t: t, envelopes: []string{
+		`{"type":"hello"}`,
+		`{"envelope_id":"e1","type":"events_api","payload":{"event":{"type":"app_mention","channel":"C1","user":"U1","text":"<@B1> fix it","ts":"1.1"}}}`,
+		`{"envelope_id":"e2","type":"events_api","payload":{"event":{"type":"message","channel":"C1","user":"U1","text":"<@B1> fix it","ts":"1.1"}}}`,
+		`{"envelope_id":"e3","type":"events_api","payload":{"event":{"type":"message","channel":"C1","bot_id":"B1","text":"my reply","ts":"1.2"}}}`,
+		`{"envelope_id":"e4","type":"events_api","payload":{"event":{"type":"message","subtype":"message_changed","channel":"C1","ts":"1.3"}}}`,
+		`{"envelope_id":"e5","type":"events_api","payload":{"event":{"type":"message","channel":"C1","user":"U2","text":"and this","ts":"1.4","thread_ts":"1.1"}}}`,
+	}}
+	var wsURL string
+	srv := httptest.NewServer(f.handler(&wsURL))
+	defer srv.Close()
+	wsURL = "ws" + strings.TrimPrefix(srv.URL, "http") + "/socket"
+
+	c := New("xapp-test", "xoxb-test")
+	c.APIURL = srv.URL + "/api"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got []Event
+	c.Listen(ctx, func(e Event) {
+		got = append(got, e)
+		if len(got) == 2 {
+			cancel()
+		}
+	})
+
+	if len(got) != 2 || got[0].Text != "<@B1> fix it" || got[1].User != "U2" {
+		t.Fatalf("Expected the mention once and the thread reply, got %+v", got)
+	}
+	if got[0].Thread() != "1.1" || got[1].Thread() != "1.1" {
+		t.Errorf("Expected both in thread 1.1, got %q and %q", got[0].Thread(), got[1].Thread())
+	}
+	// The acks were sent before the events were handled, but the server
+	// may still be reading them
+	deadline := time.Now().Add(2 * time.Second)
+	for f.ackCount() < 5 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.acks) < 5 || !strings.Contains(f.acks[4], `"e5"`) || !strings.Contains(f.acks[0], `"e1"`) {
+		t.Errorf("Expected every envelope acknowledged, got %v", f.acks)
+	}
+}
+
+func TestPostMessage(t *testing.T) {
+	f := &fakeSlack{t: t}
+	var wsURL string
+	srv := httptest.NewServer(f.handler(&wsURL))
+	defer srv.Close()
+
+	c := New("xapp-bad", "xoxb-test")
+	c.APIURL = srv.URL + "/api"
+	ts, err := c.PostMessage(context.Background(), "C1", "1.1", strings.Repeat("a", MaxMessageLength+10))
+	if err != nil || ts != "1700000000.000200" {
+		t.Fatalf("Unexpected post result %q (%v)", ts, err)
+	}
+	if f.posted[0]["thread_ts"] != "1.1" || !strings.HasSuffix(f.posted[0]["text"], "(truncated)") {
+		t.Errorf("Unexpected request %v", f.posted[0]["thread_ts"])
+	}
+
+	if err := c.listenOnce(context.Background(), nil, newRecent(1)); err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Errorf("Expected the Slack error, got %v", err)
+	}
+}
+
+// accept computes Sec-WebSocket-Accept, as a server does
+func accept(key string) string {
+	sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeText writes an unmasked text frame, as a server does
+func writeText(w io.Writer, text string) {
+	head := []byte{0x81}
+	if n := len(text); n < 126 {
+		head = append(head, byte(n))
+	} else {
+		head = append(head, 126, byte(n>>8), byte(n))
+	}
+	w.Write(head)
+	io.WriteString(w, text)
+}
+
+// readText reads a masked client frame
+func readText(r *bufio.Reader) (string, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return "", err
+	}
+	n := int(head[1] & 0x7F)
+	if n == 126 {
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return "", err
+		}
+		n = int(ext[0])<<8 | int(ext[1])
+	}
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(r, mask); err != nil {
+		return "", err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return string(payload), nil
+}
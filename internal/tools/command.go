@@ -0,0 +1,280 @@
+// Package tools - Shell command execution, gated by user approval
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// RunCommandTool is the name of the shell command tool
+const RunCommandTool = "run_command"
+
+// shellMetachars make a command more than one program invocation, or hide
+// its words behind quoting; such commands are never auto-approved
+const shellMetachars = ";&|`$<>()\n'\"\\"
+
+// riskyFlags make an otherwise harmless command run another program, write
+// a file anywhere, or force what it would refuse (git diff --output, go vet
+// -vettool, go build -o, git push -f...); commands passing one, as -flag,
+// --flag, -flag=value, or in a group of short flags (-fu), always ask
+var riskyFlags = map[string]bool{
+	"o": true, "output": true, "exec": true, "toolexec": true, "vettool": true,
+	"c": true, "ext-diff": true, "outputdir": true, "pkgdir": true, "trace": true,
+	"coverprofile": true, "cpuprofile": true, "memprofile": true, "blockprofile": true, "mutexprofile": true,
+	"f": true, "force": true, "force-with-lease": true, "force-if-includes": true,
+}
+
+// nameFlags are programs whose single-dash flags are whole names (Go's flag
+// package): -race is one flag there, not -r -a -c -e
+var nameFlags = map[string]bool{"go": true, "gofmt": true, "goimports": true, "staticcheck": true}
+
+// RunCommandArgs are the arguments of run_command
+type RunCommandArgs struct {
+	Command string `json:"command"`
+}
+
+// CommandOptions configure run_command
+type CommandOptions struct {
+	Dir       string
+	Timeout   time.Duration
+	MaxOutput int                       // Bytes of output kept (the tail)
+	Approve   func(command string) bool // Asked before every run
+	Shell     Shell                     // Runs the command (nil: RunShell on the host)
+}
+
+// CommandResult is the outcome of a shell command
+type CommandResult struct {
+	Output   string
+	ExitCode int
+	Err      error // Set when the command could not run or timed out
+}
+
+// Status describes how the command ended
+func (r CommandResult) Status() string {
+	if r.Err != nil {
+		return r.Err.Error()
+	}
+	return fmt.Sprintf("exit code %d", r.ExitCode)
+}
+
+// String formats the result for the model
+func (r CommandResult) String() string {
+	status := r.Status()
+	if r.Output == "" {
+		return status
+	}
+	return status + "\n" + r.Output
+}
+
+// Shell runs a command in a workspace directory. RunShell runs it on the
+// host; a Sandbox runs it in a container.
+type Shell func(ctx context.Context, dir, command string, timeout time.Duration, maxOutput int) CommandResult
+
+// RunShell runs a command through the platform shell, capturing combined
+// output. Output beyond maxOutput bytes is cut from the front, since errors
+// and summaries tend to come last.
+func RunShell(ctx context.Context, dir, command string, timeout time.Duration, maxOutput int) CommandResult {
+	if runtime.GOOS == "windows" {
+		return runProcess(ctx, dir, timeout, maxOutput, "cmd", "/C", command)
+	}
+	return runProcess(ctx, dir, timeout, maxOutput, "sh", "-c", command)
+}
+
+// runProcess runs a program, capturing its combined output as RunShell does
+func runProcess(ctx context.Context, dir string, timeout time.Duration, maxOutput int, name string, args ...string) CommandResult {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.WaitDelay = time.Second // Don't wait on children still holding the output pipe
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	result := CommandResult{Output: strings.TrimSpace(out.String())}
+	if maxOutput > 0 && len(result.Output) > maxOutput {
+		cut := len(result.Output) - maxOutput
+		result.Output = fmt.Sprintf("... (%d bytes truncated)\n%s", cut, result.Output[cut:])
+	}
+
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Err = fmt.Errorf("timed out after %s", timeout)
+		result.ExitCode = -1
+	case err != nil:
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.Err = err
+			result.ExitCode = -1
+		}
+	}
+	return result
+}
+
+// MatchCommand reports whether a command is auto-approved by an allowlist.
+// Patterns match whole words: "git diff" only that command, "git diff*"
+// also with more arguments. Commands using shell syntax or a risky flag
+// never match.
+func MatchCommand(command string, patterns []string) bool {
+	command = strings.TrimSpace(command)
+	if !autoApprovable(command) {
+		return false
+	}
+	for _, p := range patterns {
+		if matchWords(command, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// autoApprovable reports whether a command is a single program invocation
+// without risky flags, so that a pattern may approve it
+func autoApprovable(command string) bool {
+	if command == "" || strings.ContainsAny(command, shellMetachars) {
+		return false
+	}
+	args := strings.Fields(command)
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if riskyFlags[name] {
+			return false
+		}
+		for _, short := range shortFlags(args[0], arg) {
+			if riskyFlags[string(short)] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// shortFlags returns the letters of a group of short flags ("-fdx"), or
+// nil when arg is not one. A value may follow the letters ("-ofile"), so
+// every letter counts.
+func shortFlags(program, arg string) []rune {
+	if len(arg) < 2 || arg[0] != '-' || arg[1] == '-' || nameFlags[baseName(program)] {
+		return nil
+	}
+	letters := []rune(arg[1:])
+	for _, r := range letters {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return nil
+		}
+	}
+	return letters
+}
+
+// matchWords matches a command against a pattern word by word. A trailing
+// "*" allows more arguments, so "ls*" matches "ls -la" but not "lsof".
+func matchWords(command, pattern string) bool {
+	return matchArgs(strings.Fields(command), pattern)
+}
+
+// matchArgs matches the words of a command against a pattern, as
+// matchWords. Short flags next to each other count as one set, so "rm -rf"
+// matches "rm -fr" and "rm -r -f"; with a trailing "*" the command's set
+// may hold more flags ("rm -rf*" matches "rm -rfv").
+func matchArgs(args []string, pattern string) bool {
+	prefix, wild := strings.CutSuffix(strings.TrimSpace(pattern), "*")
+	want := strings.Fields(prefix)
+	if len(want) == 0 || len(args) == 0 {
+		return false
+	}
+	have, need := argUnits(args), argUnits(want)
+	if len(have) < len(need) || (!wild && len(have) != len(need)) {
+		return false
+	}
+	for i, u := range need {
+		if u.flags == nil {
+			if have[i].word != u.word {
+				return false
+			}
+			continue
+		}
+		if have[i].flags == nil || (!wild && len(have[i].flags) != len(u.flags)) {
+			return false
+		}
+		for r := range u.flags {
+			if !have[i].flags[r] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// argUnit is a word of a command, or a run of short flags as a set
+type argUnit struct {
+	word  string
+	flags map[rune]bool
+}
+
+// argUnits groups the runs of short flags of a command's words
+func argUnits(args []string) []argUnit {
+	units := make([]argUnit, 0, len(args))
+	for _, arg := range args {
+		letters := shortFlags(args[0], arg)
+		if letters == nil {
+			units = append(units, argUnit{word: arg})
+			continue
+		}
+		if last := len(units) - 1; last < 0 || units[last].flags == nil {
+			units = append(units, argUnit{flags: make(map[rune]bool)})
+		}
+		for _, r := range letters {
+			units[len(units)-1].flags[r] = true
+		}
+	}
+	return units
+}
+
+// RunCommand returns the run_command tool
+func RunCommand(opts CommandOptions) *Tool {
+	return &Tool{
+		Name:        RunCommandTool,
+		Description: "Run a shell command in the project directory and return its exit code and output. The user approves each command.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"command": {"type": "string", "description": "Shell command to run, e.g. go test ./..."}
+			},
+			"required": ["command"]
+		}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args RunCommandArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+			if strings.TrimSpace(args.Command) == "" {
+				return "", fmt.Errorf("command is empty")
+			}
+
+			if opts.Approve != nil && !opts.Approve(args.Command) {
+				return "not run: the user declined this command", nil
+			}
+			run := opts.Shell
+			if run == nil {
+				run = RunShell
+			}
+			return run(ctx, opts.Dir, args.Command, opts.Timeout, opts.MaxOutput).String(), nil
+		},
+	}
+}
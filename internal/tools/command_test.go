@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchCommand(t *testing.T) {
+	patterns := []string{"go test*", "git status"}
+
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"go test ./...", true},
+		{"git status", true},
+		{"git status --short", false},
+		{"go test ./... && rm -rf /", false},
+		{"go test $(whoami)", false},
+		{"go test ./... > out.txt", false},
+		{"rm -rf /", false},
+		{"", false},
+		{"go testx", false},
+		{"go test -c ./...", false},
+		{"go test -exec ./x ./...", false},
+		{"go test --exec=./x ./...", false},
+		{"go test -toolexec ./x ./...", false},
+		{"go test -o /tmp/t ./...", false},
+		{"go test -coverprofile=/etc/x ./...", false},
+		{"go test -run 'TestA' ./...", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchCommand(tt.command, patterns); got != tt.want {
+			t.Errorf("MatchCommand(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestMatchCommand_DefaultAllowlist(t *testing.T) {
+	patterns := []string{"go build*", "go test*", "go vet*", "git status*", "git diff*", "git log*", "ls*"}
+
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"ls -la", true},
+		{"ls", true},
+		{"lsof -i", false},
+		{"lsblk", false},
+		{"git diff HEAD~1", true},
+		{"git diff --output=/home/u/.bashrc", false},
+		{"git diff --output /home/u/.bashrc", false},
+		{"git log --output=/tmp/x", false},
+		{"git -c core.pager=sh status", false},
+		{"git diff --ext-diff", false},
+		{"go vet ./...", true},
+		{"go vet -vettool=./x ./...", false},
+		{"go build ./...", true},
+		{"go build -toolexec ./x ./...", false},
+		{"go build -o /usr/local/bin/go ./...", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchCommand(tt.command, patterns); got != tt.want {
+			t.Errorf("MatchCommand(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestMatchCommand_ShortFlags(t *testing.T) {
+	patterns := []string{"git push*", "git clean*", "rm*", "ls*", "go test*"}
+
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"git push -u origin main", true},
+		{"git push -fu origin main", false},
+		{"git push -uf origin main", false},
+		{"git clean -n", true},
+		{"git clean -fdx", false},
+		{"rm -r build", true},
+		{"rm -rf build", false},
+		{"rm -fr build", false},
+		{"ls -la", true},
+		{"ls -ofile", false},
+		{"go test -race ./...", true}, // Go's single-dash flags are names
+		{"go test -count=1 ./...", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchCommand(tt.command, patterns); got != tt.want {
+			t.Errorf("MatchCommand(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		command, pattern string
+		want             bool
+	}{
+		{"rm -fr build", "rm -rf*", true},
+		{"rm -r -f build", "rm -rf*", true},
+		{"rm -rfv build", "rm -rf*", true},
+		{"rm -r build", "rm -rf*", false},
+		{"rm -rfv", "rm -rf", false}, // Without *, the same flags only
+	} {
+		if got := matchWords(tt.command, tt.pattern); got != tt.want {
+			t.Errorf("matchWords(%q, %q) = %v, want %v", tt.command, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	approved := true
+	tool := RunCommand(CommandOptions{
+		Dir:     t.TempDir(),
+		Timeout: time.Second,
+		Approve: func(string) bool { return approved },
+	})
+	run := func(command string) string {
+		args, _ := json.Marshal(RunCommandArgs{Command: command})
+		out, err := tool.Handler(context.Background(), args)
+		if err != nil {
+			t.Fatalf("%s: %v", command, err)
+		}
+		return out
+	}
+
+	if out := run("echo hello"); out != "exit code 0\nhello" {
+		t.Errorf("echo = %q", out)
+	}
+	if out := run("echo oops >&2; exit 3"); out != "exit code 3\noops" {
+		t.Errorf("failing command = %q", out)
+	}
+	if out := run("sleep 5"); !strings.HasPrefix(out, "timed out") {
+		t.Errorf("slow command = %q, want a timeout", out)
+	}
+
+	approved = false
+	if out := run("echo hello"); !strings.Contains(out, "declined") {
+		t.Errorf("declined command = %q", out)
+	}
+}
+
+func TestRunShell_TruncatesFront(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	r := RunShell(context.Background(), t.TempDir(), "printf 'aaaaaaaaaabbbbb'", time.Second, 5)
+	if !strings.HasSuffix(r.Output, "\nbbbbb") || !strings.Contains(r.Output, "10 bytes truncated") {
+		t.Errorf("output = %q", r.Output)
+	}
+}
@@ -0,0 +1,319 @@
+// Package tools - The project's dependencies and the versions it pins
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DependenciesTool is the name of the dependency lookup tool
+const DependenciesTool = "dependencies"
+
+const (
+	maxReadmeBytes = 4 * 1024 // README excerpt returned by info
+	depsTimeout    = 30 * time.Second
+)
+
+// Dependency is a module or package a manifest declares
+type Dependency struct {
+	Name      string
+	Version   string // As written in the manifest ("" when unpinned)
+	Ecosystem string // go, npm, or pypi
+	Manifest  string
+	Note      string // indirect, dev, replaced => ...
+}
+
+// ParseGoMod reads the requirements and replacements of a go.mod file
+func ParseGoMod(content string) []Dependency {
+	deps := make([]Dependency, 0)
+	replaced := make(map[string]string)
+	block := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		comment := ""
+		if i := strings.Index(line, "//"); i >= 0 {
+			comment = strings.TrimSpace(line[i+2:])
+			line = strings.TrimSpace(line[:i])
+		}
+
+		switch {
+		case line == ")":
+			block = ""
+			continue
+		case strings.HasSuffix(line, "("):
+			block = strings.TrimSpace(strings.TrimSuffix(line, "("))
+			continue
+		}
+
+		directive, rest := block, line
+		if block == "" {
+			directive, rest, _ = strings.Cut(line, " ")
+		}
+		fields := strings.Fields(rest)
+		switch directive {
+		case "require":
+			if len(fields) >= 2 {
+				dep := Dependency{Name: fields[0], Version: fields[1], Ecosystem: "go", Manifest: "go.mod"}
+				if comment == "indirect" {
+					dep.Note = "indirect"
+				}
+				deps = append(deps, dep)
+			}
+		case "replace":
+			if old, target, ok := strings.Cut(rest, "=>"); ok {
+				replaced[strings.Fields(old)[0]] = strings.TrimSpace(target)
+			}
+		}
+	}
+
+	for i, dep := range deps {
+		if target, ok := replaced[dep.Name]; ok {
+			deps[i].Note = strings.TrimSpace(deps[i].Note + " replaced => " + target)
+		}
+	}
+	return deps
+}
+
+// ParsePackageJSON reads the dependencies and devDependencies of a package.json
+func ParsePackageJSON(content string) ([]Dependency, error) {
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+		return nil, fmt.Errorf("package.json: %w", err)
+	}
+
+	deps := make([]Dependency, 0)
+	for _, group := range []struct {
+		deps map[string]string
+		note string
+	}{{pkg.Dependencies, ""}, {pkg.DevDependencies, "dev"}} {
+		names := make([]string, 0, len(group.deps))
+		for name := range group.deps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			deps = append(deps, Dependency{Name: name, Version: group.deps[name], Ecosystem: "npm", Manifest: "package.json", Note: group.note})
+		}
+	}
+	return deps, nil
+}
+
+var requirementPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[^\]]*\])?\s*((?:[=<>!~]=?|===)\s*[^;#\s,]+(?:\s*,\s*(?:[=<>!~]=?)\s*[^;#\s,]+)*)?`)
+
+// ParseRequirements reads a pip requirements file
+func ParseRequirements(content string) []Dependency {
+	deps := make([]Dependency, 0)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue // Comments and options such as -r, -e, --index-url
+		}
+		m := requirementPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		deps = append(deps, Dependency{
+			Name:      m[1],
+			Version:   strings.ReplaceAll(m[3], " ", ""),
+			Ecosystem: "pypi",
+			Manifest:  "requirements.txt",
+		})
+	}
+	return deps
+}
+
+// ProjectDependencies reads every supported manifest at the root of dir
+func ProjectDependencies(dir string) ([]Dependency, error) {
+	deps := make([]Dependency, 0)
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		deps = append(deps, ParseGoMod(string(data))...)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		npm, err := ParsePackageJSON(string(data))
+		if err != nil {
+			return deps, err
+		}
+		deps = append(deps, npm...)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "requirements.txt")); err == nil {
+		deps = append(deps, ParseRequirements(string(data))...)
+	}
+	return deps, nil
+}
+
+// FindDependency matches a name exactly, or as the module of a package
+// path (github.com/x/y/sub finds github.com/x/y), case-insensitively for pypi
+func FindDependency(deps []Dependency, name string) *Dependency {
+	var best *Dependency
+	for i, dep := range deps {
+		switch {
+		case dep.Name == name, dep.Ecosystem == "pypi" && strings.EqualFold(dep.Name, name):
+			return &deps[i]
+		case dep.Ecosystem == "go" && strings.HasPrefix(name, dep.Name+"/"):
+			if best == nil || len(dep.Name) > len(best.Name) {
+				best = &deps[i]
+			}
+		}
+	}
+	return best
+}
+
+// docsURL links to the documentation of the exact version in use
+func docsURL(dep Dependency) string {
+	version := strings.TrimLeft(dep.Version, "=^~<>! ")
+	switch dep.Ecosystem {
+	case "go":
+		return "https://pkg.go.dev/" + dep.Name + "@" + dep.Version
+	case "npm":
+		if version == "" || strings.ContainsAny(version, " *|x") {
+			return "https://www.npmjs.com/package/" + dep.Name
+		}
+		return "https://www.npmjs.com/package/" + dep.Name + "/v/" + version
+	case "pypi":
+		if strings.HasPrefix(dep.Version, "==") {
+			return "https://pypi.org/project/" + dep.Name + "/" + version + "/"
+		}
+		return "https://pypi.org/project/" + dep.Name + "/"
+	}
+	return ""
+}
+
+// DependencyInfo describes one dependency: the version the project uses,
+// what is actually installed, and the start of its README when available
+func DependencyInfo(ctx context.Context, dir string, dep Dependency) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s (%s, from %s)", dep.Name, dep.Version, dep.Ecosystem, dep.Manifest)
+	if dep.Note != "" {
+		fmt.Fprintf(&sb, " [%s]", dep.Note)
+	}
+	sb.WriteString("\nDocs: " + docsURL(dep) + "\n")
+
+	readmeDir := ""
+	switch dep.Ecosystem {
+	case "go":
+		res := RunShell(ctx, dir, "go list -m -json "+shellQuote(dep.Name), depsTimeout, 0)
+		var mod struct {
+			Version string
+			Dir     string
+			Replace *struct{ Version, Dir string }
+		}
+		if res.ExitCode == 0 && json.Unmarshal([]byte(res.Output), &mod) == nil {
+			if mod.Replace != nil {
+				mod.Version, mod.Dir = mod.Replace.Version, mod.Replace.Dir
+			}
+			if mod.Version != "" {
+				sb.WriteString("Resolved: " + mod.Version + "\n")
+			}
+			readmeDir = mod.Dir
+		}
+	case "npm":
+		readmeDir = filepath.Join(dir, "node_modules", filepath.FromSlash(dep.Name))
+		var pkg struct{ Version string }
+		if data, err := os.ReadFile(filepath.Join(readmeDir, "package.json")); err == nil && json.Unmarshal(data, &pkg) == nil {
+			sb.WriteString("Installed: " + pkg.Version + "\n")
+		} else {
+			sb.WriteString("Installed: not found in node_modules\n")
+		}
+	case "pypi":
+		res := RunShell(ctx, dir, "python3 -m pip show "+shellQuote(dep.Name), depsTimeout, 0)
+		for _, line := range strings.Split(res.Output, "\n") {
+			if strings.HasPrefix(line, "Version:") || strings.HasPrefix(line, "Summary:") {
+				sb.WriteString(strings.Replace(line, "Version:", "Installed:", 1) + "\n")
+			}
+		}
+	}
+
+	if readme := readmeExcerpt(readmeDir); readme != "" {
+		sb.WriteString("\nREADME:\n" + readme)
+	}
+	return sb.String()
+}
+
+// readmeExcerpt returns the start of a package directory's README
+func readmeExcerpt(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	for _, name := range []string{"README.md", "README", "README.rst", "readme.md", "README.txt"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if len(data) > maxReadmeBytes {
+			return string(data[:maxReadmeBytes]) + "\n... (truncated)"
+		}
+		return string(data)
+	}
+	return ""
+}
+
+// shellQuote quotes an argument for sh -c
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// DependenciesArgs are the arguments of dependencies
+type DependenciesArgs struct {
+	Name string `json:"name,omitempty"`
+}
+
+// DependenciesOptions configure dependencies
+type DependenciesOptions struct {
+	Dir string // Project root holding the manifests
+}
+
+// Dependencies returns the dependencies tool
+func Dependencies(opts DependenciesOptions) *Tool {
+	return &Tool{
+		Name:        DependenciesTool,
+		Description: "List the project's dependencies with the versions it pins (go.mod, package.json, requirements.txt), or, given a name, show the version in use, the installed version, a docs link, and its README. Check this before using a library's API.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"name": {"type": "string", "description": "Module or package to look up; omit to list all"}
+			}
+		}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args DependenciesArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+
+			deps, err := ProjectDependencies(opts.Dir)
+			if err != nil {
+				return "", err
+			}
+			if len(deps) == 0 {
+				return "no go.mod, package.json, or requirements.txt dependencies found", nil
+			}
+
+			if args.Name == "" {
+				var sb strings.Builder
+				for _, dep := range deps {
+					fmt.Fprintf(&sb, "%s %s %s", dep.Ecosystem, dep.Name, dep.Version)
+					if dep.Note != "" {
+						fmt.Fprintf(&sb, " (%s)", dep.Note)
+					}
+					sb.WriteString("\n")
+				}
+				return sb.String(), nil
+			}
+
+			dep := FindDependency(deps, args.Name)
+			if dep == nil {
+				return fmt.Sprintf("%s is not a dependency of this project", args.Name), nil
+			}
+			return DependencyInfo(ctx, opts.Dir, *dep), nil
+		},
+	}
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const goMod = `module example.com/app
+
+go 1.22
+
+require github.com/google/uuid v1.6.0
+
+require (
+	github.com/fsnotify/fsnotify v1.7.0
+	golang.org/x/sys v0.13.0 // indirect
+)
+
+replace github.com/google/uuid => ../uuid
+`
+
+func TestParseGoMod(t *testing.T) {
+	got := ParseGoMod(goMod)
+	want := []Dependency{
+		{Name: "github.com/google/uuid", Version: "v1.6.0", Ecosystem: "go", Manifest: "go.mod", Note: "replaced => ../uuid"},
+		{Name: "github.com/fsnotify/fsnotify", Version: "v1.7.0", Ecosystem: "go", Manifest: "go.mod"},
+		{Name: "golang.org/x/sys", Version: "v0.13.0", Ecosystem: "go", Manifest: "go.mod", Note: "indirect"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseGoMod() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParsePackageJSON(t *testing.T) {
+	got, err := ParsePackageJSON(`{"dependencies": {"react": "^18.2.0", "axios": "1.6.0"}, "devDependencies": {"jest": "~29.0.0"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Dependency{
+		{Name: "axios", Version: "1.6.0", Ecosystem: "npm", Manifest: "package.json"},
+		{Name: "react", Version: "^18.2.0", Ecosystem: "npm", Manifest: "package.json"},
+		{Name: "jest", Version: "~29.0.0", Ecosystem: "npm", Manifest: "package.json", Note: "dev"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePackageJSON() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParsePackageJSON("{"); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	content := "# web\nrequests==2.31.0\nDjango >= 4.2, < 5.0\n-r base.txt\nuvicorn[standard]~=0.23\nnumpy\nattrs==23.1 ; python_version > '3.8'\n"
+	got := ParseRequirements(content)
+	want := map[string]string{
+		"requests": "==2.31.0",
+		"Django":   ">=4.2,<5.0",
+		"uvicorn":  "~=0.23",
+		"numpy":    "",
+		"attrs":    "==23.1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseRequirements() = %+v, want %d entries", got, len(want))
+	}
+	for _, dep := range got {
+		if v, ok := want[dep.Name]; !ok || v != dep.Version {
+			t.Errorf("%s: version %q, want %q", dep.Name, dep.Version, v)
+		}
+	}
+}
+
+func TestFindDependency(t *testing.T) {
+	deps := append(ParseGoMod(goMod), Dependency{Name: "Django", Ecosystem: "pypi"})
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"github.com/fsnotify/fsnotify", "github.com/fsnotify/fsnotify"},
+		{"golang.org/x/sys/unix", "golang.org/x/sys"},
+		{"django", "Django"},
+		{"github.com/fsnotify", ""},
+		{"left-pad", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if dep := FindDependency(deps, tt.name); dep != nil {
+			got = dep.Name
+		}
+		if got != tt.want {
+			t.Errorf("FindDependency(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDependenciesTool(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies": {"left-pad": "^1.3.0"}}`), 0644)
+	pkgDir := filepath.Join(dir, "node_modules", "left-pad")
+	os.MkdirAll(pkgDir, 0755)
+	os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte(`{"version": "1.3.0"}`), 0644)
+	os.WriteFile(filepath.Join(pkgDir, "README.md"), []byte("# left-pad\nleftPad(str, len, ch)"), 0644)
+
+	tool := Dependencies(DependenciesOptions{Dir: dir})
+
+	out, err := tool.Handler(context.Background(), json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "npm left-pad ^1.3.0\n" {
+		t.Errorf("list = %q", out)
+	}
+
+	out, err = tool.Handler(context.Background(), json.RawMessage(`{"name": "left-pad"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Installed: 1.3.0", "https://www.npmjs.com/package/left-pad/v/1.3.0", "leftPad(str, len, ch)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("info missing %q:\n%s", want, out)
+		}
+	}
+
+	out, _ = tool.Handler(context.Background(), json.RawMessage(`{"name": "lodash"}`))
+	if !strings.Contains(out, "not a dependency") {
+		t.Errorf("unknown package = %q", out)
+	}
+}
@@ -0,0 +1,78 @@
+// Package tools - File editing tools. These have no handler: the chat applies
+// them as a batch through its normal pipeline (confirmation, path guards,
+// backups, validation, auto-commit).
+package tools
+
+import "encoding/json"
+
+// File tool names
+const (
+	WriteFileTool  = "write_file"
+	EditFileTool   = "edit_file"
+	DeleteFileTool = "delete_file"
+)
+
+// WriteFileArgs are the arguments of write_file
+type WriteFileArgs struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// EditFileArgs are the arguments of edit_file
+type EditFileArgs struct {
+	Path    string `json:"path"`
+	Search  string `json:"search"`
+	Replace string `json:"replace"`
+}
+
+// DeleteFileArgs are the arguments of delete_file
+type DeleteFileArgs struct {
+	Path string `json:"path"`
+}
+
+// IsFileTool reports whether name is one of the file editing tools
+func IsFileTool(name string) bool {
+	return name == WriteFileTool || name == EditFileTool || name == DeleteFileTool
+}
+
+// FileTools returns the file editing tool definitions
+func FileTools() []*Tool {
+	return []*Tool{
+		{
+			Name:        WriteFileTool,
+			Description: "Create a file or replace its entire content.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": {"type": "string", "description": "File path relative to the project root"},
+					"content": {"type": "string", "description": "Complete new file content"}
+				},
+				"required": ["path", "content"]
+			}`),
+		},
+		{
+			Name:        EditFileTool,
+			Description: "Replace one exact snippet of an existing file. The search text must match the file, including indentation; include enough lines to be unique.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": {"type": "string", "description": "File path relative to the project root"},
+					"search": {"type": "string", "description": "Existing text to replace"},
+					"replace": {"type": "string", "description": "Replacement text"}
+				},
+				"required": ["path", "search", "replace"]
+			}`),
+		},
+		{
+			Name:        DeleteFileTool,
+			Description: "Delete a file.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": {"type": "string", "description": "File path relative to the project root"}
+				},
+				"required": ["path"]
+			}`),
+		},
+	}
+}
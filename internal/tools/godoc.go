@@ -0,0 +1,101 @@
+// Package tools - Looking up Go package documentation with go doc
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// GoDocTool is the name of the Go documentation tool
+const GoDocTool = "go_doc"
+
+const goDocTimeout = 30 * time.Second
+
+var (
+	goPackagePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-~/]+$`)
+	goSymbolPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+)
+
+// GoDocArgs are the arguments of go_doc
+type GoDocArgs struct {
+	Package string `json:"package"`
+	Symbol  string `json:"symbol,omitempty"`
+	All     bool   `json:"all,omitempty"`
+}
+
+// GoDocOptions configure go_doc
+type GoDocOptions struct {
+	Dir       string // Module root, so dependencies resolve to the versions in go.mod
+	MaxOutput int
+}
+
+// goDocCommand builds the go doc invocation for a package and optional symbol
+func goDocCommand(args GoDocArgs) (string, error) {
+	if !goPackagePattern.MatchString(args.Package) {
+		return "", fmt.Errorf("invalid package path %q", args.Package)
+	}
+	command := "go doc"
+	if args.All && args.Symbol == "" {
+		command += " -all"
+	}
+	command += " " + args.Package
+	if args.Symbol != "" {
+		if !goSymbolPattern.MatchString(args.Symbol) {
+			return "", fmt.Errorf("invalid symbol %q (e.g. Client or Client.Do)", args.Symbol)
+		}
+		command += " " + args.Symbol
+	}
+	return command, nil
+}
+
+// pkgsiteURL links to a package's documentation on pkg.go.dev, at the
+// version go.mod requires when the package belongs to a dependency
+func pkgsiteURL(dir, pkg string) string {
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		if dep := FindDependency(ParseGoMod(string(data)), pkg); dep != nil {
+			return "https://pkg.go.dev/" + dep.Name + "@" + dep.Version + strings.TrimPrefix(pkg, dep.Name)
+		}
+	}
+	return "https://pkg.go.dev/" + pkg
+}
+
+// GoDoc returns the go_doc tool
+func GoDoc(opts GoDocOptions) *Tool {
+	return &Tool{
+		Name:        GoDocTool,
+		Description: "Show the documentation and exact signatures of a Go package or symbol (standard library or a dependency, at the version go.mod requires) using go doc. Check this instead of guessing an API.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"package": {"type": "string", "description": "Import path, e.g. net/http or github.com/fsnotify/fsnotify"},
+				"symbol": {"type": "string", "description": "Optional type, function, or Type.Method, e.g. Client.Do"},
+				"all": {"type": "boolean", "description": "Full package documentation instead of a summary (ignored with symbol)"}
+			},
+			"required": ["package"]
+		}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args GoDocArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+			command, err := goDocCommand(args)
+			if err != nil {
+				return "", err
+			}
+
+			res := RunShell(ctx, opts.Dir, command, goDocTimeout, opts.MaxOutput)
+			if res.Err == nil && res.ExitCode == 0 {
+				return res.Output, nil
+			}
+			// Not in the module cache (or not found at all): point at pkg.go.dev
+			return fmt.Sprintf("go doc failed (%s):\n%s\n\nDocumentation online: %s",
+				res.Status(), res.Output, pkgsiteURL(opts.Dir, args.Package)), nil
+		},
+	}
+}
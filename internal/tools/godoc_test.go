@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoDocCommand(t *testing.T) {
+	tests := []struct {
+		args    GoDocArgs
+		want    string
+		wantErr bool
+	}{
+		{GoDocArgs{Package: "net/http"}, "go doc net/http", false},
+		{GoDocArgs{Package: "net/http", All: true}, "go doc -all net/http", false},
+		{GoDocArgs{Package: "net/http", Symbol: "Client.Do", All: true}, "go doc net/http Client.Do", false},
+		{GoDocArgs{Package: "net/http; rm -rf /"}, "", true},
+		{GoDocArgs{Package: "strings", Symbol: "Cut$(id)"}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := goDocCommand(tt.args)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("goDocCommand(%+v) = %q, %v; want %q", tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestPkgsiteURL(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644)
+
+	if got, want := pkgsiteURL(dir, "golang.org/x/sys/unix"), "https://pkg.go.dev/golang.org/x/sys@v0.13.0/unix"; got != want {
+		t.Errorf("pkgsiteURL() = %q, want %q", got, want)
+	}
+	if got, want := pkgsiteURL(dir, "net/http"), "https://pkg.go.dev/net/http"; got != want {
+		t.Errorf("pkgsiteURL() = %q, want %q", got, want)
+	}
+}
+
+func TestGoDoc(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	tool := GoDoc(GoDocOptions{Dir: t.TempDir()})
+
+	out, err := tool.Handler(context.Background(), json.RawMessage(`{"package": "strings", "symbol": "Cut"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "func Cut(s, sep string) (before, after string, found bool)") {
+		t.Errorf("go_doc strings Cut = %q", out)
+	}
+
+	out, _ = tool.Handler(context.Background(), json.RawMessage(`{"package": "example.com/nope"}`))
+	if !strings.Contains(out, "https://pkg.go.dev/example.com/nope") {
+		t.Errorf("missing package = %q", out)
+	}
+}
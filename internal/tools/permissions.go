@@ -0,0 +1,211 @@
+// Package tools - Persistent allow/deny/ask rules for tool calls
+package tools
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// Permission decisions
+const (
+	PermAllow = "allow"
+	PermDeny  = "deny"
+	PermAsk   = "ask"
+)
+
+// PermissionRule decides calls to a tool, or to run_command for commands
+// matching Pattern ("" covers every call of the tool)
+type PermissionRule struct {
+	ID       int64
+	Tool     string
+	Pattern  string
+	Decision string
+}
+
+// Permissions reads and writes the tool_permissions table
+type Permissions struct {
+	db *sql.DB
+}
+
+// NewPermissions creates a permission store
+func NewPermissions(db *sql.DB) *Permissions {
+	return &Permissions{db: db}
+}
+
+// Rules returns every rule, grouped by tool
+func (p *Permissions) Rules() ([]PermissionRule, error) {
+	rows, err := p.db.Query(`SELECT permission_id, tool, pattern, decision FROM tool_permissions ORDER BY tool, pattern`)
+	if err != nil {
+		return nil, fmt.Errorf("list permissions: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]PermissionRule, 0)
+	for rows.Next() {
+		var r PermissionRule
+		if err := rows.Scan(&r.ID, &r.Tool, &r.Pattern, &r.Decision); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// Set records a decision for a tool and pattern, replacing any earlier one
+func (p *Permissions) Set(tool, pattern, decision string) error {
+	switch decision {
+	case PermAllow, PermDeny, PermAsk:
+	default:
+		return fmt.Errorf("unknown decision %q (allow, deny, or ask)", decision)
+	}
+	_, err := p.db.Exec(`
+		INSERT INTO tool_permissions (tool, pattern, decision) VALUES (?, ?, ?)
+		ON CONFLICT(tool, pattern) DO UPDATE SET decision = excluded.decision
+	`, tool, strings.TrimSpace(pattern), decision)
+	if err != nil {
+		return fmt.Errorf("save permission: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes a rule by ID
+func (p *Permissions) Remove(id int64) error {
+	res, err := p.db.Exec(`DELETE FROM tool_permissions WHERE permission_id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("remove permission: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no permission rule %d", id)
+	}
+	return nil
+}
+
+// Decide returns the decision for a call, or "" when no rule applies. The
+// rule with the longest matching pattern wins, and on a tie deny beats ask
+// beats allow. Patterns match whole words (see ruleMatches).
+func (p *Permissions) Decide(tool, command string) (string, error) {
+	rules, err := p.toolRules(tool)
+	if err != nil {
+		return "", err
+	}
+	return decide(rules, command), nil
+}
+
+func (p *Permissions) toolRules(tool string) ([]PermissionRule, error) {
+	rows, err := p.db.Query(`SELECT permission_id, tool, pattern, decision FROM tool_permissions WHERE tool = ?`, tool)
+	if err != nil {
+		return nil, fmt.Errorf("read permissions: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]PermissionRule, 0)
+	for rows.Next() {
+		var r PermissionRule
+		if err := rows.Scan(&r.ID, &r.Tool, &r.Pattern, &r.Decision); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// decisionRank orders decisions for ties: the most cautious wins
+var decisionRank = map[string]int{PermAllow: 0, PermAsk: 1, PermDeny: 2}
+
+func decide(rules []PermissionRule, command string) string {
+	command = strings.TrimSpace(command)
+	best := -1
+	for i, r := range rules {
+		if r.Pattern != "" && !ruleMatches(r, command) {
+			continue
+		}
+		if best < 0 || len(r.Pattern) > len(rules[best].Pattern) ||
+			(len(r.Pattern) == len(rules[best].Pattern) && decisionRank[r.Decision] > decisionRank[rules[best].Decision]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return rules[best].Decision
+}
+
+// commandWrappers run the program named by a later argument
+var commandWrappers = map[string]bool{
+	"env": true, "command": true, "builtin": true, "exec": true, "sudo": true, "doas": true, "su": true,
+	"nohup": true, "nice": true, "ionice": true, "time": true, "timeout": true, "stdbuf": true,
+	"setsid": true, "taskset": true, "xargs": true, "sh": true, "bash": true, "zsh": true, "dash": true,
+}
+
+// ruleMatches reports whether a rule's pattern covers a command, word by
+// word as matchWords. An allow rule only covers a command the allowlist
+// could approve (one program, no shell syntax or risky flag) that is
+// exactly its pattern. Deny and ask rules cover a command when any program
+// it runs matches: they see through chaining, quoting, paths ("/bin/rm"),
+// assignments, and wrappers such as env, sudo, or sh -c.
+func ruleMatches(r PermissionRule, command string) bool {
+	if r.Decision == PermAllow {
+		return autoApprovable(command) && matchWords(command, r.Pattern)
+	}
+	for _, words := range programs(command) {
+		for i := range words {
+			if i > 0 && !commandWrappers[baseName(words[0])] {
+				break // Only a wrapper's arguments may name the program
+			}
+			args := append([]string{baseName(words[i])}, words[i+1:]...)
+			if matchArgs(args, r.Pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// programs splits a command into the words of each program it may run:
+// quotes and backslashes are dropped, shell syntax separates programs, and
+// leading VAR=value assignments are skipped
+func programs(command string) [][]string {
+	command = strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune("'\"\\", r):
+			return -1
+		case strings.ContainsRune(shellMetachars, r):
+			return '\n'
+		}
+		return r
+	}, command)
+
+	var progs [][]string
+	for _, line := range strings.Split(command, "\n") {
+		words := strings.Fields(line)
+		for len(words) > 0 && isAssignment(words[0]) {
+			words = words[1:]
+		}
+		if len(words) > 0 {
+			progs = append(progs, words)
+		}
+	}
+	return progs
+}
+
+// isAssignment reports whether a word sets a variable (NAME=value)
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// baseName strips the directory of a program path
+func baseName(word string) string {
+	return path.Base(strings.ReplaceAll(word, "\\", "/"))
+}
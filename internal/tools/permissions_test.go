@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func TestDecide(t *testing.T) {
+	rules := []PermissionRule{
+		{Tool: RunCommandTool, Pattern: "go test*", Decision: PermAllow},
+		{Tool: RunCommandTool, Pattern: "go test ./internal/danger*", Decision: PermDeny},
+		{Tool: RunCommandTool, Pattern: "rm*", Decision: PermAsk},
+		{Tool: RunCommandTool, Pattern: "make", Decision: PermAllow},
+		{Tool: RunCommandTool, Pattern: "make", Decision: PermDeny},
+	}
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"go test ./...", PermAllow},
+		{"go test ./internal/danger -v", PermDeny}, // Longer pattern wins
+		{"rm -rf build", PermAsk},
+		{"rm -rf build && ls", PermAsk},        // Ask and deny rules see chained commands
+		{"go test ./... && rm -rf /", PermAsk}, // Allow rules never match them
+		{"go test ./... && ls", ""},
+		{"make", PermDeny}, // Tie: the cautious decision
+		{"make install", ""},
+		{"ls", ""},
+		{"go testx", ""},                   // All rules match whole words
+		{"go test -exec ./evil ./...", ""}, // Allow rules never risky flags
+		{"rm-all", ""},
+		{"  rm   -rf build", PermAsk}, // Ask and deny rules see through spacing,
+		{"env rm -rf build", PermAsk}, // wrappers,
+		{"command rm build", PermAsk},
+		{"sudo -u root rm build", PermAsk},
+		{`sh -c "rm -rf build"`, PermAsk}, // quoting,
+		{"/bin/rm build", PermAsk},        // paths,
+		{"FOO=1 rm build", PermAsk},       // and assignments
+		{"git rm build", ""},              // Other programs' arguments are not programs
+	}
+	for _, tt := range tests {
+		if got := decide(rules, tt.command); got != tt.want {
+			t.Errorf("decide(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+
+	toolWide := append(rules, PermissionRule{Tool: RunCommandTool, Decision: PermDeny})
+	if got := decide(toolWide, "ls"); got != PermDeny {
+		t.Errorf("tool-wide rule: decide(ls) = %q, want deny", got)
+	}
+	if got := decide(toolWide, "go test ./..."); got != PermAllow {
+		t.Errorf("pattern beats tool-wide rule: got %q", got)
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	engine, err := core.NewEngine(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	defer engine.Close()
+	p := NewPermissions(engine.DB())
+
+	if err := p.Set(RunCommandTool, "go test*", PermAllow); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Set(RunCommandTool, "go test*", PermAsk); err != nil { // Replaces
+		t.Fatal(err)
+	}
+	if err := p.Set(WebSearchTool, "", PermDeny); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Set(WebSearchTool, "", "maybe"); err == nil {
+		t.Error("Set with an unknown decision: want an error")
+	}
+
+	rules, err := p.Rules()
+	if err != nil || len(rules) != 2 {
+		t.Fatalf("Rules() = %+v, %v; want 2 rules", rules, err)
+	}
+	if got, _ := p.Decide(RunCommandTool, "go test ./..."); got != PermAsk {
+		t.Errorf("Decide(go test) = %q, want ask", got)
+	}
+	if got, _ := p.Decide(WebSearchTool, ""); got != PermDeny {
+		t.Errorf("Decide(web_search) = %q, want deny", got)
+	}
+	if got, _ := p.Decide(ReadFileTool, ""); got != "" {
+		t.Errorf("Decide(read_file) = %q, want no rule", got)
+	}
+
+	if err := p.Remove(rules[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Remove(rules[0].ID); err == nil {
+		t.Error("removing a missing rule: want an error")
+	}
+}
+
+func TestPermissions_NextLaunch(t *testing.T) {
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// "Always allow" in one launch, then a restart on the default database
+	first, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewPermissions(first.DB()).Set(RunCommandTool, "go test*", PermAllow); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	second, err := core.NewEngine("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if got, err := NewPermissions(second.DB()).Decide(RunCommandTool, "go test ./..."); err != nil || got != PermAllow {
+		t.Errorf("Expected the rule to outlast the restart, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,110 @@
+// Package tools - Reading workspace files on demand
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// ReadFileTool is the name of the file reading tool
+const ReadFileTool = "read_file"
+
+// ReadFileArgs are the arguments of read_file
+type ReadFileArgs struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line,omitempty"` // 1-based, inclusive
+	EndLine   int    `json:"end_line,omitempty"`   // 1-based, inclusive
+}
+
+// ReadFileOptions configure read_file
+type ReadFileOptions struct {
+	// Resolve validates a requested path (workspace bounds, .goclodeignore,
+	// protected paths) and returns the path to open
+	Resolve  func(path string) (string, error)
+	MaxBytes int
+	// Screen checks what was read for embedded instructions before it
+	// reaches the model; nil passes it through
+	Screen func(source, text string) string
+}
+
+// ReadFile returns the read_file tool
+func ReadFile(opts ReadFileOptions) *Tool {
+	return &Tool{
+		Name:        ReadFileTool,
+		Description: "Read a file from the project, optionally only a range of lines. Use it to look at code before editing it.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string", "description": "File path relative to the project root"},
+				"start_line": {"type": "integer", "description": "First line to read (1-based)"},
+				"end_line": {"type": "integer", "description": "Last line to read (inclusive)"}
+			},
+			"required": ["path"]
+		}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args ReadFileArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+
+			path, err := opts.Resolve(args.Path)
+			if err != nil {
+				return "", err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("read %s: %w", args.Path, err)
+			}
+			if workspace.IsBinary(data) {
+				return "", fmt.Errorf("%s is a binary file", args.Path)
+			}
+
+			out := readRange(args.Path, string(data), args.StartLine, args.EndLine, opts.MaxBytes)
+			if opts.Screen != nil {
+				out = opts.Screen(args.Path, out)
+			}
+			return out, nil
+		},
+	}
+}
+
+// readRange formats lines start..end of content (0 means the file's first
+// or last line), cut at maxBytes with a note saying where to continue
+func readRange(path, content string, start, end, maxBytes int) string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	total := len(lines)
+
+	if start < 1 {
+		start = 1
+	}
+	if end < 1 || end > total {
+		end = total
+	}
+	if start > end {
+		return fmt.Sprintf("%s has %d lines; nothing in the requested range", path, total)
+	}
+
+	var body strings.Builder
+	last := start - 1
+	for _, line := range lines[start-1 : end] {
+		if maxBytes > 0 && body.Len()+len(line) > maxBytes {
+			break
+		}
+		body.WriteString(line)
+		last++
+	}
+
+	header := fmt.Sprintf("%s (lines %d-%d of %d)", path, start, last, total)
+	if last < end {
+		return fmt.Sprintf("%s\n%s\n... (truncated; continue with start_line=%d)", header, body.String(), last+1)
+	}
+	return header + "\n" + body.String()
+}
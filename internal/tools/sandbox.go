@@ -0,0 +1,167 @@
+// Package tools - Running commands inside a per-project container
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SandboxWorkdir is where the workspace is mounted in the container
+const SandboxWorkdir = "/workspace"
+
+// sandboxSetupTimeout bounds inspecting, creating, and removing the container
+const sandboxSetupTimeout = 5 * time.Minute
+
+var containerNameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// Sandbox runs commands in a long-lived container of the project: the
+// workspace is bind-mounted at /workspace, and the network is off unless
+// enabled. The container is created on first use and kept between sessions
+// so toolchain caches survive; Remove discards it (e.g. after changing the
+// image). A command that times out is cut off from GoClode, but processes
+// it started may keep running in the container until it is removed.
+type Sandbox struct {
+	Runtime string // docker or podman (any CLI with the same run/exec/inspect verbs)
+	Image   string
+	Root    string // Host workspace
+	Network bool
+	Name    string
+
+	mu    sync.Mutex
+	ready bool
+}
+
+// NewSandbox describes the container of the workspace at root
+func NewSandbox(runtime, image, root string, network bool) *Sandbox {
+	return &Sandbox{
+		Runtime: runtime,
+		Image:   image,
+		Root:    root,
+		Network: network,
+		Name:    ContainerName(root),
+	}
+}
+
+// ContainerName names a workspace's container after its directory and a
+// hash of its path, so each project gets its own
+func ContainerName(root string) string {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	sum := sha256.Sum256([]byte(root))
+	base := strings.Trim(containerNameUnsafe.ReplaceAllString(filepath.Base(root), "-"), "-.")
+	if base == "" {
+		base = "workspace"
+	}
+	return "goclode-" + base + "-" + hex.EncodeToString(sum[:4])
+}
+
+// Status reports whether the container exists and is running
+func (s *Sandbox) Status(ctx context.Context) (exists, running bool) {
+	res := runProcess(ctx, "", sandboxSetupTimeout, 0, s.Runtime, "inspect", "-f", "{{.State.Running}}", s.Name)
+	if res.Err != nil || res.ExitCode != 0 {
+		return false, false
+	}
+	return true, strings.TrimSpace(res.Output) == "true"
+}
+
+// Start makes sure the container is running, creating it when needed
+func (s *Sandbox) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ready {
+		return nil
+	}
+
+	exists, running := s.Status(ctx)
+	var res CommandResult
+	switch {
+	case running:
+	case exists:
+		res = runProcess(ctx, "", sandboxSetupTimeout, 0, s.Runtime, "start", s.Name)
+	default:
+		if s.Image == "" {
+			return fmt.Errorf("no sandbox image configured (set sandbox_image)")
+		}
+		res = runProcess(ctx, "", sandboxSetupTimeout, 0, s.Runtime, s.createArgs()...)
+	}
+	if res.Err != nil || res.ExitCode != 0 {
+		return fmt.Errorf("start sandbox %s: %s", s.Name, res.String())
+	}
+	s.ready = true
+	return nil
+}
+
+// createArgs are the arguments that create the container. Commands run as
+// the host user so files they write in the workspace keep their owner.
+func (s *Sandbox) createArgs() []string {
+	root, err := filepath.Abs(s.Root)
+	if err != nil {
+		root = s.Root
+	}
+	args := []string{"run", "-d", "--name", s.Name,
+		"-v", root + ":" + SandboxWorkdir, "-w", SandboxWorkdir,
+		"-e", "HOME=/tmp", "--label", "goclode.workspace=" + root}
+	if !s.Network {
+		args = append(args, "--network", "none")
+	}
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 && gid >= 0 {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", uid, gid))
+	}
+	return append(args, s.Image, "sleep", "infinity")
+}
+
+// Run runs a command in the container, in the directory matching dir; it
+// is a Shell
+func (s *Sandbox) Run(ctx context.Context, dir, command string, timeout time.Duration, maxOutput int) CommandResult {
+	workdir, err := s.containerPath(dir)
+	if err != nil {
+		return CommandResult{Err: err, ExitCode: -1}
+	}
+	if err := s.Start(ctx); err != nil {
+		return CommandResult{Err: err, ExitCode: -1}
+	}
+
+	res := runProcess(ctx, "", timeout, maxOutput, s.Runtime, "exec", "-w", workdir, s.Name, "sh", "-c", command)
+	if res.Err != nil || res.ExitCode != 0 {
+		// The container may have been stopped or removed behind our back:
+		// check again before the next command
+		s.mu.Lock()
+		s.ready = false
+		s.mu.Unlock()
+	}
+	return res
+}
+
+// containerPath maps a host directory inside the workspace to the container
+func (s *Sandbox) containerPath(dir string) (string, error) {
+	if dir == "" {
+		return SandboxWorkdir, nil
+	}
+	rel, err := filepath.Rel(s.Root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the sandboxed workspace", dir)
+	}
+	return path.Join(SandboxWorkdir, filepath.ToSlash(rel)), nil
+}
+
+// Remove deletes the container; the next command creates a fresh one
+func (s *Sandbox) Remove(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = false
+	res := runProcess(ctx, "", sandboxSetupTimeout, 0, s.Runtime, "rm", "-f", s.Name)
+	if res.Err != nil || res.ExitCode != 0 {
+		return fmt.Errorf("remove sandbox %s: %s", s.Name, res.String())
+	}
+	return nil
+}
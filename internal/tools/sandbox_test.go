@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRuntime writes a docker stand-in that logs its arguments, knows no
+// container until "run" creates one, and runs exec'd commands on the host
+func fakeRuntime(t *testing.T) (bin, log string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	dir := t.TempDir()
+	bin = filepath.Join(dir, "docker")
+	log = filepath.Join(dir, "calls")
+	state := filepath.Join(dir, "created")
+	script := `#!/bin/sh
+echo "$@" >> ` + log + `
+case "$1" in
+inspect) [ -f ` + state + ` ] && echo true && exit 0; echo "no such container" >&2; exit 1 ;;
+run) touch ` + state + `; echo 0123abcd ;;
+rm) rm -f ` + state + ` ;;
+exec) shift; while [ "$1" != "sh" ]; do shift; done; exec "$@" ;;
+esac
+`
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return bin, log
+}
+
+func TestSandboxRun(t *testing.T) {
+	bin, log := fakeRuntime(t)
+	root := t.TempDir()
+	s := NewSandbox(bin, "golang:1.22", root, false)
+
+	res := s.Run(context.Background(), filepath.Join(root, "cmd"), "echo hi", time.Minute, 0)
+	if res.ExitCode != 0 || res.Output != "hi" {
+		t.Fatalf("Run = %+v", res)
+	}
+	res = s.Run(context.Background(), root, "exit 3", time.Minute, 0)
+	if res.ExitCode != 3 {
+		t.Errorf("exit code = %d, want 3", res.ExitCode)
+	}
+
+	data, _ := os.ReadFile(log)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{"inspect", "run -d --name " + s.Name, "exec -w /workspace/cmd " + s.Name, "exec -w /workspace " + s.Name}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %q", calls)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(calls[i], prefix) {
+			t.Errorf("call %d = %q, want prefix %q", i, calls[i], prefix)
+		}
+	}
+	if !strings.Contains(calls[1], "--network none") || !strings.Contains(calls[1], root+":/workspace") {
+		t.Errorf("create call = %q", calls[1])
+	}
+
+	if res := s.Run(context.Background(), t.TempDir(), "true", time.Minute, 0); res.Err == nil {
+		t.Error("expected an error for a directory outside the workspace")
+	}
+}
+
+func TestContainerName(t *testing.T) {
+	a, b := ContainerName("/src/My App"), ContainerName("/other/My App")
+	if !strings.HasPrefix(a, "goclode-My-App-") {
+		t.Errorf("ContainerName = %q", a)
+	}
+	if a == b {
+		t.Errorf("same name %q for different workspaces", a)
+	}
+}
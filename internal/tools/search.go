@@ -0,0 +1,139 @@
+// Package tools - Regex search over the workspace
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// SearchTool is the name of the code search tool
+const SearchTool = "search"
+
+const (
+	maxSearchFileBytes = 1024 * 1024 // Larger files are skipped
+	maxSearchLineLen   = 200         // Longer matching lines are cut
+)
+
+// SearchArgs are the arguments of search
+type SearchArgs struct {
+	Pattern         string `json:"pattern"`
+	Glob            string `json:"glob,omitempty"`
+	CaseInsensitive bool   `json:"case_insensitive,omitempty"`
+}
+
+// SearchOptions configure search
+type SearchOptions struct {
+	Root       string
+	Files      func() []string               // Candidate files, relative to Root (already gitignore-filtered)
+	Filter     func() func(path string) bool // Builds a per-search check excluding protected paths
+	MaxResults int
+}
+
+// Search returns the search tool
+func Search(opts SearchOptions) *Tool {
+	return &Tool{
+		Name:        SearchTool,
+		Description: "Search the project's files with a regular expression (RE2 syntax) to find definitions and usages. Returns path:line: text for each match.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"pattern": {"type": "string", "description": "Regular expression, e.g. func \\w+Handler"},
+				"glob": {"type": "string", "description": "Only search matching files, e.g. *.go or internal/*"},
+				"case_insensitive": {"type": "boolean"}
+			},
+			"required": ["pattern"]
+		}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args SearchArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+
+			pattern := args.Pattern
+			if args.CaseInsensitive {
+				pattern = "(?i)" + pattern
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return "", fmt.Errorf("invalid pattern: %w", err)
+			}
+
+			matches, truncated := SearchFiles(ctx, opts, re, args.Glob)
+			if len(matches) == 0 {
+				return "no matches", nil
+			}
+			out := strings.Join(matches, "\n")
+			if truncated {
+				out += fmt.Sprintf("\n... (stopped after %d matches; narrow the pattern or glob)", len(matches))
+			}
+			return out, nil
+		},
+	}
+}
+
+// SearchFiles returns "path:line: text" for lines matching re, and whether
+// it stopped at opts.MaxResults
+func SearchFiles(ctx context.Context, opts SearchOptions, re *regexp.Regexp, glob string) ([]string, bool) {
+	allowed := func(string) bool { return true }
+	if opts.Filter != nil {
+		allowed = opts.Filter()
+	}
+
+	matches := make([]string, 0)
+	for _, file := range opts.Files() {
+		if ctx.Err() != nil {
+			break
+		}
+		if !matchGlob(glob, file) || !allowed(file) {
+			continue
+		}
+
+		full := filepath.Join(opts.Root, filepath.FromSlash(file))
+		if info, err := os.Stat(full); err != nil || info.Size() > maxSearchFileBytes {
+			continue
+		}
+		data, err := os.ReadFile(full)
+		if err != nil || workspace.IsBinary(data) {
+			continue
+		}
+
+		for i, line := range strings.Split(string(data), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			line = strings.TrimRight(line, "\r")
+			if len(line) > maxSearchLineLen {
+				line = line[:maxSearchLineLen] + "..."
+			}
+			matches = append(matches, fmt.Sprintf("%s:%d: %s", file, i+1, line))
+			if opts.MaxResults > 0 && len(matches) >= opts.MaxResults {
+				return matches, true
+			}
+		}
+	}
+	return matches, false
+}
+
+// matchGlob matches a slash path against a glob by full path, by base name,
+// or as a directory prefix ("internal/" or "internal/*")
+func matchGlob(glob, file string) bool {
+	if glob == "" {
+		return true
+	}
+	if ok, _ := path.Match(glob, file); ok {
+		return true
+	}
+	if ok, _ := path.Match(glob, path.Base(file)); ok {
+		return true
+	}
+	dir := strings.TrimSuffix(strings.TrimSuffix(glob, "*"), "/")
+	return dir != "" && !strings.ContainsAny(dir, "*?[") && strings.HasPrefix(file, dir+"/")
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		glob, file string
+		want       bool
+	}{
+		{"", "a/b.go", true},
+		{"*.go", "a/b.go", true},
+		{"*.go", "a/b.py", false},
+		{"internal/*", "internal/ui/chat.go", true},
+		{"internal/", "internal/ui/chat.go", true},
+		{"internal/ui/*.go", "internal/ui/chat.go", true},
+		{"cmd/*", "internal/ui/chat.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.glob, tt.file); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.glob, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":       "package main\n\nfunc Run() {}\n",
+		"pkg/util.go":   "package pkg\n\nfunc Helper() { Run() }\n",
+		"secret.go":     "func Run() // protected\n",
+		"notes/todo.md": "call run() later\n",
+	}
+	names := make([]string, 0)
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		names = append(names, name)
+	}
+
+	tool := Search(SearchOptions{
+		Root:  dir,
+		Files: func() []string { return names },
+		Filter: func() func(string) bool {
+			return func(path string) bool { return path != "secret.go" }
+		},
+	})
+	search := func(args SearchArgs) string {
+		raw, _ := json.Marshal(args)
+		out, err := tool.Handler(context.Background(), raw)
+		if err != nil {
+			t.Fatalf("search %+v: %v", args, err)
+		}
+		return out
+	}
+
+	out := search(SearchArgs{Pattern: `Run\(\)`, Glob: "*.go"})
+	if !strings.Contains(out, "main.go:3: func Run() {}") || !strings.Contains(out, "pkg/util.go:3:") {
+		t.Errorf("search = %q, want both Go matches", out)
+	}
+	if strings.Contains(out, "secret.go") || strings.Contains(out, "todo.md") {
+		t.Errorf("search = %q, want protected and non-matching files left out", out)
+	}
+
+	if out := search(SearchArgs{Pattern: `run\(\)`, CaseInsensitive: true, Glob: "notes/"}); out != "notes/todo.md:1: call run() later" {
+		t.Errorf("case-insensitive search = %q", out)
+	}
+	if out := search(SearchArgs{Pattern: "nothing here"}); out != "no matches" {
+		t.Errorf("search without matches = %q", out)
+	}
+}
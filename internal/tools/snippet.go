@@ -0,0 +1,119 @@
+// Package tools - Running scratch code outside the project's files
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RunSnippetTool is the name of the scratch code tool
+const RunSnippetTool = "run_snippet"
+
+// ScratchDir holds snippet directories, relative to the workspace root.
+// It is inside the workspace so a sandbox container sees it too.
+const ScratchDir = ".goclode/scratch"
+
+var goPackageClause = regexp.MustCompile(`(?m)^package\s+\w+`)
+
+// RunSnippetArgs are the arguments of run_snippet
+type RunSnippetArgs struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// SnippetOptions configure run_snippet
+type SnippetOptions struct {
+	Root      string // Workspace root; snippets run under ScratchDir
+	Timeout   time.Duration
+	MaxOutput int
+	Approve   func(language, code string) bool // Asked before every run
+	Shell     Shell                            // Runs the snippet (nil: RunShell on the host)
+}
+
+// snippetFiles returns the files a snippet needs and the command that runs it
+func snippetFiles(language, code string) (map[string]string, string, error) {
+	switch strings.ToLower(language) {
+	case "go", "golang":
+		if !goPackageClause.MatchString(code) {
+			code = "package main\n\n" + code
+		}
+		return map[string]string{
+			"go.mod":  "module scratch\n",
+			"main.go": code,
+		}, "go run .", nil
+	case "python", "python3", "py":
+		return map[string]string{"snippet.py": code}, "python3 snippet.py", nil
+	}
+	return nil, "", fmt.Errorf("unsupported language %q (go or python)", language)
+}
+
+// ExecuteSnippet writes a snippet to a fresh scratch directory, runs it,
+// and removes the directory
+func ExecuteSnippet(ctx context.Context, opts SnippetOptions, language, code string) (CommandResult, error) {
+	files, command, err := snippetFiles(language, code)
+	if err != nil {
+		return CommandResult{}, err
+	}
+
+	base := filepath.Join(opts.Root, ScratchDir)
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return CommandResult{}, fmt.Errorf("create scratch dir: %w", err)
+	}
+	dir, err := os.MkdirTemp(base, "snippet-")
+	if err != nil {
+		return CommandResult{}, fmt.Errorf("create scratch dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return CommandResult{}, fmt.Errorf("write snippet: %w", err)
+		}
+	}
+
+	run := opts.Shell
+	if run == nil {
+		run = RunShell
+	}
+	return run(ctx, dir, command, opts.Timeout, opts.MaxOutput), nil
+}
+
+// RunSnippet returns the run_snippet tool
+func RunSnippet(opts SnippetOptions) *Tool {
+	return &Tool{
+		Name:        RunSnippetTool,
+		Description: "Run a small Go or Python program in a scratch directory outside the project and return its exit code and output. Use it to check a computation, try an API, or reproduce a bug before editing real files. Go snippets are a complete main program that can only import the standard library; the package clause may be omitted.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"language": {"type": "string", "enum": ["go", "python"]},
+				"code": {"type": "string", "description": "Program source"}
+			},
+			"required": ["language", "code"]
+		}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args RunSnippetArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+			if strings.TrimSpace(args.Code) == "" {
+				return "", fmt.Errorf("code is empty")
+			}
+
+			if opts.Approve != nil && !opts.Approve(args.Language, args.Code) {
+				return "not run: the user declined this snippet", nil
+			}
+			result, err := ExecuteSnippet(ctx, opts, args.Language, args.Code)
+			if err != nil {
+				return "", err
+			}
+			return result.String(), nil
+		},
+	}
+}
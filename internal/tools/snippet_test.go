@@ -0,0 +1,66 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnippetFiles(t *testing.T) {
+	files, command, err := snippetFiles("go", "import \"fmt\"\n\nfunc main() { fmt.Println(1) }\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if command != "go run ." || !strings.HasPrefix(files["main.go"], "package main\n") || files["go.mod"] == "" {
+		t.Errorf("go snippet = %q, %v", command, files)
+	}
+
+	files, _, _ = snippetFiles("go", "package main\n\nfunc main() {}\n")
+	if strings.Count(files["main.go"], "package ") != 1 {
+		t.Errorf("package clause added twice:\n%s", files["main.go"])
+	}
+
+	if _, _, err := snippetFiles("ruby", "puts 1"); err == nil {
+		t.Error("expected an error for an unsupported language")
+	}
+}
+
+func TestRunSnippet(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	root := t.TempDir()
+	var approved string
+	tool := RunSnippet(SnippetOptions{
+		Root:    root,
+		Timeout: time.Minute,
+		Approve: func(language, code string) bool {
+			approved = language
+			return language == "go"
+		},
+	})
+
+	out, err := tool.Handler(context.Background(), json.RawMessage(`{"language": "go", "code": "import \"fmt\"\n\nfunc main() { fmt.Println(6 * 7) }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved != "go" || !strings.HasPrefix(out, "exit code 0") || !strings.HasSuffix(out, "42") {
+		t.Errorf("run_snippet = %q", out)
+	}
+
+	out, _ = tool.Handler(context.Background(), json.RawMessage(`{"language": "python", "code": "print(1)"}`))
+	if !strings.Contains(out, "declined") {
+		t.Errorf("declined snippet = %q", out)
+	}
+
+	// Scratch directories are removed after each run
+	entries, _ := os.ReadDir(filepath.Join(root, ScratchDir))
+	if len(entries) != 0 {
+		t.Errorf("scratch dir not cleaned up: %v", entries)
+	}
+}
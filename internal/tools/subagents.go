@@ -0,0 +1,138 @@
+// Package tools - Delegating independent subtasks to parallel sub-agents
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DelegateTool is the name of the sub-agent tool
+const DelegateTool = "delegate"
+
+// SubTask is one independent piece of work for a sub-agent
+type SubTask struct {
+	Name string `json:"name"`
+	Task string `json:"task"`
+}
+
+// SubResult is what a sub-agent reports back
+type SubResult struct {
+	Name    string
+	Summary string // The sub-agent's final reply
+	Tokens  int    // Tokens spent, prompt and completion
+	Rounds  int    // Model calls made
+	Err     error  // Set when the sub-agent failed or hit its budget
+}
+
+// RunSubAgents runs tasks with at most limit running at once and returns
+// their results in task order
+func RunSubAgents(ctx context.Context, tasks []SubTask, limit int, run func(ctx context.Context, task SubTask) SubResult) []SubResult {
+	if limit <= 0 {
+		limit = 1
+	}
+	results := make([]SubResult, len(tasks))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task SubTask) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = SubResult{Name: task.Name, Err: ctx.Err()}
+				return
+			}
+			results[i] = run(ctx, task)
+			results[i].Name = task.Name
+		}(i, task)
+	}
+	wg.Wait()
+	return results
+}
+
+// FormatSubResults merges sub-agent results into one report for the model
+func FormatSubResults(results []SubResult) string {
+	var sb strings.Builder
+	total := 0
+	for _, r := range results {
+		status := "done"
+		if r.Err != nil {
+			status = "failed: " + r.Err.Error()
+		}
+		fmt.Fprintf(&sb, "## %s (%s; %d rounds, %d tokens)\n", r.Name, status, r.Rounds, r.Tokens)
+		if summary := strings.TrimSpace(r.Summary); summary != "" {
+			sb.WriteString(summary + "\n")
+		}
+		sb.WriteString("\n")
+		total += r.Tokens
+	}
+	fmt.Fprintf(&sb, "%d sub-agents, %d tokens in total", len(results), total)
+	return sb.String()
+}
+
+// DelegateArgs are the arguments of delegate
+type DelegateArgs struct {
+	Tasks []SubTask `json:"tasks"`
+}
+
+// DelegateOptions configure delegate
+type DelegateOptions struct {
+	MaxTasks func() int // Tasks accepted per call
+	// Run carries out the tasks (each in its own context and conversation)
+	// and returns their results
+	Run func(ctx context.Context, tasks []SubTask) []SubResult
+}
+
+// Delegate returns the delegate tool
+func Delegate(opts DelegateOptions) *Tool {
+	return &Tool{
+		Name:        DelegateTool,
+		Description: "Hand independent subtasks (e.g. \"write tests for parser.go\" and \"update the README\") to sub-agents that work in parallel with the same tools but a fresh context, and get a summary of what each did. Only for tasks that do not depend on each other; describe each task completely, since sub-agents do not see this conversation.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"tasks": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {
+							"name": {"type": "string", "description": "Short label, e.g. tests"},
+							"task": {"type": "string", "description": "Complete, self-contained instructions"}
+						},
+						"required": ["name", "task"]
+					}
+				}
+			},
+			"required": ["tasks"]
+		}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args DelegateArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+
+			tasks := make([]SubTask, 0, len(args.Tasks))
+			for i, t := range args.Tasks {
+				if strings.TrimSpace(t.Task) == "" {
+					continue
+				}
+				if strings.TrimSpace(t.Name) == "" {
+					t.Name = fmt.Sprintf("task %d", i+1)
+				}
+				tasks = append(tasks, t)
+			}
+			if len(tasks) == 0 {
+				return "", fmt.Errorf("no tasks given")
+			}
+			if max := opts.MaxTasks(); max > 0 && len(tasks) > max {
+				return "", fmt.Errorf("%d tasks given, at most %d per call", len(tasks), max)
+			}
+			return FormatSubResults(opts.Run(ctx, tasks)), nil
+		},
+	}
+}
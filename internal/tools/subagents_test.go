@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunSubAgents(t *testing.T) {
+	tasks := []SubTask{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	results := RunSubAgents(context.Background(), tasks, 2, func(ctx context.Context, task SubTask) SubResult {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return SubResult{Summary: "did " + task.Name, Tokens: 10}
+	})
+
+	if peak > 2 {
+		t.Errorf("%d sub-agents ran at once, limit 2", peak)
+	}
+	for i, r := range results {
+		if r.Name != tasks[i].Name || r.Summary != "did "+tasks[i].Name {
+			t.Errorf("result %d = %+v", i, r)
+		}
+	}
+}
+
+func TestRunSubAgentsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := RunSubAgents(ctx, []SubTask{{Name: "a"}, {Name: "b"}}, 1, func(ctx context.Context, task SubTask) SubResult {
+		return SubResult{Err: ctx.Err()}
+	})
+	for _, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("%s: err = %v, want context.Canceled", r.Name, r.Err)
+		}
+	}
+}
+
+func TestDelegate(t *testing.T) {
+	var got []SubTask
+	tool := Delegate(DelegateOptions{
+		MaxTasks: func() int { return 2 },
+		Run: func(ctx context.Context, tasks []SubTask) []SubResult {
+			got = tasks
+			return []SubResult{
+				{Name: tasks[0].Name, Summary: "Added parser tests.", Rounds: 3, Tokens: 1200},
+				{Name: tasks[1].Name, Err: errors.New("stopped at the token budget"), Rounds: 5, Tokens: 50000},
+			}
+		},
+	})
+
+	out, err := tool.Handler(context.Background(), json.RawMessage(`{"tasks": [{"name": "tests", "task": "Write tests"}, {"task": "Update docs"}, {"name": "empty", "task": " "}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].Name != "task 2" {
+		t.Errorf("tasks = %+v", got)
+	}
+	for _, want := range []string{"## tests (done; 3 rounds, 1200 tokens)", "Added parser tests.", "## task 2 (failed: stopped at the token budget", "51200 tokens in total"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := tool.Handler(context.Background(), json.RawMessage(`{"tasks": [{"task": "a"}, {"task": "b"}, {"task": "c"}]}`)); err == nil {
+		t.Error("expected an error above MaxTasks")
+	}
+}
@@ -0,0 +1,126 @@
+// Package tools - Running the project's tests
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RunTestsTool is the name of the test runner tool
+const RunTestsTool = "run_tests"
+
+// testDetectors map a marker file to the test command it implies, in priority order
+var testDetectors = []struct {
+	marker  string
+	command string
+}{
+	{"go.mod", "go test ./..."},
+	{"package.json", "npm test"},
+	{"pytest.ini", "pytest"},
+	{"pyproject.toml", "pytest"},
+	{"setup.py", "pytest"},
+}
+
+// DetectTestCommand guesses the test command from the project's files; "" if unknown
+func DetectTestCommand(dir string) string {
+	for _, d := range testDetectors {
+		if _, err := os.Stat(filepath.Join(dir, d.marker)); err == nil {
+			return d.command
+		}
+	}
+	return ""
+}
+
+var testFailurePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^\s*--- FAIL: (\S+)`),                   // go test
+	regexp.MustCompile(`^FAIL\s+(\S+)\s+\[build failed\]`),      // go test, package does not compile
+	regexp.MustCompile(`^FAILED (\S+)`),                         // pytest -q summary
+	regexp.MustCompile(`^\s*(?:✕|×|●) (.+?)(?: \(\d+ ?ms\))?$`), // jest
+}
+
+// TestFailures extracts the names of failing tests from test output
+func TestFailures(output string) []string {
+	failures := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		for _, p := range testFailurePatterns {
+			if m := p.FindStringSubmatch(line); m != nil && !seen[m[1]] {
+				seen[m[1]] = true
+				failures = append(failures, m[1])
+				break
+			}
+		}
+	}
+	return failures
+}
+
+// TestResult is the outcome of a test run
+type TestResult struct {
+	Command  string
+	Failures []string
+	CommandResult
+}
+
+// Passed reports whether the tests ran and succeeded
+func (r TestResult) Passed() bool {
+	return r.Err == nil && r.ExitCode == 0
+}
+
+// Report formats a failed run for the model
+func (r TestResult) Report() string {
+	var sb strings.Builder
+	sb.WriteString("$ " + r.Command + "\n")
+	if len(r.Failures) > 0 {
+		sb.WriteString("Failing: " + strings.Join(r.Failures, ", ") + "\n")
+	}
+	sb.WriteString(r.CommandResult.String())
+	return sb.String()
+}
+
+// ExecuteTests runs a test command with shell (nil: RunShell) and parses
+// its failures
+func ExecuteTests(ctx context.Context, shell Shell, dir, command string, timeout time.Duration, maxOutput int) TestResult {
+	if shell == nil {
+		shell = RunShell
+	}
+	result := TestResult{Command: command}
+	result.CommandResult = shell(ctx, dir, command, timeout, maxOutput)
+	if !result.Passed() {
+		result.Failures = TestFailures(result.Output)
+	}
+	return result
+}
+
+// RunTestsOptions configure run_tests
+type RunTestsOptions struct {
+	Dir       string
+	Command   func() string // Test command at call time ("" when none is known)
+	Timeout   time.Duration
+	MaxOutput int
+	Shell     Shell // Runs the tests (nil: RunShell on the host)
+}
+
+// RunTests returns the run_tests tool
+func RunTests(opts RunTestsOptions) *Tool {
+	return &Tool{
+		Name:        RunTestsTool,
+		Description: "Run the project's test suite and return the failing tests and output.",
+		Parameters:  json.RawMessage(`{"type": "object", "properties": {}}`),
+		Handler: func(ctx context.Context, _ json.RawMessage) (string, error) {
+			command := opts.Command()
+			if command == "" {
+				return "no test command configured or detected", nil
+			}
+			result := ExecuteTests(ctx, opts.Shell, opts.Dir, command, opts.Timeout, opts.MaxOutput)
+			if result.Passed() {
+				return "$ " + command + "\nall tests passed", nil
+			}
+			return result.Report(), nil
+		},
+	}
+}
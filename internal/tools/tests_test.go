@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTestFailures(t *testing.T) {
+	output := `=== RUN   TestA
+--- FAIL: TestA (0.00s)
+    a_test.go:10: boom
+    --- FAIL: TestA/sub (0.00s)
+FAIL
+FAIL	example.com/pkg	0.01s
+FAIL	example.com/broken [build failed]
+FAILED tests/test_x.py::test_y - AssertionError
+  ✕ renders header (12 ms)`
+
+	got := TestFailures(output)
+	want := []string{"TestA", "TestA/sub", "example.com/broken", "tests/test_x.py::test_y", "renders header"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("TestFailures = %q, want %q", got, want)
+	}
+}
+
+func TestDetectTestCommand(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"go.mod"}, "go test ./..."},
+		{[]string{"package.json"}, "npm test"},
+		{[]string{"pyproject.toml"}, "pytest"},
+		{[]string{"go.mod", "package.json"}, "go test ./..."},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		for _, f := range tt.files {
+			os.WriteFile(filepath.Join(dir, f), nil, 0644)
+		}
+		if got := DetectTestCommand(dir); got != tt.want {
+			t.Errorf("DetectTestCommand(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
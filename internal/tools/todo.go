@@ -0,0 +1,76 @@
+// Package tools - The agent's todo list for multi-step work
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TodoTool is the name of the todo list tool
+const TodoTool = "todo"
+
+// TodoItem is one entry of the todo list
+type TodoItem struct {
+	Content string `json:"content"`
+	Status  string `json:"status"` // pending, in_progress, or done
+}
+
+// TodoArgs are the arguments of todo
+type TodoArgs struct {
+	Todos []TodoItem `json:"todos"`
+}
+
+// TodoOptions configure todo
+type TodoOptions struct {
+	Save func(items []TodoItem) error // Replaces the stored list
+	List func() string                // The stored list, formatted
+}
+
+// Todo returns the todo tool
+func Todo(opts TodoOptions) *Tool {
+	return &Tool{
+		Name:        TodoTool,
+		Description: "Record the plan for multi-step work as a todo list the user sees and that survives restarts. Send the whole list each time: add steps as pending, mark the one you are working on in_progress (only one at a time), and mark steps done as soon as they are finished. Skip it for simple one-step requests.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"todos": {
+					"type": "array",
+					"items": {
+						"type": "object",
+						"properties": {
+							"content": {"type": "string", "description": "What the step does"},
+							"status": {"type": "string", "enum": ["pending", "in_progress", "done"]}
+						},
+						"required": ["content", "status"]
+					}
+				}
+			},
+			"required": ["todos"]
+		}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args TodoArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+
+			items := make([]TodoItem, 0, len(args.Todos))
+			for _, item := range args.Todos {
+				item.Content = strings.TrimSpace(item.Content)
+				if item.Content == "" {
+					continue
+				}
+				if item.Status == "" {
+					item.Status = "pending"
+				}
+				items = append(items, item)
+			}
+			if err := opts.Save(items); err != nil {
+				return "", err
+			}
+			return opts.List(), nil
+		},
+	}
+}
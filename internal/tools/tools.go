@@ -0,0 +1,93 @@
+// Package tools provides functions the LLM can invoke through tool calling.
+// Each tool has a JSON schema sent with the request and a handler run when
+// the model calls it; results are fed back as tool messages.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+// Handler executes a tool with its JSON-encoded arguments
+type Handler func(ctx context.Context, args json.RawMessage) (string, error)
+
+// Tool is a function exposed to the model
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage // JSON schema of the arguments object
+	Handler     Handler
+}
+
+// Registry holds the tools available to the model
+type Registry struct {
+	tools map[string]*Tool
+	mu    sync.RWMutex
+}
+
+// NewRegistry creates an empty tool registry
+func NewRegistry() *Registry {
+	return &Registry{tools: make(map[string]*Tool)}
+}
+
+// Register adds or replaces a tool
+func (r *Registry) Register(t *Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[t.Name] = t
+}
+
+// Get returns a tool by name
+func (r *Registry) Get(name string) (*Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// Definitions returns the provider-facing definitions, sorted by name
+func (r *Registry) Definitions() []providers.Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]providers.Tool, 0, len(r.tools))
+	for _, t := range r.tools {
+		defs = append(defs, providers.Tool{
+			Type: "function",
+			Function: providers.ToolFunction{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			},
+		})
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Function.Name < defs[j].Function.Name
+	})
+	return defs
+}
+
+// Call runs the tool a model requested
+func (r *Registry) Call(ctx context.Context, call providers.ToolCall) (string, error) {
+	t, ok := r.Get(call.Function.Name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Function.Name)
+	}
+	if t.Handler == nil {
+		return "", fmt.Errorf("tool %q cannot be called directly", t.Name)
+	}
+
+	args := json.RawMessage(call.Function.Arguments)
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	if !json.Valid(args) {
+		return "", fmt.Errorf("invalid arguments for %s: not JSON", t.Name)
+	}
+	return t.Handler(ctx, args)
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	for _, tool := range FileTools() {
+		r.Register(tool)
+	}
+	r.Register(&Tool{
+		Name:       "echo",
+		Parameters: json.RawMessage(`{"type": "object"}`),
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			return string(args), nil
+		},
+	})
+
+	defs := r.Definitions()
+	names := make([]string, 0, len(defs))
+	for _, d := range defs {
+		if d.Type != "function" || !json.Valid(d.Function.Parameters) {
+			t.Errorf("bad definition for %s", d.Function.Name)
+		}
+		names = append(names, d.Function.Name)
+	}
+	want := []string{"delete_file", "echo", "edit_file", "write_file"}
+	if len(names) != len(want) {
+		t.Fatalf("Definitions() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Definitions()[%d] = %s, want %s", i, names[i], want[i])
+		}
+	}
+
+	call := func(name, args string) (string, error) {
+		return r.Call(context.Background(), providers.ToolCall{
+			Function: providers.ToolCallFunction{Name: name, Arguments: args},
+		})
+	}
+
+	if out, err := call("echo", `{"a":1}`); err != nil || out != `{"a":1}` {
+		t.Errorf("echo = %q, %v", out, err)
+	}
+	if out, _ := call("echo", ""); out != "{}" {
+		t.Errorf("empty arguments = %q, want {}", out)
+	}
+	if _, err := call("echo", "{"); err == nil {
+		t.Error("invalid JSON should fail")
+	}
+	if _, err := call("missing", "{}"); err == nil {
+		t.Error("unknown tool should fail")
+	}
+	if _, err := call(WriteFileTool, "{}"); err == nil {
+		t.Error("file tools have no handler and should fail")
+	}
+}
@@ -0,0 +1,303 @@
+// Package tools - Web search through pluggable search engine backends
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WebSearchTool is the name of the web search tool
+const WebSearchTool = "web_search"
+
+// WebResult is one search engine hit
+type WebResult struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+// SearchBackend queries a web search engine
+type SearchBackend interface {
+	Name() string
+	Search(ctx context.Context, query string, n int) ([]WebResult, error)
+}
+
+// NewSearchBackend returns the backend named brave, searxng, or duckduckgo
+func NewSearchBackend(name, searxngURL, braveKey string) (SearchBackend, error) {
+	client := &http.Client{Timeout: 20 * time.Second}
+	switch strings.ToLower(name) {
+	case "brave":
+		if braveKey == "" {
+			return nil, fmt.Errorf("brave search needs an API key (set brave_api_key or BRAVE_API_KEY)")
+		}
+		return &BraveBackend{APIKey: braveKey, BaseURL: "https://api.search.brave.com", Client: client}, nil
+	case "searxng":
+		if searxngURL == "" {
+			return nil, fmt.Errorf("searxng needs searxng_url")
+		}
+		return &SearxNGBackend{BaseURL: strings.TrimSuffix(searxngURL, "/"), Client: client}, nil
+	case "duckduckgo", "ddg":
+		return &DuckDuckGoBackend{BaseURL: "https://html.duckduckgo.com", Client: client}, nil
+	case "":
+		return nil, fmt.Errorf("web search is disabled (set web_search_backend)")
+	default:
+		return nil, fmt.Errorf("unknown web search backend %q (brave, searxng, or duckduckgo)", name)
+	}
+}
+
+// getJSON fetches a URL and decodes its JSON body into v
+func getJSON(ctx context.Context, client *http.Client, rawURL string, header http.Header, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return err
+	}
+	for k, vals := range header {
+		req.Header[k] = vals
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("search error %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// BraveBackend uses the Brave Search API
+type BraveBackend struct {
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+}
+
+// Name returns the backend name
+func (b *BraveBackend) Name() string { return "brave" }
+
+// Search queries Brave's web search endpoint
+func (b *BraveBackend) Search(ctx context.Context, query string, n int) ([]WebResult, error) {
+	var res struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	u := fmt.Sprintf("%s/res/v1/web/search?q=%s&count=%d", b.BaseURL, url.QueryEscape(query), n)
+	if err := getJSON(ctx, b.Client, u, http.Header{"X-Subscription-Token": {b.APIKey}}, &res); err != nil {
+		return nil, err
+	}
+
+	results := make([]WebResult, 0, len(res.Web.Results))
+	for _, r := range res.Web.Results {
+		results = append(results, WebResult{Title: stripTags(r.Title), URL: r.URL, Snippet: stripTags(r.Description)})
+	}
+	return limitResults(results, n), nil
+}
+
+// SearxNGBackend uses a SearxNG instance's JSON API (format=json must be
+// enabled in its settings)
+type SearxNGBackend struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// Name returns the backend name
+func (b *SearxNGBackend) Name() string { return "searxng" }
+
+// Search queries the instance's /search endpoint
+func (b *SearxNGBackend) Search(ctx context.Context, query string, n int) ([]WebResult, error) {
+	var res struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	u := fmt.Sprintf("%s/search?q=%s&format=json", b.BaseURL, url.QueryEscape(query))
+	if err := getJSON(ctx, b.Client, u, nil, &res); err != nil {
+		return nil, err
+	}
+
+	results := make([]WebResult, 0, len(res.Results))
+	for _, r := range res.Results {
+		results = append(results, WebResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return limitResults(results, n), nil
+}
+
+// DuckDuckGoBackend scrapes DuckDuckGo's HTML results page; it needs no key
+type DuckDuckGoBackend struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+var (
+	ddgResultPattern  = regexp.MustCompile(`(?s)<a[^>]+class="result__a"[^>]+href="([^"]+)"[^>]*>(.*?)</a>`)
+	ddgSnippetPattern = regexp.MustCompile(`(?s)class="result__snippet"[^>]*>(.*?)</a>`)
+	tagPattern        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Name returns the backend name
+func (b *DuckDuckGoBackend) Name() string { return "duckduckgo" }
+
+// Search fetches and parses the results page
+func (b *DuckDuckGoBackend) Search(ctx context.Context, query string, n int) ([]WebResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", b.BaseURL+"/html/?q="+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; GoClode)")
+
+	resp, err := b.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search error %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2*1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	return limitResults(parseDuckDuckGo(string(body)), n), nil
+}
+
+// parseDuckDuckGo extracts results from an html.duckduckgo.com page, pairing
+// each result link with the snippet that follows it
+func parseDuckDuckGo(page string) []WebResult {
+	links := ddgResultPattern.FindAllStringSubmatchIndex(page, -1)
+	results := make([]WebResult, 0, len(links))
+	for i, m := range links {
+		r := WebResult{
+			URL:   ddgTarget(html.UnescapeString(page[m[2]:m[3]])),
+			Title: stripTags(page[m[4]:m[5]]),
+		}
+		end := len(page)
+		if i+1 < len(links) {
+			end = links[i+1][0]
+		}
+		if s := ddgSnippetPattern.FindStringSubmatch(page[m[1]:end]); s != nil {
+			r.Snippet = stripTags(s[1])
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// ddgTarget unwraps DuckDuckGo's redirect links (//duckduckgo.com/l/?uddg=...)
+func ddgTarget(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if u.Scheme == "" {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+func stripTags(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(s, ""))), " ")
+}
+
+func limitResults(results []WebResult, n int) []WebResult {
+	if n > 0 && len(results) > n {
+		return results[:n]
+	}
+	return results
+}
+
+// FormatResults renders results as a numbered list
+func FormatResults(results []WebResult) string {
+	var sb strings.Builder
+	for i, r := range results {
+		fmt.Fprintf(&sb, "%d. %s\n   %s\n", i+1, r.Title, r.URL)
+		if r.Snippet != "" {
+			fmt.Fprintf(&sb, "   %s\n", r.Snippet)
+		}
+	}
+	return sb.String()
+}
+
+// WebSearchArgs are the arguments of web_search
+type WebSearchArgs struct {
+	Query string `json:"query"`
+}
+
+// WebSearchOptions configure web_search
+type WebSearchOptions struct {
+	Backend    func() (SearchBackend, error) // Resolved at call time (config is hot-reloadable)
+	MaxResults func() int
+	// Summarize condenses the results for the question before they reach
+	// the context; nil or an error falls back to the raw list
+	Summarize func(ctx context.Context, query, results string) (string, error)
+	// Screen checks the results for embedded instructions before they are
+	// summarized or reach the model; nil passes them through
+	Screen func(source, text string) string
+}
+
+// WebSearch returns the web_search tool
+func WebSearch(opts WebSearchOptions) *Tool {
+	return &Tool{
+		Name:        WebSearchTool,
+		Description: "Search the web, e.g. for how to use a library or what an error means. Returns a summary of the top results with their URLs. Do not include secrets or private code in the query.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"query": {"type": "string", "description": "Search query, e.g. golang fsnotify watch directory recursively"}
+			},
+			"required": ["query"]
+		}`),
+		Handler: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args WebSearchArgs
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+			if strings.TrimSpace(args.Query) == "" {
+				return "", fmt.Errorf("query is empty")
+			}
+
+			backend, err := opts.Backend()
+			if err != nil {
+				return "", err
+			}
+			results, err := backend.Search(ctx, args.Query, opts.MaxResults())
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", backend.Name(), err)
+			}
+			if len(results) == 0 {
+				return "no results", nil
+			}
+
+			list := FormatResults(results)
+			if opts.Screen != nil {
+				list = opts.Screen(fmt.Sprintf("web search %q", args.Query), list)
+			}
+			if opts.Summarize != nil {
+				if summary, err := opts.Summarize(ctx, args.Query, list); err == nil && summary != "" {
+					return summary, nil
+				}
+			}
+			return list, nil
+		},
+	}
+}
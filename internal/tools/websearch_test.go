@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const ddgPage = `<div class="result">
+<a rel="nofollow" class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fpkg.go.dev%2Fgithub.com%2Ffsnotify%2Ffsnotify&amp;rut=abc">fsnotify <b>package</b></a>
+<a class="result__snippet" href="x">Package fsnotify provides a cross-platform &amp; <b>file</b> watcher.</a>
+</div>
+<div class="result">
+<a rel="nofollow" class="result__a" href="https://example.com/post">Watching dirs</a>
+</div>`
+
+func TestParseDuckDuckGo(t *testing.T) {
+	got := parseDuckDuckGo(ddgPage)
+	want := []WebResult{
+		{Title: "fsnotify package", URL: "https://pkg.go.dev/github.com/fsnotify/fsnotify", Snippet: "Package fsnotify provides a cross-platform & file watcher."},
+		{Title: "Watching dirs", URL: "https://example.com/post"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDuckDuckGo() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSearchBackends(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		switch r.URL.Path {
+		case "/res/v1/web/search":
+			if r.Header.Get("X-Subscription-Token") != "key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"web": {"results": [{"title": "<strong>%s</strong>", "url": "https://a", "description": "A"}, {"title": "B", "url": "https://b"}]}}`, q)
+		case "/search":
+			fmt.Fprintf(w, `{"results": [{"title": "%s", "url": "https://a", "content": "A"}]}`, q)
+		case "/html/":
+			fmt.Fprint(w, ddgPage)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	backends := []SearchBackend{
+		&BraveBackend{APIKey: "key", BaseURL: srv.URL, Client: srv.Client()},
+		&SearxNGBackend{BaseURL: srv.URL, Client: srv.Client()},
+		&DuckDuckGoBackend{BaseURL: srv.URL, Client: srv.Client()},
+	}
+	for _, b := range backends {
+		results, err := b.Search(context.Background(), "fsnotify", 1)
+		if err != nil {
+			t.Errorf("%s: %v", b.Name(), err)
+			continue
+		}
+		if len(results) != 1 || !strings.Contains(results[0].Title, "fsnotify") {
+			t.Errorf("%s: results = %+v", b.Name(), results)
+		}
+	}
+
+	brave := &BraveBackend{APIKey: "wrong", BaseURL: srv.URL, Client: srv.Client()}
+	if _, err := brave.Search(context.Background(), "x", 1); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("bad key: err = %v", err)
+	}
+}
+
+func TestNewSearchBackend(t *testing.T) {
+	if _, err := NewSearchBackend("brave", "", ""); err == nil {
+		t.Error("brave without a key: want an error")
+	}
+	if _, err := NewSearchBackend("searxng", "", ""); err == nil {
+		t.Error("searxng without a URL: want an error")
+	}
+	if _, err := NewSearchBackend("", "", ""); err == nil {
+		t.Error("empty backend: want an error")
+	}
+	if b, err := NewSearchBackend("DuckDuckGo", "", ""); err != nil || b.Name() != "duckduckgo" {
+		t.Errorf("duckduckgo: %v, %v", b, err)
+	}
+}
+
+type fakeBackend []WebResult
+
+func (f fakeBackend) Name() string { return "fake" }
+func (f fakeBackend) Search(ctx context.Context, query string, n int) ([]WebResult, error) {
+	return limitResults(f, n), nil
+}
+
+func TestWebSearchTool(t *testing.T) {
+	backend := fakeBackend{{Title: "A", URL: "https://a", Snippet: "first"}, {Title: "B", URL: "https://b"}}
+	call := func(opts WebSearchOptions) string {
+		raw, _ := json.Marshal(WebSearchArgs{Query: "how to x"})
+		out, err := WebSearch(opts).Handler(context.Background(), raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	opts := WebSearchOptions{
+		Backend:    func() (SearchBackend, error) { return backend, nil },
+		MaxResults: func() int { return 5 },
+	}
+
+	if got, want := call(opts), "1. A\n   https://a\n   first\n2. B\n   https://b\n"; got != want {
+		t.Errorf("raw results = %q, want %q", got, want)
+	}
+
+	opts.Summarize = func(ctx context.Context, query, results string) (string, error) {
+		return fmt.Sprintf("%s: %d results", query, strings.Count(results, "https://")), nil
+	}
+	if got := call(opts); got != "how to x: 2 results" {
+		t.Errorf("summary = %q", got)
+	}
+
+	opts.Summarize = func(ctx context.Context, query, results string) (string, error) {
+		return "", fmt.Errorf("provider down")
+	}
+	if got := call(opts); !strings.HasPrefix(got, "1. A") {
+		t.Errorf("failed summary should fall back to the list, got %q", got)
+	}
+}
@@ -0,0 +1,253 @@
+// Package ui - /agent: plan a goal as steps, then carry them out one by one
+package ui
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+// maxPlanSteps bounds the plans the model may propose
+const maxPlanSteps = 12
+
+// maxStepResult caps the step summary stored with a plan
+const maxStepResult = 500
+
+const planPrompt = `You plan coding tasks for an agent that can edit files, run commands, and run tests. Break the user's goal into a short sequence of concrete steps, each small enough to do and check in one go. End with a step that verifies the work (build or tests). Reply with only a numbered list, one step per line, at most 12 steps.`
+
+var planStepPattern = regexp.MustCompile(`^\s*(?:\d+[.)]|[-*])\s+(.+)$`)
+
+// parsePlan reads the steps from a numbered or bulleted list
+func parsePlan(text string) []string {
+	steps := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		m := planStepPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		step := strings.TrimSpace(strings.Trim(strings.TrimSpace(m[1]), "*"))
+		if step != "" {
+			steps = append(steps, step)
+		}
+		if len(steps) == maxPlanSteps {
+			break
+		}
+	}
+	return steps
+}
+
+// handleAgent plans a goal and runs it, or manages the current plan:
+// /agent <goal>, /agent (show), /agent resume, /agent stop
+func (c *Chat) handleAgent(args []string) error {
+	plan, err := c.session.ActivePlan()
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	switch {
+	case sub == "" || sub == "status":
+		if plan == nil {
+			fmt.Println("\033[90mNo plan in progress. Start one with /agent <goal>\033[0m")
+			return nil
+		}
+		printPlan(plan)
+		return nil
+
+	case sub == "stop" && len(args) == 1:
+		if plan == nil {
+			return fmt.Errorf("no plan in progress")
+		}
+		if err := c.session.SetPlanStatus(plan.ID, session.PlanStopped); err != nil {
+			return err
+		}
+		fmt.Printf("\033[33m⏹  Stopped plan: %s\033[0m\n", plan.Goal)
+		return nil
+
+	case sub == "resume" && len(args) == 1:
+		if plan == nil {
+			return fmt.Errorf("no plan to resume")
+		}
+		return c.runPlan(plan)
+	}
+
+	if plan != nil {
+		return fmt.Errorf("a plan is in progress (%s): /agent resume or /agent stop first", plan.Goal)
+	}
+	return c.startPlan(strings.Join(args, " "))
+}
+
+// startPlan asks the model for a plan, confirms it with the user, saves it, and runs it
+func (c *Chat) startPlan(goal string) error {
+	provider := c.registry.Current()
+	if provider == nil {
+		return fmt.Errorf("no provider available")
+	}
+	if err := c.checkBudget(); err != nil {
+		return err
+	}
+	if !c.engine.GetConfigBool("tool_calls") {
+		fmt.Println("\033[33m⚠️  tool_calls is off: steps can edit files but not run commands or tests\033[0m")
+	}
+
+	fmt.Println("\033[90m🗺  Planning...\033[0m")
+	resp, err := provider.Generate(c.ctx, &providers.Request{
+		Messages: []providers.Message{
+			{Role: "system", Content: planPrompt + c.repoMap()},
+			{Role: "user", Content: goal},
+		},
+		Temperature: 0.3,
+	})
+	if err != nil {
+		return fmt.Errorf("plan: %w", err)
+	}
+	steps := parsePlan(resp.Content)
+	if len(steps) == 0 {
+		return fmt.Errorf("the model did not return a plan:\n%s", resp.Content)
+	}
+
+	fmt.Printf("\n\033[33mPlan for: %s\033[0m\n", goal)
+	for i, step := range steps {
+		fmt.Printf("  %d. %s\n", i+1, step)
+	}
+	confirm, _ := c.ask("\n\033[36mRun this plan? [Y/n] \033[0m")
+	if confirm = strings.ToLower(strings.TrimSpace(confirm)); confirm == "n" || confirm == "no" {
+		fmt.Println("\033[90mPlan discarded\033[0m")
+		return nil
+	}
+
+	plan, err := c.session.CreatePlan(goal, steps)
+	if err != nil {
+		return err
+	}
+	c.agentCheckpoint()
+	return c.runPlan(plan)
+}
+
+// runPlan carries out a plan's remaining steps through the normal chat and
+// tool loop. Ctrl-C stops after the current model call; the plan stays
+// active so /agent resume picks up at the interrupted step.
+func (c *Chat) runPlan(plan *session.Plan) error {
+	parent := c.ctx
+	ctx, cancel := context.WithCancel(parent)
+	c.ctx = ctx
+	c.setAgentStop(cancel)
+	defer func() {
+		c.setAgentStop(nil)
+		cancel()
+		c.ctx = parent
+	}()
+
+	fmt.Println("\033[90m(Ctrl-C stops the agent; /agent resume continues)\033[0m")
+	for step := plan.Next(); step != nil; step = plan.Next() {
+		fmt.Printf("\n\033[36m▶ Step %d/%d: %s\033[0m\n", step.Index, len(plan.Steps), step.Description)
+		step.Status = session.StepRunning
+		c.session.SetStepStatus(plan.ID, step.Index, step.Status, "")
+
+		c.fixRound = 0
+		c.lastReply = ""
+		prompt := stepPrompt(plan, step)
+		err := c.handleChat(&Intent{Type: IntentCode, Content: prompt, Raw: prompt, Confidence: 1.0})
+
+		if ctx.Err() != nil {
+			step.Status = session.StepPending
+			c.session.SetStepStatus(plan.ID, step.Index, step.Status, "")
+			fmt.Printf("\n\033[33m⏸  Agent stopped at step %d; /agent resume to continue\033[0m\n", step.Index)
+			return nil
+		}
+		if err != nil {
+			step.Status, step.Result = session.StepFailed, err.Error()
+			c.session.SetStepStatus(plan.ID, step.Index, step.Status, step.Result)
+			return fmt.Errorf("step %d failed: %w (/agent resume retries it)", step.Index, err)
+		}
+
+		step.Status, step.Result = session.StepDone, truncateResult(c.lastReply)
+		c.session.SetStepStatus(plan.ID, step.Index, step.Status, step.Result)
+		fmt.Printf("\033[32m✓ Step %d/%d done\033[0m\n", step.Index, len(plan.Steps))
+	}
+
+	if err := c.session.SetPlanStatus(plan.ID, session.PlanDone); err != nil {
+		return err
+	}
+	fmt.Printf("\n\033[32m✓ Plan complete: %s\033[0m\n", plan.Goal)
+	return nil
+}
+
+// stepPrompt tells the model where it is in the plan and what to do now
+func stepPrompt(plan *session.Plan, current *session.PlanStep) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "You are working through a plan for this goal: %s\n\nPlan:\n", plan.Goal)
+	for _, step := range plan.Steps {
+		mark := " "
+		switch step.Status {
+		case session.StepDone:
+			mark = "x"
+		case session.StepSkipped:
+			mark = "-"
+		}
+		fmt.Fprintf(&sb, "[%s] %d. %s\n", mark, step.Index, step.Description)
+		if step.Result != "" && step.Status == session.StepDone {
+			fmt.Fprintf(&sb, "      Result: %s\n", strings.ReplaceAll(step.Result, "\n", " "))
+		}
+	}
+	fmt.Fprintf(&sb, "\nNow do step %d only: %s\n", current.Index, current.Description)
+	sb.WriteString("Use the tools to read, edit, run commands, and test as needed. When the step is done, reply with a one or two sentence summary of what you did.")
+	return sb.String()
+}
+
+// truncateResult keeps the start of a step summary
+func truncateResult(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxStepResult {
+		return s[:maxStepResult] + "..."
+	}
+	return s
+}
+
+// printPlan shows a plan with each step's status
+func printPlan(plan *session.Plan) {
+	fmt.Printf("\n\033[33mPlan: %s\033[0m\n", plan.Goal)
+	for _, step := range plan.Steps {
+		icon := "\033[90m○\033[0m"
+		switch step.Status {
+		case session.StepDone:
+			icon = "\033[32m✓\033[0m"
+		case session.StepRunning:
+			icon = "\033[36m▶\033[0m"
+		case session.StepFailed:
+			icon = "\033[31m✗\033[0m"
+		case session.StepSkipped:
+			icon = "\033[90m-\033[0m"
+		}
+		fmt.Printf("  %s %d. %s\n", icon, step.Index, step.Description)
+	}
+	fmt.Println("\033[90m/agent resume to continue, /agent stop to abandon\033[0m")
+}
+
+// setAgentStop records the cancel function Ctrl-C calls while /agent runs
+func (c *Chat) setAgentStop(stop context.CancelFunc) {
+	c.agentMu.Lock()
+	defer c.agentMu.Unlock()
+	c.agentStop = stop
+}
+
+// stopAgent interrupts a running /agent; it reports whether one was running
+func (c *Chat) stopAgent() bool {
+	c.agentMu.Lock()
+	defer c.agentMu.Unlock()
+	if c.agentStop == nil {
+		return false
+	}
+	c.agentStop()
+	c.agentStop = nil
+	return true
+}
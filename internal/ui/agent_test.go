@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+func TestParsePlan(t *testing.T) {
+	text := `Here is the plan:
+
+1. Add a **--verbose** flag to main.go
+2) Thread it into the logger
+- Run go test ./...
+
+Let me know!`
+	want := []string{"Add a **--verbose** flag to main.go", "Thread it into the logger", "Run go test ./..."}
+	if got := parsePlan(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePlan() = %q, want %q", got, want)
+	}
+
+	if got := parsePlan("I can't plan that."); len(got) != 0 {
+		t.Errorf("parsePlan(prose) = %q", got)
+	}
+
+	long := strings.Repeat("1. step\n", 20)
+	if got := parsePlan(long); len(got) != maxPlanSteps {
+		t.Errorf("parsePlan() kept %d steps, want %d", len(got), maxPlanSteps)
+	}
+}
+
+func TestStepPrompt(t *testing.T) {
+	plan := &session.Plan{
+		Goal: "add a flag",
+		Steps: []session.PlanStep{
+			{Index: 1, Description: "parse it", Status: session.StepDone, Result: "added\nparsing"},
+			{Index: 2, Description: "use it", Status: session.StepPending},
+		},
+	}
+	got := stepPrompt(plan, &plan.Steps[1])
+	for _, want := range []string{"goal: add a flag", "[x] 1. parse it", "Result: added parsing", "[ ] 2. use it", "Now do step 2 only: use it"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("stepPrompt() missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Package ui - /analyze: asking the model to diagnose the debug data
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+const analyzeSystemPrompt = `You are debugging GoClode, a Go coding assistant built around SQLite-backed modules and event hooks. Be specific: name the events, modules, and assertions involved, and keep recommendations short.`
+
+// maxFollowUpTasks caps the tasks taken from an analysis
+const maxFollowUpTasks = 10
+
+var followUpHeading = regexp.MustCompile(`(?i)^[#*\s]*follow[- ]up tasks\s*:?[*\s]*$`)
+
+// followUpTasks reads the tasks listed under the "Follow-up tasks:"
+// heading of an analysis
+func followUpTasks(text string) []string {
+	tasks := make([]string, 0)
+	inSection := false
+	for _, line := range strings.Split(text, "\n") {
+		if followUpHeading.MatchString(strings.TrimSpace(line)) {
+			inSection = true
+			continue
+		}
+		if !inSection || strings.TrimSpace(line) == "" {
+			continue
+		}
+		m := planStepPattern.FindStringSubmatch(line)
+		if m == nil {
+			break // The list is over
+		}
+		task := strings.TrimSpace(m[1])
+		if len(task) > 4 && strings.HasPrefix(task, "**") && strings.HasSuffix(task, "**") {
+			task = strings.TrimSpace(task[2 : len(task)-2])
+		}
+		if task == "" || strings.EqualFold(strings.Trim(task, "."), "none") {
+			continue
+		}
+		tasks = append(tasks, task)
+		if len(tasks) == maxFollowUpTasks {
+			break
+		}
+	}
+	return tasks
+}
+
+// handleAnalyze sends the debug log, failed traces, and failed assertions
+// to the current provider, shows its recommendations, and offers to add
+// its follow-up tasks to the todo list: /analyze [what to focus on]
+func (c *Chat) handleAnalyze(args []string) error {
+	provider := c.registry.Current()
+	if provider == nil {
+		return fmt.Errorf("no provider available")
+	}
+	if err := c.checkBudget(); err != nil {
+		return err
+	}
+	traces, err := c.debug.Traces(1, false)
+	if err != nil {
+		return err
+	}
+	if len(traces) == 0 && len(c.modules.GetDebugLog()) == 0 {
+		return fmt.Errorf("no debug data to analyze yet: turn on /debug and reproduce the problem")
+	}
+
+	prompt := c.debug.GenerateLLMDebugPrompt()
+	if focus := strings.TrimSpace(strings.Join(args, " ")); focus != "" {
+		prompt += "\n\nFocus on: " + focus
+	}
+
+	fmt.Println("\033[90m🔍 Analyzing debug data...\033[0m")
+	resp, err := provider.Generate(c.ctx, &providers.Request{
+		Messages: []providers.Message{
+			{Role: "system", Content: analyzeSystemPrompt},
+			{Role: "user", Content: prompt},
+		},
+		Temperature: 0.3,
+	})
+	if err != nil {
+		return fmt.Errorf("analyze: %w", err)
+	}
+	fmt.Printf("\n%s\n", strings.TrimSpace(resp.Content))
+
+	tasks := followUpTasks(resp.Content)
+	if len(tasks) == 0 {
+		return nil
+	}
+	confirm, _ := c.ask(fmt.Sprintf("\n\033[36mAdd %d follow-up tasks to the todo list? [y/N] \033[0m", len(tasks)))
+	if confirm = strings.ToLower(strings.TrimSpace(confirm)); confirm != "y" && confirm != "yes" {
+		return nil
+	}
+	for _, task := range tasks {
+		if err := c.session.AddTask(task); err != nil {
+			return err
+		}
+	}
+	c.printTasks()
+	c.updatePrompt()
+	return nil
+}
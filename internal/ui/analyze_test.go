@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFollowUpTasks(t *testing.T) {
+	text := `## Root cause
+1. The learning hook fails on empty payloads
+
+## Suggested fixes
+- Check the payload before reading it
+
+**Follow-up tasks:**
+- Guard handleUndo against a missing prompt
+2. Add a test for **empty payloads**
+
+That should cover it.`
+	want := []string{"Guard handleUndo against a missing prompt", "Add a test for **empty payloads**"}
+	if got := followUpTasks(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("followUpTasks() = %q, want %q", got, want)
+	}
+
+	if got := followUpTasks("Follow-up tasks:\n- none"); len(got) != 0 {
+		t.Errorf("followUpTasks(none) = %q", got)
+	}
+	if got := followUpTasks("- Fix it\n- Test it"); len(got) != 0 {
+		t.Errorf("followUpTasks(no heading) = %q", got)
+	}
+}
@@ -0,0 +1,377 @@
+// Package ui - goclode attach: a terminal client of the --listen server,
+// tmux style: leave it, and the turn in progress keeps running
+package ui
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultAttachAddr is where goclode attach looks for a server
+const DefaultAttachAddr = ":7777"
+
+// attachStartTimeout bounds the wait for a server attach started
+const attachStartTimeout = 10 * time.Second
+
+// attachLog is the log of servers attach starts, in the workspace
+var attachLog = filepath.Join(".goclode", "listen.log")
+
+// attachClient is a connection to a --listen server
+type attachClient struct {
+	conn    net.Conn
+	wmu     sync.Mutex // Serializes requests
+	mu      sync.Mutex // Guards nextID and waiting
+	nextID  int
+	waiting map[string]chan rpcResponse
+	out     io.Writer
+}
+
+// rpcResponse is a response the client reads
+type rpcResponse struct {
+	Result json.RawMessage
+	Error  *rpcError
+}
+
+// Attach connects the terminal to the --listen server on addr, replays
+// the turn in progress and follows it. /detach, Ctrl+D, Ctrl+C, or closing
+// the terminal leave the server and its turn running; /stop stops it. With
+// no server on addr, one is started in the background with serverArgs
+// before --listen, logging to .goclode/listen.log. The token initialize
+// sends is the one attach gave that server, or else the one the running
+// server wrote in .goclode.
+func Attach(addr string, serverArgs []string) error {
+	addr, err := loopbackAddr(addr)
+	if err != nil {
+		return err
+	}
+	var token string
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		if conn, token, err = startServer(addr, serverArgs); err != nil {
+			return err
+		}
+	} else if token, err = readListenToken(addr); err != nil {
+		conn.Close()
+		return err
+	}
+	defer conn.Close()
+
+	cl := &attachClient{conn: conn, waiting: make(map[string]chan rpcResponse), out: os.Stdout}
+	go cl.read()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	var info struct {
+		SessionID string `json:"session_id"`
+		Provider  string `json:"provider"`
+		Workdir   string `json:"workdir"`
+	}
+	if err := cl.call(ctx, "initialize", map[string]string{"token": token}, &info); err != nil {
+		return err
+	}
+	fmt.Printf("\033[32m🔌 Attached to session %s (%s) in %s\033[0m\n", shortHash(info.SessionID), info.Provider, info.Workdir)
+	fmt.Println("\033[90m/apply, /diff, /discard, /detach (Ctrl+D), /stop; anything else goes to the model\033[0m")
+
+	var turn rpcTurn
+	if err := cl.call(ctx, "task/attach", nil, &turn); err != nil {
+		return err
+	}
+	if turn.Input != "" {
+		asker := ""
+		if turn.User != "" {
+			asker = turn.User + " "
+		}
+		fmt.Printf("\n\033[36m%s> %s\033[0m\n%s", asker, turn.Input, turn.Text)
+		if turn.Running {
+			fmt.Print("\033[90m (still running…)\033[0m")
+		} else {
+			cl.summarize(turn.Result, turn.Error)
+		}
+		fmt.Println()
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return cl.detach()
+		case line, ok := <-lines:
+			if !ok {
+				return cl.detach()
+			}
+			done, err := cl.command(ctx, strings.TrimSpace(line))
+			if ctx.Err() != nil {
+				return cl.detach()
+			}
+			if err != nil {
+				fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+			}
+			if done {
+				return nil
+			}
+		}
+	}
+}
+
+// startServer starts a detached --listen server and connects to it,
+// returning the token it gave the server
+func startServer(addr string, serverArgs []string) (net.Conn, string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := newListenToken()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(attachLog), 0o755); err != nil {
+		return nil, "", err
+	}
+	log, err := os.OpenFile(attachLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, "", err
+	}
+	defer log.Close()
+
+	cmd := exec.Command(exe, append(append([]string(nil), serverArgs...), "--listen", addr, "--detach")...)
+	cmd.Env = append(os.Environ(), listenTokenEnv+"="+token)
+	cmd.Stdout, cmd.Stderr = log, log
+	if err := cmd.Start(); err != nil {
+		return nil, "", fmt.Errorf("start server: %w", err)
+	}
+	fmt.Printf("\033[90mStarted a server on %s (log: %s)\033[0m\n", addr, attachLog)
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	deadline := time.Now().Add(attachStartTimeout)
+	for time.Now().Before(deadline) {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			return conn, token, nil
+		}
+		select {
+		case err := <-exited:
+			return nil, "", fmt.Errorf("server exited (%v); see %s", err, attachLog)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return nil, "", fmt.Errorf("server did not start on %s; see %s", addr, attachLog)
+}
+
+// command runs a line typed at the prompt and reports whether to leave
+func (cl *attachClient) command(ctx context.Context, line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	switch fields[0] {
+	case "/detach", "/quit":
+		return true, cl.detach()
+
+	case "/stop":
+		err := cl.call(ctx, "exit", nil, nil)
+		fmt.Println("\033[90mServer stopped\033[0m")
+		return true, err
+
+	case "/diff":
+		var result struct {
+			Changes []changePreview `json:"changes"`
+		}
+		if err := cl.call(ctx, "changes/preview", nil, &result); err != nil {
+			return false, err
+		}
+		if len(result.Changes) == 0 {
+			fmt.Println("\033[90mNo pending changes\033[0m")
+		}
+		for _, ch := range result.Changes {
+			fmt.Printf("\033[33m%s (%s)\033[0m\n", ch.Path, ch.Operation)
+			if ch.Error != "" {
+				fmt.Printf("  ⚠️  %s\n", ch.Error)
+			}
+			fmt.Print(ch.Diff)
+		}
+		return false, nil
+
+	case "/apply":
+		var result struct {
+			Applied           bool     `json:"applied"`
+			Files             []string `json:"files"`
+			ValidationFailure string   `json:"validation_failure"`
+		}
+		if err := cl.call(ctx, "changes/apply", map[string][]string{"paths": fields[1:]}, &result); err != nil {
+			return false, err
+		}
+		if result.Applied {
+			fmt.Printf("\033[32m✓ Applied %s\033[0m\n", strings.Join(result.Files, ", "))
+		} else {
+			fmt.Println("\033[33m❌ Not applied\033[0m")
+		}
+		if result.ValidationFailure != "" {
+			fmt.Printf("\033[33mValidation failed:\033[0m\n%s\n", result.ValidationFailure)
+		}
+		return false, nil
+
+	case "/discard":
+		var result map[string]int
+		if err := cl.call(ctx, "changes/discard", nil, &result); err != nil {
+			return false, err
+		}
+		fmt.Printf("\033[90mDiscarded %d change(s)\033[0m\n", result["discarded"])
+		return false, nil
+	}
+
+	var result json.RawMessage
+	err := cl.call(ctx, "chat/send", map[string]string{"text": line}, &result)
+	if err != nil {
+		return false, err
+	}
+	cl.summarize(result, "")
+	fmt.Println()
+	return false, nil
+}
+
+// summarize ends a streamed reply with the changes it proposes
+func (cl *attachClient) summarize(result interface{}, failure string) {
+	if failure != "" {
+		fmt.Printf("\n\033[33m⚠️  %s\033[0m", failure)
+		return
+	}
+	data, _ := json.Marshal(result)
+	var r struct {
+		Changes []changePreview `json:"changes"`
+	}
+	if json.Unmarshal(data, &r) != nil || len(r.Changes) == 0 {
+		return
+	}
+	fmt.Print("\n\n\033[33m📁 Proposed:\033[0m")
+	for _, ch := range r.Changes {
+		fmt.Printf("\n  %s (%s)", ch.Path, ch.Operation)
+	}
+	fmt.Print("\n\033[90m/diff to review, /apply to apply\033[0m")
+}
+
+// detach leaves the server running
+func (cl *attachClient) detach() error {
+	fmt.Println("\n\033[90mDetached; the server keeps running (goclode attach to come back)\033[0m")
+	return nil
+}
+
+// call sends a request and waits for its response, decoded into result
+func (cl *attachClient) call(ctx context.Context, method string, params, result interface{}) error {
+	cl.mu.Lock()
+	cl.nextID++
+	id := fmt.Sprintf("%d", cl.nextID)
+	wait := make(chan rpcResponse, 1)
+	cl.waiting[id] = wait
+	cl.mu.Unlock()
+
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	cl.wmu.Lock()
+	_, err = fmt.Fprintf(cl.conn, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	cl.wmu.Unlock()
+	if err != nil {
+		return fmt.Errorf("server: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case resp, ok := <-wait:
+		if !ok {
+			return fmt.Errorf("server closed the connection")
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	}
+}
+
+// read prints streamed chunks and passes responses to their callers
+// until the server closes the connection
+func (cl *attachClient) read() {
+	r := bufio.NewReader(cl.conn)
+	for {
+		body, err := readFrame(r)
+		if err != nil {
+			break
+		}
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  *rpcError       `json:"error"`
+		}
+		if json.Unmarshal(body, &msg) != nil {
+			continue
+		}
+
+		switch msg.Method {
+		case "chat/chunk":
+			var p struct {
+				Delta string `json:"delta"`
+			}
+			if json.Unmarshal(msg.Params, &p) == nil {
+				fmt.Fprint(cl.out, p.Delta)
+			}
+			continue
+		case "task/done":
+			var turn rpcTurn
+			if json.Unmarshal(msg.Params, &turn) == nil {
+				cl.summarize(turn.Result, turn.Error)
+				fmt.Fprintln(cl.out)
+			}
+			continue
+		}
+
+		var id string
+		if json.Unmarshal(msg.ID, &id) != nil {
+			continue
+		}
+		cl.mu.Lock()
+		wait, ok := cl.waiting[id]
+		delete(cl.waiting, id)
+		cl.mu.Unlock()
+		if ok {
+			wait <- rpcResponse{Result: msg.Result, Error: msg.Error}
+		}
+	}
+
+	cl.mu.Lock()
+	for id, wait := range cl.waiting {
+		close(wait)
+		delete(cl.waiting, id)
+	}
+	cl.mu.Unlock()
+}
@@ -0,0 +1,373 @@
+// Package ui - Bot modes: a team shares one workspace from a chat
+// platform, each thread with its own session
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// botUpdateInterval spaces the edits of a streaming reply, under the
+// platforms' rate limits
+const botUpdateInterval = 1500 * time.Millisecond
+
+// maxBotDiff caps each file's diff posted in a thread
+const maxBotDiff = 3000
+
+// ansiPattern matches terminal color codes
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// botCommands are the words run as commands rather than sent to the model:
+// alone, or with a "/" or "!" prefix to pass arguments
+var botCommands = map[string]string{
+	"apply":   "Apply the changes proposed in this thread",
+	"discard": "Drop the proposed changes",
+	"diff":    "Show uncommitted changes in the workspace",
+	"status":  "Show git and session status",
+	"undo":    "Undo this thread's last change",
+	"pr":      "Open a pull request: pr [title]",
+	"help":    "List these commands",
+}
+
+// botAccess restricts a bot to the people and places of two config lists:
+// a message is answered only when each list that is set contains its
+// author and its place (a Discord server, a Slack channel)
+type botAccess struct {
+	users  map[string]bool
+	places map[string]bool
+}
+
+// loadBotAccess reads the access lists. A bot drives the workspace, so it
+// does not start until at least one list is set.
+func (c *Chat) loadBotAccess(usersKey, placesKey string) (botAccess, error) {
+	read := func(key string) map[string]bool {
+		raw, _ := c.engine.GetConfig(key)
+		ids := workspace.ParsePatternList(raw)
+		if len(ids) == 0 {
+			return nil
+		}
+		set := make(map[string]bool, len(ids))
+		for _, id := range ids {
+			set[id] = true
+		}
+		return set
+	}
+	a := botAccess{users: read(usersKey), places: read(placesKey)}
+	if a.users == nil && a.places == nil {
+		return a, fmt.Errorf("set %s or %s: anyone the bot can hear could change this workspace", usersKey, placesKey)
+	}
+	return a, nil
+}
+
+// allows reports whether a user may use the bot in a place
+func (a botAccess) allows(user, place string) bool {
+	return (a.users == nil || a.users[user]) && (a.places == nil || a.places[place])
+}
+
+// allowsDirect reports whether a user may use the bot in direct messages:
+// only people named in the users list
+func (a botAccess) allowsDirect(user string) bool {
+	return a.users[user]
+}
+
+// botMessenger posts to one thread of a chat platform
+type botMessenger interface {
+	// Post sends a message and returns its ID
+	Post(text string) (string, error)
+
+	// Update replaces the text of a message Post sent, with a reply still
+	// streaming
+	Update(id, text string) error
+
+	// Finish replaces the text of a message with the whole reply, adding
+	// messages if the platform's limit needs it
+	Finish(id, text string) error
+}
+
+// botServer handles the messages of every thread, one at a time: they
+// share the workspace and the chat
+type botServer struct {
+	chat     *Chat
+	platform string
+	pending  map[string][]FileChange // Proposed changes by thread
+}
+
+func newBotServer(c *Chat, platform string) *botServer {
+	return &botServer{chat: c, platform: platform, pending: make(map[string][]FileChange)}
+}
+
+// known reports whether a thread already has a session, so its messages
+// are for the bot even without a mention
+func (b *botServer) known(thread string) bool {
+	id, _ := b.chat.session.ThreadSession(b.platform + ":" + thread)
+	return id != ""
+}
+
+// handle answers a message of a thread
+func (b *botServer) handle(thread, text string, m botMessenger) {
+	if err := b.chat.threadSession(b.platform + ":" + thread); err != nil {
+		m.Post("⚠️ " + err.Error())
+		return
+	}
+
+	if name, args, ok := botCommand(text); ok {
+		out, err := b.command(thread, name, args)
+		if err != nil {
+			out = strings.TrimSpace(out + "\n⚠️ " + err.Error())
+		}
+		if out == "" {
+			out = "Done."
+		}
+		m.Post(out)
+		return
+	}
+
+	if err := b.converse(thread, text, m); err != nil {
+		m.Post("⚠️ " + err.Error())
+	}
+}
+
+// botCommand recognizes a command: a bare command word, or one prefixed
+// with "/" or "!" followed by arguments
+func botCommand(text string) (string, []string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	word := strings.ToLower(fields[0])
+	prefixed := strings.HasPrefix(word, "/") || strings.HasPrefix(word, "!")
+	word = strings.TrimLeft(word, "/!")
+	if _, ok := botCommands[word]; !ok || (!prefixed && len(fields) > 1) {
+		return "", nil, false
+	}
+	return word, fields[1:], true
+}
+
+// command runs a command and returns what it printed
+func (b *botServer) command(thread, name string, args []string) (string, error) {
+	c := b.chat
+	switch name {
+	case "help":
+		var sb strings.Builder
+		for _, cmd := range []string{"apply", "discard", "diff", "status", "undo", "pr", "help"} {
+			fmt.Fprintf(&sb, "`%s` %s\n", cmd, botCommands[cmd])
+		}
+		sb.WriteString("Anything else goes to the model.")
+		return sb.String(), nil
+
+	case "status":
+		return captureOutput(c.showStatus)
+
+	case "diff":
+		out, err := captureOutput(c.showDiff)
+		return codeBlock("diff", out), err
+
+	case "undo":
+		return captureOutput(func() error { return c.handleUndo(args) })
+
+	case "pr":
+		return captureOutput(func() error { return c.handlePR(args) })
+
+	case "discard":
+		n := len(b.pending[thread])
+		delete(b.pending, thread)
+		return fmt.Sprintf("Discarded %d proposed change(s).", n), nil
+
+	case "apply":
+		changes := b.pending[thread]
+		if len(changes) == 0 {
+			return "", fmt.Errorf("no proposed changes in this thread")
+		}
+		var applied bool
+		out, err := captureOutput(func() error {
+			var err error
+			applied, err = c.applyChanges(changes)
+			return err
+		})
+		if applied {
+			delete(b.pending, thread)
+		}
+		if failure := c.pendingFix; failure != "" {
+			c.pendingFix = ""
+			out += "\nValidation failed:\n" + codeBlock("", failure)
+		}
+		return out, err
+	}
+	return "", fmt.Errorf("unknown command %s", name)
+}
+
+// converse sends a message to the model, streaming the reply into one
+// message, then posts the diffs of the changes it proposes
+func (b *botServer) converse(thread, text string, m botMessenger) error {
+	c := b.chat
+	id, err := m.Post("_Thinking…_")
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var reply strings.Builder
+	last := time.Now()
+	c.onDelta = func(delta string) {
+		mu.Lock()
+		defer mu.Unlock()
+		reply.WriteString(delta)
+		if time.Since(last) >= botUpdateInterval {
+			last = time.Now()
+			m.Update(id, reply.String()+" …")
+		}
+	}
+	defer func() { c.onDelta = nil }()
+
+	intent := c.parser.Parse(text)
+	if intent == nil || (intent.Type != IntentCode && intent.Type != IntentQuestion) {
+		intent = &Intent{Type: IntentCode, Content: text, Raw: text, Confidence: 1.0}
+	}
+	turn, err := c.converse(intent, nil)
+	if err != nil {
+		m.Finish(id, "⚠️ "+err.Error())
+		return nil
+	}
+	content := turn.resp.Content
+	if strings.TrimSpace(content) == "" {
+		content = "_(empty reply)_"
+	}
+	if err := m.Finish(id, content); err != nil {
+		return err
+	}
+
+	changes := c.extractFileChanges(turn.resp.Content)
+	c.emitChatComplete(turn, len(changes))
+	if len(changes) == 0 {
+		return nil
+	}
+	b.pending[thread] = changes
+
+	var sb strings.Builder
+	for _, ch := range changes {
+		p := c.previewChange(ch)
+		fmt.Fprintf(&sb, "*%s* (%s)\n", p.Path, p.Operation)
+		if p.Error != "" {
+			fmt.Fprintf(&sb, "⚠️ %s\n", p.Error)
+			continue
+		}
+		if p.Diff != "" {
+			sb.WriteString(codeBlock("diff", shorten(p.Diff, maxBotDiff)))
+		}
+	}
+	sb.WriteString("Reply `apply` to apply these changes, or `discard`.")
+	_, err = m.Post(sb.String())
+	return err
+}
+
+// threadSession makes the thread's session current, starting one for a
+// new thread
+func (c *Chat) threadSession(thread string) error {
+	id, err := c.session.ThreadSession(thread)
+	if err != nil {
+		return err
+	}
+	return c.boundSession(id, "thread", thread, c.session.BindThread)
+}
+
+// boundSession makes the session id current, or when there is none yet
+// starts one and binds it with bind(value), key naming value in the
+// session_start event
+func (c *Chat) boundSession(id, key, value string, bind func(string) error) error {
+	if id == c.session.Current() {
+		return nil
+	}
+	if id != "" {
+		return c.resumeSession(id)
+	}
+
+	providerID := "cerebras"
+	if p := c.registry.Current(); p != nil {
+		providerID = p.ID()
+	}
+	sess, err := c.session.Create(providerID)
+	if err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	if err := bind(value); err != nil {
+		return err
+	}
+	c.backups = workspace.NewBackups(c.git.WorkDir(), sess.ID)
+	c.assignVariant()
+	c.taskCommit, c.taskSummaries = "", nil
+	c.modules.Emit("session_start", map[string]interface{}{
+		"session_id": sess.ID,
+		"provider":   providerID,
+		key:          value,
+	})
+	return nil
+}
+
+// captureOutput runs fn and returns what it printed, without colors; the
+// terminal still shows it
+func captureOutput(fn func() error) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(io.MultiWriter(&buf, stdout), r)
+		close(done)
+	}()
+
+	err = fn()
+	os.Stdout = stdout
+	w.Close()
+	<-done
+	r.Close()
+	return strings.TrimSpace(ansiPattern.ReplaceAllString(buf.String(), "")), err
+}
+
+// splitMessage cuts text into parts of at most limit bytes, at line ends
+// when it can
+func splitMessage(text string, limit int) []string {
+	parts := make([]string, 0, 1)
+	for len(text) > limit {
+		cut := strings.LastIndex(text[:limit], "\n")
+		if cut <= 0 {
+			cut = limit
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		parts = append(parts, text[:cut])
+		text = strings.TrimPrefix(text[cut:], "\n")
+	}
+	return append(parts, text)
+}
+
+// codeBlock fences text for a chat message
+func codeBlock(lang, text string) string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return ""
+	}
+	return "```" + lang + "\n" + text + "\n```\n"
+}
+
+// configSecret reads a credential from config, falling back to the
+// environment variable of the same name in upper case
+func (c *Chat) configSecret(key string) string {
+	if value, _ := c.engine.GetConfig(key); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToUpper(key))
+}
@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBotCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		name string
+		args int
+		ok   bool
+	}{
+		{"apply", "apply", 0, true},
+		{"  Status ", "status", 0, true},
+		{"/pr Add retries", "pr", 2, true},
+		{"!undo", "undo", 0, true},
+		{"undo the rename in main.go", "", 0, false}, // A request for the model
+		{"/deploy now", "", 0, false},
+		{"", "", 0, false},
+	}
+	for _, tt := range tests {
+		name, args, ok := botCommand(tt.text)
+		if name != tt.name || len(args) != tt.args || ok != tt.ok {
+			t.Errorf("botCommand(%q) = %q, %v, %v", tt.text, name, args, ok)
+		}
+	}
+}
+
+func TestBotPendingCommands(t *testing.T) {
+	b := newBotServer(&Chat{}, "test")
+	b.pending["t1"] = []FileChange{{Path: "a.go"}, {Path: "b.go"}}
+
+	if out, err := b.command("t2", "apply", nil); err == nil {
+		t.Errorf("Expected apply without proposed changes to fail, got %q", out)
+	}
+	out, err := b.command("t1", "discard", nil)
+	if err != nil || !strings.Contains(out, "2") || len(b.pending["t1"]) != 0 {
+		t.Errorf("Unexpected discard result %q (%v)", out, err)
+	}
+	if out, _ := b.command("t1", "help", nil); !strings.Contains(out, "`apply`") {
+		t.Errorf("Expected the command list, got %q", out)
+	}
+}
+
+func TestBotAccess(t *testing.T) {
+	both := botAccess{users: map[string]bool{"U1": true}, places: map[string]bool{"G1": true}}
+	users := botAccess{users: map[string]bool{"U1": true}}
+	places := botAccess{places: map[string]bool{"G1": true}}
+
+	tests := []struct {
+		access      botAccess
+		user, place string
+		want        bool
+	}{
+		{both, "U1", "G1", true},
+		{both, "U2", "G1", false},
+		{both, "U1", "G2", false},
+		{users, "U1", "G2", true},
+		{users, "U2", "G1", false},
+		{places, "U2", "G1", true},
+		{places, "U2", "G2", false},
+	}
+	for _, tt := range tests {
+		if got := tt.access.allows(tt.user, tt.place); got != tt.want {
+			t.Errorf("allows(%s, %s) with %+v = %v, want %v", tt.user, tt.place, tt.access, got, tt.want)
+		}
+	}
+
+	// Direct messages need the user named, whatever the places
+	if places.allowsDirect("U2") || !users.allowsDirect("U1") || users.allowsDirect("U2") {
+		t.Error("Direct messages must be limited to the users list")
+	}
+}
+
+func TestCaptureOutput(t *testing.T) {
+	out, err := captureOutput(func() error {
+		fmt.Printf("\033[32m✓ Committed\033[0m abc123\n")
+		return fmt.Errorf("then failed")
+	})
+	if out != "✓ Committed abc123" || err == nil {
+		t.Errorf("Unexpected capture %q (%v)", out, err)
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	text := strings.Repeat("line\n", 10) + strings.Repeat("é", 20)
+	parts := splitMessage(text, 16)
+	if strings.ReplaceAll(strings.Join(parts, ""), "\n", "") != strings.ReplaceAll(text, "\n", "") {
+		t.Errorf("Expected the parts to rebuild the text, got %q", parts)
+	}
+	for _, p := range parts {
+		if len(p) > 16 || !utf8.ValidString(p) {
+			t.Errorf("Bad part %q", p)
+		}
+	}
+	if parts := splitMessage("short", 16); len(parts) != 1 || parts[0] != "short" {
+		t.Errorf("Expected one part, got %q", parts)
+	}
+}
+
+func TestDiscordCommands(t *testing.T) {
+	commands := discordCommands()
+	if len(commands) != len(botCommands) {
+		t.Fatalf("Expected every bot command, got %d", len(commands))
+	}
+	for _, cmd := range commands {
+		if cmd.Name == "pr" && (len(cmd.Options) != 1 || cmd.Options[0].Name != "title") {
+			t.Errorf("Expected /pr to take a title, got %+v", cmd.Options)
+		}
+	}
+}
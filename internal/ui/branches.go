@@ -0,0 +1,67 @@
+// Package ui - Guard against auto-committing on protected branches
+package ui
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// checkBranch reports whether auto-commit may proceed on the current branch.
+// On a protected branch it asks (or, per protected_branch_action, refuses or
+// switches to a session branch without asking).
+func (c *Chat) checkBranch() bool {
+	branch, err := c.git.CurrentBranch()
+	if err != nil || branch == c.branchApproved {
+		return true
+	}
+
+	raw, _ := c.engine.GetConfig("protected_branches")
+	if !isProtectedBranch(branch, workspace.ParsePatternList(raw)) {
+		return true
+	}
+
+	sessionBranch := "goclode/" + shortHash(c.session.Current())
+	action, _ := c.engine.GetConfig("protected_branch_action")
+	switch action {
+	case "refuse":
+		fmt.Printf("\033[33m⚠️  Not committing on protected branch %s; try git switch -c %s\033[0m\n", branch, sessionBranch)
+		return false
+	case "branch":
+		return c.switchToBranch(sessionBranch)
+	}
+
+	fmt.Printf("\n\033[33m⚠️  %s is a protected branch\033[0m\n", branch)
+	choice, _ := c.ask(fmt.Sprintf("\033[36mCommit on [b]ranch %s, commit [a]nyway, or [s]kip? [B/a/s] \033[0m", sessionBranch))
+	switch strings.ToLower(strings.TrimSpace(choice)) {
+	case "", "b", "branch":
+		return c.switchToBranch(sessionBranch)
+	case "a", "anyway":
+		c.branchApproved = branch
+		return true
+	}
+	fmt.Println("\033[90mChanges left uncommitted\033[0m")
+	return false
+}
+
+// switchToBranch creates and checks out a branch for this session's commits
+func (c *Chat) switchToBranch(name string) bool {
+	if err := c.git.CreateBranch(name); err != nil {
+		fmt.Printf("\033[33m⚠️  %v; changes left uncommitted\033[0m\n", err)
+		return false
+	}
+	fmt.Printf("\033[32m✓ Switched to new branch %s\033[0m\n", name)
+	return true
+}
+
+// isProtectedBranch matches a branch against glob patterns like "release/*"
+func isProtectedBranch(branch string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, branch); ok {
+			return true
+		}
+	}
+	return false
+}
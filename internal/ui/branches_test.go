@@ -0,0 +1,25 @@
+package ui
+
+import "testing"
+
+func TestIsProtectedBranch(t *testing.T) {
+	patterns := []string{"main", "master", "release/*"}
+
+	tests := []struct {
+		branch string
+		want   bool
+	}{
+		{"main", true},
+		{"master", true},
+		{"release/1.2", true},
+		{"release/1.2/hotfix", false},
+		{"feature/main", false},
+		{"goclode/abcd1234", false},
+	}
+
+	for _, tt := range tests {
+		if got := isProtectedBranch(tt.branch, patterns); got != tt.want {
+			t.Errorf("isProtectedBranch(%q) = %v, want %v", tt.branch, got, tt.want)
+		}
+	}
+}
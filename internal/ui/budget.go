@@ -0,0 +1,148 @@
+// Package ui - Spending budgets: warnings past budget_warn_pct, and LLM
+// calls refused once budget_session or budget_daily is spent
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+// budgetState is a spending limit and what has been spent against it
+type budgetState struct {
+	name  string // session or daily
+	limit float64
+	spent float64
+}
+
+// budgets returns the budgets that are set, in the budget_unit, with
+// their spend
+func (c *Chat) budgets() ([]budgetState, string, error) {
+	unit, _ := c.engine.GetConfig("budget_unit")
+	if unit != "tokens" {
+		unit = "usd"
+	}
+	limits := []struct {
+		name, key string
+		since     time.Time
+	}{
+		{"session", "budget_session", time.Time{}},
+		{"daily", "budget_daily", startOfDay(time.Now())},
+	}
+
+	budgets := make([]budgetState, 0, len(limits))
+	for _, l := range limits {
+		value, _ := c.engine.GetConfig(l.key)
+		limit, err := strconv.ParseFloat(value, 64)
+		if err != nil || limit <= 0 {
+			continue
+		}
+		spend, err := c.session.SessionSpend()
+		if !l.since.IsZero() {
+			spend, err = c.session.SpendSince(l.since)
+		}
+		if err != nil {
+			return nil, unit, err
+		}
+		b := budgetState{name: l.name, limit: limit, spent: spend.Cost}
+		if unit == "tokens" {
+			b.spent = float64(spend.Tokens())
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, unit, nil
+}
+
+// startOfDay is local midnight of t's day
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// formatSpend shows an amount in the budget unit
+func formatSpend(amount float64, unit string) string {
+	if unit == "tokens" {
+		return fmt.Sprintf("%.0f tokens", amount)
+	}
+	return fmt.Sprintf("$%.2f", amount)
+}
+
+// checkBudget is called before each LLM call. Once a budget is spent it
+// refuses, until /budget override; past budget_warn_pct it warns, once per
+// budget.
+func (c *Chat) checkBudget() error {
+	budgets, unit, err := c.budgets()
+	if err != nil {
+		core.Logger("ui").Warn("Budget not checked", "error", err)
+		return nil
+	}
+	warnPct := c.engine.GetConfigInt("budget_warn_pct")
+
+	c.budgetMu.Lock()
+	defer c.budgetMu.Unlock()
+	for _, b := range budgets {
+		if b.spent >= b.limit {
+			if c.budgetOverride {
+				continue
+			}
+			return fmt.Errorf("%s budget spent: %s of %s (/budget override to continue)",
+				b.name, formatSpend(b.spent, unit), formatSpend(b.limit, unit))
+		}
+		if warnPct > 0 && b.spent >= b.limit*float64(warnPct)/100 && !c.budgetWarned[b.name] {
+			if c.budgetWarned == nil {
+				c.budgetWarned = make(map[string]bool)
+			}
+			c.budgetWarned[b.name] = true
+			fmt.Printf("\033[33m⚠️  %s budget %.0f%% spent: %s of %s\033[0m\n",
+				b.name, 100*b.spent/b.limit, formatSpend(b.spent, unit), formatSpend(b.limit, unit))
+		}
+	}
+	return nil
+}
+
+// handleBudget runs /budget [override | on]: shows the spend against each
+// budget, or lifts and restores the stop for this session
+func (c *Chat) handleBudget(args []string) error {
+	if len(args) > 0 {
+		c.budgetMu.Lock()
+		defer c.budgetMu.Unlock()
+		switch args[0] {
+		case "override":
+			c.budgetOverride = true
+			fmt.Println("\033[33m⚠️  Budgets no longer stop LLM calls this session (/budget on restores them)\033[0m")
+			return nil
+		case "on":
+			c.budgetOverride = false
+			fmt.Println("\033[32m✓ Budgets stop LLM calls again\033[0m")
+			return nil
+		}
+		return fmt.Errorf("usage: /budget [override | on]")
+	}
+
+	budgets, unit, err := c.budgets()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n\033[33mBudgets:\033[0m")
+	if len(budgets) == 0 {
+		fmt.Println("  \033[90mNone set: /config budget_session or budget_daily <amount> (budget_unit: usd or tokens)\033[0m")
+	}
+	for _, b := range budgets {
+		fmt.Printf("  %-8s %s of %s (%.0f%%)\n", b.name, formatSpend(b.spent, unit), formatSpend(b.limit, unit), 100*b.spent/b.limit)
+	}
+	if unit == "usd" {
+		if p := c.registry.Current(); p != nil {
+			if price, err := c.registry.Pricing(p.ID()); err == nil && price.In == 0 && price.Out == 0 {
+				fmt.Printf("  \033[33m⚠️  %s has no price_in or price_out: its replies cost $0\033[0m\n", p.Name())
+			}
+		}
+	}
+	c.budgetMu.Lock()
+	if c.budgetOverride {
+		fmt.Println("  \033[33mOverridden: budgets do not stop LLM calls (/budget on)\033[0m")
+	}
+	c.budgetMu.Unlock()
+	return nil
+}
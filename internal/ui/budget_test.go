@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+func TestCheckBudget(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+	sm := session.NewManager(engine)
+	if _, err := sm.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	c := &Chat{engine: engine, session: sm}
+
+	if err := c.checkBudget(); err != nil {
+		t.Fatalf("Expected no budget by default, got %v", err)
+	}
+	engine.SetConfig("budget_unit", "tokens")
+	engine.SetConfig("budget_session", "1000")
+	sm.AddMessage("assistant", "reply", &providers.Response{TokensIn: 700, TokensOut: 150})
+	if err := c.checkBudget(); err != nil || !c.budgetWarned["session"] {
+		t.Fatalf("Expected a warning past 80%%, got %v (warned %v)", err, c.budgetWarned)
+	}
+
+	sm.AddMessage("assistant", "reply", &providers.Response{TokensIn: 100, TokensOut: 50})
+	err := c.checkBudget()
+	if err == nil || !strings.Contains(err.Error(), "1000 tokens of 1000 tokens") {
+		t.Fatalf("Expected the session budget to stop calls, got %v", err)
+	}
+
+	c.handleBudget([]string{"override"})
+	if err := c.checkBudget(); err != nil {
+		t.Errorf("Expected /budget override to lift the stop, got %v", err)
+	}
+	c.handleBudget([]string{"on"})
+	engine.SetConfig("budget_session", "0")
+	engine.SetConfig("budget_daily", "500")
+	if err := c.checkBudget(); err == nil || !strings.HasPrefix(err.Error(), "daily budget") {
+		t.Errorf("Expected the daily budget to stop calls, got %v", err)
+	}
+}
@@ -3,31 +3,52 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/debugserver"
+	"github.com/hazyhaar/GoClode/internal/diff"
 	"github.com/hazyhaar/GoClode/internal/git"
+	"github.com/hazyhaar/GoClode/internal/index"
+	"github.com/hazyhaar/GoClode/internal/lsp"
+	"github.com/hazyhaar/GoClode/internal/modules"
 	"github.com/hazyhaar/GoClode/internal/providers"
 	"github.com/hazyhaar/GoClode/internal/session"
+	"github.com/hazyhaar/GoClode/internal/tools"
+	"github.com/hazyhaar/GoClode/internal/validate"
+	"github.com/hazyhaar/GoClode/internal/workspace"
 	"github.com/chzyer/readline"
 )
 
 // Chat is the main conversational interface
 type Chat struct {
-	engine   *core.Engine
-	modules  *core.ModuleManager
-	registry *providers.Registry
-	session  *session.Manager
-	git      *git.Manager
-	parser   *IntentParser
+	engine    *core.Engine
+	modules   *core.ModuleManager
+	registry  *providers.Registry
+	session   *session.Manager
+	git       *git.Manager
+	parser    *IntentParser
+	backups   *workspace.Backups
+	validator *validate.Runner
+	index     *workspace.FileIndex
+	symbols   *index.Store
+	tools     *tools.Registry
+
+	permissions *tools.Permissions
+	learning    *modules.LearningModule
+	debug       *modules.DebugModule
 
 	rl      *readline.Instance
 	ctx     context.Context
@@ -36,6 +57,54 @@ type Chat struct {
 	// State
 	debugMode    bool
 	shutdownOnce sync.Once
+	pendingFix   string // Validation failures awaiting an auto-fix round
+	fixRound     int    // Auto-fix rounds spent on the current request
+
+	branchApproved string // Protected branch the user agreed to commit to
+
+	// Amend mode: the commit of the current task and what it has changed so far
+	taskCommit    string
+	taskSummaries []string
+
+	lastReply string // Text of the last model reply (agent step summaries)
+	agentMu   sync.Mutex
+	agentStop context.CancelFunc // Set while /agent runs; Ctrl-C stops the agent instead of exiting
+
+	lspClients map[string]*lsp.Client // Language servers by file extension, started on demand
+
+	sandboxMu sync.Mutex
+	sandbox   *tools.Sandbox // Container of the sandbox config, created on first use
+
+	subAgentMu sync.Mutex // Sub-agents take turns running tool calls
+
+	budgetMu       sync.Mutex
+	budgetOverride bool            // /budget override: spent budgets no longer stop LLM calls
+	budgetWarned   map[string]bool // Budgets already warned about
+
+	offlineMu sync.Mutex
+	offline   bool // No provider reachable: requests are queued
+	replayDue bool // Back online: the queue is sent before the next prompt
+	replaying bool // A queued request is being sent; lost again, it keeps its place
+	requeued  bool // The request being replayed was queued again instead of sent
+
+	lastUnsure string // Last ambiguous request, learned from the command that follows
+
+	variantPrompt string // System prompt of the experiment variant served, "" for system_prompt
+	inFlight      string    // Input being handled, recorded if it crashes
+	inFlightAt    time.Time // When it started
+	inFlightMu    sync.Mutex
+
+	debugServer *debugserver.Server // Started by --debug-addr
+
+	onDelta func(delta string) // Receives streamed text instead of the terminal (--stdio)
+
+	input       *terminalInput // Stdin of readline on a terminal
+	interactive bool           // Run reads the terminal: lines typed during a reply are queued
+	typedAhead  []string       // Lines typed during a reply, handled next
+	draft       string         // Line being typed when a reply ended
+
+	seenMu sync.Mutex
+	seen   map[string][32]byte // Files the model was given, by content hash, to notice edits made elsewhere
 }
 
 // NewChat creates a new chat interface
@@ -43,18 +112,30 @@ func NewChat(engine *core.Engine) (*Chat, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Initialize components
-	modules := core.NewModuleManager(engine)
+	moduleMgr := core.NewModuleManager(engine)
 	registry := providers.NewRegistry(engine.DB())
 	sessionMgr := session.NewManager(engine)
 	gitMgr := git.NewManager("")
 	parser := NewIntentParser(engine.DB())
 
+	toolRegistry := tools.NewRegistry()
+	for _, t := range tools.FileTools() {
+		toolRegistry.Register(t)
+	}
+
 	// Setup readline
+	stdin, input := stdinInput()
 	rl, err := readline.NewEx(&readline.Config{
-		Prompt:          "\033[36m>\033[0m ",
+		Prompt:          basePrompt,
 		HistoryFile:     ".goclode/history",
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
+		// The current streams, not readline's defaults captured at
+		// startup: --stdio swaps them so the terminal never reads the
+		// editor's requests
+		Stdin:  stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
 	})
 	if err != nil {
 		cancel()
@@ -62,17 +143,97 @@ func NewChat(engine *core.Engine) (*Chat, error) {
 	}
 
 	chat := &Chat{
-		engine:   engine,
-		modules:  modules,
-		registry: registry,
-		session:  sessionMgr,
-		git:      gitMgr,
-		parser:   parser,
-		rl:       rl,
-		ctx:      ctx,
-		cancel:   cancel,
+		engine:    engine,
+		modules:   moduleMgr,
+		registry:  registry,
+		session:   sessionMgr,
+		git:       gitMgr,
+		parser:    parser,
+		validator: validate.NewRunner(engine.DB(), gitMgr.WorkDir()),
+		tools:     toolRegistry,
+		rl:        rl,
+		input:     input,
+		ctx:       ctx,
+		cancel:    cancel,
+
+		permissions: tools.NewPermissions(engine.DB()),
+		learning:    modules.NewLearningModule(engine, moduleMgr),
+		debug:       modules.NewDebugModule(engine, moduleMgr),
 	}
 
+	chat.useDefaultProvider()
+	chat.setupRedaction()
+	chat.learning.SetProject(gitMgr.WorkDir())
+	if err := chat.learning.OpenGlobal(""); err != nil {
+		fmt.Printf("\033[33m⚠️  %v: global learning stays in this project\033[0m\n", err)
+	}
+	modules.NewReportModule(engine, moduleMgr, gitMgr.WorkDir())
+	parser.SetLearned(chat.learnedIntent)
+	moduleMgr.SetTracer(chat.debug)
+
+	chat.tools.Register(tools.RunCommand(tools.CommandOptions{
+		Dir:       gitMgr.WorkDir(),
+		Timeout:   time.Duration(engine.GetConfigInt("command_timeout")) * time.Second,
+		MaxOutput: maxCommandOutput,
+		Approve:   chat.approveCommand,
+		Shell:     chat.runShell,
+	}))
+
+	chat.tools.Register(tools.RunTests(tools.RunTestsOptions{
+		Dir:       gitMgr.WorkDir(),
+		Command:   chat.testCommand,
+		Timeout:   time.Duration(engine.GetConfigInt("test_timeout")) * time.Second,
+		MaxOutput: maxCommandOutput,
+		Shell:     chat.runShell,
+	}))
+
+	chat.tools.Register(tools.RunSnippet(tools.SnippetOptions{
+		Root:      gitMgr.WorkDir(),
+		Timeout:   time.Duration(engine.GetConfigInt("command_timeout")) * time.Second,
+		MaxOutput: maxCommandOutput,
+		Approve:   chat.approveSnippet,
+		Shell:     chat.runShell,
+	}))
+
+	chat.tools.Register(tools.ReadFile(tools.ReadFileOptions{
+		Resolve:  chat.modelReadPath,
+		MaxBytes: maxFileContextBytes,
+		Screen:   chat.screenContent,
+	}))
+
+	chat.tools.Register(tools.Search(tools.SearchOptions{
+		Root:       gitMgr.WorkDir(),
+		Files:      chat.indexedFiles,
+		Filter:     chat.searchFilter,
+		MaxResults: maxSearchResults,
+	}))
+
+	chat.tools.Register(tools.WebSearch(tools.WebSearchOptions{
+		Backend:    chat.webSearchBackend,
+		MaxResults: chat.webSearchResults,
+		Summarize:  chat.summarizeSearch,
+		Screen:     chat.screenContent,
+	}))
+
+	chat.tools.Register(tools.Dependencies(tools.DependenciesOptions{
+		Dir: gitMgr.WorkDir(),
+	}))
+
+	chat.tools.Register(tools.GoDoc(tools.GoDocOptions{
+		Dir:       gitMgr.WorkDir(),
+		MaxOutput: maxCommandOutput,
+	}))
+
+	chat.tools.Register(tools.Todo(tools.TodoOptions{
+		Save: chat.saveTodos,
+		List: chat.listTodos,
+	}))
+
+	chat.tools.Register(tools.Delegate(tools.DelegateOptions{
+		MaxTasks: func() int { return engine.GetConfigInt("subagent_max_tasks") },
+		Run:      chat.runSubAgents,
+	}))
+
 	// Set provider in git for commit messages
 	if p := registry.Current(); p != nil {
 		gitMgr.SetProvider(p.ID())
@@ -83,37 +244,46 @@ func NewChat(engine *core.Engine) (*Chat, error) {
 
 // Run starts the chat loop
 func (c *Chat) Run() error {
+	defer c.recoverCrash()
+
 	// Handle signals
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
-		<-sigCh
-		c.shutdown()
+		for sig := range sigCh {
+			if sig == syscall.SIGINT && c.stopAgent() {
+				continue
+			}
+			c.shutdown()
+			return
+		}
 	}()
 
-	// Create session
-	providerID := "cerebras"
-	if p := c.registry.Current(); p != nil {
-		providerID = p.ID()
-	}
-
-	sess, err := c.session.Create(providerID)
+	sess, err := c.startSession()
 	if err != nil {
-		return fmt.Errorf("create session: %w", err)
+		return err
 	}
 
 	// Welcome message
 	c.printWelcome(sess)
-
-	// Emit session start event
-	c.modules.Emit("session_start", map[string]interface{}{
-		"session_id": sess.ID,
-		"provider":   providerID,
-	})
+	if id, n, err := c.session.UnfinishedTasks(); err == nil && id != "" {
+		fmt.Printf("\033[33m📋 Session %s has %d unfinished tasks: /resume %s\033[0m\n\n", id[:8], n, id[:8])
+	}
+	if plan, err := c.session.ActivePlan(); err == nil {
+		fmt.Printf("\033[33m🗺  Plan in progress: %s (/agent resume to continue it)\033[0m\n\n", plan.Goal)
+	}
+	c.printCrashes()
+	if pending, err := c.session.PendingRequests(); err == nil && len(pending) > 0 {
+		fmt.Printf("\033[33m📥 %d requests queued while offline are sent once the provider answers (/queue)\033[0m\n\n", len(pending))
+		c.resumeQueue()
+	}
 
 	// Main loop
+	c.interactive = true
 	for {
-		line, err := c.rl.Readline()
+		c.replayQueue()
+		c.updatePrompt()
+		line, err := c.nextInput()
 		if err != nil {
 			if err == readline.ErrInterrupt {
 				continue
@@ -134,19 +304,53 @@ func (c *Chat) Run() error {
 		if intent == nil {
 			continue
 		}
+		intent = c.learnIntent(intent)
 
 		// Handle intent
+		c.setInFlight(line)
 		if err := c.handleIntent(intent); err != nil {
 			fmt.Printf("\033[31mError: %v\033[0m\n", err)
 		}
+		c.setInFlight("")
 	}
 
 	c.shutdown()
 	return nil
 }
 
+// startSession creates the session with its backups, indexes, and
+// experiment variant, and starts the background work
+func (c *Chat) startSession() (*session.Session, error) {
+	providerID := "cerebras"
+	if p := c.registry.Current(); p != nil {
+		providerID = p.ID()
+	}
+
+	sess, err := c.session.Create(providerID)
+	if err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+
+	c.backups = workspace.NewBackups(c.git.WorkDir(), sess.ID)
+	c.assignVariant()
+	c.index = workspace.NewFileIndex(c.git.WorkDir())
+	c.symbols = index.NewStore(c.engine, c.git.WorkDir())
+	c.goSafe(c.syncSymbols)
+	c.goSafe(c.watchWorkspace)
+	c.goSafe(func() { c.modules.RunCron(c.ctx, time.Hour) })
+
+	// Emit session start event
+	c.modules.Emit("session_start", map[string]interface{}{
+		"session_id": sess.ID,
+		"provider":   providerID,
+	})
+	return sess, nil
+}
+
 // handleIntent routes intents to handlers
 func (c *Chat) handleIntent(intent *Intent) error {
+	c.fixRound = 0
+
 	// Emit intent event for debugging
 	c.modules.Emit("intent_parsed", map[string]interface{}{
 		"type":       string(intent.Type),
@@ -174,7 +378,10 @@ func (c *Chat) handleIntent(intent *Intent) error {
 		return c.showDiff()
 
 	case IntentUndo:
-		return c.handleUndo()
+		return c.handleUndo(intent.Args)
+
+	case IntentRedo:
+		return c.handleRedo(intent.Args)
 
 	case IntentSwitch:
 		return c.handleSwitch(intent.Provider)
@@ -186,7 +393,102 @@ func (c *Chat) handleIntent(intent *Intent) error {
 		return c.toggleDebug()
 
 	case IntentFeedback:
-		return c.handleFeedback(intent.Raw)
+		return c.handleFeedback(intent)
+
+	case IntentInspect:
+		return c.handleIntentInspect(intent)
+
+	case IntentLearn:
+		return c.handleLearn(intent.Args)
+
+	case IntentExperiment:
+		return c.handleExperiment(intent.Args)
+
+	case IntentTrace:
+		return c.handleTrace(intent.Args)
+
+	case IntentAnalyze:
+		return c.handleAnalyze(intent.Args)
+
+	case IntentDump:
+		return c.handleDump(intent.Args)
+
+	case IntentInit:
+		return c.handleInit(intent.Args)
+
+	case IntentPrompt:
+		return c.handlePrompt(intent.Args)
+
+	case IntentBudget:
+		return c.handleBudget(intent.Args)
+
+	case IntentUsage:
+		return c.handleUsage(intent.Args)
+
+	case IntentAudit:
+		return c.handleAudit(intent.Args)
+
+	case IntentQueue:
+		return c.handleQueue(intent.Args)
+
+	case IntentContinue:
+		return c.handleContinue()
+
+	case IntentCheckpoint:
+		return c.handleCheckpoint(intent.Args)
+
+	case IntentRollback:
+		return c.handleRollback(intent.Args)
+
+	case IntentLog:
+		return c.showLog(intent.Args)
+
+	case IntentTask:
+		c.taskCommit = ""
+		fmt.Println("\033[32m✓ New task: the next changes get their own commit\033[0m")
+		return nil
+
+	case IntentTest:
+		return c.handleTest(intent.Args)
+
+	case IntentRun:
+		return c.handleRun(intent.Raw)
+
+	case IntentSandbox:
+		return c.handleSandbox(intent.Args)
+
+	case IntentTodo:
+		return c.handleTodo(intent.Args)
+
+	case IntentResume:
+		return c.handleResume(intent.Args)
+
+	case IntentRecover:
+		return c.handleResumeRecovered(intent.Args)
+
+	case IntentSymbols:
+		return c.handleSymbols(intent.Args)
+
+	case IntentIndex:
+		return c.handleIndex(intent.Args)
+
+	case IntentAgent:
+		return c.handleAgent(intent.Args)
+
+	case IntentPermissions:
+		return c.handlePermissions(intent.Args)
+
+	case IntentResolve:
+		return c.handleResolve(intent.Args)
+
+	case IntentPush:
+		return c.handlePush(intent.Args)
+
+	case IntentPR:
+		return c.handlePR(intent.Args)
+
+	case IntentRestore:
+		return c.handleRestore(intent.Args)
 
 	case IntentCode, IntentQuestion:
 		return c.handleChat(intent)
@@ -200,91 +502,203 @@ func (c *Chat) handleIntent(intent *Intent) error {
 
 // handleChat handles code/question intents
 func (c *Chat) handleChat(intent *Intent) error {
+	if c.isOffline() {
+		return c.queueRequest(intent.Raw)
+	}
+
+	// Offer tools when enabled; the model may call them over several rounds
+	var toolDefs []providers.Tool
+	if c.engine.GetConfigBool("tool_calls") {
+		toolDefs = c.tools.Definitions()
+	}
+
+	turn, err := c.converse(intent, toolDefs)
+	if errors.Is(err, errOffline) {
+		return c.queueRequest(intent.Raw)
+	}
+	if err != nil {
+		return err
+	}
+	filesChanged := turn.toolFiles
+
+	// Extract and apply file changes written as markdown
+	changes := c.extractFileChanges(turn.resp.Content)
+	if len(changes) > 0 && turn.resp.Truncated() {
+		fmt.Println("\033[90mThe changes in this reply are applied once /continue finishes it\033[0m")
+		changes = nil
+	}
+	if len(changes) > 0 {
+		if _, err := c.applyChanges(changes); err != nil {
+			fmt.Printf("\033[33m⚠️  Could not apply changes: %v\033[0m\n", err)
+		}
+		filesChanged += len(changes)
+	}
+
+	c.emitChatComplete(turn, filesChanged)
+
+	if failure := c.pendingFix; failure != "" {
+		c.pendingFix = ""
+		return c.runFixRound(failure)
+	}
+
+	return nil
+}
+
+// chatTurn is the outcome of sending a request to the model
+type chatTurn struct {
+	resp      *providers.Response // Final reply
+	tokensIn  int
+	tokensOut int
+	latency   int64
+	toolFiles int // File tool calls run
+}
+
+// converse sends a request to the model with the session's context,
+// running the tools it calls for up to max_tool_rounds rounds, and saves
+// the exchange
+func (c *Chat) converse(intent *Intent, toolDefs []providers.Tool) (*chatTurn, error) {
 	provider := c.registry.Current()
 	if provider == nil {
-		return fmt.Errorf("no provider available")
+		return nil, fmt.Errorf("no provider available")
 	}
 
 	// Build messages with context
 	messages, err := c.buildMessages(intent)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Save user message
 	c.session.AddMessage("user", intent.Raw, nil)
 
-	// Show thinking indicator
-	fmt.Print("\033[90m🤔 Thinking...\033[0m")
-
-	// Stream response
-	start := time.Now()
-	stream, err := provider.Stream(c.ctx, &providers.Request{
-		Messages:    messages,
-		Temperature: 0.7,
-	})
-	if err != nil {
-		fmt.Println()
-		return fmt.Errorf("stream: %w", err)
-	}
-
-	// Clear thinking indicator
-	fmt.Print("\r\033[K")
+	maxRounds := c.engine.GetConfigInt("max_tool_rounds")
+	turn := &chatTurn{}
+	for round := 0; ; round++ {
+		resp, err := c.streamResponse(provider, messages, toolDefs)
+		if err != nil {
+			if errors.Is(err, errOffline) {
+				if round == 0 {
+					// Never reached the model: the caller may queue it
+					c.session.DropUnansweredMessage()
+					return nil, err
+				}
+				err = fmt.Errorf("stopped after %d tool rounds: %v", round, err)
+			}
+			return nil, err
+		}
+		turn.resp = resp
+		turn.tokensIn += resp.TokensIn
+		turn.tokensOut += resp.TokensOut
+		turn.latency += resp.Latency
 
-	var fullResponse strings.Builder
-	var tokensIn, tokensOut int
+		// Save assistant message
+		c.session.AddMessage("assistant", messageContent(resp), resp)
 
-	for chunk := range stream {
-		if chunk.Error != nil {
-			return chunk.Error
+		if len(resp.ToolCalls) == 0 {
+			break
 		}
-
-		if chunk.Delta != "" {
-			fmt.Print(chunk.Delta)
-			fullResponse.WriteString(chunk.Delta)
+		if round >= maxRounds {
+			fmt.Printf("\033[33m⚠️  Stopping after %d tool rounds\033[0m\n", round)
+			break
 		}
 
-		if chunk.Done {
-			tokensIn = chunk.TokensIn
-			tokensOut = chunk.TokensOut
+		messages = append(messages, providers.Message{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		messages = append(messages, c.dispatchToolCalls(resp.ToolCalls)...)
+		for _, call := range resp.ToolCalls {
+			if tools.IsFileTool(call.Function.Name) {
+				turn.toolFiles++
+			}
 		}
 	}
-	fmt.Println()
 
-	response := fullResponse.String()
-	latency := time.Since(start).Milliseconds()
+	c.lastReply = turn.resp.Content
+	return turn, nil
+}
 
-	// Save assistant message
-	c.session.AddMessage("assistant", response, &providers.Response{
-		TokensIn:  tokensIn,
-		TokensOut: tokensOut,
-		Latency:   latency,
-		Model:     provider.ID(),
+// emitChatComplete emits the completion event of a request
+func (c *Chat) emitChatComplete(turn *chatTurn, files int) {
+	c.modules.Emit("chat_complete", map[string]interface{}{
+		"tokens_in":  turn.tokensIn,
+		"tokens_out": turn.tokensOut,
+		"latency_ms": turn.latency,
+		"files":      files,
 	})
+}
 
-	// Extract and apply file changes
-	changes := c.extractFileChanges(response)
-	if len(changes) > 0 {
-		if err := c.applyChanges(changes); err != nil {
-			fmt.Printf("\033[33m⚠️  Could not apply changes: %v\033[0m\n", err)
+// runFixRound sends validation failures back to the LLM, bounded by max_fix_iterations
+func (c *Chat) runFixRound(failure string) error {
+	maxRounds := c.engine.GetConfigInt("max_fix_iterations")
+	if c.fixRound >= maxRounds {
+		if maxRounds > 0 {
+			fmt.Printf("\033[33m⚠️  Still failing after %d fix rounds, stopping\033[0m\n", c.fixRound)
 		}
+		return nil
 	}
+	c.fixRound++
 
-	// Emit completion event
-	c.modules.Emit("chat_complete", map[string]interface{}{
-		"tokens_in":  tokensIn,
-		"tokens_out": tokensOut,
-		"latency_ms": latency,
-		"files":      len(changes),
+	fmt.Printf("\n\033[33m🔧 Auto-fix round %d/%d\033[0m\n", c.fixRound, maxRounds)
+
+	prompt := "The changes you just made failed validation or commit checks:\n\n```\n" + failure +
+		"\n```\n\nFix these errors. Only change what is needed."
+	return c.handleChat(&Intent{
+		Type:       IntentCode,
+		Content:    prompt,
+		Raw:        prompt,
+		Confidence: 1.0,
 	})
+}
 
-	return nil
+// runValidators validates written files and returns a failure report, if any
+func (c *Chat) runValidators(runner *validate.Runner, files []string) string {
+	results := runner.Run(c.ctx, files)
+	if len(results) == 0 {
+		return ""
+	}
+
+	lintFix := c.engine.GetConfigBool("lint_fix")
+	blocking := make([]validate.Result, 0, len(results))
+	for _, r := range results {
+		if r.Passed() {
+			fmt.Printf("\033[32m✓ %s\033[0m \033[90m(%s)\033[0m\n", r.Validator.Name, r.Duration.Round(time.Millisecond))
+			continue
+		}
+		if r.Missing {
+			fmt.Printf("\033[90m- %s: %v\033[0m\n", r.Validator.Name, r.Err)
+			continue
+		}
+
+		// Lint findings are reported; only fixed by the LLM with lint_fix
+		if r.Validator.Kind == validate.KindLint && !lintFix {
+			fmt.Printf("\033[33m⚠️  %s: %v\033[0m\n", r.Validator.Name, r.Err)
+		} else {
+			blocking = append(blocking, r)
+			fmt.Printf("\033[31m✗ %s: %v\033[0m\n", r.Validator.Name, r.Err)
+		}
+		lines := strings.Split(r.Output, "\n")
+		if len(lines) > 20 {
+			lines = append(lines[:20], fmt.Sprintf("... (%d more lines)", len(lines)-20))
+		}
+		for _, line := range lines {
+			if line != "" {
+				fmt.Printf("  \033[90m%s\033[0m\n", line)
+			}
+		}
+	}
+
+	return validate.FailureReport(blocking)
 }
 
 // buildMessages builds the message list for the LLM
 func (c *Chat) buildMessages(intent *Intent) ([]providers.Message, error) {
 	// Get system prompt
 	systemPrompt, _ := c.engine.GetConfig("system_prompt")
+	if c.variantPrompt != "" {
+		systemPrompt = c.variantPrompt
+	}
 	if systemPrompt == "" {
 		systemPrompt = `You are GoClode, an AI coding assistant. Help users write and modify code.
 For file changes, use this format:
@@ -294,11 +708,25 @@ For file changes, use this format:
 // complete file content here
 ` + "```" + `
 
+For small edits to an existing file, send only the changed region instead:
+
+**File: path/to/file.ext**
+` + "```" + `language
+<<<<<<< SEARCH
+exact lines to find
+=======
+replacement lines
+>>>>>>> REPLACE
+` + "```" + `
+
+To delete a file, write **Delete: path/to/file.ext** on its own line.
+To rename or move a file, write **Rename: old/path.ext -> new/path.ext**.
+
 Be concise and direct.`
 	}
 
 	messages := []providers.Message{
-		{Role: "system", Content: systemPrompt},
+		{Role: "system", Content: systemPrompt + c.instructionsContext() + c.styleContext() + c.undoContext(intent.Content) + c.repoMap() + c.todoContext()},
 	}
 
 	// Add context from previous messages
@@ -310,10 +738,10 @@ Be concise and direct.`
 	contextMessages, _ := c.session.GetContextMessages(maxContext)
 	messages = append(messages, contextMessages...)
 
-	// Add current message
+	// Add current message, with any @file mentions attached
 	messages = append(messages, providers.Message{
 		Role:    "user",
-		Content: intent.Raw,
+		Content: intent.Raw + c.fileContext(intent.Raw) + c.gitContext(intent) + c.retrievedContext(intent),
 	})
 
 	return messages, nil
@@ -324,8 +752,29 @@ func (c *Chat) extractFileChanges(response string) []FileChange {
 	changes := make([]FileChange, 0)
 	seen := make(map[string]bool)
 
+	// Rename directives come first so later content for the new path applies on top:
+	// **Rename: old/path.ext -> new/path.ext**
+	renamePattern := regexp.MustCompile("\\*\\*(?:Rename|Move):?\\s*`?([a-zA-Z0-9_\\-./]+)`?\\s*(?:->|→|to)\\s*`?([a-zA-Z0-9_\\-./]+)`?\\*\\*")
+	for _, match := range renamePattern.FindAllStringSubmatch(response, -1) {
+		oldPath, newPath := match[1], match[2]
+		if oldPath == newPath || seen[oldPath] {
+			continue
+		}
+		seen[oldPath] = true
+		changes = append(changes, FileChange{
+			Path:    newPath,
+			OldPath: oldPath,
+		})
+	}
+
+	// Edit blocks for the same file add up, or apply to a whole-file block
+	// for it; lastFile takes unnamed ones
+	editsAt := make(map[string]int)
+	wholeAt := make(map[string]int)
+	lastFile := ""
+
 	// Find all code blocks with their language
-	codeBlockPattern := regexp.MustCompile("(?s)```([a-z]+)\n(.*?)```")
+	codeBlockPattern := regexp.MustCompile("(?s)```([a-zA-Z0-9_+\\-]*)([^\n`]*)\n(.*?)```")
 	codeBlocks := codeBlockPattern.FindAllStringSubmatchIndex(response, -1)
 
 	// Extension map for languages
@@ -344,38 +793,106 @@ func (c *Chat) extractFileChanges(response string) []FileChange {
 	}
 
 	for _, blockIdx := range codeBlocks {
-		if len(blockIdx) < 6 {
+		if len(blockIdx) < 8 {
 			continue
 		}
 
-		lang := response[blockIdx[2]:blockIdx[3]]
-		content := response[blockIdx[4]:blockIdx[5]]
+		lang := strings.ToLower(response[blockIdx[2]:blockIdx[3]])
+		info := response[blockIdx[4]:blockIdx[5]]
+		content := response[blockIdx[6]:blockIdx[7]]
 
-		// Look for filename in text before this code block (up to 500 chars)
-		searchStart := blockIdx[0] - 500
-		if searchStart < 0 {
-			searchStart = 0
+		// Explicit paths win: fence info string, then a first-line comment
+		filename := fenceFilename(lang, info)
+		if name, rest := firstLineFilename(content); name != "" {
+			if filename == "" {
+				filename = name
+			}
+			content = rest
 		}
-		textBefore := response[searchStart:blockIdx[0]]
 
-		var filename string
-		for _, pattern := range filenamePatterns {
-			matches := pattern.FindAllStringSubmatch(textBefore, -1)
-			if len(matches) > 0 {
-				filename = matches[len(matches)-1][1]
-				break
+		// Otherwise look for filename in text before this code block (up to 500 chars).
+		// Untagged blocks are usually shell snippets or output, so they need an explicit path.
+		if filename == "" && lang != "" {
+			searchStart := blockIdx[0] - 500
+			if searchStart < 0 {
+				searchStart = 0
+			}
+			textBefore := response[searchStart:blockIdx[0]]
+
+			for _, pattern := range filenamePatterns {
+				matches := pattern.FindAllStringSubmatch(textBefore, -1)
+				if len(matches) > 0 {
+					filename = matches[len(matches)-1][1]
+					break
+				}
+			}
+		}
+
+		// Partial edits name their target or continue the block before;
+		// they are never matched against existing files
+		var edits []EditBlock
+		if hasEditBlocks(content) {
+			parsed, err := parseEditBlocks(content)
+			if err != nil {
+				continue
+			}
+			edits = parsed
+		} else if lang == "diff" || isUnifiedDiff(content) {
+			diffPath, parsed := parseUnifiedDiff(content)
+			if filename == "" {
+				filename = diffPath
+			}
+			if len(parsed) == 0 {
+				continue
+			}
+			edits = parsed
+		}
+		if edits != nil {
+			// An unnamed edit block continues the file before it
+			if filename == "" {
+				filename = lastFile
+			}
+			if filename == "" {
+				continue
+			}
+			lastFile = filename
+			if i, ok := editsAt[filename]; ok {
+				changes[i].Edits = append(changes[i].Edits, edits...)
+				continue
+			}
+			if i, ok := wholeAt[filename]; ok {
+				updated, err := applyEdits(changes[i].Content, edits, false)
+				if err != nil {
+					fmt.Printf("\033[33m⚠️  Edit block for %s skipped: it does not apply to the file written above (%v)\033[0m\n", filename, err)
+					continue
+				}
+				changes[i].Content = updated
+				continue
 			}
+			if seen[filename] {
+				continue
+			}
+			seen[filename] = true
+			editsAt[filename] = len(changes)
+			changes = append(changes, FileChange{
+				Path:  filename,
+				Edits: edits,
+			})
+			continue
 		}
 
-		// If no filename found, generate one based on language
+		// If no filename found, match the code against existing files
 		if filename == "" {
 			ext, ok := langToExt[lang]
 			if !ok {
 				continue
 			}
-			filename = "main" + ext
+			if filename = c.guessFilename(content, ext); filename == "" {
+				continue
+			}
 		}
 
+		lastFile = filename
 		if seen[filename] {
 			continue
 		}
@@ -386,106 +903,717 @@ func (c *Chat) extractFileChanges(response string) []FileChange {
 			continue
 		}
 
+		wholeAt[filename] = len(changes)
 		changes = append(changes, FileChange{
 			Path:    filename,
 			Content: content,
 		})
 	}
 
+	// Deletion directives: **Delete: path/to/file.ext**
+	deletePattern := regexp.MustCompile("\\*\\*Delete:?\\s*`?([a-zA-Z0-9_\\-./]+)`?\\*\\*")
+	for _, match := range deletePattern.FindAllStringSubmatch(response, -1) {
+		filename := match[1]
+		if seen[filename] {
+			continue
+		}
+		seen[filename] = true
+		changes = append(changes, FileChange{
+			Path:   filename,
+			Delete: true,
+		})
+	}
+
 	return changes
 }
 
-// FileChange represents a file to be created/modified
+var (
+	fenceKeyPattern      = regexp.MustCompile(`(?i)\b(?:title|path|file|filename)\s*=\s*["']?([^\s"']+)["']?`)
+	fenceBarePathPattern = regexp.MustCompile(`^[a-zA-Z0-9_\-./]+\.[a-zA-Z0-9]+$`)
+	firstLineFilePattern = regexp.MustCompile(`(?i)^\s*(?://|#|--|/\*|<!--)\s*(?:file|filename|path)\s*:\s*([a-zA-Z0-9_\-./]+)\s*(?:\*/|-->)?\s*$`)
+)
+
+// fenceFilename extracts a path from a code fence info string,
+// e.g. "go title=main.go", "path=cmd/x/main.go", or "go:main.go"
+func fenceFilename(lang, info string) string {
+	// The key may have been read as the language, as in "path=x.go"
+	if m := fenceKeyPattern.FindStringSubmatch(lang + info); m != nil {
+		return m[1]
+	}
+
+	info = strings.TrimSpace(strings.TrimPrefix(info, ":"))
+	if fields := strings.Fields(info); len(fields) == 1 && fenceBarePathPattern.MatchString(fields[0]) {
+		return fields[0]
+	}
+	return ""
+}
+
+// firstLineFilename detects a leading "// file: x.go" style comment and
+// returns the path and the content without that line
+func firstLineFilename(content string) (string, string) {
+	firstLine, rest, _ := strings.Cut(content, "\n")
+	if m := firstLineFilePattern.FindStringSubmatch(firstLine); m != nil {
+		return m[1], rest
+	}
+	return "", content
+}
+
+// FileChange represents a file to be created/modified/deleted
 type FileChange struct {
 	Path    string
 	Content string
+	Edits   []EditBlock // Partial edits, resolved into Content before writing
+	Delete  bool        // Remove the file instead of writing it
+	OldPath string      // Rename source; Path is the destination
 }
 
-// applyChanges applies file changes and commits
-func (c *Chat) applyChanges(changes []FileChange) error {
+// IsRename reports whether the change moves a file
+func (fc FileChange) IsRename() bool {
+	return fc.OldPath != ""
+}
+
+// applyChanges applies file changes and commits; it reports whether they were applied
+func (c *Chat) applyChanges(changes []FileChange) (bool, error) {
 	if len(changes) == 0 {
-		return nil
+		return false, nil
+	}
+
+	// Keep every target inside the workspace and off protected, binary, and generated files
+	policy, _ := c.engine.GetConfig("path_policy")
+	guard := c.workspaceGuard()
+	allowGenerated := c.engine.GetConfigBool("allow_generated_edits")
+	for i := range changes {
+		path, err := sanitizePath(c.git.WorkDir(), changes[i].Path, policy)
+		if err != nil {
+			return false, fmt.Errorf("unsafe path: %w", err)
+		}
+		if err := guard.Check(path); err != nil {
+			return false, fmt.Errorf("refusing to modify: %w", err)
+		}
+		changes[i].Path = path
+
+		if !changes[i].Delete && !changes[i].IsRename() && !allowGenerated {
+			if err := workspace.CheckEditable(path); err != nil {
+				return false, fmt.Errorf("refusing to modify: %w (set allow_generated_edits to override)", err)
+			}
+		}
+
+		if changes[i].IsRename() {
+			oldPath, err := sanitizePath(c.git.WorkDir(), changes[i].OldPath, policy)
+			if err != nil {
+				return false, fmt.Errorf("unsafe path: %w", err)
+			}
+			if err := guard.Check(oldPath); err != nil {
+				return false, fmt.Errorf("refusing to modify: %w", err)
+			}
+			changes[i].OldPath = oldPath
+		}
+	}
+
+	// Edits apply to the working copy the model saw, so resolve them first
+	if err := c.resolveEdits(changes); err != nil {
+		return false, err
+	}
+
+	// Keep the user's uncommitted edits out of GoClode's changes and commit
+	if c.stashUserChanges(changes) {
+		defer c.restoreUserChanges()
 	}
 
 	// Show summary
 	fmt.Println("\n\033[33m📁 Files to modify:\033[0m")
 	for _, ch := range changes {
 		exists := fileExists(ch.Path)
-		if exists {
+		if ch.Delete {
+			fmt.Printf("  🗑️  %s (delete)\n", ch.Path)
+		} else if ch.IsRename() {
+			fmt.Printf("  🚚 %s → %s (rename)\n", ch.OldPath, ch.Path)
+		} else if exists && len(ch.Edits) > 0 {
+			fmt.Printf("  📝 %s (modify, %d hunks)\n", ch.Path, len(ch.Edits))
+		} else if exists {
 			fmt.Printf("  📝 %s (modify)\n", ch.Path)
 		} else {
 			fmt.Printf("  ✨ %s (create)\n", ch.Path)
 		}
 	}
 
-	// Ask for confirmation if enabled
+	// Large changes often mean a truncated file: require an explicit "yes"
+	originals := make(map[string]string)
+	for _, ch := range changes {
+		if !ch.Delete && !ch.IsRename() && fileExists(ch.Path) {
+			originals[ch.Path], _ = c.git.GetFileContent(ch.Path)
+		}
+	}
+	warnings := largeChangeWarnings(changes, originals,
+		c.engine.GetConfigInt("large_change_delete_pct"), c.engine.GetConfigInt("large_change_max_files"))
+	if len(warnings) > 0 {
+		fmt.Println("\n\033[33m⚠️  Large change:\033[0m")
+		for _, w := range warnings {
+			fmt.Printf("  • %s\n", w)
+		}
+		confirm, _ := c.ask("\033[36mType 'yes' to apply anyway: \033[0m")
+		if strings.ToLower(strings.TrimSpace(confirm)) != "yes" {
+			fmt.Println("\033[33m❌ Cancelled\033[0m")
+			return false, nil
+		}
+	}
+
+	// Writes through symlinks must stay inside the workspace
+	if !c.confirmSymlinks(changes) {
+		fmt.Println("\033[33m❌ Cancelled\033[0m")
+		return false, nil
+	}
+
+	// Ask for confirmation if enabled ("p" reviews each hunk, like git add -p)
+	reviewHunks := c.engine.GetConfigBool("confirm_hunks")
 	if c.engine.GetConfigBool("confirm_changes") {
-		fmt.Print("\n\033[36mApply changes? [Y/n] \033[0m")
-		var confirm string
-		fmt.Scanln(&confirm)
+		confirm, _ := c.ask("\n\033[36mApply changes? [Y/n/p] \033[0m")
 		confirm = strings.ToLower(strings.TrimSpace(confirm))
-		if confirm != "" && confirm != "y" && confirm != "yes" {
+		if confirm == "p" {
+			reviewHunks = true
+		} else if confirm != "" && confirm != "y" && confirm != "yes" {
 			fmt.Println("\033[33m❌ Cancelled\033[0m")
-			return nil
+			return false, nil
+		}
+	}
+
+	if reviewHunks {
+		changes = c.reviewHunks(changes)
+		if len(changes) == 0 {
+			fmt.Println("\033[33m❌ No hunks accepted\033[0m")
+			return false, nil
+		}
+	}
+
+	// In staging mode, prove the changes in a shadow copy first
+	validateChanges := c.engine.GetConfigBool("validate_changes")
+	if validateChanges && c.engine.GetConfigBool("stage_changes") {
+		ok, err := c.stageChanges(changes)
+		if err != nil {
+			return false, fmt.Errorf("stage: %w", err)
 		}
+		if !ok {
+			fmt.Println("\033[33m❌ Validation failed in stage; working tree left untouched\033[0m")
+			return false, nil
+		}
+		validateChanges = false
 	}
 
-	// Apply changes
+	// Apply changes, backing up anything we overwrite
+	c.backups.Begin()
+	c.session.BeginBatch()
 	filePaths := make([]string, 0, len(changes))
+	befores := make(map[string]string) // Modified files' content before GoClode wrote them
 	for _, ch := range changes {
+		if ch.Delete {
+			if err := c.backups.Save(ch.Path); err != nil {
+				return false, fmt.Errorf("backup %s: %w", ch.Path, err)
+			}
+
+			contentBefore, _ := c.git.GetFileContent(ch.Path)
+			if err := os.Remove(ch.Path); err != nil {
+				if os.IsNotExist(err) {
+					fmt.Printf("\033[33m⚠️  %s does not exist, skipping delete\033[0m\n", ch.Path)
+					continue
+				}
+				return false, fmt.Errorf("delete %s: %w", ch.Path, err)
+			}
+
+			patch := diff.Unified(ch.Path, contentBefore, "")
+			c.session.RecordFileChange(ch.Path, "delete", contentBefore, "", patch)
+			filePaths = append(filePaths, ch.Path)
+			c.untrackFile(ch.Path)
+			c.emitFileApplied(ch.Path, "delete", patch)
+
+			fmt.Printf("\033[32m✓ %s (deleted)\033[0m\n", ch.Path)
+			continue
+		}
+
+		if ch.IsRename() {
+			if err := c.renameFile(ch.OldPath, ch.Path); err != nil {
+				return false, err
+			}
+
+			content, _ := c.git.GetFileContent(ch.Path)
+			patch := fmt.Sprintf("rename from %s\nrename to %s\n", ch.OldPath, ch.Path)
+			c.session.RecordFileChange(ch.Path, "rename", content, content, patch)
+			filePaths = append(filePaths, ch.OldPath, ch.Path)
+			c.untrackFile(ch.OldPath)
+			c.trackFile(ch.Path)
+			c.emitFileApplied(ch.Path, "rename", patch)
+
+			fmt.Printf("\033[32m✓ %s → %s\033[0m\n", ch.OldPath, ch.Path)
+			continue
+		}
+
 		// Create directories if needed
-		dir := ch.Path[:max(0, strings.LastIndex(ch.Path, "/"))]
-		if dir != "" {
-			os.MkdirAll(dir, 0755)
+		if err := ensureDir(ch.Path); err != nil {
+			return false, err
 		}
 
-		// Get content before for recording
+		// Get content before for recording; an empty file still exists,
+		// so undo must empty it rather than delete it
 		contentBefore, _ := c.git.GetFileContent(ch.Path)
-		operation := "modify"
-		if contentBefore == "" {
-			operation = "create"
+		operation := "create"
+		if fileExists(ch.Path) {
+			operation = "modify"
 		}
 
-		// Write file
-		if err := os.WriteFile(ch.Path, []byte(ch.Content), 0644); err != nil {
-			return fmt.Errorf("write %s: %w", ch.Path, err)
+		if err := c.backups.Save(ch.Path); err != nil {
+			return false, fmt.Errorf("backup %s: %w", ch.Path, err)
+		}
+
+		// Write file, keeping its permissions
+		if err := workspace.WriteFile(ch.Path, ch.Content); err != nil {
+			return false, fmt.Errorf("write %s: %w", ch.Path, err)
 		}
 
 		// Record change
-		c.session.RecordFileChange(ch.Path, operation, contentBefore, ch.Content, "")
+		patch := diff.Unified(ch.Path, contentBefore, ch.Content)
+		c.session.RecordFileChange(ch.Path, operation, contentBefore, ch.Content, patch)
 		filePaths = append(filePaths, ch.Path)
+		if operation == "modify" {
+			befores[ch.Path] = contentBefore
+		}
+		c.trackFile(ch.Path)
+		c.emitFileApplied(ch.Path, operation, patch)
 
 		fmt.Printf("\033[32m✓ %s\033[0m\n", ch.Path)
 	}
 
+	// Validate before committing so formatters' output is what gets committed
+	if validateChanges {
+		c.pendingFix = c.runValidators(c.validator, filePaths)
+
+		// Keep undo snapshots in sync with what formatters wrote
+		for _, ch := range changes {
+			if ch.Delete || ch.IsRename() {
+				continue
+			}
+			if data, err := os.ReadFile(ch.Path); err == nil && string(data) != ch.Content {
+				c.session.UpdateContentAfter(ch.Path, string(data))
+			}
+		}
+
+		if report := c.lspDiagnostics(filePaths); report != "" {
+			if c.pendingFix != "" {
+				report = c.pendingFix + "\n\n" + report
+			}
+			c.pendingFix = report
+		}
+	}
+
 	// Auto-commit if enabled
-	if c.engine.GetConfigBool("auto_commit") && c.git.IsRepo() {
-		message := fmt.Sprintf("GoClode: %s", summarizeChanges(changes))
-		hash, err := c.git.AutoCommit(filePaths, message)
-		if err != nil {
+	if c.engine.GetConfigBool("auto_commit") && c.git.IsRepo() && c.checkBranch() {
+		summary := summarizeChanges(changes)
+		message := fmt.Sprintf("GoClode: %s", summary)
+		amend := c.engine.GetConfigBool("amend_commits") && c.canAmend()
+		if amend {
+			message = amendMessage(append(c.taskSummaries, summary))
+		}
+		if trailers, err := c.engine.GetConfig("commit_trailers"); err == nil {
+			c.git.SetTrailers(trailers)
+		}
+
+		files := c.stageEdits(filePaths, befores)
+		var hash string
+		var err error
+		if amend {
+			hash, err = c.git.AmendCommit(files, message)
+		} else {
+			hash, err = c.git.AutoCommit(files, message)
+		}
+		var hookErr *git.HookError
+		if errors.As(err, &hookErr) {
+			// Let the LLM fix what the hooks complained about; the changes stay staged
+			fmt.Printf("\033[33m⚠️  Git commit %v\033[0m\n", err)
+			if hookErr.Output != "" {
+				fmt.Printf("  \033[90m%s\033[0m\n", strings.ReplaceAll(hookErr.Output, "\n", "\n  "))
+			}
+			report := fmt.Sprintf("git %s hook rejected the commit:\n%s", strings.Join(hookErr.Hooks, "/"), hookErr.Output)
+			if c.pendingFix != "" {
+				report = c.pendingFix + "\n\n" + report
+			}
+			c.pendingFix = report
+		} else if err != nil {
 			fmt.Printf("\033[33m⚠️  Git commit failed: %v\033[0m\n", err)
+		} else if amend {
+			c.session.AmendGitCommit(c.taskCommit, hash, message, len(filePaths))
+			c.taskCommit, c.taskSummaries = hash, append(c.taskSummaries, summary)
+			fmt.Printf("\033[90m📦 Amended: %s%s\033[0m\n", hash[:8], c.recordCommitStat(hash))
 		} else {
 			c.session.RecordGitCommit(hash, message, len(filePaths))
-			fmt.Printf("\033[90m📦 Committed: %s\033[0m\n", hash[:8])
+			c.taskCommit, c.taskSummaries = hash, []string{summary}
+			fmt.Printf("\033[90m📦 Committed: %s%s\033[0m\n", hash[:8], c.recordCommitStat(hash))
+		}
+
+		if err == nil && c.engine.GetConfigBool("auto_push") {
+			if branch, err := c.git.CurrentBranch(); err == nil {
+				if err := c.pushBranch(c.remoteName(), branch); err != nil {
+					fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+				}
+			}
 		}
 	}
 
 	fmt.Println("\033[32m✓ Done\033[0m")
+	return true, nil
+}
+
+// emitFileApplied notifies modules of one applied file so hooks can format,
+// index, or report it
+func (c *Chat) emitFileApplied(path, operation, patch string) {
+	c.modules.Emit("file_applied", map[string]interface{}{
+		"path":      path,
+		"operation": operation,
+		"language":  validate.LanguageFor(path),
+		"diff":      patch,
+	})
+}
+
+// recordCommitStat stores a commit's shortstat and returns it for display
+func (c *Chat) recordCommitStat(hash string) string {
+	stat, err := c.git.CommitStat(hash)
+	if err != nil {
+		return ""
+	}
+	c.session.SetCommitStats(hash, stat.Files, stat.Insertions, stat.Deletions)
+	return " (" + stat.String() + ")"
+}
+
+// canAmend reports whether the current task's commit is still HEAD and unpushed
+func (c *Chat) canAmend() bool {
+	if c.taskCommit == "" {
+		return false
+	}
+	head, err := c.git.CurrentCommit()
+	return err == nil && head == c.taskCommit && !c.git.IsPushed(c.taskCommit)
+}
+
+// stageEdits stages only GoClode's hunks of files the user had also edited
+// and returns the files that can be staged whole
+func (c *Chat) stageEdits(files []string, befores map[string]string) []string {
+	whole := make([]string, 0, len(files))
+	for _, path := range files {
+		if before, ok := befores[path]; ok {
+			staged, err := c.git.StageEdit(path, before)
+			if err != nil {
+				fmt.Printf("\033[33m⚠️  Committing all of %s (%v)\033[0m\n", path, err)
+			} else if staged {
+				fmt.Printf("\033[90m✂️  Committing only GoClode's hunks of %s\033[0m\n", path)
+				continue
+			}
+		}
+		whole = append(whole, path)
+	}
+	return whole
+}
+
+// stashUserChanges offers to stash uncommitted edits to tracked files
+// (auto_stash: ask, always, never) and reports whether it did. Files the
+// changes touch are never stashed: their new content was resolved from the
+// working copy, and stageEdits commits only GoClode's hunks of them.
+func (c *Chat) stashUserChanges(changes []FileChange) bool {
+	mode, _ := c.engine.GetConfig("auto_stash")
+	if mode == "never" || !c.git.IsRepo() || !c.git.HasTrackedChanges() {
+		return false
+	}
+
+	if edited := editedByUser(c.git, changes); len(edited) > 0 {
+		fmt.Printf("\033[90mNot stashing: you have uncommitted edits to %s\033[0m\n", strings.Join(edited, ", "))
+		return false
+	}
+
+	if mode != "always" {
+		confirm, _ := c.ask("\n\033[36mYou have uncommitted changes. Stash them while applying? [Y/n] \033[0m")
+		confirm = strings.ToLower(strings.TrimSpace(confirm))
+		if confirm != "" && confirm != "y" && confirm != "yes" {
+			return false
+		}
+	}
+
+	stashed, err := c.git.Stash("goclode: auto-stash")
+	if err != nil {
+		fmt.Printf("\033[33m⚠️  Could not stash: %v\033[0m\n", err)
+		return false
+	}
+	if stashed {
+		fmt.Println("\033[90m📥 Stashed your uncommitted changes\033[0m")
+	}
+	return stashed
+}
+
+// editedByUser returns the files of changes with uncommitted edits
+func editedByUser(g *git.Manager, changes []FileChange) []string {
+	dirty, err := g.ChangedFiles()
+	if err != nil {
+		return nil
+	}
+	isDirty := make(map[string]bool, len(dirty))
+	for _, path := range dirty {
+		isDirty[filepath.Clean(path)] = true
+	}
+	edited := make([]string, 0)
+	for _, ch := range changes {
+		for _, path := range []string{ch.Path, ch.OldPath} {
+			if path != "" && isDirty[filepath.Clean(path)] {
+				edited = append(edited, path)
+			}
+		}
+	}
+	return edited
+}
+
+// restoreUserChanges re-applies the auto-stash; on conflict it stays in the stash list
+func (c *Chat) restoreUserChanges() {
+	if err := c.git.StashPop(); err != nil {
+		fmt.Printf("\033[33m⚠️  Your stashed changes conflict with GoClode's: resolve the conflicts, then run git stash drop\033[0m\n")
+		return
+	}
+	fmt.Println("\033[90m📤 Restored your uncommitted changes\033[0m")
+}
+
+// guessFilename picks the file an unnamed code block belongs to. Without a
+// confident match it falls back to "main"+ext, but never overwrites an
+// existing main file the code does not resemble.
+func (c *Chat) guessFilename(content, ext string) string {
+	if c.index == nil {
+		return "main" + ext
+	}
+
+	if c.symbols != nil {
+		if path, _ := c.symbols.GuessPath(workspace.Declarations(content), ext); path != "" {
+			return path
+		}
+	}
+	if path, _ := c.index.GuessPath(content, ext); path != "" {
+		return path
+	}
+
+	filename := "main" + ext
+	if fileExists(filename) {
+		fmt.Printf("\033[33m⚠️  Skipping %s block without a filename (no matching file found)\033[0m\n", ext)
+		return ""
+	}
+	return filename
+}
+
+// stageChanges applies changes to a copy of the workspace in .goclode/stage
+// and runs validators there. On success the staged content (including any
+// formatter rewrites) replaces the proposed content; on failure the report is
+// queued for an auto-fix round and false is returned.
+func (c *Chat) stageChanges(changes []FileChange) (bool, error) {
+	stage, err := workspace.NewStage(c.git.WorkDir(), c.index.Files())
+	if err != nil {
+		return false, err
+	}
+	defer stage.Cleanup()
+
+	files := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		switch {
+		case ch.Delete:
+			err = stage.Remove(ch.Path)
+		case ch.IsRename():
+			err = stage.Rename(ch.OldPath, ch.Path)
+			files = append(files, ch.OldPath)
+		default:
+			err = stage.Write(ch.Path, ch.Content)
+		}
+		if err != nil {
+			return false, err
+		}
+		files = append(files, ch.Path)
+	}
+
+	fmt.Println("\033[90m🧪 Validating in stage...\033[0m")
+	if report := c.runValidators(c.validator.WithWorkDir(stage.Dir()), files); report != "" {
+		c.pendingFix = report
+		return false, nil
+	}
+
+	for i, ch := range changes {
+		if ch.Delete || ch.IsRename() {
+			continue
+		}
+		if content, err := stage.Read(ch.Path); err == nil {
+			changes[i].Content = content
+		}
+	}
+	return true, nil
+}
+
+// confirmSymlinks asks before writing through symlinks that leave the
+// workspace, or deleting/renaming a link (which acts on the link, not its target)
+func (c *Chat) confirmSymlinks(changes []FileChange) bool {
+	root := c.git.WorkDir()
+	risks := make([]string, 0)
+	for _, ch := range changes {
+		if filepath.IsAbs(ch.Path) {
+			continue // Explicitly allowed outside the workspace by path_policy
+		}
+
+		if ch.Delete || ch.IsRename() {
+			src := ch.Path
+			if ch.IsRename() {
+				src = ch.OldPath
+			}
+			if isSymlink(src) {
+				risks = append(risks, fmt.Sprintf("%s is a symlink; the link itself will be changed", src))
+			}
+			if r := symlinkRisk(root, src, false); r != "" {
+				risks = append(risks, r)
+			}
+		}
+		if !ch.Delete {
+			if r := symlinkRisk(root, ch.Path, !ch.IsRename()); r != "" {
+				risks = append(risks, r)
+			}
+		}
+	}
+	if len(risks) == 0 {
+		return true
+	}
+
+	fmt.Println("\n\033[33m⚠️  Symlinks:\033[0m")
+	for _, r := range risks {
+		fmt.Printf("  • %s\n", r)
+	}
+	confirm, _ := c.ask("\033[36mContinue anyway? [y/N] \033[0m")
+	confirm = strings.ToLower(strings.TrimSpace(confirm))
+	return confirm == "y" || confirm == "yes"
+}
+
+// resolveEdits resolves partial edits against the current file contents,
+// then matches the existing file's line endings, final newline, and BOM.
+// A file the batch renames is read from its old path, where it still is.
+func (c *Chat) resolveEdits(changes []FileChange) error {
+	renamedFrom := make(map[string]string)
+	for _, ch := range changes {
+		if ch.IsRename() {
+			renamedFrom[ch.Path] = ch.OldPath
+		}
+	}
+
+	fuzzy := c.engine.GetConfigBool("fuzzy_edits")
+	for i := range changes {
+		if changes[i].Delete || changes[i].IsRename() {
+			continue
+		}
+		source := changes[i].Path
+		if old, ok := renamedFrom[source]; ok && !fileExists(source) {
+			source = old
+		}
+		if len(changes[i].Edits) > 0 {
+			original, err := c.git.GetFileContent(source)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", source, err)
+			}
+			updated, err := applyEdits(original, changes[i].Edits, fuzzy)
+			if err != nil {
+				return fmt.Errorf("edit %s: %w", changes[i].Path, err)
+			}
+			changes[i].Content = updated
+		}
+		changes[i].Content = workspace.DetectFormat(source).Apply(changes[i].Content)
+	}
 	return nil
 }
 
-// handleUndo reverts the last change
-func (c *Chat) handleUndo() error {
-	if !c.git.IsRepo() {
-		return fmt.Errorf("not a git repository")
+// renameFile moves a file, using git mv inside a repo so history follows it
+func (c *Chat) renameFile(oldPath, newPath string) error {
+	if !fileExists(oldPath) {
+		return fmt.Errorf("rename %s: file does not exist", oldPath)
+	}
+	if fileExists(newPath) {
+		return fmt.Errorf("rename %s: %s already exists", oldPath, newPath)
+	}
+
+	if err := ensureDir(newPath); err != nil {
+		return err
 	}
 
-	hash, err := c.git.Undo()
+	if c.git.IsRepo() {
+		if err := c.git.Move(oldPath, newPath); err == nil {
+			return nil
+		}
+		// Untracked files cannot be moved by git; fall back to a plain rename
+	}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return fmt.Errorf("rename %s: %w", oldPath, err)
+	}
+	return nil
+}
+
+// handleRestore restores a file from .goclode/backups
+func (c *Chat) handleRestore(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /restore <file> [version]")
+	}
+
+	policy, _ := c.engine.GetConfig("path_policy")
+	path, err := sanitizePath(c.git.WorkDir(), args[0], policy)
+	if err != nil {
+		return err
+	}
+
+	versions, err := c.backups.Versions(path)
 	if err != nil {
 		return err
 	}
+	if len(versions) == 0 {
+		return fmt.Errorf("no backups of %s", path)
+	}
+
+	choice := 1
+	if len(args) > 1 {
+		fmt.Sscanf(args[1], "%d", &choice)
+	} else {
+		fmt.Printf("\n\033[33mBackups of %s:\033[0m\n", path)
+		for i, v := range versions {
+			current := ""
+			if v.Session == c.session.Current() {
+				current = " \033[36m(this session)\033[0m"
+			}
+			fmt.Printf("  %d. %s  session %s%s\n", i+1, v.Timestamp.Format("2006-01-02 15:04:05"), v.Session[:min(8, len(v.Session))], current)
+		}
+
+		input, _ := c.ask("\n\033[36mRestore which version? [1] \033[0m")
+		if input = strings.TrimSpace(input); input != "" {
+			if _, err := fmt.Sscanf(input, "%d", &choice); err != nil {
+				fmt.Println("\033[33m❌ Cancelled\033[0m")
+				return nil
+			}
+		}
+	}
+
+	if choice < 1 || choice > len(versions) {
+		return fmt.Errorf("no backup #%d (have %d)", choice, len(versions))
+	}
+	backup := versions[choice-1]
+
+	// Back up the current content so the restore itself can be undone
+	contentBefore, _ := c.git.GetFileContent(path)
+	existed := fileExists(path)
+	c.backups.Begin()
+	c.session.BeginBatch()
+	if err := c.backups.Save(path); err != nil {
+		return fmt.Errorf("backup %s: %w", path, err)
+	}
+
+	if err := c.backups.Restore(backup); err != nil {
+		return err
+	}
+
+	contentAfter, _ := c.git.GetFileContent(path)
+	operation := "create"
+	if existed {
+		operation = "modify"
+	}
+	c.session.RecordFileChange(path, operation, contentBefore, contentAfter, "")
 
-	fmt.Printf("\033[32m✓ Reverted commit %s\033[0m\n", hash[:8])
+	fmt.Printf("\033[32m✓ Restored %s from %s\033[0m\n", path, backup.Timestamp.Format("2006-01-02 15:04:05"))
 	return nil
 }
 
@@ -519,28 +1647,45 @@ func (c *Chat) handleSwitch(providerID string) error {
 	return nil
 }
 
-// handleConfig handles config commands
+// handleConfig handles config commands: /config [files | <key> | unset
+// <key> | <key> <value>]
 func (c *Chat) handleConfig(args []string) error {
 	if len(args) == 0 {
-		// Show current config
-		fmt.Println("\n\033[33mConfiguration:\033[0m")
-		rows, err := c.engine.Query("SELECT key, value FROM config ORDER BY key")
+		// Show the effective config, with where each value comes from
+		entries, err := c.engine.ConfigEntries()
 		if err != nil {
 			return err
 		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var key, value string
-			rows.Scan(&key, &value)
+		fmt.Println("\n\033[33mConfiguration:\033[0m")
+		for _, e := range entries {
+			value := e.Value
 			// Truncate long values
 			if len(value) > 50 {
 				value = value[:47] + "..."
 			}
-			fmt.Printf("  %s = %s\n", key, value)
+			source := ""
+			if e.Source != core.ConfigSourceDefault {
+				source = "  \033[90m(" + e.Source + ")\033[0m"
+			}
+			fmt.Printf("  %s = %s%s\n", e.Key, value, source)
+		}
+		return nil
+	}
+
+	if args[0] == "files" && len(args) == 1 {
+		return c.showConfigFiles()
+	}
+	if args[0] == "unset" && len(args) == 2 {
+		if err := c.engine.UnsetConfig(args[1]); err != nil {
+			return err
 		}
+		value, source, _ := c.engine.GetConfigSource(args[1])
+		fmt.Printf("\033[32m✓ Unset %s; now %s (%s)\033[0m\n", args[1], value, source)
 		return nil
 	}
+	if len(args) == 1 {
+		return c.showConfigKey(args[0])
+	}
 
 	if len(args) >= 2 {
 		// Set config
@@ -557,33 +1702,136 @@ func (c *Chat) handleConfig(args []string) error {
 
 // toggleDebug toggles debug mode
 func (c *Chat) toggleDebug() error {
-	c.debugMode = !c.debugMode
+	c.SetDebug(!c.debugMode)
 	if c.debugMode {
-		c.modules.EnableDebug()
 		fmt.Println("\033[33m🔧 Debug mode enabled\033[0m")
 	} else {
-		c.modules.DisableDebug()
 		fmt.Println("\033[33m🔧 Debug mode disabled\033[0m")
 	}
 	return nil
 }
 
-// handleFeedback handles feedback
-func (c *Chat) handleFeedback(raw string) error {
-	rating := 0
-	if strings.Contains(raw, "👍") || strings.Contains(raw, "+1") || strings.Contains(raw, "good") || strings.Contains(raw, "merci") {
-		rating = 1
+// SetDebug turns debug mode on or off: events are traced and recorded, and
+// logging goes down to the debug level
+func (c *Chat) SetDebug(on bool) {
+	c.debugMode = on
+	if on {
+		c.modules.EnableDebug()
+	} else {
+		c.modules.DisableDebug()
+	}
+}
+
+// feedbackRating reads a thumbs up or down out of feedback text, 0 if neither
+func feedbackRating(text string) int {
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		switch strings.Trim(word, ".,;:!?") {
+		case "👍", "+1", "good", "merci", "bien":
+			return 1
+		case "👎", "-1", "bad", "mal":
+			return -1
+		}
+	}
+	return 0
+}
+
+// handleFeedback records 👍/👎, or /feedback <reason>, against the last reply
+func (c *Chat) handleFeedback(intent *Intent) error {
+	feedbackType := session.FeedbackRating
+	content := strings.TrimSpace(intent.Raw)
+	if intent.Command == "feedback" {
+		content = strings.TrimSpace(strings.TrimPrefix(content, "/feedback"))
+		if content == "" {
+			return fmt.Errorf("usage: /feedback <what was good or wrong about the last reply>")
+		}
+		feedbackType = session.FeedbackComment
+	}
+	rating := feedbackRating(content)
+
+	if _, err := c.session.FeedbackLastReply(rating, feedbackType, content); err != nil {
+		return err
+	}
+	if feedbackType == session.FeedbackComment && rating <= 0 {
+		c.learning.NoteStyle(content)
+	}
+
+	switch {
+	case rating > 0:
 		fmt.Println("\033[32m👍 Thanks for the positive feedback!\033[0m")
-	} else if strings.Contains(raw, "👎") || strings.Contains(raw, "-1") || strings.Contains(raw, "bad") {
-		rating = -1
+	case rating < 0:
 		fmt.Println("\033[33m👎 Thanks for the feedback. I'll try to improve.\033[0m")
+	default:
+		fmt.Println("\033[32m✓ Feedback recorded\033[0m")
 	}
+	return nil
+}
 
-	// Record feedback in learning_patterns for future use
-	c.engine.Exec(`
-		INSERT INTO learning_patterns (pattern_id, pattern_type, input_pattern, metadata)
-		VALUES (?, 'feedback', ?, ?)
-	`, fmt.Sprintf("fb_%d", time.Now().UnixNano()), raw, fmt.Sprintf(`{"rating":%d}`, rating))
+// intentTestPhrase returns the phrase of "/intent test <phrase>", from the
+// raw input so its spacing survives: a phrase in double quotes or
+// backquotes is unquoted as in Go, one in single quotes loses them, and
+// anything else is taken as typed
+func intentTestPhrase(raw string) string {
+	rest := strings.TrimSpace(raw)
+	for i := 0; i < 2; i++ { // The command, then "test"
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			return ""
+		}
+		rest = strings.TrimSpace(rest[end:])
+	}
+
+	switch {
+	case strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, "`"):
+		if phrase, err := strconv.Unquote(rest); err == nil {
+			return phrase
+		}
+	case len(rest) >= 2 && rest[0] == '\'' && rest[len(rest)-1] == '\'':
+		return rest[1 : len(rest)-1]
+	}
+	return rest
+}
+
+// handleIntentInspect handles /intent commands
+func (c *Chat) handleIntentInspect(intent *Intent) error {
+	if len(intent.Args) == 0 || intent.Args[0] != "test" {
+		return fmt.Errorf("usage: /intent test \"<phrase>\"")
+	}
+
+	phrase := intentTestPhrase(intent.Raw)
+	if phrase == "" {
+		return fmt.Errorf("usage: /intent test \"<phrase>\"")
+	}
+
+	result := c.parser.Parse(phrase)
+	if result == nil {
+		fmt.Println("\033[90mNo intent (empty input)\033[0m")
+		return nil
+	}
+
+	fmt.Println("\n\033[33mIntent test:\033[0m")
+	fmt.Printf("  Input:      %s\n", phrase)
+	fmt.Printf("  Intent:     %s\n", result.Type)
+	if result.Command != "" {
+		fmt.Printf("  Command:    /%s %s\n", result.Command, strings.Join(result.Args, " "))
+	}
+	if result.Action != "" {
+		fmt.Printf("  Action:     %s\n", result.Action)
+	}
+	if len(result.Files) > 0 {
+		fmt.Printf("  Files:      %s\n", strings.Join(result.Files, ", "))
+	}
+	if result.Provider != "" {
+		fmt.Printf("  Provider:   %s\n", result.Provider)
+	}
+	fmt.Printf("  Confidence: %.2f\n", result.Confidence)
+	switch {
+	case result.Pattern != "":
+		fmt.Printf("  Pattern:    %q\n", result.Pattern)
+	case result.Command != "":
+		fmt.Println("  Pattern:    (slash command)")
+	default:
+		fmt.Println("  Pattern:    (none, default code intent)")
+	}
 
 	return nil
 }
@@ -626,11 +1874,22 @@ func (c *Chat) showStatus() error {
 	fmt.Printf("  Messages: %d\n", stats["messages"])
 	fmt.Printf("  Tokens: %d in / %d out\n", stats["tokens_in"], stats["tokens_out"])
 	fmt.Printf("  Files modified: %d\n", stats["files_modified"])
-	fmt.Printf("  Commits: %d\n", stats["commits"])
+	fmt.Printf("  Commits: %d (+%d/−%d lines)\n", stats["commits"], stats["insertions"], stats["deletions"])
 
 	if c.registry.Current() != nil {
 		fmt.Printf("  Provider: %s\n", c.registry.Current().Name())
 	}
+	for _, f := range c.engine.EnvFiles() {
+		// Names only: the values are secrets
+		fmt.Printf("  Environment: %s set %s", f.Path, strings.Join(f.Set, ", "))
+		if len(f.Skipped) > 0 {
+			fmt.Printf(" \033[90m(already set: %s)\033[0m", strings.Join(f.Skipped, ", "))
+		}
+		if len(f.Ignored) > 0 {
+			fmt.Printf(" \033[33m(not loaded: %s)\033[0m", strings.Join(f.Ignored, ", "))
+		}
+		fmt.Println()
+	}
 
 	if c.git.IsRepo() {
 		branch, _ := c.git.CurrentBranch()
@@ -680,7 +1939,15 @@ func (c *Chat) printWelcome(sess *session.Session) {
 			fmt.Printf("\033[32m✓ Provider: %s\033[0m\n", p.Name())
 		} else {
 			fmt.Printf("\033[31m✗ Provider %s not configured\033[0m\n", p.Name())
-			fmt.Printf("  Set %s environment variable\n", "CEREBRAS_API_KEY")
+			keyEnv := "its API key"
+			if configs, err := c.registry.Configs(); err == nil {
+				for _, cfg := range configs {
+					if cfg.ID == p.ID() {
+						keyEnv = cfg.APIKeyEnv
+					}
+				}
+			}
+			fmt.Printf("  Run goclode setup, or set %s\n", keyEnv)
 		}
 	}
 
@@ -701,19 +1968,57 @@ func (c *Chat) printHelp() {
 	fmt.Print(`
 ` + "\033[33mCommands:\033[0m" + `
   /help       - Show this help
+  /init [focus] - Write GOCLODE.md, the project instructions sent with every request
+  /prompt [list | show | edit <name> | use <name> [name=value | @file[:lines] | text]] - Stored prompt templates
   /history    - Show message history
   /status     - Show session status
   /diff       - Show last changes
-  /undo       - Undo last change
+  /undo       - Undo last change (repeat to go further back)
+  /redo       - Redo the last undo
+  /undo last | file <path> - Restore the last batch or one file from snapshots
+  /redo last | file <path> - Re-apply a snapshot undo
+  /restore    - Restore a file from backup
+  /checkpoint [name | rm <name>] - List checkpoints, or save the whole working tree as one
+  /rollback <name> - Return the working tree to a checkpoint, commits since included
+  /agent <goal> - Plan the goal as steps and carry them out (Ctrl-C stops)
+  /agent [resume|stop] - Show, continue, or abandon the current plan
+  /test [cmd] - Run the tests and let the LLM fix failures (max_test_iterations)
+  /run <cmd>  - Run a command; if it fails, offer to have the LLM fix it
+  /sandbox [rm] - Show or remove the container commands run in (sandbox)
+  /todo [add <task> | start|done|undo|rm <n> | clear] - Show or edit the todo list
+  /resume [n|id] - List recent sessions, or continue one with its todo list
+  /resume-recovered [id] - Continue a session that crashed, retrying its last request
+  /resolve [file...] - Resolve merge/rebase conflicts with the LLM, hunk by hunk
+  /symbols <name|file> - Find where a symbol is declared, or outline a file
+  /index [status] - Embed new and changed files for retrieval (rag_top_k)
+  /push [remote] - Push the current branch (auto_push does it after commits)
+  /pr [title] - Push the branch and open a pull/merge request
+  /log [hash] - List this session's commits, or show one commit's diff
+  /task       - Start a new task (with amend_commits, ends amending)
   /provider   - List/switch providers
-  /config     - Show/set configuration
+  /usage [--since 30d] [--until date] [--by day,provider,model,session] [--json] - Tokens and cost over a time range
+  /audit [n]  - Show the audit log: secrets redacted from requests, prompt injections found in files and web results
+  /continue   - Resume a reply cut short (max_response_tokens, max_response_seconds)
+  /queue [run | clear] - Requests queued while the provider could not be reached; sent when it answers again
+  /drop       - While a reply streams, take back the last input typed ahead (type_ahead)
+  /budget [override | on] - Show spend against budget_session and budget_daily, or lift their stop
+  /config [files | <key> | unset <key> | <key> <value>] - Show the effective config and its sources, or set a value
+  /permissions [allow|deny|ask <tool> [pattern] | rm <id>] - Review or edit tool permission rules
   /debug      - Toggle debug mode
+  /trace [errors | <id>] - List debug mode traces, or show one's events and assertions
+  /analyze [focus] - Have the model diagnose the debug data and suggest follow-up tasks
+  /dump [n] [file.zip] - Bundle the last n messages, provider requests, config, and traces for a bug report
+  /intent test "<phrase>" - Show how a phrase would be parsed
+  /feedback <reason> - Say what was good or wrong about the last reply
+  /learn stats - Show learned intents, hit rates, preferences, and recent suggestions
+  /experiment [start <name> <prompt>... | stop | stats [name] | prompt <id> <text>] - A/B test system prompts
   /exit       - Exit GoClode
 
 ` + "\033[33mExamples:\033[0m" + `
   "Create a README.md file"
   "Add a fibonacci function in utils/math.go"
   "Fix the bug in main.go"
+  "Explain @internal/core/db.go"
   "Undo" or "Annule"
   "Switch to openrouter"
 `)
@@ -730,7 +2035,11 @@ func (c *Chat) shutdown() {
 		})
 
 		c.cancel()
+		c.closeLSP()
+		c.closeDebugServer()
 		c.rl.Close()
+		c.modules.Close()
+		c.learning.Close()
 		c.engine.Close()
 	})
 }
@@ -744,11 +2053,33 @@ func fileExists(path string) bool {
 
 func summarizeChanges(changes []FileChange) string {
 	if len(changes) == 1 {
+		if changes[0].Delete {
+			return fmt.Sprintf("delete %s", changes[0].Path)
+		}
+		if changes[0].IsRename() {
+			return fmt.Sprintf("rename %s to %s", changes[0].OldPath, changes[0].Path)
+		}
 		return fmt.Sprintf("update %s", changes[0].Path)
 	}
 	return fmt.Sprintf("update %d files", len(changes))
 }
 
+// amendMessage describes a task built up over several applies
+func amendMessage(summaries []string) string {
+	message := "GoClode: " + summaries[0]
+	if len(summaries) > 1 {
+		message += "\n\n- " + strings.Join(summaries[1:], "\n- ")
+	}
+	return message
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/git"
+)
+
+func TestExtractFileChanges_Delete(t *testing.T) {
+	c := &Chat{}
+	response := "Removing the old helper.\n\n**Delete: utils/old.go**\n\n**File: utils/new.go**\n```go\npackage utils\n```\n"
+
+	changes := c.extractFileChanges(response)
+	if len(changes) != 2 {
+		t.Fatalf("Changes count = %d, want 2 (%+v)", len(changes), changes)
+	}
+
+	var deleted *FileChange
+	for i := range changes {
+		if changes[i].Delete {
+			deleted = &changes[i]
+		}
+	}
+	if deleted == nil {
+		t.Fatal("Expected a delete change")
+	}
+	if deleted.Path != "utils/old.go" {
+		t.Errorf("Delete path = %q, want utils/old.go", deleted.Path)
+	}
+}
+
+func TestExtractFileChanges_Rename(t *testing.T) {
+	c := &Chat{}
+	response := "**Rename: pkg/old.go -> pkg/new.go**\n\n**File: pkg/new.go**\n```go\npackage pkg\n```\n"
+
+	changes := c.extractFileChanges(response)
+	if len(changes) != 2 {
+		t.Fatalf("Changes count = %d, want 2 (%+v)", len(changes), changes)
+	}
+
+	if !changes[0].IsRename() || changes[0].OldPath != "pkg/old.go" || changes[0].Path != "pkg/new.go" {
+		t.Errorf("Unexpected rename change: %+v", changes[0])
+	}
+	if changes[1].IsRename() || changes[1].Path != "pkg/new.go" {
+		t.Errorf("Unexpected content change: %+v", changes[1])
+	}
+}
+
+func TestResolveEdits_Rename(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	os.MkdirAll(filepath.Join(dir, "pkg"), 0o755)
+	os.WriteFile(filepath.Join(dir, "pkg", "old.go"), []byte("package pkg\r\n\r\nvar x = 1\r\n"), 0o644)
+
+	engine := setupTestDB(t)
+	defer engine.Close()
+	c := &Chat{engine: engine, git: git.NewManager(dir)}
+
+	response := "**Rename: pkg/old.go -> pkg/new.go**\n\n**File: pkg/new.go**\n```go\n<<<<<<< SEARCH\nvar x = 1\n=======\nvar x = 2\n>>>>>>> REPLACE\n```\n"
+	changes := c.extractFileChanges(response)
+	if len(changes) != 2 {
+		t.Fatalf("Changes count = %d, want 2 (%+v)", len(changes), changes)
+	}
+	if err := c.resolveEdits(changes); err != nil {
+		t.Fatalf("resolveEdits failed: %v", err)
+	}
+	if want := "package pkg\r\n\r\nvar x = 2\r\n"; changes[1].Content != want {
+		t.Errorf("Content = %q, want %q (edited from the old path, in its format)", changes[1].Content, want)
+	}
+}
+
+func TestExtractFileChanges_FenceInfo(t *testing.T) {
+	c := &Chat{}
+
+	tests := []struct {
+		name     string
+		response string
+		wantPath string
+		wantBody string
+	}{
+		{"title attribute", "```go title=main.go\npackage main\n```", "main.go", "package main"},
+		{"path attribute", "```path=cmd/x/main.go\npackage main\n```", "cmd/x/main.go", "package main"},
+		{"quoted title", "```python title=\"app/run.py\"\nprint(1)\n```", "app/run.py", "print(1)"},
+		{"lang colon path", "```go:pkg/a.go\npackage pkg\n```", "pkg/a.go", "package pkg"},
+		{"first line comment", "```go\n// file: util/x.go\npackage util\n```", "util/x.go", "package util"},
+		{"hash comment", "```python\n# filename: tools/gen.py\nprint(2)\n```", "tools/gen.py", "print(2)"},
+		{"info beats prose", "See `other.go`:\n```go title=real.go\npackage x\n```", "real.go", "package x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := c.extractFileChanges(tt.response)
+			if len(changes) != 1 {
+				t.Fatalf("Changes count = %d, want 1 (%+v)", len(changes), changes)
+			}
+			if changes[0].Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", changes[0].Path, tt.wantPath)
+			}
+			if changes[0].Content != tt.wantBody {
+				t.Errorf("Content = %q, want %q", changes[0].Content, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestExtractFileChanges_UntaggedBlockNeedsExplicitPath(t *testing.T) {
+	c := &Chat{}
+	response := "Run `main.go` with:\n```\ngo run main.go\n```\n"
+
+	if changes := c.extractFileChanges(response); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %+v", changes)
+	}
+}
+
+func TestParseRenameDiff(t *testing.T) {
+	from, to := parseRenameDiff("rename from old/a.go\nrename to new/a.go\n")
+	if from != "old/a.go" || to != "new/a.go" {
+		t.Errorf("parseRenameDiff() = %q, %q", from, to)
+	}
+
+	if from, to := parseRenameDiff(""); from != "" || to != "" {
+		t.Errorf("parseRenameDiff(\"\") = %q, %q", from, to)
+	}
+}
+
+func TestAmendMessage(t *testing.T) {
+	tests := []struct {
+		summaries []string
+		want      string
+	}{
+		{[]string{"update a.go"}, "GoClode: update a.go"},
+		{[]string{"update a.go", "update 2 files", "delete b.go"},
+			"GoClode: update a.go\n\n- update 2 files\n- delete b.go"},
+	}
+
+	for _, tt := range tests {
+		if got := amendMessage(tt.summaries); got != tt.want {
+			t.Errorf("amendMessage(%q) = %q, want %q", tt.summaries, got, tt.want)
+		}
+	}
+}
+
+func TestFeedbackRating(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"👍", 1},
+		{"+1", 1},
+		{"Good!", 1},
+		{"-1 edited the wrong file", -1},
+		{"bad, the test never runs", -1},
+		{"normal output, but too long", 0},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		if got := feedbackRating(tt.text); got != tt.want {
+			t.Errorf("feedbackRating(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package ui - Checkpoints: /checkpoint saves the whole working tree and
+// /rollback puts it back in one step, whatever was committed in between
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// checkpointNamePattern is what a checkpoint may be called: a git ref
+// component and a file name
+var checkpointNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// beforeRollback is the checkpoint taken before each rollback, so the
+// rollback itself can be undone
+const beforeRollback = "before-rollback"
+
+// checkpointEntry is a checkpoint of either kind, for listing
+type checkpointEntry struct {
+	Name    string
+	Created time.Time
+}
+
+// validCheckpointName rejects names git or the file system would not take
+func validCheckpointName(name string) error {
+	if !checkpointNamePattern.MatchString(name) || strings.Contains(name, "..") || strings.HasSuffix(name, ".lock") {
+		return fmt.Errorf("checkpoint names use letters, digits, '.', '_' and '-': %q", name)
+	}
+	return nil
+}
+
+// handleCheckpoint lists, saves, or removes checkpoints:
+// /checkpoint [name | rm <name>]
+func (c *Chat) handleCheckpoint(args []string) error {
+	switch {
+	case len(args) == 0:
+		return c.listCheckpoints()
+	case len(args) == 2 && args[0] == "rm":
+		if err := c.deleteCheckpoint(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("\033[32m✓ Removed checkpoint %s\033[0m\n", args[1])
+		return nil
+	case len(args) == 1:
+		if err := c.saveCheckpoint(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("\033[90m   /rollback %s returns the working tree to this point\033[0m\n", args[0])
+		return nil
+	}
+	return fmt.Errorf("usage: /checkpoint [name | rm <name>]")
+}
+
+// saveCheckpoint saves the working tree as name: a git commit kept under
+// refs/goclode/checkpoints in a repository, a tarball elsewhere
+func (c *Chat) saveCheckpoint(name string) error {
+	if err := validCheckpointName(name); err != nil {
+		return err
+	}
+	if c.git.IsRepo() {
+		cp, err := c.git.CreateCheckpoint(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\033[32m📍 Checkpoint %s saved (%s)\033[0m\n", name, cp.Hash[:min(len(cp.Hash), 8)])
+		return nil
+	}
+	cp, err := workspace.CreateCheckpoint(c.git.WorkDir(), name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\033[32m📍 Checkpoint %s saved (%d files)\033[0m\n", name, cp.Files)
+	return nil
+}
+
+// deleteCheckpoint removes a checkpoint of either kind
+func (c *Chat) deleteCheckpoint(name string) error {
+	if c.git.IsRepo() {
+		return c.git.DeleteCheckpoint(name)
+	}
+	return workspace.DeleteCheckpoint(c.git.WorkDir(), name)
+}
+
+// checkpoints lists the checkpoints, newest first
+func (c *Chat) checkpoints() ([]checkpointEntry, error) {
+	entries := make([]checkpointEntry, 0)
+	if c.git.IsRepo() {
+		checkpoints, err := c.git.Checkpoints()
+		if err != nil {
+			return nil, err
+		}
+		for _, cp := range checkpoints {
+			entries = append(entries, checkpointEntry{Name: cp.Name, Created: cp.Created})
+		}
+		return entries, nil
+	}
+	checkpoints, err := workspace.Checkpoints(c.git.WorkDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, cp := range checkpoints {
+		entries = append(entries, checkpointEntry{Name: cp.Name, Created: cp.Created})
+	}
+	return entries, nil
+}
+
+// listCheckpoints prints the checkpoints
+func (c *Chat) listCheckpoints() error {
+	entries, err := c.checkpoints()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("\033[90mNo checkpoints. Save one with /checkpoint <name>\033[0m")
+		return nil
+	}
+	fmt.Println("\n\033[33mCheckpoints:\033[0m")
+	for _, e := range entries {
+		fmt.Printf("  %s  %s\n", e.Created.Format("2006-01-02 15:04:05"), e.Name)
+	}
+	return nil
+}
+
+// handleRollback returns the working tree to a checkpoint, saving the
+// current one first as before-rollback: /rollback <name>
+func (c *Chat) handleRollback(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: /rollback <name> (/checkpoint lists them)")
+	}
+	name := args[0]
+	entries, err := c.checkpoints()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, e := range entries {
+		found = found || e.Name == name
+	}
+	if !found {
+		return fmt.Errorf("no checkpoint %s (/checkpoint lists them)", name)
+	}
+
+	confirm, _ := c.ask(fmt.Sprintf("\033[36mRoll back the working tree to %s? Files changed since are overwritten and new ones removed [y/N] \033[0m", name))
+	if confirm = strings.ToLower(confirm); confirm != "y" && confirm != "yes" {
+		fmt.Println("\033[33m❌ Cancelled\033[0m")
+		return nil
+	}
+
+	// Restored from itself, before-rollback cannot be replaced first
+	if name != beforeRollback {
+		c.deleteCheckpoint(beforeRollback) // Only the latest is kept
+		if err := c.saveCheckpoint(beforeRollback); err != nil {
+			return fmt.Errorf("save the current state first: %w", err)
+		}
+	}
+
+	if c.git.IsRepo() {
+		cp, err := c.git.Checkpoint(name)
+		if err != nil {
+			return err
+		}
+		err = c.git.RestoreCheckpoint(cp)
+	} else {
+		err = workspace.RestoreCheckpoint(c.git.WorkDir(), name)
+	}
+	if err != nil {
+		return err
+	}
+
+	// A rollback is not an outside edit to warn about
+	c.seenMu.Lock()
+	c.seen = nil
+	c.seenMu.Unlock()
+	if c.index != nil {
+		c.index.Refresh()
+		c.goSafe(c.syncSymbols)
+	}
+
+	fmt.Printf("\033[32m⏪ Rolled back to %s\033[0m\n", name)
+	if name != beforeRollback {
+		fmt.Printf("\033[90m   /rollback %s undoes the rollback\033[0m\n", beforeRollback)
+	}
+	return nil
+}
+
+// agentCheckpoint saves a checkpoint before /agent runs a plan, unless
+// agent_checkpoint is off
+func (c *Chat) agentCheckpoint() {
+	if !c.engine.GetConfigBool("agent_checkpoint") {
+		return
+	}
+	name := "agent-" + time.Now().Format("20060102-150405")
+	if err := c.saveCheckpoint(name); err != nil {
+		fmt.Printf("\033[33m⚠️  No checkpoint before the agent run: %v\033[0m\n", err)
+		return
+	}
+	fmt.Printf("\033[90m   /rollback %s undoes the whole run\033[0m\n", name)
+}
@@ -0,0 +1,60 @@
+// Package ui - /config views of the config files layered over the database
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+// showConfigKey shows a setting's effective value, and the value of every
+// layer that sets it, lowest first
+func (c *Chat) showConfigKey(key string) error {
+	value, source, err := c.engine.GetConfigSource(key)
+	if err != nil {
+		return err
+	}
+	if source == "" {
+		return fmt.Errorf("no setting %s", key)
+	}
+	fmt.Printf("  %s = %s  \033[90m(%s)\033[0m\n", key, value, source)
+
+	for _, l := range c.engine.ConfigLayers() {
+		if v, ok := l.Values[key]; ok {
+			mark := "  "
+			if l.Name == source {
+				mark = "→ "
+			}
+			fmt.Printf("  \033[90m%s%-8s %s = %s\033[0m\n", mark, l.Name, l.Path, v)
+		}
+		for _, ignored := range l.Ignored {
+			if ignored == key {
+				fmt.Printf("  \033[33m  %-8s %s sets it, ignored: only %s or /config may\033[0m\n", l.Name, l.Path, core.GlobalConfigPath())
+			}
+		}
+	}
+	if source == core.ConfigSourceDatabase {
+		fmt.Printf("\033[90m  Set with /config; /config unset %s goes back to the files\033[0m\n", key)
+	}
+	return nil
+}
+
+// showConfigFiles lists the config files that apply here, lowest
+// precedence first
+func (c *Chat) showConfigFiles() error {
+	fmt.Println("\n\033[33mConfig files\033[0m \033[90m(each over the one before; /config values over all)\033[0m")
+	for _, l := range c.engine.ConfigLayers() {
+		status := "\033[90mnot found\033[0m"
+		if l.Err != nil {
+			status = "\033[33m⚠️  " + l.Err.Error() + "\033[0m"
+		} else if l.Values != nil {
+			status = fmt.Sprintf("%d setting(s)", len(l.Values))
+			if len(l.Ignored) > 0 {
+				status += fmt.Sprintf(" \033[33m(ignored: %s)\033[0m", strings.Join(l.Ignored, ", "))
+			}
+		}
+		fmt.Printf("  %-8s %s  %s\n", l.Name, l.Path, status)
+	}
+	return nil
+}
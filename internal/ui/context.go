@@ -0,0 +1,171 @@
+// Package ui - File context attached to prompts via @file mentions
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// maxFileContextBytes caps how much of a single @file is sent to the LLM
+const maxFileContextBytes = 100 * 1024
+
+// maxBlameLines caps the line range of an @file:start-end blame mention
+const maxBlameLines = 200
+
+var (
+	fileMentionPattern  = regexp.MustCompile(`(?:^|\s)@([a-zA-Z0-9_\-./]+[a-zA-Z0-9_\-/])`)
+	blameMentionPattern = regexp.MustCompile(`(?:^|\s)@([a-zA-Z0-9_\-./]+[a-zA-Z0-9_\-/]):(\d+)(?:-(\d+))?`)
+)
+
+// workspaceGuard builds the read/write guard from .goclodeignore and config
+func (c *Chat) workspaceGuard() *workspace.Guard {
+	raw, _ := c.engine.GetConfig("protected_paths")
+	return workspace.NewGuard(c.git.WorkDir(), workspace.ParsePatternList(raw))
+}
+
+// readablePath checks a path the LLM asked to read: inside the workspace
+// (whatever path_policy allows for writes), not ignored or protected, and
+// not escaping through a symlink
+func (c *Chat) readablePath(requested string) (string, error) {
+	root := c.git.WorkDir()
+	path, err := sanitizePath(root, requested, PathPolicyReject)
+	if err != nil {
+		return "", err
+	}
+	if err := c.workspaceGuard().Check(path); err != nil {
+		return "", err
+	}
+	if risk := symlinkRisk(root, path, true); risk != "" {
+		return "", fmt.Errorf("%s", risk)
+	}
+	return filepath.Join(root, path), nil
+}
+
+// maxSearchResults caps the matches the search tool returns
+const maxSearchResults = 100
+
+// indexedFiles returns the workspace files the search tool looks through
+func (c *Chat) indexedFiles() []string {
+	if c.index == nil {
+		return nil
+	}
+	return c.index.Files()
+}
+
+// searchFilter tells the search tool which files it may look inside
+func (c *Chat) searchFilter() func(path string) bool {
+	guard := c.workspaceGuard()
+	return func(path string) bool {
+		return guard.Check(path) == nil
+	}
+}
+
+// fileContext returns the contents of files mentioned as @path in the input,
+// formatted for inclusion in the user message.
+func (c *Chat) fileContext(input string) string {
+	matches := fileMentionPattern.FindAllStringSubmatch(input, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+
+	guard := c.workspaceGuard()
+	policy, _ := c.engine.GetConfig("path_policy")
+	seen := make(map[string]bool)
+
+	var sb strings.Builder
+	for _, match := range matches {
+		path, err := sanitizePath(c.git.WorkDir(), match[1], policy)
+		if err != nil || seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		if err := guard.Check(path); err != nil {
+			fmt.Printf("\033[33m⚠️  Not including @%s: %v\033[0m\n", match[1], err)
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		if workspace.IsBinary(data) || workspace.IsGenerated(data) {
+			fmt.Printf("\033[33m⚠️  Not including @%s: binary or generated file\033[0m\n", match[1])
+			continue
+		}
+
+		content := string(data)
+		truncated := ""
+		if len(content) > maxFileContextBytes {
+			content = content[:maxFileContextBytes]
+			truncated = "\n... (truncated)"
+		}
+
+		content = c.screenContent(path, content)
+		fmt.Fprintf(&sb, "\n\n**File: %s**\n```\n%s%s\n```", path, content, truncated)
+		c.sawFile(path)
+		fmt.Printf("\033[90m📎 Attached %s\033[0m\n", path)
+	}
+
+	sb.WriteString(c.blameContext(input))
+	return sb.String()
+}
+
+// blameContext attributes lines mentioned as @path:line or @path:start-end to
+// the commits that last changed them, so "why is this line like this?" can
+// be answered from history
+func (c *Chat) blameContext(input string) string {
+	matches := blameMentionPattern.FindAllStringSubmatch(input, -1)
+	if len(matches) == 0 || !c.git.IsRepo() {
+		return ""
+	}
+
+	guard := c.workspaceGuard()
+	policy, _ := c.engine.GetConfig("path_policy")
+
+	var sb strings.Builder
+	for _, match := range matches {
+		path, err := sanitizePath(c.git.WorkDir(), match[1], policy)
+		if err != nil || guard.Check(path) != nil {
+			continue
+		}
+
+		start, _ := strconv.Atoi(match[2])
+		end := start
+		if match[3] != "" {
+			end, _ = strconv.Atoi(match[3])
+		}
+		if start < 1 || end < start {
+			continue
+		}
+		end = min(end, start+maxBlameLines-1)
+
+		lines, err := c.git.Blame(path, start, end)
+		if err != nil {
+			fmt.Printf("\033[33m⚠️  No blame for @%s:%s: %v\033[0m\n", match[1], match[2], err)
+			continue
+		}
+
+		fmt.Fprintf(&sb, "\n\n**Blame: %s lines %d-%d**\n```\n", path, start, end)
+		for _, l := range lines {
+			fmt.Fprintf(&sb, "%4d %s %s %s %q | %s\n", l.Line, shortHash(l.Hash), l.Author,
+				l.Time.Format("2006-01-02"), l.Summary, l.Content)
+		}
+		sb.WriteString("```")
+		fmt.Printf("\033[90m🔎 Attached blame for %s:%d-%d\033[0m\n", path, start, end)
+	}
+
+	return sb.String()
+}
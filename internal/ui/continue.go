@@ -0,0 +1,53 @@
+// Package ui - /continue: finishing a reply cut short by
+// max_response_tokens, max_response_seconds, or the model's own limit
+package ui
+
+import "fmt"
+
+// continuePrompt asks the model to pick up where its reply stopped
+const continuePrompt = "Your previous reply was cut short. Continue it exactly where it stopped, without repeating or summarizing what you already wrote."
+
+// handleContinue resumes the last reply if it was cut short, appends the
+// rest to it, and applies the file changes of the whole reply
+func (c *Chat) handleContinue() error {
+	reply, _, err := c.session.TruncatedReply()
+	if err != nil {
+		return err
+	}
+	if reply == nil {
+		return fmt.Errorf("the last reply was not cut short; nothing to continue")
+	}
+	provider := c.registry.Current()
+	if provider == nil {
+		return fmt.Errorf("no provider available")
+	}
+
+	// The context ends with the reply so far
+	messages, err := c.buildMessages(&Intent{Type: IntentContinue, Raw: continuePrompt})
+	if err != nil {
+		return err
+	}
+	resp, err := c.streamResponse(provider, messages, nil)
+	if err != nil {
+		return err
+	}
+	if err := c.session.ExtendReply(reply.ID, resp); err != nil {
+		return err
+	}
+	full := reply.Content + resp.Content
+	c.lastReply = full
+	if resp.Truncated() {
+		return nil
+	}
+
+	if changes := c.extractFileChanges(full); len(changes) > 0 {
+		if _, err := c.applyChanges(changes); err != nil {
+			fmt.Printf("\033[33m⚠️  Could not apply changes: %v\033[0m\n", err)
+		}
+	}
+	if failure := c.pendingFix; failure != "" {
+		c.pendingFix = ""
+		return c.runFixRound(failure)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+// scriptedProvider streams its chunks, then hangs until the request is
+// cancelled if hang is set
+type scriptedProvider struct {
+	chunks []providers.StreamChunk
+	hang   bool
+	req    *providers.Request
+}
+
+func (p *scriptedProvider) ID() string        { return "scripted" }
+func (p *scriptedProvider) Name() string      { return "Scripted" }
+func (p *scriptedProvider) Models() []string  { return nil }
+func (p *scriptedProvider) IsAvailable() bool { return true }
+
+func (p *scriptedProvider) Generate(ctx context.Context, req *providers.Request) (*providers.Response, error) {
+	return nil, nil
+}
+
+func (p *scriptedProvider) Stream(ctx context.Context, req *providers.Request) (<-chan providers.StreamChunk, error) {
+	p.req = req
+	ch := make(chan providers.StreamChunk, len(p.chunks)+1)
+	go func() {
+		defer close(ch)
+		for _, chunk := range p.chunks {
+			ch <- chunk
+		}
+		if p.hang {
+			<-ctx.Done()
+			ch <- providers.StreamChunk{Error: ctx.Err(), Done: true}
+		}
+	}()
+	return ch, nil
+}
+
+func TestStreamResponseLimits(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+	sm := session.NewManager(engine)
+	if _, err := sm.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	c := &Chat{engine: engine, session: sm, ctx: context.Background(), onDelta: func(d string) { out.WriteString(d) }}
+
+	engine.SetConfig("max_response_tokens", "50")
+	length := &scriptedProvider{chunks: []providers.StreamChunk{
+		{Delta: "partial"},
+		{Done: true, FinishReason: providers.FinishLength, ToolCalls: []providers.ToolCall{{ID: "1"}}},
+	}}
+	resp, err := c.streamResponse(length, nil, nil)
+	if err != nil || !resp.Truncated() || resp.Content != "partial" {
+		t.Fatalf("Expected a reply cut at the length limit, got %+v, %v", resp, err)
+	}
+	if length.req.MaxTokens != 50 || len(resp.ToolCalls) != 0 {
+		t.Errorf("Expected max_tokens 50 sent and partial tool calls dropped, got %d, %+v", length.req.MaxTokens, resp.ToolCalls)
+	}
+
+	engine.SetConfig("max_response_seconds", "1")
+	slow := &scriptedProvider{chunks: []providers.StreamChunk{{Delta: "slow start"}}, hang: true}
+	resp, err = c.streamResponse(slow, nil, nil)
+	if err != nil || resp.FinishReason != providers.FinishTimeout || resp.Content != "slow start" {
+		t.Fatalf("Expected a reply stopped by the time limit, got %+v, %v", resp, err)
+	}
+
+	done := &scriptedProvider{chunks: []providers.StreamChunk{{Delta: "all"}, {Done: true, FinishReason: "stop"}}}
+	if resp, err := c.streamResponse(done, nil, nil); err != nil || resp.Truncated() {
+		t.Errorf("Expected a complete reply, got %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,70 @@
+// Package ui - The debug server's view of the chat: session state for
+// --debug-addr
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/debugserver"
+)
+
+// ServeDebug starts the debug server on a localhost address
+func (c *Chat) ServeDebug(addr string) error {
+	srv := debugserver.New(c.engine.DB(), c.modules, c.debugState)
+	listening, err := srv.Start(addr)
+	if err != nil {
+		return err
+	}
+	c.debugServer = srv
+	fmt.Printf("\033[90m🔧 Debug server on http://%s/\033[0m\n", listening)
+	return nil
+}
+
+// closeDebugServer stops the debug server, if started
+func (c *Chat) closeDebugServer() {
+	if c.debugServer != nil {
+		c.debugServer.Close()
+	}
+}
+
+// setInFlight records the input being handled, "" once done
+func (c *Chat) setInFlight(input string) {
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+	c.inFlight, c.inFlightAt = input, time.Now()
+}
+
+// currentInput returns the input being handled and since when
+func (c *Chat) currentInput() (string, time.Time) {
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+	return c.inFlight, c.inFlightAt
+}
+
+// debugState describes the session for the debug server
+func (c *Chat) debugState() map[string]interface{} {
+	state := map[string]interface{}{
+		"session_id": c.session.Current(),
+	}
+	if p := c.registry.Current(); p != nil {
+		state["provider"] = p.ID()
+	}
+	if experiment, variant := c.session.Variant(); experiment != "" {
+		state["experiment"], state["variant"] = experiment, variant
+	}
+	if input, since := c.currentInput(); input != "" {
+		state["in_flight"] = map[string]interface{}{
+			"input":      input,
+			"started_at": since,
+			"running_ms": time.Since(since).Milliseconds(),
+		}
+	}
+	c.agentMu.Lock()
+	state["agent_running"] = c.agentStop != nil
+	c.agentMu.Unlock()
+	if tasks, err := c.session.Tasks(); err == nil {
+		state["tasks"] = tasks
+	}
+	return state
+}
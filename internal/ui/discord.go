@@ -0,0 +1,199 @@
+// Package ui - Discord bot mode (goclode discord)
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/discord"
+)
+
+// discordChannel posts in a Discord channel or thread, replying to a
+// message
+type discordChannel struct {
+	ctx     context.Context
+	client  *discord.Client
+	channel string
+	replyTo string
+}
+
+func (d discordChannel) Post(text string) (string, error) {
+	var id string
+	for _, part := range splitMessage(text, discord.MaxMessageLength) {
+		var err error
+		if id, err = d.client.CreateMessage(d.ctx, d.channel, d.replyTo, part); err != nil {
+			return id, err
+		}
+	}
+	return id, nil
+}
+
+func (d discordChannel) Update(id, text string) error {
+	return d.client.EditMessage(d.ctx, d.channel, id, text)
+}
+
+func (d discordChannel) Finish(id, text string) error {
+	parts := splitMessage(text, discord.MaxMessageLength)
+	if err := d.client.EditMessage(d.ctx, d.channel, id, parts[0]); err != nil {
+		return err
+	}
+	for _, part := range parts[1:] {
+		if _, err := d.client.CreateMessage(d.ctx, d.channel, "", part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discordResponse answers a slash command: the first post fills the
+// deferred response, later ones follow up
+type discordResponse struct {
+	ctx       context.Context
+	client    *discord.Client
+	token     string
+	responded bool
+}
+
+// originalResponse is the ID of an interaction's own response
+const originalResponse = "@original"
+
+func (d *discordResponse) Post(text string) (string, error) {
+	var id string
+	for _, part := range splitMessage(text, discord.MaxMessageLength) {
+		var err error
+		if !d.responded {
+			d.responded = true
+			id, err = originalResponse, d.client.EditResponse(d.ctx, d.token, part)
+		} else {
+			id, err = d.client.FollowUp(d.ctx, d.token, part)
+		}
+		if err != nil {
+			return id, err
+		}
+	}
+	return id, nil
+}
+
+func (d *discordResponse) Update(id, text string) error {
+	if id == originalResponse {
+		return d.client.EditResponse(d.ctx, d.token, text)
+	}
+	return d.client.EditFollowUp(d.ctx, d.token, id, text)
+}
+
+func (d *discordResponse) Finish(id, text string) error {
+	parts := splitMessage(text, discord.MaxMessageLength)
+	if err := d.Update(id, parts[0]); err != nil {
+		return err
+	}
+	for _, part := range parts[1:] {
+		if _, err := d.client.FollowUp(d.ctx, d.token, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discordCommands are the bot commands as slash commands
+func discordCommands() []discord.Command {
+	names := make([]string, 0, len(botCommands))
+	for name := range botCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	commands := make([]discord.Command, 0, len(names))
+	for _, name := range names {
+		cmd := discord.Command{Name: name, Description: botCommands[name]}
+		if name == "pr" {
+			cmd.Options = []discord.CommandOption{{Type: 3, Name: "title", Description: "Pull request title"}}
+		}
+		commands = append(commands, cmd)
+	}
+	return commands
+}
+
+// ServeDiscord answers Discord until interrupted. The bot replies when
+// mentioned, and to every message of a thread it replied in; each channel
+// or thread is a session. Only the users and servers of
+// discord_allowed_users and discord_allowed_guilds are answered, and
+// direct messages only with discord_allow_dms. The bot commands are also
+// slash commands: /status, /diff, /undo...
+func (c *Chat) ServeDiscord() error {
+	token := c.configSecret("discord_token")
+	if token == "" {
+		return fmt.Errorf("set discord_token (or DISCORD_TOKEN)")
+	}
+	access, err := c.loadBotAccess("discord_allowed_users", "discord_allowed_guilds")
+	if err != nil {
+		return err
+	}
+	allowDMs := c.engine.GetConfigBool("discord_allow_dms")
+	allowed := func(user, guild string) bool {
+		if guild == "" {
+			return allowDMs && access.allowsDirect(user)
+		}
+		return access.allows(user, guild)
+	}
+	client := discord.New(token)
+
+	ctx, stop := signal.NotifyContext(c.ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	defer c.recoverCrash()
+	if _, err := c.startSession(); err != nil {
+		return err
+	}
+	bots := newBotServer(c, "discord")
+	log := core.Logger("discord")
+
+	fmt.Printf("\033[32m💬 Connecting to Discord (Ctrl+C to stop)\033[0m\n")
+	err = client.Listen(ctx, discord.Handler{
+		Ready: func() {
+			fmt.Printf("\033[32m✓ Listening on Discord as %s\033[0m\n", client.UserID)
+			if err := client.RegisterCommands(ctx, discordCommands()); err != nil {
+				log.Warn("Could not register slash commands", "error", err)
+			}
+		},
+		Message: func(m discord.Message) {
+			direct := m.GuildID == ""
+			if !m.MentionsUser(client.UserID) && !direct && !(m.InThread && bots.known(m.ChannelID)) {
+				return
+			}
+			if !allowed(m.Author.ID, m.GuildID) {
+				log.Info("Ignored a message from a user not allowed", "user", m.Author.ID, "guild", m.GuildID)
+				return
+			}
+			text := m.Content
+			for _, mention := range []string{"<@" + client.UserID + ">", "<@!" + client.UserID + ">"} {
+				text = strings.ReplaceAll(text, mention, "")
+			}
+			text = strings.TrimSpace(text)
+			fmt.Printf("\033[90m[discord %s] %s\033[0m\n", m.ChannelID, shorten(text, 80))
+			c.setInFlight(text)
+			bots.handle(m.ChannelID, text, discordChannel{ctx: ctx, client: client, channel: m.ChannelID, replyTo: m.ID})
+			c.setInFlight("")
+		},
+		Command: func(in discord.Interaction) {
+			if !allowed(in.UserID, in.GuildID) {
+				log.Info("Refused a command from a user not allowed", "user", in.UserID, "guild", in.GuildID, "command", in.Name)
+				(&discordResponse{ctx: ctx, client: client, token: in.Token}).Post("⚠️ You are not allowed to use this bot.")
+				return
+			}
+			text := strings.TrimSpace("/" + in.Name + " " + in.Options["title"])
+			fmt.Printf("\033[90m[discord %s] %s\033[0m\n", in.ChannelID, text)
+			c.setInFlight(text)
+			bots.handle(in.ChannelID, text, &discordResponse{ctx: ctx, client: client, token: in.Token})
+			c.setInFlight("")
+		},
+	})
+
+	c.shutdown()
+	return err
+}
@@ -0,0 +1,224 @@
+// Package ui - /dump: a repro bundle of the session for bug reports
+// against GoClode itself
+package ui
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/modules"
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/redact"
+)
+
+const (
+	dumpDir        = ".goclode/dumps"
+	dumpMessages   = 50 // Messages bundled by default
+	dumpTraces     = 20 // Latest traces bundled, with their events
+	redactedSecret = "[REDACTED]"
+)
+
+// secretConfigKeys are the config keys holding credentials, whose values
+// are not bundled
+var secretConfigKeys = map[string]bool{
+	"brave_api_key": true, "github_token": true, "gitlab_token": true, "bitbucket_token": true,
+	"slack_app_token": true, "slack_bot_token": true, "discord_token": true,
+	"github_webhook_secret": true, "sentry_webhook_secret": true, "report_slack_webhook": true,
+	"smtp_username": true, "smtp_password": true,
+}
+
+// secretConfigKey matches other keys that look like credentials, such as
+// ones set by hand
+var secretConfigKey = regexp.MustCompile(`(?i)(key|secret|token|password|webhook)`)
+
+// redactSecrets replaces credentials in text: known secret values, then
+// anything that looks like one. It never touches quotes or backslashes, so
+// JSON stays valid.
+func redactSecrets(text string, known []string) string {
+	for _, secret := range known {
+		if len(secret) >= redact.MinSecretLength {
+			text = strings.ReplaceAll(text, secret, redactedSecret)
+		}
+	}
+	for _, p := range redact.Patterns {
+		text = p.Re.ReplaceAllStringFunc(text, func(match string) string {
+			if m := p.Re.FindStringSubmatch(match); len(m) > 1 {
+				return m[1] + redactedSecret
+			}
+			return redactedSecret
+		})
+	}
+	return text
+}
+
+// knownSecrets returns the values of the providers' API key variables and
+// of the variables the environment files set
+func (c *Chat) knownSecrets() []string {
+	return c.secretValues(nil)
+}
+
+// secretValues returns the values of the providers' API key variables and
+// of the variables the environment files set whose names match, or all of
+// them if match is nil
+func (c *Chat) secretValues(match func(name string) bool) []string {
+	rows, err := c.engine.Query(`SELECT DISTINCT api_key_env FROM providers WHERE api_key_env IS NOT NULL AND api_key_env != ''`)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	secrets := make([]string, 0)
+	for rows.Next() {
+		var env string
+		if rows.Scan(&env) == nil {
+			if v := os.Getenv(env); v != "" {
+				secrets = append(secrets, v)
+			}
+		}
+	}
+	for _, f := range c.engine.EnvFiles() {
+		for _, key := range f.Set {
+			if match != nil && !match(key) {
+				continue
+			}
+			if v := os.Getenv(key); v != "" {
+				secrets = append(secrets, v)
+			}
+		}
+	}
+	return secrets
+}
+
+// dumpConfig returns the config, without the values of secret-looking keys
+func (c *Chat) dumpConfig() (map[string]string, error) {
+	entries, err := c.engine.ConfigEntries()
+	if err != nil {
+		return nil, err
+	}
+	config := make(map[string]string)
+	for _, e := range entries {
+		value := e.Value
+		secret := secretConfigKeys[e.Key] || (secretConfigKey.MatchString(e.Key) && !strings.HasSuffix(e.Key, "_tokens"))
+		if secret && value != "" {
+			value = redactedSecret
+		}
+		config[e.Key] = value
+	}
+	return config, nil
+}
+
+// dumpTraces returns the latest traces with their events and assertions
+func (c *Chat) dumpTraces() ([]*modules.TraceDetail, error) {
+	traces, err := c.debug.Traces(dumpTraces, false)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]*modules.TraceDetail, 0, len(traces))
+	for _, t := range traces {
+		detail, err := c.debug.Trace(t.ID)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, detail)
+	}
+	return details, nil
+}
+
+// handleDump writes the repro bundle: /dump [messages] [file.zip]
+func (c *Chat) handleDump(args []string) error {
+	limit, path := dumpMessages, ""
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+			limit = n
+		} else {
+			path = arg
+		}
+	}
+	if path == "" {
+		path = filepath.Join(dumpDir, fmt.Sprintf("dump-%s-%s.zip", shorten(c.session.Current(), 8), time.Now().Format("20060102-150405")))
+	}
+
+	messages, err := c.session.LastMessages(limit)
+	if err != nil {
+		return err
+	}
+	config, err := c.dumpConfig()
+	if err != nil {
+		return err
+	}
+	traces, err := c.dumpTraces()
+	if err != nil {
+		return err
+	}
+	requests := providers.RecentRequests()
+
+	manifest := map[string]interface{}{
+		"created_at":   time.Now(),
+		"session_id":   c.session.Current(),
+		"database":     c.engine.Path(),
+		"go":           runtime.Version(),
+		"os":           runtime.GOOS + "/" + runtime.GOARCH,
+		"debug_mode":   c.debugMode,
+		"messages":     len(messages),
+		"requests":     len(requests),
+		"traces":       len(traces),
+		"debug_events": len(c.modules.GetDebugLog()),
+	}
+	if p := c.registry.Current(); p != nil {
+		manifest["provider"] = p.ID()
+	}
+	if experiment, variant := c.session.Variant(); experiment != "" {
+		manifest["experiment"], manifest["variant"] = experiment, variant
+	}
+
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{"manifest.json", manifest},
+		{"messages.json", messages},
+		{"requests.json", requests},
+		{"config.json", config},
+		{"traces.json", traces},
+		{"debug_events.json", c.modules.GetDebugLog()},
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	secrets := c.knownSecrets()
+	zw := zip.NewWriter(f)
+	for _, file := range files {
+		data, err := json.MarshalIndent(file.v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("dump %s: %w", file.name, err)
+		}
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(redactSecrets(string(data), secrets))); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\033[32m✓ Wrote %s\033[0m \033[90m(%d messages, %d requests, %d traces; secrets redacted, check before sharing)\033[0m\n",
+		path, len(messages), len(requests), len(traces))
+	return nil
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	payload := map[string]string{
+		"auth":    "Bearer csk-abcdefghijklmnop1234",
+		"content": "my github token: ghp_0123456789abcdefXYZ and password=hunter22 here",
+		"env":     "the key is supersecretvalue42",
+		"quoted":  `api_key="abcd\"efgh"`,
+		"plain":   "nothing to see",
+	}
+	data, _ := json.Marshal(payload)
+	out := redactSecrets(string(data), []string{"supersecretvalue42", "short"})
+
+	var redacted map[string]string
+	if err := json.Unmarshal([]byte(out), &redacted); err != nil {
+		t.Fatalf("Redacted JSON is invalid: %v\n%s", err, out)
+	}
+	for _, secret := range []string{"csk-abcdefghijklmnop1234", "ghp_0123456789abcdefXYZ", "hunter22", "supersecretvalue42", "abcd"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Secret %q left in %s", secret, out)
+		}
+	}
+	if redacted["auth"] != "Bearer "+redactedSecret {
+		t.Errorf("Expected the bearer prefix kept, got %q", redacted["auth"])
+	}
+	if !strings.Contains(redacted["content"], "password="+redactedSecret) {
+		t.Errorf("Expected password= kept, got %q", redacted["content"])
+	}
+	if redacted["plain"] != "nothing to see" {
+		t.Errorf("Unexpected change: %q", redacted["plain"])
+	}
+}
+
+func TestDumpConfig(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+	c := &Chat{engine: engine}
+	webhook := "https://hooks.slack.com/services/T000/B000/XXXXXXXXXXXXXXXX"
+	engine.SetConfig("report_slack_webhook", webhook)
+	engine.SetConfig("github_token", "ghp_0123456789abcdef")
+	engine.SetConfig("max_response_tokens", "4000")
+
+	config, err := c.dumpConfig()
+	if err != nil {
+		t.Fatalf("dumpConfig failed: %v", err)
+	}
+	for _, key := range []string{"report_slack_webhook", "github_token"} {
+		if config[key] != redactedSecret {
+			t.Errorf("%s = %q, want it redacted", key, config[key])
+		}
+	}
+	if config["max_response_tokens"] != "4000" {
+		t.Errorf("max_response_tokens = %q, want it kept", config["max_response_tokens"])
+	}
+	if config["slack_bot_token"] != "" {
+		t.Errorf("Expected an unset secret left empty, got %q", config["slack_bot_token"])
+	}
+}
@@ -0,0 +1,323 @@
+// Package ui - Partial file edits (SEARCH/REPLACE blocks and unified diffs)
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EditBlock is a single search/replace hunk proposed by the LLM.
+// An empty Search appends Replace to the end of the file.
+type EditBlock struct {
+	Search  string
+	Replace string
+}
+
+const (
+	editSearchMarker  = "<<<<<<< SEARCH"
+	editDividerMarker = "======="
+	editReplaceMarker = ">>>>>>> REPLACE"
+)
+
+// fuzzyMatchThreshold is the minimum fraction of matching lines for a fuzzy hit
+const fuzzyMatchThreshold = 0.8
+
+// diffHunkHeader matches a hunk header, capturing its old and new line
+// counts (1 when left out)
+var diffHunkHeader = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// hasEditBlocks reports whether a code block contains SEARCH/REPLACE hunks
+func hasEditBlocks(content string) bool {
+	return strings.Contains(content, editSearchMarker) && strings.Contains(content, editReplaceMarker)
+}
+
+// isUnifiedDiff reports whether a code block looks like a unified diff
+func isUnifiedDiff(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if diffHunkHeader.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEditBlocks parses SEARCH/REPLACE hunks from a code block
+func parseEditBlocks(content string) ([]EditBlock, error) {
+	edits := make([]EditBlock, 0)
+	lines := strings.Split(content, "\n")
+
+	for i := 0; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != editSearchMarker {
+			continue
+		}
+
+		var search, replace []string
+		j := i + 1
+		for ; j < len(lines) && strings.TrimSpace(lines[j]) != editDividerMarker; j++ {
+			search = append(search, lines[j])
+		}
+		if j >= len(lines) {
+			return nil, fmt.Errorf("unterminated SEARCH block")
+		}
+
+		k := j + 1
+		for ; k < len(lines) && strings.TrimSpace(lines[k]) != editReplaceMarker; k++ {
+			replace = append(replace, lines[k])
+		}
+		if k >= len(lines) {
+			return nil, fmt.Errorf("unterminated REPLACE block")
+		}
+
+		edits = append(edits, EditBlock{
+			Search:  strings.Join(search, "\n"),
+			Replace: strings.Join(replace, "\n"),
+		})
+		i = k
+	}
+
+	if len(edits) == 0 {
+		return nil, fmt.Errorf("no SEARCH/REPLACE blocks found")
+	}
+	return edits, nil
+}
+
+// parseUnifiedDiff converts unified diff hunks into edit blocks.
+// It returns the target path from the +++ header when present. A hunk
+// takes the lines its header counts whatever they start with, so an added
+// "++ x" or a removed "-- x" is not a file header; after them, lines that
+// look like hunk lines still count, as models often miscount.
+func parseUnifiedDiff(content string) (string, []EditBlock) {
+	var path string
+	edits := make([]EditBlock, 0)
+	var search, replace []string
+	inHunk := false
+	oldLeft, newLeft := 0, 0 // Lines of the hunk header yet to come
+
+	flush := func() {
+		if inHunk && (len(search) > 0 || len(replace) > 0) {
+			edits = append(edits, EditBlock{
+				Search:  strings.Join(search, "\n"),
+				Replace: strings.Join(replace, "\n"),
+			})
+		}
+		search, replace = nil, nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		inBody := oldLeft > 0 || newLeft > 0
+		switch {
+		case !inBody && strings.HasPrefix(line, "+++ "):
+			flush()
+			inHunk = false
+			path = strings.TrimSpace(strings.TrimPrefix(line, "+++ "))
+			path = strings.TrimPrefix(path, "b/")
+		case !inBody && strings.HasPrefix(line, "--- "):
+			// Old file header, path comes from +++
+		case diffHunkHeader.MatchString(line):
+			flush()
+			inHunk = true
+			counts := diffHunkHeader.FindStringSubmatch(line)
+			oldLeft, newLeft = hunkCount(counts[1]), hunkCount(counts[2])
+		case !inHunk:
+			continue
+		case strings.HasPrefix(line, "-"):
+			search = append(search, line[1:])
+			oldLeft--
+		case strings.HasPrefix(line, "+"):
+			replace = append(replace, line[1:])
+			newLeft--
+		case strings.HasPrefix(line, " "):
+			search = append(search, line[1:])
+			replace = append(replace, line[1:])
+			oldLeft, newLeft = oldLeft-1, newLeft-1
+		case line == "":
+			search = append(search, "")
+			replace = append(replace, "")
+			oldLeft, newLeft = oldLeft-1, newLeft-1
+		}
+	}
+	flush()
+
+	if path == "/dev/null" {
+		path = ""
+	}
+	return path, edits
+}
+
+// hunkCount reads a line count of a hunk header, 1 when left out
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// applyEdits applies edit blocks to the original content in order. fuzzy
+// allows a hunk to land on the most similar lines when nothing matches.
+func applyEdits(original string, edits []EditBlock, fuzzy bool) (string, error) {
+	content := original
+	for i, edit := range edits {
+		updated, err := applyEdit(content, edit, fuzzy)
+		if err != nil {
+			return "", fmt.Errorf("hunk %d: %w", i+1, err)
+		}
+		content = updated
+	}
+	return content, nil
+}
+
+// applyEdit locates the search text on whole lines and replaces it. The
+// match must be unique: exact lines first, then lines equal once surrounding
+// whitespace is ignored, then (only when fuzzy) the most similar window.
+func applyEdit(content string, edit EditBlock, fuzzy bool) (string, error) {
+	if strings.TrimSpace(edit.Search) == "" {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		return content + edit.Replace, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	searchLines := strings.Split(edit.Search, "\n")
+	lead, trail := blankEdges(searchLines)
+	searchLines = searchLines[lead : len(searchLines)-trail]
+	if len(searchLines) == 0 || len(searchLines) > len(lines) {
+		return "", fmt.Errorf("search text not found")
+	}
+
+	// 1. Exact lines, 2. lines ignoring surrounding whitespace
+	matchers := []func(a, b string) bool{
+		func(a, b string) bool { return a == b },
+		func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) },
+	}
+	start := -1
+	for _, eq := range matchers {
+		found := findLines(lines, searchLines, eq)
+		if len(found) > 1 {
+			return "", fmt.Errorf("search text is ambiguous: it matches %d places (lines %s); include more context", len(found), lineList(found))
+		}
+		if len(found) == 1 {
+			start = found[0]
+			break
+		}
+	}
+
+	// 3. Best window by line similarity, if enabled
+	if start < 0 && fuzzy {
+		var err error
+		if start, err = findSimilarLines(lines, searchLines); err != nil {
+			return "", err
+		}
+	}
+
+	if start < 0 {
+		return "", fmt.Errorf("search text not found")
+	}
+
+	// Blank lines trimmed off the search are kept in the file, so drop them
+	// from the replacement too, and carry the file's indentation over
+	var replaceLines []string
+	if edit.Replace != "" {
+		replaceLines = strings.Split(edit.Replace, "\n")
+		rLead, rTrail := blankEdges(replaceLines)
+		rLead, rTrail = min(rLead, lead), min(rTrail, trail)
+		replaceLines = replaceLines[rLead : len(replaceLines)-rTrail]
+		replaceLines = reindent(replaceLines, indentOf(searchLines[0]), indentOf(lines[start]))
+	}
+
+	result := make([]string, 0, len(lines))
+	result = append(result, lines[:start]...)
+	result = append(result, replaceLines...)
+	result = append(result, lines[start+len(searchLines):]...)
+	return strings.Join(result, "\n"), nil
+}
+
+// findLines returns every index where needle matches haystack
+func findLines(haystack, needle []string, eq func(a, b string) bool) []int {
+	var found []int
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		match := true
+		for j := range needle {
+			if !eq(haystack[i+j], needle[j]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// findSimilarLines returns the window most similar to needle above the
+// threshold, or -1. Two windows sharing the best score are ambiguous.
+func findSimilarLines(haystack, needle []string) (int, error) {
+	var best []int
+	bestScore := 0.0
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		same := 0
+		for j := range needle {
+			if strings.TrimSpace(haystack[i+j]) == strings.TrimSpace(needle[j]) {
+				same++
+			}
+		}
+		score := float64(same) / float64(len(needle))
+		switch {
+		case score > bestScore:
+			best, bestScore = []int{i}, score
+		case score == bestScore && len(best) > 0:
+			best = append(best, i)
+		}
+	}
+	if bestScore < fuzzyMatchThreshold {
+		return -1, nil
+	}
+	if len(best) > 1 {
+		return -1, fmt.Errorf("search text is ambiguous: %d places are equally similar (lines %s); include more context", len(best), lineList(best))
+	}
+	return best[0], nil
+}
+
+// lineList formats 0-based line indexes as 1-based line numbers
+func lineList(idx []int) string {
+	nums := make([]string, len(idx))
+	for i, n := range idx {
+		nums[i] = strconv.Itoa(n + 1)
+	}
+	return strings.Join(nums, ", ")
+}
+
+// blankEdges counts the blank lines at the start and at the end of lines
+func blankEdges(lines []string) (lead, trail int) {
+	for lead < len(lines) && strings.TrimSpace(lines[lead]) == "" {
+		lead++
+	}
+	for trail < len(lines)-lead && strings.TrimSpace(lines[len(lines)-1-trail]) == "" {
+		trail++
+	}
+	return lead, trail
+}
+
+// indentOf returns the leading whitespace of a line
+func indentOf(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// reindent swaps the from indentation prefix of each line for to
+func reindent(lines []string, from, to string) []string {
+	if from == to {
+		return lines
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if strings.HasPrefix(line, from) && strings.TrimSpace(line) != "" {
+			line = to + line[len(from):]
+		}
+		out[i] = line
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEditBlocks(t *testing.T) {
+	content := `<<<<<<< SEARCH
+func a() {}
+=======
+func a() { return }
+>>>>>>> REPLACE
+<<<<<<< SEARCH
+=======
+func b() {}
+>>>>>>> REPLACE`
+
+	edits, err := parseEditBlocks(content)
+	if err != nil {
+		t.Fatalf("parseEditBlocks failed: %v", err)
+	}
+	if len(edits) != 2 {
+		t.Fatalf("Edits count = %d, want 2", len(edits))
+	}
+	if edits[0].Search != "func a() {}" || edits[0].Replace != "func a() { return }" {
+		t.Errorf("Unexpected first edit: %+v", edits[0])
+	}
+	if edits[1].Search != "" {
+		t.Errorf("Second edit search = %q, want empty", edits[1].Search)
+	}
+
+	if _, err := parseEditBlocks("<<<<<<< SEARCH\nfoo\n"); err == nil {
+		t.Error("Expected error for unterminated block")
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	original := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+
+	tests := []struct {
+		name    string
+		edit    EditBlock
+		fuzzy   bool
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "exact",
+			edit: EditBlock{Search: "println(\"hi\")", Replace: "println(\"bye\")"},
+			want: "package main\n\nfunc main() {\n\tprintln(\"bye\")\n}\n",
+		},
+		{
+			name: "indentation differs",
+			edit: EditBlock{Search: "func main() {\n    println(\"hi\")\n}", Replace: "func main() {}"},
+			want: "package main\n\nfunc main() {}\n",
+		},
+		{
+			name: "append",
+			edit: EditBlock{Search: "", Replace: "func extra() {}"},
+			want: original + "func extra() {}",
+		},
+		{
+			name:    "not found",
+			edit:    EditBlock{Search: "func missing() {}", Replace: ""},
+			wantErr: true,
+		},
+		{
+			name:    "part of a line",
+			edit:    EditBlock{Search: "println(\"h", Replace: "println(\"b"},
+			wantErr: true,
+		},
+		{
+			name:    "similar lines need fuzzy",
+			edit:    EditBlock{Search: "package main\n\nfunc main() {\n\tprintln(\"hi\")\n\tprintln(\"x\")", Replace: "package main"},
+			wantErr: true,
+		},
+		{
+			name:  "fuzzy",
+			edit:  EditBlock{Search: "package main\n\nfunc main() {\n\tprintln(\"hi\")\n\tprintln(\"x\")", Replace: "package main"},
+			fuzzy: true,
+			want:  "package main\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyEdits(original, []EditBlock{tt.edit}, tt.fuzzy)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyEdits failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEdits_Unique(t *testing.T) {
+	// A search for a line prefix must not edit a longer line
+	if got, err := applyEdits("x := 10\n", []EditBlock{{Search: "x := 1", Replace: "x := 2"}}, false); err == nil {
+		t.Errorf("Expected not found, got %q", got)
+	}
+
+	// Several hits are refused, with or without fuzzy matching
+	original := "a()\nb()\na()\n"
+	for _, fuzzy := range []bool{false, true} {
+		_, err := applyEdits(original, []EditBlock{{Search: "a()", Replace: "c()"}}, fuzzy)
+		if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+			t.Errorf("fuzzy=%v: err = %v, want ambiguous", fuzzy, err)
+		}
+	}
+
+	// Enough context makes it unique
+	got, err := applyEdits(original, []EditBlock{{Search: "b()\na()", Replace: "b()\nc()"}}, false)
+	if err != nil {
+		t.Fatalf("applyEdits failed: %v", err)
+	}
+	if got != "a()\nb()\nc()\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	diff := `--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var x = 1
++var x = 2
+ `
+
+	path, edits := parseUnifiedDiff(diff)
+	if path != "main.go" {
+		t.Errorf("Path = %q, want main.go", path)
+	}
+	if len(edits) != 1 {
+		t.Fatalf("Edits count = %d, want 1", len(edits))
+	}
+
+	got, err := applyEdits("package main\nvar x = 1\n", edits, false)
+	if err != nil {
+		t.Fatalf("applyEdits failed: %v", err)
+	}
+	if got != "package main\nvar x = 2\n" {
+		t.Errorf("got %q", got)
+	}
+
+	// Lines the hunk counts are its own, even those that look like headers
+	diff = `--- a/notes.md
++++ b/notes.md
+@@ -1,3 +1,3 @@
+ # Notes
+--- draft
++++ counter
+ end
+--- a/other.md
++++ b/other.md
+@@ -1 +1 @@
+-a
++b
+`
+	path, edits = parseUnifiedDiff(diff)
+	if path != "other.md" || len(edits) != 2 {
+		t.Fatalf("parseUnifiedDiff = %q, %d edits; want other.md, 2", path, len(edits))
+	}
+	if edits[0].Search != "# Notes\n-- draft\nend" || edits[0].Replace != "# Notes\n++ counter\nend" {
+		t.Errorf("Unexpected first hunk %+v", edits[0])
+	}
+}
+
+func TestExtractFileChanges_EditBlocks(t *testing.T) {
+	c := &Chat{}
+	response := "**File: main.go**\n```go\n<<<<<<< SEARCH\nvar x = 1\n=======\nvar x = 2\n>>>>>>> REPLACE\n```\n\nAnd a second hunk for the same file:\n\n```go\n<<<<<<< SEARCH\na\n=======\nb\n>>>>>>> REPLACE\n```\n"
+
+	changes := c.extractFileChanges(response)
+	if len(changes) != 1 {
+		t.Fatalf("Changes count = %d, want 1 (%+v)", len(changes), changes)
+	}
+	if changes[0].Path != "main.go" || len(changes[0].Edits) != 2 {
+		t.Errorf("Unexpected change: %+v", changes[0])
+	}
+
+	// Named twice, or not at all after a named block, edits add up
+	response = "```go title=a.go\n<<<<<<< SEARCH\na\n=======\nb\n>>>>>>> REPLACE\n```\n" +
+		"```diff\n@@ -1 +1 @@\n-c\n+d\n```\n" +
+		"```go title=a.go\n<<<<<<< SEARCH\ne\n=======\nf\n>>>>>>> REPLACE\n```\n"
+	changes = c.extractFileChanges(response)
+	if len(changes) != 1 || changes[0].Path != "a.go" || len(changes[0].Edits) != 3 {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+
+	// An edit block after a whole file edits that content
+	response = "```go title=b.go\npackage b\n\nvar x = 1\n```\n" +
+		"```go\n<<<<<<< SEARCH\nvar x = 1\n=======\nvar x = 2\n>>>>>>> REPLACE\n```\n"
+	changes = c.extractFileChanges(response)
+	if len(changes) != 1 || changes[0].Content != "package b\n\nvar x = 2" || len(changes[0].Edits) != 0 {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+}
@@ -0,0 +1,137 @@
+// Package ui - /experiment: A/B testing system prompt variants against
+// feedback and undo rates
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+const experimentUsage = "usage: /experiment [start <name> <prompt>... | stop | stats [name] | prompt <id> <text>]"
+
+// assignVariant serves the current session its variant of the running
+// experiment, if any
+func (c *Chat) assignVariant() {
+	variant, prompt, err := c.session.AssignVariant()
+	if err != nil {
+		core.Logger("experiments").Warn("No variant assigned", "error", err)
+	}
+	c.variantPrompt = prompt
+	if variant != "" {
+		experiment, _ := c.session.Variant()
+		core.Logger("experiments").Debug("Variant assigned", "experiment", experiment, "variant", variant)
+	}
+}
+
+// handleExperiment lists experiments and their results, or manages them:
+// /experiment start <name> <prompt>..., /experiment stop,
+// /experiment stats [name], /experiment prompt <id> <text>
+func (c *Chat) handleExperiment(args []string) error {
+	if len(args) == 0 {
+		return c.listExperiments()
+	}
+
+	switch args[0] {
+	case "start":
+		if len(args) < 4 {
+			return fmt.Errorf("usage: /experiment start <name> <prompt> <prompt>... (%s for the system_prompt setting)", session.ControlVariant)
+		}
+		if err := c.session.StartExperiment(args[1], args[2:]); err != nil {
+			return err
+		}
+		fmt.Printf("\033[32m✓ Experiment %s started: %s\033[0m\n", args[1], strings.Join(args[2:], " vs "))
+		// The current session joins it from its next message
+		c.assignVariant()
+		return nil
+
+	case "stop":
+		name, err := c.session.StopExperiment()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return fmt.Errorf("no experiment is running")
+		}
+		c.variantPrompt = ""
+		fmt.Printf("\033[32m✓ Experiment %s stopped\033[0m\n", name)
+		return c.printExperimentStats(name)
+
+	case "stats":
+		name := ""
+		if len(args) > 1 {
+			name = args[1]
+		} else if running, err := c.session.RunningExperiment(); err != nil {
+			return err
+		} else if running != nil {
+			name = running.Name
+		}
+		if name == "" {
+			return fmt.Errorf("no experiment is running: /experiment stats <name>")
+		}
+		return c.printExperimentStats(name)
+
+	case "prompt":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: /experiment prompt <id> <system prompt text>")
+		}
+		if args[1] == session.ControlVariant {
+			return fmt.Errorf("%s is reserved for the system_prompt setting", session.ControlVariant)
+		}
+		if err := c.session.SaveSystemPrompt(args[1], strings.Join(args[2:], " ")); err != nil {
+			return err
+		}
+		fmt.Printf("\033[32m✓ Saved system prompt %s\033[0m\n", args[1])
+		return nil
+	}
+	return fmt.Errorf(experimentUsage)
+}
+
+// listExperiments shows the experiments and the prompts available as variants
+func (c *Chat) listExperiments() error {
+	experiments, err := c.session.Experiments()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n\033[33mExperiments:\033[0m")
+	if len(experiments) == 0 {
+		fmt.Println("  \033[90m(none)\033[0m")
+	}
+	for _, e := range experiments {
+		status := "\033[90m" + e.Status + "\033[0m"
+		if e.Status == session.ExperimentRunning {
+			status = "\033[32m" + e.Status + "\033[0m"
+		}
+		fmt.Printf("  %-20s %s  %s\n", e.Name, status, strings.Join(e.Variants, " vs "))
+	}
+	if _, variant := c.session.Variant(); variant != "" {
+		fmt.Printf("  \033[90mThis session is served %s\033[0m\n", variant)
+	}
+
+	prompts, err := c.session.SystemPrompts()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n\033[33mVariants:\033[0m %s %s\n", session.ControlVariant, strings.Join(prompts, " "))
+	fmt.Println("\033[90m" + experimentUsage + "\033[0m")
+	return nil
+}
+
+// printExperimentStats compares an experiment's variants
+func (c *Chat) printExperimentStats(name string) error {
+	stats, err := c.session.ExperimentStats(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n\033[33mExperiment %s:\033[0m\n", name)
+	fmt.Printf("  \033[90m%-20s %8s %8s %8s %8s %8s\033[0m\n", "variant", "sessions", "replies", "👍", "👎", "undone")
+	for _, s := range stats {
+		fmt.Printf("  %-20s %8d %8d %7.0f%% %7.0f%% %7.0f%%\n",
+			shorten(s.Variant, 20), s.Sessions, s.Replies, 100*s.LikeRate(), 100*s.DislikeRate(), 100*s.UndoRate())
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Package ui - Git context: code requests carry the uncommitted changes,
+// so "fix the failing change" works without pasting the diff
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// bytesPerToken converts git_context_tokens to bytes, at about four
+// characters a token
+const bytesPerToken = 4
+
+// gitContextPart is a section of the git context, in the order kept when
+// the budget runs out
+type gitContextPart struct {
+	title string
+	text  string
+}
+
+// gitContext returns the git status, the staged and unstaged diff
+// summaries, and as much of their patches as git_context_tokens allows,
+// for code requests
+func (c *Chat) gitContext(intent *Intent) string {
+	if intent.Type != IntentCode {
+		return ""
+	}
+	budget := c.engine.GetConfigInt("git_context_tokens") * bytesPerToken
+	if budget <= 0 || !c.git.IsRepo() {
+		return ""
+	}
+	status, err := c.git.ShortStatus()
+	if err != nil || strings.TrimSpace(status) == "" {
+		return ""
+	}
+
+	parts := []gitContextPart{{"git status --short", status}}
+	for _, stat := range []bool{true, false} {
+		for _, staged := range []bool{true, false} {
+			text, err := c.git.WorkingDiff(staged, stat)
+			if err != nil || text == "" {
+				continue
+			}
+			title := "git diff"
+			if staged {
+				title += " --cached"
+			}
+			if stat {
+				title += " --stat"
+			} else {
+				text = c.screenContent(title, text)
+			}
+			parts = append(parts, gitContextPart{title, text})
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\nUncommitted changes in the repository:")
+	room := budget
+	for i, p := range parts {
+		text := p.text
+		truncated := ""
+		if len(text) > room {
+			text = cutToLines(text, room)
+			truncated = "... (truncated)\n"
+		}
+		if text == "" {
+			if i == 0 {
+				return "" // Not even the status fits
+			}
+			break
+		}
+		fmt.Fprintf(&sb, "\n\n%s:\n```\n%s%s```", p.title, text, truncated)
+		room -= len(text)
+		if truncated != "" {
+			break
+		}
+	}
+	fmt.Println("\033[90m📎 Attached the uncommitted changes (git_context_tokens)\033[0m")
+	return sb.String()
+}
+
+// cutToLines returns the whole lines of text that fit in n bytes
+func cutToLines(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	if i := strings.LastIndexByte(text[:max(n, 0)], '\n'); i >= 0 {
+		return text[:i+1]
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/git"
+)
+
+func TestGitContext(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n"), 0644)
+	run("add", ".")
+	run("commit", "-q", "-m", "init")
+	os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n\nfunc broken() {\n"+strings.Repeat("\t// filler\n", 200)+"}\n"), 0644)
+
+	engine := setupTestDB(t)
+	defer engine.Close()
+	c := &Chat{engine: engine, git: git.NewManager(repo)}
+	code := &Intent{Type: IntentCode, Raw: "fix the failing change"}
+
+	if got := c.gitContext(code); got != "" {
+		t.Errorf("Expected no git context by default, got %q", got)
+	}
+
+	engine.SetConfig("git_context_tokens", "100")
+	got := c.gitContext(code)
+	for _, want := range []string{"git status --short", "M main.go", "git diff --stat", "+func broken() {", "(truncated)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in the git context, got:\n%s", want, got)
+		}
+	}
+	if len(got) > 100*bytesPerToken+200 {
+		t.Errorf("Expected the patch cut to the budget, got %d bytes", len(got))
+	}
+
+	if got := c.gitContext(&Intent{Type: IntentQuestion, Raw: "what changed?"}); got != "" {
+		t.Errorf("Expected git context only for code requests, got %q", got)
+	}
+}
@@ -0,0 +1,128 @@
+// Package ui - Interactive per-hunk review of file changes
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/diff"
+)
+
+// hunkContextLines is the number of unchanged lines shown around each hunk
+const hunkContextLines = 3
+
+// reviewHunks asks the user to accept or reject each hunk of every change to
+// an existing file, and returns the changes rebuilt from the accepted hunks.
+// New files, deletions, and renames are kept as they are.
+func (c *Chat) reviewHunks(changes []FileChange) []FileChange {
+	result := make([]FileChange, 0, len(changes))
+	quit := false
+
+	for _, ch := range changes {
+		if ch.Delete || ch.IsRename() || !fileExists(ch.Path) {
+			if !quit {
+				result = append(result, ch)
+			}
+			continue
+		}
+		if quit {
+			continue
+		}
+
+		original, err := c.git.GetFileContent(ch.Path)
+		if err != nil {
+			result = append(result, ch)
+			continue
+		}
+
+		hunks := diff.Compute(original, ch.Content, hunkContextLines)
+		if len(hunks) == 0 {
+			fmt.Printf("\033[90m%s unchanged\033[0m\n", ch.Path)
+			continue
+		}
+
+		accepted := make([]bool, len(hunks))
+		decided := false
+		for i, h := range hunks {
+			if decided {
+				accepted[i] = accepted[i-1]
+				continue
+			}
+
+			fmt.Printf("\n\033[1m%s\033[0m (hunk %d/%d)\n", ch.Path, i+1, len(hunks))
+			printHunk(h)
+
+			switch c.askHunk() {
+			case "y":
+				accepted[i] = true
+			case "n":
+				accepted[i] = false
+			case "a":
+				accepted[i] = true
+				decided = true
+			case "d":
+				accepted[i] = false
+				decided = true
+			case "q":
+				quit = true
+			}
+
+			if quit {
+				break
+			}
+		}
+
+		applied := 0
+		for _, ok := range accepted {
+			if ok {
+				applied++
+			}
+		}
+		if applied == 0 {
+			continue
+		}
+
+		ch.Content = diff.ApplyHunks(original, hunks, accepted)
+		result = append(result, ch)
+	}
+
+	return result
+}
+
+// askHunk prompts until it gets a valid hunk answer; EOF quits the review
+func (c *Chat) askHunk() string {
+	for {
+		answer, err := c.ask("\033[36mApply this hunk? [y,n,a,d,q,?] \033[0m")
+		if err == io.EOF {
+			return "q"
+		}
+
+		switch answer = strings.ToLower(strings.TrimSpace(answer)); answer {
+		case "y", "yes", "n", "no", "a", "d", "q":
+			return answer[:1]
+		}
+
+		fmt.Println("  y - apply this hunk")
+		fmt.Println("  n - skip this hunk")
+		fmt.Println("  a - apply this and all remaining hunks in the file")
+		fmt.Println("  d - skip this and all remaining hunks in the file")
+		fmt.Println("  q - stop reviewing; skip this and all remaining hunks")
+	}
+}
+
+// printHunk prints a hunk with colored additions and removals
+func printHunk(h diff.Hunk) {
+	fmt.Printf("\033[36m%s\033[0m\n", h.Header())
+	for _, op := range h.Ops {
+		line := strings.TrimSuffix(op.Line, "\n")
+		switch op.Kind {
+		case diff.Delete:
+			fmt.Printf("\033[31m-%s\033[0m\n", line)
+		case diff.Insert:
+			fmt.Printf("\033[32m+%s\033[0m\n", line)
+		default:
+			fmt.Printf(" %s\n", line)
+		}
+	}
+}
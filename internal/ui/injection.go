@@ -0,0 +1,56 @@
+// Package ui - Prompt-injection screening: files and web results that
+// read like instructions to the model are flagged, and neutralized unless
+// injection_screening is warn
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/injection"
+)
+
+// maxInjectionsShown caps the findings the warning quotes
+const maxInjectionsShown = 3
+
+// screenContent checks content read from source for embedded instructions
+// before it enters the context, warning the user and auditing what it finds
+func (c *Chat) screenContent(source, text string) string {
+	mode, _ := c.engine.GetConfig("injection_screening")
+	if mode == "off" {
+		return text
+	}
+	findings := injection.Scan(text)
+	if len(findings) == 0 {
+		return text
+	}
+	action := "flagged only (injection_screening is warn)"
+	if mode != "warn" {
+		text, findings = injection.Neutralize(text)
+		action = "neutralized"
+	}
+
+	quoted := make([]string, 0, maxInjectionsShown)
+	for _, f := range findings[:min(len(findings), maxInjectionsShown)] {
+		quoted = append(quoted, fmt.Sprintf("%s %q", f.Kind, f.Text))
+	}
+	if len(findings) > maxInjectionsShown {
+		quoted = append(quoted, fmt.Sprintf("%d more", len(findings)-maxInjectionsShown))
+	}
+	fmt.Printf("\033[33m🛡️  Possible prompt injection in %s, %s: %s\033[0m\n", source, action, strings.Join(quoted, ", "))
+
+	sessionID := ""
+	if c.session != nil {
+		sessionID = c.session.Current()
+	}
+	err := c.engine.Audit(sessionID, "prompt_injection", map[string]interface{}{
+		"source":   source,
+		"action":   action,
+		"findings": findings,
+	})
+	if err != nil {
+		core.Logger("ui").Warn("Prompt injection not audited", "error", err)
+	}
+	return text
+}
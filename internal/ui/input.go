@@ -0,0 +1,252 @@
+// Package ui - Terminal input: lines typed while a reply streams are
+// queued and sent after the turn, and the questions asked during a turn
+// are read through readline, which owns the terminal
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chzyer/readline"
+)
+
+// typeAheadPrompt is shown under a streaming reply
+const typeAheadPrompt = "\033[90m⏳ next>\033[0m "
+
+// dropCommand, typed while a reply streams, cancels the last queued input
+const dropCommand = "/drop"
+
+// interruptKey is Ctrl+C, sent to end a line read early
+const interruptKey = 0x03
+
+// inputChunk is a read of the real stdin
+type inputChunk struct {
+	data []byte
+	err  error
+}
+
+// terminalInput is the stdin readline reads. The real stdin is only read
+// when readline asks, and keys can be sent to end a read that is waiting
+// on the user.
+type terminalInput struct {
+	src     io.Reader
+	demand  chan struct{}
+	chunks  chan inputChunk
+	inject  chan []byte
+	pending []byte // Read from src, not yet handed out
+	waiting bool   // A read of src is outstanding
+}
+
+func newTerminalInput(src io.Reader) *terminalInput {
+	in := &terminalInput{
+		src:    src,
+		demand: make(chan struct{}),
+		chunks: make(chan inputChunk),
+		inject: make(chan []byte, 1),
+	}
+	go in.pump()
+	return in
+}
+
+// pump reads src once per demand
+func (in *terminalInput) pump() {
+	for range in.demand {
+		buf := make([]byte, 1024)
+		n, err := in.src.Read(buf)
+		in.chunks <- inputChunk{data: buf[:n], err: err}
+	}
+}
+
+// Read returns what the user typed or keys sent with send, whichever
+// comes first. Readline's terminal loop is the only reader.
+func (in *terminalInput) Read(p []byte) (int, error) {
+	if len(in.pending) > 0 {
+		n := copy(p, in.pending)
+		in.pending = in.pending[n:]
+		return n, nil
+	}
+	if !in.waiting {
+		in.waiting = true
+		in.demand <- struct{}{}
+	}
+	select {
+	case c := <-in.chunks:
+		in.waiting = false
+		n := copy(p, c.data)
+		in.pending = append(in.pending, c.data[n:]...)
+		if n == 0 {
+			return 0, c.err
+		}
+		return n, nil
+	case keys := <-in.inject:
+		return copy(p, keys), nil
+	}
+}
+
+// send hands keys to the next read, unless keys are already waiting
+func (in *terminalInput) send(keys []byte) {
+	select {
+	case in.inject <- keys:
+	default:
+	}
+}
+
+// discard drops keys sent but never read
+func (in *terminalInput) discard() {
+	select {
+	case <-in.inject:
+	default:
+	}
+}
+
+// ask shows a question and reads the answer, trimmed; the error is
+// io.EOF once input ends
+func (c *Chat) ask(question string) (string, error) {
+	if c.input == nil {
+		fmt.Print(question)
+		var answer string
+		_, err := fmt.Scanln(&answer)
+		return strings.TrimSpace(answer), err
+	}
+	// Readline redraws its prompt line: a question opening with a blank
+	// line prints it first
+	if trimmed := strings.TrimLeft(question, "\n"); trimmed != question {
+		fmt.Print(question[:len(question)-len(trimmed)])
+		question = trimmed
+	}
+	c.rl.SetPrompt(question)
+	c.rl.HistoryDisable() // Answers are not worth recalling
+	defer c.rl.HistoryEnable()
+	answer, err := c.rl.Readline()
+	if err == readline.ErrInterrupt {
+		return "", nil
+	}
+	return strings.TrimSpace(answer), err
+}
+
+// typeAhead reads the next inputs while a reply streams. The reply is
+// written a line at a time above the input line.
+type typeAhead struct {
+	chat     *Chat
+	out      io.Writer
+	partial  string // Reply text after the last newline written
+	stopping atomic.Bool
+	erase    bool // The read was cut short, leaving its input line
+	done     chan struct{}
+	once     sync.Once
+}
+
+// startTypeAhead lets the user type while a reply streams, or returns nil
+// when the chat does not own a terminal or type_ahead is off
+func (c *Chat) startTypeAhead() *typeAhead {
+	if !c.interactive || c.input == nil || c.onDelta != nil || !c.engine.GetConfigBool("type_ahead") {
+		return nil
+	}
+	t := &typeAhead{chat: c, out: c.rl.Stdout(), done: make(chan struct{})}
+	go t.read()
+	return t
+}
+
+// read queues the lines typed until the reply ends. Ctrl+C on an empty
+// line stops an agent or quits, as it does while no input is read.
+func (t *typeAhead) read() {
+	defer close(t.done)
+	c := t.chat
+	for !t.stopping.Load() {
+		c.rl.SetPrompt(typeAheadPrompt)
+		line, err := c.rl.Readline()
+		if t.stopping.Load() {
+			switch {
+			case err == readline.ErrInterrupt:
+				c.draft = line // Finished at the next prompt
+				t.erase = true
+			case err == nil:
+				c.queueInput(t.out, line)
+			}
+			return
+		}
+		switch {
+		case err == readline.ErrInterrupt:
+			if line == "" && !c.stopAgent() {
+				c.shutdown()
+				return
+			}
+		case err != nil:
+			return
+		default:
+			c.queueInput(t.out, line)
+		}
+	}
+}
+
+// print writes streamed text, holding back the line being written
+func (t *typeAhead) print(delta string) {
+	t.partial += delta
+	if i := strings.LastIndexByte(t.partial, '\n'); i >= 0 {
+		t.out.Write([]byte(t.partial[:i+1]))
+		t.partial = t.partial[i+1:]
+	}
+}
+
+// stop ends the reply's last line and stops reading, keeping what was
+// being typed as a draft
+func (t *typeAhead) stop() {
+	t.once.Do(func() {
+		t.out.Write([]byte(t.partial + "\n"))
+		t.stopping.Store(true)
+		t.chat.input.send([]byte{interruptKey})
+		<-t.done
+		t.chat.input.discard()
+		if t.erase {
+			fmt.Print("\033[1A\033[2K\r")
+		}
+	})
+}
+
+// queueInput queues a line typed during a reply; /drop takes back the last
+func (c *Chat) queueInput(out io.Writer, line string) {
+	line = strings.TrimSpace(line)
+	switch {
+	case line == "":
+	case line == dropCommand && len(c.typedAhead) == 0:
+		fmt.Fprintln(out, "\033[90m📭 Nothing queued\033[0m")
+	case line == dropCommand:
+		dropped := c.typedAhead[len(c.typedAhead)-1]
+		c.typedAhead = c.typedAhead[:len(c.typedAhead)-1]
+		fmt.Fprintf(out, "\033[90m🗑️  Dropped: %s\033[0m\n", dropped)
+	default:
+		c.typedAhead = append(c.typedAhead, line)
+		fmt.Fprintf(out, "\033[36m📥 Queued (%d): %s\033[0m \033[90m%s cancels it\033[0m\n", len(c.typedAhead), line, dropCommand)
+	}
+}
+
+// nextInput returns the next line to handle: a queued one, or one read
+// at the prompt, starting from the draft left by a reply's end
+func (c *Chat) nextInput() (string, error) {
+	if len(c.typedAhead) > 0 {
+		line := c.typedAhead[0]
+		c.typedAhead = c.typedAhead[1:]
+		fmt.Printf("%s%s\n", basePrompt, line)
+		return line, nil
+	}
+	draft := c.draft
+	c.draft = ""
+	if draft != "" {
+		return c.rl.ReadlineWithDefault(draft)
+	}
+	return c.rl.Readline()
+}
+
+// stdinInput wraps stdin for readline when it is a terminal; elsewhere
+// readline reads it directly
+func stdinInput() (io.ReadCloser, *terminalInput) {
+	if !readline.DefaultIsTerminal() {
+		return readline.NewCancelableStdin(os.Stdin), nil
+	}
+	in := newTerminalInput(os.Stdin)
+	return io.NopCloser(in), in
+}
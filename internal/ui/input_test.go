@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTerminalInput(t *testing.T) {
+	r, w := io.Pipe()
+	in := newTerminalInput(r)
+	buf := make([]byte, 16)
+
+	// A key sent ends a read waiting on the user
+	done := make(chan string)
+	go func() {
+		n, _ := in.Read(buf)
+		done <- string(buf[:n])
+	}()
+	time.Sleep(10 * time.Millisecond)
+	in.send([]byte{interruptKey})
+	select {
+	case got := <-done:
+		if got != "\x03" {
+			t.Fatalf("Expected the key sent, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Read still waiting after a key was sent")
+	}
+
+	// What the user types meanwhile goes to the next read, not lost
+	go w.Write([]byte("hello"))
+	n, err := in.Read(buf)
+	if err != nil || string(buf[:n]) != "hello" {
+		t.Fatalf("Expected the typed text, got %q, %v", buf[:n], err)
+	}
+
+	in.send([]byte{interruptKey})
+	in.discard()
+	go w.Close()
+	if _, err := in.Read(buf); err != io.EOF {
+		t.Errorf("Expected discarded keys dropped and EOF passed on, got %v", err)
+	}
+}
+
+func TestQueueInput(t *testing.T) {
+	c := &Chat{}
+	c.queueInput(io.Discard, "  explain main.go ")
+	c.queueInput(io.Discard, "")
+	c.queueInput(io.Discard, "add tests")
+	c.queueInput(io.Discard, "/undo")
+	c.queueInput(io.Discard, dropCommand)
+	if len(c.typedAhead) != 2 {
+		t.Fatalf("Expected two inputs after /drop, got %q", c.typedAhead)
+	}
+
+	for _, want := range []string{"explain main.go", "add tests"} {
+		got, err := c.nextInput()
+		if err != nil || got != want {
+			t.Errorf("Expected %q handled next, got %q, %v", want, got, err)
+		}
+	}
+
+	c.queueInput(io.Discard, dropCommand)
+	if len(c.typedAhead) != 0 {
+		t.Errorf("Expected /drop with nothing queued not queued, got %q", c.typedAhead)
+	}
+}
@@ -0,0 +1,210 @@
+// Package ui - Project instructions: GOCLODE.md files added to the system
+// prompt, and /init to write one
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+// instructionsFile is the file /init writes
+const instructionsFile = "GOCLODE.md"
+
+// Caps on what instructions and /init send to the model
+const (
+	maxInstructionBytes = 32 * 1024 // Per instruction file
+	maxInitFiles        = 300       // Paths listed to /init
+	maxInitFileBytes    = 4096      // Per README or manifest shown to /init
+)
+
+// initManifests are the files /init reads to learn how a project builds
+var initManifests = []string{
+	"README.md", "README", "go.mod", "package.json", "Cargo.toml", "pyproject.toml",
+	"requirements.txt", "Makefile", "Dockerfile", ".github/workflows/ci.yml",
+}
+
+const initSystemPrompt = `You write GOCLODE.md, the instructions a coding assistant reads before every request in a project. From what you are shown of the repository, write concise Markdown covering:
+- What the project is, in one or two sentences
+- How to build, test, and lint it (exact commands)
+- Its layout: the main directories and what lives in them
+- Conventions to follow: naming, error handling, tests, formatting, anything unusual
+Only state what the files support; do not invent commands. Answer with the file's content only, no code fence around it.`
+
+// instructionFiles returns the instruction files found in dir and its
+// parent directories, outermost first so the nearest comes last; in a
+// directory, the first of names comes last
+func instructionFiles(dir string, names []string) []string {
+	found := make([]string, 0)
+	for {
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				found = append(found, path)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found
+}
+
+// instructionNames reads the instruction_files config
+func (c *Chat) instructionNames() []string {
+	raw, _ := c.engine.GetConfig("instruction_files")
+	names := make([]string, 0)
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// instructionsContext returns the project instructions sent with each
+// request; the files are read every time, so edits apply at once
+func (c *Chat) instructionsContext() string {
+	names := c.instructionNames()
+	if len(names) == 0 {
+		return ""
+	}
+	root, err := filepath.Abs(c.git.WorkDir())
+	if err != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, path := range instructionFiles(root, names) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		content := strings.TrimSpace(string(data))
+		if content == "" {
+			continue
+		}
+		if len(content) > maxInstructionBytes {
+			content = content[:maxInstructionBytes] + "\n... (truncated)"
+		}
+		name := path
+		if rel, err := filepath.Rel(root, path); err == nil {
+			name = rel
+		}
+		fmt.Fprintf(&sb, "\n\n--- %s ---\n%s", name, content)
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "\n\nProject instructions (follow them; where they disagree, the later file wins):" + sb.String()
+}
+
+// handleInit has the model write GOCLODE.md from the repository's files,
+// layout, and symbols, or revise the existing one: /init [what to stress]
+func (c *Chat) handleInit(args []string) error {
+	provider := c.registry.Current()
+	if provider == nil {
+		return fmt.Errorf("no provider available")
+	}
+	if err := c.checkBudget(); err != nil {
+		return err
+	}
+	path := filepath.Join(c.git.WorkDir(), instructionsFile)
+	existing, _ := os.ReadFile(path)
+
+	prompt := c.initContext()
+	if len(existing) > 0 {
+		prompt += "\n\nThe current " + instructionsFile + ", to revise (keep what still holds):\n" + string(existing)
+	}
+	if focus := strings.TrimSpace(strings.Join(args, " ")); focus != "" {
+		prompt += "\n\nThe user asks to stress: " + focus
+	}
+
+	fmt.Printf("\033[90m🔍 Analyzing the repository for %s...\033[0m\n", instructionsFile)
+	resp, err := provider.Generate(c.ctx, &providers.Request{
+		Messages: []providers.Message{
+			{Role: "system", Content: initSystemPrompt},
+			{Role: "user", Content: prompt},
+		},
+		Temperature: 0.3,
+	})
+	if err != nil {
+		return fmt.Errorf("init: %w", err)
+	}
+	content := stripFence(resp.Content)
+	if content == "" {
+		return fmt.Errorf("init: empty answer")
+	}
+	fmt.Printf("\n%s\n", content)
+
+	action := "Write"
+	if len(existing) > 0 {
+		action = "Replace"
+	}
+	confirm, _ := c.ask(fmt.Sprintf("\n\033[36m%s %s? [y/N] \033[0m", action, instructionsFile))
+	if confirm = strings.ToLower(strings.TrimSpace(confirm)); confirm != "y" && confirm != "yes" {
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(content+"\n"), 0o644); err != nil {
+		return err
+	}
+	if c.index != nil {
+		c.index.Add(instructionsFile)
+	}
+	fmt.Printf("\033[32m✓ Wrote %s; it is sent with every request (instruction_files)\033[0m\n", instructionsFile)
+	return nil
+}
+
+// initContext shows /init the repository: its files, manifests, and
+// symbol map
+func (c *Chat) initContext() string {
+	var sb strings.Builder
+	files := c.indexedFiles()
+	sb.WriteString("Files:\n")
+	for i, f := range files {
+		if i == maxInitFiles {
+			fmt.Fprintf(&sb, "... (%d more)\n", len(files)-i)
+			break
+		}
+		sb.WriteString(f + "\n")
+	}
+
+	guard := c.workspaceGuard()
+	for _, name := range initManifests {
+		if guard.Check(name) != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.git.WorkDir(), name))
+		if err != nil {
+			continue
+		}
+		content := string(data)
+		if len(content) > maxInitFileBytes {
+			content = content[:maxInitFileBytes] + "\n... (truncated)"
+		}
+		fmt.Fprintf(&sb, "\n--- %s ---\n%s\n", name, content)
+	}
+	sb.WriteString(c.repoMap())
+	return sb.String()
+}
+
+// stripFence removes a code fence wrapped around a whole answer
+func stripFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") || len(s) < 6 {
+		return s
+	}
+	_, body, ok := strings.Cut(s, "\n")
+	if !ok {
+		return s
+	}
+	return strings.TrimSpace(strings.TrimSuffix(body, "```"))
+}
@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInstructionFiles(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	os.MkdirAll(filepath.Join(repo, "sub"), 0o755)
+	os.WriteFile(filepath.Join(root, "GOCLODE.md"), []byte("parent"), 0o644)
+	os.WriteFile(filepath.Join(repo, "AGENTS.md"), []byte("agents"), 0o644)
+	os.WriteFile(filepath.Join(repo, "GOCLODE.md"), []byte("repo"), 0o644)
+	os.Mkdir(filepath.Join(repo, "sub", "GOCLODE.md"), 0o755) // Not a file
+
+	got := instructionFiles(filepath.Join(repo, "sub"), []string{"GOCLODE.md", "AGENTS.md"})
+	want := []string{
+		filepath.Join(root, "GOCLODE.md"),
+		filepath.Join(repo, "AGENTS.md"),
+		filepath.Join(repo, "GOCLODE.md"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("instructionFiles = %v, want %v", got, want)
+	}
+}
+
+func TestStripFence(t *testing.T) {
+	tests := map[string]string{
+		"# Project\n\nBuild with make.": "# Project\n\nBuild with make.",
+		"```markdown\n# Project\n```":   "# Project",
+		"```\n# Project\n```\n":         "# Project",
+	}
+	for in, want := range tests {
+		if got := stripFence(in); got != want {
+			t.Errorf("stripFence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -26,6 +26,37 @@ const (
 	IntentExit        IntentType = "exit"          // Exit/quit
 	IntentFeedback    IntentType = "feedback"      // Positive/negative feedback
 	IntentDebug       IntentType = "debug"         // Debug mode
+	IntentInspect     IntentType = "intent"        // Inspect intent parsing
+	IntentRestore     IntentType = "restore"       // Restore a file from backup
+	IntentPR          IntentType = "pr"            // Open a pull/merge request
+	IntentLog         IntentType = "log"           // List this session's commits
+	IntentTask        IntentType = "task"          // Start a new task (amend mode)
+	IntentPush        IntentType = "push"          // Push the current branch
+	IntentResolve     IntentType = "resolve"       // Resolve merge conflicts
+	IntentTest        IntentType = "test"          // Run tests, fixing failures
+	IntentSymbols     IntentType = "symbols"       // Search the symbol index
+	IntentIndex       IntentType = "index"         // Build the embedding index
+	IntentAgent       IntentType = "agent"         // Plan and run a multi-step goal
+	IntentPermissions IntentType = "permissions"   // Review tool permission rules
+	IntentRun         IntentType = "run"           // Run a command, offering to fix failures
+	IntentSandbox     IntentType = "sandbox"       // Show or reset the command container
+	IntentTodo        IntentType = "todo"          // Show or edit the todo list
+	IntentResume      IntentType = "resume"        // Return to an earlier session
+	IntentRecover     IntentType = "resume-recovered" // Return to a session that crashed
+	IntentLearn       IntentType = "learn"         // Inspect what the learning module learned
+	IntentExperiment  IntentType = "experiment"    // Run system prompt A/B experiments
+	IntentTrace       IntentType = "trace"         // Inspect debug traces
+	IntentAnalyze     IntentType = "analyze"       // Have the model diagnose the debug data
+	IntentDump        IntentType = "dump"          // Write a repro bundle for a bug report
+	IntentInit        IntentType = "init"          // Write GOCLODE.md from the repository
+	IntentPrompt      IntentType = "prompt"        // List, edit, or send stored prompt templates
+	IntentBudget      IntentType = "budget"        // Show spend against the budgets, or override them
+	IntentUsage       IntentType = "usage"         // Report tokens and cost over a time range
+	IntentAudit       IntentType = "audit"         // Show the audit log
+	IntentQueue       IntentType = "queue"         // Show, send, or drop the requests queued while offline
+	IntentContinue    IntentType = "continue"      // Resume a reply cut short by a response limit
+	IntentCheckpoint  IntentType = "checkpoint"    // List, save, or remove working tree checkpoints
+	IntentRollback    IntentType = "rollback"      // Return the working tree to a checkpoint
 )
 
 // Intent represents a parsed user intent
@@ -40,6 +71,8 @@ type Intent struct {
 	Args       []string
 	Confidence float64
 	Raw        string
+	Pattern    string // Pattern that triggered the match, if any
+	Learned    bool   // Routed to a learned command; Content holds the input
 }
 
 // IntentParser parses user input into intents
@@ -48,8 +81,18 @@ type IntentParser struct {
 	patterns       map[IntentType][]string
 	filePatterns   []*regexp.Regexp
 	actionPatterns map[string][]string
+	learned        LearnedLookup
 }
 
+// LearnedLookup returns the command learned for an input and its
+// confidence, "" when there is none
+type LearnedLookup func(input string) (string, float64, error)
+
+// learnedWeight scales a learned intent's confidence before it competes
+// with the rule-based match: a certain one (0.9) beats a pattern (0.8), one
+// at the 0.7 floor only beats the default code intent (0.6)
+const learnedWeight = 0.9
+
 // NewIntentParser creates a new intent parser
 func NewIntentParser(db *sql.DB) *IntentParser {
 	ip := &IntentParser{
@@ -146,13 +189,14 @@ func (ip *IntentParser) Parse(input string) *Intent {
 				intent.Type = intentType
 				intent.Content = input
 				intent.Confidence = 0.8
+				intent.Pattern = pattern
 
 				// Extract provider for switch intent
 				if intentType == IntentSwitch {
 					intent.Provider = ip.extractProvider(input)
 				}
 
-				return intent
+				return ip.blendLearned(intent)
 			}
 		}
 	}
@@ -168,7 +212,38 @@ func (ip *IntentParser) Parse(input string) *Intent {
 	intent.Content = input
 	intent.Confidence = 0.6
 
-	return intent
+	return ip.blendLearned(intent)
+}
+
+// SetLearned lets learned intents compete with the rule-based matches
+func (ip *IntentParser) SetLearned(lookup LearnedLookup) {
+	ip.learned = lookup
+}
+
+// blendLearned routes input to its learned command when that scores above
+// the rule-based match
+func (ip *IntentParser) blendLearned(intent *Intent) *Intent {
+	if ip.learned == nil {
+		return intent
+	}
+	command, confidence, err := ip.learned(intent.Raw)
+	if err != nil || !strings.HasPrefix(command, "/") {
+		return intent
+	}
+	score := confidence * learnedWeight
+	if score <= intent.Confidence {
+		return intent
+	}
+
+	learned := ip.parseCommand(command)
+	if learned == nil {
+		return intent
+	}
+	learned.Content = intent.Raw
+	learned.Confidence = score
+	learned.Pattern = "learned: " + command
+	learned.Learned = true
+	return learned
 }
 
 // parseCommand parses a slash command
@@ -209,6 +284,70 @@ func (ip *IntentParser) parseCommand(input string) *Intent {
 		intent.Type = IntentRedo
 	case "debug":
 		intent.Type = IntentDebug
+	case "intent":
+		intent.Type = IntentInspect
+	case "feedback":
+		intent.Type = IntentFeedback
+	case "learn":
+		intent.Type = IntentLearn
+	case "experiment":
+		intent.Type = IntentExperiment
+	case "trace":
+		intent.Type = IntentTrace
+	case "analyze":
+		intent.Type = IntentAnalyze
+	case "dump":
+		intent.Type = IntentDump
+	case "init":
+		intent.Type = IntentInit
+	case "prompt":
+		intent.Type = IntentPrompt
+	case "budget":
+		intent.Type = IntentBudget
+	case "usage":
+		intent.Type = IntentUsage
+	case "audit":
+		intent.Type = IntentAudit
+	case "queue":
+		intent.Type = IntentQueue
+	case "continue":
+		intent.Type = IntentContinue
+	case "checkpoint":
+		intent.Type = IntentCheckpoint
+	case "rollback":
+		intent.Type = IntentRollback
+	case "restore":
+		intent.Type = IntentRestore
+	case "pr", "mr":
+		intent.Type = IntentPR
+	case "log":
+		intent.Type = IntentLog
+	case "task":
+		intent.Type = IntentTask
+	case "push":
+		intent.Type = IntentPush
+	case "resolve":
+		intent.Type = IntentResolve
+	case "test":
+		intent.Type = IntentTest
+	case "run":
+		intent.Type = IntentRun
+	case "sandbox":
+		intent.Type = IntentSandbox
+	case "todo", "todos":
+		intent.Type = IntentTodo
+	case "resume":
+		intent.Type = IntentResume
+	case "resume-recovered":
+		intent.Type = IntentRecover
+	case "symbols", "sym":
+		intent.Type = IntentSymbols
+	case "index":
+		intent.Type = IntentIndex
+	case "agent":
+		intent.Type = IntentAgent
+	case "permissions", "perms":
+		intent.Type = IntentPermissions
 	case "provider", "model", "switch":
 		intent.Type = IntentSwitch
 		if len(args) > 0 {
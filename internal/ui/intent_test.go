@@ -206,6 +206,23 @@ func TestIntentParser_SlashCommands(t *testing.T) {
 		{"undo", "/undo", IntentUndo, "undo"},
 		{"debug", "/debug", IntentDebug, "debug"},
 		{"provider", "/provider cerebras", IntentSwitch, "provider"},
+		{"run", "/run go build ./...", IntentRun, "run"},
+		{"feedback", "/feedback wrong file edited", IntentFeedback, "feedback"},
+		{"learn", "/learn stats", IntentLearn, "learn"},
+		{"experiment", "/experiment stats", IntentExperiment, "experiment"},
+		{"trace", "/trace errors", IntentTrace, "trace"},
+		{"analyze", "/analyze slow hooks", IntentAnalyze, "analyze"},
+		{"resume-recovered", "/resume-recovered 1a2b", IntentRecover, "resume-recovered"},
+		{"dump", "/dump 20 bug.zip", IntentDump, "dump"},
+		{"init", "/init", IntentInit, "init"},
+		{"prompt", "/prompt use code_review @main.go", IntentPrompt, "prompt"},
+		{"budget", "/budget override", IntentBudget, "budget"},
+		{"usage", "/usage --since 7d --by provider,model", IntentUsage, "usage"},
+		{"audit", "/audit 50", IntentAudit, "audit"},
+		{"queue", "/queue run", IntentQueue, "queue"},
+		{"continue", "/continue", IntentContinue, "continue"},
+		{"checkpoint", "/checkpoint before-refactor", IntentCheckpoint, "checkpoint"},
+		{"rollback", "/rollback before-refactor", IntentRollback, "rollback"},
 	}
 
 	for _, tt := range tests {
@@ -225,3 +242,105 @@ func TestIntentParser_SlashCommands(t *testing.T) {
 		})
 	}
 }
+
+func TestIntentParser_MatchedPattern(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+
+	parser := NewIntentParser(engine.DB())
+
+	tests := []struct {
+		name        string
+		input       string
+		wantType    IntentType
+		wantPattern string
+	}{
+		{"undo", "undo", IntentUndo, "undo"},
+		{"switch", "switch to cerebras", IntentSwitch, "switch to"},
+		{"code default", "Crée un fichier README.md", IntentCode, ""},
+		{"intent command", "/intent test undo", IntentInspect, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intent := parser.Parse(tt.input)
+			if intent == nil {
+				t.Fatal("Expected non-nil intent")
+			}
+
+			if intent.Type != tt.wantType {
+				t.Errorf("Type = %v, want %v", intent.Type, tt.wantType)
+			}
+
+			if intent.Pattern != tt.wantPattern {
+				t.Errorf("Pattern = %q, want %q", intent.Pattern, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestIntentParser_BlendLearned(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+
+	parser := NewIntentParser(engine.DB())
+	learned := map[string]float64{}
+	parser.SetLearned(func(input string) (string, float64, error) {
+		if c, ok := learned[input]; ok {
+			return "/diff", c, nil
+		}
+		return "", 0, nil
+	})
+
+	tests := []struct {
+		name        string
+		input       string
+		confidence  float64
+		wantType    IntentType
+		wantLearned bool
+	}{
+		{"unlearned input", "where are we at", 0, IntentCode, false},
+		{"learned beats default", "where are we at", 0.7, IntentDiff, true},
+		{"confident learned beats pattern", "undo that mess", 1.0, IntentDiff, true},
+		{"pattern beats weaker learned", "undo that mess", 0.8, IntentUndo, false},
+		{"slash commands stay", "/status", 1.0, IntentStatus, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			learned = map[string]float64{}
+			if tt.confidence > 0 {
+				learned[tt.input] = tt.confidence
+			}
+			intent := parser.Parse(tt.input)
+			if intent.Type != tt.wantType || intent.Learned != tt.wantLearned {
+				t.Errorf("Parse(%q) = %s (learned %v), want %s (learned %v)", tt.input, intent.Type, intent.Learned, tt.wantType, tt.wantLearned)
+			}
+			if intent.Learned && (intent.Content != tt.input || intent.Raw != "/diff") {
+				t.Errorf("learned intent Content = %q, Raw = %q", intent.Content, intent.Raw)
+			}
+		})
+	}
+}
+
+func TestIntentTestPhrase(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`/intent test undo the last change`, "undo the last change"},
+		{`/intent   test   annule  ça`, "annule  ça"},
+		{`/intent test "say \"hi\" to the team"`, `say "hi" to the team`},
+		{"/intent test `raw \\n text`", `raw \n text`},
+		{`/intent test 'run the tests'`, "run the tests"},
+		{`/intent test "unbalanced`, `"unbalanced`},
+		{`/intent test it's done`, "it's done"},
+		{`/intent test`, ""},
+		{`/intent test   `, ""},
+	}
+	for _, tt := range tests {
+		if got := intentTestPhrase(tt.raw); got != tt.want {
+			t.Errorf("intentTestPhrase(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
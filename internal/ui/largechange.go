@@ -0,0 +1,52 @@
+// Package ui - Guard against suspiciously large changes (e.g. truncated files)
+package ui
+
+import (
+	"fmt"
+
+	"github.com/hazyhaar/GoClode/internal/diff"
+)
+
+// minLargeChangeLines ignores small files, where any edit is a large share
+const minLargeChangeLines = 10
+
+// largeChangeWarnings lists reasons a batch of changes needs extra confirmation.
+// originals holds the current content of modified files; a threshold of 0 disables its check.
+func largeChangeWarnings(changes []FileChange, originals map[string]string, maxDeletePct, maxFiles int) []string {
+	warnings := make([]string, 0)
+
+	if maxFiles > 0 && len(changes) > maxFiles {
+		warnings = append(warnings, fmt.Sprintf("%d files touched (limit %d)", len(changes), maxFiles))
+	}
+
+	if maxDeletePct <= 0 {
+		return warnings
+	}
+	for _, ch := range changes {
+		if ch.Delete || ch.IsRename() {
+			continue
+		}
+		original, ok := originals[ch.Path]
+		if !ok {
+			continue
+		}
+
+		oldLines := diff.SplitLines(original)
+		if len(oldLines) < minLargeChangeLines {
+			continue
+		}
+
+		removed := 0
+		for _, op := range diff.Lines(oldLines, diff.SplitLines(ch.Content)) {
+			if op.Kind == diff.Delete {
+				removed++
+			}
+		}
+
+		pct := removed * 100 / len(oldLines)
+		if pct > maxDeletePct {
+			warnings = append(warnings, fmt.Sprintf("%s loses %d of %d lines (%d%%)", ch.Path, removed, len(oldLines), pct))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLargeChangeWarnings(t *testing.T) {
+	original := strings.Repeat("line\n", 20)
+	truncated := strings.Repeat("line\n", 5)
+	edited := strings.Repeat("line\n", 18) + "new\nnew\n"
+
+	tests := []struct {
+		name      string
+		changes   []FileChange
+		originals map[string]string
+		deletePct int
+		maxFiles  int
+		want      int
+	}{
+		{
+			name:      "truncated file",
+			changes:   []FileChange{{Path: "a.go", Content: truncated}},
+			originals: map[string]string{"a.go": original},
+			deletePct: 50,
+			want:      1,
+		},
+		{
+			name:      "small edit",
+			changes:   []FileChange{{Path: "a.go", Content: edited}},
+			originals: map[string]string{"a.go": original},
+			deletePct: 50,
+			want:      0,
+		},
+		{
+			name:      "small file ignored",
+			changes:   []FileChange{{Path: "a.go", Content: ""}},
+			originals: map[string]string{"a.go": "a\nb\n"},
+			deletePct: 50,
+			want:      0,
+		},
+		{
+			name:      "explicit delete ignored",
+			changes:   []FileChange{{Path: "a.go", Delete: true}},
+			originals: map[string]string{"a.go": original},
+			deletePct: 50,
+			want:      0,
+		},
+		{
+			name:      "too many files",
+			changes:   []FileChange{{Path: "a.go"}, {Path: "b.go"}, {Path: "c.go"}},
+			originals: map[string]string{},
+			maxFiles:  2,
+			want:      1,
+		},
+		{
+			name:      "disabled",
+			changes:   []FileChange{{Path: "a.go", Content: truncated}},
+			originals: map[string]string{"a.go": original},
+			want:      0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := largeChangeWarnings(tt.changes, tt.originals, tt.deletePct, tt.maxFiles)
+			if len(got) != tt.want {
+				t.Errorf("largeChangeWarnings() = %v, want %d warnings", got, tt.want)
+			}
+		})
+	}
+}
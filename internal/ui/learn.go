@@ -0,0 +1,73 @@
+// Package ui - /learn: auditing what the learning module has learned
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/modules"
+)
+
+const (
+	learnStatsLimit = 10
+	learnStatsDays  = 7
+)
+
+// handleLearn shows learning statistics: /learn [stats]
+func (c *Chat) handleLearn(args []string) error {
+	if len(args) > 0 && args[0] != "stats" {
+		return fmt.Errorf("usage: /learn stats")
+	}
+
+	stats, err := c.learning.Stats(learnStatsLimit, learnStatsDays)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n\033[33mLearning:\033[0m")
+	fmt.Printf("  %d learned intents, %d preferences, %d code patterns\n", stats.Intents, stats.Preferences, stats.Patterns)
+	if stats.Offered > 0 {
+		fmt.Printf("  Suggestions (last %d days): %d offered, %d accepted (%.0f%%)\n",
+			learnStatsDays, stats.Offered, stats.Accepted, 100*float64(stats.Accepted)/float64(stats.Offered))
+	}
+
+	fmt.Println("\n\033[33mTop intents:\033[0m")
+	if len(stats.TopIntents) == 0 {
+		fmt.Println("  \033[90m(none yet: type a command right after a request it should have understood)\033[0m")
+	}
+	for _, li := range stats.TopIntents {
+		fmt.Printf("  %-30s → %-12s \033[90mconf %.2f  hits %d/%d (%.0f%%)  %s\033[0m\n",
+			shorten(li.InputPattern, 30), li.DetectedIntent, li.Confidence,
+			li.SuccessCount, li.SuccessCount+li.FailureCount, 100*li.HitRate(), c.learning.ScopeLabel(li.Scope))
+	}
+
+	fmt.Println("\n\033[33mPreferences:\033[0m")
+	if len(stats.TopPrefs) == 0 {
+		fmt.Println("  \033[90m(none yet)\033[0m")
+	}
+	for _, p := range stats.TopPrefs {
+		scope, name := modules.SplitScopedKey(p.Key)
+		fmt.Printf("  %-30s %s \033[90mconf %.2f  %s\033[0m\n", shorten(name, 30), shorten(p.Value, 40), p.Confidence, c.learning.ScopeLabel(scope))
+	}
+
+	fmt.Printf("\n\033[33mRecent suggestions (last %d days):\033[0m\n", learnStatsDays)
+	if len(stats.RecentRoutes) == 0 {
+		fmt.Println("  \033[90m(none: learning has not changed any routing)\033[0m")
+	}
+	for _, r := range stats.RecentRoutes {
+		mark := "\033[32m✓\033[0m"
+		if !r.Accepted {
+			mark = "\033[31m✗\033[0m"
+		}
+		fmt.Printf("  %s %s  %-30s → %s\n", mark, r.At.Format(time.DateTime), shorten(r.Input, 30), r.Intent)
+	}
+	return nil
+}
+
+// shorten cuts s to n runes, marking the cut with an ellipsis
+func shorten(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+// Package ui - /log: commits made during this session
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// showLog lists the session's commits, or shows one commit's diff with "/log <hash>"
+func (c *Chat) showLog(args []string) error {
+	if !c.git.IsRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	commits, err := c.session.SessionCommits()
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		hash := args[0]
+		if strings.HasPrefix(hash, "-") {
+			return fmt.Errorf("invalid commit %q", hash)
+		}
+		for _, commit := range commits {
+			if strings.HasPrefix(commit.Hash, hash) {
+				hash = commit.Hash
+				break
+			}
+		}
+		out, err := c.git.Show(hash)
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+		return nil
+	}
+
+	if len(commits) == 0 {
+		fmt.Println("\033[90mNo commits in this session\033[0m")
+		return nil
+	}
+
+	fmt.Println("\n\033[33mSession commits:\033[0m")
+	for _, commit := range commits {
+		state := ""
+		switch {
+		case commit.RevertHash != "":
+			state = fmt.Sprintf(" \033[33m(undone by %s)\033[0m", shortHash(commit.RevertHash))
+		case !c.git.IsAncestor(commit.Hash):
+			state = " \033[90m(not on this branch)\033[0m"
+		}
+
+		fmt.Printf("  \033[36m%s\033[0m %s %s \033[90m(%d files)\033[0m%s\n",
+			shortHash(commit.Hash), commit.CreatedAt.Format("15:04:05"),
+			firstLine(commit.Message), commit.FilesChanged, state)
+	}
+	fmt.Println("\033[90mUse /log <hash> to show a commit's diff\033[0m")
+	return nil
+}
+
+// shortHash abbreviates a commit hash for display
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
@@ -0,0 +1,120 @@
+// Package ui - Language server diagnostics for applied changes
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/lsp"
+)
+
+// lspLanguageIDs maps extensions to LSP language identifiers
+var lspLanguageIDs = map[string]string{
+	".go": "go", ".py": "python", ".rs": "rust", ".js": "javascript",
+	".jsx": "javascriptreact", ".ts": "typescript", ".tsx": "typescriptreact",
+	".java": "java", ".rb": "ruby", ".c": "c", ".cpp": "cpp",
+}
+
+func lspLanguageID(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if id, ok := lspLanguageIDs[ext]; ok {
+		return id
+	}
+	return strings.TrimPrefix(ext, ".")
+}
+
+// lspServers returns the configured server command per extension
+func (c *Chat) lspServers() map[string]string {
+	raw, _ := c.engine.GetConfig("lsp_servers")
+	servers := make(map[string]string)
+	if err := json.Unmarshal([]byte(raw), &servers); err != nil && raw != "" {
+		core.Logger("lsp").Warn("Invalid lsp_servers", "error", err)
+	}
+	return servers
+}
+
+// lspClient returns the running server for an extension, starting it on
+// first use. A server that fails to start is not retried this session.
+func (c *Chat) lspClient(ext, command string) *lsp.Client {
+	if client, ok := c.lspClients[ext]; ok {
+		return client
+	}
+	if c.lspClients == nil {
+		c.lspClients = make(map[string]*lsp.Client)
+	}
+
+	fmt.Printf("\033[90m🩺 Starting %s\033[0m\n", command)
+	client, err := lsp.Start(c.ctx, strings.Fields(command), c.git.WorkDir())
+	if err != nil {
+		core.Logger("lsp").Warn("Language server not started", "command", command, "error", err)
+	}
+	c.lspClients[ext] = client // nil after a failure
+	return client
+}
+
+// lspDiagnostics sends changed files to their language servers (lsp_enabled)
+// and returns the errors they report, formatted for an auto-fix round.
+// Warnings are shown but not sent back.
+func (c *Chat) lspDiagnostics(files []string) string {
+	if !c.engine.GetConfigBool("lsp_enabled") {
+		return ""
+	}
+	servers := c.lspServers()
+	timeout := time.Duration(c.engine.GetConfigInt("lsp_timeout")) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	byExt := make(map[string]map[string]string)
+	for _, path := range files {
+		ext := strings.ToLower(filepath.Ext(path))
+		if servers[ext] == "" {
+			continue
+		}
+		if byExt[ext] == nil {
+			byExt[ext] = make(map[string]string)
+		}
+		data, _ := os.ReadFile(path)
+		byExt[ext][path] = string(data) // "" closes deleted files
+	}
+
+	var errs []string
+	for ext, contents := range byExt {
+		client := c.lspClient(ext, servers[ext])
+		if client == nil {
+			continue
+		}
+		diags, err := client.Diagnostics(c.ctx, contents, lspLanguageID, timeout)
+		if err != nil {
+			fmt.Printf("\033[33m⚠️  %s: %v\033[0m\n", servers[ext], err)
+			continue
+		}
+
+		if warnings := lsp.Format(diags, lsp.SeverityWarning); warnings != "" {
+			if report := lsp.Format(diags, lsp.SeverityError); report != "" {
+				errs = append(errs, report)
+			}
+			fmt.Printf("\033[33m🩺 %s:\033[0m\n  \033[90m%s\033[0m\n", servers[ext], strings.ReplaceAll(warnings, "\n", "\n  "))
+		} else {
+			fmt.Printf("\033[32m✓ %s\033[0m\n", servers[ext])
+		}
+	}
+	if len(errs) == 0 {
+		return ""
+	}
+	return "Language server diagnostics:\n" + strings.Join(errs, "\n")
+}
+
+// closeLSP shuts down the language servers started this session
+func (c *Chat) closeLSP() {
+	for _, client := range c.lspClients {
+		if client != nil {
+			client.Close()
+		}
+	}
+}
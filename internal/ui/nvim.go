@@ -0,0 +1,370 @@
+// Package ui - Editor protocol over TCP and WebSocket, for editors and
+// browsers that connect to a long-running GoClode (goclode --listen), such
+// as the Neovim plugin
+package ui
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/websocket"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// maxRangeLines caps the lines of one buffer range sent as context
+const maxRangeLines = 2000
+
+// bufferRange is a selection in an editor buffer, attached to chat/send.
+// Text is the buffer's content, which may not be saved yet; without it the
+// lines are read from disk.
+type bufferRange struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"` // 1-based, inclusive
+	EndLine   int    `json:"end_line"`   // Inclusive; 0 means the end of the file
+	Text      string `json:"text"`
+}
+
+// rangeContext formats buffer ranges for the user message, like @file
+// mentions
+func (c *Chat) rangeContext(ranges []bufferRange) (string, error) {
+	var sb strings.Builder
+	for _, r := range ranges {
+		path, err := c.readablePath(r.Path)
+		if err != nil {
+			return "", err
+		}
+		start := r.StartLine
+		if start < 1 {
+			start = 1
+		}
+		if r.EndLine != 0 && r.EndLine < start {
+			return "", fmt.Errorf("%s: range %d-%d ends before it starts", r.Path, r.StartLine, r.EndLine)
+		}
+
+		text := r.Text
+		if text == "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			lines := strings.Split(string(data), "\n")
+			end := r.EndLine
+			if end == 0 || end > len(lines) {
+				end = len(lines)
+			}
+			if start > end {
+				return "", fmt.Errorf("%s has %d lines", r.Path, len(lines))
+			}
+			text = strings.Join(lines[start-1:end], "\n")
+		}
+		if lines := strings.Split(text, "\n"); len(lines) > maxRangeLines {
+			text = strings.Join(lines[:maxRangeLines], "\n") + "\n... (truncated)"
+		}
+
+		lines := fmt.Sprintf("from line %d", start)
+		if r.EndLine != 0 {
+			lines = fmt.Sprintf("lines %d-%d", start, r.EndLine)
+		}
+		fmt.Fprintf(&sb, "\n\n**File: %s (%s)**\n```\n%s\n```", r.Path, lines, text)
+	}
+	return sb.String(), nil
+}
+
+// ServeTCP serves the editor protocol of ServeStdio on addr, which must be
+// a loopback address (":7777" means 127.0.0.1:7777), over a single session.
+// Connections come and go: a turn keeps running when the one that asked
+// for it closes, and task/attach replays and follows it, which is how
+// goclode attach reconnects. It stops when a client sends exit, or on
+// interrupt unless detached: then only SIGTERM stops it, and closing the
+// terminal does not. Neovim can connect with vim.lsp.rpc.connect, which
+// speaks the same framing.
+//
+// Every connection must first send initialize with a "token" param: the
+// one in GOCLODE_LISTEN_TOKEN at startup, or else a random one, written
+// to .goclode/listen-<port>.token where only the user can read it. That
+// token makes the client the owner; each name in listen_users gets its
+// own in .goclode/listen-users, kept across restarts. Each client has its
+// own session, learning, and pending changes, and sees only its own turns;
+// a <name>.config file next to its token layers its settings over the
+// project's (like the repository's files, without trusted keys).
+//
+// Browsers connect with a WebSocket to ws://localhost:<port>/ws on the same
+// port: each text message is one JSON-RPC message, the token goes in
+// initialize all the same, and pages from other sites are refused.
+func (c *Chat) ServeTCP(addr string, detach bool) error {
+	addr, err := loopbackAddr(addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	defer ln.Close()
+
+	token := os.Getenv(listenTokenEnv)
+	os.Unsetenv(listenTokenEnv) // Not for the commands the session runs
+	if token == "" {
+		if token, err = newListenToken(); err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+	}
+	// The bound address: with port 0, the port the system picked
+	tokenPath := listenTokenPath(ln.Addr().String())
+	if err := writeListenToken(tokenPath, token); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	defer os.Remove(tokenPath)
+	tokens, err := c.listenUserTokens()
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	tokens[token] = listenOwner
+
+	defer c.recoverCrash()
+	if _, err := c.startSession(); err != nil {
+		return err
+	}
+	if err := c.session.BindUser(listenOwner); err != nil {
+		return err
+	}
+
+	stopSignals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	if detach {
+		signal.Ignore(os.Interrupt, syscall.SIGHUP)
+		stopSignals = []os.Signal{syscall.SIGTERM}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, stopSignals...)
+	defer signal.Stop(signals)
+	go func() {
+		if _, ok := <-signals; ok {
+			ln.Close()
+		}
+	}()
+
+	s := newStdioServer(c)
+	s.tokens = tokens
+	s.client = listenOwner
+	var mu sync.Mutex
+	conns := make(map[net.Conn]bool)
+	var wg sync.WaitGroup
+
+	fmt.Printf("\033[32m🔌 Listening for editors on %s (token in %s)\033[0m\n", ln.Addr(), tokenPath)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			break // Interrupted, or a client sent exit
+		}
+		fmt.Printf("\033[90mClient connected from %s\033[0m\n", conn.RemoteAddr())
+		mu.Lock()
+		conns[conn] = true
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			exit, err := s.serveConn(conn)
+			conn.Close()
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+			if err != nil {
+				fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+			}
+			if exit {
+				ln.Close()
+				return
+			}
+			fmt.Printf("\033[90mClient disconnected\033[0m\n")
+		}()
+	}
+
+	// Hang up on the other clients, then let a running turn finish
+	mu.Lock()
+	for conn := range conns {
+		conn.Close()
+	}
+	mu.Unlock()
+	wg.Wait()
+
+	s.close()
+	c.shutdown()
+	return nil
+}
+
+// listenTokenEnv gives a --listen server its token; goclode attach sets it
+// for the servers it starts
+const listenTokenEnv = "GOCLODE_LISTEN_TOKEN"
+
+// serveConn serves a connection in LSP framing, or over a WebSocket when
+// it opens with an HTTP request
+func (s *stdioServer) serveConn(conn net.Conn) (bool, error) {
+	r := bufio.NewReader(conn)
+	if head, err := r.Peek(4); err != nil || string(head) != "GET " {
+		return s.serve(r, conn)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(rpcWriteTimeout))
+	req, err := http.ReadRequest(r)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		return false, fmt.Errorf("websocket: %w", err)
+	}
+	if status, reason := wsRefusal(req); status != 0 {
+		websocket.Refuse(conn, status, reason)
+		return false, fmt.Errorf("websocket: %s", reason)
+	}
+	ws, err := websocket.Accept(conn, r, req)
+	if err != nil {
+		return false, err
+	}
+	defer ws.Close()
+	return s.serveWebSocket(ws)
+}
+
+// wsRefusal returns why a WebSocket request is refused, or a zero status.
+// A page can point its own domain at 127.0.0.1 (DNS rebinding), and any
+// page can open a WebSocket to localhost, so both Host and Origin, which
+// browsers always send, must be local.
+func wsRefusal(req *http.Request) (int, string) {
+	if req.URL.Path != "/ws" {
+		return http.StatusNotFound, "the WebSocket endpoint is /ws"
+	}
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+	}
+	if !isLoopbackHost(strings.Trim(host, "[]")) {
+		return http.StatusForbidden, "forbidden host"
+	}
+	if origin := req.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !isLoopbackHost(u.Hostname()) {
+			return http.StatusForbidden, "forbidden origin " + origin
+		}
+	}
+	if !websocket.IsUpgrade(req) {
+		return http.StatusBadRequest, "not a WebSocket upgrade"
+	}
+	return 0, ""
+}
+
+// listenOwner names the clients holding the server's own token
+const listenOwner = "owner"
+
+// listenUserName is what a listen_users name may be: it names a file
+var listenUserName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// listenUsersDir holds the tokens of listen_users, in the workspace
+var listenUsersDir = filepath.Join(".goclode", "listen-users")
+
+// listenUserTokens maps the token of each name in listen_users to it,
+// creating the tokens that do not exist yet
+func (c *Chat) listenUserTokens() (map[string]string, error) {
+	raw, _ := c.engine.GetConfig("listen_users")
+	tokens := make(map[string]string)
+	for _, name := range workspace.ParsePatternList(raw) {
+		if !listenUserName.MatchString(name) || name == listenOwner {
+			return nil, fmt.Errorf("listen_users: %q is not a valid name", name)
+		}
+		path := filepath.Join(listenUsersDir, name+".token")
+		data, err := os.ReadFile(path)
+		token := strings.TrimSpace(string(data))
+		switch {
+		case err == nil && token != "":
+			if err := os.Chmod(path, 0o600); err != nil {
+				return nil, err
+			}
+		case err == nil || os.IsNotExist(err):
+			if token, err = newListenToken(); err != nil {
+				return nil, err
+			}
+			if err := writeListenToken(path, token); err != nil {
+				return nil, err
+			}
+			fmt.Printf("\033[90mCreated the token of %s in %s\033[0m\n", name, path)
+		default:
+			return nil, err
+		}
+		if _, dup := tokens[token]; dup {
+			return nil, fmt.Errorf("listen_users: %s shares its token with another user", name)
+		}
+		tokens[token] = name
+	}
+	return tokens, nil
+}
+
+// listenUserConfigPath is the config file of a listen_users name
+func listenUserConfigPath(name string) string {
+	return filepath.Join(listenUsersDir, name+".config")
+}
+
+// listenTokenPath is where the --listen server on addr writes its token
+func listenTokenPath(addr string) string {
+	_, port, _ := net.SplitHostPort(addr)
+	return filepath.Join(".goclode", "listen-"+port+".token")
+}
+
+// newListenToken returns a random token
+func newListenToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// writeListenToken writes a token only its owner can read, even over an
+// older file left with other permissions
+func writeListenToken(path, token string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	os.Remove(path)
+	return os.WriteFile(path, []byte(token+"\n"), 0o600)
+}
+
+// readListenToken reads the token of the --listen server on addr
+func readListenToken(addr string) (string, error) {
+	data, err := os.ReadFile(listenTokenPath(addr))
+	if err != nil {
+		return "", fmt.Errorf("read the server's token: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// loopbackAddr checks that addr is a loopback address, defaulting its
+// host to 127.0.0.1
+func loopbackAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if !isLoopbackHost(host) {
+		return "", fmt.Errorf("%s is not a localhost address", host)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// isLoopbackHost reports whether host is localhost or a loopback address
+func isLoopbackHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
@@ -0,0 +1,198 @@
+// Package ui - Offline mode: when the provider cannot be reached, requests
+// are queued in pending_requests while local commands keep working, and
+// sent once it answers again; /queue shows and manages the queue
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+// errOffline marks a request not sent because no provider is reachable
+var errOffline = errors.New("offline")
+
+// pingTimeout bounds a reachability check
+const pingTimeout = 10 * time.Second
+
+// isOffline reports whether requests are being queued
+func (c *Chat) isOffline() bool {
+	c.offlineMu.Lock()
+	defer c.offlineMu.Unlock()
+	return c.offline
+}
+
+// goOffline enters offline mode after the provider could not be reached,
+// and starts checking for it to come back
+func (c *Chat) goOffline(cause error) {
+	c.offlineMu.Lock()
+	already := c.offline
+	c.offline, c.replayDue = true, false
+	c.offlineMu.Unlock()
+	if already {
+		return
+	}
+	fmt.Printf("\n\033[33m📴 Offline: the provider cannot be reached (%v)\033[0m\n", cause)
+	fmt.Println("\033[90m   Requests are queued (/queue) and sent when it answers again; local commands still work\033[0m")
+	c.goSafe(c.watchConnectivity)
+}
+
+// goOnline leaves offline mode; queued requests are sent before the next
+// prompt
+func (c *Chat) goOnline() {
+	c.offlineMu.Lock()
+	defer c.offlineMu.Unlock()
+	c.offline, c.replayDue = false, true
+}
+
+// watchConnectivity pings the current provider every
+// offline_probe_interval seconds until it answers
+func (c *Chat) watchConnectivity() {
+	interval := time.Duration(c.engine.GetConfigInt("offline_probe_interval")) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !c.isOffline() {
+			return // /queue run went back online
+		}
+		if err := c.ping(); providers.IsUnreachable(err) {
+			continue
+		}
+		c.goOnline()
+		pending, _ := c.session.PendingRequests()
+		fmt.Printf("\n\033[32m📶 Back online: %d queued requests are sent at the next prompt (press Enter)\033[0m\n", len(pending))
+		return
+	}
+}
+
+// ping checks the current provider can be reached; providers that cannot
+// be pinged are assumed to be
+func (c *Chat) ping() error {
+	pinger, ok := c.registry.Current().(providers.Pinger)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, pingTimeout)
+	defer cancel()
+	return pinger.Ping(ctx)
+}
+
+// resumeQueue picks up the requests an earlier launch queued: they are
+// sent before the first prompt if the provider answers, else once it does
+func (c *Chat) resumeQueue() {
+	if err := c.ping(); providers.IsUnreachable(err) {
+		c.goOffline(err)
+		return
+	}
+	c.goOnline()
+}
+
+// queueRequest queues a prompt for when the provider answers again
+func (c *Chat) queueRequest(prompt string) error {
+	if c.replaying {
+		c.requeued = true
+		fmt.Println("\033[33m📥 Offline again: the request stays queued\033[0m")
+		return nil
+	}
+	n, err := c.session.QueueRequest(prompt)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\033[33m📥 Queued while offline (%d pending, /queue)\033[0m\n", n)
+	return nil
+}
+
+// replayQueue sends the queued requests, oldest first, once back online.
+// It stops if the provider is lost again; the rest stay queued.
+func (c *Chat) replayQueue() {
+	c.offlineMu.Lock()
+	due := c.replayDue
+	c.replayDue = false
+	c.offlineMu.Unlock()
+	if !due {
+		return
+	}
+
+	pending, err := c.session.PendingRequests()
+	if err != nil {
+		fmt.Printf("\033[31mError: %v\033[0m\n", err)
+		return
+	}
+	for i, r := range pending {
+		if c.isOffline() {
+			return
+		}
+		fmt.Printf("\033[36m↻ Queued request %d/%d: %s\033[0m\n", i+1, len(pending), r.Prompt)
+		if intent := c.parser.Parse(r.Prompt); intent != nil {
+			c.replaying, c.requeued = true, false
+			c.setInFlight(r.Prompt)
+			if err := c.handleIntent(intent); err != nil {
+				fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			}
+			c.setInFlight("")
+			c.replaying = false
+			if c.requeued {
+				return
+			}
+		}
+		if err := c.session.RemovePendingRequest(r.ID); err != nil {
+			fmt.Printf("\033[31mError: %v\033[0m\n", err)
+			return
+		}
+	}
+}
+
+// handleQueue shows or manages the queued requests: /queue [run | clear]
+func (c *Chat) handleQueue(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "run":
+			if err := c.ping(); providers.IsUnreachable(err) {
+				return fmt.Errorf("still offline: %v", err)
+			}
+			c.goOnline()
+			c.replayQueue()
+			return nil
+		case "clear":
+			n, err := c.session.ClearPendingRequests()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\033[32m✓ Dropped %d queued requests\033[0m\n", n)
+			return nil
+		default:
+			return fmt.Errorf("usage: /queue [run | clear]")
+		}
+	}
+
+	pending, err := c.session.PendingRequests()
+	if err != nil {
+		return err
+	}
+	state := "online"
+	if c.isOffline() {
+		state = "offline"
+	}
+	fmt.Printf("\n\033[33mQueued requests (%s):\033[0m\n", state)
+	if len(pending) == 0 {
+		fmt.Println("  \033[90m(none)\033[0m")
+		return nil
+	}
+	for _, r := range pending {
+		fmt.Printf("  %s  %s\n", r.CreatedAt.Format("15:04:05"), r.Prompt)
+	}
+	fmt.Println("\033[90m/queue run sends them now; /queue clear drops them\033[0m")
+	return nil
+}
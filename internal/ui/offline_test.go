@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+func TestOfflineQueue(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+	sm := session.NewManager(engine)
+	if _, err := sm.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	c := &Chat{engine: engine, session: sm, offline: true}
+
+	if err := c.handleChat(&Intent{Type: IntentCode, Raw: "add a test"}); err != nil {
+		t.Fatalf("handleChat: %v", err)
+	}
+	pending, _ := sm.PendingRequests()
+	if len(pending) != 1 || pending[0].Prompt != "add a test" {
+		t.Fatalf("Expected the request queued, got %+v", pending)
+	}
+	if msgs, _ := sm.GetMessages(10); len(msgs) != 0 {
+		t.Errorf("Expected nothing recorded for a queued request, got %d messages", len(msgs))
+	}
+
+	if _, err := c.streamResponse(nil, nil, nil); !errors.Is(err, errOffline) {
+		t.Errorf("Expected LLM calls refused while offline, got %v", err)
+	}
+
+	// A queued request that fails again keeps its place
+	c.replaying = true
+	c.queueRequest("add a test")
+	if pending, _ := sm.PendingRequests(); len(pending) != 1 || !c.requeued {
+		t.Errorf("Expected no second copy, got %+v", pending)
+	}
+
+	c.replaying = false
+	c.handleQueue([]string{"clear"})
+	if pending, _ := sm.PendingRequests(); len(pending) != 0 {
+		t.Errorf("Expected /queue clear to empty the queue, got %+v", pending)
+	}
+}
+
+func TestResumeQueue(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+	sm := session.NewManager(engine)
+	sm.Create("cerebras")
+	sm.QueueRequest("add a test") // Left by an earlier launch
+
+	c := &Chat{engine: engine, session: sm, registry: &providers.Registry{}}
+	c.resumeQueue()
+	if c.isOffline() || !c.replayDue {
+		t.Errorf("Expected the queue to be sent before the first prompt, offline %v, due %v", c.offline, c.replayDue)
+	}
+}
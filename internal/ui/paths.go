@@ -0,0 +1,152 @@
+// Package ui - Workspace path safety for LLM-proposed file changes
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Path policies (config key: path_policy)
+const (
+	PathPolicyReject = "reject" // Refuse paths outside the workspace (default)
+	PathPolicyReroot = "reroot" // Move outside paths back under the workspace
+	PathPolicyAllow  = "allow"  // Explicit override: write anywhere
+)
+
+// sanitizePath validates a proposed path against the workspace root and
+// returns a clean path relative to it (or absolute, under PathPolicyAllow).
+func sanitizePath(root, path, policy string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("empty path")
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("resolve workspace: %w", err)
+	}
+
+	// LLMs emit Windows-style separators regardless of the host OS
+	target := filepath.FromSlash(strings.ReplaceAll(path, `\`, "/"))
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(root, target)
+	}
+	target = filepath.Clean(target)
+
+	rel, err := filepath.Rel(root, target)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		if rel == "." {
+			return "", fmt.Errorf("%s: refers to the workspace root", path)
+		}
+		return rel, nil
+	}
+
+	switch policy {
+	case PathPolicyAllow:
+		return target, nil
+	case PathPolicyReroot:
+		return rerootPath(path)
+	default:
+		return "", fmt.Errorf("%s: outside the workspace (set path_policy to %q or %q to override)",
+			path, PathPolicyReroot, PathPolicyAllow)
+	}
+}
+
+// rerootPath strips volume, root, and parent segments so the path lands
+// inside the workspace: "../../etc/x" -> "etc/x", "/tmp/y" -> "tmp/y".
+func rerootPath(path string) (string, error) {
+	p := filepath.FromSlash(path)
+	p = strings.TrimPrefix(p, filepath.VolumeName(p))
+
+	parts := make([]string, 0)
+	for _, part := range strings.Split(filepath.ToSlash(p), "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(parts) > 0 {
+				parts = parts[:len(parts)-1]
+			}
+		default:
+			parts = append(parts, part)
+		}
+	}
+
+	if len(parts) == 0 {
+		return "", fmt.Errorf("%s: nothing left after re-rooting", path)
+	}
+	return filepath.Join(parts...), nil
+}
+
+// ensureDir creates the parent directories of path
+func ensureDir(path string) error {
+	dir := filepath.Dir(path)
+	if dir == "." || dir == string(filepath.Separator) {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// symlinkRisk explains why writing path needs confirmation: a symlink on the
+// way that leads outside the workspace, or a broken link. followFile also
+// resolves the final element (writes follow it; deletes and renames act on
+// the link itself). Returns "" when the path is safe.
+func symlinkRisk(root, path string, followFile bool) string {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return ""
+	}
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		realRoot = absRoot
+	}
+
+	target := path
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(absRoot, target)
+	}
+	if !followFile {
+		target = filepath.Dir(target)
+	}
+
+	resolved, err := resolveExisting(target)
+	if err != nil {
+		return fmt.Sprintf("%s goes through a broken symlink", path)
+	}
+
+	rel, err := filepath.Rel(realRoot, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Sprintf("%s resolves to %s, outside the workspace", path, resolved)
+	}
+	return ""
+}
+
+// isSymlink reports whether path itself is a symbolic link
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// resolveExisting resolves symlinks in the longest existing prefix of path
+// and appends the remaining (not yet created) elements
+func resolveExisting(path string) (string, error) {
+	rest := ""
+	for p := filepath.Clean(path); ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); err == nil {
+			resolved, err := filepath.EvalSymlinks(p)
+			if err != nil {
+				return "", err
+			}
+			return filepath.Join(resolved, rest), nil
+		}
+
+		if filepath.Dir(p) == p {
+			return path, nil
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+	}
+}
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSanitizePath(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name    string
+		path    string
+		policy  string
+		want    string
+		wantErr bool
+	}{
+		{"relative", "pkg/main.go", PathPolicyReject, filepath.Join("pkg", "main.go"), false},
+		{"dotted inside", "./pkg/../main.go", PathPolicyReject, "main.go", false},
+		{"absolute inside", filepath.Join(root, "a.go"), PathPolicyReject, "a.go", false},
+		{"traversal rejected", "../../etc/passwd", PathPolicyReject, "", true},
+		{"absolute rejected", "/etc/passwd", PathPolicyReject, "", true},
+		{"default policy rejects", "../x.go", "", "", true},
+		{"traversal rerooted", "../../etc/passwd", PathPolicyReroot, filepath.Join("etc", "passwd"), false},
+		{"absolute rerooted", "/tmp/x.go", PathPolicyReroot, filepath.Join("tmp", "x.go"), false},
+		{"windows separators", `pkg\sub\main.go`, PathPolicyReject, filepath.Join("pkg", "sub", "main.go"), false},
+		{"windows traversal rejected", `..\..\secret.txt`, PathPolicyReject, "", true},
+		{"root itself", ".", PathPolicyReject, "", true},
+		{"empty", "", PathPolicyReject, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sanitizePath(root, tt.path, tt.policy)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sanitizePath failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The allow policy keeps the absolute target
+	got, err := sanitizePath(root, "/etc/passwd", PathPolicyAllow)
+	if err != nil || got != "/etc/passwd" {
+		t.Errorf("allow policy: got %q, %v", got, err)
+	}
+}
+
+func TestEnsureDir(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name string
+		path string
+		dir  string // Expected directory, relative to root; "" if none is created
+	}{
+		{"top level file", "main.go", ""},
+		{"nested", filepath.Join("a", "b", "c", "main.go"), filepath.Join("a", "b", "c")},
+		{"dotted relative", filepath.Join(".", "x", "..", "y", "main.go"), "y"},
+		{"dot directory", filepath.Join(".config", "app.yaml"), ".config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ensureDir(filepath.Join(root, tt.path)); err != nil {
+				t.Fatalf("ensureDir failed: %v", err)
+			}
+			if tt.dir == "" {
+				return
+			}
+			info, err := os.Stat(filepath.Join(root, tt.dir))
+			if err != nil || !info.IsDir() {
+				t.Errorf("%s not created: %v", tt.dir, err)
+			}
+		})
+	}
+
+	// Relative paths without a directory are a no-op
+	if err := ensureDir("main.go"); err != nil {
+		t.Errorf("ensureDir(main.go) = %v", err)
+	}
+
+	// Windows-style paths are usable once sanitized
+	path, err := sanitizePath(root, `src\pkg\util.go`, PathPolicyReject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ensureDir(filepath.Join(root, path)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "src", "pkg")); err != nil {
+		t.Errorf("src/pkg not created: %v", err)
+	}
+}
+
+func TestSymlinkRisk(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	os.MkdirAll(filepath.Join(root, "pkg"), 0755)
+	os.WriteFile(filepath.Join(root, "pkg", "real.go"), []byte("package pkg\n"), 0644)
+	os.WriteFile(filepath.Join(outside, "secret"), []byte("x"), 0644)
+
+	links := map[string]string{
+		"inside.go":  filepath.Join(root, "pkg", "real.go"),
+		"escape.txt": filepath.Join(outside, "secret"),
+		"linkdir":    outside,
+		"broken":     filepath.Join(root, "missing", "x"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		followFile bool
+		risky      bool
+	}{
+		{"regular file", filepath.Join("pkg", "real.go"), true, false},
+		{"new file", filepath.Join("pkg", "new", "a.go"), true, false},
+		{"link inside", "inside.go", true, false},
+		{"link escaping", "escape.txt", true, true},
+		{"link escaping, not followed", "escape.txt", false, false},
+		{"directory link escaping", filepath.Join("linkdir", "new.go"), true, true},
+		{"broken link", "broken", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := symlinkRisk(root, tt.path, tt.followFile)
+			if (got != "") != tt.risky {
+				t.Errorf("symlinkRisk(%s) = %q, want risky=%v", tt.path, got, tt.risky)
+			}
+		})
+	}
+
+	if !isSymlink(filepath.Join(root, "inside.go")) || isSymlink(filepath.Join(root, "pkg", "real.go")) {
+		t.Error("isSymlink misreports links")
+	}
+}
@@ -0,0 +1,80 @@
+// Package ui - /permissions: review and edit remembered tool decisions
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/tools"
+)
+
+// ruleLabel describes what a permission rule covers
+func ruleLabel(tool, pattern string) string {
+	if pattern == "" {
+		return tool
+	}
+	return fmt.Sprintf("%s %q", tool, pattern)
+}
+
+// handlePermissions lists rules, or edits them:
+// /permissions allow|deny|ask <tool> [pattern], /permissions rm <id>
+func (c *Chat) handlePermissions(args []string) error {
+	if len(args) == 0 {
+		rules, err := c.permissions.Rules()
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n\033[33mTool permissions:\033[0m")
+		if len(rules) == 0 {
+			fmt.Println("  \033[90m(none)\033[0m")
+		}
+		for _, r := range rules {
+			color := "32"
+			switch r.Decision {
+			case tools.PermDeny:
+				color = "31"
+			case tools.PermAsk:
+				color = "33"
+			}
+			fmt.Printf("  \033[90m%3d\033[0m \033[%sm%-5s\033[0m %s\n", r.ID, color, r.Decision, ruleLabel(r.Tool, r.Pattern))
+		}
+		fmt.Println("\033[90mrun_command falls back to command_allowlist, then asks; other tools run unless a rule says otherwise\033[0m")
+		return nil
+	}
+
+	switch args[0] {
+	case tools.PermAllow, tools.PermDeny, tools.PermAsk:
+		if len(args) < 2 {
+			return fmt.Errorf("usage: /permissions %s <tool> [command pattern]", args[0])
+		}
+		tool := args[1]
+		if _, ok := c.tools.Get(tool); !ok {
+			return fmt.Errorf("unknown tool %q", tool)
+		}
+		pattern := strings.Trim(strings.Join(args[2:], " "), `"'`)
+		if pattern != "" && tool != tools.RunCommandTool {
+			return fmt.Errorf("patterns only apply to %s", tools.RunCommandTool)
+		}
+		if err := c.permissions.Set(tool, pattern, args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("\033[32m✓ %s %s\033[0m\n", args[0], ruleLabel(tool, pattern))
+		return nil
+
+	case "rm", "remove", "delete":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: /permissions rm <id>")
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid rule id %q", args[1])
+		}
+		if err := c.permissions.Remove(id); err != nil {
+			return err
+		}
+		fmt.Printf("\033[32m✓ Removed rule %d\033[0m\n", id)
+		return nil
+	}
+	return fmt.Errorf("usage: /permissions [allow|deny|ask <tool> [pattern] | rm <id>]")
+}
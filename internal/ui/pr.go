@@ -0,0 +1,84 @@
+// Package ui - Pull/merge requests through the detected forge
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/git"
+)
+
+// handlePR pushes the current branch and opens a pull request (merge request
+// on GitLab) against the remote's default branch
+func (c *Chat) handlePR(args []string) error {
+	if !c.git.IsRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	remoteName := c.remoteName()
+	rawURL, err := c.git.RemoteURL(remoteName)
+	if err != nil {
+		return fmt.Errorf("remote %s: %w", remoteName, err)
+	}
+	remote, err := git.ParseRemoteURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	forgeType, _ := c.engine.GetConfig("forge_type")
+	if forgeType == "" {
+		forgeType = git.DetectForgeType(remote)
+	}
+	forge, err := git.NewForge(remote, forgeType, c.forgeToken(forgeType))
+	if err != nil {
+		return err
+	}
+
+	head, err := c.git.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	base := c.git.DefaultBranch(remoteName)
+	if head == base {
+		return fmt.Errorf("on %s already; create a branch for the changes first", base)
+	}
+
+	subjects, _ := c.git.Subjects(remoteName + "/" + base + "..HEAD")
+	title := strings.Join(args, " ")
+	if title == "" && len(subjects) > 0 {
+		title = subjects[0]
+	}
+	if title == "" {
+		title = head
+	}
+
+	var body strings.Builder
+	for _, s := range subjects {
+		fmt.Fprintf(&body, "- %s\n", s)
+	}
+
+	if err := c.pushBranch(remoteName, head); err != nil {
+		return err
+	}
+
+	url, err := forge.CreatePullRequest(c.ctx, git.PullRequest{
+		Title: title,
+		Body:  body.String(),
+		Head:  head,
+		Base:  base,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", forge.Name(), err)
+	}
+
+	fmt.Printf("\033[32m✓ Opened %s → %s: %s\033[0m\n", head, base, url)
+	return nil
+}
+
+// forgeToken reads <forge>_token from config, falling back to the environment
+func (c *Chat) forgeToken(forgeType string) string {
+	if forgeType == "" {
+		return ""
+	}
+	return c.configSecret(forgeType + "_token")
+}
@@ -0,0 +1,268 @@
+// Package ui - /prompt: listing, editing, and sending the templates of
+// the prompts table
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/prompt"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// promptFilePattern matches a file argument: @path or @path:start-end
+var promptFilePattern = regexp.MustCompile(`^@([^:]+)(?::(\d+)(?:-(\d+))?)?$`)
+
+// promptArgs are the values /prompt use was given
+type promptArgs struct {
+	vars  map[string]string // name=value and name=@file
+	file  string            // A bare @file: its path, with any :start-end
+	input string            // The other words
+}
+
+// parsePromptArgs splits /prompt use arguments into name=value pairs, a
+// bare @file, and free text
+func parsePromptArgs(args []string) promptArgs {
+	pa := promptArgs{vars: make(map[string]string)}
+	words := make([]string, 0)
+	for _, arg := range args {
+		if name, value, ok := strings.Cut(arg, "="); ok && name != "" && !strings.ContainsAny(name, " @") {
+			pa.vars[name] = value
+			continue
+		}
+		if pa.file == "" && promptFilePattern.MatchString(arg) {
+			pa.file = arg
+			continue
+		}
+		words = append(words, arg)
+	}
+	pa.input = strings.Join(words, " ")
+	return pa
+}
+
+// handlePrompt runs /prompt [list | show <name> | edit <name> [template] |
+// use <name> [args]]
+func (c *Chat) handlePrompt(args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		return c.listPrompts()
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("usage: /prompt [list | show <name> | edit <name> [template] | use <name> [name=value | name=@file | @file[:start-end] | text]...]")
+	}
+
+	switch args[0] {
+	case "show":
+		p, err := c.session.GetPrompt(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\033[33m%s\033[0m (%s, v%d) %s\n%s\n", p.ID, p.Category, p.Version, p.Name, p.Template)
+		return nil
+
+	case "edit":
+		template := strings.Join(args[2:], " ")
+		if template == "" {
+			current := ""
+			if p, err := c.session.GetPrompt(args[1]); err == nil {
+				current = p.Template
+			}
+			edited, err := editText(current)
+			if err != nil {
+				return err
+			}
+			if edited == current || strings.TrimSpace(edited) == "" {
+				fmt.Println("\033[90mUnchanged\033[0m")
+				return nil
+			}
+			template = edited
+		}
+		if err := c.session.SavePrompt(args[1], template); err != nil {
+			return err
+		}
+		fmt.Printf("\033[32m✓ Saved prompt %s\033[0m\n", args[1])
+		return nil
+
+	case "use":
+		p, err := c.session.GetPrompt(args[1])
+		if err != nil {
+			return err
+		}
+		if !p.Enabled {
+			return fmt.Errorf("prompt %s is disabled", p.ID)
+		}
+		text, err := c.fillPrompt(p.Template, parsePromptArgs(args[2:]))
+		if err != nil {
+			return fmt.Errorf("%s: %w", p.ID, err)
+		}
+		fmt.Printf("\033[90m📝 Prompt %s\033[0m\n", p.ID)
+		return c.handleChat(&Intent{Type: IntentCode, Content: text, Raw: text, Confidence: 1.0})
+	}
+	return fmt.Errorf("unknown /prompt command %q", args[0])
+}
+
+// listPrompts shows the stored prompts and the variables they take
+func (c *Chat) listPrompts() error {
+	prompts, err := c.session.Prompts()
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n\033[33mPrompts:\033[0m")
+	for _, p := range prompts {
+		status := ""
+		if !p.Enabled {
+			status = " \033[90m(disabled)\033[0m"
+		}
+		vars := ""
+		if len(p.Variables) > 0 {
+			vars = " {{" + strings.Join(p.Variables, "}} {{") + "}}"
+		}
+		fmt.Printf("  %-16s %-10s %s\033[90m%s\033[0m%s\n", p.ID, p.Category, p.Name, vars, status)
+	}
+	fmt.Println("\033[90m/prompt use <name> [name=value | name=@file | @file[:start-end] | text] sends one; /prompt edit <name> changes it\033[0m")
+	return nil
+}
+
+// fillPrompt renders a template. Besides the given values it knows
+// {{diff}} (the uncommitted changes) and {{branch}}; a bare @file sets
+// {{file}} and {{code}}, and {{selection}} too for a line range; free text
+// sets {{input}}, or the one variable still missing.
+func (c *Chat) fillPrompt(template string, pa promptArgs) (string, error) {
+	// Templates seeded from SQL keep their \n escapes
+	t, err := prompt.Parse(strings.ReplaceAll(template, `\n`, "\n"))
+	if err != nil {
+		return "", err
+	}
+	vars := make(map[string]string)
+	for name, value := range pa.vars {
+		if strings.HasPrefix(value, "@") {
+			_, content, err := c.promptFile(value)
+			if err != nil {
+				return "", err
+			}
+			value = content
+		}
+		vars[name] = value
+	}
+	if pa.file != "" {
+		path, content, err := c.promptFile(pa.file)
+		if err != nil {
+			return "", err
+		}
+		setDefault(vars, "file", path)
+		setDefault(vars, "code", content)
+		if strings.Contains(pa.file, ":") {
+			setDefault(vars, "selection", content)
+		}
+	}
+
+	for _, name := range t.Variables() {
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		switch name {
+		case "diff":
+			if diff, err := c.git.GetDiff(""); err == nil {
+				vars[name] = diff
+			}
+		case "branch":
+			if branch, err := c.git.CurrentBranch(); err == nil {
+				vars[name] = branch
+			}
+		}
+	}
+	if pa.input != "" {
+		vars["input"] = pa.input
+	}
+
+	text, err := t.Render(vars)
+	var missing *prompt.MissingError
+	if errors.As(err, &missing) && len(missing.Names) == 1 && pa.input != "" {
+		vars[missing.Names[0]] = pa.input
+		text, err = t.Render(vars)
+	}
+	return text, err
+}
+
+// setDefault sets a variable the arguments did not
+func setDefault(vars map[string]string, name, value string) {
+	if _, ok := vars[name]; !ok {
+		vars[name] = value
+	}
+}
+
+// promptFile reads an @file or @file:start-end argument, with the same
+// checks as @file mentions
+func (c *Chat) promptFile(arg string) (string, string, error) {
+	m := promptFilePattern.FindStringSubmatch(arg)
+	if m == nil {
+		return "", "", fmt.Errorf("%s: want @path or @path:start-end", arg)
+	}
+	path, err := c.readablePath(m[1])
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", m[1], err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	if workspace.IsBinary(data) {
+		return "", "", fmt.Errorf("%s is a binary file", m[1])
+	}
+	content := string(data)
+
+	if m[2] != "" {
+		start, _ := strconv.Atoi(m[2])
+		end := start
+		if m[3] != "" {
+			end, _ = strconv.Atoi(m[3])
+		}
+		lines := strings.Split(content, "\n")
+		if start < 1 || end < start || start > len(lines) {
+			return "", "", fmt.Errorf("%s: no lines %s in %d", m[1], strings.TrimPrefix(arg, "@"+m[1]+":"), len(lines))
+		}
+		content = strings.Join(lines[start-1:min(end, len(lines))], "\n")
+	}
+	if len(content) > maxFileContextBytes {
+		content = content[:maxFileContextBytes] + "\n... (truncated)"
+	}
+	return m[1], content, nil
+}
+
+// editText opens text in $VISUAL or $EDITOR (default vi) and returns it
+// as saved
+func editText(text string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	f, err := os.CreateTemp("", "goclode-prompt-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %w", editor, err)
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
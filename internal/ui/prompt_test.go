@@ -0,0 +1,18 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePromptArgs(t *testing.T) {
+	pa := parsePromptArgs([]string{"focus=errors", "@main.go:10-20", "why", "does", "this", "panic?", "code=@util.go", "@other.go"})
+	want := promptArgs{
+		vars:  map[string]string{"focus": "errors", "code": "@util.go"},
+		file:  "@main.go:10-20",
+		input: "why does this panic? @other.go",
+	}
+	if !reflect.DeepEqual(pa, want) {
+		t.Errorf("parsePromptArgs = %+v, want %+v", pa, want)
+	}
+}
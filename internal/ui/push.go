@@ -0,0 +1,49 @@
+// Package ui - Pushing GoClode's work to the remote
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// handlePush pushes the current branch, creating its upstream if needed
+func (c *Chat) handlePush(args []string) error {
+	if !c.git.IsRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	remote := c.remoteName()
+	if len(args) > 0 {
+		if strings.HasPrefix(args[0], "-") {
+			return fmt.Errorf("invalid remote %q", args[0])
+		}
+		remote = args[0]
+	}
+	branch, err := c.git.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if err := c.pushBranch(remote, branch); err != nil {
+		return err
+	}
+
+	fmt.Printf("\033[32m✓ Pushed %s to %s\033[0m\n", branch, remote)
+	return nil
+}
+
+// pushBranch pushes a branch with upstream creation
+func (c *Chat) pushBranch(remote, branch string) error {
+	fmt.Printf("\033[90m⬆️  Pushing %s to %s...\033[0m\n", branch, remote)
+	if err := c.git.Push(remote, branch); err != nil {
+		return fmt.Errorf("push: %w", err)
+	}
+	return nil
+}
+
+// remoteName returns the configured remote for pushes and pull requests
+func (c *Chat) remoteName() string {
+	if name, _ := c.engine.GetConfig("forge_remote"); name != "" {
+		return name
+	}
+	return "origin"
+}
@@ -0,0 +1,119 @@
+// Package ui - /index and retrieval of relevant code chunks for requests
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/index"
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// chunkStore returns the embedded chunk store for the configured embedding
+// provider and model
+func (c *Chat) chunkStore() (*index.ChunkStore, error) {
+	var provider providers.Provider
+	if id, _ := c.engine.GetConfig("embedding_provider"); id != "" {
+		p, err := c.registry.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		provider = p
+	} else if provider = c.registry.Current(); provider == nil {
+		return nil, fmt.Errorf("no provider available")
+	}
+
+	embedder, ok := provider.(providers.Embedder)
+	if !ok {
+		return nil, fmt.Errorf("provider %s cannot embed text; set embedding_provider", provider.ID())
+	}
+	model, _ := c.engine.GetConfig("embedding_model")
+	return index.NewChunkStore(c.engine, c.git.WorkDir(), embedder, model), nil
+}
+
+// embeddableContent reads a file for embedding, leaving out protected,
+// binary, and generated files so they are never sent to the provider
+func (c *Chat) embeddableContent(guard *workspace.Guard) func(path string) (string, bool) {
+	return func(path string) (string, bool) {
+		if guard.Check(path) != nil {
+			return "", false
+		}
+		data, err := os.ReadFile(filepath.Join(c.git.WorkDir(), filepath.FromSlash(path)))
+		if err != nil || workspace.IsBinary(data) || workspace.IsGenerated(data) {
+			return "", false
+		}
+		return string(data), true
+	}
+}
+
+// handleIndex embeds the workspace's new and changed files into the chunk store
+func (c *Chat) handleIndex(args []string) error {
+	store, err := c.chunkStore()
+	if err != nil {
+		return err
+	}
+	if c.index == nil {
+		return fmt.Errorf("workspace not indexed yet")
+	}
+
+	if len(args) > 0 && args[0] == "status" {
+		fmt.Printf("\033[90m%d chunks embedded\033[0m\n", store.Count())
+		return nil
+	}
+
+	c.index.Refresh()
+	progress := func(done, total int) {
+		fmt.Printf("\r\033[90m📚 Embedding %d/%d files\033[0m", done, total)
+	}
+	n, err := store.Build(c.ctx, c.index.Files(), c.embeddableContent(c.workspaceGuard()), progress)
+	if n > 0 {
+		fmt.Println()
+	}
+	if err != nil {
+		return fmt.Errorf("index: %w (%d files embedded)", err, n)
+	}
+
+	fmt.Printf("\033[32m✓ Index up to date: %d files embedded, %d chunks\033[0m\n", n, store.Count())
+	return nil
+}
+
+// retrievedContext returns the rag_top_k chunks most relevant to a code
+// request or question, once /index has built the store
+func (c *Chat) retrievedContext(intent *Intent) string {
+	if intent.Type != IntentCode && intent.Type != IntentQuestion {
+		return ""
+	}
+	k := c.engine.GetConfigInt("rag_top_k")
+	if k <= 0 {
+		return ""
+	}
+	store, err := c.chunkStore()
+	if err != nil || store.Count() == 0 {
+		return ""
+	}
+
+	chunks, err := store.Retrieve(c.ctx, intent.Raw, k)
+	if err != nil {
+		core.Logger("rag").Warn("Retrieval skipped", "error", err)
+		return ""
+	}
+
+	guard := c.workspaceGuard()
+	var sb strings.Builder
+	for _, chunk := range chunks {
+		if guard.Check(chunk.Path) != nil {
+			continue // Protected since it was indexed
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("\n\nPossibly relevant code from the project:")
+		}
+		content := c.screenContent(chunk.Path, chunk.Content)
+		fmt.Fprintf(&sb, "\n\n%s (lines %d-%d):\n```\n%s```", chunk.Path, chunk.StartLine, chunk.EndLine, content)
+		core.Logger("rag").Debug("Retrieved chunk", "path", chunk.Path, "start", chunk.StartLine, "end", chunk.EndLine, "score", chunk.Score)
+	}
+	return sb.String()
+}
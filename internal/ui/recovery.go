@@ -0,0 +1,115 @@
+// Package ui - Crash recovery: recording a panic on the session and
+// /resume-recovered to pick a crashed session back up
+package ui
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+// recoverCrash is deferred at the top of the main loop and of background
+// goroutines: on a panic it marks the session as crashed, closes everything
+// down cleanly, and exits
+func (c *Chat) recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := string(debug.Stack())
+	reason := fmt.Sprint(r)
+	log := core.Logger("recovery")
+	log.Error("Panic", "error", reason, "stack", stack)
+
+	c.shutdownOnce.Do(func() {
+		input, _ := c.currentInput()
+		if err := c.session.MarkCrashed(reason, stack, input); err != nil {
+			log.Error("Crash not recorded", "error", err)
+		}
+		c.cancel()
+		c.closeLSP()
+		c.closeDebugServer()
+		c.rl.Close()
+		c.modules.Close()
+		c.learning.Close()
+		c.engine.Close()
+	})
+	fmt.Printf("\n\033[31m💥 GoClode crashed: %s\033[0m\n", reason)
+	fmt.Println("\033[33mThe session was saved: start GoClode again here (with the same --db, if any) and /resume-recovered to continue\033[0m")
+	os.Exit(2)
+}
+
+// goSafe runs fn in a goroutine whose panics are recovered like the main loop's
+func (c *Chat) goSafe(fn func()) {
+	go func() {
+		defer c.recoverCrash()
+		fn()
+	}()
+}
+
+// printCrashes tells about crashed sessions waiting to be recovered
+func (c *Chat) printCrashes() {
+	crashes, err := c.session.CrashedSessions()
+	if err != nil || len(crashes) == 0 {
+		return
+	}
+	last := crashes[0]
+	fmt.Printf("\033[33m💥 Session %s crashed %s (%s): /resume-recovered to continue it\033[0m\n\n",
+		last.SessionID[:8], last.At.Format(time.DateTime), shorten(last.Error, 60))
+}
+
+// handleResumeRecovered continues a crashed session and offers to retry
+// the request it crashed on: /resume-recovered [id]
+func (c *Chat) handleResumeRecovered(args []string) error {
+	crashes, err := c.session.CrashedSessions()
+	if err != nil {
+		return err
+	}
+	if len(crashes) == 0 {
+		return fmt.Errorf("no crashed session to recover")
+	}
+
+	var crash *session.Crash
+	if len(args) == 0 {
+		crash = &crashes[0]
+	} else {
+		for i := range crashes {
+			if strings.HasPrefix(crashes[i].SessionID, args[0]) {
+				crash = &crashes[i]
+				break
+			}
+		}
+		if crash == nil {
+			return fmt.Errorf("no crashed session %q", args[0])
+		}
+	}
+
+	if err := c.resumeSession(crash.SessionID); err != nil {
+		return err
+	}
+	if err := c.session.MarkRecovered(crash.SessionID); err != nil {
+		return err
+	}
+	fmt.Printf("\033[90mCrashed %s: %s\033[0m\n", crash.At.Format(time.DateTime), crash.Error)
+	if c.debugMode && crash.Stack != "" {
+		fmt.Printf("\033[90m%s\033[0m\n", strings.TrimSpace(crash.Stack))
+	}
+
+	if crash.Input == "" {
+		return nil
+	}
+	confirm, _ := c.ask(fmt.Sprintf("\n\033[36mRetry the request it crashed on? %s [y/N] \033[0m", shorten(crash.Input, 80)))
+	if confirm = strings.ToLower(strings.TrimSpace(confirm)); confirm != "y" && confirm != "yes" {
+		return nil
+	}
+	intent := c.parser.Parse(crash.Input)
+	if intent == nil {
+		return nil
+	}
+	return c.handleIntent(c.learnIntent(intent))
+}
@@ -0,0 +1,107 @@
+// Package ui - Secret redaction: what providers are sent has its secrets
+// replaced with placeholders, each new one noted in the audit log; /audit
+// shows the log
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/redact"
+)
+
+// auditEntriesShown is how many audit events /audit shows by default
+const auditEntriesShown = 20
+
+// secretRedactor redacts what providers are sent, auditing the secrets it
+// finds
+type secretRedactor struct {
+	*redact.Redactor
+	chat *Chat
+}
+
+// Redact replaces the secrets in text, recording the new ones
+func (s *secretRedactor) Redact(provider, text string) string {
+	out, findings := s.Redactor.Redact(text)
+	if len(findings) == 0 {
+		return out
+	}
+	kinds := make([]string, 0, len(findings))
+	for _, f := range findings {
+		kinds = append(kinds, f.Kind+" "+f.Placeholder)
+	}
+	fmt.Printf("\033[90m🔒 Redacted before sending to %s: %s\033[0m\n", provider, strings.Join(kinds, ", "))
+
+	sessionID := ""
+	if s.chat.session != nil {
+		sessionID = s.chat.session.Current()
+	}
+	err := s.chat.engine.Audit(sessionID, "secret_redacted", map[string]interface{}{
+		"provider": provider,
+		"findings": findings,
+	})
+	if err != nil {
+		core.Logger("ui").Warn("Redaction not audited", "error", err)
+	}
+	return out
+}
+
+// setupRedaction installs the secret redactor for every provider, unless
+// redact_secrets is off
+func (c *Chat) setupRedaction() {
+	if !c.engine.GetConfigBool("redact_secrets") {
+		providers.SetRedactor(nil)
+		return
+	}
+	extra := make([]redact.Pattern, 0)
+	raw, _ := c.engine.GetConfig("redact_patterns")
+	var exprs []string
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &exprs); err != nil {
+			fmt.Printf("\033[33m⚠️  redact_patterns: %v\033[0m\n", err)
+		}
+	}
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			fmt.Printf("\033[33m⚠️  redact_patterns: %v\033[0m\n", err)
+			continue
+		}
+		extra = append(extra, redact.Pattern{Kind: "custom", Re: re})
+	}
+	// Only the secret-looking variables: APP_ENV=production must not hide
+	// every "production" from the model
+	known := c.secretValues(secretConfigKey.MatchString)
+	providers.SetRedactor(&secretRedactor{Redactor: redact.New(known, extra), chat: c})
+}
+
+// handleAudit shows the latest audit events: /audit [n]
+func (c *Chat) handleAudit(args []string) error {
+	limit := auditEntriesShown
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("usage: /audit [n]")
+		}
+		limit = n
+	}
+	entries, err := c.engine.AuditLog(limit)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\n\033[33mAudit log:\033[0m")
+	if len(entries) == 0 {
+		fmt.Println("  \033[90m(empty)\033[0m")
+	}
+	for _, a := range entries {
+		detail, _ := json.Marshal(a.Detail)
+		session := a.SessionID[:min(len(a.SessionID), 8)]
+		fmt.Printf("  %s \033[90m%-8s\033[0m %-16s %s\n", a.CreatedAt.Format("2006-01-02 15:04:05"), session, a.Event, detail)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+// Package ui - /resolve: LLM-assisted merge and rebase conflict resolution
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/diff"
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// resolveContextLines is how many lines around a conflict are sent to the LLM
+const resolveContextLines = 10
+
+const resolvePrompt = `You resolve git merge conflicts. Combine both sides so the intent of each is kept, unless they are incompatible. Reply with only the resolved code that replaces the conflicted region, in a single fenced code block, without conflict markers and without the surrounding context.`
+
+var resolutionBlockPattern = regexp.MustCompile("(?s)```[^\n]*\n(.*?)```")
+
+// conflict is one <<<<<<< / ======= / >>>>>>> region of a file
+type conflict struct {
+	Start, End  int // Line range [Start, End), markers included
+	Ours        string
+	Base        string // Only with merge.conflictStyle=diff3
+	Theirs      string
+	OursLabel   string
+	TheirsLabel string
+}
+
+// parseConflicts finds the conflict regions in a file's lines
+func parseConflicts(lines []string) []conflict {
+	conflicts := make([]conflict, 0)
+
+	var cur *conflict
+	var section *strings.Builder
+	var ours, base, theirs strings.Builder
+	for i, line := range lines {
+		marker := strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(marker, "<<<<<<<"):
+			cur = &conflict{Start: i, OursLabel: strings.TrimSpace(marker[7:])}
+			ours.Reset()
+			base.Reset()
+			theirs.Reset()
+			section = &ours
+		case cur == nil:
+			continue
+		case strings.HasPrefix(marker, "|||||||"):
+			section = &base
+		case marker == "=======":
+			section = &theirs
+		case strings.HasPrefix(marker, ">>>>>>>"):
+			cur.End = i + 1
+			cur.TheirsLabel = strings.TrimSpace(marker[7:])
+			cur.Ours, cur.Base, cur.Theirs = ours.String(), base.String(), theirs.String()
+			conflicts = append(conflicts, *cur)
+			cur = nil
+		default:
+			section.WriteString(line)
+		}
+	}
+	return conflicts
+}
+
+// applyResolutions replaces resolved conflicts (by index) with their resolution
+func applyResolutions(lines []string, conflicts []conflict, resolutions map[int]string) string {
+	var sb strings.Builder
+	pos := 0
+	for i, c := range conflicts {
+		resolution, ok := resolutions[i]
+		if !ok {
+			continue
+		}
+		for ; pos < c.Start; pos++ {
+			sb.WriteString(lines[pos])
+		}
+		if resolution != "" && !strings.HasSuffix(resolution, "\n") {
+			resolution += "\n"
+		}
+		sb.WriteString(resolution)
+		pos = c.End
+	}
+	for ; pos < len(lines); pos++ {
+		sb.WriteString(lines[pos])
+	}
+	return sb.String()
+}
+
+// handleResolve resolves the conflicts in the named files, or in every
+// unmerged file of the repository, one region at a time
+func (c *Chat) handleResolve(args []string) error {
+	provider := c.registry.Current()
+	if provider == nil {
+		return fmt.Errorf("no provider available")
+	}
+
+	files := args
+	if len(files) == 0 {
+		if !c.git.IsRepo() {
+			return fmt.Errorf("usage: /resolve <file>... (not a git repository)")
+		}
+		var err error
+		if files, err = c.git.ConflictedFiles(); err != nil {
+			return err
+		}
+	}
+	if len(files) == 0 {
+		fmt.Println("\033[90mNo conflicts to resolve\033[0m")
+		return nil
+	}
+
+	policy, _ := c.engine.GetConfig("path_policy")
+	guard := c.workspaceGuard()
+	c.backups.Begin()
+	c.session.BeginBatch()
+
+	for _, file := range files {
+		path, err := sanitizePath(c.git.WorkDir(), file, policy)
+		if err != nil {
+			return fmt.Errorf("unsafe path: %w", err)
+		}
+		if err := guard.Check(path); err != nil {
+			return fmt.Errorf("refusing to modify: %w", err)
+		}
+
+		quit, err := c.resolveFile(provider, path)
+		if err != nil {
+			return err
+		}
+		if quit {
+			break
+		}
+	}
+	return nil
+}
+
+// resolveFile proposes a resolution for each conflict in a file and writes
+// the accepted ones; it reports whether the user quit the review
+func (c *Chat) resolveFile(provider providers.Provider, path string) (bool, error) {
+	before, err := c.git.GetFileContent(path)
+	if err != nil {
+		return false, fmt.Errorf("read %s: %w", path, err)
+	}
+	lines := diff.SplitLines(before)
+	conflicts := parseConflicts(lines)
+	if len(conflicts) == 0 {
+		fmt.Printf("\033[90m%s has no conflict markers\033[0m\n", path)
+		return false, nil
+	}
+
+	resolutions := make(map[int]string)
+	quit := false
+	decided := ""
+	for i, conf := range conflicts {
+		if decided == "n" {
+			break // Skip the rest of this file
+		}
+		fmt.Printf("\n\033[90m🤖 Resolving conflict %d/%d in %s...\033[0m\n", i+1, len(conflicts), path)
+		resolution, err := c.proposeResolution(provider, path, lines, conf)
+		if err != nil {
+			fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+			continue
+		}
+
+		region := strings.Join(lines[conf.Start:conf.End], "")
+		for _, h := range diff.Compute(region, resolution, len(lines)) {
+			printHunk(h)
+		}
+
+		answer := decided
+		if answer == "" {
+			answer = c.askHunk()
+		}
+		switch answer {
+		case "y":
+			resolutions[i] = resolution
+		case "a":
+			resolutions[i] = resolution
+			decided = "y"
+		case "d":
+			decided = "n"
+		case "q":
+			quit = true
+		}
+		if quit {
+			break
+		}
+	}
+
+	if len(resolutions) == 0 {
+		return quit, nil
+	}
+
+	after := applyResolutions(lines, conflicts, resolutions)
+	if err := c.backups.Save(path); err != nil {
+		return quit, fmt.Errorf("backup %s: %w", path, err)
+	}
+	if err := workspace.WriteFile(path, after); err != nil {
+		return quit, fmt.Errorf("write %s: %w", path, err)
+	}
+	patch := diff.Unified(path, before, after)
+	c.session.RecordFileChange(path, "modify", before, after, patch)
+	c.trackFile(path)
+	c.emitFileApplied(path, "modify", patch)
+
+	remaining := len(conflicts) - len(resolutions)
+	if remaining > 0 {
+		fmt.Printf("\033[33m✓ %s: %d resolved, %d left\033[0m\n", path, len(resolutions), remaining)
+		return quit, nil
+	}
+
+	// Mark the file resolved so the merge or rebase can continue
+	if c.git.IsRepo() {
+		if err := c.git.Add(path); err != nil {
+			fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+		}
+	}
+	fmt.Printf("\033[32m✓ %s resolved\033[0m\n", path)
+	return quit, nil
+}
+
+// proposeResolution asks the LLM to resolve one conflict given its context
+func (c *Chat) proposeResolution(provider providers.Provider, path string, lines []string, conf conflict) (string, error) {
+	from := max(0, conf.Start-resolveContextLines)
+	to := min(len(lines), conf.End+resolveContextLines)
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "File: %s\n\nContext before:\n```\n%s```\n\n", path, strings.Join(lines[from:conf.Start], ""))
+	fmt.Fprintf(&prompt, "Ours (%s):\n```\n%s```\n\n", conf.OursLabel, conf.Ours)
+	if conf.Base != "" {
+		fmt.Fprintf(&prompt, "Common ancestor:\n```\n%s```\n\n", conf.Base)
+	}
+	fmt.Fprintf(&prompt, "Theirs (%s):\n```\n%s```\n\n", conf.TheirsLabel, conf.Theirs)
+	fmt.Fprintf(&prompt, "Context after:\n```\n%s```", strings.Join(lines[conf.End:to], ""))
+
+	if err := c.checkBudget(); err != nil {
+		return "", err
+	}
+	resp, err := provider.Generate(c.ctx, &providers.Request{
+		Messages: []providers.Message{
+			{Role: "system", Content: resolvePrompt},
+			{Role: "user", Content: prompt.String()},
+		},
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return "", fmt.Errorf("resolve: %w", err)
+	}
+
+	resolution := resp.Content
+	if m := resolutionBlockPattern.FindStringSubmatch(resolution); m != nil {
+		resolution = m[1]
+	}
+	if len(parseConflicts(diff.SplitLines(resolution))) > 0 {
+		return "", fmt.Errorf("proposed resolution still contains conflict markers")
+	}
+	return resolution, nil
+}
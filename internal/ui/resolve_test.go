@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/diff"
+)
+
+const conflicted = `package main
+
+<<<<<<< HEAD
+const name = "ours"
+=======
+const name = "theirs"
+>>>>>>> feature
+func a() {}
+<<<<<<< HEAD
+func b() {}
+||||||| base
+func b0() {}
+=======
+func b2() {}
+>>>>>>> feature
+`
+
+func TestParseConflicts(t *testing.T) {
+	conflicts := parseConflicts(diff.SplitLines(conflicted))
+	if len(conflicts) != 2 {
+		t.Fatalf("got %d conflicts, want 2", len(conflicts))
+	}
+
+	first := conflicts[0]
+	if first.Start != 2 || first.End != 7 {
+		t.Errorf("first range = [%d,%d), want [2,7)", first.Start, first.End)
+	}
+	if first.Ours != "const name = \"ours\"\n" || first.Theirs != "const name = \"theirs\"\n" {
+		t.Errorf("first sides = %q / %q", first.Ours, first.Theirs)
+	}
+	if first.OursLabel != "HEAD" || first.TheirsLabel != "feature" {
+		t.Errorf("labels = %q / %q", first.OursLabel, first.TheirsLabel)
+	}
+
+	second := conflicts[1]
+	if second.Base != "func b0() {}\n" || second.Theirs != "func b2() {}\n" {
+		t.Errorf("diff3 sides = base %q, theirs %q", second.Base, second.Theirs)
+	}
+}
+
+func TestApplyResolutions(t *testing.T) {
+	lines := diff.SplitLines(conflicted)
+	conflicts := parseConflicts(lines)
+
+	// Resolve only the first conflict; the second keeps its markers
+	got := applyResolutions(lines, conflicts, map[int]string{0: `const name = "both"`})
+	if len(parseConflicts(diff.SplitLines(got))) != 1 {
+		t.Fatalf("want one conflict left:\n%s", got)
+	}
+
+	got = applyResolutions(lines, conflicts, map[int]string{0: "const name = \"both\"\n", 1: "func b2() {}\n"})
+	want := "package main\n\nconst name = \"both\"\nfunc a() {}\nfunc b2() {}\n"
+	if got != want {
+		t.Errorf("applyResolutions =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+// Package ui - /run: run a command and offer to fix what makes it fail
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/tools"
+)
+
+// runArgs returns the command after /run, as typed (quotes and spacing kept)
+func runArgs(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if _, rest, ok := strings.Cut(raw, " "); ok {
+		return strings.TrimSpace(rest)
+	}
+	return ""
+}
+
+// failurePrompt asks the LLM to fix what made a command fail
+func failurePrompt(command string, result tools.CommandResult) string {
+	output := result.Output
+	if output == "" {
+		output = "(no output)"
+	}
+	return fmt.Sprintf("I ran `%s` and it failed (%s):\n\n```\n%s\n```\n\n"+
+		"Fix the code so the command succeeds.", command, result.Status(), output)
+}
+
+// handleRun runs a shell command in the workspace, shows its output, and on
+// failure offers to send the command, exit status, and output to the LLM
+func (c *Chat) handleRun(raw string) error {
+	command := runArgs(raw)
+	if command == "" {
+		return fmt.Errorf("usage: /run <command>")
+	}
+
+	fmt.Printf("\033[90m$ %s\033[0m\n", command)
+	start := time.Now()
+	timeout := time.Duration(c.engine.GetConfigInt("command_timeout")) * time.Second
+	result := c.runShell(c.ctx, c.git.WorkDir(), command, timeout, maxCommandOutput)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if result.Output != "" {
+		fmt.Println(result.Output)
+	}
+	if result.ExitCode == 0 && result.Err == nil {
+		fmt.Printf("\033[32m✓ Done\033[0m \033[90m(%s)\033[0m\n", elapsed)
+		return nil
+	}
+	fmt.Printf("\033[31m✗ %s\033[0m \033[90m(%s)\033[0m\n", result.Status(), elapsed)
+	if c.ctx.Err() != nil {
+		return nil
+	}
+
+	confirm, _ := c.ask("\033[36mFix it? [y/N] \033[0m")
+	if confirm = strings.ToLower(strings.TrimSpace(confirm)); confirm != "y" && confirm != "yes" {
+		return nil
+	}
+
+	prompt := failurePrompt(command, result)
+	return c.handleChat(&Intent{
+		Type:       IntentCode,
+		Content:    prompt,
+		Raw:        prompt,
+		Confidence: 1.0,
+	})
+}
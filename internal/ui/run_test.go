@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/tools"
+)
+
+func TestRunArgs(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"/run", ""},
+		{"/run   ", ""},
+		{"/run go test ./...", "go test ./..."},
+		{`/run  grep -r "a  b" .`, `grep -r "a  b" .`},
+	}
+	for _, tt := range tests {
+		if got := runArgs(tt.raw); got != tt.want {
+			t.Errorf("runArgs(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestFailurePrompt(t *testing.T) {
+	prompt := failurePrompt("make", tools.CommandResult{Output: "main.go:3: undefined: x", ExitCode: 2})
+	for _, want := range []string{"`make`", "exit code 2", "undefined: x"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Package ui - Running project commands in a container (sandbox config)
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/tools"
+)
+
+// currentSandbox returns the container commands run in, or nil when the
+// sandbox setting is empty and they run on the host. A changed image or
+// network setting takes effect once the old container is removed with
+// /sandbox rm.
+func (c *Chat) currentSandbox() *tools.Sandbox {
+	runtime, _ := c.engine.GetConfig("sandbox")
+	if runtime == "" {
+		return nil
+	}
+	image, _ := c.engine.GetConfig("sandbox_image")
+	network := c.engine.GetConfigBool("sandbox_network")
+
+	c.sandboxMu.Lock()
+	defer c.sandboxMu.Unlock()
+	if s := c.sandbox; s != nil && s.Runtime == runtime && s.Image == image && s.Network == network {
+		return s
+	}
+	c.sandbox = tools.NewSandbox(runtime, image, c.git.WorkDir(), network)
+	return c.sandbox
+}
+
+// runShell runs a project command in the sandbox when one is configured,
+// otherwise on the host; it is the tools.Shell of run_command, run_tests,
+// /test, and /run
+func (c *Chat) runShell(ctx context.Context, dir, command string, timeout time.Duration, maxOutput int) tools.CommandResult {
+	if s := c.currentSandbox(); s != nil {
+		return s.Run(ctx, dir, command, timeout, maxOutput)
+	}
+	return tools.RunShell(ctx, dir, command, timeout, maxOutput)
+}
+
+// handleSandbox shows the sandbox container, or removes it (/sandbox rm)
+func (c *Chat) handleSandbox(args []string) error {
+	s := c.currentSandbox()
+	if s == nil {
+		fmt.Println("\033[90mCommands run on the host. Set sandbox to docker or podman to run them in a container.\033[0m")
+		return nil
+	}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "rm", "reset":
+			if err := s.Remove(c.ctx); err != nil {
+				return err
+			}
+			fmt.Printf("\033[32m✓ Removed %s; the next command starts a fresh container\033[0m\n", s.Name)
+			return nil
+		default:
+			return fmt.Errorf("usage: /sandbox [rm]")
+		}
+	}
+
+	state := "\033[90mnot created (starts with the first command)\033[0m"
+	if exists, running := s.Status(c.ctx); running {
+		state = "\033[32mrunning\033[0m"
+	} else if exists {
+		state = "\033[33mstopped\033[0m"
+	}
+	network := "off"
+	if s.Network {
+		network = "on"
+	}
+	fmt.Printf("\n\033[33mSandbox:\033[0m %s\n", s.Name)
+	fmt.Printf("  Runtime: %s\n  Image:   %s\n  Network: %s\n  State:   %s\n", s.Runtime, s.Image, network, state)
+	fmt.Printf("  Mount:   %s -> %s\n", c.git.WorkDir(), tools.SandboxWorkdir)
+	if c.engine.GetConfigBool("sandbox_auto_approve") {
+		fmt.Println("\033[90mrun_command and run_snippet run without asking (sandbox_auto_approve); deny rules still apply\033[0m")
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+// Package ui - First-run setup: a provider, its key, the default model, and
+// auto_commit, saved where every later session reads them
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+// setupModelsShown is how many models setup lists before asking for a name
+const setupModelsShown = 20
+
+// NeedsSetup reports whether no provider has a key yet and a terminal is
+// there to ask for one
+func NeedsSetup(engine *core.Engine) bool {
+	if !readline.DefaultIsTerminal() {
+		return false
+	}
+	return len(providers.NewRegistry(engine.DB()).Available()) == 0
+}
+
+// setup is a run of the setup wizard
+type setup struct {
+	engine   *core.Engine
+	registry *providers.Registry
+	rl       *readline.Instance
+}
+
+// RunSetup asks for a provider, checks its key with a short request, and
+// asks for the default model and auto_commit. The key goes to
+// ~/.goclode/env, readable only by the user; the rest to the global config
+// file. Entering no key leaves everything as it was.
+func RunSetup(engine *core.Engine) error {
+	registry := providers.NewRegistry(engine.DB())
+	configs, err := registry.Configs()
+	if err != nil {
+		return fmt.Errorf("list providers: %w", err)
+	}
+	if len(configs) == 0 {
+		return fmt.Errorf("no providers enabled")
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Stdin:  readline.NewCancelableStdin(os.Stdin),
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("readline: %w", err)
+	}
+	defer rl.Close()
+	s := &setup{engine: engine, registry: registry, rl: rl}
+	err = s.run(configs)
+	if errors.Is(err, readline.ErrInterrupt) || errors.Is(err, io.EOF) {
+		fmt.Println("\033[90mSetup skipped\033[0m")
+		return nil
+	}
+	return err
+}
+
+// run asks the questions and saves the answers
+func (s *setup) run(configs []providers.ProviderConfig) error {
+	engine := s.engine
+
+	fmt.Println("\n\033[1m🤖 GoClode setup\033[0m")
+	fmt.Println("\033[90mNo provider has an API key yet. Ctrl+C or an empty key stops here; goclode setup starts over.\033[0m")
+
+	cfg, err := s.chooseProvider(configs)
+	if err != nil {
+		return err
+	}
+	key, model, err := s.chooseKey(cfg)
+	if err != nil || key == "" {
+		return err
+	}
+	autoCommit, err := s.askYesNo("Commit each change to git automatically?", engine.GetConfigBool("auto_commit"))
+	if err != nil {
+		return err
+	}
+
+	envPath, err := core.UserEnvPath()
+	if err != nil {
+		return err
+	}
+	if err := core.SetEnvFileValue(envPath, cfg.APIKeyEnv, key); err != nil {
+		return fmt.Errorf("save key: %w", err)
+	}
+	if model == cfg.DefaultModel {
+		model = ""
+	}
+	configPath := core.GlobalConfigPath()
+	err = core.WriteConfigFile(configPath, map[string]string{
+		"default_provider": cfg.ID,
+		"default_model":    model,
+		"auto_commit":      strconv.FormatBool(autoCommit),
+	})
+	if err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	if err := engine.LoadConfigFiles("."); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	fmt.Printf("\033[32m✓ %s saved in %s\033[0m\n", cfg.APIKeyEnv, envPath)
+	fmt.Printf("\033[32m✓ Settings saved in %s\033[0m\n\n", configPath)
+	return nil
+}
+
+// chooseProvider lists the providers and reads a number or ID
+func (s *setup) chooseProvider(configs []providers.ProviderConfig) (providers.ProviderConfig, error) {
+	fmt.Println("\n\033[33mProviders:\033[0m")
+	for i, cfg := range configs {
+		fmt.Printf("  %d. %-12s %s \033[90m(%s)\033[0m\n", i+1, cfg.Name, cfg.BaseURL, cfg.APIKeyEnv)
+	}
+	for {
+		answer, err := s.ask("Provider [1]: ")
+		if err != nil {
+			return providers.ProviderConfig{}, err
+		}
+		if answer == "" {
+			return configs[0], nil
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(configs) {
+			return configs[n-1], nil
+		}
+		for _, cfg := range configs {
+			if strings.EqualFold(answer, cfg.ID) || strings.EqualFold(answer, cfg.Name) {
+				return cfg, nil
+			}
+		}
+		fmt.Printf("\033[31mPick 1-%d\033[0m\n", len(configs))
+	}
+}
+
+// chooseKey reads the provider's key and model until a short request with
+// them succeeds. It returns no key if the user gives up.
+func (s *setup) chooseKey(cfg providers.ProviderConfig) (string, string, error) {
+	previous, wasSet := os.LookupEnv(cfg.APIKeyEnv)
+	restore := func() {
+		if wasSet {
+			os.Setenv(cfg.APIKeyEnv, previous)
+		} else {
+			os.Unsetenv(cfg.APIKeyEnv)
+		}
+		s.registry.Reload()
+	}
+
+	for {
+		data, err := s.rl.ReadPassword(fmt.Sprintf("%s API key (input hidden): ", cfg.Name))
+		if err != nil {
+			restore()
+			return "", "", err
+		}
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			restore()
+			fmt.Println("\033[90mSetup skipped\033[0m")
+			return "", "", nil
+		}
+
+		os.Setenv(cfg.APIKeyEnv, key)
+		s.registry.Reload()
+		p, err := s.registry.Get(cfg.ID)
+		if err != nil {
+			restore()
+			return "", "", err
+		}
+		model, err := s.chooseModel(cfg, p)
+		if err != nil {
+			restore()
+			return "", "", err
+		}
+
+		fmt.Printf("\033[90mChecking %s with %s...\033[0m\n", cfg.Name, model)
+		if err := checkProvider(p, model); err != nil {
+			fmt.Printf("\033[31m✗ %v\033[0m\n", err)
+			continue
+		}
+		fmt.Printf("\033[32m✓ %s answered\033[0m\n", cfg.Name)
+		return key, model, nil
+	}
+}
+
+// chooseModel offers the models the provider lists, or its default when it
+// lists none
+func (s *setup) chooseModel(cfg providers.ProviderConfig, p providers.Provider) (string, error) {
+	var models []string
+	if lister, ok := p.(providers.ModelLister); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		models, _ = lister.ListModels(ctx)
+		cancel()
+	}
+	if len(models) > 0 {
+		fmt.Println("\n\033[33mModels:\033[0m")
+		for i, m := range models[:min(len(models), setupModelsShown)] {
+			fmt.Printf("  %d. %s\n", i+1, m)
+		}
+		if len(models) > setupModelsShown {
+			fmt.Printf("\033[90m  ... %d more: type a name\033[0m\n", len(models)-setupModelsShown)
+		}
+	}
+
+	answer, err := s.ask(fmt.Sprintf("Model [%s]: ", cfg.DefaultModel))
+	if err != nil || answer == "" {
+		return cfg.DefaultModel, err
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= min(len(models), setupModelsShown) {
+		return models[n-1], nil
+	}
+	return answer, nil
+}
+
+// checkProvider sends a one-line request, proving the key and model work
+func checkProvider(p providers.Provider, model string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := p.Generate(ctx, &providers.Request{
+		Model:     model,
+		Messages:  []providers.Message{{Role: "user", Content: "Reply with OK."}},
+		MaxTokens: 16,
+	})
+	return err
+}
+
+// ask reads a trimmed line
+func (s *setup) ask(prompt string) (string, error) {
+	s.rl.SetPrompt(prompt)
+	line, err := s.rl.Readline()
+	return strings.TrimSpace(line), err
+}
+
+// askYesNo reads y or n, Enter keeping the default
+func (s *setup) askYesNo(question string, def bool) (bool, error) {
+	choices := "y/N"
+	if def {
+		choices = "Y/n"
+	}
+	for {
+		answer, err := s.ask(fmt.Sprintf("%s [%s]: ", question, choices))
+		if err != nil {
+			return def, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// useDefaultProvider makes default_provider current when it has a key,
+// with default_model if one is set
+func (c *Chat) useDefaultProvider() {
+	id, _ := c.engine.GetConfig("default_provider")
+	p, err := c.registry.Get(id)
+	if err != nil || !p.IsAvailable() {
+		return
+	}
+	c.registry.SetCurrent(id)
+	if model, _ := c.engine.GetConfig("default_model"); model != "" {
+		if err := c.registry.SetDefaultModel(id, model); err != nil {
+			core.Logger("ui").Warn("Default model not set", "provider", id, "error", err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package ui - Slack bot mode (goclode slack)
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/slack"
+)
+
+// slackThread posts in one Slack thread
+type slackThread struct {
+	ctx     context.Context
+	client  *slack.Client
+	channel string
+	thread  string
+}
+
+func (t slackThread) Post(text string) (string, error) {
+	return t.client.PostMessage(t.ctx, t.channel, t.thread, text)
+}
+
+func (t slackThread) Update(id, text string) error {
+	return t.client.UpdateMessage(t.ctx, t.channel, id, text)
+}
+
+func (t slackThread) Finish(id, text string) error {
+	return t.client.UpdateMessage(t.ctx, t.channel, id, text)
+}
+
+// ServeSlack answers Slack until interrupted. The bot replies when
+// mentioned, in direct messages, and to every message of a thread it
+// replied in; each thread is a session. Only the users and channels of
+// slack_allowed_users and slack_allowed_channels are answered; direct
+// messages only for the users listed.
+func (c *Chat) ServeSlack() error {
+	appToken, botToken := c.configSecret("slack_app_token"), c.configSecret("slack_bot_token")
+	if appToken == "" || botToken == "" {
+		return fmt.Errorf("set slack_app_token and slack_bot_token (or SLACK_APP_TOKEN and SLACK_BOT_TOKEN)")
+	}
+	access, err := c.loadBotAccess("slack_allowed_users", "slack_allowed_channels")
+	if err != nil {
+		return err
+	}
+	client := slack.New(appToken, botToken)
+
+	ctx, stop := signal.NotifyContext(c.ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	botID, err := client.BotUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer c.recoverCrash()
+	if _, err := c.startSession(); err != nil {
+		return err
+	}
+	bots := newBotServer(c, "slack")
+	log := core.Logger("slack")
+	mention := "<@" + botID + ">"
+
+	fmt.Printf("\033[32m💬 Listening on Slack as %s (Ctrl+C to stop)\033[0m\n", botID)
+	err = client.Listen(ctx, func(e slack.Event) {
+		thread := e.Channel + ":" + e.Thread()
+		direct := strings.HasPrefix(e.Channel, "D")
+		if !strings.Contains(e.Text, mention) && !direct && !bots.known(thread) {
+			return
+		}
+		if (direct && !access.allowsDirect(e.User)) || (!direct && !access.allows(e.User, e.Channel)) {
+			log.Info("Ignored a message from a user not allowed", "user", e.User, "channel", e.Channel)
+			return
+		}
+		text := strings.TrimSpace(strings.ReplaceAll(e.Text, mention, ""))
+		fmt.Printf("\033[90m[slack %s] %s\033[0m\n", thread, shorten(text, 80))
+		c.setInFlight(text)
+		bots.handle(thread, text, slackThread{ctx: ctx, client: client, channel: e.Channel, thread: e.Thread()})
+		c.setInFlight("")
+	})
+
+	c.shutdown()
+	return err
+}
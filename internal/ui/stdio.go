@@ -0,0 +1,647 @@
+// Package ui - Editor protocol: JSON-RPC 2.0 over stdio with LSP-style
+// Content-Length framing, for editor extensions (goclode --stdio)
+package ui
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/diff"
+	"github.com/hazyhaar/GoClode/internal/websocket"
+)
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000 // The request failed: no provider, unsafe path...
+)
+
+// rpcWriteTimeout bounds a write to a network connection
+const rpcWriteTimeout = 10 * time.Second
+
+// maxFrameSize caps the body of a framed message; a larger Content-Length
+// is refused before anything is allocated, since --listen reads frames
+// before the client has authenticated
+const maxFrameSize = 16 << 20
+
+// rpcRequest is a request or notification from the editor
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // Absent for notifications
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcError is the error of a failed request
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// rpcOutgoing is a response or a notification to the editor
+type rpcOutgoing struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// changePreview is a proposed file change as the editor shows it
+type changePreview struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"`
+	Operation string `json:"operation"` // create, modify, delete, or rename
+	Diff      string `json:"diff,omitempty"`
+	Content   string `json:"content,omitempty"` // The whole new file, for editors that apply it to a buffer
+	Error     string `json:"error,omitempty"`   // Why it cannot be applied
+}
+
+// stdioServer answers the requests of editor connections, one at a time:
+// they share the chat, the changes waiting for approval, and the turn
+// streaming, which outlives the connection that asked for it. On --listen,
+// each client has its own session, config file, learning, changes, and
+// turns, and the chat switches to the client it serves (serveAs).
+type stdioServer struct {
+	chat    *Chat
+	tokens  map[string]string       // Token to client name; when set, initialize must carry one (--listen)
+	busy    sync.Mutex              // Held by the request using the chat
+	pending []FileChange            // Changes of the client's last reply, until applied or discarded
+	client  string                  // Client the chat serves
+	parked  map[string][]FileChange // Pending changes of the other clients
+	exit    atomic.Bool
+
+	mu        sync.Mutex // Guards the turn and its followers
+	turn      rpcTurn
+	followers map[*rpcConn]bool // Connections the turn streams to
+}
+
+// rpcConn is one editor connection
+type rpcConn struct {
+	w    io.Writer
+	ws   *websocket.Conn // Instead of w, for browsers
+	wmu  sync.Mutex
+	user string // Client name the token gave, on --listen
+}
+
+// client returns the name of the connection's client, empty without tokens
+func (rc *rpcConn) client() string {
+	if rc == nil {
+		return ""
+	}
+	return rc.user
+}
+
+// rpcTurn is the turn in progress, or the last one, as task/attach
+// replays it
+type rpcTurn struct {
+	Input   string      `json:"input"`
+	User    string      `json:"user,omitempty"` // Client who asked, on --listen
+	Text    string      `json:"text"`           // Streamed so far
+	Running bool        `json:"running"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   string      `json:"error,omitempty"`
+
+	text strings.Builder
+}
+
+// stdioMethods lists what the editor may call, for initialize
+var stdioMethods = []string{"initialize", "chat/send", "changes/preview", "changes/apply", "changes/applied", "changes/discard", "task/attach", "shutdown", "exit"}
+
+// newStdioServer creates the server of a chat, streaming its replies to
+// the connections following them until close
+func newStdioServer(c *Chat) *stdioServer {
+	s := &stdioServer{chat: c, parked: make(map[string][]FileChange), followers: make(map[*rpcConn]bool)}
+	c.onDelta = s.delta
+	return s
+}
+
+// close stops streaming the chat's replies
+func (s *stdioServer) close() {
+	s.chat.onDelta = nil
+}
+
+// ServeStdio speaks the editor protocol on in and out until the editor
+// sends exit or closes its end. Replies stream as chat/chunk notifications;
+// the file changes they propose wait for changes/apply, which is the
+// approval, so the terminal's confirmations are skipped. Tools are not
+// offered: their permission prompts need the terminal.
+func (c *Chat) ServeStdio(in io.Reader, out io.Writer) error {
+	defer c.recoverCrash()
+
+	if _, err := c.startSession(); err != nil {
+		return err
+	}
+	s := newStdioServer(c)
+	_, err := s.serve(in, out)
+	s.close()
+	c.shutdown()
+	return err
+}
+
+// serve answers one editor connection until it sends exit, which it
+// reports, or closes its end
+func (s *stdioServer) serve(in io.Reader, out io.Writer) (bool, error) {
+	r := bufio.NewReader(in)
+	return s.serveRPC(&rpcConn{w: out}, func() ([]byte, error) { return readFrame(r) })
+}
+
+// serveWebSocket is serve over a WebSocket, one JSON-RPC message per text
+// message, for browsers
+func (s *stdioServer) serveWebSocket(ws *websocket.Conn) (bool, error) {
+	return s.serveRPC(&rpcConn{ws: ws}, func() ([]byte, error) {
+		msg, err := ws.ReadMessage()
+		var closed *websocket.CloseError
+		if errors.As(err, &closed) {
+			return nil, io.EOF
+		}
+		return msg, err
+	})
+}
+
+// serveRPC answers the messages next reads until it returns io.EOF
+func (s *stdioServer) serveRPC(conn *rpcConn, next func() ([]byte, error)) (bool, error) {
+	c := s.chat
+	defer s.unfollow(conn)
+
+	authed := len(s.tokens) == 0
+	for !s.exit.Load() {
+		body, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("rpc: %w", err)
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			conn.respond(nil, nil, &rpcError{Code: rpcParseError, Message: err.Error()})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			conn.respond(req.ID, nil, &rpcError{Code: rpcInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+			continue
+		}
+		if !authed {
+			user, rerr := s.authenticate(req)
+			if rerr != nil {
+				conn.respond(req.ID, nil, rerr)
+				return false, fmt.Errorf("rpc: client refused: %s", rerr.Message)
+			}
+			conn.user, authed = user, true
+			fmt.Printf("\033[90mClient authenticated as %s\033[0m\n", user)
+		}
+		c.setInFlight(req.Method)
+		result, err := s.handle(conn, req.Method, req.Params)
+		c.setInFlight("")
+		if len(req.ID) == 0 {
+			continue // Notifications get no response
+		}
+		var rerr *rpcError
+		if err != nil && !errors.As(err, &rerr) {
+			rerr = &rpcError{Code: rpcServerError, Message: err.Error()}
+		}
+		conn.respond(req.ID, result, rerr)
+	}
+	return s.exit.Load(), nil
+}
+
+// authenticate checks the first request of a connection, an initialize
+// whose token param is one of the server's, and returns the client it names
+func (s *stdioServer) authenticate(req rpcRequest) (string, *rpcError) {
+	if req.Method != "initialize" {
+		return "", &rpcError{Code: rpcServerError, Message: "send initialize with your token first"}
+	}
+	var p struct {
+		Token string `json:"token"`
+	}
+	if len(req.Params) > 0 {
+		json.Unmarshal(req.Params, &p)
+	}
+	user := ""
+	for token, name := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(p.Token), []byte(token)) == 1 {
+			user = name
+		}
+	}
+	if user == "" {
+		return "", &rpcError{Code: rpcServerError, Message: "bad or missing token"}
+	}
+	return user, nil
+}
+
+// serveAs switches the chat to the client of conn: its session (started
+// the first time it asks), its config file in .goclode/listen-users, its
+// learning, and its pending changes. The owner has the session of the
+// server's start, the project's config, and the project's learning.
+func (s *stdioServer) serveAs(conn *rpcConn) error {
+	user := conn.client()
+	if user == s.client {
+		return nil
+	}
+	c := s.chat
+	id, err := c.session.UserSession(user)
+	if err != nil {
+		return err
+	}
+	if err := c.boundSession(id, "user", user, c.session.BindUser); err != nil {
+		return err
+	}
+	if user == listenOwner {
+		c.learning.SetUser("")
+		c.engine.SetUserConfig("", "")
+	} else {
+		c.learning.SetUser(user)
+		if err := c.engine.SetUserConfig(user, listenUserConfigPath(user)); err != nil {
+			fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+		}
+	}
+
+	s.parked[s.client] = s.pending
+	s.pending = s.parked[user]
+	delete(s.parked, user)
+	s.client = user
+	return nil
+}
+
+// handle runs one method for a connection. Only one request at a time
+// uses the chat: the others are refused while it runs.
+func (s *stdioServer) handle(conn *rpcConn, method string, params json.RawMessage) (interface{}, error) {
+	c := s.chat
+	switch method {
+	case "initialize":
+		result := map[string]interface{}{
+			"name":       "GoClode",
+			"session_id": c.session.Current(),
+			"workdir":    c.git.WorkDir(),
+			"methods":    stdioMethods,
+		}
+		if user := conn.client(); user != "" {
+			result["user"] = user
+			result["session_id"], _ = c.session.UserSession(user)
+		}
+		if p := c.registry.Current(); p != nil {
+			result["provider"] = p.ID()
+		}
+		return result, nil
+
+	case "task/attach":
+		return s.attach(conn), nil
+
+	case "shutdown":
+		return nil, nil
+
+	case "exit":
+		s.exit.Store(true)
+		return nil, nil
+	}
+
+	if !s.busy.TryLock() {
+		return nil, &rpcError{Code: rpcServerError, Message: "busy with another request; task/attach follows the turn in progress"}
+	}
+	defer s.busy.Unlock()
+	if err := s.serveAs(conn); err != nil {
+		return nil, err
+	}
+	switch method {
+	case "chat/send":
+		var p struct {
+			Text    string        `json:"text"`
+			Context []bufferRange `json:"context"` // Editor selections to attach
+		}
+		if err := json.Unmarshal(params, &p); err != nil || strings.TrimSpace(p.Text) == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: `chat/send needs {"text": "..."}`}
+		}
+		s.begin(conn, p.Text)
+		result, err := s.send(p.Text, p.Context)
+		s.finish(conn, result, err)
+		return result, err
+
+	case "changes/preview":
+		return map[string]interface{}{"changes": s.previews()}, nil
+
+	case "changes/apply":
+		var p struct {
+			Paths []string `json:"paths"` // Only these, default all
+		}
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			}
+		}
+		return s.apply(p.Paths)
+
+	case "changes/applied":
+		var p struct {
+			Paths []string `json:"paths"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil || len(p.Paths) == 0 {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: `changes/applied needs {"paths": [...]}`}
+		}
+		return map[string]int{"accepted": s.accept(p.Paths)}, nil
+
+	case "changes/discard":
+		discarded := len(s.pending)
+		s.pending = nil
+		return map[string]int{"discarded": discarded}, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "unknown method " + method}
+}
+
+// begin starts a turn, streaming it to conn and the connections already
+// following
+func (s *stdioServer) begin(conn *rpcConn, input string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turn = rpcTurn{Input: input, User: conn.client(), Running: true}
+	if conn != nil {
+		s.followers[conn] = true
+	}
+}
+
+// delta streams a chunk of the turn
+func (s *stdioServer) delta(delta string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turn.text.WriteString(delta)
+	for f := range s.followers {
+		if f.client() != s.turn.User {
+			continue // Another client's turn
+		}
+		f.notify("chat/chunk", map[string]string{"delta": delta})
+	}
+}
+
+// finish ends the turn, sending task/done to the followers other than
+// conn, which gets the response
+func (s *stdioServer) finish(conn *rpcConn, result interface{}, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turn.Running = false
+	s.turn.Result = result
+	if err != nil {
+		s.turn.Error = err.Error()
+	}
+	done := s.turn.snapshot()
+	for f := range s.followers {
+		if f != conn && f.client() == s.turn.User {
+			f.notify("task/done", done)
+		}
+	}
+}
+
+// attach makes conn follow its client's turns and returns what streamed so
+// far of the last one, so nothing is missed or repeated; another client's
+// turn is not shown
+func (s *stdioServer) attach(conn *rpcConn) rpcTurn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conn != nil {
+		s.followers[conn] = true
+	}
+	if s.turn.User != conn.client() {
+		return rpcTurn{}
+	}
+	return s.turn.snapshot()
+}
+
+// unfollow stops streaming to a connection that closed
+func (s *stdioServer) unfollow(conn *rpcConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.followers, conn)
+}
+
+// snapshot copies a turn with its text
+func (t *rpcTurn) snapshot() rpcTurn {
+	return rpcTurn{Input: t.Input, User: t.User, Text: t.text.String(), Running: t.Running, Result: t.Result, Error: t.Error}
+}
+
+// send asks the model, keeping the changes its reply proposes for
+// changes/apply
+func (s *stdioServer) send(text string, ranges []bufferRange) (interface{}, error) {
+	c := s.chat
+	if strings.HasPrefix(strings.TrimSpace(text), "/") {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "slash commands are only available in the terminal"}
+	}
+	attached, err := c.rangeContext(ranges)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	intent := c.parser.Parse(text)
+	if intent == nil || (intent.Type != IntentCode && intent.Type != IntentQuestion) {
+		intent = &Intent{Type: IntentCode, Content: text, Raw: text, Confidence: 1.0}
+	}
+	intent.Raw += attached
+
+	turn, err := c.converse(intent, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.pending = c.extractFileChanges(turn.resp.Content)
+	c.emitChatComplete(turn, len(s.pending))
+
+	return map[string]interface{}{
+		"reply":      turn.resp.Content,
+		"tokens_in":  turn.tokensIn,
+		"tokens_out": turn.tokensOut,
+		"latency_ms": turn.latency,
+		"changes":    s.previews(),
+	}, nil
+}
+
+// previews describes the pending changes with their diffs
+func (s *stdioServer) previews() []changePreview {
+	previews := make([]changePreview, 0, len(s.pending))
+	for _, ch := range s.pending {
+		previews = append(previews, s.chat.previewChange(ch))
+	}
+	return previews
+}
+
+// apply applies the pending changes, or those to the given paths, through
+// the same checks, validation, and commit as in the terminal
+func (s *stdioServer) apply(paths []string) (interface{}, error) {
+	if len(s.pending) == 0 {
+		return nil, fmt.Errorf("no pending changes (chat/send first)")
+	}
+	changes, rest := s.pending, []FileChange(nil)
+	if len(paths) > 0 {
+		wanted := make(map[string]bool, len(paths))
+		for _, p := range paths {
+			wanted[p] = true
+		}
+		changes = nil
+		for _, ch := range s.pending {
+			if wanted[ch.Path] {
+				changes = append(changes, ch)
+			} else {
+				rest = append(rest, ch)
+			}
+		}
+		if len(changes) == 0 {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "none of the paths has a pending change"}
+		}
+	}
+
+	files := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		files = append(files, ch.Path)
+	}
+	applied, err := s.chat.applyChanges(append([]FileChange(nil), changes...))
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{"applied": applied, "files": files}
+	if applied {
+		s.pending = rest
+	}
+	if failure := s.chat.pendingFix; failure != "" {
+		s.chat.pendingFix = ""
+		result["validation_failure"] = failure
+	}
+	return result, nil
+}
+
+// accept drops the pending changes to paths the editor applied to its
+// buffers itself, and returns how many there were
+func (s *stdioServer) accept(paths []string) int {
+	applied := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		applied[p] = true
+	}
+	rest := s.pending[:0]
+	for _, ch := range s.pending {
+		if !applied[ch.Path] {
+			rest = append(rest, ch)
+		}
+	}
+	accepted := len(s.pending) - len(rest)
+	s.pending = rest
+	return accepted
+}
+
+// previewChange describes a change without applying it
+func (c *Chat) previewChange(ch FileChange) changePreview {
+	p := changePreview{Path: ch.Path, OldPath: ch.OldPath}
+	policy, _ := c.engine.GetConfig("path_policy")
+	path, err := sanitizePath(c.git.WorkDir(), ch.Path, policy)
+	if err == nil {
+		err = c.workspaceGuard().Check(path)
+	}
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	before, err := c.git.GetFileContent(path)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+
+	switch {
+	case ch.Delete:
+		p.Operation = "delete"
+		p.Diff = diff.Unified(ch.Path, before, "")
+	case ch.IsRename():
+		p.Operation = "rename"
+	default:
+		after := ch.Content
+		if len(ch.Edits) > 0 {
+			if after, err = applyEdits(before, ch.Edits, c.engine.GetConfigBool("fuzzy_edits")); err != nil {
+				p.Error = err.Error()
+				return p
+			}
+		}
+		p.Operation = "create"
+		if fileExists(path) {
+			p.Operation = "modify"
+		}
+		p.Diff = diff.Unified(ch.Path, before, after)
+		p.Content = after
+	}
+	return p
+}
+
+// respond answers a request
+func (rc *rpcConn) respond(id json.RawMessage, result interface{}, rerr *rpcError) {
+	msg := rpcOutgoing{JSONRPC: "2.0", ID: id, Error: rerr}
+	if len(id) == 0 {
+		msg.ID = json.RawMessage("null") // Unidentifiable request
+	}
+	if rerr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			msg.Error = &rpcError{Code: rpcServerError, Message: err.Error()}
+		} else {
+			msg.Result = data
+		}
+	}
+	rc.write(msg)
+}
+
+// notify sends a notification
+func (rc *rpcConn) notify(method string, params interface{}) {
+	rc.write(rpcOutgoing{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// write sends one framed message
+func (rc *rpcConn) write(msg rpcOutgoing) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	if rc.ws != nil {
+		rc.ws.WriteMessage(body) // Bounded by its own deadline
+		return
+	}
+	rc.wmu.Lock()
+	defer rc.wmu.Unlock()
+	if conn, ok := rc.w.(net.Conn); ok {
+		conn.SetWriteDeadline(time.Now().Add(rpcWriteTimeout)) // A stuck client must not stall the turn
+	}
+	fmt.Fprintf(rc.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// readFrame reads the body of one Content-Length framed message
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || (len(header) == 0 && errors.Is(err, io.ErrUnexpectedEOF)) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("bad Content-Length %q", header.Get("Content-Length"))
+	}
+	if length > maxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes is over %d", length, maxFrameSize)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
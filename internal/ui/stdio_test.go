@@ -0,0 +1,297 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/git"
+	"github.com/hazyhaar/GoClode/internal/modules"
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/session"
+	"github.com/hazyhaar/GoClode/internal/websocket"
+)
+
+func TestStdioFraming(t *testing.T) {
+	var buf bytes.Buffer
+	conn := &rpcConn{w: &buf}
+	conn.notify("chat/chunk", map[string]string{"delta": "héllo"})
+	conn.respond(json.RawMessage(`"req-1"`), nil, nil)
+
+	r := bufio.NewReader(&buf)
+	body, err := readFrame(r)
+	if err != nil {
+		t.Fatalf("readFrame: %v", err)
+	}
+	var note struct {
+		Method string            `json:"method"`
+		Params map[string]string `json:"params"`
+	}
+	if err := json.Unmarshal(body, &note); err != nil || note.Method != "chat/chunk" || note.Params["delta"] != "héllo" {
+		t.Errorf("Unexpected notification %s (%v)", body, err)
+	}
+
+	body, err = readFrame(r)
+	if err != nil {
+		t.Fatalf("readFrame: %v", err)
+	}
+	if string(body) != `{"jsonrpc":"2.0","id":"req-1","result":null}` {
+		t.Errorf("Unexpected response %s", body)
+	}
+
+	if _, err := readFrame(r); err != io.EOF {
+		t.Errorf("Expected EOF after the last frame, got %v", err)
+	}
+	if _, err := readFrame(bufio.NewReader(strings.NewReader("Content-Length: x\r\n\r\n{}"))); err == nil {
+		t.Error("Expected an error for a bad Content-Length")
+	}
+	huge := fmt.Sprintf("Content-Length: %d\r\n\r\n{}", maxFrameSize+1)
+	if _, err := readFrame(bufio.NewReader(strings.NewReader(huge))); err == nil || !strings.Contains(err.Error(), "over") {
+		t.Errorf("Expected a frame over maxFrameSize to be refused, got %v", err)
+	}
+}
+
+func TestStdioHandle(t *testing.T) {
+	s := &stdioServer{chat: &Chat{}, pending: []FileChange{{Path: "a.go"}, {Path: "b.go"}}, followers: make(map[*rpcConn]bool)}
+
+	_, err := s.handle(nil, "nope", nil)
+	if rerr, ok := err.(*rpcError); !ok || rerr.Code != rpcMethodNotFound {
+		t.Errorf("Expected method not found, got %v", err)
+	}
+	if _, err := s.handle(nil, "chat/send", json.RawMessage(`{"text":""}`)); err == nil {
+		t.Error("Expected chat/send without text to fail")
+	}
+	if _, err := s.send("/undo", nil); err == nil {
+		t.Error("Expected slash commands to be refused")
+	}
+	if _, err := s.handle(nil, "changes/apply", json.RawMessage(`{"paths":["c.go"]}`)); err == nil {
+		t.Error("Expected applying a path without pending change to fail")
+	}
+
+	result, err := s.handle(nil, "changes/applied", json.RawMessage(`{"paths":["a.go","c.go"]}`))
+	if err != nil || result.(map[string]int)["accepted"] != 1 || len(s.pending) != 1 || s.pending[0].Path != "b.go" {
+		t.Errorf("Unexpected applied result %v (%v), pending %v", result, err, s.pending)
+	}
+
+	result, err = s.handle(nil, "changes/discard", nil)
+	if err != nil || result.(map[string]int)["discarded"] != 1 || len(s.pending) != 0 {
+		t.Errorf("Unexpected discard result %v (%v), %d pending", result, err, len(s.pending))
+	}
+	if _, err := s.handle(nil, "changes/apply", nil); err == nil {
+		t.Error("Expected apply without pending changes to fail")
+	}
+
+	if _, err := s.handle(nil, "exit", nil); err != nil || !s.exit.Load() {
+		t.Errorf("Expected exit to stop the server, got %v", err)
+	}
+}
+
+func TestStdioAttach(t *testing.T) {
+	s := &stdioServer{chat: &Chat{}, followers: make(map[*rpcConn]bool)}
+	var sent, followed bytes.Buffer
+	sender, follower := &rpcConn{w: &sent}, &rpcConn{w: &followed}
+
+	s.begin(sender, "fix the bug")
+	s.delta("Looking")
+	turn := s.attach(follower)
+	if turn.Input != "fix the bug" || turn.Text != "Looking" || !turn.Running {
+		t.Errorf("Unexpected replay %+v", turn)
+	}
+	s.delta(" at it")
+	s.finish(sender, map[string]string{"reply": "done"}, nil)
+
+	frames := func(buf *bytes.Buffer) []string {
+		methods := make([]string, 0)
+		r := bufio.NewReader(buf)
+		for {
+			body, err := readFrame(r)
+			if err != nil {
+				return methods
+			}
+			var msg struct {
+				Method string `json:"method"`
+			}
+			json.Unmarshal(body, &msg)
+			methods = append(methods, msg.Method)
+		}
+	}
+	if got := strings.Join(frames(&sent), ","); got != "chat/chunk,chat/chunk" {
+		t.Errorf("Sender got %s, want both chunks and no task/done", got)
+	}
+	if got := strings.Join(frames(&followed), ","); got != "chat/chunk,task/done" {
+		t.Errorf("Follower got %s, want the chunk after attaching, then task/done", got)
+	}
+	if turn := s.attach(nil); turn.Text != "Looking at it" || turn.Running {
+		t.Errorf("Unexpected finished turn %+v", turn)
+	}
+}
+
+func TestStdioToken(t *testing.T) {
+	frame := func(body string) string {
+		return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	s := &stdioServer{chat: &Chat{}, tokens: map[string]string{"secret": listenOwner, "alice-token": "alice"}, followers: make(map[*rpcConn]bool)}
+
+	for name, first := range map[string]string{
+		"other method": `{"jsonrpc":"2.0","id":1,"method":"changes/discard"}`,
+		"no token":     `{"jsonrpc":"2.0","id":1,"method":"initialize"}`,
+		"wrong token":  `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"token":"guess"}}`,
+		"notification": `{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		var out bytes.Buffer
+		in := frame(first) + frame(`{"jsonrpc":"2.0","id":2,"method":"exit"}`)
+		if _, err := s.serve(strings.NewReader(in), &out); err == nil {
+			t.Errorf("%s: expected the client to be refused", name)
+		}
+		if s.exit.Load() {
+			t.Fatalf("%s: a refused client stopped the server", name)
+		}
+		if !strings.Contains(out.String(), `"error"`) {
+			t.Errorf("%s: expected an error response, got %q", name, out.String())
+		}
+	}
+
+	if user, rerr := s.authenticate(rpcRequest{Method: "initialize", Params: json.RawMessage(`{"token":"alice-token"}`)}); rerr != nil || user != "alice" {
+		t.Errorf("Expected alice's token to name her, got %q (%v)", user, rerr)
+	}
+}
+
+// listenTestChat returns a chat serving --listen in dir, in the owner's
+// session
+func listenTestChat(t *testing.T, dir string) *Chat {
+	t.Helper()
+	engine := setupTestDB(t)
+	t.Cleanup(func() { engine.Close() })
+	mm := core.NewModuleManager(engine)
+	sm := session.NewManager(engine)
+	if _, err := sm.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	sm.BindUser(listenOwner)
+	return &Chat{engine: engine, modules: mm, session: sm, registry: &providers.Registry{}, git: git.NewManager(dir), learning: modules.NewLearningModule(engine, mm)}
+}
+
+func TestStdioOwnership(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	os.MkdirAll(listenUsersDir, 0o755)
+	os.WriteFile(listenUserConfigPath("alice"), []byte("temperature = 0.1\n"), 0o644)
+
+	c := listenTestChat(t, dir)
+	engine, sm := c.engine, c.session
+	s := newStdioServer(c)
+	s.client = listenOwner
+	alice, bob, owner := &rpcConn{user: "alice"}, &rpcConn{user: "bob"}, &rpcConn{user: listenOwner}
+	ownerSession := sm.Current()
+
+	// Each client gets its own session and config, and only sees its changes
+	s.handle(alice, "changes/preview", nil)
+	aliceSession := sm.Current()
+	if aliceSession == ownerSession {
+		t.Fatal("Expected alice to get her own session")
+	}
+	if v, _ := engine.GetConfig("temperature"); v != "0.1" {
+		t.Errorf("Expected alice's temperature, got %q", v)
+	}
+	s.pending = []FileChange{{Path: "a.go"}}
+
+	if _, err := s.handle(bob, "changes/discard", nil); err != nil {
+		t.Fatal(err)
+	}
+	if sm.Current() == aliceSession || sm.Current() == ownerSession {
+		t.Error("Expected bob to get his own session")
+	}
+	if v, _ := engine.GetConfig("temperature"); v == "0.1" {
+		t.Error("alice's config applied to bob")
+	}
+	if _, err := s.handle(bob, "changes/apply", nil); err == nil {
+		t.Error("Expected bob to have no changes to apply")
+	}
+
+	result, _ := s.handle(alice, "changes/preview", nil)
+	if changes := result.(map[string]interface{})["changes"].([]changePreview); len(changes) != 1 || sm.Current() != aliceSession {
+		t.Errorf("Expected alice's change back in her session, got %v in %s", changes, sm.Current())
+	}
+	s.handle(owner, "changes/preview", nil)
+	if sm.Current() != ownerSession || len(s.pending) != 0 {
+		t.Errorf("Expected the owner's session without alice's changes, got %s", sm.Current())
+	}
+
+	// A client only follows its own turns
+	s.begin(alice, "refactor")
+	if turn := s.attach(bob); turn.Input != "" {
+		t.Errorf("Expected bob not to see alice's turn, got %+v", turn)
+	}
+	if turn := s.attach(alice); turn.Input != "refactor" {
+		t.Errorf("Expected alice to see her turn, got %+v", turn)
+	}
+}
+
+func TestStdioWebSocket(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	s := newStdioServer(listenTestChat(t, t.TempDir()))
+	s.tokens, s.client = map[string]string{"alice-token": "alice"}, listenOwner
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				s.serveConn(conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	url := "ws://" + ln.Addr().String() + "/ws"
+	if _, err := websocket.Dial(ctx, url, http.Header{"Origin": {"https://example.com"}}); err == nil {
+		t.Error("Expected a page from another site to be refused")
+	}
+	if _, err := websocket.Dial(ctx, "ws://"+ln.Addr().String()+"/other", nil); err == nil {
+		t.Error("Expected another path to be refused")
+	}
+
+	ws, err := websocket.Dial(ctx, url, http.Header{"Origin": {"http://localhost:3000"}})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer ws.Close()
+	ws.WriteMessage([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"token":"alice-token"}}`))
+	msg, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	var resp struct {
+		Result map[string]interface{} `json:"result"`
+		Error  *rpcError              `json:"error"`
+	}
+	if err := json.Unmarshal(msg, &resp); err != nil || resp.Error != nil || resp.Result["user"] != "alice" {
+		t.Errorf("Unexpected initialize response %s (%v)", msg, err)
+	}
+
+	ws.WriteMessage([]byte(`{"jsonrpc":"2.0","id":2,"method":"changes/preview"}`))
+	if msg, err = ws.ReadMessage(); err != nil || !strings.Contains(string(msg), `"changes":[]`) {
+		t.Errorf("Unexpected preview response %s (%v)", msg, err)
+	}
+}
@@ -0,0 +1,60 @@
+// Package ui - Learning from undos and /feedback: the user's code style,
+// and answers not to repeat
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/modules"
+)
+
+// learnStyle records the style an undone change broke, given the lines it
+// removed and added
+func (c *Chat) learnStyle(removed, added []string) {
+	prefs := modules.DetectStyle(removed, added)
+	if len(prefs) == 0 {
+		return
+	}
+	if err := c.learning.LearnStyle(prefs); err != nil {
+		core.Logger("learning").Debug("Style not learned", "error", err)
+	}
+}
+
+// styleContext gives the model the style preferences learned for this
+// project (style_preferences of them)
+func (c *Chat) styleContext() string {
+	limit := c.engine.GetConfigInt("style_preferences")
+	if limit <= 0 {
+		return ""
+	}
+	prefs, err := c.learning.StylePreferences(limit)
+	if err != nil {
+		return ""
+	}
+	return modules.StylePrompt(prefs)
+}
+
+// maxUndoneContext caps the undone requests and the answer excerpt shown
+// to the model
+const (
+	maxUndoneContext = 2
+	maxUndoneExcerpt = 300
+)
+
+// undoContext warns the model when the user undid answers to requests like
+// this one
+func (c *Chat) undoContext(request string) string {
+	undone, err := c.learning.SimilarUndone(request, maxUndoneContext)
+	if err != nil || len(undone) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\nThe user undid earlier answers to similar requests; take a different approach:")
+	for _, u := range undone {
+		fmt.Fprintf(&sb, "\n- Request: %s (undone %d times)\n  Undone answer began: %s",
+			u.Prompt, u.Failures, shorten(strings.Join(strings.Fields(u.Response), " "), maxUndoneExcerpt))
+	}
+	return sb.String()
+}
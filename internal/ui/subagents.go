@@ -0,0 +1,113 @@
+// Package ui - Sub-agents: independent subtasks run in parallel conversations
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/tools"
+)
+
+const subAgentPrompt = `You are a sub-agent working on one self-contained task for a coding assistant. Use the tools to read, edit, run commands, and test as needed. Stay within your task: other sub-agents handle the rest. When you are done, reply with a short summary of what you changed and anything left undone.`
+
+// runSubAgents carries out delegated tasks, max_subagents at a time
+func (c *Chat) runSubAgents(ctx context.Context, tasks []tools.SubTask) []tools.SubResult {
+	fmt.Printf("\033[90m🤖 Delegating %d tasks to sub-agents\033[0m\n", len(tasks))
+	results := tools.RunSubAgents(ctx, tasks, c.engine.GetConfigInt("max_subagents"), c.runSubAgent)
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Printf("\033[31m✗ [%s] %v\033[0m\n", r.Name, r.Err)
+		}
+	}
+	return results
+}
+
+// subAgentTools are the tools a sub-agent gets: all of them except
+// delegate, so sub-agents cannot spawn more
+func (c *Chat) subAgentTools() []providers.Tool {
+	defs := make([]providers.Tool, 0)
+	for _, def := range c.tools.Definitions() {
+		if def.Function.Name != tools.DelegateTool {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
+// runSubAgent runs one task in its own conversation. Model calls run in
+// parallel with the other sub-agents; tool calls take turns, since they
+// may ask the user and apply changes. The sub-agent stops after
+// max_tool_rounds or once it has spent subagent_max_tokens.
+func (c *Chat) runSubAgent(ctx context.Context, task tools.SubTask) tools.SubResult {
+	result := tools.SubResult{Name: task.Name}
+	provider := c.registry.Current()
+	if provider == nil {
+		result.Err = fmt.Errorf("no provider available")
+		return result
+	}
+
+	messages := []providers.Message{
+		{Role: "system", Content: subAgentPrompt + c.repoMap()},
+		{Role: "user", Content: task.Task},
+	}
+	toolDefs := c.subAgentTools()
+	maxRounds := c.engine.GetConfigInt("max_tool_rounds")
+	budget := c.engine.GetConfigInt("subagent_max_tokens")
+
+	fmt.Printf("\033[90m🤖 [%s] started\033[0m\n", task.Name)
+	for round := 0; ; round++ {
+		if err := c.checkBudget(); err != nil {
+			result.Err = err
+			return result
+		}
+		resp, err := provider.Generate(ctx, &providers.Request{
+			Messages:    messages,
+			Temperature: 0.3,
+			Tools:       toolDefs,
+		})
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Rounds++
+		result.Tokens += resp.TokensIn + resp.TokensOut
+		result.Summary = strings.TrimSpace(resp.Content)
+
+		if len(resp.ToolCalls) == 0 {
+			break
+		}
+		if round >= maxRounds {
+			result.Err = fmt.Errorf("stopped after %d tool rounds", round)
+			break
+		}
+		if budget > 0 && result.Tokens >= budget {
+			result.Err = fmt.Errorf("stopped at the token budget (%d of subagent_max_tokens %d)", result.Tokens, budget)
+			break
+		}
+
+		messages = append(messages, providers.Message{
+			Role:      "assistant",
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+		calls := make([]providers.ToolCall, 0, len(resp.ToolCalls))
+		for _, call := range resp.ToolCalls {
+			if call.Function.Name == tools.DelegateTool {
+				messages = append(messages, providers.Message{Role: "tool", ToolCallID: call.ID, Content: "error: sub-agents cannot delegate"})
+				continue
+			}
+			calls = append(calls, call)
+		}
+		c.subAgentMu.Lock()
+		fmt.Printf("\033[36m🤖 [%s]\033[0m\n", task.Name)
+		messages = append(messages, c.dispatchToolCalls(calls)...)
+		c.subAgentMu.Unlock()
+	}
+
+	if result.Err == nil {
+		fmt.Printf("\033[32m✓ [%s] done\033[0m \033[90m(%d rounds, %d tokens)\033[0m\n", task.Name, result.Rounds, result.Tokens)
+	}
+	return result
+}
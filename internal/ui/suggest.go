@@ -0,0 +1,85 @@
+// Package ui - "Did you mean": learned commands offered for ambiguous requests
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// suggestThreshold is the parser confidence below which a request counts
+// as ambiguous
+const suggestThreshold = 0.7
+
+// unsure reports whether the parser only fell back to a code request: no
+// pattern matched and no file was named
+func unsure(intent *Intent) bool {
+	return intent.Type == IntentCode && intent.Confidence < suggestThreshold && len(intent.Files) == 0
+}
+
+// teaches reports whether a slash command typed after an ambiguous request
+// says what that request meant (/help or /exit do not)
+func teaches(intent *Intent) bool {
+	switch intent.Type {
+	case IntentHelp, IntentExit, IntentFeedback, IntentInspect, IntentDebug:
+		return false
+	}
+	return intent.Command != ""
+}
+
+// learnIntent closes the learning loop for one input. A slash command
+// typed right after an ambiguous request is learned as its meaning; once
+// learned often enough, the request gets "Did you mean: <command>?" and
+// the answer counts as a success or a failure.
+func (c *Chat) learnIntent(intent *Intent) *Intent {
+	if intent.Learned {
+		c.lastUnsure = ""
+		fmt.Printf("\033[90m💡 %s (learned)\033[0m\n", intent.Raw)
+		c.learning.RecordRoute(intent.Content, intent.Raw, intent.Confidence, true)
+		return intent
+	}
+	if !c.engine.GetConfigBool("intent_suggestions") {
+		return intent
+	}
+	previous := c.lastUnsure
+	c.lastUnsure = ""
+
+	if teaches(intent) {
+		if previous != "" {
+			c.learning.RecordSuccess(previous, intent.Raw)
+		}
+		return intent
+	}
+	if !unsure(intent) {
+		return intent
+	}
+
+	c.lastUnsure = intent.Raw
+	suggestion, confidence, err := c.learning.GetSuggestion(intent.Raw)
+	if err != nil {
+		return intent
+	}
+
+	answer, _ := c.ask(fmt.Sprintf("\033[36m💡 Did you mean: %s? [Y/n] \033[0m", suggestion))
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "" && answer != "y" && answer != "yes" {
+		c.learning.RecordFailure(intent.Raw, suggestion)
+		c.learning.RecordRoute(intent.Raw, suggestion, confidence, false)
+		return intent
+	}
+
+	c.lastUnsure = ""
+	c.learning.RecordSuccess(intent.Raw, suggestion)
+	c.learning.RecordRoute(intent.Raw, suggestion, confidence, true)
+	if learned := c.parser.Parse(suggestion); learned != nil {
+		return learned
+	}
+	return intent
+}
+
+// learnedIntent is the parser's view of learned intents, when
+// learned_routing is on
+func (c *Chat) learnedIntent(input string) (string, float64, error) {
+	if !c.engine.GetConfigBool("learned_routing") {
+		return "", 0, nil
+	}
+	return c.learning.GetSuggestion(input)
+}
@@ -0,0 +1,27 @@
+package ui
+
+import "testing"
+
+func TestUnsure(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+
+	parser := NewIntentParser(engine.DB())
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"where are we at", true},
+		{"/diff", false},
+		{"fix the bug in main.go", false},
+		{"add a fibonacci function", true},
+		{"undo", false},
+	}
+
+	for _, tt := range tests {
+		if got := unsure(parser.Parse(tt.input)); got != tt.want {
+			t.Errorf("unsure(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package ui - Symbol index: /symbols, the repo map, and keeping both current
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/index"
+)
+
+// maxSymbolResults caps the matches /symbols prints
+const maxSymbolResults = 50
+
+// syncSymbols indexes the workspace's changed files; it runs in the
+// background at startup and before each /symbols search
+func (c *Chat) syncSymbols() {
+	if c.symbols == nil || c.index == nil {
+		return
+	}
+	if _, err := c.symbols.Sync(c.index.Files()); err != nil {
+		core.Logger("symbols").Debug("Symbol index not synced", "error", err)
+	}
+}
+
+// trackFile records a written file in the file and symbol indexes
+func (c *Chat) trackFile(path string) {
+	c.index.Add(path)
+	if c.symbols != nil {
+		c.symbols.Update(path)
+	}
+	c.wroteFile(path)
+}
+
+// untrackFile drops a removed file from the file and symbol indexes
+func (c *Chat) untrackFile(path string) {
+	c.index.Remove(path)
+	if c.symbols != nil {
+		c.symbols.Remove(path)
+	}
+	c.wroteFile(path)
+}
+
+// repoMap returns the symbol outline sent with each request, within repo_map_bytes
+func (c *Chat) repoMap() string {
+	maxBytes := c.engine.GetConfigInt("repo_map_bytes")
+	if c.symbols == nil || maxBytes <= 0 {
+		return ""
+	}
+	outline, err := c.symbols.Map(maxBytes)
+	if err != nil || outline == "" {
+		return ""
+	}
+	return "\n\nRepository map (file: declarations):\n" + outline
+}
+
+// handleSymbols lists the declarations matching a name, or outlines a file
+func (c *Chat) handleSymbols(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /symbols <name> | <file>")
+	}
+	if c.symbols == nil {
+		return fmt.Errorf("symbol index not available")
+	}
+	c.syncSymbols()
+
+	query := strings.Join(args, " ")
+	if index.Supported(query) && fileExists(query) {
+		symbols, err := c.symbols.FileSymbols(query)
+		if err != nil {
+			return err
+		}
+		if len(symbols) == 0 {
+			fmt.Printf("\033[90mNo symbols in %s\033[0m\n", query)
+			return nil
+		}
+		fmt.Printf("\n\033[33m%s:\033[0m\n", query)
+		for _, s := range symbols {
+			fmt.Printf("  \033[90m%5d\033[0m %-7s %s\n", s.Line, s.Kind, s.Signature)
+		}
+		return nil
+	}
+
+	symbols, err := c.symbols.Search(query, maxSymbolResults)
+	if err != nil {
+		return err
+	}
+	if len(symbols) == 0 {
+		fmt.Printf("\033[90mNo symbols matching %q\033[0m\n", query)
+		return nil
+	}
+	fmt.Println()
+	for _, s := range symbols {
+		fmt.Printf("  \033[36m%s:%d\033[0m %-7s %s\n", s.Path, s.Line, s.Kind, s.Signature)
+	}
+	if len(symbols) == maxSymbolResults {
+		fmt.Printf("\033[90m  ... (first %d shown)\033[0m\n", maxSymbolResults)
+	}
+	return nil
+}
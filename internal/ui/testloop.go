@@ -0,0 +1,68 @@
+// Package ui - /test: run the project's tests and loop failures back to the LLM
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/tools"
+)
+
+// testCommand returns the configured test command, or the detected one
+func (c *Chat) testCommand() string {
+	if command, _ := c.engine.GetConfig("test_command"); command != "" {
+		return command
+	}
+	return tools.DetectTestCommand(c.git.WorkDir())
+}
+
+// handleTest runs the tests ("/test <cmd>" overrides the command) and, while
+// they fail, asks the LLM for fixes up to max_test_iterations times
+func (c *Chat) handleTest(args []string) error {
+	command := strings.Join(args, " ")
+	if command == "" {
+		command = c.testCommand()
+	}
+	if command == "" {
+		return fmt.Errorf("no test command detected; set test_command or use /test <cmd>")
+	}
+
+	maxRounds := c.engine.GetConfigInt("max_test_iterations")
+	timeout := time.Duration(c.engine.GetConfigInt("test_timeout")) * time.Second
+	for round := 0; ; round++ {
+		fmt.Printf("\033[90m🧪 %s\033[0m\n", command)
+		start := time.Now()
+		result := tools.ExecuteTests(c.ctx, c.runShell, c.git.WorkDir(), command, timeout, maxCommandOutput)
+		elapsed := time.Since(start).Round(time.Millisecond)
+
+		if result.Passed() {
+			fmt.Printf("\033[32m✓ Tests passed\033[0m \033[90m(%s)\033[0m\n", elapsed)
+			return nil
+		}
+
+		fmt.Printf("\033[31m✗ Tests failed: %s\033[0m \033[90m(%s)\033[0m\n", result.Status(), elapsed)
+		for _, name := range result.Failures {
+			fmt.Printf("  \033[90m%s\033[0m\n", name)
+		}
+
+		if round >= maxRounds {
+			if maxRounds > 0 {
+				fmt.Printf("\033[33m⚠️  Still failing after %d fix rounds, stopping\033[0m\n", round)
+			}
+			return nil
+		}
+
+		fmt.Printf("\n\033[33m🔧 Test fix round %d/%d\033[0m\n", round+1, maxRounds)
+		prompt := "The tests fail:\n\n```\n" + result.Report() +
+			"\n```\n\nFix the code so the tests pass. Change the tests only if they are wrong."
+		if err := c.handleChat(&Intent{
+			Type:       IntentCode,
+			Content:    prompt,
+			Raw:        prompt,
+			Confidence: 1.0,
+		}); err != nil {
+			return err
+		}
+	}
+}
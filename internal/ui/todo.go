@@ -0,0 +1,231 @@
+// Package ui - /todo and /resume: the session's todo list and returning to it
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/session"
+	"github.com/hazyhaar/GoClode/internal/tools"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// basePrompt is the input prompt without todo progress
+const basePrompt = "\033[36m>\033[0m "
+
+// maxPromptTask caps the current task shown in the prompt
+const maxPromptTask = 30
+
+// taskList formats the todo list for the model
+func taskList(tasks []session.Task) string {
+	if len(tasks) == 0 {
+		return "The todo list is empty."
+	}
+	var sb strings.Builder
+	for _, t := range tasks {
+		mark := " "
+		switch t.Status {
+		case session.TaskInProgress:
+			mark = "~"
+		case session.TaskDone:
+			mark = "x"
+		}
+		fmt.Fprintf(&sb, "[%s] %d. %s\n", mark, t.Position, t.Content)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// taskPrompt shows todo progress and the current task before the prompt
+func taskPrompt(tasks []session.Task) string {
+	done, current := session.Progress(tasks)
+	if current == nil {
+		return basePrompt
+	}
+	label := current.Content
+	if r := []rune(label); len(r) > maxPromptTask {
+		label = string(r[:maxPromptTask-1]) + "…"
+	}
+	return fmt.Sprintf("\033[90m[%d/%d %s]\033[0m %s", done, len(tasks), label, basePrompt)
+}
+
+// updatePrompt refreshes the todo progress shown in the prompt, and marks
+// offline mode
+func (c *Chat) updatePrompt() {
+	tasks, _ := c.session.Tasks() // The prompt may still hold a question
+	prompt := taskPrompt(tasks)
+	if c.isOffline() {
+		prompt = "\033[33m[offline]\033[0m " + prompt
+	}
+	c.rl.SetPrompt(prompt)
+}
+
+// todoContext gives the model the todo list while work remains on it
+func (c *Chat) todoContext() string {
+	tasks, err := c.session.Tasks()
+	if err != nil {
+		return ""
+	}
+	if _, current := session.Progress(tasks); current == nil {
+		return ""
+	}
+	return "\n\nTodo list for the current work (keep it up to date with the todo tool):\n" + taskList(tasks)
+}
+
+// saveTodos stores the list the todo tool sends
+func (c *Chat) saveTodos(items []tools.TodoItem) error {
+	tasks := make([]session.Task, 0, len(items))
+	for _, item := range items {
+		tasks = append(tasks, session.Task{Content: item.Content, Status: item.Status})
+	}
+	if err := c.session.SetTasks(tasks); err != nil {
+		return err
+	}
+	c.printTasks()
+	return nil
+}
+
+// listTodos is the todo tool's view of the stored list
+func (c *Chat) listTodos() string {
+	tasks, err := c.session.Tasks()
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return taskList(tasks)
+}
+
+// printTasks shows the todo list with each task's status
+func (c *Chat) printTasks() {
+	tasks, err := c.session.Tasks()
+	if err != nil {
+		fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+		return
+	}
+	fmt.Println("\n\033[33mTodo:\033[0m")
+	if len(tasks) == 0 {
+		fmt.Println("  \033[90m(empty)\033[0m")
+	}
+	for _, t := range tasks {
+		icon := "\033[90m○\033[0m"
+		switch t.Status {
+		case session.TaskInProgress:
+			icon = "\033[36m▶\033[0m"
+		case session.TaskDone:
+			icon = "\033[32m✓\033[0m"
+		}
+		fmt.Printf("  %s %d. %s\n", icon, t.Position, t.Content)
+	}
+}
+
+// handleTodo shows the todo list, or edits it: /todo add <text>,
+// /todo start|done|undo <n>, /todo rm <n>, /todo clear
+func (c *Chat) handleTodo(args []string) error {
+	if len(args) == 0 {
+		c.printTasks()
+		return nil
+	}
+
+	var err error
+	switch args[0] {
+	case "add":
+		content := strings.TrimSpace(strings.Join(args[1:], " "))
+		if content == "" {
+			return fmt.Errorf("usage: /todo add <task>")
+		}
+		err = c.session.AddTask(content)
+	case "start", "done", "undo", "rm", "remove":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: /todo %s <n>", args[0])
+		}
+		n, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("invalid task number %q", args[1])
+		}
+		switch args[0] {
+		case "start":
+			err = c.session.SetTaskStatus(n, session.TaskInProgress)
+		case "done":
+			err = c.session.SetTaskStatus(n, session.TaskDone)
+		case "undo":
+			err = c.session.SetTaskStatus(n, session.TaskPending)
+		default:
+			err = c.session.RemoveTask(n)
+		}
+	case "clear":
+		var n int
+		if n, err = c.session.ClearDoneTasks(); err == nil {
+			fmt.Printf("\033[90mCleared %d done tasks\033[0m\n", n)
+		}
+	default:
+		return fmt.Errorf("usage: /todo [add <task> | start|done|undo|rm <n> | clear]")
+	}
+	if err != nil {
+		return err
+	}
+	c.printTasks()
+	return nil
+}
+
+// handleResume lists recent sessions, or switches to one by number or ID
+// prefix, bringing back its conversation and todo list
+func (c *Chat) handleResume(args []string) error {
+	sessions, err := c.session.ListSessions(10)
+	if err != nil {
+		return err
+	}
+	others := make([]session.Session, 0, len(sessions))
+	for _, s := range sessions {
+		if s.ID != c.session.Current() {
+			others = append(others, s)
+		}
+	}
+
+	if len(args) == 0 {
+		fmt.Println("\n\033[33mRecent sessions:\033[0m")
+		if len(others) == 0 {
+			fmt.Println("  \033[90m(none)\033[0m")
+		}
+		for i, s := range others {
+			tasks, _ := c.session.SessionTasks(s.ID)
+			progress := ""
+			if len(tasks) > 0 {
+				done, _ := session.Progress(tasks)
+				progress = fmt.Sprintf("  \033[90mtodo %d/%d\033[0m", done, len(tasks))
+			}
+			fmt.Printf("  %2d. %s  %s%s\n", i+1, s.ID[:8], s.LastActiveAt.Format(time.DateTime), progress)
+		}
+		fmt.Println("\033[90m/resume <n|id> to continue one\033[0m")
+		return nil
+	}
+
+	target := ""
+	if n, err := strconv.Atoi(args[0]); err == nil && n >= 1 && n <= len(others) {
+		target = others[n-1].ID
+	} else if all, err := c.session.ListSessions(1000); err == nil {
+		for _, s := range all {
+			if s.ID != c.session.Current() && strings.HasPrefix(s.ID, args[0]) {
+				target = s.ID
+				break
+			}
+		}
+	}
+	if target == "" {
+		return fmt.Errorf("no recent session %q (see /resume)", args[0])
+	}
+
+	return c.resumeSession(target)
+}
+
+// resumeSession makes an earlier session the current one
+func (c *Chat) resumeSession(id string) error {
+	if err := c.session.SetSession(id); err != nil {
+		return err
+	}
+	c.backups = workspace.NewBackups(c.git.WorkDir(), id)
+	c.assignVariant()
+	c.taskCommit, c.taskSummaries = "", nil
+	fmt.Printf("\033[32m✓ Resumed session %s\033[0m\n", id[:8])
+	c.printTasks()
+	return nil
+}
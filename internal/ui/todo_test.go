@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+func TestTaskPrompt(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks []session.Task
+		want  string
+	}{
+		{"no tasks", nil, basePrompt},
+		{"all done", []session.Task{{Content: "a", Status: session.TaskDone}}, basePrompt},
+		{"in progress", []session.Task{
+			{Content: "a", Status: session.TaskDone},
+			{Content: "b", Status: session.TaskPending},
+			{Content: "c", Status: session.TaskInProgress},
+		}, "\033[90m[1/3 c]\033[0m " + basePrompt},
+		{"long task", []session.Task{
+			{Content: "rewrite the configuration loader to use the new schema", Status: session.TaskPending},
+		}, "\033[90m[0/1 rewrite the configuration loa…]\033[0m " + basePrompt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := taskPrompt(tt.tasks); got != tt.want {
+				t.Errorf("taskPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,426 @@
+// Package ui - Tool-call dispatch: structured file edits and other tools
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/tools"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// maxCommandOutput caps the command output returned to the model
+const maxCommandOutput = 16 * 1024
+
+// approveCommand decides whether run_command may execute a command: a
+// matching permission rule first, then the command allowlist, then the user
+func (c *Chat) approveCommand(command string) bool {
+	decision, err := c.permissions.Decide(tools.RunCommandTool, command)
+	if err != nil {
+		fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+	}
+	switch decision {
+	case tools.PermAllow:
+		fmt.Printf("\033[90m$ %s\033[0m\n", command)
+		return true
+	case tools.PermDeny:
+		fmt.Printf("\033[31m✗ $ %s (denied by a permission rule)\033[0m\n", command)
+		return false
+	case tools.PermAsk:
+		return c.askPermission(tools.RunCommandTool, command, "$ "+command)
+	}
+
+	// In the container a command can only reach the workspace, so it may
+	// run unattended
+	if c.engine.GetConfigBool("sandbox_auto_approve") && c.currentSandbox() != nil {
+		fmt.Printf("\033[90m$ %s (sandbox)\033[0m\n", command)
+		return true
+	}
+
+	raw, _ := c.engine.GetConfig("command_allowlist")
+	if tools.MatchCommand(command, workspace.ParsePatternList(raw)) {
+		fmt.Printf("\033[90m$ %s\033[0m\n", command)
+		return true
+	}
+	return c.askPermission(tools.RunCommandTool, command, "$ "+command)
+}
+
+// approveSnippet decides whether run_snippet may run code: a permission
+// rule for the tool, then sandbox_auto_approve, then the user
+func (c *Chat) approveSnippet(language, code string) bool {
+	display := fmt.Sprintf("run_snippet (%s):\n%s", language, code)
+	decision, err := c.permissions.Decide(tools.RunSnippetTool, "")
+	if err != nil {
+		fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+	}
+	switch decision {
+	case tools.PermAllow:
+		fmt.Printf("\033[90m▶ run_snippet (%s)\033[0m\n", language)
+		return true
+	case tools.PermDeny:
+		fmt.Printf("\033[31m✗ run_snippet (denied by a permission rule)\033[0m\n")
+		return false
+	case tools.PermAsk:
+		return c.askPermission(tools.RunSnippetTool, "", display)
+	}
+
+	if c.engine.GetConfigBool("sandbox_auto_approve") && c.currentSandbox() != nil {
+		fmt.Printf("\033[90m▶ run_snippet (%s, sandbox)\033[0m\n", language)
+		return true
+	}
+	return c.askPermission(tools.RunSnippetTool, "", display)
+}
+
+// permitTool applies permission rules to a call of any other tool. Only
+// deny and ask rules matter: without one, tools run as before (file edits
+// are still confirmed when applied).
+func (c *Chat) permitTool(call providers.ToolCall) bool {
+	name := call.Function.Name
+	if name == tools.RunCommandTool || name == tools.RunSnippetTool {
+		return true // approveCommand and approveSnippet decide per call
+	}
+	decision, err := c.permissions.Decide(name, "")
+	if err != nil {
+		fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+	}
+	switch decision {
+	case tools.PermDeny:
+		fmt.Printf("\033[31m✗ %s (denied by a permission rule)\033[0m\n", name)
+		return false
+	case tools.PermAsk:
+		if tools.IsFileTool(name) {
+			return true // applyChanges asks before writing
+		}
+		return c.askPermission(name, "", name+" "+call.Function.Arguments)
+	}
+	return true
+}
+
+// askPermission asks the user about one call; "always" and "never" answers
+// are saved as rules for the tool and pattern
+func (c *Chat) askPermission(tool, pattern, display string) bool {
+	fmt.Printf("\n\033[33m%s\033[0m\n", display)
+	answer, _ := c.ask("\033[36mAllow? [y]es / [N]o / [a]lways / ne[v]er \033[0m")
+
+	decision := ""
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	case "a", "always":
+		decision = tools.PermAllow
+	case "v", "never":
+		decision = tools.PermDeny
+	default:
+		return false
+	}
+
+	if err := c.permissions.Set(tool, pattern, decision); err != nil {
+		fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+	} else {
+		fmt.Printf("\033[90mSaved: %s %s (edit with /permissions)\033[0m\n", decision, ruleLabel(tool, pattern))
+	}
+	return decision == tools.PermAllow
+}
+
+// streamResponse streams one completion to the terminal and returns it,
+// including any tool calls the model made. A reply that reaches
+// max_response_tokens or max_response_seconds is returned cut short, for
+// /continue to finish.
+func (c *Chat) streamResponse(provider providers.Provider, messages []providers.Message, toolDefs []providers.Tool) (*providers.Response, error) {
+	if err := c.checkBudget(); err != nil {
+		return nil, err
+	}
+	if c.isOffline() {
+		return nil, fmt.Errorf("%w: the provider cannot be reached (/queue)", errOffline)
+	}
+
+	// Show thinking indicator
+	if c.onDelta == nil {
+		fmt.Print("\033[90m🤔 Thinking...\033[0m")
+	}
+
+	ctx := c.ctx
+	seconds := c.engine.GetConfigInt("max_response_seconds")
+	if seconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(c.ctx, time.Duration(seconds)*time.Second)
+		defer cancel()
+	}
+	timedOut := func() bool {
+		return errors.Is(ctx.Err(), context.DeadlineExceeded) && c.ctx.Err() == nil
+	}
+
+	start := time.Now()
+	stream, err := provider.Stream(ctx, &providers.Request{
+		Messages:    messages,
+		Temperature: 0.7,
+		MaxTokens:   max(c.engine.GetConfigInt("max_response_tokens"), 0),
+		Tools:       toolDefs,
+	})
+	if err != nil {
+		if c.onDelta == nil {
+			fmt.Println()
+		}
+		if timedOut() {
+			return nil, fmt.Errorf("no reply within max_response_seconds (%ds)", seconds)
+		}
+		if providers.IsUnreachable(err) {
+			c.goOffline(err)
+			return nil, fmt.Errorf("%w: %v", errOffline, err)
+		}
+		return nil, fmt.Errorf("stream: %w", err)
+	}
+
+	// Clear thinking indicator
+	if c.onDelta == nil {
+		fmt.Print("\r\033[K")
+	}
+	ahead := c.startTypeAhead()
+	if ahead != nil {
+		defer ahead.stop()
+	}
+
+	var fullResponse strings.Builder
+	resp := &providers.Response{Model: provider.ID()}
+
+	for chunk := range stream {
+		if chunk.Error != nil {
+			if timedOut() {
+				resp.FinishReason = providers.FinishTimeout
+				go func() {
+					for range stream { // Let the provider finish sending
+					}
+				}()
+				break
+			}
+			if providers.IsUnreachable(chunk.Error) {
+				c.goOffline(chunk.Error)
+				return nil, fmt.Errorf("%w: %v", errOffline, chunk.Error)
+			}
+			return nil, chunk.Error
+		}
+
+		if chunk.Delta != "" {
+			switch {
+			case c.onDelta != nil:
+				c.onDelta(chunk.Delta)
+			case ahead != nil:
+				ahead.print(chunk.Delta)
+			default:
+				fmt.Print(chunk.Delta)
+			}
+			fullResponse.WriteString(chunk.Delta)
+		}
+
+		if chunk.Done {
+			resp.TokensIn = chunk.TokensIn
+			resp.TokensOut = chunk.TokensOut
+			resp.ToolCalls = chunk.ToolCalls
+			resp.FinishReason = chunk.FinishReason
+		}
+	}
+	switch {
+	case ahead != nil:
+		ahead.stop() // Ends the last line
+	case c.onDelta == nil:
+		fmt.Println()
+	}
+
+	resp.Content = fullResponse.String()
+	resp.Latency = time.Since(start).Milliseconds()
+	if resp.Truncated() {
+		c.reportTruncated(resp, seconds)
+	}
+	return resp, nil
+}
+
+// reportTruncated drops the tool calls of a reply cut short, whose
+// arguments may be incomplete, and says how to finish it
+func (c *Chat) reportTruncated(resp *providers.Response, seconds int) {
+	if len(resp.ToolCalls) > 0 {
+		core.Logger("ui").Warn("Tool calls of a truncated reply dropped", "calls", len(resp.ToolCalls))
+		resp.ToolCalls = nil
+	}
+	if c.onDelta != nil {
+		return
+	}
+	switch {
+	case resp.FinishReason == providers.FinishTimeout:
+		fmt.Printf("\033[33m⏱️  Stopped after max_response_seconds (%ds): /continue resumes the reply\033[0m\n", seconds)
+	case c.engine.GetConfigInt("max_response_tokens") > 0:
+		fmt.Printf("\033[33m✂️  Stopped at max_response_tokens (%d): /continue resumes the reply\033[0m\n", c.engine.GetConfigInt("max_response_tokens"))
+	default:
+		fmt.Println("\033[33m✂️  Stopped at the model's length limit: /continue resumes the reply\033[0m")
+	}
+}
+
+// messageContent is what gets stored for an assistant turn; tool-only
+// turns are recorded as a list of the calls made
+func messageContent(resp *providers.Response) string {
+	if resp.Content != "" || len(resp.ToolCalls) == 0 {
+		return resp.Content
+	}
+
+	names := make([]string, 0, len(resp.ToolCalls))
+	for _, call := range resp.ToolCalls {
+		names = append(names, call.Function.Name)
+	}
+	return "[tool calls: " + strings.Join(names, ", ") + "]"
+}
+
+// dispatchToolCalls runs the tools the model called and returns one tool
+// message per call. File edits are applied together as a single change set.
+func (c *Chat) dispatchToolCalls(calls []providers.ToolCall) []providers.Message {
+	results := make(map[string]string)
+
+	permitted := make([]providers.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		if c.permitTool(call) {
+			permitted = append(permitted, call)
+		} else {
+			results[call.ID] = "not run: denied by the user's permission rules"
+		}
+	}
+
+	changes, errs := toolCallsToChanges(permitted)
+	if len(changes) > 0 {
+		applied, err := c.applyChanges(changes)
+
+		status := "applied"
+		switch {
+		case err != nil:
+			status = "error: " + err.Error()
+		case c.pendingFix != "":
+			if !applied {
+				status = "not applied: validation failed:\n" + c.pendingFix
+			} else {
+				status = "applied, but validation failed:\n" + c.pendingFix
+			}
+			c.pendingFix = ""
+		case !applied:
+			status = "not applied: the user declined the changes"
+		}
+
+		for _, call := range permitted {
+			if tools.IsFileTool(call.Function.Name) {
+				results[call.ID] = status
+			}
+		}
+	}
+
+	for _, call := range permitted {
+		if err, ok := errs[call.ID]; ok {
+			results[call.ID] = "error: " + err.Error()
+			continue
+		}
+		if tools.IsFileTool(call.Function.Name) {
+			continue
+		}
+
+		fmt.Printf("\033[90m🔧 %s\033[0m\n", call.Function.Name)
+		out, err := c.tools.Call(c.ctx, call)
+		if err != nil {
+			out = "error: " + err.Error()
+		}
+		results[call.ID] = out
+	}
+
+	messages := make([]providers.Message, 0, len(calls))
+	for _, call := range calls {
+		messages = append(messages, providers.Message{
+			Role:       "tool",
+			ToolCallID: call.ID,
+			Content:    results[call.ID],
+		})
+	}
+	return messages
+}
+
+// toolCallsToChanges converts file tool calls into changes, merging edits to
+// the same file. Calls with bad arguments get an error keyed by call ID.
+func toolCallsToChanges(calls []providers.ToolCall) ([]FileChange, map[string]error) {
+	changes := make([]FileChange, 0)
+	errs := make(map[string]error)
+	byPath := make(map[string]int)
+
+	for _, call := range calls {
+		ch, err := toolCallToChange(call)
+		if err != nil {
+			errs[call.ID] = err
+			continue
+		}
+		if ch == nil {
+			continue
+		}
+
+		i, ok := byPath[ch.Path]
+		if !ok {
+			byPath[ch.Path] = len(changes)
+			changes = append(changes, *ch)
+			continue
+		}
+
+		prev := &changes[i]
+		switch {
+		case len(ch.Edits) > 0 && prev.Delete:
+			errs[call.ID] = fmt.Errorf("%s is deleted by an earlier call", ch.Path)
+		case len(ch.Edits) > 0 && len(prev.Edits) > 0:
+			prev.Edits = append(prev.Edits, ch.Edits...)
+		case len(ch.Edits) > 0:
+			// Edit of content written by an earlier call
+			updated, err := applyEdits(prev.Content, ch.Edits, false)
+			if err != nil {
+				errs[call.ID] = err
+				continue
+			}
+			prev.Content = updated
+		default:
+			// A later write or delete replaces earlier calls
+			*prev = *ch
+		}
+	}
+	return changes, errs
+}
+
+// toolCallToChange decodes one file tool call; other tools return nil
+func toolCallToChange(call providers.ToolCall) (*FileChange, error) {
+	args := []byte(call.Function.Arguments)
+
+	var ch FileChange
+	switch call.Function.Name {
+	case tools.WriteFileTool:
+		var a tools.WriteFileArgs
+		if err := json.Unmarshal(args, &a); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		ch = FileChange{Path: a.Path, Content: a.Content}
+	case tools.EditFileTool:
+		var a tools.EditFileArgs
+		if err := json.Unmarshal(args, &a); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		if a.Search == "" {
+			return nil, fmt.Errorf("search text is empty")
+		}
+		ch = FileChange{Path: a.Path, Edits: []EditBlock{{Search: a.Search, Replace: a.Replace}}}
+	case tools.DeleteFileTool:
+		var a tools.DeleteFileArgs
+		if err := json.Unmarshal(args, &a); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		ch = FileChange{Path: a.Path, Delete: true}
+	default:
+		return nil, nil
+	}
+
+	if strings.TrimSpace(ch.Path) == "" {
+		return nil, fmt.Errorf("missing path")
+	}
+	return &ch, nil
+}
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+)
+
+func toolCall(id, name, args string) providers.ToolCall {
+	return providers.ToolCall{
+		ID:       id,
+		Type:     "function",
+		Function: providers.ToolCallFunction{Name: name, Arguments: args},
+	}
+}
+
+func TestToolCallsToChanges(t *testing.T) {
+	calls := []providers.ToolCall{
+		toolCall("1", "write_file", `{"path": "new.go", "content": "package x\n\nvar a = 1\n"}`),
+		toolCall("2", "edit_file", `{"path": "old.go", "search": "a", "replace": "b"}`),
+		toolCall("3", "edit_file", `{"path": "old.go", "search": "c", "replace": "d"}`),
+		toolCall("4", "edit_file", `{"path": "new.go", "search": "var a = 1", "replace": "var a = 2"}`),
+		toolCall("5", "delete_file", `{"path": "gone.go"}`),
+		toolCall("6", "write_file", `{"content": "x"}`),
+		toolCall("7", "edit_file", `not json`),
+		toolCall("8", "read_file", `{"path": "x.go"}`),
+	}
+
+	changes, errs := toolCallsToChanges(calls)
+
+	if len(changes) != 3 {
+		t.Fatalf("got %d changes, want 3: %+v", len(changes), changes)
+	}
+	if changes[0].Path != "new.go" || changes[0].Content != "package x\n\nvar a = 2\n" {
+		t.Errorf("write then edit not merged: %+v", changes[0])
+	}
+	if changes[1].Path != "old.go" || len(changes[1].Edits) != 2 {
+		t.Errorf("edits not merged: %+v", changes[1])
+	}
+	if !changes[2].Delete || changes[2].Path != "gone.go" {
+		t.Errorf("delete not decoded: %+v", changes[2])
+	}
+
+	for _, id := range []string{"6", "7"} {
+		if errs[id] == nil {
+			t.Errorf("call %s: expected an error", id)
+		}
+	}
+	if len(errs) != 2 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestMessageContent(t *testing.T) {
+	resp := &providers.Response{ToolCalls: []providers.ToolCall{
+		toolCall("1", "write_file", "{}"),
+		toolCall("2", "delete_file", "{}"),
+	}}
+	if got := messageContent(resp); got != "[tool calls: write_file, delete_file]" {
+		t.Errorf("messageContent() = %q", got)
+	}
+
+	resp.Content = "Done."
+	if got := messageContent(resp); got != "Done." {
+		t.Errorf("messageContent() = %q", got)
+	}
+}
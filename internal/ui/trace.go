@@ -0,0 +1,104 @@
+// Package ui - /trace: inspecting what the debug module recorded
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/modules"
+)
+
+const traceListLimit = 20
+
+// traceStatus colours a trace status
+func traceStatus(t modules.Trace) string {
+	switch {
+	case t.Status == "error":
+		return "\033[31m✗ error\033[0m"
+	case t.Failed > 0:
+		return fmt.Sprintf("\033[33m✗ %d failed\033[0m", t.Failed)
+	case t.Status == "success":
+		return "\033[32m✓ ok\033[0m"
+	}
+	return "\033[90m" + t.Status + "\033[0m"
+}
+
+// handleTrace lists recent traces, only failed ones with /trace errors,
+// or shows one trace's events and assertions with /trace <id>
+func (c *Chat) handleTrace(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: /trace [errors | <id>]")
+	}
+	if len(args) == 1 && args[0] != "errors" {
+		return c.showTrace(args[0])
+	}
+
+	traces, err := c.debug.Traces(traceListLimit, len(args) == 1)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\n\033[33mTraces:\033[0m")
+	if len(traces) == 0 {
+		if c.debugMode {
+			fmt.Println("  \033[90m(none yet)\033[0m")
+		} else {
+			fmt.Println("  \033[90m(none: traces are recorded in debug mode, /debug to turn it on)\033[0m")
+		}
+	}
+	for _, t := range traces {
+		fmt.Printf("  %s  %s  %-20s %-20s %6dms  %s\n",
+			t.ID[:8], t.Start.Format(time.TimeOnly), shorten(t.Event, 20), shorten(t.Module, 20),
+			t.Duration.Milliseconds(), traceStatus(t))
+	}
+	if len(traces) > 0 {
+		fmt.Println("\033[90m/trace <id> for a trace's events and assertions\033[0m")
+	}
+	return nil
+}
+
+// showTrace prints one trace with its events and assertions
+func (c *Chat) showTrace(id string) error {
+	t, err := c.debug.Trace(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n\033[33mTrace %s\033[0m %s\n", t.ID, traceStatus(t.Trace))
+	fmt.Printf("  Event:    %s\n", t.Event)
+	fmt.Printf("  Modules:  %s\n", t.Module)
+	fmt.Printf("  Started:  %s\n", t.Start.Format("2006-01-02 15:04:05.000"))
+	fmt.Printf("  Duration: %dms\n", t.Duration.Milliseconds())
+	if t.Error != "" {
+		fmt.Printf("  \033[31mError:    %s\033[0m\n", t.Error)
+	}
+
+	fmt.Println("\n\033[33mEvents:\033[0m")
+	if len(t.Events) == 0 {
+		fmt.Println("  \033[90m(none)\033[0m")
+	}
+	for _, e := range t.Events {
+		level := e.Level
+		if level == "error" {
+			level = "\033[31m" + level + "\033[0m"
+		}
+		fmt.Printf("  +%5dms  %-5s %-12s %s \033[90m(%dms)\033[0m\n",
+			e.Time.Sub(t.Start).Milliseconds(), level, shorten(e.Module, 12), e.Message, e.Duration.Milliseconds())
+		if e.Data != "{}" && e.Data != "" {
+			fmt.Printf("           \033[90m%s\033[0m\n", shorten(e.Data, 100))
+		}
+	}
+
+	fmt.Println("\n\033[33mAssertions:\033[0m")
+	if len(t.Assertions) == 0 {
+		fmt.Println("  \033[90m(none)\033[0m")
+	}
+	for _, a := range t.Assertions {
+		if a.Passed {
+			fmt.Printf("  \033[32m✓\033[0m %s = %q\n", a.Name, a.Actual)
+		} else {
+			fmt.Printf("  \033[31m✗\033[0m %s: expected %q, got %q\n", a.Name, a.Expected, a.Actual)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+// Package ui - Undo/redo of applied changes, via git or recorded snapshots
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/modules"
+	"github.com/hazyhaar/GoClode/internal/session"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// handleUndo reverts changes. Without arguments it reverts GoClode's latest
+// live commit in a git repo (repeatedly walking back through older ones), or
+// the last applied batch elsewhere. "/undo last" and "/undo file <path>"
+// restore the snapshots recorded in files_modified.
+func (c *Chat) handleUndo(args []string) error {
+	if len(args) == 0 && c.git.IsRepo() {
+		return c.undoCommit()
+	}
+	return c.revertSnapshots(args, false)
+}
+
+// handleRedo re-applies what /undo reverted: the last reverted commit in a
+// git repo, otherwise snapshots ("/redo [last]" or "/redo file <path>")
+func (c *Chat) handleRedo(args []string) error {
+	if len(args) == 0 && c.git.IsRepo() {
+		if commit, err := c.session.LastRevertedCommit(); err == nil {
+			return c.redoCommit(commit)
+		}
+	}
+	return c.revertSnapshots(args, true)
+}
+
+// undoCommit reverts the newest GoClode commit still live on this branch
+func (c *Chat) undoCommit() error {
+	commits, err := c.session.UndoableCommits(50)
+	if err != nil {
+		return err
+	}
+
+	for _, commit := range commits {
+		if !c.git.IsAncestor(commit.Hash) {
+			continue // Made on another branch, or rewritten since
+		}
+
+		if patch, err := c.git.Show(commit.Hash); err == nil {
+			c.learnStyle(modules.PatchLines(patch))
+		}
+		revertHash, err := c.git.Revert(commit.Hash)
+		if err != nil {
+			return err
+		}
+		if err := c.session.MarkCommitReverted(commit.ID, revertHash); err != nil {
+			return err
+		}
+
+		fmt.Printf("\033[32m✓ Reverted commit %s\033[0m \033[90m%s\033[0m\n", commit.Hash[:8], firstLine(commit.Message))
+		c.recordUndo(commit.MessageID)
+		return nil
+	}
+	return fmt.Errorf("no GoClode commit to undo on this branch")
+}
+
+// redoCommit reverts the revert made by /undo
+func (c *Chat) redoCommit(commit *session.CommitRecord) error {
+	if !c.git.IsAncestor(commit.RevertHash) {
+		return fmt.Errorf("revert %s is not on this branch", commit.RevertHash[:8])
+	}
+
+	if _, err := c.git.Revert(commit.RevertHash); err != nil {
+		return err
+	}
+	if err := c.session.MarkCommitRestored(commit.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("\033[32m✓ Restored commit %s\033[0m \033[90m%s\033[0m\n", commit.Hash[:8], firstLine(commit.Message))
+	return nil
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// revertSnapshots restores content_before (undo) or content_after (redo) of
+// the selected recorded changes
+func (c *Chat) revertSnapshots(args []string, redo bool) error {
+	changes, err := c.snapshotTargets(args, redo)
+	if err != nil {
+		return err
+	}
+
+	// Undo walks the batch backwards, redo forwards
+	if !redo {
+		for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+			changes[i], changes[j] = changes[j], changes[i]
+		}
+	}
+
+	// The files should still look the way this change left them
+	conflicts := make([]string, 0)
+	for _, fc := range changes {
+		if fc.Operation == "rename" {
+			continue
+		}
+		expected := fc.ContentAfter
+		if redo {
+			expected = fc.ContentBefore
+		}
+		current, _ := c.git.GetFileContent(fc.Path)
+		if current != expected {
+			conflicts = append(conflicts, fc.Path)
+		}
+	}
+	if len(conflicts) > 0 {
+		fmt.Printf("\033[33m⚠️  Changed since: %s\033[0m\n", strings.Join(conflicts, ", "))
+		confirm, _ := c.ask("\033[36mOverwrite anyway? [y/N] \033[0m")
+		confirm = strings.ToLower(strings.TrimSpace(confirm))
+		if confirm != "y" && confirm != "yes" {
+			fmt.Println("\033[33m❌ Cancelled\033[0m")
+			return nil
+		}
+	}
+
+	if !redo {
+		for _, fc := range changes {
+			if fc.Operation == "modify" {
+				c.learnStyle(modules.ChangedLines(fc.ContentBefore, fc.ContentAfter))
+			}
+		}
+	}
+
+	c.backups.Begin()
+	ids := make([]string, 0, len(changes))
+	for _, fc := range changes {
+		if err := c.backups.Save(fc.Path); err != nil {
+			return fmt.Errorf("backup %s: %w", fc.Path, err)
+		}
+		if err := c.restoreSnapshot(fc, redo); err != nil {
+			return err
+		}
+		ids = append(ids, fc.ID)
+
+		verb := "Undid"
+		if redo {
+			verb = "Redid"
+		}
+		fmt.Printf("\033[32m✓ %s %s of %s\033[0m\n", verb, fc.Operation, fc.Path)
+	}
+
+	if err := c.session.MarkUndone(ids, !redo); err != nil {
+		return err
+	}
+	if !redo {
+		messageIDs := make([]string, 0, len(changes))
+		for _, fc := range changes {
+			messageIDs = append(messageIDs, fc.MessageID)
+		}
+		c.recordUndo(messageIDs...)
+	}
+	return nil
+}
+
+// recordUndo marks the replies whose changes were undone: a -1 in the
+// feedback table and an undo event the learning module turns into a
+// failure pattern
+func (c *Chat) recordUndo(messageIDs ...string) {
+	seen := make(map[string]bool)
+	for _, id := range messageIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		prompt, response, err := c.session.Exchange(id)
+		if err != nil {
+			continue
+		}
+		c.session.RecordFeedback(id, -1, session.FeedbackUndo, "")
+		c.modules.Emit(modules.UndoEvent, map[string]interface{}{
+			"message_id": id,
+			"prompt":     prompt,
+			"response":   response,
+		})
+	}
+}
+
+// snapshotTargets selects the recorded changes named by /undo or /redo arguments
+func (c *Chat) snapshotTargets(args []string, redo bool) ([]session.FileChange, error) {
+	if len(args) == 0 || args[0] == "last" {
+		return c.session.LastBatch(redo)
+	}
+
+	if args[0] == "file" && len(args) > 1 {
+		policy, _ := c.engine.GetConfig("path_policy")
+		path, err := sanitizePath(c.git.WorkDir(), args[1], policy)
+		if err != nil {
+			return nil, err
+		}
+		fc, err := c.session.LastFileChange(path, redo)
+		if err != nil {
+			return nil, err
+		}
+		return []session.FileChange{*fc}, nil
+	}
+
+	verb := "undo"
+	if redo {
+		verb = "redo"
+	}
+	return nil, fmt.Errorf("usage: /%s [last | file <path>]", verb)
+}
+
+// restoreSnapshot puts one file back to its state before (undo) or after (redo) a change
+func (c *Chat) restoreSnapshot(fc session.FileChange, redo bool) error {
+	if fc.Operation == "rename" {
+		from, to := parseRenameDiff(fc.Diff)
+		if from == "" || to == "" {
+			return fmt.Errorf("%s: rename record has no source path", fc.Path)
+		}
+		if redo {
+			return c.renameFile(from, to)
+		}
+		return c.renameFile(to, from)
+	}
+
+	content := fc.ContentBefore
+	exists := fc.Operation != "create"
+	if redo {
+		content = fc.ContentAfter
+		exists = fc.Operation != "delete"
+	}
+
+	if !exists {
+		if err := os.Remove(fc.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %s: %w", fc.Path, err)
+		}
+		c.untrackFile(fc.Path)
+		return nil
+	}
+
+	if err := ensureDir(fc.Path); err != nil {
+		return err
+	}
+	if err := workspace.WriteFile(fc.Path, content); err != nil {
+		return fmt.Errorf("write %s: %w", fc.Path, err)
+	}
+	c.trackFile(fc.Path)
+	return nil
+}
+
+// parseRenameDiff reads the paths from a "rename from X\nrename to Y" record
+func parseRenameDiff(diff string) (from, to string) {
+	for _, line := range strings.Split(diff, "\n") {
+		if p, ok := strings.CutPrefix(line, "rename from "); ok {
+			from = p
+		} else if p, ok := strings.CutPrefix(line, "rename to "); ok {
+			to = p
+		}
+	}
+	return from, to
+}
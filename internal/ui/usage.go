@@ -0,0 +1,190 @@
+// Package ui - /usage and goclode usage: tokens and cost by day,
+// provider, model, or session over a time range, as a table or JSON
+package ui
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+// UsageHelp describes the arguments of /usage and goclode usage
+const UsageHelp = `[--since 30d|12h|today|2006-01-02] [--until 2006-01-02] [--by day,provider,model,session] [--json]`
+
+// usageQuery is a parsed usage request
+type usageQuery struct {
+	from, to time.Time // to is zero for no limit
+	by       []string
+	json     bool
+}
+
+// parseUsageArgs reads the usage arguments; --since defaults to 30 days
+// and --by to day
+func parseUsageArgs(args []string, now time.Time) (usageQuery, error) {
+	fs := flag.NewFlagSet("usage", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	since := fs.String("since", "30d", "")
+	until := fs.String("until", "", "")
+	by := fs.String("by", "day", "")
+	asJSON := fs.Bool("json", false, "")
+	if err := fs.Parse(args); err != nil {
+		return usageQuery{}, fmt.Errorf("%w; usage: %s", err, UsageHelp)
+	}
+	if fs.NArg() > 0 {
+		return usageQuery{}, fmt.Errorf("unexpected %q; usage: %s", fs.Arg(0), UsageHelp)
+	}
+
+	q := usageQuery{json: *asJSON, by: make([]string, 0)}
+	var err error
+	if q.from, err = parseSince(*since, now); err != nil {
+		return q, err
+	}
+	if *until != "" {
+		day, err := time.ParseInLocation(time.DateOnly, *until, now.Location())
+		if err != nil {
+			return q, fmt.Errorf("--until %q: want a date like 2006-01-02", *until)
+		}
+		q.to = day.AddDate(0, 0, 1) // The whole day
+	}
+	for _, group := range strings.Split(*by, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			q.by = append(q.by, group)
+		}
+	}
+	return q, nil
+}
+
+// parseSince reads a start: a number of days (30d), a duration (12h),
+// today, or a date
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "today" {
+		return startOfDay(now), nil
+	}
+	if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil && strings.HasSuffix(s, "d") && days >= 0 {
+		return now.AddDate(0, 0, -days), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if day, err := time.ParseInLocation(time.DateOnly, s, now.Location()); err == nil {
+		return day, nil
+	}
+	return time.Time{}, fmt.Errorf("--since %q: want 30d, 12h, today, or a date like 2006-01-02", s)
+}
+
+// ReportUsage writes the usage of the given session databases, added up,
+// for /usage or goclode usage arguments
+func ReportUsage(w io.Writer, args []string, sources []*session.Manager) error {
+	q, err := parseUsageArgs(args, time.Now())
+	if err != nil {
+		return err
+	}
+	rows := make([]session.UsageRow, 0)
+	for _, m := range sources {
+		usage, err := m.Usage(q.from, q.to, q.by)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, usage...)
+	}
+	rows = session.MergeUsage(rows)
+	var total session.UsageRow
+	for _, r := range rows {
+		total.Add(r)
+	}
+
+	if q.json {
+		out := struct {
+			From  time.Time          `json:"from"`
+			To    *time.Time         `json:"to,omitempty"`
+			By    []string           `json:"by"`
+			Rows  []session.UsageRow `json:"rows"`
+			Total session.UsageRow   `json:"total"`
+		}{From: q.from, By: q.by, Rows: rows, Total: total}
+		if !q.to.IsZero() {
+			out.To = &q.to
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	writeUsageTable(w, q, rows, total)
+	return nil
+}
+
+// writeUsageTable writes usage rows as an aligned table with a total
+func writeUsageTable(w io.Writer, q usageQuery, rows []session.UsageRow, total session.UsageRow) {
+	to := "now"
+	if !q.to.IsZero() {
+		to = q.to.Add(-time.Second).Format(time.DateOnly)
+	}
+	by := "overall"
+	if len(q.by) > 0 {
+		by = "by " + strings.Join(q.by, ", ")
+	}
+	fmt.Fprintf(w, "\nUsage %s to %s, %s\n", q.from.Format("2006-01-02 15:04"), to, by)
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "  No replies recorded")
+		return
+	}
+
+	widths := make([]int, len(q.by))
+	cells := make([][]string, len(rows))
+	for i, r := range rows {
+		cells[i] = make([]string, len(q.by))
+		for j, group := range q.by {
+			cells[i][j] = usageCell(r, group)
+			widths[j] = max(widths[j], max(len(cells[i][j]), len(group)))
+		}
+	}
+
+	header := ""
+	for j, group := range q.by {
+		header += fmt.Sprintf("%-*s  ", widths[j], strings.ToUpper(group[:1])+group[1:])
+	}
+	fmt.Fprintf(w, "  %s%8s %12s %12s %10s\n", header, "Requests", "Tokens in", "Tokens out", "Cost")
+	for i, r := range rows {
+		line := ""
+		for j := range q.by {
+			line += fmt.Sprintf("%-*s  ", widths[j], cells[i][j])
+		}
+		fmt.Fprintf(w, "  %s%8d %12d %12d %10s\n", line, r.Requests, r.TokensIn, r.TokensOut, fmt.Sprintf("$%.4f", r.Cost))
+	}
+	if len(rows) > 1 {
+		pad := ""
+		for j := range q.by {
+			pad += strings.Repeat(" ", widths[j]+2)
+		}
+		if len(q.by) > 0 {
+			pad = "Total" + pad[min(len(pad), 5):]
+		}
+		fmt.Fprintf(w, "  %s%8d %12d %12d %10s\n", pad, total.Requests, total.TokensIn, total.TokensOut, fmt.Sprintf("$%.4f", total.Cost))
+	}
+}
+
+// usageCell is a row's value of a group; sessions are shortened
+func usageCell(r session.UsageRow, group string) string {
+	switch group {
+	case "day":
+		return r.Day
+	case "provider":
+		return r.Provider
+	case "model":
+		return r.Model
+	case "session":
+		return r.Session[:min(len(r.Session), 8)]
+	}
+	return ""
+}
+
+// handleUsage runs /usage over this session's database
+func (c *Chat) handleUsage(args []string) error {
+	return ReportUsage(os.Stdout, args, []*session.Manager{c.session})
+}
@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/session"
+)
+
+func TestParseUsageArgs(t *testing.T) {
+	now := time.Date(2026, 10, 16, 15, 30, 0, 0, time.Local)
+	tests := []struct {
+		args     []string
+		from, to time.Time
+		by       []string
+	}{
+		{nil, now.AddDate(0, 0, -30), time.Time{}, []string{"day"}},
+		{[]string{"--since", "12h", "--by", "provider, model"}, now.Add(-12 * time.Hour), time.Time{}, []string{"provider", "model"}},
+		{[]string{"--since=today", "--by="}, time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local), time.Time{}, []string{}},
+		{[]string{"--since", "2026-10-01", "--until", "2026-10-07"}, time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local), time.Date(2026, 10, 8, 0, 0, 0, 0, time.Local), []string{"day"}},
+	}
+	for _, tt := range tests {
+		q, err := parseUsageArgs(tt.args, now)
+		if err != nil {
+			t.Errorf("parseUsageArgs(%q): %v", tt.args, err)
+			continue
+		}
+		if !q.from.Equal(tt.from) || !q.to.Equal(tt.to) || !reflect.DeepEqual(q.by, tt.by) {
+			t.Errorf("parseUsageArgs(%q) = %v %v %q, want %v %v %q", tt.args, q.from, q.to, q.by, tt.from, tt.to, tt.by)
+		}
+	}
+
+	for _, args := range [][]string{{"--since", "last week"}, {"--until", "tomorrow"}, {"week"}} {
+		if _, err := parseUsageArgs(args, now); err == nil {
+			t.Errorf("parseUsageArgs(%q): expected an error", args)
+		}
+	}
+}
+
+func TestReportUsage(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+	sm := session.NewManager(engine)
+	if _, err := sm.Create("cerebras"); err != nil {
+		t.Fatal(err)
+	}
+	sm.AddMessage("assistant", "a", &providers.Response{Model: "glm", TokensIn: 100, TokensOut: 10})
+	sm.AddMessage("assistant", "b", &providers.Response{Model: "qwen", TokensIn: 50})
+
+	var out bytes.Buffer
+	if err := ReportUsage(&out, []string{"--by", "model"}, []*session.Manager{sm}); err != nil {
+		t.Fatalf("ReportUsage: %v", err)
+	}
+	for _, want := range []string{"by model", "Model", "glm", "qwen", "Total"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the table:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := ReportUsage(&out, []string{"--json", "--by", "provider"}, []*session.Manager{sm, sm}); err != nil {
+		t.Fatalf("ReportUsage: %v", err)
+	}
+	var report struct {
+		Rows  []session.UsageRow `json:"rows"`
+		Total session.UsageRow   `json:"total"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Bad JSON: %v\n%s", err, out.String())
+	}
+	if len(report.Rows) != 1 || report.Rows[0].Provider != "cerebras" || report.Total.Requests != 4 || report.Total.TokensIn != 300 {
+		t.Errorf("Expected both databases added up, got %+v", report)
+	}
+}
@@ -0,0 +1,136 @@
+// Package ui - Workspace watching: the file and symbol indexes follow
+// changes made outside GoClode, and files the model was given are flagged
+// when someone else edits them mid-conversation
+package ui
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+// watchWorkspace follows the workspace until the chat ends, unless
+// watch_workspace is off
+func (c *Chat) watchWorkspace() {
+	if !c.engine.GetConfigBool("watch_workspace") || c.index == nil {
+		return
+	}
+	if err := workspace.Watch(c.ctx, c.git.WorkDir(), c.workspaceChanged); err != nil {
+		core.Logger("ui").Warn("Workspace not watched", "error", err)
+	}
+}
+
+// workspaceChanged updates the indexes for changed files, and warns about
+// those the model was given that someone else edited
+func (c *Chat) workspaceChanged(changes []workspace.Change) {
+	edited := make([]string, 0)
+	for _, ch := range changes {
+		if ch.Removed {
+			c.index.Remove(ch.Path)
+		} else {
+			c.index.Add(ch.Path)
+		}
+		if c.symbols != nil {
+			if ch.Removed {
+				c.symbols.Remove(ch.Path)
+			} else {
+				c.symbols.Update(ch.Path)
+			}
+		}
+		if c.editedElsewhere(ch.Path) {
+			edited = append(edited, ch.Path)
+		}
+	}
+	if len(edited) > 0 {
+		fmt.Printf("\n\033[33m✏️  Edited outside GoClode since the model read them: %s (mention @path to send the new version)\033[0m\n", strings.Join(edited, ", "))
+	}
+}
+
+// workspaceRel returns a path as the watcher reports it, or "" outside
+// the workspace
+func (c *Chat) workspaceRel(path string) string {
+	root, err := filepath.Abs(c.git.WorkDir())
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// sawFile records the content of a file the model was given
+func (c *Chat) sawFile(path string) {
+	rel := c.workspaceRel(path)
+	if rel == "" {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(c.git.WorkDir(), rel))
+	if err != nil {
+		return
+	}
+	c.seenMu.Lock()
+	defer c.seenMu.Unlock()
+	if c.seen == nil {
+		c.seen = make(map[string][sha256.Size]byte)
+	}
+	c.seen[rel] = sha256.Sum256(data)
+}
+
+// wroteFile keeps up with GoClode's own edits of files the model was
+// given, which are not edits made elsewhere
+func (c *Chat) wroteFile(path string) {
+	c.seenMu.Lock()
+	defer c.seenMu.Unlock()
+	if len(c.seen) == 0 {
+		return
+	}
+	rel := c.workspaceRel(path)
+	if _, seen := c.seen[rel]; !seen {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(c.git.WorkDir(), rel))
+	if err != nil {
+		delete(c.seen, rel) // Removed
+		return
+	}
+	c.seen[rel] = sha256.Sum256(data)
+}
+
+// editedElsewhere reports whether a file the model was given changed
+// since, and records its new content so each edit is reported once
+func (c *Chat) editedElsewhere(rel string) bool {
+	c.seenMu.Lock()
+	defer c.seenMu.Unlock()
+	sum, seen := c.seen[rel]
+	if !seen {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(c.git.WorkDir(), rel))
+	if err != nil {
+		delete(c.seen, rel)
+		return true
+	}
+	now := sha256.Sum256(data)
+	c.seen[rel] = now
+	return now != sum
+}
+
+// modelReadPath resolves a path for read_file, recording the file as
+// given to the model
+func (c *Chat) modelReadPath(requested string) (string, error) {
+	path, err := c.readablePath(requested)
+	if err == nil {
+		c.sawFile(path)
+	}
+	return path, err
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/git"
+	"github.com/hazyhaar/GoClode/internal/workspace"
+)
+
+func TestEditedElsewhere(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "main.go")
+	os.WriteFile(path, []byte("package main\n"), 0644)
+	c := &Chat{git: git.NewManager(root), index: workspace.NewFileIndex(root)}
+
+	c.sawFile(path)
+	if c.editedElsewhere("main.go") {
+		t.Error("Expected an unchanged file not reported")
+	}
+
+	// GoClode's own edit is not someone else's
+	os.WriteFile(path, []byte("package main\n\nfunc a() {}\n"), 0644)
+	c.wroteFile("main.go")
+	if c.editedElsewhere("main.go") {
+		t.Error("Expected GoClode's edit not reported")
+	}
+
+	os.WriteFile(path, []byte("package main\n\nfunc b() {}\n"), 0644)
+	os.WriteFile(filepath.Join(root, "other.go"), []byte("package main\n"), 0644)
+	c.workspaceChanged([]workspace.Change{{Path: "main.go"}, {Path: "other.go"}})
+	if c.editedElsewhere("main.go") {
+		t.Error("Expected an outside edit reported once")
+	}
+	if files := c.index.Files(); len(files) != 2 {
+		t.Errorf("Expected the new file indexed, got %v", files)
+	}
+	if c.editedElsewhere("other.go") {
+		t.Error("Expected files the model was not given ignored")
+	}
+
+	os.Remove(path)
+	if !c.editedElsewhere("main.go") {
+		t.Error("Expected removing a file the model was given reported")
+	}
+}
@@ -0,0 +1,158 @@
+// Package ui - Webhook mode (goclode webhook): GitHub issues and Sentry
+// alerts start sessions
+package ui
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/hazyhaar/GoClode/internal/prompt"
+	"github.com/hazyhaar/GoClode/internal/webhook"
+)
+
+// maxWebhookPayload caps the delivery JSON given to a prompt
+const maxWebhookPayload = 20000
+
+// webhookLog is the messenger of webhook sessions: nobody is there to
+// answer, so replies go to the terminal
+type webhookLog struct {
+	thread string
+}
+
+func (l webhookLog) Post(text string) (string, error) {
+	fmt.Printf("\033[90m[webhook %s]\033[0m %s\n", l.thread, text)
+	return "", nil
+}
+
+func (l webhookLog) Update(id, text string) error { return nil }
+
+func (l webhookLog) Finish(id, text string) error {
+	_, err := l.Post(text)
+	return err
+}
+
+// ServeWebhooks acts on signed webhooks at addr until interrupted. Each
+// GitHub issue or Sentry issue gets a session that runs the prompt
+// webhook_<source> (see the prompts table) with the delivery; with
+// webhook_open_pr the proposed fix is applied on a branch and opened as a
+// pull request. Deliveries are untrusted text: no tools run, and a fix
+// only reaches the default branch through review.
+func (c *Chat) ServeWebhooks(addr string) error {
+	secrets := webhook.Secrets{
+		GitHub: c.configSecret("github_webhook_secret"),
+		Sentry: c.configSecret("sentry_webhook_secret"),
+	}
+	if secrets.GitHub == "" && secrets.Sentry == "" {
+		return fmt.Errorf("set github_webhook_secret or sentry_webhook_secret (or GITHUB_WEBHOOK_SECRET, SENTRY_WEBHOOK_SECRET)")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(c.ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	defer c.recoverCrash()
+	if _, err := c.startSession(); err != nil {
+		ln.Close()
+		return err
+	}
+	bots := newBotServer(c, "webhook")
+
+	fmt.Printf("\033[32m🪝 Receiving webhooks on %s (Ctrl+C to stop)\033[0m\n", ln.Addr())
+	if secrets.GitHub != "" {
+		fmt.Println("\033[90m   GitHub: POST /github (issues events, application/json)\033[0m")
+	}
+	if secrets.Sentry != "" {
+		fmt.Println("\033[90m   Sentry: POST /sentry (alert rule and issue events)\033[0m")
+	}
+	err = webhook.Serve(ctx, ln, secrets, func(e webhook.Event) {
+		c.setInFlight(e.Source + " " + e.Key)
+		c.runWebhook(bots, e)
+		c.setInFlight("")
+	})
+
+	c.shutdown()
+	return err
+}
+
+// runWebhook runs an event's prompt in its session, then opens a pull
+// request with the proposed changes if configured to
+func (c *Chat) runWebhook(bots *botServer, e webhook.Event) {
+	thread := e.Source + ":" + e.Key
+	log := webhookLog{thread: thread}
+	fmt.Printf("\033[36m[webhook %s] %s\033[0m\n", thread, shorten(e.Title, 80))
+
+	prompt, err := c.webhookPrompt(e)
+	if err != nil {
+		log.Post("⚠️ " + err.Error())
+		return
+	}
+	bots.handle(thread, prompt, log)
+
+	if len(bots.pending[thread]) == 0 || !c.engine.GetConfigBool("webhook_open_pr") {
+		return
+	}
+	if err := c.webhookPR(bots, thread, e); err != nil {
+		log.Post("⚠️ " + err.Error())
+	}
+}
+
+// webhookPrompt fills the source's prompt template: {{title}}, {{body}},
+// {{url}}, and {{payload}}, the delivery's JSON
+func (c *Chat) webhookPrompt(e webhook.Event) (string, error) {
+	var template string
+	err := c.engine.DB().QueryRow(
+		"SELECT template FROM prompts WHERE prompt_id = ? AND enabled = 1", "webhook_"+e.Source).Scan(&template)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("no enabled webhook_%s prompt", e.Source)
+	}
+	if err != nil {
+		return "", err
+	}
+	return prompt.Render(template, map[string]string{
+		"title":   e.Title,
+		"body":    e.Body,
+		"url":     e.URL,
+		"payload": shorten(e.Payload, maxWebhookPayload),
+	})
+}
+
+// webhookPR applies a thread's proposed changes on a new branch, opens a
+// pull request for it, and returns to the branch it started on
+func (c *Chat) webhookPR(bots *botServer, thread string, e webhook.Event) error {
+	if !c.engine.GetConfigBool("auto_commit") {
+		return fmt.Errorf("webhook_open_pr needs auto_commit")
+	}
+	if c.git.HasTrackedChanges() {
+		return fmt.Errorf("uncommitted changes in the workspace; not opening a pull request")
+	}
+	base, err := c.git.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if err := c.git.CreateBranch("goclode/" + e.Source + "-" + e.Key); err != nil {
+		return err
+	}
+	defer func() {
+		if err := c.git.Switch(base); err != nil {
+			fmt.Printf("\033[33m⚠️  %v\033[0m\n", err)
+		}
+	}()
+
+	if _, err := bots.command(thread, "apply", nil); err != nil {
+		return err
+	}
+	if len(bots.pending[thread]) > 0 {
+		return fmt.Errorf("changes not applied")
+	}
+	_, err = bots.command(thread, "pr", strings.Fields("Fix: "+e.Title))
+	return err
+}
@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/webhook"
+)
+
+func TestWebhookPrompt(t *testing.T) {
+	engine := setupTestDB(t)
+	defer engine.Close()
+	c := &Chat{engine: engine}
+
+	prompt, err := c.webhookPrompt(webhook.Event{
+		Source: webhook.SourceGitHub,
+		Title:  "Crash on empty input",
+		Body:   "Run it with no arguments.",
+		URL:    "https://github.com/o/r/issues/12",
+	})
+	if err != nil {
+		t.Fatalf("webhookPrompt failed: %v", err)
+	}
+	for _, want := range []string{"# Crash on empty input", "Run it with no arguments.", "(https://github.com/o/r/issues/12)"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "{{") {
+		t.Errorf("Unfilled placeholder in the prompt:\n%s", prompt)
+	}
+
+	if _, err := c.webhookPrompt(webhook.Event{Source: "gitlab"}); err == nil {
+		t.Error("Expected an error for a source without a prompt")
+	}
+}
@@ -0,0 +1,57 @@
+// Package ui - Backend and summarization for the web_search tool
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hazyhaar/GoClode/internal/providers"
+	"github.com/hazyhaar/GoClode/internal/tools"
+)
+
+const summarizeSearchPrompt = `Summarize these web search results for the question. Keep only what helps answer it: API names, code snippets, version notes, and caveats. Cite the URL of each fact you keep. Be brief.`
+
+// webSearchBackend builds the configured search backend
+func (c *Chat) webSearchBackend() (tools.SearchBackend, error) {
+	name, _ := c.engine.GetConfig("web_search_backend")
+	searxngURL, _ := c.engine.GetConfig("searxng_url")
+	braveKey, _ := c.engine.GetConfig("brave_api_key")
+	if braveKey == "" {
+		braveKey = os.Getenv("BRAVE_API_KEY")
+	}
+	return tools.NewSearchBackend(name, searxngURL, braveKey)
+}
+
+// webSearchResults is how many results web_search asks the backend for
+func (c *Chat) webSearchResults() int {
+	if n := c.engine.GetConfigInt("web_search_results"); n > 0 {
+		return n
+	}
+	return 5
+}
+
+// summarizeSearch condenses search results with the current provider so
+// only what answers the query enters the conversation
+func (c *Chat) summarizeSearch(ctx context.Context, query, results string) (string, error) {
+	provider := c.registry.Current()
+	if provider == nil || !c.engine.GetConfigBool("web_search_summarize") {
+		return "", fmt.Errorf("summarization unavailable")
+	}
+	if err := c.checkBudget(); err != nil {
+		return "", err
+	}
+	fmt.Printf("\033[90m🌐 Summarizing results for %q\033[0m\n", query)
+
+	resp, err := provider.Generate(ctx, &providers.Request{
+		Messages: []providers.Message{
+			{Role: "system", Content: summarizeSearchPrompt},
+			{Role: "user", Content: "Question: " + query + "\n\nResults:\n" + results},
+		},
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
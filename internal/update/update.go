@@ -0,0 +1,319 @@
+// Package update finds newer GoClode releases on GitHub and replaces the
+// running binary with one, once its SHA-256 matches the release's
+// checksums file
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Repository is where releases are published
+const Repository = "hazyhaar/GoClode"
+
+// ChecksumsAsset is the release asset listing the SHA-256 of the others
+const ChecksumsAsset = "checksums.txt"
+
+// maxAssetBytes bounds a download
+const maxAssetBytes = 256 << 20
+
+// APIURL is the GitHub API releases are read from
+var APIURL = "https://api.github.com"
+
+var client = &http.Client{Timeout: 5 * time.Minute}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+	Size int64  `json:"size"`
+}
+
+// Release is a published version
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Version is the release's version, without the v of its tag
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// Latest returns the latest release
+func Latest(ctx context.Context) (*Release, error) {
+	body, err := get(ctx, APIURL+"/repos/"+Repository+"/releases/latest", 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("latest release: %w", err)
+	}
+	var r Release
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("latest release: %w", err)
+	}
+	if r.Tag == "" {
+		return nil, fmt.Errorf("latest release: no tag")
+	}
+	return &r, nil
+}
+
+// cachedRelease is the last release found, kept between runs
+type cachedRelease struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Release   Release   `json:"release"`
+}
+
+// LatestCached returns the latest release, from the cache file when it is
+// younger than maxAge, and otherwise from GitHub, caching it
+func LatestCached(ctx context.Context, cache string, maxAge time.Duration) (*Release, error) {
+	var cached cachedRelease
+	if data, err := os.ReadFile(cache); err == nil && json.Unmarshal(data, &cached) == nil &&
+		time.Since(cached.CheckedAt) < maxAge && cached.Release.Tag != "" {
+		return &cached.Release, nil
+	}
+	r, err := Latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := json.Marshal(cachedRelease{CheckedAt: time.Now(), Release: *r})
+	if err := os.MkdirAll(filepath.Dir(cache), 0755); err == nil {
+		os.WriteFile(cache, data, 0644)
+	}
+	return r, nil
+}
+
+// Newer reports whether version latest is after current. Versions that
+// are not numbered, such as dev builds, are never older.
+func Newer(current, latest string) bool {
+	c, ok1 := parseVersion(current)
+	l, ok2 := parseVersion(latest)
+	if !ok1 || !ok2 {
+		return false
+	}
+	for i := range c {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion reads the major, minor, and patch numbers of v1.2.3,
+// ignoring what follows (-rc1, or git describe's -4-gabc123)
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// Binary returns the asset built for this system
+func (r *Release) Binary() (*Asset, error) {
+	arches := []string{runtime.GOARCH}
+	switch runtime.GOARCH {
+	case "amd64":
+		arches = append(arches, "x86_64")
+	case "386":
+		arches = append(arches, "i386")
+	}
+	for i, a := range r.Assets {
+		name := strings.ToLower(a.Name)
+		if name == ChecksumsAsset || strings.HasSuffix(name, ".sig") || !strings.Contains(name, runtime.GOOS) {
+			continue
+		}
+		for _, arch := range arches {
+			if strings.Contains(name, arch) {
+				return &r.Assets[i], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("release %s has no build for %s/%s", r.Tag, runtime.GOOS, runtime.GOARCH)
+}
+
+// Download fetches the binary for this system, checks it against the
+// release's checksums, and returns it unpacked
+func Download(ctx context.Context, r *Release) ([]byte, error) {
+	asset, err := r.Binary()
+	if err != nil {
+		return nil, err
+	}
+	var sums *Asset
+	for i := range r.Assets {
+		if r.Assets[i].Name == ChecksumsAsset {
+			sums = &r.Assets[i]
+		}
+	}
+	if sums == nil {
+		return nil, fmt.Errorf("release %s has no %s to verify the download with", r.Tag, ChecksumsAsset)
+	}
+
+	list, err := get(ctx, sums.URL, 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", ChecksumsAsset, err)
+	}
+	want, err := checksumOf(string(list), asset.Name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := get(ctx, asset.URL, maxAssetBytes)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", asset.Name, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return nil, fmt.Errorf("%s: checksum %s, want %s", asset.Name, got, want)
+	}
+	return unpack(asset.Name, data)
+}
+
+// checksumOf finds a file's SHA-256 in a sha256sum listing
+func checksumOf(list, name string) (string, error) {
+	for _, line := range strings.Split(list, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s does not list %s", ChecksumsAsset, name)
+}
+
+// unpack returns the goclode binary of a .tar.gz or .zip asset, or the
+// asset itself when it is not an archive
+func unpack(name string, data []byte) ([]byte, error) {
+	isBinary := func(entry string) bool {
+		base := path.Base(entry)
+		return base == "goclode" || base == "goclode.exe"
+	}
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		tr := tar.NewReader(zr)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if h.Typeflag == tar.TypeReg && isBinary(h.Name) {
+				return io.ReadAll(io.LimitReader(tr, maxAssetBytes))
+			}
+		}
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, f := range zr.File {
+			if !f.FileInfo().IsDir() && isBinary(f.Name) {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return io.ReadAll(io.LimitReader(rc, maxAssetBytes))
+			}
+		}
+	default:
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s: no goclode binary inside", name)
+}
+
+// Install replaces the binary at target, keeping the old one until the
+// new one is in place
+func Install(binary []byte, target string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".goclode-update-*")
+	if err != nil {
+		return fmt.Errorf("write next to %s: %w", target, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+
+	// Windows cannot replace a running binary, only move it aside
+	old := target + ".old"
+	os.Remove(old)
+	if err := os.Rename(target, old); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Rename(old, target)
+		return err
+	}
+	os.Remove(old) // Left on Windows while it runs; replaced next time
+	return nil
+}
+
+// get fetches a URL, failing beyond limit bytes
+func get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "GoClode")
+	if strings.HasPrefix(url, APIURL) {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("larger than %d bytes", limit)
+	}
+	return data, nil
+}
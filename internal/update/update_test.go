@@ -0,0 +1,170 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"0.1.0", "0.2.0", true},
+		{"v0.1.0", "v0.1.1", true},
+		{"0.9.0", "0.10.0", true},
+		{"1.0", "1.0.1", true},
+		{"0.2.0", "0.2.0", false},
+		{"0.3.0", "0.2.9", false},
+		{"v0.2.0-4-gabc1234", "0.2.0", false},
+		{"v0.2.0-4-gabc1234", "0.2.1", true},
+		{"dev", "9.9.9", false},
+		{"abc1234-dirty", "1.0.0", false},
+		{"0.1.0", "nightly", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+// tarGz packs files into a .tar.gz
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	zw.Close()
+	return buf.Bytes()
+}
+
+// serveRelease serves a release with one archive for this system and a
+// checksums file, whose listing of the archive is sum unless empty
+func serveRelease(t *testing.T, archive []byte, sum string) {
+	t.Helper()
+	name := fmt.Sprintf("goclode_0.2.0_%s_%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	if sum == "" {
+		s := sha256.Sum256(archive)
+		sum = hex.EncodeToString(s[:])
+	}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + Repository + "/releases/latest":
+			json.NewEncoder(w).Encode(Release{Tag: "v0.2.0", Assets: []Asset{
+				{Name: "goclode_0.2.0_plan9_mips.tar.gz", URL: srv.URL + "/other"},
+				{Name: name, URL: srv.URL + "/archive"},
+				{Name: ChecksumsAsset, URL: srv.URL + "/sums"},
+			}})
+		case "/archive":
+			w.Write(archive)
+		case "/sums":
+			fmt.Fprintf(w, "%s  goclode_0.2.0_plan9_mips.tar.gz\n%s  %s\n", strings.Repeat("0", 64), sum, name)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	saved := APIURL
+	APIURL = srv.URL
+	t.Cleanup(func() { APIURL = saved })
+}
+
+func TestDownload(t *testing.T) {
+	archive := tarGz(t, map[string]string{"README.md": "docs", "goclode_0.2.0/goclode": "new binary"})
+	serveRelease(t, archive, "")
+	ctx := context.Background()
+
+	release, err := Latest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.Version() != "0.2.0" {
+		t.Errorf("Version() = %q", release.Version())
+	}
+	binary, err := Download(ctx, release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(binary) != "new binary" {
+		t.Errorf("Download() = %q", binary)
+	}
+
+	// A checksum mismatch refuses the download
+	serveRelease(t, archive, strings.Repeat("ab", 32))
+	if release, err = Latest(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Download(ctx, release); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Download() with a bad checksum: err = %v", err)
+	}
+
+	// So does a release without checksums
+	release.Assets = release.Assets[:2]
+	if _, err := Download(ctx, release); err == nil {
+		t.Error("Download() without checksums succeeded")
+	}
+}
+
+func TestLatestCached(t *testing.T) {
+	serveRelease(t, nil, "")
+	cache := filepath.Join(t.TempDir(), "update.json")
+	ctx := context.Background()
+
+	if _, err := LatestCached(ctx, cache, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// Cached: no request reaches GitHub
+	APIURL = "http://127.0.0.1:0"
+	release, err := LatestCached(ctx, cache, time.Hour)
+	if err != nil || release.Tag != "v0.2.0" {
+		t.Fatalf("LatestCached() = %v, %v", release, err)
+	}
+	// Expired: it asks again
+	if _, err := LatestCached(ctx, cache, 0); err == nil {
+		t.Error("LatestCached() with an expired cache did not ask GitHub")
+	}
+}
+
+func TestInstall(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "goclode")
+	if err := os.WriteFile(target, []byte("old binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install([]byte("new binary"), target); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != "new binary" {
+		t.Fatalf("target = %q, %v", data, err)
+	}
+	if info, _ := os.Stat(target); runtime.GOOS != "windows" && info.Mode().Perm()&0100 == 0 {
+		t.Errorf("target mode = %v, want executable", info.Mode())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(target))
+	if len(entries) != 1 {
+		t.Errorf("left %d files next to the binary, want 1", len(entries))
+	}
+}
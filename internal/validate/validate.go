@@ -0,0 +1,229 @@
+// Package validate runs formatters, builds, and linters on files GoClode changed.
+// Validators are configured per language in the validators table (hot-reloadable).
+package validate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Validator kinds
+const (
+	KindFormat = "format" // Rewrites files in place (gofmt -w)
+	KindBuild  = "build"  // Compiles the project (go build ./...)
+	KindLint   = "lint"   // Reports findings (go vet, staticcheck, golangci-lint)
+)
+
+// Validator is a command run against changed files of one language
+type Validator struct {
+	ID         string
+	Language   string
+	Name       string
+	Command    string // {files} expands to the changed files of this language, {dirs} to their directories (./pkg)
+	Kind       string
+	Priority   int
+	TimeoutSec int
+}
+
+// Result is the outcome of running one validator
+type Result struct {
+	Validator Validator
+	Files     []string
+	Output    string
+	Err       error
+	Missing   bool // The command is not installed; not a finding
+	Duration  time.Duration
+}
+
+// Passed reports whether the validator succeeded
+func (r Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Runner loads validators from the database and runs them in a workspace
+type Runner struct {
+	db      *sql.DB
+	workDir string
+}
+
+// NewRunner creates a validator runner
+func NewRunner(db *sql.DB, workDir string) *Runner {
+	return &Runner{db: db, workDir: workDir}
+}
+
+// WithWorkDir returns a runner using the same validators in another directory
+func (r *Runner) WithWorkDir(workDir string) *Runner {
+	return &Runner{db: r.db, workDir: workDir}
+}
+
+// languageByExt maps file extensions to validator languages
+var languageByExt = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".ts": "typescript",
+	".rs": "rust", ".java": "java", ".rb": "ruby", ".sh": "shell",
+	".json": "json", ".yaml": "yaml", ".yml": "yaml", ".sql": "sql",
+}
+
+// LanguageFor returns the validator language for a path, or "" if unknown
+func LanguageFor(path string) string {
+	return languageByExt[strings.ToLower(filepath.Ext(path))]
+}
+
+// Validators returns enabled validators for a language in priority order
+func (r *Runner) Validators(language string) ([]Validator, error) {
+	rows, err := r.db.Query(`
+		SELECT validator_id, language, name, command, kind, priority, timeout_sec
+		FROM validators WHERE enabled = 1 AND language = ?
+		ORDER BY priority
+	`, language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	validators := make([]Validator, 0)
+	for rows.Next() {
+		var v Validator
+		if err := rows.Scan(&v.ID, &v.Language, &v.Name, &v.Command, &v.Kind, &v.Priority, &v.TimeoutSec); err != nil {
+			continue
+		}
+		validators = append(validators, v)
+	}
+	return validators, nil
+}
+
+// Run validates the given files, grouped by language. Deleted files are not
+// passed to formatters, but still trigger builds: removing a file can break
+// compilation as surely as editing one.
+func (r *Runner) Run(ctx context.Context, files []string) []Result {
+	byLang := make(map[string][]string)   // Changed files, deleted included
+	existing := make(map[string][]string) // Changed files still on disk
+	for _, f := range files {
+		lang := LanguageFor(f)
+		if lang == "" {
+			continue
+		}
+		byLang[lang] = append(byLang[lang], f)
+		if _, err := os.Stat(filepath.Join(r.workDir, f)); err == nil {
+			existing[lang] = append(existing[lang], f)
+		}
+	}
+
+	langs := make([]string, 0, len(byLang))
+	for lang := range byLang {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	results := make([]Result, 0)
+	for _, lang := range langs {
+		validators, err := r.Validators(lang)
+		if err != nil {
+			continue
+		}
+		for _, v := range validators {
+			if v.Kind == KindBuild {
+				results = append(results, r.runOne(ctx, v, byLang[lang]))
+				continue
+			}
+			if len(existing[lang]) > 0 {
+				results = append(results, r.runOne(ctx, v, existing[lang]))
+			}
+		}
+	}
+	return results
+}
+
+// runOne executes a single validator command (no shell involved)
+func (r *Runner) runOne(ctx context.Context, v Validator, files []string) Result {
+	result := Result{Validator: v, Files: files}
+
+	args := make([]string, 0)
+	for _, field := range strings.Fields(v.Command) {
+		switch field {
+		case "{files}":
+			args = append(args, files...)
+		case "{dirs}":
+			args = append(args, packageDirs(files)...)
+		default:
+			args = append(args, field)
+		}
+	}
+	if len(args) == 0 {
+		result.Err = fmt.Errorf("empty command")
+		return result
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		result.Err = fmt.Errorf("%s is not installed", args[0])
+		result.Missing = true
+		return result
+	}
+
+	timeout := time.Duration(v.TimeoutSec) * time.Second
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = r.workDir
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	result.Output = strings.TrimSpace(out.String())
+
+	if ctx.Err() == context.DeadlineExceeded {
+		result.Err = fmt.Errorf("timed out after %s", timeout)
+	} else if err != nil {
+		result.Err = err
+	}
+	return result
+}
+
+// packageDirs returns the distinct directories of files as ./relative paths
+func packageDirs(files []string) []string {
+	dirs := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, f := range files {
+		dir := "./" + filepath.ToSlash(filepath.Dir(f))
+		if dir == "./." {
+			dir = "."
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// FailureReport formats failed results for the LLM; empty if all passed.
+// Validators that are not installed are left out.
+func FailureReport(results []Result) string {
+	var sb strings.Builder
+	for _, r := range results {
+		if r.Passed() || r.Missing {
+			continue
+		}
+		fmt.Fprintf(&sb, "$ %s\n", r.Validator.Command)
+		if r.Output != "" {
+			sb.WriteString(r.Output)
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "(%v)\n\n", r.Err)
+	}
+	return strings.TrimSpace(sb.String())
+}
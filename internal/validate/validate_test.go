@@ -0,0 +1,108 @@
+package validate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+func TestLanguageFor(t *testing.T) {
+	tests := map[string]string{
+		"main.go":        "go",
+		"pkg/Util.PY":    "python",
+		"README.md":      "",
+		"scripts/run.sh": "shell",
+	}
+	for path, want := range tests {
+		if got := LanguageFor(path); got != want {
+			t.Errorf("LanguageFor(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := core.NewEngine(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	defer engine.Close()
+
+	_, err = engine.Exec(`
+		INSERT INTO validators (validator_id, language, name, command, kind, priority) VALUES
+		('sh_ok', 'shell', 'ok', 'true {files}', 'format', 1),
+		('sh_fail', 'shell', 'fail', 'false', 'build', 2)
+	`)
+	if err != nil {
+		t.Fatalf("insert validators: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "run.sh"), []byte("echo hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewRunner(engine.DB(), dir)
+	results := runner.Run(context.Background(), []string{"run.sh", "deleted.sh", "notes.txt"})
+
+	if len(results) != 2 {
+		t.Fatalf("Results = %d, want 2", len(results))
+	}
+	if !results[0].Passed() || results[0].Validator.Name != "ok" {
+		t.Errorf("First result: %+v", results[0])
+	}
+	if len(results[0].Files) != 1 || results[0].Files[0] != "run.sh" {
+		t.Errorf("Files = %v, want [run.sh]", results[0].Files)
+	}
+	if results[1].Passed() {
+		t.Error("Second validator should fail")
+	}
+
+	// Deleting a file still runs builds, but not formatters
+	deletion := runner.Run(context.Background(), []string{"deleted.sh"})
+	if len(deletion) != 1 || deletion[0].Validator.Name != "fail" {
+		t.Errorf("Results for a deletion = %+v, want only the build", deletion)
+	}
+
+	report := FailureReport(results)
+	if !strings.Contains(report, "$ false") || strings.Contains(report, "$ true") {
+		t.Errorf("Unexpected report: %q", report)
+	}
+}
+
+func TestPackageDirs(t *testing.T) {
+	got := packageDirs([]string{"main.go", "pkg/a.go", "pkg/b.go", "internal/x/y.go"})
+	want := []string{".", "./pkg", "./internal/x"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("packageDirs = %v, want %v", got, want)
+	}
+}
+
+func TestRunner_MissingCommand(t *testing.T) {
+	dir := t.TempDir()
+	engine, err := core.NewEngine(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	defer engine.Close()
+
+	_, err = engine.Exec(`
+		INSERT INTO validators (validator_id, language, name, command, kind, priority) VALUES
+		('sh_missing', 'shell', 'missing', 'no-such-linter-goclode {dirs}', 'lint', 1)
+	`)
+	if err != nil {
+		t.Fatalf("insert validators: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, "run.sh"), []byte("echo hi\n"), 0644)
+
+	results := NewRunner(engine.DB(), dir).Run(context.Background(), []string{"run.sh"})
+	if len(results) != 1 || !results[0].Missing {
+		t.Fatalf("Results = %+v, want one missing validator", results)
+	}
+	if report := FailureReport(results); report != "" {
+		t.Errorf("FailureReport = %q, want missing validators left out", report)
+	}
+}
@@ -0,0 +1,244 @@
+// Package webhook receives signed webhooks from GitHub and Sentry, and
+// turns the deliveries worth acting on (an issue opened, an alert raised)
+// into events.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hazyhaar/GoClode/internal/core"
+)
+
+// Sources, which are also the endpoint paths: /github and /sentry
+const (
+	SourceGitHub = "github"
+	SourceSentry = "sentry"
+)
+
+// maxPayload caps the bytes read from a delivery
+const maxPayload = 1 << 20
+
+// queueSize bounds the events waiting for the handler; deliveries past it
+// are refused so the sender retries or records the failure
+const queueSize = 16
+
+// Event is a delivery to act on
+type Event struct {
+	Source  string // github or sentry
+	Key     string // What the event is about within its source, e.g. issue-12
+	Title   string
+	Body    string
+	URL     string
+	Payload string // The delivery's JSON
+}
+
+// Secrets sign each source's deliveries; a source without one is refused
+type Secrets struct {
+	GitHub string
+	Sentry string
+}
+
+// Sign returns the hex HMAC-SHA256 of body, as both sources send it
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks a hex signature in constant time
+func verify(secret, signature string, body []byte) bool {
+	got, err := hex.DecodeString(signature)
+	if err != nil || secret == "" {
+		return false
+	}
+	want, _ := hex.DecodeString(Sign(secret, body))
+	return hmac.Equal(got, want)
+}
+
+// receiver checks and parses deliveries, queueing their events
+type receiver struct {
+	secrets Secrets
+	events  chan Event
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	source := strings.Trim(r.URL.Path, "/")
+	var secret, signature string
+	switch source {
+	case SourceGitHub:
+		secret = rc.secrets.GitHub
+		signature = strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+	case SourceSentry:
+		secret = rc.secrets.Sentry
+		signature = r.Header.Get("Sentry-Hook-Signature")
+	}
+	if secret == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayload+1))
+	if err != nil || len(body) > maxPayload {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !verify(secret, signature, body) {
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+		return
+	}
+
+	var event *Event
+	if source == SourceGitHub {
+		event, err = parseGitHub(r.Header.Get("X-GitHub-Event"), body)
+	} else {
+		event, err = parseSentry(r.Header.Get("Sentry-Hook-Resource"), body)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if event == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	select {
+	case rc.events <- *event:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "busy, try again later", http.StatusServiceUnavailable)
+	}
+}
+
+// parseGitHub reads issues being opened or reopened; other events, such
+// as the ping sent when the webhook is created, are ignored
+func parseGitHub(kind string, body []byte) (*Event, error) {
+	if kind != "issues" {
+		return nil, nil
+	}
+	var p struct {
+		Action string `json:"action"`
+		Issue  struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			Body    string `json:"body"`
+			HTMLURL string `json:"html_url"`
+		} `json:"issue"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("read issue event: %w", err)
+	}
+	if p.Action != "opened" && p.Action != "reopened" {
+		return nil, nil
+	}
+	if p.Issue.Number == 0 {
+		return nil, fmt.Errorf("issue event without an issue")
+	}
+	return &Event{
+		Source:  SourceGitHub,
+		Key:     fmt.Sprintf("issue-%d", p.Issue.Number),
+		Title:   p.Issue.Title,
+		Body:    p.Issue.Body,
+		URL:     p.Issue.HTMLURL,
+		Payload: string(body),
+	}, nil
+}
+
+// parseSentry reads alert rule triggers and new issues
+func parseSentry(resource string, body []byte) (*Event, error) {
+	var p struct {
+		Action string `json:"action"`
+		Data   struct {
+			Event *struct {
+				IssueID string `json:"issue_id"`
+				Title   string `json:"title"`
+				Culprit string `json:"culprit"`
+				WebURL  string `json:"web_url"`
+			} `json:"event"`
+			Issue *struct {
+				ID        string `json:"id"`
+				Title     string `json:"title"`
+				Culprit   string `json:"culprit"`
+				Permalink string `json:"permalink"`
+			} `json:"issue"`
+		} `json:"data"`
+	}
+	if resource != "event_alert" && resource != "issue" {
+		return nil, nil
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("read %s event: %w", resource, err)
+	}
+
+	switch {
+	case resource == "event_alert" && p.Data.Event != nil:
+		e := p.Data.Event
+		return &Event{Source: SourceSentry, Key: "issue-" + e.IssueID, Title: e.Title, Body: e.Culprit, URL: e.WebURL, Payload: string(body)}, nil
+	case resource == "issue" && p.Action == "created" && p.Data.Issue != nil:
+		i := p.Data.Issue
+		return &Event{Source: SourceSentry, Key: "issue-" + i.ID, Title: i.Title, Body: i.Culprit, URL: i.Permalink, Payload: string(body)}, nil
+	case resource == "issue":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%s event without data", resource)
+}
+
+// Serve receives deliveries on ln at /github and /sentry until ctx ends.
+// Valid ones are acknowledged at once; their events go to handle one at a
+// time, in order, and Serve returns after the one in progress.
+func Serve(ctx context.Context, ln net.Listener, secrets Secrets, handle func(Event)) error {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	rc := &receiver{secrets: secrets, events: make(chan Event, queueSize)}
+	srv := &http.Server{Handler: rc, ReadHeaderTimeout: 10 * time.Second}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-rc.events:
+				handle(e)
+			}
+		}
+	}()
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errc:
+		stop()
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdownCtx)
+	<-done
+
+	if dropped := len(rc.events); dropped > 0 {
+		core.Logger("webhook").Warn("Stopped with events still queued", "dropped", dropped)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func deliver(t *testing.T, h http.Handler, path string, headers map[string]string, body string) int {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestReceiver(t *testing.T) {
+	rc := &receiver{secrets: Secrets{GitHub: "gh-secret"}, events: make(chan Event, 1)}
+	issue := `{"action": "opened", "issue": {"number": 12, "title": "Crash on empty input", "body": "Steps...", "html_url": "https://github.com/o/r/issues/12"}}`
+	signed := map[string]string{"X-GitHub-Event": "issues", "X-Hub-Signature-256": "sha256=" + Sign("gh-secret", []byte(issue))}
+
+	if code := deliver(t, rc, "/github", map[string]string{"X-GitHub-Event": "issues", "X-Hub-Signature-256": "sha256=00"}, issue); code != http.StatusUnauthorized {
+		t.Errorf("Bad signature: got %d", code)
+	}
+	if code := deliver(t, rc, "/sentry", nil, "{}"); code != http.StatusNotFound {
+		t.Errorf("Source without a secret: got %d", code)
+	}
+	ping := `{"zen": "Keep it logically awesome."}`
+	if code := deliver(t, rc, "/github", map[string]string{"X-GitHub-Event": "ping", "X-Hub-Signature-256": "sha256=" + Sign("gh-secret", []byte(ping))}, ping); code != http.StatusNoContent {
+		t.Errorf("Ping: got %d", code)
+	}
+
+	if code := deliver(t, rc, "/github", signed, issue); code != http.StatusAccepted {
+		t.Fatalf("Issue: got %d", code)
+	}
+	e := <-rc.events
+	if e.Source != SourceGitHub || e.Key != "issue-12" || e.Title != "Crash on empty input" || e.Payload != issue {
+		t.Errorf("Unexpected event %+v", e)
+	}
+
+	rc.events <- e
+	if code := deliver(t, rc, "/github", signed, issue); code != http.StatusServiceUnavailable {
+		t.Errorf("Full queue: got %d", code)
+	}
+}
+
+func TestParseSentry(t *testing.T) {
+	alert := `{"action": "triggered", "data": {"event": {"issue_id": "42", "title": "ZeroDivisionError", "culprit": "app.compute", "web_url": "https://sentry.io/e/1"}}}`
+	e, err := parseSentry("event_alert", []byte(alert))
+	if err != nil || e == nil {
+		t.Fatalf("parseSentry failed: %v", err)
+	}
+	if e.Key != "issue-42" || e.Title != "ZeroDivisionError" || e.Body != "app.compute" {
+		t.Errorf("Unexpected event %+v", e)
+	}
+
+	resolved := `{"action": "resolved", "data": {"issue": {"id": "42", "title": "ZeroDivisionError"}}}`
+	if e, err := parseSentry("issue", []byte(resolved)); err != nil || e != nil {
+		t.Errorf("Expected a resolved issue to be ignored, got %+v (%v)", e, err)
+	}
+	if e, err := parseSentry("installation", []byte(`{}`)); err != nil || e != nil {
+		t.Errorf("Expected installations to be ignored, got %+v (%v)", e, err)
+	}
+}
+
+func TestServe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	handled := make(chan Event, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, ln, Secrets{Sentry: "s"}, func(e Event) { handled <- e })
+	}()
+
+	body := `{"action": "created", "data": {"issue": {"id": "7", "title": "KeyError", "permalink": "https://sentry.io/i/7"}}}`
+	req, _ := http.NewRequest("POST", "http://"+ln.Addr().String()+"/sentry", strings.NewReader(body))
+	req.Header.Set("Sentry-Hook-Resource", "issue")
+	req.Header.Set("Sentry-Hook-Signature", Sign("s", []byte(body)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", resp.StatusCode)
+	}
+
+	select {
+	case e := <-handled:
+		if e.Key != "issue-7" || e.URL != "https://sentry.io/i/7" {
+			t.Errorf("Unexpected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Event not handled")
+	}
+
+	cancel()
+	if err := <-served; err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+}
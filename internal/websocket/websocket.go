@@ -0,0 +1,407 @@
+// Package websocket is a minimal RFC 6455 client, enough for the chat
+// platforms' event gateways (Slack Socket Mode, the Discord gateway):
+// text messages, fragmentation, ping/pong, and close. It does not
+// negotiate extensions or compression. Accept serves the other end, for
+// browsers connecting to goclode --listen.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// acceptGUID is appended to the key to compute Sec-WebSocket-Accept
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize caps a message, fragments included
+const maxMessageSize = 16 << 20
+
+// CloseNormal is the status code of a clean close
+const CloseNormal = 1000
+
+// CloseError is returned by ReadMessage once the other end closed the
+// connection
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket closed (%d)", e.Code)
+	}
+	return fmt.Sprintf("websocket closed (%d): %s", e.Code, e.Reason)
+}
+
+// Conn is a connection, a client's or one Accept answered. ReadMessage
+// must be called from one goroutine; WriteMessage and Close are safe from
+// any.
+type Conn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	server bool // Frames go unmasked, and the client's must be masked
+	wmu    sync.Mutex
+	closed bool
+}
+
+// Dial opens a connection to a ws:// or wss:// URL
+func Dial(ctx context.Context, rawURL string, header http.Header) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	secure := false
+	switch u.Scheme {
+	case "wss":
+		secure = true
+	case "ws":
+	default:
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		if secure {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	if secure {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("websocket: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	c, err := handshake(ctx, conn, u, header)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// handshake upgrades an open connection
+func handshake(ctx context.Context, conn net.Conn, u *url.URL, header http.Header) (*Conn, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     "GET",
+		URL:        &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Host:       u.Host,
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("websocket: handshake failed: %s %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, fmt.Errorf("websocket: handshake failed: bad Sec-WebSocket-Accept")
+	}
+	return &Conn{conn: conn, r: r}, nil
+}
+
+// IsUpgrade reports whether req asks for a WebSocket
+func IsUpgrade(req *http.Request) bool {
+	return headerHas(req.Header, "Upgrade", "websocket") && headerHas(req.Header, "Connection", "upgrade")
+}
+
+// Accept answers the upgrade request req, read from conn through r, and
+// returns the server end of the connection. Checking who is asking (Host,
+// Origin, credentials) is up to the caller.
+func Accept(conn net.Conn, r *bufio.Reader, req *http.Request) (*Conn, error) {
+	key := req.Header.Get("Sec-WebSocket-Key")
+	nonce, err := base64.StdEncoding.DecodeString(key)
+	switch {
+	case req.Method != http.MethodGet || !IsUpgrade(req):
+		err = errors.New("not a WebSocket upgrade")
+	case req.Header.Get("Sec-WebSocket-Version") != "13":
+		conn.Write([]byte("HTTP/1.1 426 Upgrade Required\r\nSec-WebSocket-Version: 13\r\nContent-Length: 0\r\n\r\n"))
+		return nil, errors.New("websocket: unsupported version")
+	case err != nil || len(nonce) != 16:
+		err = errors.New("bad Sec-WebSocket-Key")
+	}
+	if err != nil {
+		Refuse(conn, http.StatusBadRequest, err.Error())
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	defer conn.SetWriteDeadline(time.Time{})
+	resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		return nil, fmt.Errorf("websocket: %w", err)
+	}
+	return &Conn{conn: conn, r: r, server: true}, nil
+}
+
+// Refuse answers an upgrade request with an HTTP error
+func Refuse(w io.Writer, status int, reason string) error {
+	_, err := fmt.Fprintf(w, "HTTP/1.1 %d %s\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s\n",
+		status, http.StatusText(status), len(reason)+1, reason)
+	return err
+}
+
+// headerHas reports whether one of the comma-separated values of a header
+// is token, ignoring case
+func headerHas(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// acceptKey computes the Sec-WebSocket-Accept answering key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ReadMessage returns the next text or binary message, answering pings on
+// the way. After the other end closes, it returns a *CloseError.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	fragmented := false
+	for {
+		f, err := readFrame(c.r)
+		if err != nil {
+			return nil, err
+		}
+		if c.server && !f.masked {
+			return nil, errors.New("websocket: unmasked frame from the client")
+		}
+		switch f.opcode {
+		case opPing:
+			if err := c.write(opPong, f.payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			closeErr := &CloseError{Code: 1005}
+			if len(f.payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(f.payload))
+				closeErr.Reason = string(f.payload[2:])
+			}
+			c.write(opClose, f.payload[:min(len(f.payload), 2)])
+			c.conn.Close()
+			return nil, closeErr
+		case opText, opBinary:
+			if fragmented {
+				return nil, fmt.Errorf("websocket: new message inside a fragmented one")
+			}
+			message = f.payload
+		case opContinuation:
+			if !fragmented {
+				return nil, fmt.Errorf("websocket: continuation without a message")
+			}
+			if len(message)+len(f.payload) > maxMessageSize {
+				return nil, fmt.Errorf("websocket: message over %d bytes", maxMessageSize)
+			}
+			message = append(message, f.payload...)
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %d", f.opcode)
+		}
+		if f.fin {
+			return message, nil
+		}
+		fragmented = true
+	}
+}
+
+// WriteMessage sends a text message
+func (c *Conn) WriteMessage(data []byte) error {
+	return c.write(opText, data)
+}
+
+// SetReadDeadline bounds the wait of ReadMessage
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// Close sends a normal close and closes the connection
+func (c *Conn) Close() error {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, CloseNormal)
+	c.write(opClose, payload)
+	return c.conn.Close()
+}
+
+// write sends one frame, masked from clients
+func (c *Conn) write(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	if opcode == opClose {
+		c.closed = true
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	return writeFrame(c.conn, opcode, payload, !c.server)
+}
+
+// frame is one decoded frame
+type frame struct {
+	fin     bool
+	opcode  byte
+	masked  bool
+	payload []byte
+}
+
+// readFrame reads a frame, unmasking it if needed
+func readFrame(r *bufio.Reader) (*frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	f := &frame{fin: head[0]&0x80 != 0, opcode: head[0] & 0x0F}
+	if head[0]&0x70 != 0 {
+		return nil, errors.New("websocket: unexpected reserved bits (no extension was negotiated)")
+	}
+	masked := head[1]&0x80 != 0
+	f.masked = masked
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("websocket: frame over %d bytes", maxMessageSize)
+	}
+	if f.opcode >= opClose && (length > 125 || !f.fin) {
+		return nil, errors.New("websocket: bad control frame")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return nil, err
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return nil, err
+	}
+	if masked {
+		for i := range f.payload {
+			f.payload[i] ^= mask[i%4]
+		}
+	}
+	return f, nil
+}
+
+// writeFrame writes a single final frame; clients must mask
+func writeFrame(w io.Writer, opcode byte, payload []byte, mask bool) error {
+	buf := make([]byte, 0, 14+len(payload))
+	buf = append(buf, 0x80|opcode)
+	maskBit := byte(0)
+	if mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if mask {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		buf = append(buf, key[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		for i := range buf[start:] {
+			buf[start+i] ^= key[i%4]
+		}
+	} else {
+		buf = append(buf, payload...)
+	}
+	_, err := w.Write(buf)
+	return err
+}
@@ -0,0 +1,164 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer upgrades, sends a fragmented greeting and a ping, echoes one
+// message back, then closes
+func echoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+
+		// "hello world" in two fragments, with a ping between them
+		rw.Write([]byte{opText, 6})
+		rw.WriteString("hello ")
+		writeFrame(rw, opPing, []byte("p"), false)
+		writeFrame(rw, opContinuation, []byte("world"), false)
+		rw.Flush()
+
+		pong, err := readFrame(rw.Reader)
+		if err != nil || pong.opcode != opPong || string(pong.payload) != "p" {
+			t.Errorf("Expected a pong, got %+v (%v)", pong, err)
+			return
+		}
+		msg, err := readFrame(rw.Reader)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		writeFrame(rw, opText, msg.payload, false)
+		writeFrame(rw, opClose, []byte{0x03, 0xE8, 'b', 'y', 'e'}, false)
+		rw.Flush()
+		readFrame(bufio.NewReader(rw)) // The client's close
+	}))
+}
+
+func TestDialReadWrite(t *testing.T) {
+	srv := echoServer(t)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	if _, err := Dial(ctx, url, nil); err == nil {
+		t.Fatal("Expected the handshake to fail without credentials")
+	}
+	conn, err := Dial(ctx, url, http.Header{"Authorization": {"Bearer test"}})
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	msg, err := conn.ReadMessage()
+	if err != nil || string(msg) != "hello world" {
+		t.Fatalf("Expected the reassembled greeting, got %q (%v)", msg, err)
+	}
+	long := strings.Repeat("x", 70000) // Needs the 64-bit length
+	if err := conn.WriteMessage([]byte(long)); err != nil {
+		t.Fatal(err)
+	}
+	msg, err = conn.ReadMessage()
+	if err != nil || string(msg) != long {
+		t.Fatalf("Expected the echo, got %d bytes (%v)", len(msg), err)
+	}
+	_, err = conn.ReadMessage()
+	closeErr, ok := err.(*CloseError)
+	if !ok || closeErr.Code != CloseNormal || closeErr.Reason != "bye" {
+		t.Errorf("Expected a normal close, got %v", err)
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455, section 1.3
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected accept key %q", got)
+	}
+}
+
+func TestAccept(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				req, err := http.ReadRequest(r)
+				if err != nil {
+					return
+				}
+				ws, err := Accept(conn, r, req)
+				if err != nil {
+					return
+				}
+				defer ws.Close()
+				msg, err := ws.ReadMessage()
+				if err != nil {
+					return
+				}
+				ws.WriteMessage(msg)
+			}()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, "ws://"+ln.Addr().String()+"/", nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteMessage([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := conn.ReadMessage(); err != nil || string(msg) != "ping" {
+		t.Errorf("Expected the echo, got %q (%v)", msg, err)
+	}
+
+	raw, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	raw.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(raw), nil)
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a request without key to be refused, got %v (%v)", resp, err)
+	}
+}
+
+func TestAccept_UnmaskedFrame(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	ws := &Conn{conn: server, r: bufio.NewReader(server), server: true}
+	go writeFrame(client, opText, []byte("hi"), false)
+	if _, err := ws.ReadMessage(); err == nil {
+		t.Error("Expected an unmasked client frame to be refused")
+	}
+}
@@ -0,0 +1,129 @@
+// Package workspace - File backups taken before GoClode overwrites anything
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// BackupDir is where backups live, relative to the workspace root
+const BackupDir = ".goclode/backups"
+
+// backupTimeFormat sorts lexically and is safe in file names
+const backupTimeFormat = "2006-01-02_15-04-05.000"
+
+// Backups stores copies of files under .goclode/backups/<session>/<timestamp>/
+type Backups struct {
+	root    string
+	session string
+	stamp   string
+}
+
+// Backup is one saved version of a file
+type Backup struct {
+	Path      string // Workspace-relative path of the original file
+	File      string // Location of the backup copy
+	Session   string
+	Timestamp time.Time
+}
+
+// NewBackups creates a backup store for a session
+func NewBackups(root, sessionID string) *Backups {
+	b := &Backups{root: root, session: sessionID}
+	b.Begin()
+	return b
+}
+
+// Begin starts a new backup batch; files saved afterwards share a timestamp
+func (b *Backups) Begin() {
+	b.stamp = time.Now().Format(backupTimeFormat)
+}
+
+// Save copies the current content of path into the current batch.
+// Missing files are not an error: there is nothing to back up.
+func (b *Backups) Save(path string) error {
+	src := path
+	if !filepath.IsAbs(src) {
+		src = filepath.Join(b.root, path)
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	dst := filepath.Join(b.root, BackupDir, b.session, b.stamp, path)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("create backup dir: %w", err)
+	}
+	if err := os.WriteFile(dst, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("backup %s: %w", path, err)
+	}
+	return nil
+}
+
+// Versions lists saved versions of path across all sessions, newest first
+func (b *Backups) Versions(path string) ([]Backup, error) {
+	pattern := filepath.Join(b.root, BackupDir, "*", "*", path)
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]Backup, 0, len(matches))
+	for _, file := range matches {
+		stampDir := filepath.Dir(file)
+		for rel := filepath.Dir(path); rel != "."; rel = filepath.Dir(rel) {
+			stampDir = filepath.Dir(stampDir)
+		}
+
+		ts, err := time.ParseInLocation(backupTimeFormat, filepath.Base(stampDir), time.Local)
+		if err != nil {
+			continue
+		}
+
+		versions = append(versions, Backup{
+			Path:      path,
+			File:      file,
+			Session:   filepath.Base(filepath.Dir(stampDir)),
+			Timestamp: ts,
+		})
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Timestamp.After(versions[j].Timestamp)
+	})
+	return versions, nil
+}
+
+// Restore writes a backed-up version over the workspace file
+func (b *Backups) Restore(backup Backup) error {
+	info, err := os.Stat(backup.File)
+	if err != nil {
+		return fmt.Errorf("stat backup: %w", err)
+	}
+	data, err := os.ReadFile(backup.File)
+	if err != nil {
+		return fmt.Errorf("read backup: %w", err)
+	}
+
+	dst := filepath.Join(b.root, backup.Path)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
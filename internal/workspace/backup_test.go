@@ -0,0 +1,66 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackups_SaveAndRestore(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join("pkg", "main.go")
+	full := filepath.Join(root, path)
+
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := NewBackups(root, "session-a")
+	if err := backups.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Second batch, a little later so timestamps differ
+	time.Sleep(5 * time.Millisecond)
+	os.WriteFile(full, []byte("v2"), 0644)
+	backups.Begin()
+	if err := backups.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	os.WriteFile(full, []byte("v3"), 0644)
+
+	// Missing files are silently skipped
+	if err := backups.Save("missing.go"); err != nil {
+		t.Errorf("Save of missing file: %v", err)
+	}
+
+	versions, err := backups.Versions(path)
+	if err != nil {
+		t.Fatalf("Versions failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("Versions count = %d, want 2", len(versions))
+	}
+	if versions[0].Session != "session-a" {
+		t.Errorf("Session = %q, want session-a", versions[0].Session)
+	}
+
+	// Newest first
+	if err := backups.Restore(versions[0]); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if data, _ := os.ReadFile(full); string(data) != "v2" {
+		t.Errorf("Restored content = %q, want v2", data)
+	}
+
+	if err := backups.Restore(versions[1]); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if data, _ := os.ReadFile(full); string(data) != "v1" {
+		t.Errorf("Restored content = %q, want v1", data)
+	}
+}
@@ -0,0 +1,210 @@
+// Package workspace - Checkpoints of workspaces outside git: the files
+// the index lists, saved as a tarball under .goclode/checkpoints
+package workspace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CheckpointDir is where checkpoint tarballs live, relative to the
+// workspace root
+const CheckpointDir = ".goclode/checkpoints"
+
+// checkpointExt ends every checkpoint file name
+const checkpointExt = ".tar.gz"
+
+// Checkpoint is a saved workspace
+type Checkpoint struct {
+	Name    string
+	Files   int // Files saved, when known
+	Created time.Time
+}
+
+// CreateCheckpoint saves the files under root, as the file index lists
+// them, as name
+func CreateCheckpoint(root, name string) (*Checkpoint, error) {
+	path := checkpointPath(root, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("checkpoint %s already exists", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	files := NewFileIndex(root).Files()
+	if err := writeCheckpoint(root, path, files); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return &Checkpoint{Name: name, Files: len(files), Created: time.Now()}, nil
+}
+
+// writeCheckpoint writes the files to a tarball at path
+func writeCheckpoint(root, path string, files []string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	zw := gzip.NewWriter(out)
+	tw := tar.NewWriter(zw)
+
+	for _, rel := range files {
+		if err := addToTar(tw, filepath.Join(root, filepath.FromSlash(rel)), rel); err != nil {
+			return fmt.Errorf("checkpoint %s: %w", rel, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// addToTar writes one file to a tarball; files gone since the index was
+// built are skipped
+func addToTar(tw *tar.Writer, path, rel string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return err
+	}
+	header := &tar.Header{
+		Name:    rel,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, info.Size())
+	return err
+}
+
+// Checkpoints lists the checkpoints of root, newest first
+func Checkpoints(root string) ([]Checkpoint, error) {
+	entries, err := os.ReadDir(filepath.Join(root, CheckpointDir))
+	if os.IsNotExist(err) {
+		return []Checkpoint{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoints := make([]Checkpoint, 0, len(entries))
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), checkpointExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, Checkpoint{Name: name, Created: info.ModTime()})
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Created.After(checkpoints[j].Created)
+	})
+	return checkpoints, nil
+}
+
+// DeleteCheckpoint removes a checkpoint
+func DeleteCheckpoint(root, name string) error {
+	err := os.Remove(checkpointPath(root, name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no checkpoint %s", name)
+	}
+	return err
+}
+
+// RestoreCheckpoint puts back the files saved as name and removes the
+// files the index lists that it did not save
+func RestoreCheckpoint(root, name string) error {
+	in, err := os.Open(checkpointPath(root, name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no checkpoint %s", name)
+	}
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("checkpoint %s: %w", name, err)
+	}
+	current := NewFileIndex(root).Files()
+
+	saved := make(map[string]bool)
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("checkpoint %s: %w", name, err)
+		}
+		rel := filepath.ToSlash(filepath.Clean(header.Name))
+		if header.Typeflag != tar.TypeReg || rel == ".." || strings.HasPrefix(rel, "../") || filepath.IsAbs(rel) {
+			continue
+		}
+		if err := restoreFile(filepath.Join(root, filepath.FromSlash(rel)), os.FileMode(header.Mode).Perm(), tr); err != nil {
+			return fmt.Errorf("restore %s: %w", rel, err)
+		}
+		saved[rel] = true
+	}
+
+	for _, rel := range current {
+		if !saved[rel] {
+			if err := os.Remove(filepath.Join(root, filepath.FromSlash(rel))); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreFile writes a file from a tarball, creating its directory
+func restoreFile(path string, mode os.FileMode, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		os.Remove(path) // Replaced, not written through
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// checkpointPath is the tarball of a checkpoint
+func checkpointPath(root, name string) string {
+	return filepath.Join(root, CheckpointDir, name+checkpointExt)
+}
@@ -0,0 +1,61 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.go":         "package main\n",
+		"internal/a/a.go": "package a\n",
+		".goclode/x.db":   "state",
+	})
+
+	cp, err := CreateCheckpoint(root, "start")
+	if err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+	if cp.Files != 2 {
+		t.Errorf("Expected the two indexed files saved, got %d", cp.Files)
+	}
+	if _, err := CreateCheckpoint(root, "start"); err == nil {
+		t.Error("Expected a second checkpoint of the same name refused")
+	}
+
+	writeFiles(t, root, map[string]string{
+		"main.go":       "package main\n\nfunc broken(\n",
+		"new.go":        "package main\n",
+		".goclode/x.db": "state, later",
+	})
+	os.Remove(filepath.Join(root, "internal/a/a.go"))
+
+	if err := RestoreCheckpoint(root, "start"); err != nil {
+		t.Fatalf("RestoreCheckpoint: %v", err)
+	}
+	for name, want := range map[string]string{
+		"main.go":         "package main\n",
+		"internal/a/a.go": "package a\n",
+		".goclode/x.db":   "state, later",
+	} {
+		if got, _ := os.ReadFile(filepath.Join(root, name)); string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "new.go")); !os.IsNotExist(err) {
+		t.Error("Expected the file added since removed")
+	}
+
+	list, err := Checkpoints(root)
+	if err != nil || len(list) != 1 || list[0].Name != "start" {
+		t.Errorf("Checkpoints = %+v, %v", list, err)
+	}
+	if err := DeleteCheckpoint(root, "start"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreCheckpoint(root, "start"); err == nil {
+		t.Error("Expected a removed checkpoint not restored")
+	}
+}
@@ -0,0 +1,69 @@
+// Package workspace - Detection of binary and generated files
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"unicode/utf8"
+)
+
+// sniffBytes is how much of a file is inspected, like git's binary detection
+const sniffBytes = 8000
+
+// generatedHeaderLines is how many leading lines may hold a generated marker
+const generatedHeaderLines = 10
+
+var generatedMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`), // Go convention
+	regexp.MustCompile(`@generated\b`),                         // Facebook/JS tooling
+	regexp.MustCompile(`(?i)\bauto-?generated\b.*\bdo not (edit|modify)\b`),
+	regexp.MustCompile(`(?i)^\W*DO NOT EDIT\b`),
+}
+
+// IsBinary reports whether data looks like a binary file: a NUL byte or
+// invalid UTF-8 in the first few kilobytes
+func IsBinary(data []byte) bool {
+	if len(data) > sniffBytes {
+		data = data[:sniffBytes]
+		// Don't count a multi-byte rune cut by the limit as invalid
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	return bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+}
+
+// IsGenerated reports whether data carries a generated-file marker in its header
+func IsGenerated(data []byte) bool {
+	lines := bytes.SplitN(data, []byte("\n"), generatedHeaderLines+1)
+	if len(lines) > generatedHeaderLines {
+		lines = lines[:generatedHeaderLines]
+	}
+	for _, line := range lines {
+		line = bytes.TrimRight(line, "\r")
+		for _, m := range generatedMarkers {
+			if m.Match(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckEditable returns an error if the existing file at path is binary or
+// generated. Missing files are editable.
+func CheckEditable(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	if IsBinary(data) {
+		return fmt.Errorf("%s is a binary file", path)
+	}
+	if IsGenerated(data) {
+		return fmt.Errorf("%s is a generated file", path)
+	}
+	return nil
+}
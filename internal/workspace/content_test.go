@@ -0,0 +1,71 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"text", []byte("package main\n"), false},
+		{"utf8", []byte("héllo wörld ✓\n"), false},
+		{"nul byte", []byte("abc\x00def"), true},
+		{"invalid utf8", []byte{0xff, 0xfe, 0x41}, true},
+		{"rune cut at limit", []byte(strings.Repeat("a", sniffBytes-1) + "é"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBinary(tt.data); got != tt.want {
+				t.Errorf("IsBinary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"go generated", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb\n", true},
+		{"go generated crlf", "// Code generated by stringer; DO NOT EDIT.\r\npackage x\r\n", true},
+		{"js generated", "/**\n * @generated\n */\n", true},
+		{"do not edit", "# DO NOT EDIT - produced by make\n", true},
+		{"plain", "package main\n\n// Don't edit lightly\n", false},
+		{"marker too deep", strings.Repeat("x\n", 20) + "// Code generated by x. DO NOT EDIT.\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsGenerated([]byte(tt.data)); got != tt.want {
+				t.Errorf("IsGenerated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEditable(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.go")
+	generated := filepath.Join(dir, "gen.go")
+	os.WriteFile(plain, []byte("package x\n"), 0644)
+	os.WriteFile(generated, []byte("// Code generated by x. DO NOT EDIT.\npackage x\n"), 0644)
+
+	if err := CheckEditable(plain); err != nil {
+		t.Errorf("plain file: %v", err)
+	}
+	if err := CheckEditable(filepath.Join(dir, "missing.go")); err != nil {
+		t.Errorf("missing file: %v", err)
+	}
+	if err := CheckEditable(generated); err == nil {
+		t.Error("generated file should not be editable")
+	}
+}
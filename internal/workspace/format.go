@@ -0,0 +1,77 @@
+// Package workspace - Preserving on-disk conventions when rewriting files
+package workspace
+
+import (
+	"os"
+	"strings"
+)
+
+const utf8BOM = "\uFEFF"
+
+// FileFormat captures conventions of an existing file that LLM output
+// usually loses: permissions, line endings, final newline, and BOM.
+type FileFormat struct {
+	Mode            os.FileMode
+	CRLF            bool
+	BOM             bool
+	TrailingNewline bool
+}
+
+// DefaultFormat is used for new files
+var DefaultFormat = FileFormat{
+	Mode:            0644,
+	TrailingNewline: true,
+}
+
+// DetectFormat inspects an existing file; missing files get DefaultFormat
+func DetectFormat(path string) FileFormat {
+	info, err := os.Stat(path)
+	if err != nil {
+		return DefaultFormat
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return DefaultFormat
+	}
+
+	content := string(data)
+	crlf := strings.Count(content, "\r\n")
+	lf := strings.Count(content, "\n") - crlf
+
+	return FileFormat{
+		Mode:            info.Mode().Perm(),
+		CRLF:            crlf > lf,
+		BOM:             strings.HasPrefix(content, utf8BOM),
+		TrailingNewline: content == "" || strings.HasSuffix(content, "\n"),
+	}
+}
+
+// Apply converts content to this format
+func (f FileFormat) Apply(content string) string {
+	content = strings.TrimPrefix(content, utf8BOM)
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	if f.TrailingNewline {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+	} else {
+		content = strings.TrimSuffix(content, "\n")
+	}
+
+	if f.CRLF {
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	}
+	if f.BOM {
+		content = utf8BOM + content
+	}
+	return content
+}
+
+// WriteFile writes content, creating new files with DefaultFormat.Mode.
+// Existing files keep their permissions (including the executable bit).
+// Content is written as is; use DetectFormat(path).Apply first to convert it.
+func WriteFile(path, content string) error {
+	return os.WriteFile(path, []byte(content), DefaultFormat.Mode)
+}
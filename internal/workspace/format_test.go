@@ -0,0 +1,62 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectFormatAndApply(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		original string
+		proposed string
+		want     string
+	}{
+		{"lf with newline", "a\nb\n", "a\nc", "a\nc\n"},
+		{"lf without newline", "a\nb", "a\nc\n", "a\nc"},
+		{"crlf", "a\r\nb\r\n", "a\nc\n", "a\r\nc\r\n"},
+		{"bom", "\uFEFFa\n", "b", "\uFEFFb\n"},
+		{"bom not doubled", "\uFEFFa\n", "\uFEFFb\n", "\uFEFFb\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.original), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := DetectFormat(path).Apply(tt.proposed); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// New files get a trailing newline
+	if got := DetectFormat(filepath.Join(dir, "missing")).Apply("x"); got != "x\n" {
+		t.Errorf("New file: got %q", got)
+	}
+}
+
+func TestWriteFile_PreservesMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(path, 0755)
+
+	if err := WriteFile(path, "#!/bin/sh\necho hi\n"); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("Mode = %v, want 0755", info.Mode().Perm())
+	}
+}
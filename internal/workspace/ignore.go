@@ -0,0 +1,233 @@
+// Package workspace provides file access rules for the project GoClode runs in
+package workspace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFile is the per-project ignore file name (gitignore syntax)
+const IgnoreFile = ".goclodeignore"
+
+// IgnoreList matches paths against gitignore-style patterns
+type IgnoreList struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	pattern  string
+	re       *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// NewIgnoreList compiles gitignore-style patterns.
+// Blank lines and # comments are skipped.
+func NewIgnoreList(patterns []string) *IgnoreList {
+	l := &IgnoreList{}
+	for _, p := range patterns {
+		l.Add(p)
+	}
+	return l
+}
+
+// LoadIgnoreFile reads patterns from a file; a missing file yields an empty list
+func LoadIgnoreFile(path string) (*IgnoreList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &IgnoreList{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	l := &IgnoreList{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		l.Add(scanner.Text())
+	}
+	return l, scanner.Err()
+}
+
+// Add compiles and appends a single pattern
+func (l *IgnoreList) Add(pattern string) {
+	p := strings.TrimRight(pattern, " \t\r")
+	if p == "" || strings.HasPrefix(p, "#") {
+		return
+	}
+
+	rule := ignoreRule{pattern: p}
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimSuffix(p, "/")
+	}
+	if strings.Contains(p, "/") {
+		rule.anchored = true
+		p = strings.TrimPrefix(p, "/")
+	}
+	if p == "" {
+		return
+	}
+
+	re, err := regexp.Compile("^" + globToRegexp(p) + "$")
+	if err != nil {
+		return
+	}
+	rule.re = re
+	l.rules = append(l.rules, rule)
+}
+
+// Len returns the number of compiled rules
+func (l *IgnoreList) Len() int {
+	return len(l.rules)
+}
+
+// Match reports whether a workspace-relative path is ignored,
+// either directly or through one of its parent directories.
+func (l *IgnoreList) Match(path string) bool {
+	if l == nil || len(l.rules) == 0 {
+		return false
+	}
+
+	path = filepath.ToSlash(filepath.Clean(path))
+	parts := strings.Split(path, "/")
+	for i := 1; i <= len(parts); i++ {
+		if l.matchOne(strings.Join(parts[:i], "/"), i < len(parts)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOne applies all rules to one path; the last matching rule wins
+func (l *IgnoreList) matchOne(path string, isDir bool) bool {
+	base := path[strings.LastIndex(path, "/")+1:]
+
+	ignored := false
+	for _, r := range l.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		subject := base
+		if r.anchored {
+			subject = path
+		}
+		if r.re.MatchString(subject) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp converts a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**"):
+			sb.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case ch == '*':
+			sb.WriteString("[^/]*")
+		case ch == '?':
+			sb.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	return sb.String()
+}
+
+// ParsePatternList parses a config value holding patterns,
+// either as a JSON array or as a comma-separated list.
+func ParsePatternList(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+
+	var patterns []string
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &patterns); err == nil {
+			return patterns
+		}
+	}
+
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// Guard decides which workspace files GoClode may read or write
+type Guard struct {
+	root      string
+	ignore    *IgnoreList
+	protected *IgnoreList
+}
+
+// NewGuard builds a guard from the workspace .goclodeignore and protected patterns
+func NewGuard(root string, protected []string) *Guard {
+	ignore, err := LoadIgnoreFile(filepath.Join(root, IgnoreFile))
+	if err != nil {
+		ignore = &IgnoreList{}
+	}
+
+	return &Guard{
+		root:      root,
+		ignore:    ignore,
+		protected: NewIgnoreList(protected),
+	}
+}
+
+// Check returns an error if the path is protected or ignored
+func (g *Guard) Check(path string) error {
+	rel := path
+	if filepath.IsAbs(path) {
+		r, err := filepath.Rel(g.root, path)
+		if err != nil {
+			return nil
+		}
+		rel = r
+	}
+
+	if g.protected.Match(rel) {
+		return fmt.Errorf("%s is a protected path", path)
+	}
+	if g.ignore.Match(rel) {
+		return fmt.Errorf("%s is listed in %s", path, IgnoreFile)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreList_Match(t *testing.T) {
+	list := NewIgnoreList([]string{
+		"# comment",
+		"*.pem",
+		".env",
+		"vendor/",
+		"/build",
+		"docs/**/*.tmp",
+		"*.log",
+		"!keep.log",
+	})
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"server.pem", true},
+		{"certs/server.pem", true},
+		{".env", true},
+		{"config/.env", true},
+		{".envrc", false},
+		{"vendor/github.com/x/y.go", true},
+		{"vendor", false}, // dir-only pattern, plain file
+		{"build/out.bin", true},
+		{"cmd/build/main.go", false}, // anchored to root
+		{"docs/a/b/c.tmp", true},
+		{"docs/c.tmp", true},
+		{"app.log", true},
+		{"keep.log", false},
+		{"main.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := list.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePatternList(t *testing.T) {
+	if got := ParsePatternList(`[".env", "*.pem"]`); len(got) != 2 || got[1] != "*.pem" {
+		t.Errorf("JSON list: got %v", got)
+	}
+	if got := ParsePatternList(".env, *.pem ,vendor/"); len(got) != 3 || got[2] != "vendor/" {
+		t.Errorf("Comma list: got %v", got)
+	}
+	if got := ParsePatternList(""); got != nil {
+		t.Errorf("Empty: got %v", got)
+	}
+}
+
+func TestGuard_Check(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, IgnoreFile), []byte("secrets/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	guard := NewGuard(root, []string{".env"})
+
+	if err := guard.Check("main.go"); err != nil {
+		t.Errorf("main.go should be allowed: %v", err)
+	}
+	if err := guard.Check(".env"); err == nil {
+		t.Error(".env should be protected")
+	}
+	if err := guard.Check("secrets/token.txt"); err == nil {
+		t.Error("secrets/ should be ignored")
+	}
+	if err := guard.Check(filepath.Join(root, "secrets", "a")); err == nil {
+		t.Error("absolute path under secrets/ should be ignored")
+	}
+}
@@ -0,0 +1,239 @@
+// Package workspace - Index of project files for matching unnamed code blocks
+package workspace
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxIndexedFiles bounds the index for very large trees
+const maxIndexedFiles = 20000
+
+// maxGuessFileBytes skips huge candidates when guessing
+const maxGuessFileBytes = 512 * 1024
+
+// MinGuessScore is the confidence needed before a guess replaces the default name
+const MinGuessScore = 0.3
+
+// skippedDirs are never indexed
+var skippedDirs = map[string]bool{
+	".git": true, ".goclode": true, "node_modules": true, "vendor": true,
+	".venv": true, "venv": true, "__pycache__": true, "target": true, "dist": true,
+}
+
+// FileIndex lists the workspace's source files
+type FileIndex struct {
+	root  string
+	mu    sync.RWMutex
+	files []string
+}
+
+// NewFileIndex creates an index and builds it
+func NewFileIndex(root string) *FileIndex {
+	ix := &FileIndex{root: root}
+	ix.Refresh()
+	return ix
+}
+
+// workspaceIgnores loads the .gitignore and .goclodeignore rules of root
+func workspaceIgnores(root string) *IgnoreList {
+	ignore := &IgnoreList{}
+	for _, name := range []string{".gitignore", IgnoreFile} {
+		if l, err := LoadIgnoreFile(filepath.Join(root, name)); err == nil {
+			ignore.rules = append(ignore.rules, l.rules...)
+		}
+	}
+	return ignore
+}
+
+// Refresh rebuilds the index, honoring .gitignore and .goclodeignore
+func (ix *FileIndex) Refresh() error {
+	ignore := workspaceIgnores(ix.root)
+
+	files := make([]string, 0)
+	err := filepath.WalkDir(ix.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(ix.root, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+
+		if d.IsDir() {
+			if skippedDirs[d.Name()] || ignore.matchOne(filepath.ToSlash(rel), true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || ignore.Match(rel) {
+			return nil
+		}
+
+		files = append(files, filepath.ToSlash(rel))
+		if len(files) >= maxIndexedFiles {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+
+	sort.Strings(files)
+
+	ix.mu.Lock()
+	ix.files = files
+	ix.mu.Unlock()
+	return err
+}
+
+// Files returns the indexed workspace-relative paths
+func (ix *FileIndex) Files() []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	files := make([]string, len(ix.files))
+	copy(files, ix.files)
+	return files
+}
+
+// Add records a file in the index
+func (ix *FileIndex) Add(path string) {
+	path = filepath.ToSlash(path)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	i := sort.SearchStrings(ix.files, path)
+	if i < len(ix.files) && ix.files[i] == path {
+		return
+	}
+	ix.files = append(ix.files, "")
+	copy(ix.files[i+1:], ix.files[i:])
+	ix.files[i] = path
+}
+
+// Remove drops a file from the index
+func (ix *FileIndex) Remove(path string) {
+	path = filepath.ToSlash(path)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	i := sort.SearchStrings(ix.files, path)
+	if i < len(ix.files) && ix.files[i] == path {
+		ix.files = append(ix.files[:i], ix.files[i+1:]...)
+	}
+}
+
+var (
+	declPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?m)^func\s+(?:\([^)]*\)\s*)?([A-Za-z_]\w*)`),
+		regexp.MustCompile(`(?m)^type\s+([A-Za-z_]\w*)`),
+		regexp.MustCompile(`(?m)^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?(?:public\s+)?class\s+([A-Za-z_]\w*)`),
+		regexp.MustCompile(`(?m)^\s*(?:async\s+)?def\s+([A-Za-z_]\w*)`),
+		regexp.MustCompile(`(?m)^\s*(?:export\s+)?(?:async\s+)?function\s+([A-Za-z_]\w*)`),
+		regexp.MustCompile(`(?m)^\s*(?:pub\s+)?(?:fn|struct|enum|trait)\s+([A-Za-z_]\w*)`),
+	}
+	goPackagePattern = regexp.MustCompile(`(?m)^package\s+(\w+)`)
+)
+
+// Declarations returns the names of functions, types, and classes declared in code
+func Declarations(code string) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, p := range declPatterns {
+		for _, m := range p.FindAllStringSubmatch(code, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// GuessPath finds the indexed file with extension ext that the code most
+// likely belongs to, based on shared declarations, Go package, and shared lines.
+// It returns "" when nothing scores at least MinGuessScore.
+func (ix *FileIndex) GuessPath(code, ext string) (string, float64) {
+	decls := Declarations(code)
+	pkg := ""
+	if m := goPackagePattern.FindStringSubmatch(code); m != nil {
+		pkg = m[1]
+	}
+	lines := significantLines(code)
+
+	best, bestScore := "", 0.0
+	for _, path := range ix.Files() {
+		if !strings.EqualFold(filepath.Ext(path), ext) {
+			continue
+		}
+
+		full := filepath.Join(ix.root, filepath.FromSlash(path))
+		if info, err := os.Stat(full); err != nil || info.Size() > maxGuessFileBytes {
+			continue
+		}
+		data, err := os.ReadFile(full)
+		if err != nil {
+			continue
+		}
+		existing := string(data)
+
+		score := 0.0
+		if len(decls) > 0 {
+			existingDecls := make(map[string]bool)
+			for _, d := range Declarations(existing) {
+				existingDecls[d] = true
+			}
+			shared := 0
+			for _, d := range decls {
+				if existingDecls[d] {
+					shared++
+				}
+			}
+			score += 0.6 * float64(shared) / float64(len(decls))
+		}
+
+		if pkg != "" {
+			if m := goPackagePattern.FindStringSubmatch(existing); m != nil && m[1] == pkg {
+				score += 0.2
+			}
+		}
+
+		if len(lines) > 0 {
+			existingLines := make(map[string]bool)
+			for _, l := range significantLines(existing) {
+				existingLines[l] = true
+			}
+			shared := 0
+			for _, l := range lines {
+				if existingLines[l] {
+					shared++
+				}
+			}
+			score += 0.2 * float64(shared) / float64(len(lines))
+		}
+
+		if score > bestScore || (score == bestScore && best != "" && len(path) < len(best)) {
+			best, bestScore = path, score
+		}
+	}
+
+	if bestScore < MinGuessScore {
+		return "", bestScore
+	}
+	return best, bestScore
+}
+
+// significantLines returns trimmed lines long enough to be distinctive
+func significantLines(code string) []string {
+	lines := make([]string, 0)
+	for _, l := range strings.Split(code, "\n") {
+		l = strings.TrimSpace(l)
+		if len(l) >= 12 {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,99 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFileIndex_Refresh(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.go":             "package main\n",
+		"internal/a/a.go":     "package a\n",
+		"build/out.bin":       "x",
+		".git/HEAD":           "ref",
+		"node_modules/x/x.js": "x",
+		".gitignore":          "build/\n",
+	})
+
+	ix := NewFileIndex(root)
+	want := []string{".gitignore", "internal/a/a.go", "main.go"}
+	if got := ix.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %v, want %v", got, want)
+	}
+
+	ix.Add("internal/b/b.go")
+	ix.Remove("main.go")
+	want = []string{".gitignore", "internal/a/a.go", "internal/b/b.go"}
+	if got := ix.Files(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after Add/Remove = %v, want %v", got, want)
+	}
+}
+
+func TestFileIndex_GuessPath(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.go": "package main\n\nfunc main() {\n\trun()\n}\n",
+		"internal/store/store.go": "package store\n\ntype Store struct{}\n\n" +
+			"func (s *Store) Get(key string) string {\n\treturn \"\"\n}\n",
+		"internal/store/util.go": "package store\n\nfunc helper() {}\n",
+		"app/models.py":          "class User:\n    def save(self):\n        pass\n",
+	})
+	ix := NewFileIndex(root)
+
+	tests := []struct {
+		name string
+		code string
+		ext  string
+		want string
+	}{
+		{
+			name: "go method on existing type",
+			code: "package store\n\nfunc (s *Store) Get(key string) string {\n\treturn s.data[key]\n}\n",
+			ext:  ".go",
+			want: "internal/store/store.go",
+		},
+		{
+			name: "python class",
+			code: "class User:\n    def save(self):\n        db.add(self)\n",
+			ext:  ".py",
+			want: "app/models.py",
+		},
+		{
+			name: "new code matches nothing",
+			code: "package other\n\nfunc Brand() {}\n",
+			ext:  ".go",
+			want: "",
+		},
+		{
+			name: "wrong extension",
+			code: "class User:\n    pass\n",
+			ext:  ".rb",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := ix.GuessPath(tt.code, tt.ext)
+			if got != tt.want {
+				t.Errorf("GuessPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
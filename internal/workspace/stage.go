@@ -0,0 +1,133 @@
+// Package workspace - Shadow copy of the workspace for validating changes
+package workspace
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// StageDir is where staged changes are applied, relative to the workspace root
+const StageDir = ".goclode/stage"
+
+// Stage is a copy of the workspace's indexed files where changes are applied
+// and validated before being promoted to the real tree
+type Stage struct {
+	root string
+	dir  string
+}
+
+// NewStage replaces any previous stage with a fresh copy of files
+func NewStage(root string, files []string) (*Stage, error) {
+	s := &Stage{root: root, dir: filepath.Join(root, StageDir)}
+	if err := os.RemoveAll(s.dir); err != nil {
+		return nil, fmt.Errorf("clear stage: %w", err)
+	}
+
+	for _, f := range files {
+		if err := copyFile(filepath.Join(root, f), filepath.Join(s.dir, f)); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			s.Cleanup()
+			return nil, fmt.Errorf("stage %s: %w", f, err)
+		}
+	}
+	return s, nil
+}
+
+// Dir returns the stage's root directory
+func (s *Stage) Dir() string {
+	return s.dir
+}
+
+// path maps a workspace-relative path into the stage
+func (s *Stage) path(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("%s is outside the workspace and cannot be staged", path)
+	}
+	return filepath.Join(s.dir, path), nil
+}
+
+// Write stores content for path in the stage
+func (s *Stage) Write(path, content string) error {
+	dst, err := s.path(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return WriteFile(dst, content)
+}
+
+// Read returns the staged content of path
+func (s *Stage) Read(path string) (string, error) {
+	src, err := s.path(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(src)
+	return string(data), err
+}
+
+// Remove deletes path from the stage
+func (s *Stage) Remove(path string) error {
+	dst, err := s.path(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Rename moves a file within the stage
+func (s *Stage) Rename(oldPath, newPath string) error {
+	src, err := s.path(oldPath)
+	if err != nil {
+		return err
+	}
+	dst, err := s.path(newPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// Cleanup removes the stage directory
+func (s *Stage) Cleanup() error {
+	return os.RemoveAll(s.dir)
+}
+
+// copyFile copies src to dst, keeping permissions
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
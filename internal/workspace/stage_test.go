@@ -0,0 +1,52 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStage(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.go":     "package a\n",
+		"pkg/b.go": "package pkg\n",
+	})
+
+	stage, err := NewStage(root, []string{"a.go", "pkg/b.go", "missing.go"})
+	if err != nil {
+		t.Fatalf("NewStage failed: %v", err)
+	}
+
+	if err := stage.Write("a.go", "package a // staged\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := stage.Rename("pkg/b.go", "pkg/c.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := stage.Write("/etc/passwd", "x"); err == nil {
+		t.Error("Write outside the workspace should fail")
+	}
+
+	// The real tree is untouched
+	if data, _ := os.ReadFile(filepath.Join(root, "a.go")); string(data) != "package a\n" {
+		t.Errorf("working tree modified: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pkg", "b.go")); err != nil {
+		t.Errorf("working tree file moved: %v", err)
+	}
+
+	if got, _ := stage.Read("a.go"); got != "package a // staged\n" {
+		t.Errorf("Read() = %q", got)
+	}
+	if _, err := stage.Read("pkg/c.go"); err != nil {
+		t.Errorf("renamed file missing from stage: %v", err)
+	}
+
+	if err := stage.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stage.Dir()); !os.IsNotExist(err) {
+		t.Errorf("stage not removed: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package workspace - Watcher: files changed under the workspace, by
+// GoClode or anyone else, reported in batches so indexes follow the tree
+// without rescanning it
+package workspace
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchQuiet is how long the tree must be quiet before a batch is reported
+const watchQuiet = 200 * time.Millisecond
+
+// maxWatchedDirs bounds the directories watched: each takes an inotify
+// watch, of which the system allows few
+const maxWatchedDirs = 4096
+
+// Change is a file written, created, or removed
+type Change struct {
+	Path    string // Workspace-relative, slash-separated
+	Removed bool
+}
+
+// watcher follows the directories the file index walks
+type watcher struct {
+	root   string
+	ignore *IgnoreList
+	fw     *fsnotify.Watcher
+	dirs   int
+}
+
+// Watch watches the files under root until ctx ends, skipping those the
+// file index skips, and calls fn with each batch of changes. It returns
+// early only if the watch cannot start.
+func Watch(ctx context.Context, root string, fn func([]Change)) error {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	w := &watcher{root: abs, ignore: workspaceIgnores(abs), fw: fw}
+	w.addTree(abs, nil)
+	w.run(ctx, fn)
+	return nil
+}
+
+// run gathers events until the tree is quiet, then reports them
+func (w *watcher) run(ctx context.Context, fn func([]Change)) {
+	defer w.fw.Close()
+	pending := make(map[string]bool)
+	quiet := time.NewTimer(watchQuiet)
+	quiet.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.fw.Events:
+			if !ok {
+				return
+			}
+			w.note(event, pending)
+			quiet.Reset(watchQuiet)
+		case <-w.fw.Errors:
+			// Lost events: the next change to those files reports them
+		case <-quiet.C:
+			if changes := w.settle(pending); len(changes) > 0 {
+				fn(changes)
+			}
+			pending = make(map[string]bool)
+		}
+	}
+}
+
+// note records the file of an event; new directories are watched, and
+// the files already in them reported
+func (w *watcher) note(event fsnotify.Event, pending map[string]bool) {
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+	info, err := os.Lstat(event.Name)
+	isDir := err == nil && info.IsDir()
+	rel, ok := w.relative(event.Name, isDir)
+	switch {
+	case !ok:
+	case isDir && event.Op&fsnotify.Create != 0:
+		w.addTree(event.Name, pending)
+	case !isDir:
+		pending[rel] = true
+	}
+}
+
+// settle turns the files noted into changes, as they are now on disk
+func (w *watcher) settle(pending map[string]bool) []Change {
+	paths := make([]string, 0, len(pending))
+	for rel := range pending {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+
+	changes := make([]Change, 0, len(paths))
+	for _, rel := range paths {
+		info, err := os.Lstat(filepath.Join(w.root, filepath.FromSlash(rel)))
+		switch {
+		case os.IsNotExist(err):
+			changes = append(changes, Change{Path: rel, Removed: true})
+		case err == nil && info.Mode().IsRegular():
+			changes = append(changes, Change{Path: rel})
+		}
+	}
+	return changes
+}
+
+// addTree watches dir and the directories under it, noting their files
+// in pending when it is not nil
+func (w *watcher) addTree(dir string, pending map[string]bool) {
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, ok := w.relative(path, d.IsDir())
+		if !ok && path != w.root {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if pending != nil && d.Type().IsRegular() {
+				pending[rel] = true
+			}
+			return nil
+		}
+		if w.dirs >= maxWatchedDirs || w.fw.Add(path) != nil {
+			return filepath.SkipDir
+		}
+		w.dirs++
+		return nil
+	})
+}
+
+// relative returns the workspace-relative path of a file or directory
+// the index would walk, or false for paths outside it, skipped, or ignored
+func (w *watcher) relative(path string, isDir bool) (string, bool) {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	parts := strings.Split(rel, "/")
+	if !isDir {
+		parts = parts[:len(parts)-1]
+	}
+	for _, part := range parts {
+		if skippedDirs[part] {
+			return "", false
+		}
+	}
+	if w.ignore.Match(rel) || isDir && w.ignore.matchOne(rel, true) {
+		return "", false
+	}
+	return rel, true
+}
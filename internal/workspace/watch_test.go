@@ -0,0 +1,72 @@
+package workspace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"main.go":           "package main\n",
+		".gitignore":        "*.log\n",
+		"node_modules/a.js": "x",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batches := make(chan []Change, 10)
+	go func() {
+		if err := Watch(ctx, root, func(c []Change) { batches <- c }); err != nil {
+			t.Error(err)
+		}
+	}()
+	time.Sleep(100 * time.Millisecond) // Let the watches be set up
+
+	changed := make(map[string]bool) // Path to removed
+	wait := func(path string) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			if _, ok := changed[path]; ok {
+				return
+			}
+			select {
+			case batch := <-batches:
+				for _, c := range batch {
+					changed[c.Path] = c.Removed
+				}
+			case <-deadline:
+				t.Fatalf("No change reported for %s, got %v", path, changed)
+			}
+		}
+	}
+
+	writeFiles(t, root, map[string]string{
+		"main.go":        "package main\n\nfunc main() {}\n",
+		"debug.log":      "x",
+		"node_modules/x": "x",
+		"pkg/sub/a.go":   "package sub\n",
+	})
+	wait("main.go")
+	wait("pkg/sub/a.go")
+	if changed["main.go"] || changed["pkg/sub/a.go"] {
+		t.Errorf("Expected writes reported as changes, got %v", changed)
+	}
+
+	os.Remove(filepath.Join(root, "main.go"))
+	delete(changed, "main.go")
+	wait("main.go")
+	if !changed["main.go"] {
+		t.Errorf("Expected main.go reported removed, got %v", changed)
+	}
+
+	for _, skipped := range []string{"debug.log", "node_modules/x"} {
+		if _, ok := changed[skipped]; ok {
+			t.Errorf("Expected %s skipped like the index skips it", skipped)
+		}
+	}
+}